@@ -0,0 +1,98 @@
+// Package announcement checks operator announcements and works out which of
+// them a display should be showing.
+package announcement
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/model"
+)
+
+const (
+	MinDuration = 10 * time.Second
+	MaxDuration = 24 * time.Hour
+
+	// MaxMarkdown keeps an announcement to something that fits on a screen.
+	MaxMarkdown = 2000
+)
+
+type Style struct {
+	Style       model.AnnouncementStyle
+	Description string
+}
+
+// Styles are all the announcement styles, in the order the form offers them.
+var Styles = []Style{
+	{model.AnnouncementStyleBanner, "Banner across the top"},
+	{model.AnnouncementStyleFullScreen, "Full screen"},
+}
+
+func isStyle(s model.AnnouncementStyle) bool {
+	return slices.ContainsFunc(Styles, func(x Style) bool { return x.Style == s })
+}
+
+// Validate checks a new announcement.  Expires is checked against Created.
+func Validate(a *model.Announcement) error {
+	if strings.TrimSpace(a.Markdown) == "" {
+		return errors.New("announcement has no text")
+	}
+	if len(a.Markdown) > MaxMarkdown {
+		return fmt.Errorf("announcement is longer than %d characters", MaxMarkdown)
+	}
+	if !isStyle(a.Style) {
+		return fmt.Errorf("no such announcement style %q", a.Style)
+	}
+	if a.TournamentID < 0 {
+		return fmt.Errorf("bad tournament id %d", a.TournamentID)
+	}
+	d := a.Expires.Sub(a.Created)
+	if d < MinDuration || d > MaxDuration {
+		return fmt.Errorf("announcement must last between %v and %v", MinDuration, MaxDuration)
+	}
+	return nil
+}
+
+// Current says if a is still showing at now.
+func Current(a *model.Announcement, now time.Time) bool {
+	return now.Before(a.Expires)
+}
+
+// For picks out the announcements a display of the given tournament should
+// show at now, oldest first.  Tournament 0 is a display with no tournament,
+// like the lobby, which only gets announcements for every display.
+func For(all []*model.Announcement, tournamentID int64, now time.Time) []*model.Announcement {
+	found := []*model.Announcement{}
+	for _, a := range all {
+		if !Current(a, now) {
+			continue
+		}
+		if a.TournamentID != 0 && a.TournamentID != tournamentID {
+			continue
+		}
+		found = append(found, a)
+	}
+	slices.SortFunc(found, func(a, b *model.Announcement) int {
+		return int(a.AnnouncementID - b.AnnouncementID)
+	})
+	return found
+}
+
+// Key sums up a list of announcements, so a display can say what it already
+// has and only hear back when that changes.
+func Key(as []*model.Announcement) string {
+	parts := []string{}
+	for _, a := range as {
+		parts = append(parts, fmt.Sprintf("%d.%d", a.AnnouncementID, a.Version))
+	}
+	return strings.Join(parts, ",")
+}
+
+// Concerns says if a change to a matters to a display of the given
+// tournament.
+func Concerns(a *model.Announcement, tournamentID int64) bool {
+	return a.TournamentID == 0 || a.TournamentID == tournamentID
+}
@@ -0,0 +1,81 @@
+package announcement
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/model"
+)
+
+var t0 = time.Date(2026, 6, 1, 19, 0, 0, 0, time.UTC)
+
+func TestValidate(t *testing.T) {
+	ok := model.Announcement{Style: model.AnnouncementStyleBanner, Markdown: "Dinner break", Created: t0, Expires: t0.Add(10 * time.Minute)}
+	for _, tc := range []struct {
+		name    string
+		edit    func(a *model.Announcement)
+		wantErr string
+	}{
+		{"ok", func(a *model.Announcement) {}, ""},
+		{"full screen", func(a *model.Announcement) { a.Style = model.AnnouncementStyleFullScreen }, ""},
+		{"blank", func(a *model.Announcement) { a.Markdown = "  " }, "no text"},
+		{"long", func(a *model.Announcement) { a.Markdown = strings.Repeat("x", MaxMarkdown+1) }, "longer"},
+		{"bad style", func(a *model.Announcement) { a.Style = "skywriting" }, "no such"},
+		{"bad tournament", func(a *model.Announcement) { a.TournamentID = -1 }, "tournament"},
+		{"too short", func(a *model.Announcement) { a.Expires = t0.Add(time.Second) }, "between"},
+		{"too long", func(a *model.Announcement) { a.Expires = t0.Add(48 * time.Hour) }, "between"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := ok
+			tc.edit(&a)
+			err := Validate(&a)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestFor(t *testing.T) {
+	all := []*model.Announcement{
+		{AnnouncementID: 3, TournamentID: 7, Expires: t0.Add(time.Minute)},
+		{AnnouncementID: 1, Expires: t0.Add(time.Minute)},
+		{AnnouncementID: 2, TournamentID: 8, Expires: t0.Add(time.Minute)},
+		{AnnouncementID: 4, Expires: t0},
+	}
+	ids := func(as []*model.Announcement) []int64 {
+		got := []int64{}
+		for _, a := range as {
+			got = append(got, a.AnnouncementID)
+		}
+		return got
+	}
+	for _, tc := range []struct {
+		tournamentID int64
+		want         []int64
+	}{
+		{7, []int64{1, 3}},
+		{8, []int64{1, 2}},
+		{0, []int64{1}},
+	} {
+		if got := ids(For(all, tc.tournamentID, t0)); !slices.Equal(got, tc.want) {
+			t.Errorf("For tournament %d = %v, want %v", tc.tournamentID, got, tc.want)
+		}
+	}
+}
+
+func TestKey(t *testing.T) {
+	as := []*model.Announcement{{AnnouncementID: 1, Version: 2}, {AnnouncementID: 5}}
+	if got := Key(as); got != "1.2,5.0" {
+		t.Errorf("Key = %q", got)
+	}
+	if got := Key(nil); got != "" {
+		t.Errorf("Key(nil) = %q", got)
+	}
+}
@@ -0,0 +1,78 @@
+/*
+apitoken makes and reads the tokens programs use with the REST API.
+
+A token is shown to its owner once, when it's made.  The database keeps
+only its SHA-256, which is enough to find it again: tokens are random, so
+there's nothing for a slow hash to protect.
+*/
+package apitoken
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// Prefix starts every token, so one pasted somewhere it shouldn't be is
+// easy to spot.
+const Prefix = "irata_"
+
+// What a token may do.  A token can only do what its owner can, too.
+const (
+	ScopeRead    = "read"    // list and read tournaments
+	ScopeControl = "control" // run the clock, count players and buy-ins
+	ScopeCreate  = "create"  // make tournaments
+)
+
+// Scopes are all the scopes, in the order they're shown.
+var Scopes = []string{ScopeRead, ScopeControl, ScopeCreate}
+
+// New makes a token, returning the secret to give to its owner and the
+// hash to store.
+func New() (secret, hash string) {
+	secret = Prefix + rand.Text()
+	return secret, Hash(secret)
+}
+
+// Hash is what's stored for a secret.
+func Hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
+
+// ParseScopes parses scopes separated by commas or spaces, returning them
+// in the order of Scopes.  There must be at least one.
+func ParseScopes(s string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' }) {
+		if !slices.Contains(Scopes, f) {
+			return nil, fmt.Errorf("unknown scope %q, want some of %s", f, strings.Join(Scopes, ", "))
+		}
+		seen[f] = true
+	}
+	if len(seen) == 0 {
+		return nil, fmt.Errorf("no scopes, want some of %s", strings.Join(Scopes, ", "))
+	}
+	scopes := []string{}
+	for _, scope := range Scopes {
+		if seen[scope] {
+			scopes = append(scopes, scope)
+		}
+	}
+	return scopes, nil
+}
+
+// FromRequest returns the bearer token in r's Authorization header, if
+// there is one.
+func FromRequest(r *http.Request) (string, bool) {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	token = strings.TrimSpace(token)
+	return token, token != ""
+}
@@ -0,0 +1,59 @@
+package apitoken
+
+import (
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestNew(t *testing.T) {
+	secret, hash := New()
+	if !strings.HasPrefix(secret, Prefix) {
+		t.Errorf("secret %q doesn't start with %q", secret, Prefix)
+	}
+	if Hash(secret) != hash {
+		t.Errorf("hash of the secret isn't the hash returned")
+	}
+	if other, _ := New(); other == secret {
+		t.Errorf("made the same secret twice")
+	}
+}
+
+func TestParseScopes(t *testing.T) {
+	for in, want := range map[string][]string{
+		"read":                 {"read"},
+		"control,read":         {"read", "control"},
+		"create control read ": {"read", "control", "create"},
+		"read,read":            {"read"},
+	} {
+		got, err := ParseScopes(in)
+		if err != nil || !slices.Equal(got, want) {
+			t.Errorf("ParseScopes(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", " , ", "read,admin"} {
+		if got, err := ParseScopes(in); err == nil {
+			t.Errorf("ParseScopes(%q) = %v", in, got)
+		}
+	}
+}
+
+func TestFromRequest(t *testing.T) {
+	for header, want := range map[string]string{
+		"Bearer irata_abc": "irata_abc",
+		"bearer irata_abc": "irata_abc",
+		"Basic dXNlcg==":   "",
+		"Bearer ":          "",
+		"":                 "",
+	} {
+		r := httptest.NewRequest("GET", "/", nil)
+		if header != "" {
+			r.Header.Set("Authorization", header)
+		}
+		got, ok := FromRequest(r)
+		if got != want || ok != (want != "") {
+			t.Errorf("FromRequest(%q) = %q, %v", header, got, ok)
+		}
+	}
+}
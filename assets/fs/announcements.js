@@ -0,0 +1,143 @@
+// announcements.js puts operator announcements over a clock, the lobby or the
+// slideshow.  It long-polls for them on its own, apart from whatever else the
+// page is doing.
+
+"use strict";
+
+// What the server last said is current, newest last.
+var announcements_current = [];
+// Announcements put away from the keyboard on this screen, by id and version,
+// so an edited announcement comes back.
+const announcements_dismissed = new Set();
+// Announcements whose sound has played here.
+const announcements_heard = new Set();
+
+function announcement_tag(a) {
+  return a.AnnouncementID + "." + a.Version;
+}
+
+function announcements_sleep(ms) {
+  return new Promise(resolve => setTimeout(resolve, ms));
+}
+
+function announcement_element(style) {
+  const id = "announcement-" + style;
+  let el = document.getElementById(id);
+  if (el === null) {
+    el = document.createElement("div");
+    el.id = id;
+    el.className = "announcement announcement-" + style;
+    el.style.display = "none";
+    el.innerHTML = '<div class="announcement-body"></div>' +
+      '<div class="announcement-hint">ESC TO DISMISS</div>';
+    el.addEventListener("click", () => dismiss_announcement(el));
+    document.body.appendChild(el);
+  }
+  return el;
+}
+
+// The newest announcement of each style that hasn't ended or been dismissed.
+function announcement_showing(style, now) {
+  return announcements_current.filter(a =>
+    a.Style === style &&
+    now < Date.parse(a.Expires) &&
+    !announcements_dismissed.has(announcement_tag(a))).pop();
+}
+
+function render_announcements() {
+  const now = irataNow();
+  for (const style of ["banner", "fullscreen"]) {
+    const el = announcement_element(style);
+    const a = announcement_showing(style, now);
+    if (!a) {
+      el.style.display = "none";
+      el.dataset.tag = "";
+      continue;
+    }
+    const tag = announcement_tag(a);
+    if (el.dataset.tag !== tag) {
+      el.querySelector(".announcement-body").innerHTML = a.HTML;
+      el.dataset.tag = tag;
+    }
+    el.style.display = "";
+  }
+}
+
+function play_announcement_sounds() {
+  // movement.js knows if the clock is muted; other pages don't have a mute.
+  const muted = typeof last_model !== "undefined" && last_model?.State?.SoundMuted === true;
+  for (const a of announcements_current) {
+    const tag = announcement_tag(a);
+    if (announcements_heard.has(tag)) {
+      continue;
+    }
+    announcements_heard.add(tag);
+    if (a.SoundPath && !muted && irataNow() < Date.parse(a.Expires)) {
+      new Audio(a.SoundPath).play().catch(e => console.log("can't play announcement sound:", e));
+    }
+  }
+}
+
+function dismiss_announcement(el) {
+  if (el.dataset.tag) {
+    announcements_dismissed.add(el.dataset.tag);
+    render_announcements();
+  }
+}
+
+// Esc or Enter puts away the full screen announcement, then the banner.  This
+// listens ahead of the clock's own key handlers, and keeps the key from them
+// if it was used.
+function handle_announcement_key(event) {
+  if (event.key !== "Escape" && event.key !== "Enter") {
+    return;
+  }
+  for (const style of ["fullscreen", "banner"]) {
+    const el = announcement_element(style);
+    if (el.style.display !== "none") {
+      dismiss_announcement(el);
+      event.preventDefault();
+      event.stopImmediatePropagation();
+      return;
+    }
+  }
+}
+
+async function announcements_listen_loop(tournament_id) {
+  // The empty key means "nothing showing," which is where we start.
+  let key = "";
+  for (;;) {
+    try {
+      const response = await fetch("/api/announcement-listen", {
+        method: "POST",
+        mode: "same-origin",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({ TournamentID: tournament_id, Key: key }),
+      });
+      if (response.status === 503) {
+        // The server is restarting; come back when it says.
+        await announcements_sleep(1000 * (Number(response.headers.get("Retry-After")) || 1));
+        continue;
+      }
+      if (!response.ok) {
+        throw new Error("announcement-listen: " + response.status);
+      }
+      const body = await response.json();
+      key = body.Key;
+      announcements_current = body.Announcements;
+      play_announcement_sounds();
+      render_announcements();
+    } catch (e) {
+      console.log("announcement listen failed, will retry:", e);
+      await announcements_sleep(5000 + Math.floor(Math.random() * 5000));
+    }
+  }
+}
+
+// Tournament 0 gets only the announcements for every display.
+function announcements_start(tournament_id) {
+  window.addEventListener("keyup", handle_announcement_key, true);
+  // Expiry happens here, without word from the server.
+  setInterval(render_announcements, 1000);
+  announcements_listen_loop(tournament_id);
+}
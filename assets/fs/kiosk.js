@@ -0,0 +1,97 @@
+// kiosk.js runs on a display that is remote-controlled from /manage/displays.
+// It registers under a device ID, long-polls for its assignment, and points a
+// full-screen frame at whatever it has been told to show.
+
+"use strict";
+
+const KIOSK_DEVICE_ID_KEY = "irata-kiosk-device-id";
+
+let kiosk_version = -1;
+let kiosk_url = null;
+
+async function kiosk_sleep(ms) {
+  await new Promise(resolve => setTimeout(resolve, ms));
+}
+
+// The device ID can be pinned with ?device=xyz in the URL, which is handy for
+// a Pi that boots straight into a browser.  It may be up to 64 letters,
+// digits and dashes.  Otherwise we make one up and
+// remember it.
+function kiosk_device_id() {
+  const params = new URLSearchParams(window.location.search);
+  let id = params.get("device");
+  if (id) {
+    localStorage.setItem(KIOSK_DEVICE_ID_KEY, id);
+    return id;
+  }
+  id = localStorage.getItem(KIOSK_DEVICE_ID_KEY);
+  if (!id) {
+    // crypto.randomUUID needs a secure context, which a LAN clock may not have.
+    const bytes = new Uint8Array(8);
+    crypto.getRandomValues(bytes);
+    id = Array.from(bytes, b => b.toString(16).padStart(2, "0")).join("");
+    localStorage.setItem(KIOSK_DEVICE_ID_KEY, id);
+  }
+  return id;
+}
+
+function show_kiosk_state(state) {
+  kiosk_version = state.Version;
+  document.getElementById("kiosk-name").textContent = state.Name || "(unnamed)";
+
+  if (state.URL === kiosk_url) {
+    return;
+  }
+  kiosk_url = state.URL;
+
+  const frame = document.getElementById("kiosk-frame");
+  const waiting = document.getElementById("kiosk-waiting");
+  if (state.URL) {
+    frame.src = state.URL;
+    frame.style.display = "block";
+    waiting.style.display = "none";
+  } else {
+    frame.removeAttribute("src");
+    frame.style.display = "none";
+    waiting.style.display = "flex";
+  }
+}
+
+async function kiosk_loop(protocol_version) {
+  const device_id = kiosk_device_id();
+  document.getElementById("kiosk-device-id").textContent = device_id;
+
+  for (;;) {
+    try {
+      const response = await fetch("/api/kiosk-listen", {
+        method: "POST",
+        mode: "same-origin",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          DeviceID: device_id,
+          Version: kiosk_version,
+          ProtocolVersion: protocol_version,
+        }),
+      });
+      if (response.status === 503) {
+        // The server is restarting; come back when it says.
+        await kiosk_sleep(1000 * (Number(response.headers.get("Retry-After")) || 1));
+        continue;
+      }
+      if (!response.ok) {
+        throw new Error("kiosk-listen: " + response.status);
+      }
+      const state = await response.json();
+      if (state.ProtocolVersion !== protocol_version) {
+        // The server has been upgraded; get the new page.
+        window.location.reload();
+        return;
+      }
+      show_kiosk_state(state);
+    } catch (e) {
+      console.log("kiosk listen failed, will retry:", e);
+      kiosk_version = -1;
+      await kiosk_sleep(5000 + Math.floor(Math.random() * 5000));
+    }
+  }
+}
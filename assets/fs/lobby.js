@@ -0,0 +1,159 @@
+// lobby.js keeps the lobby board up to date.  It long-polls
+// /api/lobby-listen for every tournament on the board at once, and ticks the
+// clocks locally in between.
+
+"use strict";
+
+// Without explicit tournament IDs, reload now and then to pick up tournaments
+// that have started (or finished) since the page was loaded.
+const LOBBY_RESCAN_MS = 5 * 60 * 1000;
+
+const lobby_models = {};
+const lobby_refreshed_at = {};
+
+async function lobby_sleep(ms) {
+  await new Promise(resolve => setTimeout(resolve, ms));
+}
+
+function lobby_ids() {
+  return Array.from(document.querySelectorAll(".lobby-cell"),
+                    cell => parseInt(cell.dataset.tournamentId));
+}
+
+function lobby_commas(n) {
+  return (n || 0).toLocaleString("en-US");
+}
+
+function lobby_time_remaining(model) {
+  const state = model.State;
+  if (state.IsClockRunning && state.CurrentLevelEndsAt) {
+    return Math.max(0, state.CurrentLevelEndsAt - irataNow());
+  }
+  return state.TimeRemainingMillis || 0;
+}
+
+function lobby_format_time(ms) {
+  const seconds = Math.ceil(ms / 1000);
+  const h = Math.floor(seconds / 3600);
+  const m = Math.floor((seconds % 3600) / 60);
+  const s = seconds % 60;
+  const ss = s < 10 ? "0" + s : "" + s;
+  if (h > 0) {
+    const mm = m < 10 ? "0" + m : "" + m;
+    return h + ":" + mm + ":" + ss;
+  }
+  return m + ":" + ss;
+}
+
+function lobby_set(cell, selector, text) {
+  cell.querySelector(selector).textContent = text;
+}
+
+function lobby_render(model) {
+  const cell = document.getElementById("lobby-" + model.EventID);
+  if (!cell) {
+    return;
+  }
+  const levels = model.Structure.Levels || [];
+  const level = levels[Math.min(model.State.CurrentLevelNumber, levels.length - 1)];
+
+  lobby_set(cell, ".lobby-name", model.EventName);
+  lobby_set(cell, ".lobby-level", level ? level.Banner : "");
+  lobby_set(cell, ".lobby-blinds", level ? level.Description : "");
+  lobby_set(cell, ".lobby-players", model.State.CurrentPlayers + " / " + model.State.BuyIns);
+  lobby_set(cell, ".lobby-average", lobby_commas(model.Transients?.AverageChips));
+  cell.classList.toggle("clock-td-break", !!(level && level.IsBreak));
+  lobby_render_clock(cell, model);
+}
+
+function lobby_render_clock(cell, model) {
+  const text = lobby_format_time(lobby_time_remaining(model));
+  lobby_set(cell, ".lobby-clock", model.State.IsClockRunning ? text : text + " ⏸");
+}
+
+// The server advances levels when time runs out, but doesn't tell anyone,
+// because nothing was saved.  So ask for the new level ourselves.
+async function lobby_refresh(id) {
+  const now = irataNow();
+  if (lobby_refreshed_at[id] && now - lobby_refreshed_at[id] < 2000) {
+    return;
+  }
+  lobby_refreshed_at[id] = now;
+  try {
+    const response = await fetch("/api/model/" + id, { mode: "same-origin" });
+    if (response.ok) {
+      const model = await response.json();
+      lobby_models[id] = model;
+      lobby_render(model);
+    }
+  } catch (e) {
+    console.log("lobby: can't refresh tournament " + id, e);
+  }
+}
+
+function lobby_tick() {
+  for (const id in lobby_models) {
+    const model = lobby_models[id];
+    const cell = document.getElementById("lobby-" + id);
+    if (cell) {
+      lobby_render_clock(cell, model);
+    }
+    if (model.State.IsClockRunning && lobby_time_remaining(model) <= 0) {
+      lobby_refresh(id);
+    }
+  }
+}
+
+async function lobby_listen_loop(protocol_version) {
+  const ids = lobby_ids();
+  for (;;) {
+    try {
+      const response = await fetch("/api/lobby-listen", {
+        method: "POST",
+        mode: "same-origin",
+        headers: { "Content-Type": "application/json" },
+        body: JSON.stringify({
+          Tournaments: ids.map(id => ({
+            TournamentID: id,
+            Version: lobby_models[id] ? lobby_models[id].Version : -1,
+          })),
+          ProtocolVersion: protocol_version,
+        }),
+      });
+      if (response.status === 503) {
+        // The server is restarting; come back when it says.
+        await lobby_sleep(1000 * (Number(response.headers.get("Retry-After")) || 1));
+        continue;
+      }
+      if (!response.ok) {
+        // Probably a deleted tournament.  Start over.
+        console.log("lobby-listen: " + response.status);
+        await lobby_sleep(10000);
+        window.location.reload();
+        return;
+      }
+      for (const model of await response.json()) {
+        if (model.Transients?.ProtocolVersion !== protocol_version) {
+          window.location.reload();
+          return;
+        }
+        lobby_models[model.EventID] = model;
+        lobby_render(model);
+      }
+    } catch (e) {
+      console.log("lobby listen failed, will retry:", e);
+      await lobby_sleep(5000 + Math.floor(Math.random() * 5000));
+    }
+  }
+}
+
+function lobby_start(protocol_version, explicit) {
+  if (!explicit) {
+    setTimeout(() => window.location.reload(), LOBBY_RESCAN_MS);
+  }
+  if (lobby_ids().length === 0) {
+    return;
+  }
+  setInterval(lobby_tick, 250);
+  lobby_listen_loop(protocol_version);
+}
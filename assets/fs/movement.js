@@ -103,6 +103,8 @@ var last_model = {
     "ProtocolVersion": undefined,
   }
 }
+// Each footer is {HTML, Weight, ValidFrom, ValidUntil}, as /api/footer-plugs
+// sends them.
 var footers = [
   "ATOMIC BATTERIES TO POWER... TURBINES TO SPEED...",
   "RETICULATING SPLINES...",
@@ -110,7 +112,7 @@ var footers = [
   "FLUXING CAPACITOR...",
   "CONGRATULATIONS, YOU AREN'T RUNNING EUNICE...",
   "TAPPING AQUARIUM...",
-];
+].map(text => ({ HTML: text, Weight: 1 }));
 var fetched_footer_plugs_id = undefined;
 
 function want_footers() {
@@ -126,7 +128,7 @@ function want_footers() {
 }
 
 async function fetch_footers(want_footer_plugs_id) {
-  const response = await fetch("/api/footerPlugs/" + want_footer_plugs_id, {});
+  const response = await fetch("/api/footer-plugs/" + want_footer_plugs_id, {});
   const footer_model = await response.json();
 
   if (want_footer_plugs_id != footer_model.FooterPlugsID) {
@@ -135,8 +137,8 @@ async function fetch_footers(want_footer_plugs_id) {
   }
 
   fetched_footer_plugs_id = want_footer_plugs_id;
-  footers = footer_model.TextPlugs;
-  shuffle_array(footers);
+  footers = footer_model.Plugs;
+  footer_deck = [];
   next_footer();
 }
 
@@ -164,18 +166,49 @@ let cached_fetch_footers_promise = function () {
   }
 }();
 
-const next_footer = (function () {
-  var next_footer_offset = 99999;
+// footer_deck is the footers still to show before reshuffling.  A footer
+// goes in once per unit of weight, so heavier footers come up more often.
+var footer_deck = [];
 
-  return function () {
-    next_footer_offset++;
-    if (next_footer_offset > footers.length) {
-      next_footer_offset = 0;
-      shuffle_array(footers);
+function footer_current(footer, now) {
+  if (footer.ValidFrom && now < Date.parse(footer.ValidFrom)) {
+    return false;
+  }
+  if (footer.ValidUntil && now >= Date.parse(footer.ValidUntil)) {
+    return false;
+  }
+  return true;
+}
+
+function deal_footers() {
+  let deck = [];
+  for (const footer of footers) {
+    for (let i = 0; i < Math.max(1, footer.Weight || 1); i++) {
+      deck.push(footer);
+    }
+  }
+  shuffle_array(deck);
+  return deck;
+}
+
+function next_footer() {
+  const now = irataNow();
+  // Deal at most twice, so a set with nothing current doesn't spin.
+  for (let deals = 0; deals < 2; ) {
+    if (footer_deck.length === 0) {
+      footer_deck = deal_footers();
+      deals++;
+      if (footer_deck.length === 0) {
+        return;
+      }
+    }
+    const footer = footer_deck.pop();
+    if (footer_current(footer, now)) {
+      set_html("footer", footer.HTML);
+      return;
     }
-    set_html("footer", footers[next_footer_offset % footers.length]);
   }
-})()
+}
 
 let footer_interval_id = undefined;
 function start_rotating_footers() {
@@ -193,77 +226,97 @@ function reset_footer_interval() {
   start_rotating_footers();
 }
 
-// Slideshow management
-const slideshow_interval_ms = 30000;
-let slideshow_enabled = false;
+// Slideshow management.  The page carries every slide it might show, each
+// with a trigger and a duration.  Which one is up is worked out from the wall
+// clock, so every client shows the same slide at the same time.
+const slideshow_tick_ms = 250;
 let slideshow_interval_id = undefined;
-let current_slide_index = 0;
+let slideshow_showing = null;
+// Esc hides the slideshow on this screen until the model changes.
+let slideshow_dismissed = false;
+
+// Generated slides (like the leaderboard) are marked empty when they have
+// nothing to say, and are skipped.
+function active_slides(trigger) {
+  return Array.from(document.querySelectorAll('.slideshow-slide:not(.slideshow-slide-empty)'))
+    .filter(el => el.dataset.trigger === trigger);
+}
 
-function count_slides() {
-  return document.querySelectorAll('.slideshow-slide').length;
+// Pick from slides as if they had been showing in turn for elapsed_ms.
+// Unless loop is set, the slides run once and then there's nothing to show.
+function pick_slide(slides, elapsed_ms, loop) {
+  const total = slides.reduce((sum, el) => sum + Number(el.dataset.durationMs), 0);
+  if (total <= 0 || !(elapsed_ms >= 0)) {
+    return null;
+  }
+  if (loop) {
+    elapsed_ms %= total;
+  } else if (elapsed_ms >= total) {
+    return null;
+  }
+  for (const el of slides) {
+    elapsed_ms -= Number(el.dataset.durationMs);
+    if (elapsed_ms < 0) {
+      return el;
+    }
+  }
+  return null;
 }
 
-function show_slide(index) {
-  const slides = document.querySelectorAll('.slideshow-slide');
-  if (slides.length === 0) {
-    return;
+function current_level() {
+  return last_model?.Structure?.Levels?.[last_model.State.CurrentLevelNumber];
+}
+
+function choose_slide() {
+  if (slideshow_dismissed || last_model === undefined) {
+    return null;
   }
-  
-  // Hide all slides
-  slides.forEach(slide => slide.style.display = 'none');
-  
-  // Show the requested slide
-  if (index >= 0 && index < slides.length) {
-    slides[index].style.display = 'block';
+  const level = current_level();
+  const on_break = level?.IsBreak ?? false;
+
+  if (is_clock_running() && level) {
+    const since_start = level.DurationMinutes * 60 * 1000 - millis_remaining_in_level();
+    const el = pick_slide(active_slides("level-start"), since_start, false);
+    if (el) {
+      return el;
+    }
   }
-}
 
-function next_slide() {
-  const slideCount = count_slides();
-  if (slideCount === 0) {
-    return;
+  if (last_model.State.Slideshow) {
+    let pool = active_slides("");
+    if (on_break) {
+      pool = pool.concat(active_slides("break"));
+    }
+    return pick_slide(pool, irataNow(), true);
   }
-  
-  current_slide_index = (current_slide_index + 1) % slideCount;
-  show_slide(current_slide_index);
+  if (on_break) {
+    return pick_slide(active_slides("break"), irataNow(), true);
+  }
+  return null;
 }
 
-function start_slideshow() {
-  const slideCount = count_slides();
-  if (slideCount === 0) {
-    console.log("No slides available");
+function update_slideshow() {
+  const el = choose_slide();
+  if (el === slideshow_showing) {
     return;
   }
-  
-  slideshow_enabled = true;
-  current_slide_index = 0;
-  
-  // Show overlay and first slide
-  const overlay = document.getElementById('slideshow-overlay');
-  if (overlay) {
-    overlay.style.display = 'block';
-  }
-  show_slide(current_slide_index);
-  
-  // Start rotation
-  if (typeof slideshow_interval_id === 'undefined') {
-    slideshow_interval_id = setInterval(next_slide, slideshow_interval_ms);
+  if (slideshow_showing) {
+    slideshow_showing.style.display = 'none';
   }
-}
+  slideshow_showing = el;
 
-function stop_slideshow() {
-  slideshow_enabled = false;
-  
-  // Hide overlay
   const overlay = document.getElementById('slideshow-overlay');
+  if (el) {
+    el.style.display = 'block';
+  }
   if (overlay) {
-    overlay.style.display = 'none';
+    overlay.style.display = el ? 'block' : 'none';
   }
-  
-  // Stop rotation
-  if (typeof slideshow_interval_id === 'number') {
-    clearInterval(slideshow_interval_id);
-    slideshow_interval_id = undefined;
+}
+
+function start_slideshow_timer() {
+  if (typeof slideshow_interval_id === 'undefined') {
+    slideshow_interval_id = setInterval(update_slideshow, slideshow_tick_ms);
   }
 }
 
@@ -284,6 +337,11 @@ async function listen_and_consume_model_changes(currentVersion, abortSignal) {
     },
     body: JSON.stringify({ TournamentID: tid, Version: currentVersion, ProtocolVersion: protocolVersion }),
   });
+  if (response.status === 503) {
+    // The server is restarting; come back when it says, on the next tick.
+    await sleep(1000 * (Number(response.headers.get("Retry-After")) || 1));
+    return Promise.reject("server restarting");
+  }
   const model = await response.json();
   import_new_model_from_server(model);
   return "fetched new model";
@@ -387,11 +445,10 @@ const moveMuteBouncer = function () {
 function import_new_model_from_server(model) {
   console.log(`new model protocol=${model.Transients.ProtocolVersion} model.Version=${model.Version}`)
 
-  if (model.State.Slideshow) {
-    start_slideshow();
-  } else {
-    stop_slideshow();
-  }
+  // Before the slideshow, which needs to know which slides are empty.
+  update_leaderboard(model);
+  update_generated_slides(model);
+  slideshow_dismissed = false;
 
   let el = document.getElementById("mute-bouncer");
   if (el) {
@@ -434,6 +491,109 @@ function import_new_model_from_server(model) {
   }
   set_text("avg-chips", model.Transients.AverageChips)
   setNextDescription();
+  update_layout_panels(model);
+  update_slideshow();
+  start_slideshow_timer();
+}
+
+// Panels that only appear in some layouts.  The classic clock has neither.
+function update_layout_panels(model) {
+  const payouts = document.getElementById("payouts");
+  if (payouts) {
+    payouts.innerHTML = protect_html(model.State.PrizePool);
+  }
+
+  const seating = document.getElementById("seating");
+  if (seating) {
+    seating.innerHTML = "";
+    let table = null;
+    let list = null;
+    for (const seat of model.State.Seating ?? []) {
+      if (seat.Table !== table) {
+        table = seat.Table;
+        const group = document.createElement("div");
+        group.className = "layout-seating-table";
+        const label = document.createElement("div");
+        label.className = "clock-rr-label";
+        label.textContent = "TABLE " + table;
+        list = document.createElement("div");
+        group.appendChild(label);
+        group.appendChild(list);
+        seating.appendChild(group);
+      }
+      const row = document.createElement("div");
+      row.textContent = seat.Seat + " " + seat.Name;
+      list.appendChild(row);
+    }
+  }
+}
+
+function format_chips(n) {
+  return (n ?? 0).toLocaleString('en-US');
+}
+
+// Fill in the chip leader box, leaderboard slides and panel from the counts
+// the floor entered.
+function update_leaderboard(model) {
+  const leaders = model.Transients.ChipLeaders ?? [];
+  const slides = document.querySelectorAll(".slide-leaderboard");
+  const tbodies = document.querySelectorAll(".leaderboard-rows");
+  const stats = document.querySelectorAll(".leaderboard-stats");
+
+  if (leaders.length === 0) {
+    hide_els_by_ids(["chip-leader-container"]);
+    slides.forEach(el => el.classList.add("slideshow-slide-empty"));
+    tbodies.forEach(el => el.innerHTML = "");
+    stats.forEach(el => el.textContent = "");
+    return;
+  }
+
+  show_els_by_ids(["chip-leader-container"]);
+  set_text("chip-leader", leaders[0].Name + " " + format_chips(leaders[0].Chips));
+
+  for (const tbody of tbodies) {
+    tbody.innerHTML = "";
+    leaders.forEach((cc, i) => {
+      tbody.appendChild(table_row([i + 1, cc.Name, format_chips(cc.Chips)]));
+    });
+  }
+  const summary = "AVG " + format_chips(model.Transients.CountedAverageChips) +
+    " · MEDIAN " + format_chips(model.Transients.MedianChips) +
+    " · COUNTED " + new Date(model.Transients.ChipsCountedAt).toLocaleTimeString([], {hour: 'numeric', minute: '2-digit'});
+  stats.forEach(el => el.textContent = summary);
+  slides.forEach(el => el.classList.remove("slideshow-slide-empty"));
+}
+
+function table_row(values) {
+  const tr = document.createElement("tr");
+  for (const v of values) {
+    const td = document.createElement("td");
+    td.textContent = v;
+    tr.appendChild(td);
+  }
+  return tr;
+}
+
+// How many levels the structure slide shows, starting with the current one.
+const structure_slide_levels = 8;
+
+// Fill in the payout and structure slides.
+function update_generated_slides(model) {
+  const payouts = model.State.PrizePool ?? "";
+  document.querySelectorAll(".payouts-body").forEach(el => el.innerHTML = protect_html(payouts));
+  document.querySelectorAll(".slide-payouts").forEach(el =>
+    el.classList.toggle("slideshow-slide-empty", payouts.trim() === ""));
+
+  const levels = (model.Structure?.Levels ?? []).slice(
+    model.State.CurrentLevelNumber, model.State.CurrentLevelNumber + structure_slide_levels);
+  for (const tbody of document.querySelectorAll(".structure-rows")) {
+    tbody.innerHTML = "";
+    for (const level of levels) {
+      tbody.appendChild(table_row([level.Banner, level.Description, level.DurationMinutes + " MIN"]));
+    }
+  }
+  document.querySelectorAll(".slide-structure").forEach(el =>
+    el.classList.toggle("slideshow-slide-empty", levels.length === 0));
 }
 
 function is_clock_running() {
@@ -444,7 +604,7 @@ function ms_until_next_break() {
   if (!is_clock_running()) {
     return undefined;
   }
-  let amt = last_model.State.CurrentLevelEndsAt - Date.now();
+  let amt = last_model.State.CurrentLevelEndsAt - irataNow();
   for (let i = last_model.State.CurrentLevelNumber + 1; i < last_model.Structure.Levels.length; i++) {
     let level = last_model.Structure.Levels[i];
     if (level.IsBreak) {
@@ -560,7 +720,7 @@ function redirect(where) {
 function millis_remaining_in_level() {
   var ends_at = last_model?.State?.CurrentLevelEndsAt;
   if (ends_at) {
-    return ends_at - Date.now();
+    return ends_at - irataNow();
   }
 
   var remaining = last_model?.State?.TimeRemainingMillis;
@@ -633,6 +793,7 @@ function advance_clock_from_wall_clock() {
         last_model.State.IsClockRunning = false;
       }
       setNextDescription();
+      update_generated_slides(last_model);
     }
 
     update_time_fields();
@@ -649,6 +810,10 @@ function update_time_fields() {
 function update_big_clock() {
   var render = to_hmmss(millis_remaining_in_level());
   var clockElement = document.getElementById("clock");
+  if (clockElement === null) {
+    // Not every layout has a clock panel.
+    return;
+  }
   clockElement.innerHTML = render;
 
   // Add/remove clock-has-hours class for responsive sizing
@@ -771,8 +936,9 @@ function installKeyboardHandlers(forWhom) {
   }
 
   function handle_escape(_) {
-    if (slideshow_enabled) {
-      stop_slideshow();
+    if (slideshow_showing) {
+      slideshow_dismissed = true;
+      update_slideshow();
     } else if (showing_help) {
       hide_help_dialog();
     } else {
@@ -788,6 +954,10 @@ function installKeyboardHandlers(forWhom) {
     redirect('/chopomatic?tid=' + tournament_id());
   }
 
+  function redirect_to_chip_counts(_) {
+    redirect(window.location.pathname + "/chips");
+  }
+
   const unauth_keycode_to_handler = {
     'Escape': handle_escape,
     'KeyC': redirect_to_chopomatic,
@@ -815,6 +985,7 @@ function installKeyboardHandlers(forWhom) {
     'KeyE': redirect_to_edit,
     'KeyF': next_footer_key,
     'KeyG': playNextLevelSound,
+    'KeyK': redirect_to_chip_counts,
     'KeyM': toggle_mute,
     'KeyR': smwa('Restart'),
     'KeyS': toggle_slideshow,
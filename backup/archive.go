@@ -0,0 +1,257 @@
+// Package backup dumps a whole site to an archive, and restores an archive
+// into an empty database.
+//
+// An archive is a gzipped tar file.  manifest.json comes first and says
+// which version of the format the rest is in.  Each kind of thing is a JSON
+// file of its own, and uploaded slide images are kept as they are under
+// media/.  Nothing in it is a plaintext secret: passwords are hashes, and
+// the cookie keys are left out, so a restored site needs new ones.
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+const (
+	// Format is what the manifest says the file is.
+	Format = "irata-backup"
+	// FormatVersion is the version of the archive layout written here.
+	// Archives from later versions are refused.
+	FormatVersion = 1
+
+	manifestFile = "manifest.json"
+	mediaDir     = "media/slides/"
+
+	// maxEntrySize guards against a damaged archive claiming some huge
+	// entry.
+	maxEntrySize = 256 << 20
+)
+
+type Manifest struct {
+	Format    string
+	Version   int
+	CreatedAt time.Time
+	// Counts is how many of each kind of thing there are, for people.
+	Counts map[string]int
+}
+
+// Slide is a slide from the library, with its uploaded image if it has one.
+// The image goes in the archive as a file of its own.
+type Slide struct {
+	Slide     *model.Slide
+	ImageType string `json:",omitempty"`
+	Image     []byte `json:"-"`
+}
+
+// Archive is everything in a site.  IDs are the ones from the database the
+// archive was taken from; Restore maps them to new ones.
+type Archive struct {
+	Manifest Manifest
+
+	SiteConfig     *model.SiteConfig
+	Users          []*state.UserBackup
+	Structures     []*model.Structure
+	FooterPlugSets []*model.FooterPlugs
+	Layouts        []*model.Layout
+	Leagues        []*model.League
+	Slides         []*Slide
+	SlideSets      []*model.SlideSet
+	Templates      []*model.TournamentTemplate
+	Tournaments    []*model.Tournament
+	Displays       []*model.Display
+	Announcements  []*model.Announcement
+}
+
+// sections names the JSON file each part of the archive is kept in.
+func (a *Archive) sections() []struct {
+	name string
+	v    any
+} {
+	return []struct {
+		name string
+		v    any
+	}{
+		{"site-config.json", &a.SiteConfig},
+		{"users.json", &a.Users},
+		{"structures.json", &a.Structures},
+		{"footer-plug-sets.json", &a.FooterPlugSets},
+		{"layouts.json", &a.Layouts},
+		{"leagues.json", &a.Leagues},
+		{"slides.json", &a.Slides},
+		{"slide-sets.json", &a.SlideSets},
+		{"templates.json", &a.Templates},
+		{"tournaments.json", &a.Tournaments},
+		{"displays.json", &a.Displays},
+		{"announcements.json", &a.Announcements},
+	}
+}
+
+func (a *Archive) counts() map[string]int {
+	return map[string]int{
+		"users":            len(a.Users),
+		"structures":       len(a.Structures),
+		"footer plug sets": len(a.FooterPlugSets),
+		"layouts":          len(a.Layouts),
+		"leagues":          len(a.Leagues),
+		"slides":           len(a.Slides),
+		"slide sets":       len(a.SlideSets),
+		"templates":        len(a.Templates),
+		"tournaments":      len(a.Tournaments),
+		"displays":         len(a.Displays),
+		"announcements":    len(a.Announcements),
+	}
+}
+
+func mediaName(slideID int64) string {
+	return mediaDir + strconv.FormatInt(slideID, 10)
+}
+
+// Write writes the archive to w.
+func Write(w io.Writer, a *Archive) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+
+	a.Manifest.Format = Format
+	a.Manifest.Version = FormatVersion
+	a.Manifest.Counts = a.counts()
+
+	modTime := a.Manifest.CreatedAt
+	add := func(name string, data []byte) error {
+		if err := tw.WriteHeader(&tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(data)),
+			ModTime: modTime,
+		}); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	addJSON := func(name string, v any) error {
+		data, err := json.MarshalIndent(v, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshaling %s: %w", name, err)
+		}
+		return add(name, data)
+	}
+
+	if err := addJSON(manifestFile, &a.Manifest); err != nil {
+		return err
+	}
+	for _, s := range a.sections() {
+		if err := addJSON(s.name, s.v); err != nil {
+			return err
+		}
+	}
+	for _, s := range a.Slides {
+		if s.ImageType == "" {
+			continue
+		}
+		if err := add(mediaName(s.Slide.SlideID), s.Image); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// Read reads an archive written by Write.
+func Read(r io.Reader) (*Archive, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	a := &Archive{}
+	sections := map[string]any{}
+	for _, s := range a.sections() {
+		sections[s.name] = s.v
+	}
+	media := map[int64][]byte{}
+
+	first := true
+	for {
+		hdr, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("reading archive: %w", err)
+		}
+		if hdr.Size > maxEntrySize {
+			return nil, fmt.Errorf("%s is too big (%d bytes)", hdr.Name, hdr.Size)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("reading %s: %w", hdr.Name, err)
+		}
+
+		if first {
+			if hdr.Name != manifestFile {
+				return nil, fmt.Errorf("not a backup archive: starts with %s, not %s", hdr.Name, manifestFile)
+			}
+			if err := json.Unmarshal(data, &a.Manifest); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", manifestFile, err)
+			}
+			if a.Manifest.Format != Format {
+				return nil, fmt.Errorf("not a backup archive: format is %q", a.Manifest.Format)
+			}
+			if a.Manifest.Version < 1 || a.Manifest.Version > FormatVersion {
+				return nil, fmt.Errorf("backup format version %d isn't supported; this irata reads version %d", a.Manifest.Version, FormatVersion)
+			}
+			first = false
+			continue
+		}
+
+		if v, ok := sections[hdr.Name]; ok {
+			if err := json.Unmarshal(data, v); err != nil {
+				return nil, fmt.Errorf("reading %s: %w", hdr.Name, err)
+			}
+			continue
+		}
+		if idStr, ok := strings.CutPrefix(hdr.Name, mediaDir); ok {
+			id, err := strconv.ParseInt(idStr, 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("bad media file name %s", hdr.Name)
+			}
+			media[id] = data
+			continue
+		}
+		return nil, fmt.Errorf("unexpected file %s in archive", hdr.Name)
+	}
+	if first {
+		return nil, errors.New("archive is empty")
+	}
+
+	for _, s := range a.Slides {
+		if s.Slide == nil {
+			return nil, errors.New("slides.json has an empty slide")
+		}
+		if s.ImageType == "" {
+			continue
+		}
+		data, ok := media[s.Slide.SlideID]
+		if !ok {
+			return nil, fmt.Errorf("image for slide %d is missing", s.Slide.SlideID)
+		}
+		s.Image = data
+	}
+
+	return a, nil
+}
@@ -0,0 +1,308 @@
+package backup
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+func sampleArchive() *Archive {
+	levels := model.StructureData{Levels: []*model.Level{{DurationMinutes: 20}}}
+	return &Archive{
+		Manifest:   Manifest{CreatedAt: time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC)},
+		SiteConfig: &model.SiteConfig{Name: "Home Game", DefaultSlideSetID: 7},
+		Users: []*state.UserBackup{{
+			UserIdentity:   model.UserIdentity{ID: 3, Nick: "alice", IsAdmin: true},
+			EmailAddresses: []string{"alice@example.com"},
+			Passwords:      []model.Password{{PasswordHash: "$argon2id$..."}},
+		}},
+		Structures:     []*model.Structure{{ID: 4, Name: "Turbo", StructureData: levels}},
+		FooterPlugSets: []*model.FooterPlugs{{FooterPlugsID: 5, Name: "Plugs", Plugs: []model.FooterPlug{{Kind: model.FooterPlugKindText, Text: "hi"}}}},
+		Layouts:        []*model.Layout{{LayoutID: 6, Name: "Big"}},
+		Leagues:        []*model.League{{LeagueID: 8, Name: "Fall", Formula: model.PointsFormula{Kind: model.PointsTable, Table: []float64{3, 2, 1}}}},
+		Slides: []*Slide{
+			{Slide: &model.Slide{SlideID: 10, Name: "logo", Kind: model.SlideKindImage, UploadedImage: true}, ImageType: "image/png", Image: []byte("\x89PNG")},
+			{Slide: &model.Slide{SlideID: 11, Name: "league", Kind: model.SlideKindLeague, LeagueID: 8}},
+		},
+		SlideSets: []*model.SlideSet{{SlideSetID: 7, Name: "Default", Entries: []model.SlideSetEntry{{SlideID: 11}, {SlideID: 99}, {SlideID: 10}}}},
+		Templates: []*model.TournamentTemplate{{
+			TournamentTemplateID: 12,
+			Name:                 "Tuesday",
+			Tournament:           &model.Tournament{EventName: "Tuesday", LayoutID: 6, LeagueID: 8, Structure: levels, State: &model.State{}},
+		}},
+		Tournaments: []*model.Tournament{{
+			EventID:         20,
+			EventName:       "Tuesday",
+			FooterPlugsID:   5,
+			LayoutID:        66,
+			SlideSetID:      7,
+			LeagueID:        8,
+			FromStructureID: 4,
+			FromTemplateID:  12,
+			Structure:       levels,
+			State:           &model.State{CurrentLevelNumber: 0, IsClockRunning: true},
+		}},
+		Displays: []*model.Display{{DisplayID: 30, DeviceID: "pi-1", Name: "Bar", Mode: model.DisplayModeTournament, TournamentID: 20}},
+		Announcements: []*model.Announcement{
+			{AnnouncementID: 40, TournamentID: 20, Markdown: "Dinner"},
+			{AnnouncementID: 41, TournamentID: 21, Markdown: "Gone"},
+			{AnnouncementID: 42, Markdown: "Everyone"},
+		},
+	}
+}
+
+func TestWriteRead(t *testing.T) {
+	a := sampleArchive()
+	var buf bytes.Buffer
+	if err := Write(&buf, a); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	got, err := Read(&buf)
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	if got.Manifest.Version != FormatVersion || got.Manifest.Counts["tournaments"] != 1 {
+		t.Errorf("manifest = %+v", got.Manifest)
+	}
+	if got.SiteConfig.Name != "Home Game" || len(got.Users) != 1 || got.Users[0].Passwords[0].PasswordHash != "$argon2id$..." {
+		t.Errorf("site config or users didn't survive: %+v %+v", got.SiteConfig, got.Users)
+	}
+	if !bytes.Equal(got.Slides[0].Image, []byte("\x89PNG")) || got.Slides[0].ImageType != "image/png" {
+		t.Errorf("slide image = %q %q", got.Slides[0].ImageType, got.Slides[0].Image)
+	}
+	if got.Slides[1].Image != nil {
+		t.Errorf("imageless slide got an image")
+	}
+	if got.Tournaments[0].FromTemplateID != 12 || !got.Tournaments[0].State.IsClockRunning {
+		t.Errorf("tournament = %+v", got.Tournaments[0])
+	}
+}
+
+// archiveWith builds an archive with just a manifest and the given files.
+func archiveWith(t *testing.T, manifest Manifest, files map[string]string) *bytes.Buffer {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	add := func(name string, data []byte) {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0600, Size: int64(len(data))}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write(data); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m, _ := json.Marshal(manifest)
+	add(manifestFile, m)
+	for name, data := range files {
+		add(name, []byte(data))
+	}
+	tw.Close()
+	gz.Close()
+	return &buf
+}
+
+func TestReadRejects(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		manifest Manifest
+		files    map[string]string
+		wantErr  string
+	}{
+		{"newer", Manifest{Format: Format, Version: FormatVersion + 1}, nil, "isn't supported"},
+		{"foreign", Manifest{Format: "tarball", Version: 1}, nil, "not a backup"},
+		{"stray file", Manifest{Format: Format, Version: 1}, map[string]string{"passwd": "root"}, "unexpected file"},
+		{"missing image", Manifest{Format: Format, Version: 1},
+			map[string]string{"slides.json": `[{"Slide":{"SlideID":1},"ImageType":"image/png"}]`}, "missing"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Read(archiveWith(t, tc.manifest, tc.files))
+			if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+	if _, err := Read(strings.NewReader("not gzip")); err == nil {
+		t.Error("garbage accepted")
+	}
+}
+
+// fakeSink hands out IDs from 100 up and keeps what it's given.
+type fakeSink struct {
+	counts map[string]int64
+	lastID int64
+
+	siteConfig    *model.SiteConfig
+	users         []*state.UserBackup
+	images        map[int64]string
+	slides        []*model.Slide
+	slideSets     []*model.SlideSet
+	templates     []*model.TournamentTemplate
+	tournaments   []*model.Tournament
+	displays      []*model.Display
+	announcements []*model.Announcement
+	writes        int
+}
+
+func newFakeSink() *fakeSink {
+	return &fakeSink{
+		lastID:     99,
+		siteConfig: &model.SiteConfig{CookieKeys: []model.CookieKeyPair{{HashKey64: "keep"}}},
+		images:     map[int64]string{},
+	}
+}
+
+func (s *fakeSink) id() (int64, error) {
+	s.writes++
+	s.lastID++
+	return s.lastID, nil
+}
+
+func (s *fakeSink) CountRows(context.Context) (map[string]int64, error) { return s.counts, nil }
+func (s *fakeSink) FetchSiteConfig(context.Context) (*model.SiteConfig, error) {
+	return s.siteConfig, nil
+}
+func (s *fakeSink) SaveSiteConfig(_ context.Context, sc *model.SiteConfig) error {
+	s.writes++
+	s.siteConfig = sc
+	return nil
+}
+func (s *fakeSink) RestoreUser(_ context.Context, u *state.UserBackup) (int64, error) {
+	s.users = append(s.users, u)
+	return s.id()
+}
+func (s *fakeSink) CreateStructure(context.Context, *model.Structure) (int64, error) { return s.id() }
+func (s *fakeSink) CreateFooterPlugSet(context.Context, string, []model.FooterPlug) (int64, error) {
+	return s.id()
+}
+func (s *fakeSink) CreateLayout(context.Context, *model.Layout) (int64, error) { return s.id() }
+func (s *fakeSink) CreateLeague(context.Context, *model.League) (int64, error) { return s.id() }
+func (s *fakeSink) CreateSlide(_ context.Context, sl *model.Slide) (int64, error) {
+	s.slides = append(s.slides, sl)
+	return s.id()
+}
+func (s *fakeSink) SaveSlideImage(_ context.Context, id int64, contentType string, data []byte) error {
+	s.images[id] = contentType
+	return nil
+}
+func (s *fakeSink) CreateSlideSet(_ context.Context, ss *model.SlideSet) (int64, error) {
+	s.slideSets = append(s.slideSets, ss)
+	return s.id()
+}
+func (s *fakeSink) CreateTournamentTemplate(_ context.Context, tt *model.TournamentTemplate) (int64, error) {
+	s.templates = append(s.templates, tt)
+	return s.id()
+}
+func (s *fakeSink) RestoreTournament(_ context.Context, t *model.Tournament) (int64, error) {
+	s.tournaments = append(s.tournaments, t)
+	return s.id()
+}
+func (s *fakeSink) RegisterDisplay(_ context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error) {
+	id, err := s.id()
+	return &model.Display{DisplayID: id, DeviceID: deviceID}, err
+}
+func (s *fakeSink) SaveDisplay(_ context.Context, d *model.Display) error {
+	s.displays = append(s.displays, d)
+	return nil
+}
+func (s *fakeSink) CreateAnnouncement(_ context.Context, a *model.Announcement) (int64, error) {
+	s.announcements = append(s.announcements, a)
+	return s.id()
+}
+
+func TestRestore(t *testing.T) {
+	sink := newFakeSink()
+	a := sampleArchive()
+	report, err := Restore(context.Background(), a, sink, false)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+
+	// IDs are handed out in restore order: user 100, structure 101,
+	// footer plug set 102, layout 103, league 104, slides 105 and 106,
+	// slide set 107, template 108, tournament 109, display 110.
+	if got := sink.slides[1].LeagueID; got != 104 {
+		t.Errorf("league slide's league = %d, want 104", got)
+	}
+	if sink.images[105] != "image/png" {
+		t.Errorf("images = %v", sink.images)
+	}
+	if got := sink.slideSets[0].Entries; !slices.Equal(got, []model.SlideSetEntry{{SlideID: 106}, {SlideID: 105}}) {
+		t.Errorf("slide set entries = %+v", got)
+	}
+	if tt := sink.templates[0].Tournament; tt.LayoutID != 103 || tt.LeagueID != 104 {
+		t.Errorf("template tournament = %+v", tt)
+	}
+	tm := sink.tournaments[0]
+	if tm.EventID != 0 || tm.FooterPlugsID != 102 || tm.LayoutID != 0 || tm.SlideSetID != 107 ||
+		tm.LeagueID != 104 || tm.FromStructureID != 101 || tm.FromTemplateID != 108 {
+		t.Errorf("tournament = %+v", tm)
+	}
+	if !tm.State.IsClockRunning {
+		t.Error("tournament state wasn't kept")
+	}
+	if a.Tournaments[0].LayoutID != 66 {
+		t.Error("restore changed the archive")
+	}
+	if d := sink.displays[0]; d.TournamentID != 109 || d.Name != "Bar" {
+		t.Errorf("display = %+v", d)
+	}
+	if len(sink.announcements) != 2 || sink.announcements[0].TournamentID != 109 || sink.announcements[1].TournamentID != 0 {
+		t.Errorf("announcements = %+v", sink.announcements)
+	}
+	if sc := sink.siteConfig; sc.Name != "Home Game" || sc.DefaultSlideSetID != 107 || sc.CookieKeys[0].HashKey64 != "keep" {
+		t.Errorf("site config = %+v", sc)
+	}
+
+	if report.Created["tournaments"] != 1 || report.Created["announcements"] != 2 {
+		t.Errorf("created = %v", report.Created)
+	}
+	wantWarnings := []string{"slide set 7 refers to slide 99", "tournament 20 refers to layout 66", "announcement 41 refers to tournament 21"}
+	if len(report.Warnings) != len(wantWarnings) {
+		t.Fatalf("warnings = %q", report.Warnings)
+	}
+	for i, w := range wantWarnings {
+		if !strings.HasPrefix(report.Warnings[i], w) {
+			t.Errorf("warning %d = %q, want one starting %q", i, report.Warnings[i], w)
+		}
+	}
+}
+
+func TestRestoreDryRun(t *testing.T) {
+	sink := newFakeSink()
+	report, err := Restore(context.Background(), sampleArchive(), sink, true)
+	if err != nil {
+		t.Fatalf("Restore: %v", err)
+	}
+	if sink.writes != 0 {
+		t.Errorf("dry run wrote %d things", sink.writes)
+	}
+	if !report.DryRun || report.Created["slides"] != 2 || len(report.Warnings) != 3 {
+		t.Errorf("report = %+v", report)
+	}
+}
+
+func TestRestoreRefuses(t *testing.T) {
+	sink := newFakeSink()
+	sink.counts = map[string]int64{"users": 0, "tournaments": 3}
+	if _, err := Restore(context.Background(), sampleArchive(), sink, true); err == nil || !strings.Contains(err.Error(), "3 in tournaments") {
+		t.Errorf("non-empty database: got %v", err)
+	}
+
+	a := sampleArchive()
+	a.Users = append(a.Users, &state.UserBackup{UserIdentity: model.UserIdentity{ID: 9, Nick: "alice"}})
+	a.Layouts = append(a.Layouts, &model.Layout{LayoutID: 6})
+	_, err := Restore(context.Background(), a, newFakeSink(), true)
+	if err == nil || !strings.Contains(err.Error(), `nick "alice" is used twice`) || !strings.Contains(err.Error(), "layout 6 appears twice") {
+		t.Errorf("bad archive: got %v", err)
+	}
+}
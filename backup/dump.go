@@ -0,0 +1,115 @@
+package backup
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/ts4z/irata/state"
+)
+
+// Source is the storage a backup is taken from.
+type Source interface {
+	state.SiteStorageReader
+	state.AppStorage
+	state.TournamentStorage
+	state.LayoutStorage
+	state.LeagueStorage
+	state.SlideStorage
+	state.TournamentTemplateStorage
+	state.DisplayStorage
+	state.AnnouncementStorage
+	FetchUserBackups(ctx context.Context) ([]*state.UserBackup, error)
+}
+
+// Dump reads everything from src.
+func Dump(ctx context.Context, src Source, now time.Time) (*Archive, error) {
+	a := &Archive{Manifest: Manifest{CreatedAt: now}}
+
+	sc, err := src.FetchSiteConfig(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching site config: %w", err)
+	}
+	cpy := *sc
+	cpy.CookieKeys = nil
+	a.SiteConfig = &cpy
+
+	if a.Users, err = src.FetchUserBackups(ctx); err != nil {
+		return nil, fmt.Errorf("fetching users: %w", err)
+	}
+
+	slugs, err := src.FetchStructureSlugs(ctx, 0, math.MaxInt32)
+	if err != nil {
+		return nil, fmt.Errorf("fetching structures: %w", err)
+	}
+	for _, slug := range slugs {
+		st, err := src.FetchStructure(ctx, slug.ID)
+		if err != nil {
+			return nil, fmt.Errorf("fetching structure %d: %w", slug.ID, err)
+		}
+		a.Structures = append(a.Structures, st)
+	}
+
+	sets, err := src.ListFooterPlugSets(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching footer plug sets: %w", err)
+	}
+	for _, set := range sets {
+		plugs, err := src.FetchPlugs(ctx, set.FooterPlugsID)
+		if err != nil {
+			return nil, fmt.Errorf("fetching footer plug set %d: %w", set.FooterPlugsID, err)
+		}
+		a.FooterPlugSets = append(a.FooterPlugSets, plugs)
+	}
+
+	if a.Layouts, err = src.FetchLayouts(ctx); err != nil {
+		return nil, fmt.Errorf("fetching layouts: %w", err)
+	}
+	if a.Leagues, err = src.FetchLeagues(ctx); err != nil {
+		return nil, fmt.Errorf("fetching leagues: %w", err)
+	}
+
+	slides, err := src.FetchSlides(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("fetching slides: %w", err)
+	}
+	for _, sl := range slides {
+		s := &Slide{Slide: sl}
+		if sl.UploadedImage {
+			if s.ImageType, s.Image, err = src.FetchSlideImage(ctx, sl.SlideID); err != nil {
+				return nil, fmt.Errorf("fetching image for slide %d: %w", sl.SlideID, err)
+			}
+		}
+		a.Slides = append(a.Slides, s)
+	}
+
+	if a.SlideSets, err = src.FetchSlideSets(ctx); err != nil {
+		return nil, fmt.Errorf("fetching slide sets: %w", err)
+	}
+	if a.Templates, err = src.FetchTournamentTemplates(ctx); err != nil {
+		return nil, fmt.Errorf("fetching tournament templates: %w", err)
+	}
+
+	overview, err := src.FetchOverview(ctx, nil, 0, math.MaxInt32)
+	if err != nil {
+		return nil, fmt.Errorf("fetching tournaments: %w", err)
+	}
+	for _, slug := range overview.Slugs {
+		t, err := src.FetchTournament(ctx, slug.TournamentID)
+		if err != nil {
+			return nil, fmt.Errorf("fetching tournament %d: %w", slug.TournamentID, err)
+		}
+		t.Transients = nil
+		a.Tournaments = append(a.Tournaments, t)
+	}
+
+	if a.Displays, err = src.FetchDisplays(ctx); err != nil {
+		return nil, fmt.Errorf("fetching displays: %w", err)
+	}
+	if a.Announcements, err = src.FetchAnnouncements(ctx, time.Time{}); err != nil {
+		return nil, fmt.Errorf("fetching announcements: %w", err)
+	}
+
+	return a, nil
+}
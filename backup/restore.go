@@ -0,0 +1,406 @@
+package backup
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// Sink is the storage a backup is restored into.
+type Sink interface {
+	state.SiteStorage
+	CountRows(ctx context.Context) (map[string]int64, error)
+	RestoreUser(ctx context.Context, u *state.UserBackup) (int64, error)
+	CreateStructure(ctx context.Context, s *model.Structure) (int64, error)
+	CreateFooterPlugSet(ctx context.Context, name string, plugs []model.FooterPlug) (int64, error)
+	CreateLayout(ctx context.Context, l *model.Layout) (int64, error)
+	CreateLeague(ctx context.Context, l *model.League) (int64, error)
+	CreateSlide(ctx context.Context, s *model.Slide) (int64, error)
+	SaveSlideImage(ctx context.Context, id int64, contentType string, data []byte) error
+	CreateSlideSet(ctx context.Context, ss *model.SlideSet) (int64, error)
+	CreateTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) (int64, error)
+	RestoreTournament(ctx context.Context, t *model.Tournament) (int64, error)
+	RegisterDisplay(ctx context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error)
+	SaveDisplay(ctx context.Context, d *model.Display) error
+	CreateAnnouncement(ctx context.Context, a *model.Announcement) (int64, error)
+}
+
+// Report says what a restore did, or would have done.
+type Report struct {
+	DryRun  bool
+	Created map[string]int
+	// Warnings are things that were restored, but not quite as they were,
+	// like a reference to something that isn't in the archive.
+	Warnings []string
+}
+
+func (r *Report) warnf(format string, args ...any) {
+	r.Warnings = append(r.Warnings, fmt.Sprintf(format, args...))
+}
+
+// Check looks for problems that would stop an archive from being restored.
+func Check(a *Archive) error {
+	var errs []error
+	complain := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	nicks := map[string]bool{}
+	emails := map[string]bool{}
+	for _, u := range a.Users {
+		if u.Nick == "" {
+			complain("user %d has no nick", u.ID)
+		}
+		if nicks[u.Nick] {
+			complain("nick %q is used twice", u.Nick)
+		}
+		nicks[u.Nick] = true
+		for _, e := range u.EmailAddresses {
+			if emails[e] {
+				complain("email address %q is used twice", e)
+			}
+			emails[e] = true
+		}
+	}
+
+	errs = append(errs, duplicates("structure", a.Structures, func(s *model.Structure) int64 { return s.ID })...)
+	errs = append(errs, duplicates("footer plug set", a.FooterPlugSets, func(fp *model.FooterPlugs) int64 { return fp.FooterPlugsID })...)
+	errs = append(errs, duplicates("layout", a.Layouts, func(l *model.Layout) int64 { return l.LayoutID })...)
+	errs = append(errs, duplicates("league", a.Leagues, func(l *model.League) int64 { return l.LeagueID })...)
+	errs = append(errs, duplicates("slide", a.Slides, func(s *Slide) int64 { return s.Slide.SlideID })...)
+	errs = append(errs, duplicates("slide set", a.SlideSets, func(ss *model.SlideSet) int64 { return ss.SlideSetID })...)
+	errs = append(errs, duplicates("template", a.Templates, func(tt *model.TournamentTemplate) int64 { return tt.TournamentTemplateID })...)
+	errs = append(errs, duplicates("tournament", a.Tournaments, func(t *model.Tournament) int64 { return t.EventID })...)
+
+	devices := map[string]bool{}
+	for _, d := range a.Displays {
+		if d.DeviceID == "" || devices[d.DeviceID] {
+			complain("display %d has a missing or repeated device ID", d.DisplayID)
+		}
+		devices[d.DeviceID] = true
+	}
+
+	for _, t := range a.Tournaments {
+		if t.State == nil || len(t.Structure.Levels) == 0 {
+			complain("tournament %d has no state or no levels", t.EventID)
+		}
+	}
+	for _, tt := range a.Templates {
+		if tt.Tournament == nil {
+			complain("template %d has no tournament", tt.TournamentTemplateID)
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+func duplicates[T any](kind string, items []T, id func(T) int64) []error {
+	var errs []error
+	seen := map[int64]bool{}
+	for _, item := range items {
+		if n := id(item); seen[n] {
+			errs = append(errs, fmt.Errorf("%s %d appears twice", kind, n))
+		} else {
+			seen[n] = true
+		}
+	}
+	return errs
+}
+
+// restorer keeps the map from each kind's old IDs to its new ones.
+type restorer struct {
+	report *Report
+
+	structures, footerPlugSets, layouts, leagues map[int64]int64
+	slides, slideSets, templates, tournaments    map[int64]int64
+}
+
+// ref maps an old ID to a new one.  A reference to something that isn't in
+// the archive is dropped.
+func (r *restorer) ref(m map[int64]int64, kind, owner string, id int64) int64 {
+	if id == 0 {
+		return 0
+	}
+	if n, ok := m[id]; ok {
+		return n
+	}
+	r.report.warnf("%s refers to %s %d, which isn't in the backup; dropped", owner, kind, id)
+	return 0
+}
+
+// tournamentRefs maps the IDs in a tournament, or a template's tournament.
+// Where it came from isn't worth a warning if it's gone.
+func (r *restorer) tournamentRefs(t *model.Tournament, owner string) {
+	t.FooterPlugsID = r.ref(r.footerPlugSets, "footer plug set", owner, t.FooterPlugsID)
+	t.LayoutID = r.ref(r.layouts, "layout", owner, t.LayoutID)
+	t.SlideSetID = r.ref(r.slideSets, "slide set", owner, t.SlideSetID)
+	t.LeagueID = r.ref(r.leagues, "league", owner, t.LeagueID)
+	t.FromStructureID = r.structures[t.FromStructureID]
+	t.FromTemplateID = r.templates[t.FromTemplateID]
+}
+
+// Restore puts the archive into sink, which must be empty.  Everything gets
+// a new ID, and references are rewritten to match.  With dryRun, the
+// archive and the database are checked, but nothing is written.
+//
+// Restore doesn't happen in one transaction; if it fails partway, start
+// over with an empty database.
+func Restore(ctx context.Context, a *Archive, sink Sink, dryRun bool) (*Report, error) {
+	if err := Check(a); err != nil {
+		return nil, fmt.Errorf("backup can't be restored:\n%w", err)
+	}
+
+	counts, err := sink.CountRows(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var full []string
+	for _, table := range slices.Sorted(maps.Keys(counts)) {
+		if counts[table] > 0 {
+			full = append(full, fmt.Sprintf("%d in %s", counts[table], table))
+		}
+	}
+	if len(full) > 0 {
+		return nil, fmt.Errorf("database isn't empty (%s); restore needs a fresh one", strings.Join(full, ", "))
+	}
+
+	if dryRun {
+		sink = &dryRunSink{Sink: sink}
+	}
+	report := &Report{DryRun: dryRun, Created: map[string]int{}}
+	r := &restorer{
+		report:         report,
+		structures:     map[int64]int64{},
+		footerPlugSets: map[int64]int64{},
+		layouts:        map[int64]int64{},
+		leagues:        map[int64]int64{},
+		slides:         map[int64]int64{},
+		slideSets:      map[int64]int64{},
+		templates:      map[int64]int64{},
+		tournaments:    map[int64]int64{},
+	}
+
+	for _, u := range a.Users {
+		if _, err := sink.RestoreUser(ctx, u); err != nil {
+			return report, fmt.Errorf("restoring user %q: %w", u.Nick, err)
+		}
+		report.Created["users"]++
+	}
+
+	for _, st := range a.Structures {
+		old := st.ID
+		cpy := *st
+		cpy.ID, cpy.Version = 0, 0
+		id, err := sink.CreateStructure(ctx, &cpy)
+		if err != nil {
+			return report, fmt.Errorf("restoring structure %d: %w", old, err)
+		}
+		r.structures[old] = id
+		report.Created["structures"]++
+	}
+
+	for _, fp := range a.FooterPlugSets {
+		id, err := sink.CreateFooterPlugSet(ctx, fp.Name, fp.Plugs)
+		if err != nil {
+			return report, fmt.Errorf("restoring footer plug set %d: %w", fp.FooterPlugsID, err)
+		}
+		r.footerPlugSets[fp.FooterPlugsID] = id
+		report.Created["footer plug sets"]++
+	}
+
+	for _, l := range a.Layouts {
+		cpy := *l
+		cpy.LayoutID, cpy.Version = 0, 0
+		id, err := sink.CreateLayout(ctx, &cpy)
+		if err != nil {
+			return report, fmt.Errorf("restoring layout %d: %w", l.LayoutID, err)
+		}
+		r.layouts[l.LayoutID] = id
+		report.Created["layouts"]++
+	}
+
+	for _, l := range a.Leagues {
+		cpy := l.Clone()
+		cpy.LeagueID, cpy.Version = 0, 0
+		id, err := sink.CreateLeague(ctx, cpy)
+		if err != nil {
+			return report, fmt.Errorf("restoring league %d: %w", l.LeagueID, err)
+		}
+		r.leagues[l.LeagueID] = id
+		report.Created["leagues"]++
+	}
+
+	for _, s := range a.Slides {
+		old := s.Slide.SlideID
+		cpy := *s.Slide
+		cpy.SlideID, cpy.Version = 0, 0
+		cpy.LeagueID = r.ref(r.leagues, "league", fmt.Sprintf("slide %d", old), cpy.LeagueID)
+		if cpy.UploadedImage && s.ImageType == "" {
+			report.warnf("slide %d had an uploaded image that isn't in the backup; dropped", old)
+			cpy.UploadedImage = false
+		}
+		id, err := sink.CreateSlide(ctx, &cpy)
+		if err != nil {
+			return report, fmt.Errorf("restoring slide %d: %w", old, err)
+		}
+		if cpy.UploadedImage {
+			if err := sink.SaveSlideImage(ctx, id, s.ImageType, s.Image); err != nil {
+				return report, fmt.Errorf("restoring image for slide %d: %w", old, err)
+			}
+		}
+		r.slides[old] = id
+		report.Created["slides"]++
+	}
+
+	for _, ss := range a.SlideSets {
+		cpy := ss.Clone()
+		cpy.SlideSetID, cpy.Version = 0, 0
+		cpy.Entries = nil
+		for _, e := range ss.Entries {
+			if e.SlideID = r.ref(r.slides, "slide", fmt.Sprintf("slide set %d", ss.SlideSetID), e.SlideID); e.SlideID != 0 {
+				cpy.Entries = append(cpy.Entries, e)
+			}
+		}
+		id, err := sink.CreateSlideSet(ctx, cpy)
+		if err != nil {
+			return report, fmt.Errorf("restoring slide set %d: %w", ss.SlideSetID, err)
+		}
+		r.slideSets[ss.SlideSetID] = id
+		report.Created["slide sets"]++
+	}
+
+	for _, tt := range a.Templates {
+		cpy := *tt
+		cpy.TournamentTemplateID, cpy.Version = 0, 0
+		cpy.Tournament = tt.Tournament.Clone()
+		r.tournamentRefs(cpy.Tournament, fmt.Sprintf("template %d", tt.TournamentTemplateID))
+		id, err := sink.CreateTournamentTemplate(ctx, &cpy)
+		if err != nil {
+			return report, fmt.Errorf("restoring template %d: %w", tt.TournamentTemplateID, err)
+		}
+		r.templates[tt.TournamentTemplateID] = id
+		report.Created["templates"]++
+	}
+
+	for _, t := range a.Tournaments {
+		cpy := t.Clone()
+		cpy.EventID, cpy.Version = 0, 0
+		r.tournamentRefs(cpy, fmt.Sprintf("tournament %d", t.EventID))
+		id, err := sink.RestoreTournament(ctx, cpy)
+		if err != nil {
+			return report, fmt.Errorf("restoring tournament %d: %w", t.EventID, err)
+		}
+		r.tournaments[t.EventID] = id
+		report.Created["tournaments"]++
+	}
+
+	for _, d := range a.Displays {
+		owner := fmt.Sprintf("display %d", d.DisplayID)
+		nd, err := sink.RegisterDisplay(ctx, d.DeviceID, d.RemoteAddr, d.UserAgent)
+		if err != nil {
+			return report, fmt.Errorf("restoring %s: %w", owner, err)
+		}
+		nd.Name = d.Name
+		nd.Mode = d.Mode
+		nd.TournamentID = r.ref(r.tournaments, "tournament", owner, d.TournamentID)
+		nd.LayoutID = r.ref(r.layouts, "layout", owner, d.LayoutID)
+		if err := sink.SaveDisplay(ctx, nd); err != nil {
+			return report, fmt.Errorf("restoring %s: %w", owner, err)
+		}
+		report.Created["displays"]++
+	}
+
+	for _, an := range a.Announcements {
+		cpy := an.Clone()
+		cpy.AnnouncementID, cpy.Version = 0, 0
+		if an.TournamentID != 0 {
+			// An announcement for no tournament goes to every display, so
+			// one whose tournament is gone is left out instead.
+			if cpy.TournamentID = r.ref(r.tournaments, "tournament", fmt.Sprintf("announcement %d", an.AnnouncementID), an.TournamentID); cpy.TournamentID == 0 {
+				continue
+			}
+		}
+		if _, err := sink.CreateAnnouncement(ctx, cpy); err != nil {
+			return report, fmt.Errorf("restoring announcement %d: %w", an.AnnouncementID, err)
+		}
+		report.Created["announcements"]++
+	}
+
+	if a.SiteConfig != nil {
+		sc, err := sink.FetchSiteConfig(ctx)
+		if err != nil {
+			return report, fmt.Errorf("fetching site config: %w", err)
+		}
+		// The new site keeps whatever cookie keys it has; the archive
+		// doesn't have any.
+		cpy := *a.SiteConfig
+		cpy.CookieKeys = sc.CookieKeys
+		cpy.DefaultSlideSetID = r.ref(r.slideSets, "slide set", "site config", cpy.DefaultSlideSetID)
+		if err := sink.SaveSiteConfig(ctx, &cpy); err != nil {
+			return report, fmt.Errorf("restoring site config: %w", err)
+		}
+	}
+
+	return report, nil
+}
+
+// dryRunSink reads from the real storage but writes nothing, making up IDs
+// for the things it would have created.
+type dryRunSink struct {
+	Sink
+	lastID int64
+}
+
+func (s *dryRunSink) nextID() (int64, error) {
+	s.lastID++
+	return s.lastID, nil
+}
+
+func (s *dryRunSink) SaveSiteConfig(context.Context, *model.SiteConfig) error { return nil }
+func (s *dryRunSink) RestoreUser(context.Context, *state.UserBackup) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) CreateStructure(context.Context, *model.Structure) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) CreateFooterPlugSet(context.Context, string, []model.FooterPlug) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) CreateLayout(context.Context, *model.Layout) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) CreateLeague(context.Context, *model.League) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) CreateSlide(context.Context, *model.Slide) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) SaveSlideImage(context.Context, int64, string, []byte) error { return nil }
+func (s *dryRunSink) CreateSlideSet(context.Context, *model.SlideSet) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) CreateTournamentTemplate(context.Context, *model.TournamentTemplate) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) RestoreTournament(context.Context, *model.Tournament) (int64, error) {
+	return s.nextID()
+}
+func (s *dryRunSink) RegisterDisplay(_ context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error) {
+	id, err := s.nextID()
+	return &model.Display{DisplayID: id, DeviceID: deviceID, RemoteAddr: remoteAddr, UserAgent: userAgent}, err
+}
+func (s *dryRunSink) SaveDisplay(context.Context, *model.Display) error { return nil }
+func (s *dryRunSink) CreateAnnouncement(context.Context, *model.Announcement) (int64, error) {
+	return s.nextID()
+}
+
+var (
+	_ Source = &state.DBStorage{}
+	_ Sink   = &state.DBStorage{}
+)
@@ -0,0 +1,183 @@
+// Package certs gets iratad its TLS certificates, either from files (say,
+// from certbot) or from an ACME CA like Let's Encrypt.  ACME certificates
+// are kept in the database, so every instance shares them and a restart
+// doesn't ask for new ones.
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/acme"
+	"golang.org/x/crypto/acme/autocert"
+)
+
+// Config says where certificates come from.  Set CertFile and KeyFile, or
+// Domains, not both.
+type Config struct {
+	CertFile string
+	KeyFile  string
+
+	// Domains are the names to get ACME certificates for.  Nothing else
+	// gets one.
+	Domains []string
+	// Email is given to the CA, which may use it to warn of expiry.
+	Email string
+	// DirectoryURL is the CA's ACME directory.  Empty means Let's Encrypt.
+	DirectoryURL string
+	// CAFile is a PEM bundle to trust for talking to the CA, for a private
+	// or test CA.  Empty means the system roots.
+	CAFile string
+	// Cache keeps certificates and the account key.  Required for ACME.
+	Cache autocert.Cache
+}
+
+// Enabled says whether c asks for TLS at all.
+func (c *Config) Enabled() bool {
+	return c.CertFile != "" || len(c.Domains) > 0
+}
+
+// Certs hands out certificates for a TLS listener.
+type Certs struct {
+	tlsConfig *tls.Config
+	manager   *autocert.Manager // nil unless ACME
+}
+
+// New checks c and gets ready to serve certificates.  File certificates are
+// loaded now, so a bad one is found at startup; ACME certificates are
+// fetched on the first connection that wants one.
+func New(c *Config) (*Certs, error) {
+	switch {
+	case c.CertFile != "" && len(c.Domains) > 0:
+		return nil, errors.New("certificate files and ACME domains are both configured; pick one")
+	case c.CertFile != "":
+		if c.KeyFile == "" {
+			return nil, errors.New("certificate file without a key file")
+		}
+		fc := &fileCert{certFile: c.CertFile, keyFile: c.KeyFile}
+		if _, err := fc.get(); err != nil {
+			return nil, err
+		}
+		return &Certs{tlsConfig: &tls.Config{
+			GetCertificate: func(*tls.ClientHelloInfo) (*tls.Certificate, error) { return fc.get() },
+		}}, nil
+	case len(c.Domains) > 0:
+		if c.Cache == nil {
+			return nil, errors.New("ACME needs a certificate cache")
+		}
+		client := &acme.Client{DirectoryURL: c.DirectoryURL}
+		if c.CAFile != "" {
+			pem, err := os.ReadFile(c.CAFile)
+			if err != nil {
+				return nil, fmt.Errorf("reading CA file: %w", err)
+			}
+			roots := x509.NewCertPool()
+			if !roots.AppendCertsFromPEM(pem) {
+				return nil, fmt.Errorf("no certificates in CA file %s", c.CAFile)
+			}
+			client.HTTPClient = &http.Client{Transport: &http.Transport{
+				TLSClientConfig: &tls.Config{RootCAs: roots},
+			}}
+		}
+		m := &autocert.Manager{
+			Prompt:     autocert.AcceptTOS,
+			Cache:      c.Cache,
+			HostPolicy: autocert.HostWhitelist(c.Domains...),
+			Email:      c.Email,
+			Client:     client,
+		}
+		return &Certs{tlsConfig: m.TLSConfig(), manager: m}, nil
+	default:
+		return nil, errors.New("no certificates configured")
+	}
+}
+
+// TLSConfig is for the HTTPS listeners.  Each call returns a new copy.
+func (c *Certs) TLSConfig() *tls.Config {
+	return c.tlsConfig.Clone()
+}
+
+// HTTPHandler is for the plain HTTP listener.  It answers the CA's
+// challenges, and passes everything else to fallback, which is usually
+// Redirect.
+func (c *Certs) HTTPHandler(fallback http.Handler) http.Handler {
+	if c.manager == nil {
+		return fallback
+	}
+	return c.manager.HTTPHandler(fallback)
+}
+
+// Redirect sends requests to the same place over HTTPS, on httpsAddr's port.
+func Redirect(httpsAddr string) http.Handler {
+	_, port, _ := net.SplitHostPort(httpsAddr)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		host := r.Host
+		if h, _, err := net.SplitHostPort(host); err == nil {
+			host = h
+		}
+		if port != "" && port != "443" {
+			host = net.JoinHostPort(host, port)
+		}
+		u := *r.URL
+		u.Scheme = "https"
+		u.Host = host
+		http.Redirect(w, r, u.String(), http.StatusMovedPermanently)
+	})
+}
+
+// fileCertCheckInterval is how often fileCert looks for a renewed
+// certificate.
+const fileCertCheckInterval = time.Minute
+
+// fileCert reloads the certificate when its file changes, so a renewal
+// doesn't need a restart.
+type fileCert struct {
+	certFile, keyFile string
+
+	mu        sync.Mutex
+	cert      *tls.Certificate
+	modTime   time.Time
+	checkedAt time.Time
+}
+
+func (fc *fileCert) get() (*tls.Certificate, error) {
+	fc.mu.Lock()
+	defer fc.mu.Unlock()
+	if fc.cert != nil && time.Since(fc.checkedAt) < fileCertCheckInterval {
+		return fc.cert, nil
+	}
+	fc.checkedAt = time.Now()
+	info, err := os.Stat(fc.certFile)
+	if err != nil {
+		if fc.cert != nil {
+			log.Printf("can't check certificate file, keeping the old one: %v", err)
+			return fc.cert, nil
+		}
+		return nil, err
+	}
+	if fc.cert != nil && info.ModTime().Equal(fc.modTime) {
+		return fc.cert, nil
+	}
+	cert, err := tls.LoadX509KeyPair(fc.certFile, fc.keyFile)
+	if err != nil {
+		if fc.cert != nil {
+			log.Printf("can't load new certificate, keeping the old one: %v", err)
+			return fc.cert, nil
+		}
+		return nil, fmt.Errorf("loading certificate: %w", err)
+	}
+	if fc.cert != nil {
+		log.Printf("loaded new certificate from %s", fc.certFile)
+	}
+	fc.cert = &cert
+	fc.modTime = info.ModTime()
+	return fc.cert, nil
+}
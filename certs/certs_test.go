@@ -0,0 +1,198 @@
+package certs
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/ts4z/irata/state"
+)
+
+func TestRedirect(t *testing.T) {
+	for _, tc := range []struct {
+		httpsAddr, host, path, want string
+	}{
+		{":443", "clock.example.com", "/t/1?x=y", "https://clock.example.com/t/1?x=y"},
+		{":443", "clock.example.com:80", "/", "https://clock.example.com/"},
+		{":8443", "localhost:8080", "/lobby", "https://localhost:8443/lobby"},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "http://"+tc.host+tc.path, nil)
+		w := httptest.NewRecorder()
+		Redirect(tc.httpsAddr).ServeHTTP(w, r)
+		if got := w.Header().Get("Location"); w.Code != http.StatusMovedPermanently || got != tc.want {
+			t.Errorf("%s %s%s: %d %q, want %q", tc.httpsAddr, tc.host, tc.path, w.Code, got, tc.want)
+		}
+	}
+}
+
+func TestNewChecksConfig(t *testing.T) {
+	for name, c := range map[string]*Config{
+		"nothing":       {},
+		"both":          {CertFile: "a", KeyFile: "b", Domains: []string{"x"}, Cache: autocert.DirCache(t.TempDir())},
+		"no key":        {CertFile: "a"},
+		"missing files": {CertFile: "/nonexistent.pem", KeyFile: "/nonexistent.key"},
+		"no cache":      {Domains: []string{"x"}},
+	} {
+		if _, err := New(c); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+// writeSelfSigned writes a certificate for name to dir, returning the
+// certificate and key file names.
+func writeSelfSigned(t *testing.T, dir, name string, serial int64) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.CreateCertificate(rand.Reader, &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+	}, &x509.Certificate{SerialNumber: big.NewInt(serial), Subject: pkix.Name{CommonName: name}}, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	certFile := filepath.Join(dir, "cert.pem")
+	keyFile := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return certFile, keyFile
+}
+
+func TestFileCert(t *testing.T) {
+	dir := t.TempDir()
+	certFile, keyFile := writeSelfSigned(t, dir, "clock.example.com", 1)
+	c, err := New(&Config{CertFile: certFile, KeyFile: keyFile})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	if c.HTTPHandler(nil) != nil {
+		t.Error("file certificates don't need challenges answered")
+	}
+
+	cert, err := c.TLSConfig().GetCertificate(&tls.ClientHelloInfo{ServerName: "clock.example.com"})
+	if err != nil || cert.Leaf.SerialNumber.Int64() != 1 {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+
+	// A renewed certificate is picked up on the next check, and a bad one
+	// is not.
+	fc := &fileCert{certFile: certFile, keyFile: keyFile}
+	if _, err := fc.get(); err != nil {
+		t.Fatal(err)
+	}
+	writeSelfSigned(t, dir, "clock.example.com", 2)
+	later := time.Now().Add(time.Hour)
+	os.Chtimes(certFile, later, later)
+	fc.checkedAt = time.Time{}
+	if cert, err := fc.get(); err != nil || cert.Leaf.SerialNumber.Int64() != 2 {
+		t.Errorf("didn't load the renewed certificate: %v", err)
+	}
+	os.WriteFile(certFile, []byte("garbage"), 0600)
+	os.Chtimes(certFile, later.Add(time.Hour), later.Add(time.Hour))
+	fc.checkedAt = time.Time{}
+	if cert, err := fc.get(); err != nil || cert.Leaf.SerialNumber.Int64() != 2 {
+		t.Errorf("a bad renewal replaced a good certificate: %v", err)
+	}
+}
+
+type mapCertCache map[string][]byte
+
+func (m mapCertCache) FetchCertCache(_ context.Context, key string) ([]byte, error) {
+	if data, ok := m[key]; ok {
+		return data, nil
+	}
+	return nil, state.ErrNotCached
+}
+
+func (m mapCertCache) SaveCertCache(_ context.Context, key string, data []byte) error {
+	m[key] = data
+	return nil
+}
+
+func (m mapCertCache) DeleteCertCache(_ context.Context, key string) error {
+	delete(m, key)
+	return nil
+}
+
+func TestDBCache(t *testing.T) {
+	ctx := context.Background()
+	c := NewDBCache(mapCertCache{})
+	if _, err := c.Get(ctx, "k"); err != autocert.ErrCacheMiss {
+		t.Errorf("Get missing key: %v", err)
+	}
+	if err := c.Put(ctx, "k", []byte("v")); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := c.Get(ctx, "k"); err != nil || string(got) != "v" {
+		t.Errorf("Get = %q, %v", got, err)
+	}
+	if err := c.Delete(ctx, "k"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := c.Get(ctx, "k"); err != autocert.ErrCacheMiss {
+		t.Errorf("Get deleted key: %v", err)
+	}
+}
+
+// TestACME gets a certificate from a local ACME CA.  Run pebble with
+// PEBBLE_VA_ALWAYS_VALID=1, then set IRATA_TEST_ACME_URL to its directory
+// (https://localhost:14000/dir) and IRATA_TEST_ACME_CA to the certificate
+// it serves with.
+func TestACME(t *testing.T) {
+	url := os.Getenv("IRATA_TEST_ACME_URL")
+	if url == "" {
+		t.Skip("IRATA_TEST_ACME_URL not set")
+	}
+	cache := mapCertCache{}
+	c, err := New(&Config{
+		Domains:      []string{"irata.test"},
+		DirectoryURL: url,
+		CAFile:       os.Getenv("IRATA_TEST_ACME_CA"),
+		Cache:        NewDBCache(cache),
+	})
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	cert, err := c.TLSConfig().GetCertificate(&tls.ClientHelloInfo{ServerName: "irata.test"})
+	if err != nil {
+		t.Fatalf("GetCertificate: %v", err)
+	}
+	if cert.Leaf.VerifyHostname("irata.test") != nil {
+		t.Errorf("got a certificate for %v", cert.Leaf.DNSNames)
+	}
+	// The key depends on the kind of certificate.
+	if cache["irata.test"] == nil && cache["irata.test+rsa"] == nil {
+		t.Errorf("certificate wasn't cached; cache has %d entries", len(cache))
+	}
+	if _, err := c.TLSConfig().GetCertificate(&tls.ClientHelloInfo{ServerName: "evil.test"}); err == nil {
+		t.Error("got a certificate for a domain not configured")
+	}
+}
@@ -0,0 +1,37 @@
+package certs
+
+import (
+	"context"
+	"errors"
+
+	"golang.org/x/crypto/acme/autocert"
+
+	"github.com/ts4z/irata/state"
+)
+
+// DBCache keeps autocert's certificates in the database.
+type DBCache struct {
+	storage state.CertCacheStorage
+}
+
+var _ autocert.Cache = (*DBCache)(nil)
+
+func NewDBCache(storage state.CertCacheStorage) *DBCache {
+	return &DBCache{storage: storage}
+}
+
+func (c *DBCache) Get(ctx context.Context, key string) ([]byte, error) {
+	data, err := c.storage.FetchCertCache(ctx, key)
+	if errors.Is(err, state.ErrNotCached) {
+		return nil, autocert.ErrCacheMiss
+	}
+	return data, err
+}
+
+func (c *DBCache) Put(ctx context.Context, key string, data []byte) error {
+	return c.storage.SaveCertCache(ctx, key, data)
+}
+
+func (c *DBCache) Delete(ctx context.Context, key string) error {
+	return c.storage.DeleteCertCache(ctx, key)
+}
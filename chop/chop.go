@@ -1,13 +1,52 @@
 // Package chop defines the interface for chop algorithms.
 package chop
 
+import (
+	"math"
+	"sort"
+)
+
 // A Chopper computes a division of prizes among players based on chip counts.
 type Chopper interface {
 	// Chop takes chip counts and prize amounts (both indexed by position),
 	// and returns the chopped payout for each player. The sum of the returned
-	// values should equal the sum of prizes.
+	// values should equal the sum of prizes, less anything the algorithm
+	// deliberately leaves in play (see package save).
 	Chop(chips []int, prizes []int) ([]int, error)
 
 	// MaxPlayers returns the maximum number of players this algorithm supports.
 	MaxPlayers() int
 }
+
+// Round rounds equities down to whole integer amounts, then hands out the
+// remaining units (up to total) to the players with the largest fractional
+// parts.  Equity-based algorithms use this so the chop adds up.
+func Round(equities []float64, total int) []int {
+	n := len(equities)
+	result := make([]int, n)
+	allocated := 0
+
+	type indexedFrac struct {
+		index int
+		frac  float64
+	}
+	fracs := make([]indexedFrac, n)
+
+	for i, eq := range equities {
+		floored := int(math.Floor(eq))
+		result[i] = floored
+		allocated += floored
+		fracs[i] = indexedFrac{i, eq - float64(floored)}
+	}
+
+	// Distribute remaining units to players with largest fractional parts.
+	leftover := total - allocated
+	sort.Slice(fracs, func(a, b int) bool {
+		return fracs[a].frac > fracs[b].frac
+	})
+	for i := 0; i < leftover && i < n; i++ {
+		result[fracs[i].index]++
+	}
+
+	return result
+}
@@ -0,0 +1,86 @@
+// Package floor implements "floor" chops, where every player locks up a
+// guaranteed amount before the rest of the money is divided.
+//
+// By default the floor is the next payout: the money the next player out
+// would get.  Nobody at the table can do worse than that anyway, so it's the
+// natural amount to lock.  An operator can also agree on a smaller custom
+// floor.
+package floor
+
+import (
+	"fmt"
+
+	"github.com/ts4z/irata/chop"
+)
+
+// Chopper pays everyone the floor, then chops the rest of the prizes with
+// Base.  If Amount is zero, the floor is the next payout.
+type Chopper struct {
+	Base   chop.Chopper
+	Amount int
+}
+
+func (c *Chopper) Name() string {
+	if n, ok := c.Base.(interface{ Name() string }); ok {
+		return n.Name() + " with floor"
+	}
+	return "floor"
+}
+
+func (c *Chopper) Chop(chips []int, prizes []int) ([]int, error) {
+	return Chop(c.Base, c.Amount, chips, prizes)
+}
+
+func (c *Chopper) MaxPlayers() int {
+	return c.Base.MaxPlayers()
+}
+
+// NextPayout returns the prize for the next player to bust, or zero if the
+// next player out doesn't get paid.
+func NextPayout(numPlayers int, prizes []int) int {
+	if numPlayers < 1 || numPlayers > len(prizes) {
+		return 0
+	}
+	return prizes[numPlayers-1]
+}
+
+// Chop pays each player amount (or the next payout, if amount is zero) and
+// chops the remaining prizes using base.
+//
+// The floor is subtracted from each paid position, so it can't exceed the
+// smallest prize still in play.  There can't be more prizes than players;
+// nobody would be left to take the extra money.
+func Chop(base chop.Chopper, amount int, chips []int, prizes []int) ([]int, error) {
+	n := len(chips)
+	if n == 0 {
+		return nil, nil
+	}
+	if amount < 0 {
+		return nil, fmt.Errorf("floor: negative floor (%d)", amount)
+	}
+	if len(prizes) > n {
+		return nil, fmt.Errorf("floor: more positions paid (%d) than players (%d)", len(prizes), n)
+	}
+
+	next := NextPayout(n, prizes)
+	if amount == 0 {
+		amount = next
+	}
+	if amount > next {
+		return nil, fmt.Errorf("floor: floor of %d exceeds the next payout (%d)", amount, next)
+	}
+
+	remaining := make([]int, len(prizes))
+	for i := range remaining {
+		remaining[i] = prizes[i] - amount
+	}
+
+	chopped, err := base.Chop(chips, remaining)
+	if err != nil {
+		return nil, err
+	}
+	for i := range chopped {
+		chopped[i] += amount
+	}
+	return chopped, nil
+}
@@ -0,0 +1,115 @@
+package floor
+
+import (
+	"testing"
+
+	"github.com/ts4z/irata/chop/icm"
+	"github.com/ts4z/irata/chop/mw"
+)
+
+func TestNextPayout(t *testing.T) {
+	prizes := []int{50, 30, 20}
+	if got := NextPayout(3, prizes); got != 20 {
+		t.Errorf("NextPayout(3) = %d, want 20", got)
+	}
+	if got := NextPayout(2, prizes); got != 30 {
+		t.Errorf("NextPayout(2) = %d, want 30", got)
+	}
+	// Bubble: the next player out gets nothing.
+	if got := NextPayout(4, prizes); got != 0 {
+		t.Errorf("NextPayout(4) = %d, want 0", got)
+	}
+}
+
+func TestDefaultFloorLocksNextPayout(t *testing.T) {
+	chips := []int{8000, 1000, 1000}
+	prizes := []int{100, 50, 20}
+
+	chopped, err := Chop(&mw.Chopper{}, 0, chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, c := range chopped {
+		if c < 20 {
+			t.Errorf("chopped[%d] = %d, below the floor of 20", i, c)
+		}
+	}
+	assertSumEquals(t, chopped, 170)
+}
+
+func TestCustomFloor(t *testing.T) {
+	chips := []int{1000, 1000, 1000}
+	prizes := []int{500, 300, 200}
+
+	chopped, err := Chop(&icm.Chopper{}, 150, chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 150 each locked, 550 left over, equal chips → 150 + 183.33
+	for i, c := range chopped {
+		if c != 333 && c != 334 {
+			t.Errorf("chopped[%d] = %d, expected 333 or 334", i, c)
+		}
+	}
+	assertSumEquals(t, chopped, 1000)
+}
+
+func TestFloorAboveNextPayout(t *testing.T) {
+	if _, err := Chop(&icm.Chopper{}, 201, []int{1000, 1000, 1000}, []int{500, 300, 200}); err == nil {
+		t.Error("expected error for floor above the next payout")
+	}
+}
+
+func TestMorePrizesThanPlayers(t *testing.T) {
+	chips := []int{1000, 1000}
+	prizes := []int{50, 30, 20}
+
+	// Third place money has nobody to go to; dropping it would lose 20.
+	if _, err := Chop(&icm.Chopper{}, 0, chips, prizes); err == nil {
+		t.Error("expected error for more prizes than players")
+	}
+}
+
+func TestNoPlayers(t *testing.T) {
+	chopped, err := Chop(&icm.Chopper{}, 0, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if chopped != nil {
+		t.Errorf("expected nil for no players, got %v", chopped)
+	}
+}
+
+func TestChopperInterface(t *testing.T) {
+	c := &Chopper{Base: &mw.Chopper{}}
+	if got := c.Name(); got != "Malmuth-Weitzman with floor" {
+		t.Errorf("Name() = %q, want %q", got, "Malmuth-Weitzman with floor")
+	}
+
+	chopped, err := c.Chop([]int{3000, 1000}, []int{60, 40})
+	if err != nil {
+		t.Fatal(err)
+	}
+	assertChop(t, chopped, 0, 55)
+	assertChop(t, chopped, 1, 45)
+}
+
+func assertChop(t *testing.T, chopped []int, player int, expected int) {
+	t.Helper()
+	if chopped[player] != expected {
+		t.Errorf("player %d: expected %d, got %d", player, expected, chopped[player])
+	}
+}
+
+func assertSumEquals(t *testing.T, chopped []int, expected int) {
+	t.Helper()
+	sum := 0
+	for _, c := range chopped {
+		sum += c
+	}
+	if sum != expected {
+		t.Errorf("sum of chopped amounts %d != %d", sum, expected)
+	}
+}
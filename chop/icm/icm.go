@@ -10,9 +10,9 @@ package icm
 import (
 	"errors"
 	"fmt"
-	"math"
 	"math/bits"
-	"sort"
+
+	"github.com/ts4z/irata/chop"
 )
 
 const MaxPlayers = 20
@@ -140,31 +140,5 @@ func Chop(chips []int, prizes []int) ([]int, error) {
 		totalPrizes += p
 	}
 
-	n := len(equities)
-	result := make([]int, n)
-	allocated := 0
-
-	type indexedFrac struct {
-		index int
-		frac  float64
-	}
-	fracs := make([]indexedFrac, n)
-
-	for i, eq := range equities {
-		floored := int(math.Floor(eq))
-		result[i] = floored
-		allocated += floored
-		fracs[i] = indexedFrac{i, eq - float64(floored)}
-	}
-
-	// Distribute remaining units to players with largest fractional parts.
-	leftover := totalPrizes - allocated
-	sort.Slice(fracs, func(a, b int) bool {
-		return fracs[a].frac > fracs[b].frac
-	})
-	for i := 0; i < leftover && i < n; i++ {
-		result[fracs[i].index]++
-	}
-
-	return result, nil
+	return chop.Round(equities, totalPrizes), nil
 }
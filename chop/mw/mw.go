@@ -0,0 +1,142 @@
+// Package mw implements the Malmuth-Weitzman chop model.
+//
+// Where ICM (Malmuth-Harville) works from the top down, asking who wins,
+// Malmuth-Weitzman works from the bottom up: the chance a player is the next
+// one out is inversely proportional to their stack.  It tends to be a little
+// kinder to big stacks than ICM, which some rooms consider more realistic.
+//
+// Reference: https://www.pokerology.com/articles/malmuth-weitzman/
+package mw
+
+import (
+	"errors"
+	"fmt"
+	"math/bits"
+
+	"github.com/ts4z/irata/chop"
+)
+
+const MaxPlayers = 20
+
+type Chopper struct{}
+
+func (c *Chopper) Name() string {
+	return "Malmuth-Weitzman"
+}
+
+func (c *Chopper) Chop(chips []int, prizes []int) ([]int, error) {
+	return Chop(chips, prizes)
+}
+
+func (c *Chopper) MaxPlayers() int {
+	return MaxPlayers
+}
+
+// CalculateEquity computes Malmuth-Weitzman equity for each remaining player.
+//
+// chips[i] is the chip count for player i (must be positive).
+// prizes[k] is the prize for finishing in position k+1 (0-indexed;
+// prizes[0] = 1st place prize). If len(prizes) < len(chips), positions
+// beyond len(prizes) receive $0.
+//
+// Returns the expected dollar value for each player. The sum of returned
+// equities equals the sum of prizes.
+func CalculateEquity(chips []int, prizes []int) ([]float64, error) {
+	n := len(chips)
+	if n == 0 {
+		return nil, errors.New("mw: no players")
+	}
+	if n > MaxPlayers {
+		return nil, fmt.Errorf("mw: too many players (max %d)", MaxPlayers)
+	}
+	for i, c := range chips {
+		if c <= 0 {
+			return nil, fmt.Errorf("mw: player %d has non-positive chip count (%d)", i, c)
+		}
+	}
+
+	// Pad prizes to length n (unspecified positions get $0).
+	paddedPrizes := make([]float64, n)
+	for i := 0; i < len(prizes) && i < n; i++ {
+		paddedPrizes[i] = float64(prizes[i])
+	}
+
+	fullSet := (1 << n) - 1
+
+	// Precompute the sum of inverse stacks for each subset.
+	inverses := make([]float64, 1<<n)
+	for mask := 1; mask <= fullSet; mask++ {
+		lowest := mask & (-mask)
+		rest := mask ^ lowest
+		player := bits.TrailingZeros(uint(lowest))
+		inverses[mask] = inverses[rest] + 1/float64(chips[player])
+	}
+
+	memo := make([][]float64, n)
+	computed := make([][]bool, n)
+	for i := range n {
+		memo[i] = make([]float64, 1<<n)
+		computed[i] = make([]bool, 1<<n)
+	}
+
+	// equity computes the expected value for player within the active set mask.
+	//
+	// The Malmuth-Weitzman model assigns:
+	//   P(player busts next | set S) = (1/chips[player]) / sum over S of (1/chips)
+	//
+	// A player's equity in state S is:
+	//   EV = P(busts) * prize_for_last_place_in_S
+	//        + sum over other players j: P(j busts) * EV(player, S \ {j})
+	var equity func(player int, mask int) float64
+	equity = func(player int, mask int) float64 {
+		if computed[player][mask] {
+			return memo[player][mask]
+		}
+		computed[player][mask] = true
+
+		size := bits.OnesCount(uint(mask))
+		prize := paddedPrizes[size-1]
+
+		if size == 1 {
+			memo[player][mask] = prize
+			return prize
+		}
+
+		total := inverses[mask]
+		result := (1 / float64(chips[player]) / total) * prize
+
+		for j := range n {
+			if j == player || mask&(1<<j) == 0 {
+				continue
+			}
+			probJ := 1 / float64(chips[j]) / total
+			result += probJ * equity(player, mask&^(1<<j))
+		}
+
+		memo[player][mask] = result
+		return result
+	}
+
+	equities := make([]float64, n)
+	for i := range n {
+		equities[i] = equity(i, fullSet)
+	}
+
+	return equities, nil
+}
+
+// Chop computes Malmuth-Weitzman equities and rounds them to whole integer
+// amounts, ensuring the total equals the sum of prizes.
+func Chop(chips []int, prizes []int) ([]int, error) {
+	equities, err := CalculateEquity(chips, prizes)
+	if err != nil {
+		return nil, err
+	}
+
+	totalPrizes := 0
+	for _, p := range prizes {
+		totalPrizes += p
+	}
+
+	return chop.Round(equities, totalPrizes), nil
+}
@@ -0,0 +1,159 @@
+package mw
+
+import (
+	"math"
+	"testing"
+
+	"github.com/ts4z/irata/chop/icm"
+)
+
+func TestTwoPlayersEqualChips(t *testing.T) {
+	chips := []int{1000, 1000}
+	prizes := []int{60, 40}
+
+	eq, err := CalculateEquity(chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Equal chips → equal equity → each gets (60+40)/2 = 50
+	assertEquity(t, eq, 0, 50.0)
+	assertEquity(t, eq, 1, 50.0)
+}
+
+func TestTwoPlayersUnequalChips(t *testing.T) {
+	chips := []int{3000, 1000}
+	prizes := []int{60, 40}
+
+	eq, err := CalculateEquity(chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Heads-up, Malmuth-Weitzman and ICM agree:
+	// P(0 busts) = (1/3000) / (1/3000 + 1/1000) = 0.25
+	// EV(0) = 0.25*40 + 0.75*60 = 55
+	// EV(1) = 0.75*40 + 0.25*60 = 45
+	assertEquity(t, eq, 0, 55.0)
+	assertEquity(t, eq, 1, 45.0)
+}
+
+func TestThreePlayersUnequalChips(t *testing.T) {
+	chips := []int{5000, 3000, 2000}
+	prizes := []int{50, 30, 20}
+
+	eq, err := CalculateEquity(chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// P(bust next) is 6:10:15 (inverse stacks, scaled by 30000).
+	// EV(0) = 6/31*20 + 10/31*EV(0 vs 2000) + 15/31*EV(0 vs 3000)
+	//       = 6/31*20 + 10/31*44.286 + 15/31*42.5 ≈ 38.721
+	assertEquity(t, eq, 0, 38.721)
+	assertEquity(t, eq, 1, 32.726)
+	assertEquity(t, eq, 2, 28.553)
+
+	assertSumEquals(t, eq, 100.0)
+}
+
+func TestChipLeaderDoesBetterThanICM(t *testing.T) {
+	// The usual complaint about ICM is that it's stingy with big stacks.
+	// Malmuth-Weitzman gives them a bit more.
+	chips := []int{8000, 1000, 1000}
+	prizes := []int{100, 50, 20}
+
+	eq, err := CalculateEquity(chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+	icmEq, err := icm.CalculateEquity(chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if eq[0] <= icmEq[0] {
+		t.Errorf("chip leader MW equity %.2f should exceed ICM equity %.2f", eq[0], icmEq[0])
+	}
+	if eq[0] >= float64(prizes[0]) {
+		t.Errorf("chip leader equity %.2f should be less than 1st prize %d", eq[0], prizes[0])
+	}
+
+	assertSumEquals(t, eq, 170.0)
+}
+
+func TestFewerPrizesThanPlayers(t *testing.T) {
+	chips := []int{4000, 3000, 2000, 1000}
+	prizes := []int{70, 30}
+
+	eq, err := CalculateEquity(chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertSumEquals(t, eq, 100.0)
+
+	for i := 0; i < len(eq)-1; i++ {
+		if eq[i] <= eq[i+1] {
+			t.Errorf("equity should decrease with chips: eq[%d]=%.2f <= eq[%d]=%.2f",
+				i, eq[i], i+1, eq[i+1])
+		}
+	}
+}
+
+func TestOnePlayer(t *testing.T) {
+	eq, err := CalculateEquity([]int{5000}, []int{100})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertEquity(t, eq, 0, 100.0)
+}
+
+func TestChopPreservesTotal(t *testing.T) {
+	chips := []int{5000, 3000, 2000}
+	prizes := []int{1000, 600, 400}
+
+	chopped, err := Chop(chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	sum := 0
+	for _, c := range chopped {
+		sum += c
+	}
+	if sum != 2000 {
+		t.Errorf("sum of chopped amounts %d != 2000", sum)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	if _, err := CalculateEquity(nil, nil); err == nil {
+		t.Error("expected error for nil chips")
+	}
+	if _, err := CalculateEquity([]int{100, 0}, []int{60, 40}); err == nil {
+		t.Error("expected error for zero chips")
+	}
+	if _, err := CalculateEquity(make([]int, MaxPlayers+1), nil); err == nil {
+		t.Error("expected error for too many players")
+	}
+}
+
+func assertEquity(t *testing.T, eq []float64, player int, expected float64) {
+	t.Helper()
+	if math.Abs(eq[player]-expected) > 0.01 {
+		t.Errorf("player %d: expected %.3f, got %.3f", player, expected, eq[player])
+	}
+}
+
+func assertSumEquals(t *testing.T, eq []float64, expected float64) {
+	t.Helper()
+	sum := 0.0
+	for _, e := range eq {
+		sum += e
+	}
+	if math.Abs(sum-expected) > 0.01 {
+		t.Errorf("sum of equities %.3f != %.3f", sum, expected)
+	}
+}
@@ -0,0 +1,68 @@
+// Package save implements "save" chops, where the players set aside a fixed
+// amount for the eventual winner, chop the rest, and play on for the save.
+//
+// This is a common compromise when the stacks are close: nobody wants to
+// give up their shot at the title (and the trophy, and the bragging rights),
+// but everybody wants to lock up most of the money.
+package save
+
+import (
+	"fmt"
+
+	"github.com/ts4z/irata/chop"
+)
+
+// Chopper reserves Amount for the winner, then chops the rest of the prizes
+// with Base.
+//
+// The returned amounts do not include the save.  Whoever wins the tournament
+// gets their chop plus Amount.
+type Chopper struct {
+	Base   chop.Chopper
+	Amount int
+}
+
+func (c *Chopper) Name() string {
+	if n, ok := c.Base.(interface{ Name() string }); ok {
+		return n.Name() + " with save"
+	}
+	return "save"
+}
+
+func (c *Chopper) Chop(chips []int, prizes []int) ([]int, error) {
+	return Chop(c.Base, c.Amount, chips, prizes)
+}
+
+func (c *Chopper) MaxPlayers() int {
+	return c.Base.MaxPlayers()
+}
+
+// Chop takes amount off the top of 1st place, then chops what is left using
+// base.  The save can't be bigger than the difference between 1st and 2nd
+// place; any more than that and the winner is guaranteed less than the
+// runner-up, which isn't a deal anyone should take.
+func Chop(base chop.Chopper, amount int, chips []int, prizes []int) ([]int, error) {
+	if amount < 0 {
+		return nil, fmt.Errorf("save: negative save (%d)", amount)
+	}
+	if amount == 0 {
+		return base.Chop(chips, prizes)
+	}
+	if len(prizes) == 0 {
+		return nil, fmt.Errorf("save: nothing to save from")
+	}
+
+	second := 0
+	if len(prizes) > 1 {
+		second = prizes[1]
+	}
+	if amount > prizes[0]-second {
+		return nil, fmt.Errorf("save: save of %d exceeds the gap between 1st (%d) and 2nd (%d)", amount, prizes[0], second)
+	}
+
+	remaining := make([]int, len(prizes))
+	copy(remaining, prizes)
+	remaining[0] -= amount
+
+	return base.Chop(chips, remaining)
+}
@@ -0,0 +1,96 @@
+package save
+
+import (
+	"testing"
+
+	"github.com/ts4z/irata/chop/icm"
+	"github.com/ts4z/irata/chop/proportional"
+)
+
+func TestNoSaveIsBaseChop(t *testing.T) {
+	chips := []int{5000, 3000, 2000}
+	prizes := []int{50, 30, 20}
+
+	chopped, err := Chop(&proportional.Chopper{}, 0, chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same as the proportional test: 40, 32, 28
+	assertChop(t, chopped, 0, 40)
+	assertChop(t, chopped, 1, 32)
+	assertChop(t, chopped, 2, 28)
+}
+
+func TestSaveComesOffTheTop(t *testing.T) {
+	chips := []int{3000, 1000}
+	prizes := []int{600, 400}
+
+	chopped, err := Chop(&proportional.Chopper{}, 100, chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// 100 saved, leaving 500/400.  Floor 400 each, 100 split 3:1.
+	// P0 = 400 + 75 = 475, P1 = 400 + 25 = 425; the winner adds 100.
+	assertChop(t, chopped, 0, 475)
+	assertChop(t, chopped, 1, 425)
+	assertSumEquals(t, chopped, 900)
+}
+
+func TestSaveWithICM(t *testing.T) {
+	chips := []int{1000, 1000, 1000}
+	prizes := []int{500, 300, 200}
+
+	chopped, err := Chop(&icm.Chopper{}, 100, chips, prizes)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Equal chips → 900/3 = 300 each
+	for i := range chopped {
+		assertChop(t, chopped, i, 300)
+	}
+	assertSumEquals(t, chopped, 900)
+}
+
+func TestSaveTooBig(t *testing.T) {
+	// 1st and 2nd are only 200 apart
+	if _, err := Chop(&icm.Chopper{}, 201, []int{1000, 1000}, []int{600, 400}); err == nil {
+		t.Error("expected error for save larger than 1st/2nd gap")
+	}
+}
+
+func TestNegativeSave(t *testing.T) {
+	if _, err := Chop(&icm.Chopper{}, -1, []int{1000, 1000}, []int{600, 400}); err == nil {
+		t.Error("expected error for negative save")
+	}
+}
+
+func TestChopperInterface(t *testing.T) {
+	c := &Chopper{Base: &icm.Chopper{}, Amount: 100}
+	if got := c.Name(); got != "ICM with save" {
+		t.Errorf("Name() = %q, want %q", got, "ICM with save")
+	}
+	if got := c.MaxPlayers(); got != icm.MaxPlayers {
+		t.Errorf("MaxPlayers() = %d, want %d", got, icm.MaxPlayers)
+	}
+}
+
+func assertChop(t *testing.T, chopped []int, player int, expected int) {
+	t.Helper()
+	if chopped[player] != expected {
+		t.Errorf("player %d: expected %d, got %d", player, expected, chopped[player])
+	}
+}
+
+func assertSumEquals(t *testing.T, chopped []int, expected int) {
+	t.Helper()
+	sum := 0
+	for _, c := range chopped {
+		sum += c
+	}
+	if sum != expected {
+		t.Errorf("sum of chopped amounts %d != %d", sum, expected)
+	}
+}
@@ -3,10 +3,16 @@ package main
 import (
 	"context"
 	"crypto/rand"
+	"database/sql"
 	"encoding/base64"
 	"fmt"
 	"log"
+	"maps"
 	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
 	"syscall"
 	"text/tabwriter"
 	"time"
@@ -17,8 +23,11 @@ import (
 	"github.com/spf13/cobra"
 	"maze.io/x/duration"
 
+	"github.com/ts4z/irata/apitoken"
+	"github.com/ts4z/irata/backup"
 	"github.com/ts4z/irata/config"
 	"github.com/ts4z/irata/dbutil"
+	"github.com/ts4z/irata/migrate"
 	"github.com/ts4z/irata/model"
 	"github.com/ts4z/irata/password"
 	"github.com/ts4z/irata/state"
@@ -42,6 +51,12 @@ var (
 	userIsAdmin bool
 
 	expireTime time.Time
+
+	backupOut     string
+	restoreDryRun bool
+
+	tokenName   string
+	tokenScopes string
 )
 
 // Should return a Userstorage, but that hides Close.
@@ -359,6 +374,248 @@ func replacePassword(cmd *cobra.Command, args []string) error {
 	return nil
 }
 
+func fetchUserID(ctx context.Context, storage *state.DBStorage, nick string) (int64, error) {
+	userRow, err := storage.FetchUserRow(ctx, nick)
+	if err != nil {
+		return 0, fmt.Errorf("fetching user %q: %w", nick, err)
+	}
+	if userRow.ID == 0 {
+		return 0, fmt.Errorf("no user %q", nick)
+	}
+	return userRow.ID, nil
+}
+
+func createToken(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	storage := newUserStorage(ctx)
+	defer storage.Close()
+
+	nick := args[0]
+	if tokenName == "" {
+		return fmt.Errorf("--name is required")
+	}
+	scopes, err := apitoken.ParseScopes(tokenScopes)
+	if err != nil {
+		return err
+	}
+	userID, err := fetchUserID(ctx, storage, nick)
+	if err != nil {
+		return err
+	}
+
+	secret, hash := apitoken.New()
+	id, err := storage.CreateAPIToken(ctx, &model.APIToken{
+		UserID: userID,
+		Name:   tokenName,
+		Scopes: scopes,
+	}, hash)
+	if err != nil {
+		return fmt.Errorf("creating token: %w", err)
+	}
+
+	fmt.Fprintf(os.Stderr, "Token %d for %q, with scopes %s.  It won't be shown again:\n", id, nick, strings.Join(scopes, ", "))
+	fmt.Println(secret)
+	return nil
+}
+
+func listTokens(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	storage := newUserStorage(ctx)
+	defer storage.Close()
+
+	nick := args[0]
+	userID, err := fetchUserID(ctx, storage, nick)
+	if err != nil {
+		return err
+	}
+	tokens, err := storage.FetchAPITokensByUserID(ctx, userID)
+	if err != nil {
+		return fmt.Errorf("fetching tokens: %w", err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(w, "id\tname\tscopes\tcreated\tlast used\n")
+	for _, t := range tokens {
+		lastUsed := "never"
+		if t.LastUsed != nil {
+			lastUsed = t.LastUsed.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\n", t.ID, t.Name, strings.Join(t.Scopes, ","), t.Created.Format(time.RFC3339), lastUsed)
+	}
+	w.Flush()
+	return nil
+}
+
+func revokeToken(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	storage := newUserStorage(ctx)
+	defer storage.Close()
+
+	nick := args[0]
+	id, err := strconv.ParseInt(args[1], 10, 64)
+	if err != nil {
+		return fmt.Errorf("bad token id %q", args[1])
+	}
+	userID, err := fetchUserID(ctx, storage, nick)
+	if err != nil {
+		return err
+	}
+	if err := storage.DeleteAPIToken(ctx, userID, id); err != nil {
+		return fmt.Errorf("revoking token %d: %w", id, err)
+	}
+	fmt.Printf("Token %d revoked.\n", id)
+	return nil
+}
+
+func newDB() *sql.DB {
+	config.Init()
+	db, err := dbutil.Connect()
+	if err != nil {
+		log.Fatalf("can't connect to database: %v", err)
+	}
+	return db
+}
+
+func migrationStatus(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	db := newDB()
+	defer db.Close()
+
+	st, err := migrate.FetchStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintf(w, "VERSION\tNAME\tAPPLIED\tCHECKSUM\n")
+	for _, a := range st.Applied {
+		fmt.Fprintf(w, "%d\t%s\t%s\t%.12s\n", a.Version, a.Name, a.AppliedAt.Format(time.RFC3339), a.Checksum)
+	}
+	for _, m := range st.Pending {
+		fmt.Fprintf(w, "%d\t%s\tpending\t%.12s\n", m.Version, m.Name, m.Checksum)
+	}
+	w.Flush()
+	if st.UpToDate() {
+		fmt.Printf("\nschema is up to date at version %d\n", st.Current)
+	} else {
+		fmt.Printf("\nschema is at version %d of %d; run \"irataadmin db migrate\"\n", st.Current, st.Latest)
+	}
+	return nil
+}
+
+func migrateDB(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	db := newDB()
+	defer db.Close()
+
+	n := 0
+	if err := migrate.Migrate(ctx, db, func(m *migrate.Migration) {
+		fmt.Printf("applied %04d_%s\n", m.Version, m.Name)
+		n++
+	}); err != nil {
+		return err
+	}
+	if n == 0 {
+		fmt.Println("schema was already up to date")
+	}
+	return nil
+}
+
+func backupSite(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+	storage := newSiteStorage(ctx)
+	defer storage.Close()
+
+	now := clock.Now()
+	a, err := backup.Dump(ctx, storage, now)
+	if err != nil {
+		return err
+	}
+
+	if backupOut == "-" {
+		return backup.Write(os.Stdout, a)
+	}
+	name := backupOut
+	if name == "" {
+		name = fmt.Sprintf("irata-backup-%s.tar.gz", now.Format("20060102-150405"))
+	}
+	// Write somewhere else first, so a failed backup doesn't leave a file
+	// that looks like a good one.
+	f, err := os.CreateTemp(filepath.Dir(name), ".irata-backup-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+	if err := backup.Write(f, a); err != nil {
+		f.Close()
+		return fmt.Errorf("writing backup: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("writing backup: %w", err)
+	}
+	if err := os.Rename(f.Name(), name); err != nil {
+		return err
+	}
+
+	fmt.Fprintf(os.Stderr, "wrote %s:\n", name)
+	printCounts(a.Manifest.Counts)
+	return nil
+}
+
+func printCounts(counts map[string]int) {
+	w := tabwriter.NewWriter(os.Stderr, 0, 0, 2, ' ', 0)
+	for _, kind := range slices.Sorted(maps.Keys(counts)) {
+		fmt.Fprintf(w, "  %s\t%d\n", kind, counts[kind])
+	}
+	w.Flush()
+}
+
+func restoreSite(cmd *cobra.Command, args []string) error {
+	ctx := context.Background()
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	a, err := backup.Read(f)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "backup taken %s, format version %d\n",
+		a.Manifest.CreatedAt.Format(time.RFC3339), a.Manifest.Version)
+
+	db := newDB()
+	if err := migrate.Check(ctx, db); err != nil {
+		return err
+	}
+	storage, err := state.NewDBStorage(ctx, db)
+	if err != nil {
+		return err
+	}
+	defer storage.Close()
+
+	report, err := backup.Restore(ctx, a, storage, restoreDryRun)
+	if report != nil {
+		for _, w := range report.Warnings {
+			fmt.Fprintf(os.Stderr, "warning: %s\n", w)
+		}
+	}
+	if err != nil {
+		return err
+	}
+
+	if report.DryRun {
+		fmt.Fprintf(os.Stderr, "dry run; nothing written.  would restore:\n")
+	} else {
+		fmt.Fprintf(os.Stderr, "restored:\n")
+	}
+	printCounts(report.Created)
+	if !report.DryRun {
+		fmt.Fprintf(os.Stderr, "backups have no cookie keys; run \"irataadmin cookie-key rotate\" before starting iratad\n")
+	}
+	return nil
+}
+
 func main() {
 	config.Init()
 
@@ -491,6 +748,63 @@ func main() {
 	userCmd.AddCommand(addUserCmd, listUserCmd, deleteUserCmd, pwCmd)
 	rootCmd.AddCommand(userCmd)
 
+	tokenCmd := &cobra.Command{
+		Use:   "token",
+		Short: "Manage users' REST API tokens",
+	}
+	createTokenCmd := &cobra.Command{
+		Use:   "create [nick]",
+		Short: "Make a token, and print it",
+		Args:  cobra.ExactArgs(1),
+		RunE:  createToken,
+	}
+	createTokenCmd.Flags().StringVar(&tokenName, "name", "", "What the token is for")
+	createTokenCmd.Flags().StringVar(&tokenScopes, "scopes", apitoken.ScopeRead, "Scopes, separated by commas: "+strings.Join(apitoken.Scopes, ", "))
+	tokenCmd.AddCommand(createTokenCmd, &cobra.Command{
+		Use:   "list [nick]",
+		Short: "List a user's tokens",
+		Args:  cobra.ExactArgs(1),
+		RunE:  listTokens,
+	}, &cobra.Command{
+		Use:   "revoke [nick] [id]",
+		Short: "Revoke one of a user's tokens",
+		Args:  cobra.ExactArgs(2),
+		RunE:  revokeToken,
+	})
+	rootCmd.AddCommand(tokenCmd)
+
+	backupCmd := &cobra.Command{
+		Use:   "backup",
+		Short: "Write everything in the site to a backup archive",
+		RunE:  backupSite,
+	}
+	backupCmd.Flags().StringVar(&backupOut, "out", "", `File to write (default irata-backup-<time>.tar.gz; "-" for stdout)`)
+
+	restoreCmd := &cobra.Command{
+		Use:   "restore [file]",
+		Short: "Restore a backup archive into an empty database",
+		Args:  cobra.ExactArgs(1),
+		RunE:  restoreSite,
+	}
+	restoreCmd.Flags().BoolVar(&restoreDryRun, "dry-run", false, "Check the backup and the database, but write nothing")
+
+	rootCmd.AddCommand(backupCmd, restoreCmd)
+
+	dbCmd := &cobra.Command{
+		Use:   "db",
+		Short: "Manage the database schema",
+	}
+	dbCmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show which schema migrations have been applied",
+		RunE:  migrationStatus,
+	}, &cobra.Command{
+		Use:   "migrate",
+		Short: "Apply pending schema migrations",
+		RunE:  migrateDB,
+	})
+	rootCmd.AddCommand(dbCmd)
+
 	if err := rootCmd.Execute(); err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -2,30 +2,56 @@ package main
 
 import (
 	"context"
+	"database/sql"
+	"flag"
 	"io/fs"
 	"log"
+	"math"
+	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/spf13/viper"
 
 	"github.com/ts4z/irata/assets"
+	"github.com/ts4z/irata/certs"
 	"github.com/ts4z/irata/config"
 	"github.com/ts4z/irata/dbcache"
 	"github.com/ts4z/irata/dbnotify"
 	"github.com/ts4z/irata/dbutil"
+	"github.com/ts4z/irata/demo"
 	"github.com/ts4z/irata/form"
 	"github.com/ts4z/irata/gossip"
+	"github.com/ts4z/irata/middleware/clientip"
+	"github.com/ts4z/irata/migrate"
+	"github.com/ts4z/irata/model"
 	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/schedule"
 	"github.com/ts4z/irata/state"
 	"github.com/ts4z/irata/tournament"
 	"github.com/ts4z/irata/ts"
 	"github.com/ts4z/irata/webapp"
+	"github.com/ts4z/irata/webhook"
+)
+
+var (
+	demoMode  = flag.Bool("demo", false, "run in memory with example data and an accelerated clock; nothing is saved")
+	demoSpeed = flag.Float64("demo-speed", 10, "how many times faster than real time the clock runs with --demo")
 )
 
 func main() {
 	ctx := context.Background()
+	flag.Parse()
 	config.Init()
 
-	clock := ts.NewRealClock()
+	var clock ts.Clock = ts.NewRealClock()
+	if *demoMode {
+		// Zero would stop the clock and less would run it backwards.
+		if !(*demoSpeed > 0) || math.IsInf(*demoSpeed, 0) {
+			log.Fatalf("--demo-speed must be a positive number, not %g", *demoSpeed)
+		}
+		clock = ts.NewAcceleratedClock(*demoSpeed)
+	}
 	subFS, err := fs.Sub(assets.FS, "fs")
 	if err != nil {
 		log.Fatalf("fs.Sub: %v", err)
@@ -35,19 +61,39 @@ func main() {
 
 	tournamentManager := tournament.NewManager(clock, state.NewDefaultPaytableStorage(), soundStorage)
 
-	db, err := dbutil.Connect()
-	if err != nil {
-		log.Fatalf("can't connect to database: %v", err)
+	var db *sql.DB
+	var unprotectedStorage state.Storage
+	if *demoMode {
+		unprotectedStorage = state.NewMemStorage()
+	} else {
+		db, err = dbutil.Connect()
+		if err != nil {
+			log.Fatalf("can't connect to database: %v", err)
+		}
+		if err := migrate.Check(context.Background(), db); err != nil {
+			log.Fatalf("can't use database: %v", err)
+		}
+		unprotectedStorage, err = state.NewDBStorage(context.Background(), db)
+		if err != nil {
+			log.Fatalf("can't configure database: %v", err)
+		}
 	}
+	defer unprotectedStorage.Close()
 
-	unprotectedStorage, err := state.NewDBStorage(context.Background(), db)
-	if err != nil {
-		log.Fatalf("can't configure database: %v", err)
+	if *demoMode {
+		if err := demo.Seed(ctx, unprotectedStorage, clock.Now()); err != nil {
+			log.Fatalf("can't load demo data: %v", err)
+		}
+		pw, err := demo.CreateAdmin(ctx, unprotectedStorage)
+		if err != nil {
+			log.Fatalf("can't create demo admin: %v", err)
+		}
+		log.Printf("demo mode: the clock runs %gx; log in as %q with password %q", *demoSpeed, demo.AdminNick, pw)
 	}
-	defer unprotectedStorage.Close()
 
 	cachedSiteConfigStorage := dbcache.NewSiteConfigStorage(unprotectedStorage, clock)
 	siteStorageReader := permission.NewSiteConfigStorageReader(cachedSiteConfigStorage)
+	siteConfigGossiper := gossip.NewSiteConfigGossiper()
 	protectedSiteConfigStorage := permission.NewSiteConfigStorage(cachedSiteConfigStorage)
 
 	bakeryFactory := permission.NewBakeryFactory(clock, cachedSiteConfigStorage)
@@ -55,17 +101,38 @@ func main() {
 		log.Fatalf("can't create bakery: %v", err)
 	}
 
+	cachedAppStorage := dbcache.NewAppStorage(dbcache.AppStorageCacheSize, unprotectedStorage)
 	appStorage := &permission.AppStorage{
-		Storage: dbcache.NewAppStorage(16, unprotectedStorage),
+		Storage: cachedAppStorage,
 	}
-	cachedTournamentStorage := dbcache.NewTournamentStorage(128, unprotectedStorage)
+	// Webhooks compare each save with what it replaces, so they go under
+	// the cache, which advances its tournaments' clocks in place.
+	hooks := webhook.New(unprotectedStorage, unprotectedStorage, tournamentManager, clock)
+	go hooks.Run(ctx, webhook.DefaultInterval)
+	cachedTournamentStorage := dbcache.NewTournamentStorage(128,
+		webhook.NewTournamentStorage(unprotectedStorage, hooks))
 	tournamentGossiper := gossip.NewTournamentGossiper(cachedTournamentStorage, tournamentManager)
+	gossipingTournamentStorage := gossip.NewTournamentStorage(cachedTournamentStorage, tournamentGossiper)
 	tournamentStorage := &permission.TournamentStorage{
-		Storage: gossip.NewTournamentStorage(
-			cachedTournamentStorage,
-			tournamentGossiper),
+		Storage: gossipingTournamentStorage,
+	}
+
+	cachedDisplayStorage := dbcache.NewDisplayStorage(64, unprotectedStorage)
+	displayGossiper := gossip.NewDisplayGossiper(cachedDisplayStorage)
+	displayStorage := &permission.DisplayStorage{
+		Storage: gossip.NewDisplayStorage(cachedDisplayStorage, displayGossiper),
+	}
+
+	announcementGossiper := gossip.NewAnnouncementGossiper(unprotectedStorage, clock)
+	announcementStorage := &permission.AnnouncementStorage{
+		Storage: gossip.NewAnnouncementStorage(unprotectedStorage, announcementGossiper),
 	}
 
+	// The scheduler works for no one in particular, so it goes around the
+	// permission checks.
+	scheduler := schedule.NewScheduler(unprotectedStorage, gossipingTournamentStorage, tournamentManager, clock)
+	go scheduler.Run(ctx, schedule.DefaultInterval)
+
 	cachedUserStorage := dbcache.NewUserStorage(128, unprotectedStorage)
 	userStorage := permission.NewUserStorage(cachedUserStorage)
 
@@ -82,31 +149,109 @@ func main() {
 	tourneyDispatcher := dbnotify.NewChangeDispatcher("tournaments",
 		tournamentGossiper, cachedTournamentStorage, cachedTournamentStorage)
 
-	// TODO: site config dispatcher, footer plug dispatcher, etc.
+	displayDispatcher := dbnotify.NewChangeDispatcher("displays",
+		displayGossiper, cachedDisplayStorage, cachedDisplayStorage)
+
+	// Announcements aren't cached, so the gossiper fetches for itself.
+	announcementDispatcher := dbnotify.NewChangeDispatcher("announcements",
+		announcementGossiper, announcementGossiper, announcementGossiper)
+
+	siteConfigDispatcher := dbnotify.NewChangeDispatcher("site_config",
+		siteConfigGossiper, cachedSiteConfigStorage, cachedSiteConfigStorage)
+
+	// Nobody subscribes to these; clients pick up the change when they next
+	// fetch, so dropping the cached copy is all there is to do.
+	footerPlugs := cachedAppStorage.FooterPlugSets()
+	footerPlugDispatcher := dbnotify.NewChangeDispatcher[*model.FooterPlugs]("footer_plug_sets",
+		nil, footerPlugs, footerPlugs)
+	structures := cachedAppStorage.Structures()
+	structureDispatcher := dbnotify.NewChangeDispatcher[*model.Structure]("structures",
+		nil, structures, structures)
+
+	consumers := []dbnotify.Consumer{
+		tourneyDispatcher, userDispatcher, displayDispatcher, announcementDispatcher,
+		siteConfigDispatcher, footerPlugDispatcher, structureDispatcher,
+	}
+
+	var dbListener dbnotify.Listener
+	if *demoMode || dbutil.DialectOf(db) == dbutil.SQLite {
+		// Memory and SQLite can't notify, but only this process writes to
+		// them, so the storage tells us itself.
+		local, err := dbnotify.NewLocalListener(consumers...)
+		if err != nil {
+			log.Fatalf("can't create local notification listener: %v", err)
+		}
+		unprotectedStorage.SetNotifier(local)
+		dbListener = local
+	} else {
+		dbListener, err = dbnotify.NewDBNotifyListener(db, consumers...)
+		if err != nil {
+			log.Fatalf("can't create db notificationlistener: %v", err)
+		}
+	}
+
+	var tlsCerts *certs.Certs
+	if config.TLSEnabled() {
+		tlsCerts, err = certs.New(&certs.Config{
+			CertFile:     config.TLSCertFile(),
+			KeyFile:      config.TLSKeyFile(),
+			Domains:      config.ACMEDomains(),
+			Email:        config.ACMEEmail(),
+			DirectoryURL: config.ACMEDirectoryURL(),
+			CAFile:       config.ACMECAFile(),
+			Cache:        certs.NewDBCache(unprotectedStorage),
+		})
+		if err != nil {
+			log.Fatalf("can't set up TLS: %v", err)
+		}
+	}
 
-	dbListener, err := dbnotify.NewDBNotifyListener(db, tourneyDispatcher, userDispatcher)
+	trustedProxies, err := clientip.ParseTrusted(config.TrustedProxies(), config.ProxyHeader())
 	if err != nil {
-		log.Fatalf("can't create db notificationlistener: %v", err)
+		log.Fatalf("can't use trusted proxies: %v", err)
 	}
 
 	app := webapp.New(ctx, &webapp.Config{
-		TournamentGossiper: tournamentGossiper,
-		DBListener:         dbListener,
-		AppStorage:         appStorage,
-		TournamentStorage:  tournamentStorage,
-		SiteStorage:        protectedSiteConfigStorage,
-		SiteStorageReader:  siteStorageReader,
-		PaytableStorage:    paytableStorage,
-		SoundStorage:       soundStorage,
-		UserStorage:        userStorage,
-		FormProcessor:      mutator,
-		SubFS:              subFS,
-		BakeryFactory:      bakeryFactory,
-		Clock:              clock,
-		TournamentManager:  tournamentManager,
+		TournamentGossiper:   tournamentGossiper,
+		DisplayGossiper:      displayGossiper,
+		AnnouncementGossiper: announcementGossiper,
+		SiteConfigGossiper:   siteConfigGossiper,
+		DBListener:           dbListener,
+		AppStorage:           appStorage,
+		TournamentStorage:    tournamentStorage,
+		DisplayStorage:       displayStorage,
+		LayoutStorage:        &permission.LayoutStorage{Storage: unprotectedStorage},
+		SlideStorage:         &permission.SlideStorage{Storage: unprotectedStorage},
+		AnnouncementStorage:  announcementStorage,
+		TemplateStorage:      &permission.TournamentTemplateStorage{Storage: unprotectedStorage},
+		LeagueStorage:        &permission.LeagueStorage{Storage: unprotectedStorage},
+		SiteStorage:          protectedSiteConfigStorage,
+		SiteStorageReader:    siteStorageReader,
+		PaytableStorage:      paytableStorage,
+		SoundStorage:         soundStorage,
+		UserStorage:          userStorage,
+		APITokenStorage:      &permission.APITokenStorage{Storage: unprotectedStorage},
+		WebhookStorage:       &permission.WebhookStorage{Storage: unprotectedStorage},
+		FormProcessor:        mutator,
+		SubFS:                subFS,
+		BakeryFactory:        bakeryFactory,
+		Clock:                clock,
+		TournamentManager:    tournamentManager,
+		TrustedProxies:       trustedProxies,
+		Certs:                tlsCerts,
+		HTTPSListenAddress:   config.HTTPSListenAddress(),
+		ServeHTTP:            !config.RedirectHTTP(),
+		HTTP3:                config.HTTP3(),
+		HealthStorage:        unprotectedStorage,
+		MetricsToken:         config.MetricsToken(),
+		DrainTimeout:         config.DrainTimeout(),
 	})
 
-	if err := app.Serve(ctx, viper.GetString("listen_address")); err != nil {
+	// SIGTERM is how Cloud Run, Kubernetes and systemd ask us to stop.
+	serveCtx, stop := signal.NotifyContext(ctx, syscall.SIGTERM, os.Interrupt)
+	defer stop()
+	if err := app.Serve(serveCtx, viper.GetString("listen_address")); err != nil {
 		log.Fatalf("can't serve: %v", err)
 	}
+	log.Printf("stopped")
 }
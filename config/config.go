@@ -7,8 +7,13 @@ package config
 import (
 	"log"
 	"os"
+	"strings"
+	"time"
+	"unicode"
 
 	"github.com/spf13/viper"
+
+	"github.com/ts4z/irata/logz"
 )
 
 // Viper-based config loader
@@ -24,15 +29,51 @@ func Init() {
 	viper.BindEnv("db_url", "IRATA_DB_URL")
 	viper.BindEnv("listen_address", "IRATA_LISTEN_ADDRESS")
 	viper.BindEnv("sql_connector", "IRATA_SQL_CONNECTOR")
+	viper.BindEnv("https_listen_address", "IRATA_HTTPS_LISTEN_ADDRESS")
+	viper.BindEnv("tls_cert_file", "IRATA_TLS_CERT_FILE")
+	viper.BindEnv("tls_key_file", "IRATA_TLS_KEY_FILE")
+	viper.BindEnv("acme_domains", "IRATA_ACME_DOMAINS")
+	viper.BindEnv("acme_email", "IRATA_ACME_EMAIL")
+	viper.BindEnv("acme_directory_url", "IRATA_ACME_DIRECTORY_URL")
+	viper.BindEnv("acme_ca_file", "IRATA_ACME_CA_FILE")
+	viper.BindEnv("redirect_http", "IRATA_REDIRECT_HTTP")
+	viper.BindEnv("http3", "IRATA_HTTP3")
+	viper.BindEnv("trusted_proxies", "IRATA_TRUSTED_PROXIES")
+	viper.BindEnv("proxy_header", "IRATA_PROXY_HEADER")
+	viper.BindEnv("log_format", "IRATA_LOG_FORMAT")
+	viper.BindEnv("log_level", "IRATA_LOG_LEVEL")
+	viper.BindEnv("log_levels", "IRATA_LOG_LEVELS")
+	viper.BindEnv("drain_timeout", "IRATA_DRAIN_TIMEOUT")
+	viper.BindEnv("metrics_token", "IRATA_METRICS_TOKEN")
 	viper.SetDefault("db_url", "")
 	viper.SetDefault("listen_address", ":8080")
 	viper.SetDefault("sql_connector", "pgx")
-	err = viper.ReadInConfig() // ignore error if config file missing
-	if err != nil {
-		log.Printf("viper can't read config file: %v", err)
+	viper.SetDefault("https_listen_address", ":8443")
+	viper.SetDefault("redirect_http", true)
+	viper.SetDefault("trusted_proxies", "127.0.0.0/8,::1")
+	viper.SetDefault("proxy_header", "X-Forwarded-For")
+	viper.SetDefault("log_format", "text")
+	viper.SetDefault("log_level", "info")
+	viper.SetDefault("drain_timeout", "8s")
+	configErr := viper.ReadInConfig() // ignore error if config file missing
+	if err := logz.Setup(&logz.Config{
+		Format: viper.GetString("log_format"),
+		Level:  viper.GetString("log_level"),
+		Levels: list("log_levels"),
+	}); err != nil {
+		log.Fatalf("can't set up logging: %v", err)
+	}
+	if configErr != nil {
+		log.Printf("viper can't read config file: %v", configErr)
 	}
 	log.Printf("Using database URL: %s", viper.GetString("db_url"))
 	log.Printf("Using listen address: %s", viper.GetString("listen_address"))
+	if TLSEnabled() {
+		log.Printf("Using HTTPS listen address: %s", viper.GetString("https_listen_address"))
+		if HTTP3() {
+			log.Printf("Serving HTTP/3 on the HTTPS listen address too")
+		}
+	}
 }
 
 func DBURL() string {
@@ -43,10 +84,91 @@ func ListenAddress() string {
 	return viper.GetString("listen_address")
 }
 
+func HTTPSListenAddress() string {
+	return viper.GetString("https_listen_address")
+}
+
+// SecureCookies is on when asked for, and whenever irata serves TLS itself.
 func SecureCookies() bool {
-	return viper.GetBool("secure_cookies")
+	return viper.GetBool("secure_cookies") || TLSEnabled()
+}
+
+func TLSCertFile() string {
+	return viper.GetString("tls_cert_file")
+}
+
+func TLSKeyFile() string {
+	return viper.GetString("tls_key_file")
+}
+
+// list splits a setting at commas and spaces.
+func list(key string) []string {
+	return strings.FieldsFunc(viper.GetString(key), func(r rune) bool {
+		return r == ',' || unicode.IsSpace(r)
+	})
+}
+
+// ACMEDomains are the names to get certificates for, separated by commas
+// or spaces.
+func ACMEDomains() []string {
+	return list("acme_domains")
+}
+
+func ACMEEmail() string {
+	return viper.GetString("acme_email")
+}
+
+func ACMEDirectoryURL() string {
+	return viper.GetString("acme_directory_url")
+}
+
+func ACMECAFile() string {
+	return viper.GetString("acme_ca_file")
+}
+
+// TLSEnabled says whether there are certificates to serve HTTPS with.
+func TLSEnabled() bool {
+	return TLSCertFile() != "" || len(ACMEDomains()) > 0
+}
+
+// RedirectHTTP says whether, with TLS on, plain HTTP requests are sent to
+// HTTPS rather than served.
+func RedirectHTTP() bool {
+	return viper.GetBool("redirect_http")
+}
+
+// HTTP3 says whether to serve HTTP/3 over QUIC, on the same port as HTTPS
+// but UDP.  It only means anything with TLS on.
+func HTTP3() bool {
+	return viper.GetBool("http3")
+}
+
+// DrainTimeout is how long iratad, told to stop, waits for requests to
+// finish before cutting them off.
+func DrainTimeout() time.Duration {
+	return viper.GetDuration("drain_timeout")
+}
+
+// MetricsToken is the bearer token a Prometheus scraper sends for
+// /metrics.  Empty means only administrators can read it.
+func MetricsToken() string {
+	return viper.GetString("metrics_token")
 }
 
 func SQLConnector() string {
 	return viper.GetString("sql_connector")
 }
+
+// TrustedProxies are the CIDRs of proxies whose Forwarded and
+// X-Forwarded-For headers are believed.  The default is the local host, for
+// a proxy on the same machine.
+func TrustedProxies() []string {
+	return list("trusted_proxies")
+}
+
+// ProxyHeader is the header the trusted proxies write the client into:
+// "X-Forwarded-For", the default, as nginx and most load balancers do, or
+// "Forwarded".  The other one is ignored.
+func ProxyHeader() string {
+	return viper.GetString("proxy_header")
+}
@@ -22,6 +22,7 @@ var (
 
 type AppStorage struct {
 	fpCache *lru.Cache[int64, *model.FooterPlugs]
+	stCache *lru.Cache[int64, *model.Structure]
 	next    state.AppStorage
 }
 
@@ -32,13 +33,18 @@ func NewAppStorage(size int, nx state.AppStorage) *AppStorage {
 	if err != nil {
 		log.Fatalf("Failed to create FooterPlugsStorage cache: %v", err)
 	}
+	stCache, err := lru.New[int64, *model.Structure](size)
+	if err != nil {
+		log.Fatalf("Failed to create structure cache: %v", err)
+	}
 	return &AppStorage{
 		fpCache: fpCache,
+		stCache: stCache,
 		next:    nx,
 	}
 }
 
-func (a *AppStorage) CreateFooterPlugSet(ctx context.Context, name string, plugs []string) (int64, error) {
+func (a *AppStorage) CreateFooterPlugSet(ctx context.Context, name string, plugs []model.FooterPlug) (int64, error) {
 	return a.next.CreateFooterPlugSet(ctx, name, plugs)
 }
 
@@ -50,7 +56,7 @@ func (a *AppStorage) CreateStructure(ctx context.Context, s *model.Structure) (i
 // DeleteFooterPlugSet implements state.AppStorage.
 func (a *AppStorage) DeleteFooterPlugSet(ctx context.Context, id int64) error {
 	err := a.next.DeleteFooterPlugSet(ctx, id)
-	if err != nil {
+	if err == nil {
 		a.fpCache.Remove(id)
 	}
 	return err
@@ -58,14 +64,14 @@ func (a *AppStorage) DeleteFooterPlugSet(ctx context.Context, id int64) error {
 
 // DeleteStructure implements state.AppStorage.
 func (a *AppStorage) DeleteStructure(ctx context.Context, id int64) error {
-	return a.next.DeleteStructure(ctx, id)
+	err := a.next.DeleteStructure(ctx, id)
+	if err == nil {
+		a.stCache.Remove(id)
+	}
+	return err
 }
 
 // FetchPlugs implements state.AppStorage.
-//
-// TODO: This does not consume from any other server instances;
-// we need to be notified of any writes we didn't make.  But for now,
-// there's one server instance, so...
 func (a *AppStorage) FetchPlugs(ctx context.Context, id int64) (*model.FooterPlugs, error) {
 	if plugs, ok := a.fpCache.Get(id); ok {
 		appStorageCacheHits.Add(1)
@@ -82,7 +88,17 @@ func (a *AppStorage) FetchPlugs(ctx context.Context, id int64) (*model.FooterPlu
 
 // FetchStructure implements state.AppStorage.
 func (a *AppStorage) FetchStructure(ctx context.Context, id int64) (*model.Structure, error) {
-	return a.next.FetchStructure(ctx, id)
+	if st, ok := a.stCache.Get(id); ok {
+		appStorageCacheHits.Add(1)
+		return st.Clone(), nil
+	}
+	appStorageCacheMisses.Add(1)
+	st, err := a.next.FetchStructure(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	a.stCache.Add(id, st.Clone())
+	return st, nil
 }
 
 // FetchStructureSlugs implements state.AppStorage.
@@ -97,11 +113,15 @@ func (a *AppStorage) ListFooterPlugSets(ctx context.Context) ([]*model.FooterPlu
 
 // SaveStructure implements state.AppStorage.
 func (a *AppStorage) SaveStructure(ctx context.Context, s *model.Structure) error {
-	return a.next.SaveStructure(ctx, s)
+	err := a.next.SaveStructure(ctx, s)
+	if err == nil {
+		a.stCache.Remove(s.ID)
+	}
+	return err
 }
 
 // UpdateFooterPlugSet implements state.AppStorage.
-func (a *AppStorage) UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []string) error {
+func (a *AppStorage) UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []model.FooterPlug) error {
 	err := a.next.UpdateFooterPlugSet(ctx, id, name, plugs)
 	if err != nil {
 		return err
@@ -114,3 +134,43 @@ func (a *AppStorage) UpdateFooterPlugSet(ctx context.Context, id int64, name str
 	a.fpCache.Remove(id)
 	return nil
 }
+
+// FooterPlugSets is the footer plug half of the cache, for
+// dbnotify.ChangeDispatcher.
+func (a *AppStorage) FooterPlugSets() *FooterPlugSetCache {
+	return &FooterPlugSetCache{a: a}
+}
+
+// Structures is the structure half of the cache, for
+// dbnotify.ChangeDispatcher.
+func (a *AppStorage) Structures() *StructureCache {
+	return &StructureCache{a: a}
+}
+
+type FooterPlugSetCache struct {
+	a *AppStorage
+}
+
+func (c *FooterPlugSetCache) Fetch(ctx context.Context, id int64) (*model.FooterPlugs, error) {
+	return c.a.FetchPlugs(ctx, id)
+}
+
+func (c *FooterPlugSetCache) CacheInvalidate(_ context.Context, id int64, version int64) {
+	if fp, ok := c.a.fpCache.Peek(id); ok && fp.Version <= version {
+		c.a.fpCache.Remove(id)
+	}
+}
+
+type StructureCache struct {
+	a *AppStorage
+}
+
+func (c *StructureCache) Fetch(ctx context.Context, id int64) (*model.Structure, error) {
+	return c.a.FetchStructure(ctx, id)
+}
+
+func (c *StructureCache) CacheInvalidate(_ context.Context, id int64, version int64) {
+	if st, ok := c.a.stCache.Peek(id); ok && st.Version <= version {
+		c.a.stCache.Remove(id)
+	}
+}
@@ -0,0 +1,117 @@
+package dbcache
+
+import (
+	"context"
+	"log"
+	"sync"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/varz"
+)
+
+var (
+	displayStorageCacheHits   = varz.NewInt("displayStorageCacheHits")
+	displayStorageCacheMisses = varz.NewInt("displayStorageCacheMisses")
+)
+
+// DisplayStorage caches displays by ID.  Heartbeat fields in the cache may be
+// stale; FetchDisplays always goes to the database, so the management page
+// sees current heartbeats.
+type DisplayStorage struct {
+	cache *lru.Cache[int64, *model.Display]
+	lock  sync.Mutex
+	next  state.DisplayStorage
+}
+
+var _ state.DisplayStorage = (*DisplayStorage)(nil)
+
+func NewDisplayStorage(size int, next state.DisplayStorage) *DisplayStorage {
+	cache, err := lru.New[int64, *model.Display](size)
+	if err != nil {
+		log.Fatalf("Failed to create DisplayStorage cache: %v", err)
+	}
+	return &DisplayStorage{
+		cache: cache,
+		next:  next,
+	}
+}
+
+func (s *DisplayStorage) Fetch(ctx context.Context, id int64) (*model.Display, error) {
+	return s.FetchDisplay(ctx, id)
+}
+
+func (s *DisplayStorage) CacheInvalidate(_ context.Context, id int64, version int64) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if d, ok := s.cache.Get(id); ok {
+		if version < 0 || d.Version <= version {
+			s.cache.Remove(id)
+		}
+	}
+}
+
+func (s *DisplayStorage) CacheStore(_ context.Context, d *model.Display) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if cached, ok := s.cache.Get(d.DisplayID); ok && cached.Version > d.Version {
+		log.Printf("cache: have display version %d, incoming %d, ignoring", cached.Version, d.Version)
+		return
+	}
+	s.cache.Add(d.DisplayID, d.Clone())
+}
+
+func (s *DisplayStorage) FetchDisplays(ctx context.Context) ([]*model.Display, error) {
+	return s.next.FetchDisplays(ctx)
+}
+
+func (s *DisplayStorage) FetchDisplay(ctx context.Context, id int64) (*model.Display, error) {
+	if d, ok := s.cache.Get(id); ok {
+		displayStorageCacheHits.Add(1)
+		return d.Clone(), nil
+	}
+
+	displayStorageCacheMisses.Add(1)
+	d, err := s.next.FetchDisplay(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.CacheStore(ctx, d)
+	return d, nil
+}
+
+func (s *DisplayStorage) FetchDisplayByDeviceID(ctx context.Context, deviceID string) (*model.Display, error) {
+	d, err := s.next.FetchDisplayByDeviceID(ctx, deviceID)
+	if err != nil {
+		return nil, err
+	}
+	s.CacheStore(ctx, d)
+	return d, nil
+}
+
+func (s *DisplayStorage) RegisterDisplay(ctx context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error) {
+	d, err := s.next.RegisterDisplay(ctx, deviceID, remoteAddr, userAgent)
+	if err != nil {
+		return nil, err
+	}
+	s.CacheStore(ctx, d)
+	return d, nil
+}
+
+func (s *DisplayStorage) SaveDisplay(ctx context.Context, d *model.Display) error {
+	if err := s.next.SaveDisplay(ctx, d); err != nil {
+		return err
+	}
+	s.CacheStore(ctx, d)
+	return nil
+}
+
+func (s *DisplayStorage) DeleteDisplay(ctx context.Context, id int64) error {
+	err := s.next.DeleteDisplay(ctx, id)
+	if err == nil {
+		s.CacheInvalidate(ctx, id, -1)
+	}
+	return err
+}
@@ -2,6 +2,7 @@ package dbcache
 
 import (
 	"context"
+	"sync"
 	"time"
 
 	"github.com/ts4z/irata/model"
@@ -9,8 +10,8 @@ import (
 	"github.com/ts4z/irata/varz"
 )
 
-// Note that this assumes it is the only writer, and it is not.
-// This will be less true in the future if we have multiple app servers.
+// Other instances' writes arrive through dbnotify, which calls
+// CacheInvalidate; the TTL is there in case a notification is lost.
 
 const (
 	ttl = time.Duration(30) * time.Minute
@@ -24,6 +25,7 @@ type SiteStorage struct {
 	clock Nower
 	next  state.SiteStorage
 
+	mu           sync.Mutex
 	cachedConfig *model.SiteConfig
 	fetchedAt    time.Time
 }
@@ -44,25 +46,47 @@ func NewSiteConfigStorage(next state.SiteStorage, clock Nower) *SiteStorage {
 
 // FetchSiteConfig implements state.SiteStorage.
 func (s *SiteStorage) FetchSiteConfig(ctx context.Context) (*model.SiteConfig, error) {
+	s.mu.Lock()
 	if s.cachedConfig != nil && s.fetchedAt.Add(ttl).After(s.clock.Now()) {
+		defer s.mu.Unlock()
 		siteStorageCacheHits.Add(1)
 		return s.cachedConfig, nil
 	}
+	s.mu.Unlock()
 	siteStorageCacheMisses.Add(1)
 	config, err := s.next.FetchSiteConfig(ctx)
 	if err != nil {
 		return nil, err
 	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
 	s.fetchedAt = s.clock.Now()
 	s.cachedConfig = config
 	return config, nil
 }
 
+// Fetch is FetchSiteConfig for dbnotify.ChangeDispatcher; there's only one
+// site config, so it ignores id.
+func (s *SiteStorage) Fetch(ctx context.Context, _ int64) (*model.SiteConfig, error) {
+	return s.FetchSiteConfig(ctx)
+}
+
+// CacheInvalidate drops the cached config.  The config has no version, so
+// it always does.
+func (s *SiteStorage) CacheInvalidate(_ context.Context, _ int64, _ int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.cachedConfig = nil
+}
+
 // SaveSiteConfig implements state.SiteStorage.
 func (s *SiteStorage) SaveSiteConfig(ctx context.Context, config *model.SiteConfig) error {
 	err := s.next.SaveSiteConfig(ctx, config)
 	if err == nil {
+		s.mu.Lock()
+		defer s.mu.Unlock()
 		s.cachedConfig = config
+		s.fetchedAt = s.clock.Now()
 	}
 	return err
 }
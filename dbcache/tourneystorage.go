@@ -32,15 +32,15 @@ func (s *TournamentStorage) CreateTournament(ctx context.Context, t *model.Tourn
 // DeleteTournament implements state.TournamentStorage.
 func (s *TournamentStorage) DeleteTournament(ctx context.Context, id int64) error {
 	err := s.next.DeleteTournament(ctx, id)
-	if err != nil {
+	if err == nil {
 		s.cache.Remove(id)
 	}
 	return err
 }
 
 // FetchOverview implements state.TournamentStorage.
-func (s *TournamentStorage) FetchOverview(ctx context.Context, offset int, limit int) (*model.Overview, error) {
-	return s.next.FetchOverview(ctx, offset, limit)
+func (s *TournamentStorage) FetchOverview(ctx context.Context, lifecycles []model.Lifecycle, offset int, limit int) (*model.Overview, error) {
+	return s.next.FetchOverview(ctx, lifecycles, offset, limit)
 }
 
 // Alternate name, making this suitable for dbnotify CacheStorage interface.
@@ -3,7 +3,9 @@
 package dbnotify provides a backchannel from the database to push changes to
 models out to other locations.
 
-TODO: Deleted notifications.  Gossip supports them, we don't.  Bug.
+Every table that holds a model has a trigger that notifies <table>_changes
+on insert, update and delete, so any instance sharing the database can drop
+what it has cached and tell its clients.
 */
 
 package dbnotify
@@ -13,13 +15,18 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
-	"log"
+	"math"
+	"sync/atomic"
 	"time"
 
 	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/stdlib"
+
+	"github.com/ts4z/irata/logz"
 )
 
+var logger = logz.New()
+
 const (
 	sleepOnErrorTime = 5 * time.Second
 )
@@ -28,13 +35,33 @@ type NotificationEvent struct {
 	Table   string
 	OnID    int64
 	Version int64
+	// Deleted is set when the row is gone; Version is then the last one it had.
+	Deleted bool
+
+	// requestID is the request that made the change, when it was made here.
+	requestID string
+}
+
+// Listener hands changes to the consumers until ctx is done.
+type Listener interface {
+	Listen(ctx context.Context) error
+
+	// Listening says whether Listen is running and has started listening.
+	// Without it, caches go stale and clients aren't told about changes.
+	Listening() bool
 }
 
 type DBNotifyListener struct {
 	db                  *sql.DB
 	tableNameToConsumer map[string]Consumer
+	listening           atomic.Bool
 }
 
+var (
+	_ Listener = &DBNotifyListener{}
+	_ Listener = &LocalListener{}
+)
+
 type CacheStorage[StoredType any] interface {
 	CacheInvalidate(ctx context.Context, key int64, version int64)
 }
@@ -48,7 +75,7 @@ type ChangeConsumer interface {
 // Caller must implement.
 type ClientNotifier[StoredType any] interface {
 	NotifyUpdated(ctx context.Context, m StoredType)
-	// TODO: Add and require NotifyDeleted
+	NotifyDeleted(ctx context.Context, id int64)
 }
 
 type StorageFetcher[StoredType any] interface {
@@ -82,7 +109,7 @@ type Consumer interface {
 	Consume(ctx context.Context, event *NotificationEvent)
 }
 
-func NewDBNotifyListener(db *sql.DB, consumers ...Consumer) (*DBNotifyListener, error) {
+func consumersByTable(consumers []Consumer) (map[string]Consumer, error) {
 	m := make(map[string]Consumer)
 	for _, c := range consumers {
 		tableName := c.TableName()
@@ -91,7 +118,14 @@ func NewDBNotifyListener(db *sql.DB, consumers ...Consumer) (*DBNotifyListener,
 		}
 		m[tableName] = c
 	}
+	return m, nil
+}
 
+func NewDBNotifyListener(db *sql.DB, consumers ...Consumer) (*DBNotifyListener, error) {
+	m, err := consumersByTable(consumers)
+	if err != nil {
+		return nil, err
+	}
 	return &DBNotifyListener{db: db, tableNameToConsumer: m}, nil
 }
 
@@ -101,6 +135,10 @@ func (cl *DBNotifyListener) Close() error {
 	return err
 }
 
+func (cl *DBNotifyListener) Listening() bool {
+	return cl.listening.Load()
+}
+
 func (cl *DBNotifyListener) Listen(ctx context.Context) error {
 	conn, err := cl.db.Conn(ctx)
 	if err != nil {
@@ -131,10 +169,13 @@ func (cl *DBNotifyListener) Listen(ctx context.Context) error {
 
 	ch := make(chan *NotificationEvent)
 	defer close(ch)
-	go cl.consumeEvents(ctx, ch)
+	go consumeEvents(ctx, cl.tableNameToConsumer, ch)
+
+	cl.listening.Store(true)
+	defer cl.listening.Store(false)
 
 	for {
-		log.Printf("(awaiting db notifications...)")
+		logger.DebugContext(ctx, "awaiting db notifications")
 		var notification *pgconn.Notification
 		if nf, err := pgxConn.Conn().WaitForNotification(ctx); err == nil {
 			notification = nf
@@ -145,11 +186,11 @@ func (cl *DBNotifyListener) Listen(ctx context.Context) error {
 			return fmt.Errorf("error waiting for notification: %w", err)
 		}
 
-		log.Printf("(received db notification %d %s)", notification.PID, string(notification.Payload))
+		logger.DebugContext(ctx, "received db notification", "pid", notification.PID, "payload", notification.Payload)
 
 		event := &NotificationEvent{}
 		if err := json.Unmarshal([]byte(notification.Payload), &event); err != nil {
-			log.Printf("can't unmarshal notification payload '%s': %v", notification.Payload, err)
+			logger.ErrorContext(ctx, "can't unmarshal notification payload", "payload", notification.Payload, "err", err)
 			time.Sleep(sleepOnErrorTime)
 			continue
 		}
@@ -158,21 +199,28 @@ func (cl *DBNotifyListener) Listen(ctx context.Context) error {
 	}
 }
 
-func (cd *DBNotifyListener) consumeEvents(ctx context.Context, ch <-chan *NotificationEvent) {
+func consumeEvents(ctx context.Context, consumers map[string]Consumer, ch <-chan *NotificationEvent) {
 	for {
 		select {
 		case <-ctx.Done():
 			// Unclear if we need this; we are already reading on a channel that can close.
-			log.Printf("stopping DBChangeListener consumeEvents due to context done: %v", ctx.Err())
+			logger.InfoContext(ctx, "stopping consumeEvents due to context done", "err", ctx.Err())
 			return
-		case event := <-ch:
-			log.Printf("received db notification event: %+v", event)
+		case event, ok := <-ch:
+			if !ok {
+				return
+			}
+			ctx := ctx
+			if event.requestID != "" {
+				ctx = logz.WithRequestID(ctx, event.requestID)
+			}
+			logger.DebugContext(ctx, "received db notification event", "event", event)
 			go func() {
-				listener, ok := cd.tableNameToConsumer[event.Table]
+				listener, ok := consumers[event.Table]
 				if ok {
 					listener.Consume(ctx, event)
 				} else {
-					log.Printf("no listener for table %s", event.Table)
+					logger.WarnContext(ctx, "no listener for table", "table", event.Table)
 				}
 			}()
 		}
@@ -180,12 +228,22 @@ func (cd *DBNotifyListener) consumeEvents(ctx context.Context, ch <-chan *Notifi
 }
 
 func (cd *ChangeDispatcher[StoredType]) Consume(ctx context.Context, event *NotificationEvent) {
+	if event.Deleted {
+		// Nothing cached can be newer than a deleted row.
+		cd.cacheStorage.CacheInvalidate(ctx, event.OnID, math.MaxInt64)
+		if cd.clientNotifier != nil {
+			cd.clientNotifier.NotifyDeleted(ctx, event.OnID)
+		}
+		return
+	}
+
 	cd.cacheStorage.CacheInvalidate(ctx, event.OnID, event.Version)
 
 	// Read-through.
 	item, err := cd.fetcher.Fetch(ctx, event.OnID)
 	if err != nil {
-		log.Printf("drop notiication: can't fetch item %s %d: %v", cd.tableName, event.OnID, err)
+		logger.WarnContext(ctx, "drop notification: can't fetch item", "table", cd.tableName, "id", event.OnID, "err", err)
+		return
 	}
 
 	if cd.clientNotifier != nil {
@@ -0,0 +1,69 @@
+package dbnotify
+
+import (
+	"context"
+	"sync/atomic"
+
+	"github.com/ts4z/irata/logz"
+)
+
+// localQueueSize is how many changes can be waiting for Listen before
+// Notify starts dropping them.
+const localQueueSize = 1024
+
+// LocalListener is for databases that can't send notifications, like
+// SQLite, where the only writer is this process anyway.  Storage calls
+// Notify after each change it commits, and Listen hands the changes to the
+// consumers just as DBNotifyListener does for Postgres.
+type LocalListener struct {
+	tableNameToConsumer map[string]Consumer
+	ch                  chan *NotificationEvent
+	listening           atomic.Bool
+}
+
+func NewLocalListener(consumers ...Consumer) (*LocalListener, error) {
+	m, err := consumersByTable(consumers)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalListener{
+		tableNameToConsumer: m,
+		ch:                  make(chan *NotificationEvent, localQueueSize),
+	}, nil
+}
+
+// Notify queues a change.  It never blocks a write: if nobody is
+// listening, or the listener has fallen far behind, the change is dropped,
+// and clients catch up when they next re-sync.
+func (l *LocalListener) Notify(ctx context.Context, table string, id, version int64) {
+	l.queue(ctx, &NotificationEvent{Table: table, OnID: id, Version: version})
+}
+
+// NotifyDeleted queues a deletion, the same way.
+func (l *LocalListener) NotifyDeleted(ctx context.Context, table string, id int64) {
+	l.queue(ctx, &NotificationEvent{Table: table, OnID: id, Deleted: true})
+}
+
+func (l *LocalListener) queue(ctx context.Context, event *NotificationEvent) {
+	event.requestID = logz.RequestID(ctx)
+	// Storage reports every table; only queue what someone wants.
+	if _, ok := l.tableNameToConsumer[event.Table]; !ok {
+		return
+	}
+	select {
+	case l.ch <- event:
+	default:
+		logger.WarnContext(ctx, "dropped local notification", "event", event)
+	}
+}
+
+func (l *LocalListener) Listening() bool {
+	return l.listening.Load()
+}
+
+func (l *LocalListener) Listen(ctx context.Context) error {
+	l.listening.Store(true)
+	defer l.listening.Store(false)
+	consumeEvents(ctx, l.tableNameToConsumer, l.ch)
+	return ctx.Err()
+}
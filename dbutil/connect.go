@@ -6,8 +6,10 @@ import (
 	"log"
 	"maps"
 	"slices"
+	"strings"
 
 	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "modernc.org/sqlite"
 
 	"github.com/ts4z/irata/config"
 )
@@ -21,6 +23,36 @@ func connectWithPgx() (*sql.DB, error) {
 	return sql.Open("pgx", url)
 }
 
+// sqlitePragmas are set on every SQLite connection.  WAL lets readers carry
+// on while someone writes; writers wait their turn rather than failing, and
+// take the write lock when their transaction begins, since SQLite can't
+// upgrade a read transaction if someone else got there first.
+const sqlitePragmas = "_pragma=foreign_keys(1)&_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_txlock=immediate"
+
+func connectWithSQLite() (*sql.DB, error) {
+	url := config.DBURL()
+	log.Printf("Opening SQLite database %s", url)
+	if url == "" {
+		return nil, errors.New("database URL is empty")
+	}
+	return OpenSQLite(url)
+}
+
+// OpenSQLite opens the SQLite database name, which is a file name or a
+// file: URI.  ("file:demo?mode=memory&cache=shared" is a database in
+// memory.)
+func OpenSQLite(name string) (*sql.DB, error) {
+	if !strings.HasPrefix(name, "file:") {
+		name = "file:" + name
+	}
+	if strings.Contains(name, "?") {
+		name += "&" + sqlitePragmas
+	} else {
+		name += "?" + sqlitePragmas
+	}
+	return sql.Open("sqlite", name)
+}
+
 // Connect establishes a database connection using the configured SQL connector.
 //
 // A previouis version of this code supported the Google Cloud connector.  That
@@ -29,7 +61,8 @@ func connectWithPgx() (*sql.DB, error) {
 // the various GCP libraries.  Since we're not using it, better not have it.
 func Connect() (*sql.DB, error) {
 	factories := map[string]func() (*sql.DB, error){
-		"pgx": connectWithPgx,
+		"pgx":    connectWithPgx,
+		"sqlite": connectWithSQLite,
 	}
 	factory, ok := factories[config.SQLConnector()]
 	if !ok {
@@ -0,0 +1,24 @@
+package dbutil
+
+import (
+	"database/sql"
+
+	"modernc.org/sqlite"
+)
+
+// Dialect is which SQL database is on the other end.  Most SQL in irata is
+// written to work on both; the schema and change notification aren't.
+type Dialect string
+
+const (
+	Postgres Dialect = "postgres"
+	SQLite   Dialect = "sqlite"
+)
+
+// DialectOf tells which dialect db speaks, from its driver.
+func DialectOf(db *sql.DB) Dialect {
+	if _, ok := db.Driver().(*sqlite.Driver); ok {
+		return SQLite
+	}
+	return Postgres
+}
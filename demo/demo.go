@@ -0,0 +1,129 @@
+// Package demo is irata with nothing to set up: state.MemStorage, loaded
+// with the examples from example.sql and an administrator, that is gone
+// when the server stops.  It's for trying out structures, showing
+// irata to a new club, and tests that want a whole working backend.
+package demo
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/password"
+	"github.com/ts4z/irata/state"
+)
+
+// AdminNick is the administrator CreateAdmin makes.
+const AdminNick = "admin"
+
+// Seed loads the examples into s, which should be empty, and gives the site
+// a cookie key so people can log in.
+func Seed(ctx context.Context, s state.Storage, now time.Time) error {
+	config, err := s.FetchSiteConfig(ctx)
+	if err != nil {
+		return err
+	}
+	config.Name = siteConfig.Name
+	config.Theme = siteConfig.Theme
+	key, err := newCookieKey(now)
+	if err != nil {
+		return err
+	}
+	config.CookieKeys = []model.CookieKeyPair{key}
+	if err := s.SaveSiteConfig(ctx, config); err != nil {
+		return fmt.Errorf("saving site config: %w", err)
+	}
+
+	plugsID, err := s.CreateFooterPlugSet(ctx, footerPlugSetName, footerPlugs)
+	if err != nil {
+		return fmt.Errorf("creating footer plugs: %w", err)
+	}
+	for _, st := range structures {
+		cpy := *st
+		if _, err := s.CreateStructure(ctx, &cpy); err != nil {
+			return fmt.Errorf("creating structure %q: %w", st.Name, err)
+		}
+	}
+	for _, l := range layouts {
+		if _, err := s.CreateLayout(ctx, l.Clone()); err != nil {
+			return fmt.Errorf("creating layout %q: %w", l.Name, err)
+		}
+	}
+	slideIDs := []int64{}
+	for _, sl := range slides {
+		id, err := s.CreateSlide(ctx, sl.Clone())
+		if err != nil {
+			return fmt.Errorf("creating slide %q: %w", sl.Name, err)
+		}
+		slideIDs = append(slideIDs, id)
+	}
+	for _, ss := range slideSets {
+		cpy := *ss
+		cpy.Entries = nil
+		for _, e := range ss.Entries {
+			cpy.Entries = append(cpy.Entries, model.SlideSetEntry{SlideID: slideIDs[e.SlideID], Trigger: e.Trigger})
+		}
+		if _, err := s.CreateSlideSet(ctx, &cpy); err != nil {
+			return fmt.Errorf("creating slide set %q: %w", ss.Name, err)
+		}
+	}
+	for _, l := range leagues {
+		if _, err := s.CreateLeague(ctx, l.Clone()); err != nil {
+			return fmt.Errorf("creating league %q: %w", l.Name, err)
+		}
+	}
+	for _, tt := range templates {
+		cpy := *tt
+		cpy.Tournament = tt.Tournament.Clone()
+		cpy.Tournament.FooterPlugsID = plugsID
+		if _, err := s.CreateTournamentTemplate(ctx, &cpy); err != nil {
+			return fmt.Errorf("creating template %q: %w", tt.Name, err)
+		}
+	}
+	for _, t := range tournaments {
+		cpy := t.Clone()
+		cpy.FooterPlugsID = plugsID
+		if _, err := s.CreateTournament(ctx, cpy); err != nil {
+			return fmt.Errorf("creating tournament %q: %w", t.EventName, err)
+		}
+	}
+	return nil
+}
+
+// CreateAdmin makes the administrator, AdminNick, with a new random
+// password, which it returns.
+func CreateAdmin(ctx context.Context, s state.Storage) (string, error) {
+	b := make([]byte, 9)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	pw := base64.RawURLEncoding.EncodeToString(b)
+	if err := s.CreateUserWithEmailAndPassword(ctx, AdminNick, AdminNick+"@example.com", password.Hash(pw), true); err != nil {
+		return "", fmt.Errorf("creating %s: %w", AdminNick, err)
+	}
+	return pw, nil
+}
+
+// newCookieKey makes a key good for as long as any demo runs.
+func newCookieKey(now time.Time) (model.CookieKeyPair, error) {
+	hashKey := make([]byte, 32)
+	blockKey := make([]byte, 16)
+	if _, err := rand.Read(hashKey); err != nil {
+		return model.CookieKeyPair{}, err
+	}
+	if _, err := rand.Read(blockKey); err != nil {
+		return model.CookieKeyPair{}, err
+	}
+	return model.CookieKeyPair{
+		Validity: model.CookieKeyValidity{
+			MintFrom:   now,
+			MintUntil:  now.AddDate(10, 0, 0),
+			HonorUntil: now.AddDate(10, 0, 0),
+		},
+		HashKey64:  base64.StdEncoding.EncodeToString(hashKey),
+		BlockKey64: base64.StdEncoding.EncodeToString(blockKey),
+	}, nil
+}
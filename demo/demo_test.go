@@ -0,0 +1,89 @@
+package demo
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/ts4z/irata/password"
+	"github.com/ts4z/irata/state"
+)
+
+func TestSeed(t *testing.T) {
+	ctx := context.Background()
+	s := state.NewMemStorage()
+	now := time.Now()
+	if err := Seed(ctx, s, now); err != nil {
+		t.Fatalf("Seed: %v", err)
+	}
+	pw, err := CreateAdmin(ctx, s)
+	if err != nil {
+		t.Fatalf("CreateAdmin: %v", err)
+	}
+
+	counts, err := s.CountRows(ctx)
+	if err != nil {
+		t.Fatalf("CountRows: %v", err)
+	}
+	for table, want := range map[string]int64{
+		"tournaments":          int64(len(tournaments)),
+		"structures":           int64(len(structures)),
+		"footer_plug_sets":     1,
+		"layouts":              int64(len(layouts)),
+		"slides":               int64(len(slides)),
+		"slide_sets":           int64(len(slideSets)),
+		"tournament_templates": int64(len(templates)),
+		"leagues":              int64(len(leagues)),
+		"users":                1,
+	} {
+		if counts[table] != want {
+			t.Errorf("%d %s, want %d", counts[table], table, want)
+		}
+	}
+
+	overview, err := s.FetchOverview(ctx, nil, 0, 10)
+	if err != nil {
+		t.Fatalf("FetchOverview: %v", err)
+	}
+	for _, slug := range overview.Slugs {
+		tm, err := s.FetchTournament(ctx, slug.TournamentID)
+		if err != nil {
+			t.Fatalf("FetchTournament: %v", err)
+		}
+		if plugs, err := s.FetchPlugs(ctx, tm.FooterPlugsID); err != nil || len(plugs.Plugs) != len(footerPlugs) {
+			t.Errorf("%s has footer plugs %d: %v", tm.EventName, tm.FooterPlugsID, err)
+		}
+	}
+
+	sets, err := s.FetchSlideSets(ctx)
+	if err != nil {
+		t.Fatalf("FetchSlideSets: %v", err)
+	}
+	for _, e := range sets[0].Entries {
+		if _, err := s.FetchSlide(ctx, e.SlideID); err != nil {
+			t.Errorf("slide set has slide %d: %v", e.SlideID, err)
+		}
+	}
+
+	row, err := s.FetchUserRow(ctx, AdminNick)
+	if err != nil {
+		t.Fatalf("FetchUserRow: %v", err)
+	}
+	checker, err := password.NewChecker(clockwork.NewRealClock(), row)
+	if err != nil {
+		t.Fatalf("NewChecker: %v", err)
+	}
+	if _, err := checker.Validate(pw); err != nil || !row.IsAdmin {
+		t.Errorf("admin can't log in: %v (admin %v)", err, row.IsAdmin)
+	}
+
+	config, err := s.FetchSiteConfig(ctx)
+	if err != nil {
+		t.Fatalf("FetchSiteConfig: %v", err)
+	}
+	if len(config.CookieKeys) != 1 || !config.CookieKeys[0].Validity.MintUntil.After(now) {
+		t.Errorf("cookie keys = %+v", config.CookieKeys)
+	}
+}
@@ -0,0 +1,240 @@
+package demo
+
+import (
+	"time"
+
+	"github.com/ts4z/irata/model"
+)
+
+// These are example.sql, in Go.  References between them are by position
+// in these lists; Seed turns them into IDs.
+
+var siteConfig = model.SiteConfig{
+	Name:  "Irata Poker Tournament Clock",
+	Theme: "irata",
+}
+
+// tournaments all use the footer plugs.
+var tournaments = []*model.Tournament{&peterBARGE, &mainEvent}
+
+var peterBARGE = model.Tournament{
+	EventName:   "PeterBARGE",
+	Description: "$100 Freezeout at Pinball Pirate",
+	Structure: model.StructureData{
+		ChipsPerBuyIn: 3000,
+		Levels: []*model.Level{
+			{Banner: "PeterBARGE 3D", Description: "SU & DEAL @ 11:00AM", DurationMinutes: 59, IsBreak: true},
+			{Banner: "LEVEL 1", Description: "BLINDS 25-50", DurationMinutes: 18},
+			{Banner: "LEVEL 2", Description: "BLINDS 50-75", DurationMinutes: 18},
+			{Banner: "LEVEL 3", Description: "BLINDS 50-100", DurationMinutes: 18},
+			{Banner: "LEVEL 4", Description: "BLINDS 75-150", DurationMinutes: 18},
+			{Banner: "LEVEL 5", Description: "BLINDS 100-200", DurationMinutes: 18},
+			{Banner: "BREAK", Description: "PICTURE TIME & REMOVE 25s", DurationMinutes: 20, IsBreak: true},
+			{Banner: "LEVEL 6", Description: "BLINDS 200-300", DurationMinutes: 18},
+			{Banner: "LEVEL 7", Description: "BLINDS 200-400", DurationMinutes: 18},
+			{Banner: "LEVEL 8", Description: "BLINDS 300-600", DurationMinutes: 18},
+			{Banner: "LEVEL 9", Description: "BLINDS 500-1000", DurationMinutes: 18},
+			{Banner: "LEVEL 10", Description: "BLINDS 800-1600", DurationMinutes: 18},
+			{Banner: "BREAK", Description: "REMOVE 100s", DurationMinutes: 5, IsBreak: true},
+			{Banner: "LEVEL 11", Description: "BLINDS 1500-2500", DurationMinutes: 18},
+			{Banner: "LEVEL 12", Description: "BLINDS 2K-4K", DurationMinutes: 18},
+			{Banner: "LEVEL 13?!", Description: "BLINDS 3K-6K", DurationMinutes: 18},
+			{Banner: "LEVEL 14?!!", Description: "BLINDS 5K-10K", DurationMinutes: 18},
+			{Banner: "LEVEL 15?!?!!", Description: "BLINDS 6K-12K", DurationMinutes: 18},
+			{Banner: "LEVEL 16!!????", Description: "BLINDS 8K-16K", DurationMinutes: 18},
+			{Banner: "LEVEL 17 (sigh)", Description: "BLINDS 10K-20K", DurationMinutes: 18},
+			{Banner: "GO HOME ALREADY", Description: "BLINDS 55000-55000", DurationMinutes: 59},
+		},
+	},
+	State: &model.State{
+		CurrentPlayers: 33,
+		BuyIns:         33,
+		PrizePool:      "Yadda\nYadda\nYadda",
+	},
+}
+
+var mainEvent = model.Tournament{
+	EventName:   "WSOP #61 MAIN EVENT",
+	Description: "The Big Dance",
+	Structure: model.StructureData{
+		ChipsPerBuyIn: 60000,
+		Levels: []*model.Level{
+			{Banner: "SETTING UP", Description: "PLAYERS: TAKE YOUR SEATS", DurationMinutes: 60, IsBreak: true},
+			{Banner: "LEVEL 1 - NO LIMIT TEXAS HOLDEM", Description: "BLINDS 100-100 w/100 BB ANTE", DurationMinutes: 120},
+			{Banner: "LEVEL 2 - NO LIMIT TEXAS HOLDEM", Description: "BLINDS 100-200 w/200 BB ANTE", DurationMinutes: 120},
+			{Banner: "LEVEL 3 - NO LIMIT TEXAS HOLDEM", Description: "BLINDS 200-300 w/300 BB ANTE", DurationMinutes: 120},
+			{Banner: "LEVEL 3 - NO LIMIT TEXAS HOLDEM", Description: "BLINDS 200-400 w/400 BB ANTE", DurationMinutes: 120},
+		},
+	},
+	State: &model.State{
+		CurrentPlayers: 33,
+		BuyIns:         33,
+		PrizePool:      "1..$10,000,000\n2...$5,000,000\n3...$3,000,000\n......",
+	},
+}
+
+var structures = []*model.Structure{
+	{
+		Name: "BREMER 3000",
+		StructureData: model.StructureData{
+			Levels: []*model.Level{
+				{Banner: "WELCOME TO THE EVENT", Description: "AWAITING START...", DurationMinutes: 60, IsBreak: true},
+				{Banner: "LEVEL 1", Description: "25-50 + 50 ANTE", DurationMinutes: 18},
+				{Banner: "LEVEL 2", Description: "50-75 + 75 ANTE", DurationMinutes: 18},
+				{Banner: "LEVEL 3", Description: "50-100 + 100 ANTE", DurationMinutes: 18},
+				{Banner: "LEVEL 4", Description: "75-150 + 150 ANTE", DurationMinutes: 18},
+				{Banner: "LEVEL 5", Description: "100-200 + 200 ANTE", DurationMinutes: 18},
+				{Banner: "LEVEL 6", Description: "PICTURE TIME", DurationMinutes: 20, IsBreak: true},
+				{Banner: "LEVEL 7", Description: "200-300 + 300 ANTE", DurationMinutes: 18},
+				{Banner: "LEVEL 8", Description: "200-400 + 400 ANTE", DurationMinutes: 18},
+			},
+		},
+	},
+}
+
+const footerPlugSetName = "Mostly BARGE In-Jokes"
+
+var footerPlugs = []model.FooterPlug{
+	{Kind: model.FooterPlugKindText, Text: "\"There are no strangers here,\njust friends\nyou haven't met yet.\"\n-Peter Secor"},
+	{Kind: model.FooterPlugKindText, Text: "THANK YOU MARIO!\nBUT OUR PRINCESS\n IS IN ANOTHER CASTLE!"},
+	{Kind: model.FooterPlugKindText, Text: "I am a lucky player;\na powerful winning force\nsurrounds me.\n-Mike Caro"},
+	{Kind: model.FooterPlugKindText, Text: "this space intentionally left blank"},
+	{Kind: model.FooterPlugKindText, Text: "SPONSORED BY PINBALLPIRATE.COM"},
+	{Kind: model.FooterPlugKindText, Text: "SPONSORED BY TS4Z.NET"},
+	{Kind: model.FooterPlugKindText, Text: "NOT SPONSORED BY\nPOKERSTARS.COM"},
+	{Kind: model.FooterPlugKindText, Text: "WWW.BARGE.ORG"},
+	{Kind: model.FooterPlugKindText, Text: "WWW.BJRGE.ORG"},
+	{Kind: model.FooterPlugKindText, Text: "FARGOPOKER.ORG"},
+	{Kind: model.FooterPlugKindText, Text: "ATLARGEPOKER.COM"},
+	{Kind: model.FooterPlugKindText, Text: "ARGEMPOKER.COM"},
+	{Kind: model.FooterPlugKindText, Text: "PETER.BARGE.ORG"},
+	{Kind: model.FooterPlugKindText, Text: "CRAFTPOKER.COM"},
+	{Kind: model.FooterPlugKindText, Text: "BARGECHIPS.ORG"},
+	{Kind: model.FooterPlugKindText, Text: "this space for rent"},
+	{Kind: model.FooterPlugKindText, Text: "\"COCKTAILS!\""},
+	{Kind: model.FooterPlugKindText, Text: "WABOR"},
+	{Kind: model.FooterPlugKindText, Text: "WHEN IN NEW YORK...\nVISIT THE MAYFAIR CLUB"},
+	{Kind: model.FooterPlugKindText, Text: "WHEN IN PARIS...\nVISIT THE AVIATION CLUB"},
+	{Kind: model.FooterPlugKindText, Text: "May the flop be with you.\n-Doyle Brunson"},
+	{Kind: model.FooterPlugKindText, Text: "Don't you know who **I** am?\n-Phil Gordon"},
+	{Kind: model.FooterPlugKindText, Text: "WHO BUT W.B. MASON?"},
+	{Kind: model.FooterPlugKindText, Text: "It is morally wrong to allow\nsuckers to keep their money.\n-\"Canada Bill\" Jones"},
+	{Kind: model.FooterPlugKindText, Text: "May all your cards be\nlive and all your\npots be monsters.\n-Mike Sexton"},
+	{Kind: model.FooterPlugKindText, Text: "MAKE SEVEN - UP YOURS"},
+	{Kind: model.FooterPlugKindText, Text: "\"Daddy, I got cider in my ear\"\n-Sky Masterson,\nin Guys and Dolls"},
+	{Kind: model.FooterPlugKindText, Text: "Trust everyone,\nbut always\ncut the cards.\n-Benny Binion"},
+	{Kind: model.FooterPlugKindText, Text: "Poker is a hard way to\nmake an easy living.\n-Doyle Brunson"},
+	{Kind: model.FooterPlugKindText, Text: "The object of poker is to\nkeep your money away from\nPhil Ivey\nfor as long as possible.\n-Gus Hansen"},
+	{Kind: model.FooterPlugKindText, Text: "To be a poker champion,\nyou must have a strong bladder.\n-Jack McClelland"},
+	{Kind: model.FooterPlugKindText, Text: "No-limit hold’em:\nHours of boredom\n followed by moments of sheer terror.\n -Tom McEvoy"},
+	{Kind: model.FooterPlugKindText, Text: "Please don't tap on the aquarium."},
+	{Kind: model.FooterPlugKindText, Text: "The rule is this:\nyou spot a\nman's tell, you don't\nsay a fucking word.\n-Mike McDermott, in Rounders"},
+	{Kind: model.FooterPlugKindText, Text: "A Smith & Wesson\nbeats four aces.\n-\"Canada Bill\" Jones"},
+	{Kind: model.FooterPlugKindText, Text: "Pay that man his money.\n-Teddy KGB, in Rounders"},
+	{Kind: model.FooterPlugKindText, Text: "You win some,\nyou lose some,\nand you keep\nit to yourself.\n-Mike Caro"},
+	{Kind: model.FooterPlugKindText, Text: "If you speak the truth,\nyou spoil the game.\n-Mike Caro"},
+	{Kind: model.FooterPlugKindText, Text: "In the beginning,\neverything was\neven money.\n-Mike Caro"},
+	{Kind: model.FooterPlugKindText, Text: "It's hard to convince\na winner that he's losing.\n-Mike Caro"},
+	{Kind: model.FooterPlugKindText, Text: "If an opponent\nwon't watch you bet,\nthen you\nprobably shouldn't.\n-Mike Caro"},
+	{Kind: model.FooterPlugKindText, Text: "Just play every hand,\nyou can’t miss them all.\n-Sammy Farha"},
+	{Kind: model.FooterPlugKindText, Text: "Last night\nI stayed\nup late playing\npoker\nwith Tarot cards.\nI got a full house\nand four\npeople died.\n-Steven Wright"},
+	{Kind: model.FooterPlugKindText, Text: "Going on tilt\nis not \n\"mixing up your play.\"\n-Steve Badger"},
+	{Kind: model.FooterPlugKindText, Text: "The guy who invented\npoker was bright,\nbut the guy who\ninvented the chip\nwas a genius.\n-\"Big Julie\" Weintraub"},
+	{Kind: model.FooterPlugKindText, Text: "Sex is good,\nthey say,\nbut poker lasts longer.\n-Al Alvarez"},
+	{Kind: model.FooterPlugKindText, Text: "Money won\nis twice as sweet\nas money earned.\n-\"Fast Eddie\" Felson\nin The Color of Money"},
+	{Kind: model.FooterPlugKindText, Text: "Fold and live\nto fold again. -Stu Ungar"},
+	{Kind: model.FooterPlugKindText, Text: "Life is not\nalways a matter\nof holding\ngood cards, but\nsometimes,\nplaying a\npoor hand\nwell.\n-Jack London"},
+	{Kind: model.FooterPlugKindText, Text: "The lack of money is the\nroot of all evil.\n-Mark Twain"},
+	{Kind: model.FooterPlugKindText, Text: "Learning to\nplay two pairs\ncorrectly is as difficult\nas getting\na college education,\nand just as expensive.\n-Mark Twain"},
+	{Kind: model.FooterPlugKindText, Text: "You're not going\nto like this,\nNolan."},
+	{Kind: model.FooterPlugKindText, Text: "I toss a chip to the dealer.\nDealer: \"What's this for?\"\nMe: \"You laughed at my dumb joke.\"\nDealer: \"Appreciate it.\" -QB"},
+	{Kind: model.FooterPlugKindText, Text: "Gillian: \"So Dan,\nhow does\nthis work?\n\"Deadhead: \"Dan puts\nout chips.\nPeople take 'em.\"\n-as reported by QB"},
+	{Kind: model.FooterPlugKindText, Text: "Here's the thing about poker...\nnobody gives a shit.\n-Dan Goldman"},
+	{Kind: model.FooterPlugKindText, Text: "It cost me a couple\nmillion dollars\nto develop\nthis reputation.\n-Daniel Negreanu,\non being known to be\nhard-to-bluff"},
+	{Kind: model.FooterPlugKindText, Text: "\"But it's a great game!\"\n\"Yeah, it's a great game\nbecause YOU'RE in it!\"\n-Daniel Negreanu"},
+	{Kind: model.FooterPlugKindText, Text: "This is my third rodeo."},
+	{Kind: model.FooterPlugKindQRCode, Text: "WWW.BARGE.ORG", URL: "https://www.barge.org/", Weight: 3},
+	{Kind: model.FooterPlugKindMarkdown, Text: "**SHUFFLE UP**\n\nand deal"},
+}
+
+var layouts = []*model.Layout{
+	{
+		Name: "Payouts and seating",
+		Landscape: model.LayoutVariant{
+			Areas:   []string{"clock clock payouts", "blinds blinds payouts", "seating footer stats"},
+			Columns: "1fr 1fr 1fr",
+			Rows:    "3fr 1fr 2fr",
+		},
+		Portrait: model.LayoutVariant{
+			Areas: []string{"clock clock", "blinds blinds", "payouts stats", "seating seating"},
+			Rows:  "3fr 1fr 3fr 2fr",
+		},
+	},
+}
+
+var slides = []*model.Slide{
+	{Name: "Welcome", Kind: model.SlideKindMarkdown, Markdown: "# Welcome\n\nPlease silence your phones.", DurationSeconds: 15},
+	{Name: "Payouts", Kind: model.SlideKindPayouts, DurationSeconds: 20},
+	{Name: "Coming up", Kind: model.SlideKindStructure, DurationSeconds: 10},
+	{Name: "Chip leaders", Kind: model.SlideKindLeaderboard, DurationSeconds: 20},
+}
+
+// slideSets' entries give a SlideID that is an index into slides.
+var slideSets = []*model.SlideSet{
+	{
+		Name: "Breaks and level changes",
+		Entries: []model.SlideSetEntry{
+			{SlideID: 0, Trigger: model.SlideTriggerRotation},
+			{SlideID: 3, Trigger: model.SlideTriggerRotation},
+			{SlideID: 1, Trigger: model.SlideTriggerBreak},
+			{SlideID: 3, Trigger: model.SlideTriggerBreak},
+			{SlideID: 2, Trigger: model.SlideTriggerLevelStart},
+		},
+	},
+}
+
+// templates' tournaments use the footer plugs.
+var templates = []*model.TournamentTemplate{
+	{
+		Name: "Thursday Turbo",
+		Tournament: &model.Tournament{
+			EventName:   "Thursday Turbo",
+			Description: "$40 Turbo with one add-on",
+			Structure: model.StructureData{
+				ChipsPerBuyIn: 5000,
+				ChipsPerAddOn: 5000,
+				Levels: []*model.Level{
+					{Banner: "LEVEL 1", Description: "BLINDS 25-50", DurationMinutes: 12},
+					{Banner: "LEVEL 2", Description: "BLINDS 50-100", DurationMinutes: 12},
+					{Banner: "LEVEL 3", Description: "BLINDS 100-200", DurationMinutes: 12},
+					{Banner: "BREAK", Description: "ADD-ONS", DurationMinutes: 10, IsBreak: true},
+					{Banner: "LEVEL 4", Description: "BLINDS 200-400", DurationMinutes: 12},
+					{Banner: "LEVEL 5", Description: "BLINDS 300-600", DurationMinutes: 12},
+					{Banner: "LEVEL 6", Description: "BLINDS 500-1000", DurationMinutes: 12},
+				},
+			},
+			State: &model.State{
+				AutoComputePrizePool: true,
+			},
+		},
+		Recurrence: model.Recurrence{
+			Frequency: model.RecurrenceWeekly,
+			Weekdays:  []time.Weekday{time.Thursday},
+			StartTime: "19:00",
+		},
+	},
+}
+
+var leagues = []*model.League{
+	{
+		Name:        "Home League",
+		Season:      "2026",
+		Description: "Thursday Turbos count toward the season",
+		Formula: model.PointsFormula{
+			Kind:          model.PointsSqrt,
+			Scale:         10,
+			Participation: 1,
+		},
+		CountBest: 10,
+	},
+}
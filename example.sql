@@ -1,15 +1,18 @@
-INSERT INTO site_info (key, value) VALUES
-  ('conf', $json$
+-- Load after "irataadmin db migrate", which makes a plain site config.
+-- demo/fixtures.go has the same examples in Go, for iratad --demo; keep them in step.
+INSERT INTO site_config (id, value) VALUES
+  (1, $json$
     {
       "Name": "Irata Poker Tournament Clock",
       "Site": "iratapoker.com",
       "Theme": "irata"
     }
-  $json$);
+  $json$)
+  ON CONFLICT (id) DO UPDATE SET value = EXCLUDED.value;
 
-INSERT INTO tournaments (tournament_id, handle, model_data) 
+INSERT INTO tournaments (tournament_id, model_data)
 OVERRIDING SYSTEM VALUE
-VALUES (1, 'peterbarge', $json$
+VALUES (1, $json$
     {
        "EventName": "PeterBARGE",
        "Description": "$100 Freezeout at Pinball Pirate",
@@ -51,9 +54,9 @@ VALUES (1, 'peterbarge', $json$
     }
     $json$);
 
-INSERT INTO tournaments (tournament_id, handle, model_data) 
+INSERT INTO tournaments (tournament_id, model_data)
 OVERRIDING SYSTEM VALUE
-VALUES (2, 'main', $json$
+VALUES (2, $json$
     {
        "EventName": "WSOP #61 MAIN EVENT",
        "Description": "The Big Dance",
@@ -148,7 +151,8 @@ VALUES (1, 'BREMER 3000', $json$
 INSERT INTO footer_plug_sets (id, name) OVERRIDING SYSTEM VALUE VALUES (1, 'Mostly BARGE In-Jokes') ;
 
 -- Insert plugs (each plug as a separate row)
-INSERT INTO text_footer_plugs (footer_plug_set_id, text) VALUES
+INSERT INTO footer_plugs (footer_plug_set_id, model_data)
+SELECT set_id, jsonb_build_object('Kind', 'text', 'Text', text) FROM (VALUES
 (1, '"There are no strangers here,
 just friends
 you haven''t met yet."
@@ -326,4 +330,112 @@ hard-to-bluff'),
 "Yeah, it''s a great game
 because YOU''RE in it!"
 -Daniel Negreanu'),
-(1, 'This is my third rodeo.');
+(1, 'This is my third rodeo.')
+) AS plugs(set_id, text);
+
+-- A few plugs that aren't plain text.
+INSERT INTO footer_plugs (footer_plug_set_id, model_data) VALUES
+(1, '{"Kind": "qr", "Text": "WWW.BARGE.ORG", "URL": "https://www.barge.org/", "Weight": 3}'),
+(1, '{"Kind": "markdown", "Text": "**SHUFFLE UP**\n\nand deal"}');
+
+INSERT INTO layouts (layout_id, model_data)
+OVERRIDING SYSTEM VALUE
+VALUES (1, $json$
+    {
+       "Name": "Payouts and seating",
+       "Landscape": {
+          "Areas": ["clock clock payouts", "blinds blinds payouts", "seating footer stats"],
+          "Columns": "1fr 1fr 1fr",
+          "Rows": "3fr 1fr 2fr"
+       },
+       "Portrait": {
+          "Areas": ["clock clock", "blinds blinds", "payouts stats", "seating seating"],
+          "Rows": "3fr 1fr 3fr 2fr"
+       }
+    }
+    $json$);
+
+INSERT INTO slides (slide_id, model_data)
+OVERRIDING SYSTEM VALUE
+VALUES
+(1, '{"Name": "Welcome", "Kind": "markdown", "Markdown": "# Welcome\n\nPlease silence your phones.", "DurationSeconds": 15}'),
+(2, '{"Name": "Payouts", "Kind": "payouts", "DurationSeconds": 20}'),
+(3, '{"Name": "Coming up", "Kind": "structure", "DurationSeconds": 10}'),
+(4, '{"Name": "Chip leaders", "Kind": "leaderboard", "DurationSeconds": 20}');
+
+INSERT INTO slide_sets (slide_set_id, model_data)
+OVERRIDING SYSTEM VALUE
+VALUES (1, $json$
+    {
+       "Name": "Breaks and level changes",
+       "Entries": [
+          {"SlideID": 1, "Trigger": ""},
+          {"SlideID": 4, "Trigger": ""},
+          {"SlideID": 2, "Trigger": "break"},
+          {"SlideID": 4, "Trigger": "break"},
+          {"SlideID": 3, "Trigger": "level-start"}
+       ]
+    }
+    $json$);
+
+INSERT INTO tournament_templates (tournament_template_id, name, model_data)
+OVERRIDING SYSTEM VALUE
+VALUES (1, 'Thursday Turbo', $json$
+    {
+       "Name": "Thursday Turbo",
+       "Tournament": {
+          "EventName": "Thursday Turbo",
+          "Description": "$40 Turbo with one add-on",
+          "FooterPlugsID": 1,
+          "Structure": {
+             "ChipsPerBuyIn": 5000,
+             "ChipsPerAddOn": 5000,
+             "Levels": [
+                { "Banner": "LEVEL 1", "Description": "BLINDS 25-50", "DurationMinutes": 12 },
+                { "Banner": "LEVEL 2", "Description": "BLINDS 50-100", "DurationMinutes": 12 },
+                { "Banner": "LEVEL 3", "Description": "BLINDS 100-200", "DurationMinutes": 12 },
+                { "Banner": "BREAK", "Description": "ADD-ONS", "DurationMinutes": 10, "IsBreak": true },
+                { "Banner": "LEVEL 4", "Description": "BLINDS 200-400", "DurationMinutes": 12 },
+                { "Banner": "LEVEL 5", "Description": "BLINDS 300-600", "DurationMinutes": 12 },
+                { "Banner": "LEVEL 6", "Description": "BLINDS 500-1000", "DurationMinutes": 12 }
+             ]
+          },
+          "State": {
+             "AutoComputePrizePool": true,
+             "TimeRemainingMillis": 720000
+          }
+       },
+       "Recurrence": {
+          "Frequency": "weekly",
+          "Weekdays": [4],
+          "StartTime": "19:00"
+       }
+    }
+    $json$);
+
+INSERT INTO leagues (league_id, name, model_data)
+OVERRIDING SYSTEM VALUE
+VALUES (1, 'Home League', $json$
+    {
+       "Name": "Home League",
+       "Season": "2026",
+       "Description": "Thursday Turbos count toward the season",
+       "Formula": {
+          "Kind": "sqrt",
+          "Scale": 10,
+          "Participation": 1
+       },
+       "CountBest": 10
+    }
+    $json$);
+
+-- The inserts above chose their own IDs, so move the identity sequences
+-- past them.
+SELECT setval(pg_get_serial_sequence('tournaments', 'tournament_id'), (SELECT MAX(tournament_id) FROM tournaments));
+SELECT setval(pg_get_serial_sequence('structures', 'structure_id'), (SELECT MAX(structure_id) FROM structures));
+SELECT setval(pg_get_serial_sequence('footer_plug_sets', 'id'), (SELECT MAX(id) FROM footer_plug_sets));
+SELECT setval(pg_get_serial_sequence('layouts', 'layout_id'), (SELECT MAX(layout_id) FROM layouts));
+SELECT setval(pg_get_serial_sequence('slides', 'slide_id'), (SELECT MAX(slide_id) FROM slides));
+SELECT setval(pg_get_serial_sequence('slide_sets', 'slide_set_id'), (SELECT MAX(slide_set_id) FROM slide_sets));
+SELECT setval(pg_get_serial_sequence('tournament_templates', 'tournament_template_id'), (SELECT MAX(tournament_template_id) FROM tournament_templates));
+SELECT setval(pg_get_serial_sequence('leagues', 'league_id'), (SELECT MAX(league_id) FROM leagues));
@@ -0,0 +1,202 @@
+// Package footerplug checks footer plugs, reads them from the footer set
+// editor, and turns them into the HTML the clock shows.
+package footerplug
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"html"
+	"html/template"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/textutil"
+	"github.com/yuin/goldmark"
+)
+
+const (
+	// MaxWeight keeps one plug from drowning out the rest of its set.
+	MaxWeight = 100
+
+	// MaxURL is as long a URL as we'll take.  It matches what /qr will encode.
+	MaxURL = 1024
+
+	// TimeLayout is what a datetime-local input sends.
+	TimeLayout = "2006-01-02T15:04"
+
+	// DefaultSponsorCaption goes over a sponsor logo with no caption of its
+	// own.
+	DefaultSponsorCaption = "SPONSORED BY"
+)
+
+type Kind struct {
+	Kind        model.FooterPlugKind
+	Description string
+}
+
+// Kinds are all the kinds of plug, in the order the editor offers them.
+var Kinds = []Kind{
+	{model.FooterPlugKindText, "Text"},
+	{model.FooterPlugKindMarkdown, "Markdown"},
+	{model.FooterPlugKindImage, "Image by URL"},
+	{model.FooterPlugKindQRCode, "QR code for a URL"},
+	{model.FooterPlugKindSponsor, "Sponsor logo by URL"},
+}
+
+// KindOf is the plug's kind.  Plugs saved before there were kinds are text.
+func KindOf(p *model.FooterPlug) model.FooterPlugKind {
+	if p.Kind == "" {
+		return model.FooterPlugKindText
+	}
+	return p.Kind
+}
+
+func isKind(k model.FooterPlugKind) bool {
+	return slices.ContainsFunc(Kinds, func(x Kind) bool { return x.Kind == k })
+}
+
+// WeightOf is the plug's weight.  Zero counts as one.
+func WeightOf(p *model.FooterPlug) int {
+	if p.Weight <= 0 {
+		return 1
+	}
+	return p.Weight
+}
+
+// Validate checks a plug from the editor.
+func Validate(p *model.FooterPlug) error {
+	kind := KindOf(p)
+	if !isKind(kind) {
+		return fmt.Errorf("no such kind of plug %q", p.Kind)
+	}
+	switch kind {
+	case model.FooterPlugKindText, model.FooterPlugKindMarkdown:
+		if strings.TrimSpace(p.Text) == "" {
+			return fmt.Errorf("%s plug has no text", kind)
+		}
+	default:
+		if p.URL == "" {
+			return fmt.Errorf("%s plug needs a URL", kind)
+		}
+		if len(p.URL) > MaxURL {
+			return fmt.Errorf("URL is longer than %d characters", MaxURL)
+		}
+	}
+	if p.Weight < 0 || p.Weight > MaxWeight {
+		return fmt.Errorf("weight must be between 1 and %d", MaxWeight)
+	}
+	if !p.ValidFrom.IsZero() && !p.ValidUntil.IsZero() && !p.ValidUntil.After(p.ValidFrom) {
+		return errors.New("plug must end after it starts")
+	}
+	return nil
+}
+
+// Current says if the plug should be shown at now.
+func Current(p *model.FooterPlug, now time.Time) bool {
+	if !p.ValidFrom.IsZero() && now.Before(p.ValidFrom) {
+		return false
+	}
+	if !p.ValidUntil.IsZero() && !now.Before(p.ValidUntil) {
+		return false
+	}
+	return true
+}
+
+func parseTime(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(TimeLayout, s, loc)
+}
+
+// FormatTime formats t for a datetime-local input.
+func FormatTime(t time.Time, loc *time.Location) string {
+	if t.IsZero() {
+		return ""
+	}
+	return t.In(loc).Format(TimeLayout)
+}
+
+// ParsePlugs reads the footer set editor, which sends parallel Kind, Text,
+// URL, Weight, ValidFrom and ValidUntil values, one of each per row.  Rows
+// with neither text nor a URL are dropped.  Times are read in loc.
+func ParsePlugs(form url.Values, loc *time.Location) ([]model.FooterPlug, error) {
+	kinds := form["Kind"]
+	fields := []string{"Text", "URL", "Weight", "ValidFrom", "ValidUntil"}
+	for _, f := range fields {
+		if len(form[f]) != len(kinds) {
+			return nil, fmt.Errorf("got %d kinds but %d %s values", len(kinds), len(form[f]), f)
+		}
+	}
+
+	plugs := []model.FooterPlug{}
+	for i, kind := range kinds {
+		p := model.FooterPlug{
+			Kind: model.FooterPlugKind(kind),
+			Text: strings.TrimSpace(form["Text"][i]),
+			URL:  strings.TrimSpace(form["URL"][i]),
+		}
+		if p.Text == "" && p.URL == "" {
+			continue
+		}
+		if w := strings.TrimSpace(form["Weight"][i]); w != "" {
+			n, err := strconv.Atoi(w)
+			if err != nil {
+				return nil, fmt.Errorf("row %d: bad weight %q", i+1, w)
+			}
+			p.Weight = n
+		}
+		var err error
+		if p.ValidFrom, err = parseTime(form["ValidFrom"][i], loc); err != nil {
+			return nil, fmt.Errorf("row %d: bad start time: %w", i+1, err)
+		}
+		if p.ValidUntil, err = parseTime(form["ValidUntil"][i], loc); err != nil {
+			return nil, fmt.Errorf("row %d: bad end time: %w", i+1, err)
+		}
+		if err := Validate(&p); err != nil {
+			return nil, fmt.Errorf("row %d: %w", i+1, err)
+		}
+		plugs = append(plugs, p)
+	}
+	return plugs, nil
+}
+
+var snippets = template.Must(template.New("footerplug").Parse(`
+{{- define "image" }}<img class="footer-plug-image" src="{{ .URL }}" alt="{{ .Text }}">{{ end -}}
+{{- define "qr" }}<div class="footer-plug-qr"><img src="/qr?text={{ .URL }}" alt="QR code">{{ if .Text }}<span>{{ .Text }}</span>{{ end }}</div>{{ end -}}
+{{- define "sponsor" }}<div class="footer-plug-sponsor"><span>{{ .Text }}</span><img src="{{ .URL }}" alt="{{ .Text }}"></div>{{ end -}}
+`))
+
+// Render turns a plug into the HTML that goes in the footer.
+func Render(p *model.FooterPlug) (string, error) {
+	var buf bytes.Buffer
+	switch kind := KindOf(p); kind {
+	case model.FooterPlugKindText:
+		return textutil.WrapAttributionInNobr(html.EscapeString(p.Text)), nil
+	case model.FooterPlugKindMarkdown:
+		// goldmark leaves raw HTML out unless asked otherwise.
+		if err := goldmark.Convert([]byte(p.Text), &buf); err != nil {
+			return "", err
+		}
+	case model.FooterPlugKindImage, model.FooterPlugKindQRCode:
+		if err := snippets.ExecuteTemplate(&buf, string(kind), p); err != nil {
+			return "", err
+		}
+	case model.FooterPlugKindSponsor:
+		data := *p
+		if data.Text == "" {
+			data.Text = DefaultSponsorCaption
+		}
+		if err := snippets.ExecuteTemplate(&buf, string(kind), &data); err != nil {
+			return "", err
+		}
+	default:
+		return "", fmt.Errorf("no such kind of plug %q", p.Kind)
+	}
+	return buf.String(), nil
+}
@@ -0,0 +1,128 @@
+package footerplug
+
+import (
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/model"
+)
+
+func TestValidate(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		name    string
+		p       model.FooterPlug
+		wantErr string
+	}{
+		{"legacy text", model.FooterPlug{Text: "hello"}, ""},
+		{"markdown", model.FooterPlug{Kind: model.FooterPlugKindMarkdown, Text: "*hi*"}, ""},
+		{"qr", model.FooterPlug{Kind: model.FooterPlugKindQRCode, URL: "https://example.com/"}, ""},
+		{"weighted", model.FooterPlug{Text: "x", Weight: 5}, ""},
+		{"window", model.FooterPlug{Text: "x", ValidFrom: t0, ValidUntil: t0.Add(time.Hour)}, ""},
+		{"bad kind", model.FooterPlug{Kind: "hologram", Text: "x"}, "no such kind"},
+		{"empty text", model.FooterPlug{Kind: model.FooterPlugKindText, Text: " "}, "no text"},
+		{"no url", model.FooterPlug{Kind: model.FooterPlugKindSponsor, Text: "Acme"}, "needs a URL"},
+		{"long url", model.FooterPlug{Kind: model.FooterPlugKindImage, URL: strings.Repeat("x", MaxURL+1)}, "longer"},
+		{"heavy", model.FooterPlug{Text: "x", Weight: MaxWeight + 1}, "weight"},
+		{"backwards", model.FooterPlug{Text: "x", ValidFrom: t0, ValidUntil: t0}, "end after"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(&tc.p)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestCurrent(t *testing.T) {
+	t0 := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	p := model.FooterPlug{ValidFrom: t0, ValidUntil: t0.Add(time.Hour)}
+	for _, tc := range []struct {
+		at   time.Time
+		want bool
+	}{
+		{t0.Add(-time.Second), false},
+		{t0, true},
+		{t0.Add(59 * time.Minute), true},
+		{t0.Add(time.Hour), false},
+	} {
+		if got := Current(&p, tc.at); got != tc.want {
+			t.Errorf("Current at %v = %v, want %v", tc.at, got, tc.want)
+		}
+	}
+	if !Current(&model.FooterPlug{}, t0) {
+		t.Errorf("plug with no window should always be current")
+	}
+}
+
+func TestParsePlugs(t *testing.T) {
+	loc := time.FixedZone("PDT", -7*3600)
+	form := url.Values{
+		"Kind":       {"text", "qr", "markdown"},
+		"Text":       {" Shuffle up and deal ", "Scan me", ""},
+		"URL":        {"", "https://example.com/", ""},
+		"Weight":     {"", "3", ""},
+		"ValidFrom":  {"", "2026-05-01T18:00", ""},
+		"ValidUntil": {"", "", ""},
+	}
+	plugs, err := ParsePlugs(form, loc)
+	if err != nil {
+		t.Fatalf("ParsePlugs: %v", err)
+	}
+	if len(plugs) != 2 {
+		t.Fatalf("got %d plugs, want 2 (blank row dropped)", len(plugs))
+	}
+	if plugs[0].Text != "Shuffle up and deal" || plugs[0].Weight != 0 {
+		t.Errorf("first plug = %+v", plugs[0])
+	}
+	want := time.Date(2026, 5, 2, 1, 0, 0, 0, time.UTC)
+	if plugs[1].Weight != 3 || !plugs[1].ValidFrom.Equal(want) || !plugs[1].ValidUntil.IsZero() {
+		t.Errorf("second plug = %+v", plugs[1])
+	}
+	if got := FormatTime(plugs[1].ValidFrom, loc); got != "2026-05-01T18:00" {
+		t.Errorf("FormatTime = %q", got)
+	}
+
+	form["Weight"] = []string{"", "lots", ""}
+	if _, err := ParsePlugs(form, loc); err == nil || !strings.Contains(err.Error(), "row 2") {
+		t.Errorf("got error %v, want one about row 2", err)
+	}
+	form["Weight"] = []string{""}
+	if _, err := ParsePlugs(form, loc); err == nil {
+		t.Errorf("ragged form should fail")
+	}
+}
+
+func TestRender(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		p    model.FooterPlug
+		want []string
+	}{
+		{"text", model.FooterPlug{Text: "<b>fish</b>"}, []string{"&lt;b&gt;fish&lt;/b&gt;"}},
+		{"markdown", model.FooterPlug{Kind: model.FooterPlugKindMarkdown, Text: "**big** <script>x</script>"}, []string{"<strong>big</strong>", "raw HTML omitted"}},
+		{"image", model.FooterPlug{Kind: model.FooterPlugKindImage, URL: "https://example.com/a.png"}, []string{`class="footer-plug-image"`, `src="https://example.com/a.png"`}},
+		{"qr", model.FooterPlug{Kind: model.FooterPlugKindQRCode, URL: "https://example.com/?a=1&b=2"}, []string{`src="/qr?text=https%3a%2f%2fexample.com%2f%3fa%3d1%26b%3d2"`}},
+		{"sponsor", model.FooterPlug{Kind: model.FooterPlugKindSponsor, URL: "https://example.com/logo.png"}, []string{DefaultSponsorCaption}},
+		{"evil url", model.FooterPlug{Kind: model.FooterPlugKindImage, URL: "javascript:alert(1)"}, []string{"#ZgotmplZ"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Render(&tc.p)
+			if err != nil {
+				t.Fatalf("Render: %v", err)
+			}
+			for _, w := range tc.want {
+				if !strings.Contains(got, w) {
+					t.Errorf("Render = %q, want it to contain %q", got, w)
+				}
+			}
+		})
+	}
+}
@@ -24,6 +24,9 @@ type FormProcessor struct {
 	clock             nower
 }
 
+// ScheduledStartLayout is what a datetime-local input sends.
+const ScheduledStartLayout = "2006-01-02T15:04"
+
 type nower interface {
 	Now() time.Time
 }
@@ -151,6 +154,9 @@ func (a *FormProcessor) ApplyFormToTournament(ctx context.Context, form url.Valu
 
 	maybeCopyInt64(form, &t.FooterPlugsID, "FooterPlugsID")
 	maybeCopyInt64(form, &t.NextLevelSoundID, "NextLevelSoundID")
+	maybeCopyInt64(form, &t.LayoutID, "LayoutID")
+	maybeCopyInt64(form, &t.SlideSetID, "SlideSetID")
+	maybeCopyInt64(form, &t.LeagueID, "LeagueID")
 
 	maybeCopyInt(form, &t.PrizePoolPerBuyIn, "PrizePoolPerBuyIn")
 	maybeCopyInt(form, &t.PrizePoolPerAddOn, "PrizePoolPerAddOn")
@@ -163,6 +169,25 @@ func (a *FormProcessor) ApplyFormToTournament(ctx context.Context, form url.Valu
 	maybeCopyString(form, &t.Description, "Description")
 	maybeCopyString(form, &t.Theme, "Theme")
 
+	// A datetime-local input; blank means unscheduled.
+	if v, ok := form["ScheduledStart"]; ok && len(v) > 0 {
+		if v[0] == "" {
+			t.ScheduledStart = time.Time{}
+		} else if start, err := time.ParseInLocation(ScheduledStartLayout, v[0], time.Local); err != nil {
+			return he.HTTPCodedErrorf(400, "invalid scheduled start")
+		} else {
+			t.ScheduledStart = start
+		}
+	}
+
+	// The lifecycle select shows where the tournament is, so only a change
+	// is a choice.
+	if l := model.Lifecycle(form.Get("Lifecycle")); l != "" && l != tournament.LifecycleOf(t) {
+		if err := a.tournamentMutator.SetLifecycle(t, l); err != nil {
+			return err
+		}
+	}
+
 	maybeCopyInt(form, &t.State.AddOns, "AddOns")
 	maybeCopyInt(form, &t.State.AmountPerSave, "AmountPerSave")
 	maybeCopyInt(form, &t.State.BuyIns, "BuyIns")
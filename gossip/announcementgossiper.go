@@ -0,0 +1,186 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/ts4z/irata/announcement"
+	"github.com/ts4z/irata/dbnotify"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/ts"
+)
+
+type announcementChannels struct {
+	tournamentID   int64
+	errCh          chan<- error
+	announcementCh chan<- []*model.Announcement
+}
+
+// AnnouncementGossiper tells clocks when the announcements they should be
+// showing change.  Clocks listen for their tournament; an announcement for
+// every display wakes them all.
+type AnnouncementGossiper struct {
+	listeners   []announcementChannels
+	listenersMu sync.Mutex
+	next        state.AnnouncementStorage
+	clock       ts.Clock
+}
+
+var _ dbnotify.ClientNotifier[*model.Announcement] = &AnnouncementGossiper{}
+
+func NewAnnouncementGossiper(next state.AnnouncementStorage, clock ts.Clock) *AnnouncementGossiper {
+	return &AnnouncementGossiper{
+		next:  next,
+		clock: clock,
+	}
+}
+
+func (g *AnnouncementGossiper) current(ctx context.Context, tournamentID int64) ([]*model.Announcement, error) {
+	now := g.clock.Now()
+	all, err := g.next.FetchAnnouncements(ctx, now)
+	if err != nil {
+		return nil, err
+	}
+	return announcement.For(all, tournamentID, now), nil
+}
+
+// ListenAnnouncements eventually writes exactly once to either errCh or
+// announcementCh: immediately if the announcements for the tournament don't
+// match key (see announcement.Key), otherwise when they change.
+func (g *AnnouncementGossiper) ListenAnnouncements(ctx context.Context, tournamentID int64, key string, errCh chan<- error, announcementCh chan<- []*model.Announcement) {
+	g.listenersMu.Lock()
+	defer g.listenersMu.Unlock()
+
+	as, err := g.current(ctx, tournamentID)
+	if err != nil {
+		errCh <- fmt.Errorf("can't listen for announcements: %v", err)
+		return
+	}
+	if announcement.Key(as) != key {
+		announcementCh <- as
+		return
+	}
+
+	g.listeners = append(g.listeners, announcementChannels{tournamentID, errCh, announcementCh})
+}
+
+// Forget removes a listener that has given up waiting.
+func (g *AnnouncementGossiper) Forget(announcementCh chan<- []*model.Announcement) {
+	g.listenersMu.Lock()
+	defer g.listenersMu.Unlock()
+	g.listeners = slices.DeleteFunc(g.listeners, func(chs announcementChannels) bool {
+		return chs.announcementCh == announcementCh
+	})
+}
+
+// takeListeners removes and returns the listeners that want wants.
+func (g *AnnouncementGossiper) takeListeners(wants func(tournamentID int64) bool) []announcementChannels {
+	g.listenersMu.Lock()
+	defer g.listenersMu.Unlock()
+	taken := []announcementChannels{}
+	g.listeners = slices.DeleteFunc(g.listeners, func(chs announcementChannels) bool {
+		if wants(chs.tournamentID) {
+			taken = append(taken, chs)
+			return true
+		}
+		return false
+	})
+	return taken
+}
+
+func (g *AnnouncementGossiper) wake(ctx context.Context, listeners []announcementChannels) {
+	if len(listeners) == 0 {
+		return
+	}
+	now := g.clock.Now()
+	all, err := g.next.FetchAnnouncements(ctx, now)
+	for _, chs := range listeners {
+		// Channels are buffered by the listener, so this doesn't block.
+		if err != nil {
+			chs.errCh <- fmt.Errorf("can't fetch announcements: %v", err)
+		} else {
+			chs.announcementCh <- announcement.For(all, chs.tournamentID, now)
+		}
+	}
+	logger.InfoContext(ctx, "notified listeners of an announcement change", "listeners", len(listeners))
+}
+
+// NotifyUpdated implements dbnotify.ClientNotifier.
+func (g *AnnouncementGossiper) NotifyUpdated(ctx context.Context, a *model.Announcement) {
+	if a == nil {
+		return
+	}
+	g.wake(ctx, g.takeListeners(func(tournamentID int64) bool {
+		return announcement.Concerns(a, tournamentID)
+	}))
+}
+
+// NotifyDeleted wakes everybody, since the announcement is gone and so is
+// any record of whom it concerned.
+func (g *AnnouncementGossiper) NotifyDeleted(ctx context.Context, id int64) {
+	g.wake(ctx, g.takeListeners(func(int64) bool { return true }))
+}
+
+// Fetch lets the gossiper stand in as the dbnotify.StorageFetcher.
+func (g *AnnouncementGossiper) Fetch(ctx context.Context, id int64) (*model.Announcement, error) {
+	return g.next.FetchAnnouncement(ctx, id)
+}
+
+// CacheInvalidate lets the gossiper stand in as the dbnotify.CacheStorage.
+// Announcements aren't cached, so there's nothing to do.
+func (g *AnnouncementGossiper) CacheInvalidate(context.Context, int64, int64) {}
+
+// AnnouncementStorage intercepts announcement writes and tells the gossiper
+// about them.
+type AnnouncementStorage struct {
+	gossiper *AnnouncementGossiper
+	next     state.AnnouncementStorage
+}
+
+var _ state.AnnouncementStorage = (*AnnouncementStorage)(nil)
+
+func NewAnnouncementStorage(storage state.AnnouncementStorage, g *AnnouncementGossiper) *AnnouncementStorage {
+	return &AnnouncementStorage{
+		next:     storage,
+		gossiper: g,
+	}
+}
+
+func (s *AnnouncementStorage) FetchAnnouncements(ctx context.Context, expiringAfter time.Time) ([]*model.Announcement, error) {
+	return s.next.FetchAnnouncements(ctx, expiringAfter)
+}
+
+func (s *AnnouncementStorage) FetchAnnouncement(ctx context.Context, id int64) (*model.Announcement, error) {
+	return s.next.FetchAnnouncement(ctx, id)
+}
+
+func (s *AnnouncementStorage) CreateAnnouncement(ctx context.Context, a *model.Announcement) (int64, error) {
+	id, err := s.next.CreateAnnouncement(ctx, a)
+	if err != nil {
+		return 0, err
+	}
+	created := a.Clone()
+	created.AnnouncementID = id
+	s.gossiper.NotifyUpdated(ctx, created)
+	return id, nil
+}
+
+func (s *AnnouncementStorage) SaveAnnouncement(ctx context.Context, a *model.Announcement) error {
+	if err := s.next.SaveAnnouncement(ctx, a); err != nil {
+		return err
+	}
+	s.gossiper.NotifyUpdated(ctx, a)
+	return nil
+}
+
+func (s *AnnouncementStorage) DeleteAnnouncement(ctx context.Context, id int64) error {
+	if err := s.next.DeleteAnnouncement(ctx, id); err != nil {
+		return err
+	}
+	s.gossiper.NotifyDeleted(ctx, id)
+	return nil
+}
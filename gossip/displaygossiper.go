@@ -0,0 +1,169 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"sync"
+
+	"github.com/ts4z/irata/dbnotify"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+type displayChannels struct {
+	version   int64 // what the listener has
+	errCh     chan<- error
+	displayCh chan<- *model.Display
+}
+
+// DisplayGossiper tells kiosk displays when an operator has reassigned them.
+type DisplayGossiper struct {
+	listeners   map[int64][]displayChannels
+	listenersMu sync.Mutex
+	next        CacheStorage[model.Display]
+}
+
+var _ dbnotify.ClientNotifier[*model.Display] = &DisplayGossiper{}
+
+func NewDisplayGossiper(next CacheStorage[model.Display]) *DisplayGossiper {
+	return &DisplayGossiper{
+		listeners: make(map[int64][]displayChannels),
+		next:      next,
+	}
+}
+
+// ListenDisplayVersion eventually writes exactly once to either errCh or
+// displayCh: immediately if the stored display isn't at the given version,
+// otherwise when it changes.
+func (g *DisplayGossiper) ListenDisplayVersion(ctx context.Context, id int64, version int64, errCh chan<- error, displayCh chan<- *model.Display) {
+	g.listenersMu.Lock()
+	defer g.listenersMu.Unlock()
+
+	d, err := g.next.Fetch(ctx, id)
+	if err != nil {
+		errCh <- fmt.Errorf("can't listen for changes: can't fetch display %d: %v", id, err)
+		return
+	}
+
+	if d.Version != version {
+		displayCh <- d
+		return
+	}
+
+	g.listeners[id] = append(g.listeners[id], displayChannels{version, errCh, displayCh})
+}
+
+// Forget removes a listener that has given up waiting, so the map doesn't grow
+// without bound while nobody touches the display.
+func (g *DisplayGossiper) Forget(id int64, displayCh chan<- *model.Display) {
+	g.listenersMu.Lock()
+	defer g.listenersMu.Unlock()
+	listeners := g.listeners[id]
+	for i, chs := range listeners {
+		if chs.displayCh == displayCh {
+			g.listeners[id] = append(listeners[:i], listeners[i+1:]...)
+			break
+		}
+	}
+	if len(g.listeners[id]) == 0 {
+		delete(g.listeners, id)
+	}
+}
+
+func (g *DisplayGossiper) resetListeners(id int64) []displayChannels {
+	g.listenersMu.Lock()
+	defer g.listenersMu.Unlock()
+	listeners := g.listeners[id]
+	delete(g.listeners, id)
+	return listeners
+}
+
+// takeListenersBehind removes and returns the listeners that don't have
+// version yet.
+func (g *DisplayGossiper) takeListenersBehind(id, version int64) []displayChannels {
+	g.listenersMu.Lock()
+	defer g.listenersMu.Unlock()
+	var behind, current []displayChannels
+	for _, chs := range g.listeners[id] {
+		if chs.version == version {
+			current = append(current, chs)
+		} else {
+			behind = append(behind, chs)
+		}
+	}
+	if len(current) == 0 {
+		delete(g.listeners, id)
+	} else {
+		g.listeners[id] = current
+	}
+	return behind
+}
+
+// NotifyUpdated implements dbnotify.ClientNotifier.  A display at the
+// version its listeners already have, as after a heartbeat, wakes no one.
+func (g *DisplayGossiper) NotifyUpdated(ctx context.Context, d *model.Display) {
+	if d == nil {
+		return
+	}
+	listeners := g.takeListenersBehind(d.DisplayID, d.Version)
+	for _, chs := range listeners {
+		// Channels are buffered by the listener, so this doesn't block.
+		chs.displayCh <- d.Clone()
+	}
+	if len(listeners) > 0 {
+		logger.InfoContext(ctx, "notified listeners of display change", "listeners", len(listeners), "display", d.DisplayID, "version", d.Version)
+	}
+}
+
+func (g *DisplayGossiper) NotifyDeleted(ctx context.Context, id int64) {
+	for _, chs := range g.resetListeners(id) {
+		chs.errCh <- fmt.Errorf("display %d has been deleted", id)
+	}
+}
+
+// DisplayStorage intercepts display writes and tells the gossiper about them.
+type DisplayStorage struct {
+	gossiper *DisplayGossiper
+	next     state.DisplayStorage
+}
+
+var _ state.DisplayStorage = (*DisplayStorage)(nil)
+
+func NewDisplayStorage(storage state.DisplayStorage, g *DisplayGossiper) *DisplayStorage {
+	return &DisplayStorage{
+		next:     storage,
+		gossiper: g,
+	}
+}
+
+func (s *DisplayStorage) FetchDisplays(ctx context.Context) ([]*model.Display, error) {
+	return s.next.FetchDisplays(ctx)
+}
+
+func (s *DisplayStorage) FetchDisplay(ctx context.Context, id int64) (*model.Display, error) {
+	return s.next.FetchDisplay(ctx, id)
+}
+
+func (s *DisplayStorage) FetchDisplayByDeviceID(ctx context.Context, deviceID string) (*model.Display, error) {
+	return s.next.FetchDisplayByDeviceID(ctx, deviceID)
+}
+
+func (s *DisplayStorage) RegisterDisplay(ctx context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error) {
+	return s.next.RegisterDisplay(ctx, deviceID, remoteAddr, userAgent)
+}
+
+func (s *DisplayStorage) SaveDisplay(ctx context.Context, d *model.Display) error {
+	if err := s.next.SaveDisplay(ctx, d); err != nil {
+		return err
+	}
+	s.gossiper.NotifyUpdated(ctx, d)
+	return nil
+}
+
+func (s *DisplayStorage) DeleteDisplay(ctx context.Context, id int64) error {
+	if err := s.next.DeleteDisplay(ctx, id); err != nil {
+		return err
+	}
+	s.gossiper.NotifyDeleted(ctx, id)
+	return nil
+}
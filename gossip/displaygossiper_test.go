@@ -0,0 +1,155 @@
+package gossip
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// fakeDisplays is display storage and the cache in front of it.
+type fakeDisplays struct {
+	mu       sync.Mutex
+	displays map[int64]*model.Display
+}
+
+var _ state.DisplayStorage = &fakeDisplays{}
+var _ CacheStorage[model.Display] = &fakeDisplays{}
+
+func (f *fakeDisplays) Fetch(ctx context.Context, id int64) (*model.Display, error) {
+	return f.FetchDisplay(ctx, id)
+}
+
+func (f *fakeDisplays) CacheInvalidate(ctx context.Context, id, version int64) {}
+
+func (f *fakeDisplays) FetchDisplays(ctx context.Context) ([]*model.Display, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakeDisplays) FetchDisplay(ctx context.Context, id int64) (*model.Display, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	d, ok := f.displays[id]
+	if !ok {
+		return nil, fmt.Errorf("no display %d", id)
+	}
+	return d.Clone(), nil
+}
+
+func (f *fakeDisplays) FetchDisplayByDeviceID(ctx context.Context, deviceID string) (*model.Display, error) {
+	return nil, fmt.Errorf("not implemented")
+}
+
+func (f *fakeDisplays) RegisterDisplay(ctx context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	for _, d := range f.displays {
+		if d.DeviceID == deviceID {
+			d.LastHeartbeat = time.Now()
+			d.RemoteAddr = remoteAddr
+			d.UserAgent = userAgent
+			return d.Clone(), nil
+		}
+	}
+	return nil, fmt.Errorf("no device %q", deviceID)
+}
+
+func (f *fakeDisplays) SaveDisplay(ctx context.Context, d *model.Display) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.displays[d.DisplayID].Version != d.Version {
+		return fmt.Errorf("optimistic lock failure")
+	}
+	d.Version++
+	f.displays[d.DisplayID] = d.Clone()
+	return nil
+}
+
+func (f *fakeDisplays) DeleteDisplay(ctx context.Context, id int64) error {
+	return fmt.Errorf("not implemented")
+}
+
+func newDisplayFixture() (*fakeDisplays, *DisplayGossiper, *DisplayStorage) {
+	f := &fakeDisplays{displays: map[int64]*model.Display{
+		1: {DisplayID: 1, Version: 3, DeviceID: "lobby", Mode: model.DisplayModeLobby},
+	}}
+	g := NewDisplayGossiper(f)
+	return f, g, NewDisplayStorage(f, g)
+}
+
+func listenDisplay(t *testing.T, g *DisplayGossiper, version int64) (chan error, chan *model.Display) {
+	errCh := make(chan error, 1)
+	displayCh := make(chan *model.Display, 1)
+	g.ListenDisplayVersion(context.Background(), 1, version, errCh, displayCh)
+	return errCh, displayCh
+}
+
+func TestDisplayAssignmentWakesListener(t *testing.T) {
+	ctx := context.Background()
+	_, g, s := newDisplayFixture()
+	_, displayCh := listenDisplay(t, g, 3)
+	select {
+	case d := <-displayCh:
+		t.Fatalf("woke before anything changed: %+v", d)
+	default:
+	}
+
+	d, err := s.FetchDisplay(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Mode = model.DisplayModeTournament
+	d.TournamentID = 7
+	if err := s.SaveDisplay(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-displayCh:
+		if got.Version != 4 || got.TournamentID != 7 {
+			t.Errorf("got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("listener wasn't woken")
+	}
+}
+
+func TestDisplayHeartbeatDoesNotWakeListener(t *testing.T) {
+	ctx := context.Background()
+	_, g, s := newDisplayFixture()
+	_, displayCh := listenDisplay(t, g, 3)
+
+	hb, err := s.RegisterDisplay(ctx, "lobby", "10.0.0.9", "kiosk/2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Even if the refreshed display is passed along, as a notification
+	// from another server might be, its version is what the listener has.
+	g.NotifyUpdated(ctx, hb)
+	select {
+	case d := <-displayCh:
+		t.Fatalf("heartbeat woke the listener: %+v", d)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// It's still listening.
+	d, err := s.FetchDisplay(ctx, 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d.Name = "Lobby"
+	if err := s.SaveDisplay(ctx, d); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case got := <-displayCh:
+		if got.Name != "Lobby" {
+			t.Errorf("got %+v", got)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("listener wasn't woken")
+	}
+}
@@ -7,7 +7,13 @@ The name is imperfect, but see the section "Promotion" on https://en.wikipedia.o
 
 package gossip
 
-import "context"
+import (
+	"context"
+
+	"github.com/ts4z/irata/logz"
+)
+
+var logger = logz.New()
 
 type CacheStorage[T any] interface {
 	Fetch(ctx context.Context, id int64) (*T, error)
@@ -0,0 +1,52 @@
+package gossip
+
+import (
+	"context"
+	"sync"
+
+	"github.com/ts4z/irata/dbnotify"
+	"github.com/ts4z/irata/model"
+)
+
+// SiteConfigGossiper tells the parts of the server that hold on to the site
+// config, like the CORS origins and the cookie bakery, when it changes.
+// Unlike the other gossipers, its listeners are in-process and stay
+// subscribed.
+//
+// Saves aren't intercepted here: the site_config dispatcher hears about
+// every save, this process's included, and one reload per change is enough.
+type SiteConfigGossiper struct {
+	mu          sync.Mutex
+	subscribers []func(context.Context, *model.SiteConfig)
+}
+
+var _ dbnotify.ClientNotifier[*model.SiteConfig] = &SiteConfigGossiper{}
+
+func NewSiteConfigGossiper() *SiteConfigGossiper {
+	return &SiteConfigGossiper{}
+}
+
+// Subscribe calls fn with the new config after each change, for as long as
+// the server runs.
+func (g *SiteConfigGossiper) Subscribe(fn func(context.Context, *model.SiteConfig)) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.subscribers = append(g.subscribers, fn)
+}
+
+// NotifyUpdated implements dbnotify.ClientNotifier.
+func (g *SiteConfigGossiper) NotifyUpdated(ctx context.Context, sc *model.SiteConfig) {
+	if sc == nil {
+		return
+	}
+	g.mu.Lock()
+	subscribers := append([]func(context.Context, *model.SiteConfig){}, g.subscribers...)
+	g.mu.Unlock()
+	for _, fn := range subscribers {
+		fn(ctx, sc)
+	}
+}
+
+// NotifyDeleted implements dbnotify.ClientNotifier.  The site config is
+// never deleted.
+func (g *SiteConfigGossiper) NotifyDeleted(ctx context.Context, id int64) {}
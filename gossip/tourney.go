@@ -41,10 +41,6 @@ func (s *TournamentStorage) CreateTournament(ctx context.Context, t *model.Tourn
 
 func (g *TournamentGossiper) NotifyDeleted(ctx context.Context, id int64) {
 	// Purge any active listeners.
-
-	g.tournamentListenersMu.Lock()
-	defer g.tournamentListenersMu.Unlock()
-
 	listeners := g.resetTournamentListeners(id)
 
 	for _, ch := range listeners {
@@ -69,8 +65,8 @@ func (s *TournamentStorage) DeleteTournament(ctx context.Context, id int64) erro
 }
 
 // FetchOverview implements state.TournamentListenerStorage.
-func (s *TournamentStorage) FetchOverview(ctx context.Context, offset int, limit int) (*model.Overview, error) {
-	return s.next.FetchOverview(ctx, offset, limit)
+func (s *TournamentStorage) FetchOverview(ctx context.Context, lifecycles []model.Lifecycle, offset int, limit int) (*model.Overview, error) {
+	return s.next.FetchOverview(ctx, lifecycles, offset, limit)
 }
 
 // FetchTournament implements state.TournamentListenerStorage.
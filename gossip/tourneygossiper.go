@@ -2,14 +2,19 @@ package gossip
 
 import (
 	"context"
+	"expvar"
 	"fmt"
-	"log"
+	"slices"
+	"strconv"
 	"sync"
 
 	"github.com/ts4z/irata/model"
 	"github.com/ts4z/irata/tournament"
+	"github.com/ts4z/irata/varz"
 )
 
+var listenersByTournament = varz.NewGaugeMap("listenersByTournament")
+
 // TournamentGossiper provides a tattletale for changes to tournaments.  Subscribers can either
 // modify tournaments locally, or wait for the db notification to percolate back.
 type TournamentGossiper struct {
@@ -42,15 +47,45 @@ func (s *TournamentGossiper) ListenTournamentVersion(ctx context.Context, id int
 		if t.Version < version {
 			// This is un-possible, but a malicious client could be messing with us,
 			// or we could just have a bug.
-			log.Printf("can't happen: reported version %d is newer than stored version %d for tournament %d", version, t.Version, id)
+			logger.WarnContext(ctx, "can't happen: reported version is newer than stored version", "tournament", id, "reported", version, "stored", t.Version)
 		}
 		s.mgr.FillTransientsAndAdvanceClock(ctx, t)
 		tournamentCh <- t
 		return
 	}
 
-	log.Printf("gossiper: client listening for tournament %d changes from version %d", id, version)
-	s.tournamentListeners[id] = append(s.tournamentListeners[id], channels{errCh, tournamentCh})
+	logger.DebugContext(ctx, "client listening for tournament changes", "tournament", id, "version", version)
+	chs := channels{errCh, tournamentCh}
+	s.tournamentListeners[id] = append(s.tournamentListeners[id], chs)
+	s.countListeners(id)
+	// A client that gives up shouldn't be counted, or notified.
+	context.AfterFunc(ctx, func() { s.forgetListener(id, chs) })
+}
+
+func (s *TournamentGossiper) forgetListener(id int64, chs channels) {
+	s.tournamentListenersMu.Lock()
+	defer s.tournamentListenersMu.Unlock()
+	listeners := slices.DeleteFunc(s.tournamentListeners[id], func(c channels) bool { return c == chs })
+	if len(listeners) == 0 {
+		delete(s.tournamentListeners, id)
+	} else {
+		s.tournamentListeners[id] = listeners
+	}
+	s.countListeners(id)
+}
+
+// countListeners updates the listener gauge for id.  The caller holds
+// tournamentListenersMu.
+func (s *TournamentGossiper) countListeners(id int64) {
+	key := strconv.FormatInt(id, 10)
+	n := len(s.tournamentListeners[id])
+	if n == 0 {
+		listenersByTournament.Delete(key)
+		return
+	}
+	v := new(expvar.Int)
+	v.Set(int64(n))
+	listenersByTournament.Set(key, v)
 }
 
 func (s *TournamentGossiper) resetTournamentListeners(id int64) []channels {
@@ -58,6 +93,7 @@ func (s *TournamentGossiper) resetTournamentListeners(id int64) []channels {
 	defer s.tournamentListenersMu.Unlock()
 	listeners := s.tournamentListeners[id]
 	delete(s.tournamentListeners, id)
+	s.countListeners(id)
 	return listeners
 }
 
@@ -70,6 +106,6 @@ func (g *TournamentGossiper) NotifyUpdated(ctx context.Context, t *model.Tournam
 			// Pass the updated tournament directly
 			chs.tournamentCh <- t.Clone()
 		}
-		log.Printf("notified %d listeners of tournament %d version %d change", len(listeners), t.EventID, t.Version)
+		logger.InfoContext(ctx, "notified listeners of tournament change", "listeners", len(listeners), "tournament", t.EventID, "version", t.Version)
 	}()
 }
@@ -16,6 +16,11 @@ func (u *UserGossiper) NotifyUpdated(ctx context.Context, m *model.UserIdentity)
 	// dropped, no way to subscribe to these.
 }
 
+// NotifyDeleted implements dbnotify.ClientNotifier.
+func (u *UserGossiper) NotifyDeleted(ctx context.Context, id int64) {
+	// dropped, as above.
+}
+
 var _ dbnotify.ClientNotifier[*model.UserIdentity] = &UserGossiper{}
 
 func NewUserGossiper(cache CacheStorage[model.UserIdentity]) *UserGossiper {
@@ -1,6 +1,7 @@
 package he
 
 import (
+	"errors"
 	"fmt"
 	"log" // all kids love log
 	"net/http"
@@ -30,6 +31,16 @@ func (e *HTTPError) Error() string {
 	return e.err.Error()
 }
 
+// Code is the response code for err: its own, if it's an HTTPError, and
+// otherwise 500.
+func Code(err error) int {
+	var he *HTTPError
+	if errors.As(err, &he) {
+		return he.code
+	}
+	return http.StatusInternalServerError
+}
+
 // SendErrorToHTTPClient sends err as an HTTP error.  If it happens to be our
 // special HTTPCodedError, we can include a better respone code; otherwise,
 // client gets 500 and it's on us.
@@ -0,0 +1,186 @@
+// Package layout checks clock display layouts and turns them into CSS.
+//
+// A layout places named panels on a CSS grid, once for landscape screens and
+// once for portrait ones.  The panels themselves are in the view-layout
+// template; this package only knows their names.
+package layout
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+
+	"github.com/ts4z/irata/model"
+)
+
+// Panel is one of the building blocks of a layout.
+type Panel struct {
+	Name        string
+	Description string
+}
+
+// Panels are all the panels a layout can use, in the order they are drawn.
+var Panels = []Panel{
+	{"clock", "Event name, level and the big clock"},
+	{"blinds", "Current blinds and the next level"},
+	{"stats", "Players, buy-ins, add-ons, prize pool, average stack and next break"},
+	{"payouts", "The payouts, large"},
+	{"seating", "Seating chart"},
+	{"footer", "Rotating footer plugs"},
+	{"leaderboard", "Chip leaders"},
+	{"image", "An image, or a QR code"},
+}
+
+const emptyCell = "."
+
+// Track sizes go straight into the style sheet, so keep them boring.
+var trackSizesRE = regexp.MustCompile(`^[0-9a-z%.,() ]*$`)
+
+func IsPanel(name string) bool {
+	return slices.ContainsFunc(Panels, func(p Panel) bool { return p.Name == name })
+}
+
+// ParseAreas turns text with one grid row per line into Areas, normalizing
+// the spacing and dropping blank lines.
+func ParseAreas(text string) []string {
+	areas := []string{}
+	for line := range strings.Lines(text) {
+		if fields := strings.Fields(line); len(fields) > 0 {
+			areas = append(areas, strings.Join(fields, " "))
+		}
+	}
+	return areas
+}
+
+// ValidateVariant checks that v is a grid the browser will accept: every row
+// is the same width, every name is a panel, and each panel covers a
+// rectangle.
+func ValidateVariant(v *model.LayoutVariant) error {
+	if len(v.Areas) == 0 {
+		return errors.New("no rows")
+	}
+
+	type box struct{ top, left, bottom, right, cells int }
+	boxes := map[string]*box{}
+	width := -1
+	for r, row := range v.Areas {
+		cells := strings.Fields(row)
+		if width < 0 {
+			width = len(cells)
+		} else if len(cells) != width {
+			return fmt.Errorf("row %d has %d cells, but row 1 has %d", r+1, len(cells), width)
+		}
+		for c, name := range cells {
+			if name == emptyCell {
+				continue
+			}
+			if !IsPanel(name) {
+				return fmt.Errorf("row %d: no such panel %q", r+1, name)
+			}
+			b, ok := boxes[name]
+			if !ok {
+				boxes[name] = &box{r, c, r, c, 1}
+				continue
+			}
+			b.top, b.left = min(b.top, r), min(b.left, c)
+			b.bottom, b.right = max(b.bottom, r), max(b.right, c)
+			b.cells++
+		}
+	}
+	for name, b := range boxes {
+		if (b.bottom-b.top+1)*(b.right-b.left+1) != b.cells {
+			return fmt.Errorf("panel %q must fill a rectangle", name)
+		}
+	}
+
+	if !trackSizesRE.MatchString(v.Columns) {
+		return fmt.Errorf("bad column sizes %q", v.Columns)
+	}
+	if !trackSizesRE.MatchString(v.Rows) {
+		return fmt.Errorf("bad row sizes %q", v.Rows)
+	}
+	return nil
+}
+
+// Validate checks a whole layout.
+func Validate(l *model.Layout) error {
+	if strings.TrimSpace(l.Name) == "" {
+		return errors.New("layout needs a name")
+	}
+	if err := ValidateVariant(&l.Landscape); err != nil {
+		return fmt.Errorf("landscape: %w", err)
+	}
+	if err := ValidateVariant(&l.Portrait); err != nil {
+		return fmt.Errorf("portrait: %w", err)
+	}
+	if slices.Contains(Used(l), "image") && l.ImageURL == "" && l.QRCodeText == "" {
+		return errors.New("the image panel needs an image URL or QR code text")
+	}
+	return nil
+}
+
+func variantUses(v *model.LayoutVariant, name string) bool {
+	for _, row := range v.Areas {
+		if slices.Contains(strings.Fields(row), name) {
+			return true
+		}
+	}
+	return false
+}
+
+// Used returns the panels that appear in either variant, in drawing order.
+func Used(l *model.Layout) []string {
+	used := []string{}
+	for _, p := range Panels {
+		if variantUses(&l.Landscape, p.Name) || variantUses(&l.Portrait, p.Name) {
+			used = append(used, p.Name)
+		}
+	}
+	return used
+}
+
+func writeGrid(sb *strings.Builder, indent string, v *model.LayoutVariant) {
+	fmt.Fprintf(sb, "%s.layout-grid {\n%s    grid-template-areas:", indent, indent)
+	for _, row := range v.Areas {
+		fmt.Fprintf(sb, " %q", row)
+	}
+	sb.WriteString(";\n")
+	if v.Columns != "" {
+		fmt.Fprintf(sb, "%s    grid-template-columns: %s;\n", indent, v.Columns)
+	}
+	if v.Rows != "" {
+		fmt.Fprintf(sb, "%s    grid-template-rows: %s;\n", indent, v.Rows)
+	}
+	fmt.Fprintf(sb, "%s}\n", indent)
+}
+
+// StyleSheet renders a validated layout as CSS for the view-layout template.
+// Panels that are only in one variant are hidden in the other orientation.
+func StyleSheet(l *model.Layout) string {
+	sb := &strings.Builder{}
+	writeGrid(sb, "", &l.Landscape)
+	used := Used(l)
+	for _, name := range used {
+		fmt.Fprintf(sb, ".layout-panel-%s { grid-area: %s; }\n", name, name)
+	}
+
+	sb.WriteString("@media (orientation: portrait) {\n")
+	writeGrid(sb, "    ", &l.Portrait)
+	for _, name := range used {
+		if !variantUses(&l.Portrait, name) {
+			fmt.Fprintf(sb, "    .layout-panel-%s { display: none; }\n", name)
+		}
+	}
+	sb.WriteString("}\n")
+
+	sb.WriteString("@media not (orientation: portrait) {\n")
+	for _, name := range used {
+		if !variantUses(&l.Landscape, name) {
+			fmt.Fprintf(sb, "    .layout-panel-%s { display: none; }\n", name)
+		}
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
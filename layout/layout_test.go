@@ -0,0 +1,91 @@
+package layout
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ts4z/irata/model"
+)
+
+func TestParseAreas(t *testing.T) {
+	got := ParseAreas("clock   clock stats\n\n  blinds blinds stats  \r\n")
+	want := []string{"clock clock stats", "blinds blinds stats"}
+	if !slices.Equal(got, want) {
+		t.Errorf("ParseAreas = %q, want %q", got, want)
+	}
+}
+
+func TestValidateVariant(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		v       model.LayoutVariant
+		wantErr string
+	}{
+		{"ok", model.LayoutVariant{Areas: []string{"clock clock stats", "blinds footer stats"}, Columns: "1fr 1fr minmax(0, 1fr)"}, ""},
+		{"empty cells ok", model.LayoutVariant{Areas: []string{"clock .", ". image"}}, ""},
+		{"no rows", model.LayoutVariant{}, "no rows"},
+		{"ragged", model.LayoutVariant{Areas: []string{"clock stats", "blinds"}}, "row 2 has 1 cells"},
+		{"unknown panel", model.LayoutVariant{Areas: []string{"clock weather"}}, `no such panel "weather"`},
+		{"L-shaped", model.LayoutVariant{Areas: []string{"clock clock", "clock stats"}}, `"clock" must fill a rectangle`},
+		{"split", model.LayoutVariant{Areas: []string{"clock stats clock"}}, `"clock" must fill a rectangle`},
+		{"css injection", model.LayoutVariant{Areas: []string{"clock"}, Rows: "1fr; } body { display: none"}, "bad row sizes"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := ValidateVariant(&tc.v)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestValidateImageNeedsSource(t *testing.T) {
+	l := &model.Layout{
+		Name:      "sponsor",
+		Landscape: model.LayoutVariant{Areas: []string{"clock image"}},
+		Portrait:  model.LayoutVariant{Areas: []string{"clock"}},
+	}
+	if err := Validate(l); err == nil {
+		t.Error("image panel with no image validated")
+	}
+	l.QRCodeText = "https://example.com/"
+	if err := Validate(l); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestStyleSheet(t *testing.T) {
+	l := &model.Layout{
+		Name:      "test",
+		Landscape: model.LayoutVariant{Areas: []string{"clock stats", "blinds stats"}, Columns: "3fr 1fr"},
+		Portrait:  model.LayoutVariant{Areas: []string{"clock", "blinds", "seating"}},
+	}
+	if got, want := Used(l), []string{"clock", "blinds", "stats", "seating"}; !slices.Equal(got, want) {
+		t.Errorf("Used = %q, want %q", got, want)
+	}
+
+	css := StyleSheet(l)
+	for _, want := range []string{
+		`grid-template-areas: "clock stats" "blinds stats";`,
+		`grid-template-columns: 3fr 1fr;`,
+		`grid-template-areas: "clock" "blinds" "seating";`,
+		`.layout-panel-seating { grid-area: seating; }`,
+	} {
+		if !strings.Contains(css, want) {
+			t.Errorf("style sheet missing %q:\n%s", want, css)
+		}
+	}
+
+	portrait, landscape, _ := strings.Cut(css[strings.Index(css, "@media (orientation: portrait)"):], "@media not")
+	if !strings.Contains(portrait, ".layout-panel-stats { display: none; }") {
+		t.Errorf("stats should be hidden in portrait:\n%s", css)
+	}
+	if !strings.Contains(landscape, ".layout-panel-seating { display: none; }") {
+		t.Errorf("seating should be hidden in landscape:\n%s", css)
+	}
+}
@@ -0,0 +1,305 @@
+// Package league scores tournament standings into season leaderboards.
+//
+// A league's tournaments each have final standings, entered by hand when
+// they finish.  Each finish is worth points by the league's formula, from
+// the place, the field size (the tournament's buy-ins) and the buy-in; a
+// player's points for the season are the sum of their finishes, or of
+// their best few.
+package league
+
+import (
+	"cmp"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/model"
+)
+
+const (
+	// MaxTable is how many places a points table can have.
+	MaxTable = 100
+
+	// SlideRows is how many players a leaderboard slide shows.
+	SlideRows = 10
+)
+
+type Kind struct {
+	Kind        model.PointsKind
+	Description string
+}
+
+// Kinds are the points formulas, in the order the editor offers them.
+var Kinds = []Kind{
+	{model.PointsTable, "Fixed points for each place"},
+	{model.PointsField, "A point for each player outlasted, plus one"},
+	{model.PointsSqrt, "Square root of field size over place"},
+}
+
+// Validate checks a league from the editor.
+func Validate(l *model.League) error {
+	if strings.TrimSpace(l.Name) == "" {
+		return errors.New("league needs a name")
+	}
+	f := &l.Formula
+	if !slices.ContainsFunc(Kinds, func(k Kind) bool { return k.Kind == f.Kind }) {
+		return fmt.Errorf("no such points formula %q", f.Kind)
+	}
+	if f.Kind == model.PointsTable && len(f.Table) == 0 {
+		return errors.New("points table is empty")
+	}
+	if len(f.Table) > MaxTable {
+		return fmt.Errorf("points table has more than %d places", MaxTable)
+	}
+	for _, p := range f.Table {
+		if p < 0 || math.IsNaN(p) || math.IsInf(p, 0) {
+			return fmt.Errorf("bad points %v in table", p)
+		}
+	}
+	if f.Scale < 0 || f.BaseBuyIn < 0 || f.Participation < 0 || l.CountBest < 0 {
+		return errors.New("scale, base buy-in, participation points and best finishes can't be negative")
+	}
+	return nil
+}
+
+// ParseTable reads a points table, as "10, 7, 5" or one number per line.
+func ParseTable(text string) ([]float64, error) {
+	table := []float64{}
+	for _, f := range strings.FieldsFunc(text, func(r rune) bool {
+		return r == ',' || r == ' ' || r == '\t' || r == '\n' || r == '\r'
+	}) {
+		p, err := strconv.ParseFloat(f, 64)
+		if err != nil {
+			return nil, fmt.Errorf("bad points %q", f)
+		}
+		table = append(table, p)
+	}
+	return table, nil
+}
+
+// FormatTable is the inverse of ParseTable.
+func FormatTable(table []float64) string {
+	s := make([]string, len(table))
+	for i, p := range table {
+		s[i] = FormatPoints(p)
+	}
+	return strings.Join(s, ", ")
+}
+
+// FormatPoints writes points without trailing zeroes.
+func FormatPoints(p float64) string {
+	return strconv.FormatFloat(p, 'f', -1, 64)
+}
+
+// Points is what finishing in place, in a field of this size, at this
+// buy-in, is worth.  Points are rounded to hundredths.
+func Points(f model.PointsFormula, place, field, buyIn int) float64 {
+	if place < 1 {
+		return 0
+	}
+	// Nobody finishes out of the field; the buy-in count may be short.
+	field = max(field, place)
+
+	var p float64
+	switch f.Kind {
+	case model.PointsTable:
+		if place <= len(f.Table) {
+			p = f.Table[place-1]
+		}
+	case model.PointsField:
+		p = float64(field - place + 1)
+	case model.PointsSqrt:
+		p = math.Sqrt(float64(field) / float64(place))
+	}
+	if f.Scale != 0 {
+		p *= f.Scale
+	}
+	if f.BaseBuyIn > 0 && buyIn > 0 {
+		p *= float64(buyIn) / float64(f.BaseBuyIn)
+	}
+	p += f.Participation
+	return math.Round(p*100) / 100
+}
+
+// Event is one scored tournament.
+type Event struct {
+	TournamentID int64
+	Name         string
+	When         time.Time // zero if unknown
+	Field        int
+}
+
+// Result is one player's finish in one event.
+type Result struct {
+	Place  int
+	Points float64
+	// Counted is false for a finish that isn't among the player's best.
+	Counted bool
+}
+
+// Row is one player's line on the leaderboard.
+type Row struct {
+	Rank    int // players tied on points share a rank
+	Name    string
+	Points  float64
+	Played  int
+	Wins    int
+	Best    int       // best place
+	Results []*Result // parallel to Board.Events; nil where the player didn't play
+}
+
+// Board is a league's leaderboard.
+type Board struct {
+	League *model.League
+	Events []*Event
+	Rows   []*Row
+}
+
+func when(t *model.Tournament) time.Time {
+	if t.State != nil && !t.State.FinishedAt.IsZero() {
+		return t.State.FinishedAt
+	}
+	return t.ScheduledStart
+}
+
+// Leaderboard scores the league's tournaments.  Tournaments without
+// standings haven't been scored yet and are left out.  Players are matched
+// by name, ignoring case.
+func Leaderboard(l *model.League, tournaments []*model.Tournament) *Board {
+	tournaments = slices.DeleteFunc(slices.Clone(tournaments), func(t *model.Tournament) bool {
+		return t.State == nil || len(t.State.Standings) == 0
+	})
+	slices.SortStableFunc(tournaments, func(a, b *model.Tournament) int {
+		return cmp.Or(when(a).Compare(when(b)), cmp.Compare(a.EventID, b.EventID))
+	})
+
+	b := &Board{League: l, Events: []*Event{}, Rows: []*Row{}}
+	byName := map[string]*Row{}
+	for i, t := range tournaments {
+		field := max(t.State.BuyIns, len(t.State.Standings))
+		b.Events = append(b.Events, &Event{
+			TournamentID: t.EventID,
+			Name:         t.EventName,
+			When:         when(t),
+			Field:        field,
+		})
+		for _, s := range t.State.Standings {
+			key := strings.ToLower(s.Name)
+			row, ok := byName[key]
+			if !ok {
+				row = &Row{Name: s.Name, Results: make([]*Result, len(tournaments))}
+				byName[key] = row
+				b.Rows = append(b.Rows, row)
+			}
+			if row.Results[i] != nil {
+				continue // listed twice; the tournament should have caught it
+			}
+			row.Results[i] = &Result{
+				Place:   s.Place,
+				Points:  Points(l.Formula, s.Place, field, t.PrizePoolPerBuyIn),
+				Counted: true,
+			}
+		}
+	}
+
+	for _, row := range b.Rows {
+		results := []*Result{}
+		for _, r := range row.Results {
+			if r != nil {
+				results = append(results, r)
+			}
+		}
+		slices.SortStableFunc(results, func(a, b *Result) int { return cmp.Compare(b.Points, a.Points) })
+		for i, r := range results {
+			if l.CountBest > 0 && i >= l.CountBest {
+				r.Counted = false
+				continue
+			}
+			row.Points += r.Points
+		}
+		row.Points = math.Round(row.Points*100) / 100
+		row.Played = len(results)
+		for _, r := range results {
+			if r.Place == 1 {
+				row.Wins++
+			}
+			if row.Best == 0 || r.Place < row.Best {
+				row.Best = r.Place
+			}
+		}
+	}
+
+	slices.SortStableFunc(b.Rows, func(x, y *Row) int {
+		return cmp.Or(
+			cmp.Compare(y.Points, x.Points),
+			cmp.Compare(y.Wins, x.Wins),
+			cmp.Compare(x.Best, y.Best),
+			cmp.Compare(strings.ToLower(x.Name), strings.ToLower(y.Name)))
+	})
+	for i, row := range b.Rows {
+		row.Rank = i + 1
+		if i > 0 && row.Points == b.Rows[i-1].Points {
+			row.Rank = b.Rows[i-1].Rank
+		}
+	}
+	return b
+}
+
+// TopRows is the head of the board, as a slide shows it.
+func (b *Board) TopRows() []*Row {
+	return b.Rows[:min(SlideRows, len(b.Rows))]
+}
+
+// csvText keeps spreadsheets from taking a name for a formula.
+func csvText(s string) string {
+	if s != "" && strings.ContainsRune("=+-@", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// WriteCSV writes the leaderboard as a spreadsheet: a row per player with
+// their totals and their points from each event, which is blank where they
+// didn't play.
+func WriteCSV(w io.Writer, b *Board) error {
+	cw := csv.NewWriter(w)
+	header := []string{"Rank", "Name", "Points", "Played", "Wins", "Best"}
+	for _, e := range b.Events {
+		name := e.Name
+		if !e.When.IsZero() {
+			name += " " + e.When.Format(time.DateOnly)
+		}
+		header = append(header, csvText(name))
+	}
+	if err := cw.Write(header); err != nil {
+		return err
+	}
+	for _, row := range b.Rows {
+		record := []string{
+			strconv.Itoa(row.Rank),
+			csvText(row.Name),
+			FormatPoints(row.Points),
+			strconv.Itoa(row.Played),
+			strconv.Itoa(row.Wins),
+			strconv.Itoa(row.Best),
+		}
+		for _, r := range row.Results {
+			if r == nil {
+				record = append(record, "")
+			} else {
+				record = append(record, FormatPoints(r.Points))
+			}
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
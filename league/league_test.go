@@ -0,0 +1,152 @@
+package league
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/model"
+)
+
+func TestPoints(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		f            model.PointsFormula
+		place, field int
+		buyIn        int
+		want         float64
+	}{
+		{"table", model.PointsFormula{Kind: model.PointsTable, Table: []float64{10, 7, 5}}, 2, 20, 0, 7},
+		{"table out of the points", model.PointsFormula{Kind: model.PointsTable, Table: []float64{10, 7, 5}}, 4, 20, 0, 0},
+		{"participation", model.PointsFormula{Kind: model.PointsTable, Table: []float64{10}, Participation: 1}, 4, 20, 0, 1},
+		{"field", model.PointsFormula{Kind: model.PointsField}, 1, 12, 0, 12},
+		{"field last", model.PointsFormula{Kind: model.PointsField}, 12, 12, 0, 1},
+		{"field short count", model.PointsFormula{Kind: model.PointsField}, 14, 12, 0, 1},
+		{"sqrt", model.PointsFormula{Kind: model.PointsSqrt, Scale: 10}, 4, 16, 0, 20},
+		{"sqrt rounds", model.PointsFormula{Kind: model.PointsSqrt}, 3, 10, 0, 1.83},
+		{"buy-in", model.PointsFormula{Kind: model.PointsField, BaseBuyIn: 20}, 1, 10, 40, 20},
+		{"no place", model.PointsFormula{Kind: model.PointsField}, 0, 10, 0, 0},
+	} {
+		if got := Points(tc.f, tc.place, tc.field, tc.buyIn); got != tc.want {
+			t.Errorf("%s: got %v, want %v", tc.name, got, tc.want)
+		}
+	}
+}
+
+func tournament(id int64, day int, buyIns int, standings ...string) *model.Tournament {
+	t := &model.Tournament{
+		EventID:   id,
+		EventName: "Game",
+		State: &model.State{
+			BuyIns:     buyIns,
+			FinishedAt: time.Date(2026, 3, day, 23, 0, 0, 0, time.UTC),
+		},
+	}
+	for i, name := range standings {
+		t.State.Standings = append(t.State.Standings, &model.Standing{Place: i + 1, Name: name})
+	}
+	return t
+}
+
+func TestLeaderboard(t *testing.T) {
+	l := &model.League{
+		Name:    "Home",
+		Formula: model.PointsFormula{Kind: model.PointsTable, Table: []float64{10, 6, 3}},
+	}
+	b := Leaderboard(l, []*model.Tournament{
+		tournament(2, 8, 3, "Bob", "alice", "Carol"),
+		tournament(1, 1, 3, "Alice", "Bob", "Carol"),
+		tournament(3, 15, 0), // not scored yet
+	})
+
+	if len(b.Events) != 2 || b.Events[0].TournamentID != 1 {
+		t.Fatalf("events out of order or unscored event included: %+v", b.Events)
+	}
+	want := []struct {
+		rank   int
+		name   string
+		points float64
+		wins   int
+	}{
+		{1, "Alice", 16, 1},
+		{1, "Bob", 16, 1},
+		{3, "Carol", 6, 0},
+	}
+	if len(b.Rows) != len(want) {
+		t.Fatalf("got %d rows, want %d", len(b.Rows), len(want))
+	}
+	for i, w := range want {
+		r := b.Rows[i]
+		if r.Rank != w.rank || r.Name != w.name || r.Points != w.points || r.Wins != w.wins || r.Played != 2 {
+			t.Errorf("row %d: got %+v, want %+v", i, r, w)
+		}
+	}
+}
+
+func TestLeaderboardCountsBest(t *testing.T) {
+	l := &model.League{
+		Name:      "Home",
+		Formula:   model.PointsFormula{Kind: model.PointsTable, Table: []float64{10, 6, 3}},
+		CountBest: 1,
+	}
+	b := Leaderboard(l, []*model.Tournament{
+		tournament(1, 1, 3, "Alice", "Bob"),
+		tournament(2, 8, 3, "Bob", "Alice"),
+	})
+	for _, r := range b.Rows {
+		if r.Points != 10 {
+			t.Errorf("%s has %v points, want 10", r.Name, r.Points)
+		}
+	}
+	if alice := b.Rows[0]; alice.Results[1].Counted {
+		t.Errorf("Alice's second place shouldn't count")
+	}
+}
+
+func TestWriteCSV(t *testing.T) {
+	l := &model.League{Formula: model.PointsFormula{Kind: model.PointsField}}
+	b := Leaderboard(l, []*model.Tournament{
+		tournament(1, 1, 4, "Alice", "=cmd"),
+		tournament(2, 2, 2, "Alice"),
+	})
+	sb := &strings.Builder{}
+	if err := WriteCSV(sb, b); err != nil {
+		t.Fatal(err)
+	}
+	want := "Rank,Name,Points,Played,Wins,Best,Game 2026-03-01,Game 2026-03-02\n" +
+		"1,Alice,6,2,2,1,4,2\n" +
+		"2,'=cmd,3,1,0,2,3,\n"
+	if got := sb.String(); got != want {
+		t.Errorf("got\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestParseTable(t *testing.T) {
+	table, err := ParseTable("10, 7.5\n5 3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := FormatTable(table); got != "10, 7.5, 5, 3" {
+		t.Errorf("got %q", got)
+	}
+	if _, err := ParseTable("10, x"); err == nil {
+		t.Error("ParseTable should reject x")
+	}
+}
+
+func TestValidate(t *testing.T) {
+	good := &model.League{Name: "Home", Formula: model.PointsFormula{Kind: model.PointsSqrt}}
+	if err := Validate(good); err != nil {
+		t.Errorf("Validate(good) = %v", err)
+	}
+	for _, bad := range []*model.League{
+		{Formula: model.PointsFormula{Kind: model.PointsSqrt}},
+		{Name: "Home", Formula: model.PointsFormula{Kind: "dice"}},
+		{Name: "Home", Formula: model.PointsFormula{Kind: model.PointsTable}},
+		{Name: "Home", Formula: model.PointsFormula{Kind: model.PointsField, Scale: -1}},
+	} {
+		if err := Validate(bad); err == nil {
+			t.Errorf("Validate(%+v) should fail", bad)
+		}
+	}
+}
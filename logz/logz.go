@@ -0,0 +1,209 @@
+/*
+logz sets up log/slog for irata: text or JSON output, a level for each
+package, and the request ID of whatever the log line is about.
+
+A package makes its logger once, with New, and logs through it with a
+context where it has one:
+
+	var logger = logz.New()
+
+	logger.InfoContext(ctx, "notified listeners", "tournament", id)
+
+Plain log.Printf still works; it goes through the same output at Info, but
+without a package or a request ID.
+*/
+package logz
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path"
+	"runtime"
+	"strings"
+	"sync/atomic"
+)
+
+// Config says how to log.  The zero value logs text at Info to stderr.
+type Config struct {
+	// Format is "text" or "json".
+	Format string
+
+	// Level is the level for everything not named in Levels.
+	Level string
+
+	// Levels are "package=level" pairs, like "gossip=debug".  A package is
+	// named by the last part of its path, or by the whole path.
+	Levels []string
+
+	// Writer is where the logs go; nil is stderr.
+	Writer io.Writer
+}
+
+// settings is everything Setup decides, swapped in all at once.
+type settings struct {
+	handler slog.Handler
+	level   slog.Level
+	levels  map[string]slog.Level
+}
+
+var current atomic.Pointer[settings]
+
+func init() {
+	current.Store(&settings{
+		handler: slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelDebug}),
+		level:   slog.LevelInfo,
+	})
+}
+
+// Setup starts logging as c says, for loggers from New and for the log
+// package alike.
+func Setup(c *Config) error {
+	level, err := ParseLevel(c.Level)
+	if err != nil {
+		return err
+	}
+	levels := map[string]slog.Level{}
+	for _, pair := range c.Levels {
+		pkg, l, ok := strings.Cut(pair, "=")
+		if !ok || pkg == "" {
+			return fmt.Errorf("log level %q isn't package=level", pair)
+		}
+		if levels[pkg], err = ParseLevel(l); err != nil {
+			return err
+		}
+	}
+
+	w := c.Writer
+	if w == nil {
+		w = os.Stderr
+	}
+	// Levels are checked by our handler; the inner one takes everything.
+	opts := &slog.HandlerOptions{Level: slog.Level(-100)}
+	var h slog.Handler
+	switch strings.ToLower(c.Format) {
+	case "", "text":
+		h = slog.NewTextHandler(w, opts)
+	case "json":
+		h = slog.NewJSONHandler(w, opts)
+	default:
+		return fmt.Errorf("unknown log format %q, want text or json", c.Format)
+	}
+
+	current.Store(&settings{handler: h, level: level, levels: levels})
+	// This sends the log package through slog too, at Info.
+	slog.SetDefault(slog.New(&handler{}))
+	return nil
+}
+
+// ParseLevel parses a level name (debug, info, warn, error), or "" for
+// Info.
+func ParseLevel(s string) (slog.Level, error) {
+	var l slog.Level
+	if s == "" {
+		return slog.LevelInfo, nil
+	}
+	if err := l.UnmarshalText([]byte(s)); err != nil {
+		return 0, fmt.Errorf("bad log level %q: %w", s, err)
+	}
+	return l, nil
+}
+
+// New makes a logger for the calling package.  Its lines say which
+// package they came from, and obey that package's level.
+func New() *slog.Logger {
+	return slog.New(&handler{pkg: callerPackage()})
+}
+
+// callerPackage is the import path of New's caller.
+func callerPackage() string {
+	pc, _, _, ok := runtime.Caller(2)
+	if !ok {
+		return ""
+	}
+	fn := runtime.FuncForPC(pc)
+	if fn == nil {
+		return ""
+	}
+	n := fn.Name()
+	// Cut at the first dot after the last slash: "a/b/pkg.init.0" is
+	// "a/b/pkg".
+	slash := strings.LastIndex(n, "/")
+	if dot := strings.Index(n[slash+1:], "."); dot != -1 {
+		n = n[:slash+1+dot]
+	}
+	return n
+}
+
+// handler adds the package and request ID to each record, checks the
+// package's level, and passes the rest on to whatever Setup chose.
+type handler struct {
+	pkg string // import path; "" for the log package and slog.Default
+
+	// attrs and groups made by With and WithGroup, replayed onto the
+	// current inner handler, since Setup may replace it after we're made.
+	ops []func(slog.Handler) slog.Handler
+}
+
+func (h *handler) level(s *settings) slog.Level {
+	if h.pkg != "" {
+		if l, ok := s.levels[h.pkg]; ok {
+			return l
+		}
+		if l, ok := s.levels[path.Base(h.pkg)]; ok {
+			return l
+		}
+	}
+	return s.level
+}
+
+func (h *handler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= h.level(current.Load())
+}
+
+func (h *handler) Handle(ctx context.Context, r slog.Record) error {
+	s := current.Load()
+	if h.pkg != "" {
+		r.AddAttrs(slog.String("pkg", path.Base(h.pkg)))
+	}
+	if id := RequestID(ctx); id != "" {
+		r.AddAttrs(slog.String("request_id", id))
+	}
+	inner := s.handler
+	for _, op := range h.ops {
+		inner = op(inner)
+	}
+	return inner.Handle(ctx, r)
+}
+
+func (h *handler) with(op func(slog.Handler) slog.Handler) *handler {
+	ops := append(h.ops[:len(h.ops):len(h.ops)], op)
+	return &handler{pkg: h.pkg, ops: ops}
+}
+
+func (h *handler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithAttrs(attrs) })
+}
+
+func (h *handler) WithGroup(name string) slog.Handler {
+	return h.with(func(inner slog.Handler) slog.Handler { return inner.WithGroup(name) })
+}
+
+type requestIDKey struct{}
+
+// WithRequestID returns ctx carrying a request ID, which every line logged
+// with it will show.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+// RequestID is the request ID ctx carries, or "".
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
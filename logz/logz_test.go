@@ -0,0 +1,89 @@
+package logz
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+var testLogger = New()
+
+func TestCallerPackage(t *testing.T) {
+	h := testLogger.Handler().(*handler)
+	if h.pkg != "github.com/ts4z/irata/logz" {
+		t.Errorf("package is %q", h.pkg)
+	}
+}
+
+func setup(t *testing.T, c *Config) *bytes.Buffer {
+	t.Helper()
+	buf := &bytes.Buffer{}
+	c.Writer = buf
+	if err := Setup(c); err != nil {
+		t.Fatal(err)
+	}
+	return buf
+}
+
+func TestJSONWithRequestID(t *testing.T) {
+	buf := setup(t, &Config{Format: "json"})
+	ctx := WithRequestID(context.Background(), "req-1")
+	testLogger.With("a", 1).InfoContext(ctx, "hello", "b", 2)
+
+	var got map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("%v in %q", err, buf.String())
+	}
+	for k, want := range map[string]any{
+		"msg":        "hello",
+		"pkg":        "logz",
+		"request_id": "req-1",
+		"a":          1.0,
+		"b":          2.0,
+	} {
+		if got[k] != want {
+			t.Errorf("%s = %v, want %v", k, got[k], want)
+		}
+	}
+}
+
+func TestPackageLevels(t *testing.T) {
+	buf := setup(t, &Config{Level: "warn", Levels: []string{"logz=debug"}})
+	testLogger.Debug("from logz")
+	log.Printf("from log")
+	slog.Info("from slog")
+	slog.Warn("warned")
+	out := buf.String()
+	if !strings.Contains(out, "from logz") {
+		t.Errorf("package level ignored:\n%s", out)
+	}
+	if strings.Contains(out, `msg="from log"`) || strings.Contains(out, `msg="from slog"`) {
+		t.Errorf("global level ignored:\n%s", out)
+	}
+	if !strings.Contains(out, "warned") {
+		t.Errorf("warning missing:\n%s", out)
+	}
+
+	buf = setup(t, &Config{Levels: []string{"github.com/ts4z/irata/logz=error"}})
+	testLogger.Warn("quiet")
+	if buf.Len() != 0 {
+		t.Errorf("full package path ignored:\n%s", buf.String())
+	}
+}
+
+func TestSetupErrors(t *testing.T) {
+	for _, c := range []*Config{
+		{Format: "xml"},
+		{Level: "loud"},
+		{Levels: []string{"gossip"}},
+		{Levels: []string{"gossip=loud"}},
+	} {
+		if err := Setup(c); err == nil {
+			t.Errorf("Setup(%+v) worked", c)
+		}
+	}
+}
@@ -0,0 +1,247 @@
+// Package clientip works out who a request is really from.  Behind a proxy,
+// RemoteAddr is the proxy; the client is in the Forwarded or
+// X-Forwarded-For header, which anyone can write, so those are only
+// believed when the proxy that sent them is one we trust.  Only the header
+// the proxies write is read: a proxy that appends to X-Forwarded-For passes
+// a client's own Forwarded header along untouched.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/netip"
+	"strings"
+)
+
+// Client is where a request came from, as best we can tell.
+type Client struct {
+	// Addr is the client's address.  It's invalid if RemoteAddr wasn't an
+	// address, as in some tests.
+	Addr netip.Addr
+	// Scheme and Host are what the client asked for: "https" and
+	// "clock.example.com", say, even if the proxy spoke plain HTTP to us.
+	Scheme string
+	Host   string
+}
+
+// String is the address, or "unknown".
+func (c *Client) String() string {
+	if !c.Addr.IsValid() {
+		return "unknown"
+	}
+	return c.Addr.String()
+}
+
+// BaseURL is the scheme and host the client used, like
+// "https://clock.example.com".
+func (c *Client) BaseURL() string {
+	return c.Scheme + "://" + c.Host
+}
+
+// The headers a proxy may write.
+const (
+	HeaderForwarded     = "Forwarded"
+	HeaderXForwardedFor = "X-Forwarded-For"
+)
+
+// Trusted is the set of proxies whose forwarding headers are believed.
+type Trusted struct {
+	prefixes []netip.Prefix
+	header   string
+}
+
+// ParseTrusted takes CIDRs, or bare addresses, of trusted proxies, and the
+// header they write, HeaderForwarded or HeaderXForwardedFor.
+func ParseTrusted(cidrs []string, header string) (*Trusted, error) {
+	t := &Trusted{header: http.CanonicalHeaderKey(header)}
+	if t.header != HeaderForwarded && t.header != HeaderXForwardedFor {
+		return nil, fmt.Errorf("bad proxy header %q, want %s or %s", header, HeaderForwarded, HeaderXForwardedFor)
+	}
+	for _, s := range cidrs {
+		if !strings.Contains(s, "/") {
+			addr, err := netip.ParseAddr(s)
+			if err != nil {
+				return nil, fmt.Errorf("bad trusted proxy %q: %w", s, err)
+			}
+			t.prefixes = append(t.prefixes, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		p, err := netip.ParsePrefix(s)
+		if err != nil {
+			return nil, fmt.Errorf("bad trusted proxy %q: %w", s, err)
+		}
+		t.prefixes = append(t.prefixes, p.Masked())
+	}
+	return t, nil
+}
+
+func (t *Trusted) trusts(addr netip.Addr) bool {
+	if t == nil || !addr.IsValid() {
+		return false
+	}
+	addr = addr.Unmap()
+	for _, p := range t.prefixes {
+		if p.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// hop is one proxy's report of whom it heard from.
+type hop struct {
+	addr   netip.Addr
+	scheme string
+	host   string
+}
+
+// Resolve works out the client of r.  Hops are read right to left, from the
+// proxy nearest us, and the first one not from a trusted proxy is the
+// client.  Only the header the trusted proxies write is read.
+func (t *Trusted) Resolve(r *http.Request) *Client {
+	c := &Client{Addr: parseNode(r.RemoteAddr), Scheme: "http", Host: r.Host}
+	if r.TLS != nil {
+		c.Scheme = "https"
+	}
+	if !t.trusts(c.Addr) {
+		return c
+	}
+
+	var hops []hop
+	if t.header == HeaderForwarded {
+		hops = parseForwarded(r.Header.Values(HeaderForwarded))
+	} else {
+		hops = parseXFF(r.Header)
+	}
+	for i := len(hops) - 1; i >= 0; i-- {
+		h := hops[i]
+		if !h.addr.IsValid() {
+			// Unknown or obfuscated: the hop before it is as far as we
+			// can see.
+			break
+		}
+		c.Addr = h.addr
+		if h.scheme != "" {
+			c.Scheme = h.scheme
+		}
+		if h.host != "" {
+			c.Host = h.host
+		}
+		if !t.trusts(h.addr) {
+			break
+		}
+	}
+	return c
+}
+
+// parseForwarded reads RFC 7239 Forwarded headers, in order.
+func parseForwarded(values []string) []hop {
+	hops := []hop{}
+	for _, v := range values {
+		for _, element := range splitQuoted(v, ',') {
+			h := hop{}
+			for _, pair := range splitQuoted(element, ';') {
+				k, v, ok := strings.Cut(strings.TrimSpace(pair), "=")
+				if !ok {
+					continue
+				}
+				v = strings.Trim(v, `"`)
+				switch strings.ToLower(k) {
+				case "for":
+					h.addr = parseNode(v)
+				case "proto":
+					if v == "http" || v == "https" {
+						h.scheme = v
+					}
+				case "host":
+					h.host = v
+				}
+			}
+			hops = append(hops, h)
+		}
+	}
+	return hops
+}
+
+// parseXFF reads X-Forwarded-For, with X-Forwarded-Proto and
+// X-Forwarded-Host, which only the nearest proxy is taken to have set.
+func parseXFF(header http.Header) []hop {
+	hops := []hop{}
+	for _, v := range header.Values(HeaderXForwardedFor) {
+		for _, s := range strings.Split(v, ",") {
+			hops = append(hops, hop{addr: parseNode(strings.TrimSpace(s))})
+		}
+	}
+	if len(hops) > 0 {
+		last := &hops[len(hops)-1]
+		if proto := lastValue(header, "X-Forwarded-Proto"); proto == "http" || proto == "https" {
+			last.scheme = proto
+		}
+		last.host = lastValue(header, "X-Forwarded-Host")
+	}
+	return hops
+}
+
+func lastValue(header http.Header, key string) string {
+	values := header.Values(key)
+	if len(values) == 0 {
+		return ""
+	}
+	parts := strings.Split(values[len(values)-1], ",")
+	return strings.TrimSpace(parts[len(parts)-1])
+}
+
+// parseNode parses an address with an optional port: "192.0.2.1",
+// "192.0.2.1:80", "[2001:db8::1]:80" or "2001:db8::1".  Anything else, like
+// "unknown", is invalid.
+func parseNode(s string) netip.Addr {
+	if host, _, err := net.SplitHostPort(s); err == nil {
+		s = host
+	}
+	s = strings.TrimSuffix(strings.TrimPrefix(s, "["), "]")
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Addr{}
+	}
+	return addr.Unmap()
+}
+
+// splitQuoted splits s at sep, except inside double quotes.
+func splitQuoted(s string, sep byte) []string {
+	parts := []string{}
+	quoted := false
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case s[i] == '"':
+			quoted = !quoted
+		case s[i] == '\\' && quoted:
+			i++
+		case s[i] == sep && !quoted:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+type contextKey struct{}
+
+// Handler puts the Client in the request's context for everything after it.
+func Handler(t *Trusted, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ctx := context.WithValue(r.Context(), contextKey{}, t.Resolve(r))
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Of returns the Client that Handler found for r.  Without Handler, no
+// proxy is trusted.
+func Of(r *http.Request) *Client {
+	if c, ok := r.Context().Value(contextKey{}).(*Client); ok {
+		return c
+	}
+	return (*Trusted)(nil).Resolve(r)
+}
@@ -0,0 +1,186 @@
+package clientip
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestResolve(t *testing.T) {
+	proxies := []string{"10.0.0.0/8", "::1", "2001:db8:ffff::/48"}
+	trusted := map[string]*Trusted{}
+	for _, header := range []string{HeaderXForwardedFor, HeaderForwarded} {
+		var err error
+		if trusted[header], err = ParseTrusted(proxies, header); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, tc := range []struct {
+		name       string
+		header     string // that the proxies write; X-Forwarded-For if empty
+		remoteAddr string
+		headers    map[string][]string
+		tls        bool
+		want       string
+		wantBase   string
+	}{
+		{name: "direct", remoteAddr: "198.51.100.7:5555", want: "198.51.100.7", wantBase: "http://clock.example.com"},
+		{name: "direct TLS", remoteAddr: "198.51.100.7:5555", tls: true, want: "198.51.100.7", wantBase: "https://clock.example.com"},
+		{
+			name:       "spoofed XFF from an untrusted peer",
+			remoteAddr: "198.51.100.7:5555",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}, "X-Forwarded-Proto": {"https"}},
+			want:       "198.51.100.7",
+			wantBase:   "http://clock.example.com",
+		},
+		{
+			name:       "XFF through one proxy",
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.7"}, "X-Forwarded-Proto": {"https"}, "X-Forwarded-Host": {"poker.example.org"}},
+			want:       "198.51.100.7",
+			wantBase:   "https://poker.example.org",
+		},
+		{
+			name:       "XFF with a spoofed entry on the left",
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4, 198.51.100.7", "10.2.2.2"}},
+			want:       "198.51.100.7",
+			wantBase:   "http://clock.example.com",
+		},
+		{
+			name:       "XFF all trusted",
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"X-Forwarded-For": {"10.3.3.3, 10.2.2.2"}},
+			want:       "10.3.3.3",
+			wantBase:   "http://clock.example.com",
+		},
+		{
+			name:       "XFF garbage",
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"X-Forwarded-For": {"198.51.100.7, not-an-ip"}},
+			want:       "10.1.1.1",
+			wantBase:   "http://clock.example.com",
+		},
+		{
+			name:       "client's own Forwarded through an XFF proxy",
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"Forwarded": {"for=1.2.3.4;proto=https;host=evil.example.com"}, "X-Forwarded-For": {"198.51.100.7"}},
+			want:       "198.51.100.7",
+			wantBase:   "http://clock.example.com",
+		},
+		{
+			name:       "client's own Forwarded and no XFF",
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"Forwarded": {"for=1.2.3.4"}},
+			want:       "10.1.1.1",
+			wantBase:   "http://clock.example.com",
+		},
+		{
+			name:       "Forwarded",
+			header:     HeaderForwarded,
+			remoteAddr: "[::1]:5555",
+			headers:    map[string][]string{"Forwarded": {`for="[2001:db8:cafe::17]:4711";proto=https;host=poker.example.org`}},
+			want:       "2001:db8:cafe::17",
+			wantBase:   "https://poker.example.org",
+		},
+		{
+			name:       "Forwarded across headers, ignoring XFF",
+			header:     HeaderForwarded,
+			remoteAddr: "[::1]:5555",
+			headers: map[string][]string{
+				"Forwarded":       {"for=1.2.3.4, for=198.51.100.7;proto=https", `for="[2001:db8:ffff::2]";by=_proxy`},
+				"X-Forwarded-For": {"203.0.113.9"},
+			},
+			want:     "198.51.100.7",
+			wantBase: "https://clock.example.com",
+		},
+		{
+			name:       "Forwarded obfuscated",
+			header:     HeaderForwarded,
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"Forwarded": {"for=_hidden, for=10.2.2.2"}},
+			want:       "10.2.2.2",
+			wantBase:   "http://clock.example.com",
+		},
+		{
+			name:       "Forwarded with a quoted comma",
+			header:     HeaderForwarded,
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"Forwarded": {`for=198.51.100.7;host="a,b"`}},
+			want:       "198.51.100.7",
+			wantBase:   "http://a,b",
+		},
+		{
+			name:       "client's own XFF through a Forwarded proxy",
+			header:     HeaderForwarded,
+			remoteAddr: "10.1.1.1:5555",
+			headers:    map[string][]string{"X-Forwarded-For": {"1.2.3.4"}, "Forwarded": {"for=198.51.100.7"}},
+			want:       "198.51.100.7",
+			wantBase:   "http://clock.example.com",
+		},
+		{
+			name:       "IPv4 mapped",
+			remoteAddr: "[::ffff:10.1.1.1]:5555",
+			headers:    map[string][]string{"X-Forwarded-For": {"::ffff:198.51.100.7"}},
+			want:       "198.51.100.7",
+			wantBase:   "http://clock.example.com",
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodGet, "http://clock.example.com/", nil)
+			r.RemoteAddr = tc.remoteAddr
+			for k, vs := range tc.headers {
+				for _, v := range vs {
+					r.Header.Add(k, v)
+				}
+			}
+			if tc.tls {
+				r.TLS = &tls.ConnectionState{}
+			}
+			header := tc.header
+			if header == "" {
+				header = HeaderXForwardedFor
+			}
+			c := trusted[header].Resolve(r)
+			if c.String() != tc.want || c.BaseURL() != tc.wantBase {
+				t.Errorf("got %s %s, want %s %s", c, c.BaseURL(), tc.want, tc.wantBase)
+			}
+		})
+	}
+}
+
+func TestParseTrusted(t *testing.T) {
+	if _, err := ParseTrusted([]string{"10.0.0.0/33"}, HeaderXForwardedFor); err == nil {
+		t.Error("no error for a bad prefix")
+	}
+	if _, err := ParseTrusted([]string{"proxy.example.com"}, HeaderXForwardedFor); err == nil {
+		t.Error("no error for a name")
+	}
+	if _, err := ParseTrusted([]string{"10.0.0.0/8"}, "X-Real-IP"); err == nil {
+		t.Error("no error for an unknown header")
+	}
+	if _, err := ParseTrusted([]string{"10.0.0.0/8"}, "forwarded"); err != nil {
+		t.Errorf("header case matters: %v", err)
+	}
+}
+
+func TestHandler(t *testing.T) {
+	trusted, _ := ParseTrusted([]string{"127.0.0.1"}, HeaderXForwardedFor)
+	var got *Client
+	h := Handler(trusted, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = Of(r)
+	}))
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	r.RemoteAddr = "127.0.0.1:1234"
+	r.Header.Set("X-Forwarded-For", "198.51.100.7")
+	h.ServeHTTP(httptest.NewRecorder(), r)
+	if got == nil || got.String() != "198.51.100.7" {
+		t.Errorf("got %v", got)
+	}
+
+	// Without the handler, nobody is trusted.
+	if c := Of(r); c.String() != "127.0.0.1" {
+		t.Errorf("Of without Handler = %v", c)
+	}
+}
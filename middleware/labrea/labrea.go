@@ -10,6 +10,8 @@ import (
 	"strings"
 	"sync"
 	"time"
+
+	"github.com/ts4z/irata/middleware/clientip"
 )
 
 type Clock interface {
@@ -108,7 +110,7 @@ func (h *Tarpit) flush(w http.ResponseWriter) {
 }
 
 func (h *Tarpit) Mishandle(w http.ResponseWriter, r *http.Request) {
-	minimum := time.Duration(11*h.countIP(r.RemoteAddr)) * time.Millisecond
+	minimum := time.Duration(11*h.countIP(clientip.Of(r).String())) * time.Millisecond
 	initialDelay := h.randomDelay(minimum, 3*time.Second)
 	time.Sleep(initialDelay)
 
@@ -172,7 +174,7 @@ func (h *Tarpit) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	if _, ok := h.paths[path]; ok {
 		h.Mishandle(w, r)
 		// duration := h.clock.Now().Sub(start)
-		// log.Printf("[tarpit] 404 %v %v (%v)", r.RemoteAddr, r.URL.Path, duration)
+		// log.Printf("[tarpit] 404 %v %v (%v)", clientip.Of(r), r.URL.Path, duration)
 		return
 	}
 
@@ -1,11 +1,21 @@
 package middleware
 
 import (
-	"log"
 	"net/http"
+	"strconv"
 	"time"
+
+	"github.com/ts4z/irata/logz"
+	"github.com/ts4z/irata/middleware/clientip"
+	"github.com/ts4z/irata/varz"
 )
 
+var logger = logz.New()
+
+// Long polls run up to an hour, so the buckets do too.
+var requestSecondsByCode = varz.NewHistogramMap("requestSecondsByCode",
+	[]float64{.001, .005, .01, .05, .1, .5, 1, 5, 10, 60, 600, 3600})
+
 type Clock interface {
 	Now() time.Time
 }
@@ -22,18 +32,18 @@ func NewRequestLogger(next http.Handler, clock Clock) *RequestLogger {
 	return &RequestLogger{next: next, clock: clock}
 }
 
-func remoteAddr(r *http.Request) string {
-	if r.Header.Get("X-Forwarded-For") != "" {
-		return r.Header.Get("X-Forwarded-For")
-	}
-	return r.RemoteAddr
-}
-
 func (rl *RequestLogger) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	start := rl.clock.Now()
 	ww := &codeWatcher{w: w}
 	rl.next.ServeHTTP(ww, r)
 	code := ww.Code()
 	duration := time.Since(start)
-	log.Printf("[access log] %d %v %v (%v)", code, remoteAddr(r), r.URL.Path, duration)
+	requestSecondsByCode.Observe(strconv.Itoa(code), duration.Seconds())
+	logger.InfoContext(r.Context(), "access log",
+		"code", code,
+		"client", clientip.Of(r).String(),
+		"method", r.Method,
+		"path", r.URL.Path,
+		"proto", r.Proto,
+		"duration", duration)
 }
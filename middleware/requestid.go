@@ -0,0 +1,43 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"net/http"
+
+	"github.com/ts4z/irata/logz"
+)
+
+// RequestIDHeader carries the request ID back to the client, so a user
+// reporting a problem can say which request it was.  A proxy in front can
+// send one in the same header, to tie its logs to ours.
+const RequestIDHeader = "X-Request-ID"
+
+// RequestID gives each request an ID, in its context for logging and in
+// the response header.
+func RequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(RequestIDHeader)
+		if !validRequestID(id) {
+			id = rand.Text()
+		}
+		w.Header().Set(RequestIDHeader, id)
+		next.ServeHTTP(w, r.WithContext(logz.WithRequestID(r.Context(), id)))
+	})
+}
+
+// validRequestID keeps a request ID from somebody else short and free of
+// anything that would mess up a log line.
+func validRequestID(id string) bool {
+	if id == "" || len(id) > 64 {
+		return false
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9':
+		case c == '-', c == '_', c == '.':
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,271 @@
+// Package migrate keeps the database schema up to date.
+//
+// Migrations are SQL files named like 0003_some_name.sql, numbered from 1
+// with no gaps, and built into the binary.  Each one that has been applied
+// is recorded in schema_version with a checksum of its text, so a migration
+// that was changed after it shipped is caught rather than silently skipped.
+// Applied migrations are never edited; fixes go in a new one.
+//
+// Postgres and SQLite each have their own series, numbered separately, in
+// directories named for the dbutil.Dialect.
+package migrate
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"embed"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/ts4z/irata/dbutil"
+)
+
+//go:embed postgres/*.sql sqlite/*.sql
+var migrationsFS embed.FS
+
+// lockID is the advisory lock held while migrating, so two irataadmins
+// can't migrate at once.  It's "irata" in ASCII.
+const lockID = 0x6972617461
+
+type Migration struct {
+	Version  int
+	Name     string
+	SQL      string
+	Checksum string // hex SHA-256 of SQL
+}
+
+// Applied is a migration as recorded in schema_version.
+type Applied struct {
+	Version   int
+	Name      string
+	Checksum  string
+	AppliedAt time.Time
+}
+
+var fileName = regexp.MustCompile(`^(\d{4})_([a-z0-9_]+)\.sql$`)
+
+// Load reads the migrations in the top directory of fsys.  They must be
+// numbered from 1 with no gaps.
+func Load(fsys fs.FS) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	migrations := []*Migration{}
+	for _, e := range entries {
+		m := fileName.FindStringSubmatch(e.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration file %s isn't named like 0001_name.sql", e.Name())
+		}
+		version, _ := strconv.Atoi(m[1])
+		text, err := fs.ReadFile(fsys, e.Name())
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(text)
+		migrations = append(migrations, &Migration{
+			Version:  version,
+			Name:     m[2],
+			SQL:      string(text),
+			Checksum: hex.EncodeToString(sum[:]),
+		})
+	}
+	slices.SortFunc(migrations, func(a, b *Migration) int { return a.Version - b.Version })
+	for i, m := range migrations {
+		if m.Version != i+1 {
+			return nil, fmt.Errorf("migration %04d_%s should be number %d", m.Version, m.Name, i+1)
+		}
+	}
+	return migrations, nil
+}
+
+// For returns the migrations built in for the given dialect.
+func For(d dbutil.Dialect) []*Migration {
+	sub, err := fs.Sub(migrationsFS, string(d))
+	if err != nil {
+		panic(err)
+	}
+	migrations, err := Load(sub)
+	if err != nil {
+		// The tests make sure this can't happen.
+		panic(err)
+	}
+	return migrations
+}
+
+// Status compares what the database has applied with what this binary
+// knows about.
+type Status struct {
+	Applied []*Applied
+	Pending []*Migration
+	// Current is the version the database is at; Latest is the version
+	// this binary wants.
+	Current, Latest int
+}
+
+func (s *Status) UpToDate() bool {
+	return len(s.Pending) == 0
+}
+
+// Plan works out the status, or why the database can't be used with these
+// migrations at all: it has been migrated by a newer irata, or a migration
+// it has applied doesn't match the one here.
+func Plan(known []*Migration, applied []*Applied) (*Status, error) {
+	st := &Status{Applied: applied, Latest: len(known)}
+	for i, a := range applied {
+		if a.Version != i+1 {
+			return nil, fmt.Errorf("schema_version is missing migration %d", i+1)
+		}
+		if a.Version > len(known) {
+			return nil, fmt.Errorf("database schema is at version %d, newer than this irata knows (%d); upgrade irata",
+				applied[len(applied)-1].Version, len(known))
+		}
+		m := known[a.Version-1]
+		if a.Checksum != m.Checksum {
+			return nil, fmt.Errorf("migration %04d_%s has changed since it was applied (checksum %.12s, now %.12s)",
+				m.Version, m.Name, a.Checksum, m.Checksum)
+		}
+		st.Current = a.Version
+	}
+	st.Pending = known[len(applied):]
+	return st, nil
+}
+
+var createSchemaVersion = map[dbutil.Dialect]string{
+	dbutil.Postgres: `CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP WITH TIME ZONE NOT NULL
+	)`,
+	dbutil.SQLite: `CREATE TABLE IF NOT EXISTS schema_version (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		checksum TEXT NOT NULL,
+		applied_at TIMESTAMP NOT NULL
+	)`,
+}
+
+var schemaVersionExists = map[dbutil.Dialect]string{
+	dbutil.Postgres: `SELECT to_regclass('schema_version') IS NOT NULL`,
+	dbutil.SQLite:   `SELECT COUNT(*) > 0 FROM sqlite_master WHERE type = 'table' AND name = 'schema_version'`,
+}
+
+type querier interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// fetchApplied reads schema_version.  A database that doesn't have one has
+// applied nothing.
+func fetchApplied(ctx context.Context, d dbutil.Dialect, db querier) ([]*Applied, error) {
+	var exists bool
+	if err := db.QueryRowContext(ctx, schemaVersionExists[d]).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("looking for schema_version: %w", err)
+	}
+	if !exists {
+		return nil, nil
+	}
+	rows, err := db.QueryContext(ctx, `SELECT version, name, checksum, applied_at FROM schema_version ORDER BY version`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	applied := []*Applied{}
+	for rows.Next() {
+		a := &Applied{}
+		if err := rows.Scan(&a.Version, &a.Name, &a.Checksum, &a.AppliedAt); err != nil {
+			return nil, err
+		}
+		applied = append(applied, a)
+	}
+	return applied, rows.Err()
+}
+
+// FetchStatus reads the database's status.
+func FetchStatus(ctx context.Context, db *sql.DB) (*Status, error) {
+	d := dbutil.DialectOf(db)
+	applied, err := fetchApplied(ctx, d, db)
+	if err != nil {
+		return nil, err
+	}
+	return Plan(For(d), applied)
+}
+
+// Check is for the server at startup: it fails unless the database is
+// exactly at the version this binary wants.
+func Check(ctx context.Context, db *sql.DB) error {
+	st, err := FetchStatus(ctx, db)
+	if err != nil {
+		return err
+	}
+	if !st.UpToDate() {
+		return fmt.Errorf("database schema is at version %d, but this irata needs version %d; run \"irataadmin db migrate\"",
+			st.Current, st.Latest)
+	}
+	return nil
+}
+
+// Migrate applies the pending migrations, each in a transaction of its own,
+// and calls applied after each one.
+func Migrate(ctx context.Context, db *sql.DB, applied func(*Migration)) error {
+	d := dbutil.DialectOf(db)
+	if _, err := db.ExecContext(ctx, createSchemaVersion[d]); err != nil {
+		return fmt.Errorf("creating schema_version: %w", err)
+	}
+	known := For(d)
+	for _, m := range known {
+		done, err := apply(ctx, db, d, known, m)
+		if err != nil {
+			return fmt.Errorf("migration %04d_%s: %w", m.Version, m.Name, err)
+		}
+		if done && applied != nil {
+			applied(m)
+		}
+	}
+	return nil
+}
+
+// apply applies m unless it already has been.  The lock and the check
+// happen inside the transaction, so someone else migrating at the same
+// time is harmless.  (SQLite transactions take the write lock when they
+// begin; see dbutil.)
+func apply(ctx context.Context, db *sql.DB, d dbutil.Dialect, known []*Migration, m *Migration) (bool, error) {
+	tx, err := dbutil.NewTx(ctx, db, nil)
+	if err != nil {
+		return false, err
+	}
+	defer tx.MaybeRollback()
+
+	if d == dbutil.Postgres {
+		if _, err := tx.Exec(ctx, `SELECT pg_advisory_xact_lock($1)`, lockID); err != nil {
+			return false, err
+		}
+	}
+	applied, err := fetchApplied(ctx, d, tx.Tx())
+	if err != nil {
+		return false, err
+	}
+	if _, err := Plan(known, applied); err != nil {
+		return false, err
+	}
+	if len(applied) >= m.Version {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(ctx, m.SQL); err != nil {
+		return false, err
+	}
+	if _, err := tx.Exec(ctx,
+		`INSERT INTO schema_version (version, name, checksum, applied_at) VALUES ($1, $2, $3, $4)`,
+		m.Version, m.Name, m.Checksum, time.Now().UTC()); err != nil {
+		return false, err
+	}
+	return true, tx.Commit()
+}
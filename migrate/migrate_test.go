@@ -0,0 +1,94 @@
+package migrate
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/ts4z/irata/dbutil"
+)
+
+func TestFor(t *testing.T) {
+	for _, d := range []dbutil.Dialect{dbutil.Postgres, dbutil.SQLite} {
+		migrations := For(d)
+		if len(migrations) == 0 {
+			t.Fatalf("%s: no migrations", d)
+		}
+		for i, m := range migrations {
+			if m.Version != i+1 || m.Checksum == "" || m.SQL == "" {
+				t.Errorf("%s: migration %d = %d %q %q", d, i, m.Version, m.Name, m.Checksum)
+			}
+			// Things the old schema.sql got wrong.
+			if strings.Contains(m.SQL, "NEW.key") || strings.Contains(m.SQL, "(handle)") {
+				t.Errorf("%s: migration %04d_%s has an old mistake in it", d, m.Version, m.Name)
+			}
+		}
+	}
+}
+
+func TestLoad(t *testing.T) {
+	got, err := Load(fstest.MapFS{
+		"0002_second.sql": {Data: []byte("SELECT 2;")},
+		"0001_first.sql":  {Data: []byte("SELECT 1;")},
+	})
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if len(got) != 2 || got[0].Name != "first" || got[1].Version != 2 {
+		t.Errorf("got %+v", got)
+	}
+	if got[0].Checksum == got[1].Checksum {
+		t.Error("different migrations have the same checksum")
+	}
+
+	for name, fsys := range map[string]fstest.MapFS{
+		"gap":      {"0001_a.sql": {}, "0003_c.sql": {}},
+		"bad name": {"0001_a.sql": {}, "2_b.sql": {}},
+		"repeated": {"0001_a.sql": {}, "0001_b.sql": {}},
+	} {
+		if _, err := Load(fsys); err == nil {
+			t.Errorf("%s: no error", name)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	known := []*Migration{
+		{Version: 1, Name: "a", Checksum: "aaa"},
+		{Version: 2, Name: "b", Checksum: "bbb"},
+		{Version: 3, Name: "c", Checksum: "ccc"},
+	}
+	for _, tc := range []struct {
+		name        string
+		applied     []*Applied
+		wantCurrent int
+		wantPending int
+		wantErr     string
+	}{
+		{"fresh", nil, 0, 3, ""},
+		{"partway", []*Applied{{Version: 1, Checksum: "aaa"}}, 1, 2, ""},
+		{"up to date", []*Applied{{Version: 1, Checksum: "aaa"}, {Version: 2, Checksum: "bbb"}, {Version: 3, Checksum: "ccc"}}, 3, 0, ""},
+		{"changed", []*Applied{{Version: 1, Checksum: "aaa"}, {Version: 2, Checksum: "xxx"}}, 0, 0, "has changed"},
+		{"too new", []*Applied{{Version: 1, Checksum: "aaa"}, {Version: 2, Checksum: "bbb"}, {Version: 3, Checksum: "ccc"}, {Version: 4, Checksum: "ddd"}}, 0, 0, "newer"},
+		{"gap", []*Applied{{Version: 1, Checksum: "aaa"}, {Version: 3, Checksum: "ccc"}}, 0, 0, "missing migration 2"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			st, err := Plan(known, tc.applied)
+			if tc.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+					t.Errorf("got error %v, want one containing %q", err, tc.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if st.Current != tc.wantCurrent || len(st.Pending) != tc.wantPending || st.Latest != 3 {
+				t.Errorf("got current %d, %d pending, latest %d", st.Current, len(st.Pending), st.Latest)
+			}
+			if st.UpToDate() != (tc.wantPending == 0) {
+				t.Errorf("UpToDate = %v", st.UpToDate())
+			}
+		})
+	}
+}
@@ -1,14 +1,8 @@
-DROP TABLE structures CASCADE;
-DROP TABLE tournaments CASCADE;
-DROP TABLE text_footer_plugs CASCADE;
-DROP TABLE footer_plug_sets CASCADE;
-DROP TABLE users CASCADE;
-DROP TABLE passwords CASCADE;
-DROP TABLE user_email_addresses CASCADE;
-DROP TABLE site_info CASCADE; -- obsolete name
-DROP TABLE site_config CASCADE;
+-- The tables irata started with.  Everything here is IF NOT EXISTS, so a
+-- database loaded from the old schema.sql can take up migrations where it
+-- left off.
 
-CREATE TABLE users (
+CREATE TABLE IF NOT EXISTS users (
     user_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
     nick VARCHAR(20) NOT NULL UNIQUE,
     is_admin BOOLEAN DEFAULT FALSE NOT NULL,
@@ -16,67 +10,49 @@ CREATE TABLE users (
     model_data JSONB DEFAULT '{}' NOT NULL
 );
 
-CREATE INDEX idx_user_nick ON users(nick);
+CREATE INDEX IF NOT EXISTS idx_user_nick ON users(nick);
 
-CREATE TABLE passwords (
+CREATE TABLE IF NOT EXISTS passwords (
     password_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
     user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
     hashed_password VARCHAR(255) NOT NULL,
     expires TIMESTAMP WITHOUT TIME ZONE
 );
 
-CREATE TABLE user_email_addresses (
+CREATE TABLE IF NOT EXISTS user_email_addresses (
     email_address VARCHAR(255) NOT NULL PRIMARY KEY,
-    user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
-    FOREIGN KEY (user_id) REFERENCES users(user_id)
+    user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE
 );
 
-CREATE INDEX idx_user_email_addresses_email 
+CREATE INDEX IF NOT EXISTS idx_user_email_addresses_email
     ON user_email_addresses(email_address);
 
-CREATE TABLE footer_plug_sets (
-   id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
-   name TEXT NOT NULL,
-   version BIGINT DEFAULT 0 NOT NULL
-);
-
-CREATE TABLE text_footer_plugs (
-   id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
-   version BIGINT DEFAULT 0 NOT NULL,
-   footer_plug_set_id BIGINT NOT NULL REFERENCES footer_plug_sets(id) ON DELETE CASCADE,
-   text TEXT NOT NuLL
-);
-
--- obsolete
-CREATE TABLE site_info (
-   key TEXT PRIMARY KEY UNIQUE NOT NULL,
-    value JSONB NOT NULL,
+CREATE TABLE IF NOT EXISTS footer_plug_sets (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    name TEXT NOT NULL,
     version BIGINT DEFAULT 0 NOT NULL
 );
 
 -- Unlike other tables, we will not auto-generate the key here.
 -- The keys should be known.
 -- key 1 = SiteConfig
-CREATE TABLE site_config (
-   id BIGINT PRIMARY KEY UNIQUE,
-   value JSONB NOT NULL,
-   version BIGINT DEFAULT 0 NOT NULL
-);
- 
-CREATE TABLE tournaments (
-       tournament_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
-       version BIGINT DEFAULT 0 NOT NULL,
-       model_data JSONB NOT NULL
+CREATE TABLE IF NOT EXISTS site_config (
+    id BIGINT PRIMARY KEY,
+    value JSONB NOT NULL,
+    version BIGINT DEFAULT 0 NOT NULL
 );
 
-CREATE INDEX idx_tournaments_handle 
-    ON tournaments(handle); 
+CREATE TABLE IF NOT EXISTS tournaments (
+    tournament_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data JSONB NOT NULL
+);
 
-CREATE TABLE structures (
-       structure_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
-       version BIGINT DEFAULT 0,
-       name TEXT NOT NULL,
-       model_data JSONB NOT NULL
+CREATE TABLE IF NOT EXISTS structures (
+    structure_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    version BIGINT DEFAULT 0,
+    name TEXT NOT NULL,
+    model_data JSONB NOT NULL
 );
 
 CREATE OR REPLACE FUNCTION notify_tournaments_change()
@@ -96,12 +72,14 @@ AFTER INSERT OR UPDATE ON tournaments
 FOR EACH ROW
 EXECUTE FUNCTION notify_tournaments_change();
 
+-- The old schema.sql had the notify use a key column, which site_config
+-- doesn't have, so every write to it failed.
 CREATE OR REPLACE FUNCTION notify_site_config_change()
 RETURNS TRIGGER AS $$
 BEGIN
     PERFORM pg_notify('site_config_changes', json_build_object(
         'Table', 'site_config',
-        'OnID', NEW.key,
+        'OnID', NEW.id,
         'Version', NEW.version
     )::text);
     RETURN NEW;
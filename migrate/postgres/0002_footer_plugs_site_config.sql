@@ -0,0 +1,32 @@
+-- Footer plugs became typed JSON (model.FooterPlug) in a table of their
+-- own, and the site config moved from site_info to site_config.  Old rows
+-- are carried over, and the old tables dropped.
+
+CREATE TABLE IF NOT EXISTS footer_plugs (
+    id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    footer_plug_set_id BIGINT NOT NULL REFERENCES footer_plug_sets(id) ON DELETE CASCADE,
+    model_data JSONB NOT NULL
+);
+
+DO $$
+BEGIN
+    IF to_regclass('text_footer_plugs') IS NOT NULL THEN
+        INSERT INTO footer_plugs (footer_plug_set_id, model_data)
+            SELECT footer_plug_set_id, jsonb_build_object('Kind', 'text', 'Text', text)
+            FROM text_footer_plugs ORDER BY id;
+        DROP TABLE text_footer_plugs;
+    END IF;
+
+    IF to_regclass('site_info') IS NOT NULL THEN
+        INSERT INTO site_config (id, value)
+            SELECT 1, value FROM site_info WHERE key = 'conf'
+            ON CONFLICT (id) DO NOTHING;
+        DROP TABLE site_info;
+    END IF;
+END;
+$$;
+
+-- A new site needs a site config to start with.
+INSERT INTO site_config (id, value)
+    VALUES (1, '{"Name": "Irata Poker Tournament Clock", "Theme": "irata"}')
+    ON CONFLICT (id) DO NOTHING;
@@ -0,0 +1,54 @@
+-- Clock display layouts.  model_data is a model.Layout.
+CREATE TABLE IF NOT EXISTS layouts (
+    layout_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data JSONB NOT NULL
+);
+
+-- The slide library.  model_data is a model.Slide.  An uploaded image lives
+-- in its own columns so the slide list doesn't drag it around.
+CREATE TABLE IF NOT EXISTS slides (
+    slide_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data JSONB NOT NULL,
+    image_type TEXT,
+    image_data BYTEA
+);
+
+-- model_data is a model.SlideSet, which refers to slides by ID.
+CREATE TABLE IF NOT EXISTS slide_sets (
+    slide_set_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data JSONB NOT NULL
+);
+
+-- Kiosk displays.  device_id is made up by the client and is stable for the
+-- life of the device.  Heartbeats touch only the last_heartbeat, remote_addr
+-- and user_agent columns, and don't bump the version.
+CREATE TABLE IF NOT EXISTS displays (
+    display_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    device_id TEXT NOT NULL UNIQUE,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data JSONB DEFAULT '{}' NOT NULL,
+    last_heartbeat TIMESTAMP WITH TIME ZONE,
+    remote_addr TEXT DEFAULT '' NOT NULL,
+    user_agent TEXT DEFAULT '' NOT NULL
+);
+
+CREATE OR REPLACE FUNCTION notify_displays_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('displays_changes', json_build_object(
+        'Table', 'displays',
+        'OnID', NEW.display_id,
+        'Version', NEW.version
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+-- Only fire on assignment changes, not on every heartbeat.
+CREATE OR REPLACE TRIGGER displays_notify
+AFTER UPDATE OF version, model_data ON displays
+FOR EACH ROW
+EXECUTE FUNCTION notify_displays_change();
@@ -0,0 +1,36 @@
+-- Operator announcements.  expires is kept out of the JSON so the clocks'
+-- query for what's current doesn't have to look inside it.
+CREATE TABLE IF NOT EXISTS announcements (
+    announcement_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    version BIGINT DEFAULT 0 NOT NULL,
+    expires TIMESTAMP WITH TIME ZONE NOT NULL,
+    model_data JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_announcements_expires ON announcements(expires);
+
+CREATE OR REPLACE FUNCTION notify_announcements_change()
+RETURNS TRIGGER AS $$
+BEGIN
+    PERFORM pg_notify('announcements_changes', json_build_object(
+        'Table', 'announcements',
+        'OnID', NEW.announcement_id,
+        'Version', NEW.version
+    )::text);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+CREATE OR REPLACE TRIGGER announcements_notify
+AFTER INSERT OR UPDATE ON announcements
+FOR EACH ROW
+EXECUTE FUNCTION notify_announcements_change();
+
+-- Templates for recurring tournaments.  The tournaments they make are
+-- ordinary tournaments; see the schedule package.
+CREATE TABLE IF NOT EXISTS tournament_templates (
+    tournament_template_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    version BIGINT DEFAULT 0 NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    model_data JSONB NOT NULL
+);
@@ -0,0 +1,23 @@
+-- lifecycle is a copy of State.Lifecycle, kept out of the JSON so the index
+-- can filter on it.  league_id is a copy of LeagueID, 0 for none.
+ALTER TABLE tournaments ADD COLUMN IF NOT EXISTS lifecycle VARCHAR(20) DEFAULT 'scheduled' NOT NULL;
+ALTER TABLE tournaments ADD COLUMN IF NOT EXISTS league_id BIGINT DEFAULT 0 NOT NULL;
+
+-- Tournaments from before lifecycles have none in their JSON; they start
+-- out scheduled, and the scheduler sorts out the real lifecycles within a
+-- few minutes.
+UPDATE tournaments SET
+    lifecycle = COALESCE(NULLIF(model_data->'State'->>'Lifecycle', ''), 'scheduled'),
+    league_id = COALESCE((model_data->>'LeagueID')::BIGINT, 0);
+
+CREATE INDEX IF NOT EXISTS idx_tournaments_lifecycle ON tournaments(lifecycle);
+CREATE INDEX IF NOT EXISTS idx_tournaments_league ON tournaments(league_id);
+
+-- Leagues score the standings of their tournaments into a leaderboard;
+-- see the league package.  Tournaments name their league in league_id.
+CREATE TABLE IF NOT EXISTS leagues (
+    league_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    version BIGINT DEFAULT 0 NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    model_data JSONB NOT NULL
+);
@@ -0,0 +1,83 @@
+-- Every table holding a model notifies <table>_changes on insert, update
+-- and delete, so other instances can drop what they've cached.  One
+-- function serves them all; the trigger names the id column.
+
+CREATE OR REPLACE FUNCTION notify_change()
+RETURNS TRIGGER AS $$
+DECLARE
+    data JSONB;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        data := to_jsonb(OLD);
+    ELSE
+        data := to_jsonb(NEW);
+    END IF;
+    PERFORM pg_notify(TG_TABLE_NAME || '_changes', json_build_object(
+        'Table', TG_TABLE_NAME,
+        'OnID', (data ->> TG_ARGV[0])::BIGINT,
+        'Version', COALESCE((data ->> 'version')::BIGINT, 0),
+        'Deleted', TG_OP = 'DELETE'
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS tournaments_notify ON tournaments;
+DROP TRIGGER IF EXISTS site_config_notify ON site_config;
+DROP TRIGGER IF EXISTS displays_notify ON displays;
+DROP TRIGGER IF EXISTS announcements_notify ON announcements;
+DROP FUNCTION IF EXISTS notify_tournaments_change();
+DROP FUNCTION IF EXISTS notify_site_config_change();
+DROP FUNCTION IF EXISTS notify_displays_change();
+DROP FUNCTION IF EXISTS notify_announcements_change();
+
+CREATE TRIGGER users_notify
+AFTER INSERT OR UPDATE OR DELETE ON users
+FOR EACH ROW EXECUTE FUNCTION notify_change('user_id');
+
+CREATE TRIGGER site_config_notify
+AFTER INSERT OR UPDATE OR DELETE ON site_config
+FOR EACH ROW EXECUTE FUNCTION notify_change('id');
+
+CREATE TRIGGER tournaments_notify
+AFTER INSERT OR UPDATE OR DELETE ON tournaments
+FOR EACH ROW EXECUTE FUNCTION notify_change('tournament_id');
+
+CREATE TRIGGER structures_notify
+AFTER INSERT OR UPDATE OR DELETE ON structures
+FOR EACH ROW EXECUTE FUNCTION notify_change('structure_id');
+
+-- Saving a footer plug set bumps its version, so the plugs themselves
+-- needn't notify.
+CREATE TRIGGER footer_plug_sets_notify
+AFTER INSERT OR UPDATE OR DELETE ON footer_plug_sets
+FOR EACH ROW EXECUTE FUNCTION notify_change('id');
+
+CREATE TRIGGER layouts_notify
+AFTER INSERT OR UPDATE OR DELETE ON layouts
+FOR EACH ROW EXECUTE FUNCTION notify_change('layout_id');
+
+CREATE TRIGGER slides_notify
+AFTER INSERT OR UPDATE OR DELETE ON slides
+FOR EACH ROW EXECUTE FUNCTION notify_change('slide_id');
+
+CREATE TRIGGER slide_sets_notify
+AFTER INSERT OR UPDATE OR DELETE ON slide_sets
+FOR EACH ROW EXECUTE FUNCTION notify_change('slide_set_id');
+
+-- Only fire on assignment changes, not on every heartbeat.
+CREATE TRIGGER displays_notify
+AFTER UPDATE OF version, model_data OR DELETE ON displays
+FOR EACH ROW EXECUTE FUNCTION notify_change('display_id');
+
+CREATE TRIGGER announcements_notify
+AFTER INSERT OR UPDATE OR DELETE ON announcements
+FOR EACH ROW EXECUTE FUNCTION notify_change('announcement_id');
+
+CREATE TRIGGER tournament_templates_notify
+AFTER INSERT OR UPDATE OR DELETE ON tournament_templates
+FOR EACH ROW EXECUTE FUNCTION notify_change('tournament_template_id');
+
+CREATE TRIGGER leagues_notify
+AFTER INSERT OR UPDATE OR DELETE ON leagues
+FOR EACH ROW EXECUTE FUNCTION notify_change('league_id');
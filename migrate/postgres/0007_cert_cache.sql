@@ -0,0 +1,8 @@
+-- Certificates and account keys from the ACME CA, shared by every instance
+-- so they don't each ask for their own.  Keys are autocert's.
+
+CREATE TABLE IF NOT EXISTS cert_cache (
+    key TEXT PRIMARY KEY,
+    data BYTEA NOT NULL,
+    updated TIMESTAMP WITH TIME ZONE DEFAULT now() NOT NULL
+);
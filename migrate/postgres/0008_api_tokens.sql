@@ -0,0 +1,15 @@
+-- Tokens for the REST API.  Only a hash of each token is kept, so the
+-- database can't be used to make requests.  Scopes are a comma-separated
+-- list, like "read,control".
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    token_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    user_id BIGINT NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created TIMESTAMP WITH TIME ZONE DEFAULT now() NOT NULL,
+    last_used TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_api_tokens_user ON api_tokens(user_id);
@@ -0,0 +1,37 @@
+-- Outgoing webhooks.  A webhook with no tournament hears about every
+-- tournament.  Events are a comma-separated list, like
+-- "level.started,clock.paused".  The secret signs each delivery, so it has
+-- to be kept as it is.
+
+CREATE TABLE IF NOT EXISTS webhooks (
+    webhook_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    tournament_id BIGINT,
+    url TEXT NOT NULL,
+    secret TEXT NOT NULL,
+    events TEXT NOT NULL,
+    created TIMESTAMP WITH TIME ZONE DEFAULT now() NOT NULL
+);
+
+-- The outbox, which is also the delivery log.  dedupe_key names the change
+-- a delivery is about, so that servers sharing the database, or one
+-- restarting, don't send it twice.  A pending delivery is tried again at
+-- next_attempt.
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+    delivery_id BIGINT GENERATED ALWAYS AS IDENTITY PRIMARY KEY,
+    webhook_id BIGINT NOT NULL REFERENCES webhooks(webhook_id) ON DELETE CASCADE,
+    tournament_id BIGINT NOT NULL,
+    event TEXT NOT NULL,
+    dedupe_key TEXT NOT NULL,
+    payload TEXT NOT NULL,
+    status TEXT NOT NULL,
+    attempts INTEGER DEFAULT 0 NOT NULL,
+    next_attempt TIMESTAMP WITH TIME ZONE NOT NULL,
+    last_status INTEGER DEFAULT 0 NOT NULL,
+    last_error TEXT DEFAULT '' NOT NULL,
+    created TIMESTAMP WITH TIME ZONE DEFAULT now() NOT NULL,
+    delivered TIMESTAMP WITH TIME ZONE,
+    UNIQUE (webhook_id, dedupe_key)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_due ON webhook_deliveries(status, next_attempt);
@@ -0,0 +1,116 @@
+-- The whole schema, as of when SQLite came along.  It follows the Postgres
+-- migrations table for table; see there for what the tables are for.
+-- model_data is JSON text, and there are no notify triggers: DBStorage
+-- tells an in-process dbnotify listener about changes instead.
+
+CREATE TABLE users (
+    user_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    nick VARCHAR(20) NOT NULL UNIQUE,
+    is_admin BOOLEAN DEFAULT FALSE NOT NULL,
+    is_operator BOOLEAN DEFAULT FALSE NOT NULL,
+    model_data TEXT DEFAULT '{}' NOT NULL
+);
+
+CREATE TABLE passwords (
+    password_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    hashed_password VARCHAR(255) NOT NULL,
+    expires TIMESTAMP
+);
+
+CREATE TABLE user_email_addresses (
+    email_address VARCHAR(255) NOT NULL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE
+);
+
+CREATE TABLE footer_plug_sets (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    name TEXT NOT NULL,
+    version BIGINT DEFAULT 0 NOT NULL
+);
+
+CREATE TABLE footer_plugs (
+    id INTEGER PRIMARY KEY AUTOINCREMENT,
+    footer_plug_set_id INTEGER NOT NULL REFERENCES footer_plug_sets(id) ON DELETE CASCADE,
+    model_data TEXT NOT NULL
+);
+
+CREATE TABLE site_config (
+    id INTEGER PRIMARY KEY,
+    value TEXT NOT NULL,
+    version BIGINT DEFAULT 0 NOT NULL
+);
+
+INSERT INTO site_config (id, value)
+    VALUES (1, '{"Name": "Irata Poker Tournament Clock", "Theme": "irata"}');
+
+CREATE TABLE tournaments (
+    tournament_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version BIGINT DEFAULT 0 NOT NULL,
+    lifecycle VARCHAR(20) DEFAULT 'scheduled' NOT NULL,
+    league_id BIGINT DEFAULT 0 NOT NULL,
+    model_data TEXT NOT NULL
+);
+
+CREATE INDEX idx_tournaments_lifecycle ON tournaments(lifecycle);
+CREATE INDEX idx_tournaments_league ON tournaments(league_id);
+
+CREATE TABLE structures (
+    structure_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version BIGINT DEFAULT 0,
+    name TEXT NOT NULL,
+    model_data TEXT NOT NULL
+);
+
+CREATE TABLE layouts (
+    layout_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data TEXT NOT NULL
+);
+
+CREATE TABLE slides (
+    slide_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data TEXT NOT NULL,
+    image_type TEXT,
+    image_data BLOB
+);
+
+CREATE TABLE slide_sets (
+    slide_set_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data TEXT NOT NULL
+);
+
+CREATE TABLE displays (
+    display_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    device_id TEXT NOT NULL UNIQUE,
+    version BIGINT DEFAULT 0 NOT NULL,
+    model_data TEXT DEFAULT '{}' NOT NULL,
+    last_heartbeat TIMESTAMP,
+    remote_addr TEXT DEFAULT '' NOT NULL,
+    user_agent TEXT DEFAULT '' NOT NULL
+);
+
+CREATE TABLE announcements (
+    announcement_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version BIGINT DEFAULT 0 NOT NULL,
+    expires TIMESTAMP NOT NULL,
+    model_data TEXT NOT NULL
+);
+
+CREATE INDEX idx_announcements_expires ON announcements(expires);
+
+CREATE TABLE tournament_templates (
+    tournament_template_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version BIGINT DEFAULT 0 NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    model_data TEXT NOT NULL
+);
+
+CREATE TABLE leagues (
+    league_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    version BIGINT DEFAULT 0 NOT NULL,
+    name VARCHAR(200) NOT NULL,
+    model_data TEXT NOT NULL
+);
@@ -0,0 +1,8 @@
+-- Certificates and account keys from the ACME CA; see the Postgres
+-- migration of the same name.
+
+CREATE TABLE cert_cache (
+    key TEXT PRIMARY KEY,
+    data BLOB NOT NULL,
+    updated TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL
+);
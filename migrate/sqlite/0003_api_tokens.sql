@@ -0,0 +1,13 @@
+-- Tokens for the REST API; see the Postgres migration of the same name.
+
+CREATE TABLE api_tokens (
+    token_id INTEGER PRIMARY KEY AUTOINCREMENT,
+    user_id INTEGER NOT NULL REFERENCES users(user_id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    scopes TEXT NOT NULL,
+    token_hash TEXT NOT NULL UNIQUE,
+    created TIMESTAMP DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    last_used TIMESTAMP
+);
+
+CREATE INDEX idx_api_tokens_user ON api_tokens(user_id);
@@ -8,6 +8,7 @@
 package model
 
 import (
+	"slices"
 	"time"
 )
 
@@ -39,6 +40,63 @@ func (ui *UserIdentity) Clone() *UserIdentity {
 	return &new
 }
 
+// APIToken lets a program use the REST API as the user who made it, but
+// only for what its scopes allow.  The secret itself isn't kept.
+type APIToken struct {
+	ID       int64
+	UserID   int64
+	Name     string
+	Scopes   []string
+	Created  time.Time
+	LastUsed *time.Time
+}
+
+func (t *APIToken) HasScope(scope string) bool {
+	return slices.Contains(t.Scopes, scope)
+}
+
+// Webhook is a URL told about tournament events as they happen.  Each
+// delivery is signed with Secret.
+type Webhook struct {
+	ID           int64
+	TournamentID int64 // 0 means every tournament
+	URL          string
+	Secret       string
+	Events       []string
+	Created      time.Time
+}
+
+func (w *Webhook) Wants(tournamentID int64, event string) bool {
+	return (w.TournamentID == 0 || w.TournamentID == tournamentID) && slices.Contains(w.Events, event)
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	WebhookPending   WebhookDeliveryStatus = "pending"
+	WebhookDelivered WebhookDeliveryStatus = "delivered"
+	WebhookFailed    WebhookDeliveryStatus = "failed" // gave up
+)
+
+// WebhookDelivery is one event on its way to one webhook, or the record of
+// it having gone.  Key identifies the change it's about, so the same change
+// is never sent twice.
+type WebhookDelivery struct {
+	ID           int64
+	WebhookID    int64
+	TournamentID int64
+	Event        string
+	Key          string
+	Payload      string
+	Status       WebhookDeliveryStatus
+	Attempts     int
+	NextAttempt  time.Time
+	LastStatus   int // HTTP status of the last attempt, 0 if there wasn't one
+	LastError    string
+	Created      time.Time
+	Delivered    *time.Time
+}
+
 type CookieKeyValidity struct {
 	MintFrom   time.Time
 	MintUntil  time.Time
@@ -60,7 +118,8 @@ type SiteConfig struct {
 	Theme                   string
 	DefaultNextLevelSoundID int64
 	CookieKeys              []CookieKeyPair
-	Slides                  []string
+	Slides                  []string // image URLs, used when there's no slide set
+	DefaultSlideSetID       int64
 	Motd                    string // Message of the day in Markdown
 }
 
@@ -72,21 +131,48 @@ type Level struct {
 	IsBreak         bool
 }
 
-// FooterPlugs is possible values for decorating the footer.
-//
-// This is intended to support things other than text, but that's not
-// implemented yet.
+// FooterPlugKind says how a footer plug is drawn.
+type FooterPlugKind string
+
+const (
+	FooterPlugKindText     FooterPlugKind = "text"
+	FooterPlugKindMarkdown FooterPlugKind = "markdown"
+	FooterPlugKindImage    FooterPlugKind = "image"
+	FooterPlugKindQRCode   FooterPlugKind = "qr"
+	FooterPlugKindSponsor  FooterPlugKind = "sponsor"
+)
+
+// FooterPlug is one thing the footer can show.
+type FooterPlug struct {
+	Kind FooterPlugKind
+
+	// Text is the plug itself for text and Markdown plugs, and a caption for
+	// the others.
+	Text string
+	// URL is the image for image and sponsor plugs, and what the QR code
+	// says for QR code plugs.
+	URL string
+
+	// Weight is how often this plug comes up relative to the others in its
+	// set.  Zero counts as one.
+	Weight int
+
+	// The plug is only shown between these times.  Zero means no limit.
+	ValidFrom  time.Time `json:",omitzero"`
+	ValidUntil time.Time `json:",omitzero"`
+}
+
+// FooterPlugs is a set of footer plugs, which a tournament rotates through.
 type FooterPlugs struct {
 	FooterPlugsID int64
 	Version       int64
 	Name          string
-	TextPlugs     []string
+	Plugs         []FooterPlug
 }
 
 func (fp *FooterPlugs) Clone() *FooterPlugs {
 	new := *fp
-	new.TextPlugs = make([]string, len(fp.TextPlugs))
-	copy(new.TextPlugs, fp.TextPlugs)
+	new.Plugs = append([]FooterPlug(nil), fp.Plugs...)
 	return &new
 }
 
@@ -100,6 +186,16 @@ type Tournament struct {
 	FooterPlugsID    int64
 	NextLevelSoundID int64
 	Theme            string // Theme override; empty string means use SiteConfig.Theme
+	LayoutID         int64  // 0 means the classic clock layout
+	SlideSetID       int64  // 0 means SiteConfig.DefaultSlideSetID
+
+	// ScheduledStart is when the tournament is meant to start, for the
+	// calendar.  Zero means it isn't on the calendar.
+	ScheduledStart time.Time `json:",omitzero"`
+	// FromTemplateID is the template this was made from, if any.
+	FromTemplateID int64 `json:",omitzero"`
+	// LeagueID is the league the tournament scores points in, if any.
+	LeagueID int64 `json:",omitzero"`
 
 	PrizePoolPerBuyIn int // amount to prize pool per buy-in
 	PrizePoolPerAddOn int // amount to prize pool per add-on
@@ -149,6 +245,12 @@ func (old *StructureData) Clone() *StructureData {
 	return &new
 }
 
+func (old *Structure) Clone() *Structure {
+	new := *old
+	new.StructureData = *old.StructureData.Clone()
+	return &new
+}
+
 type StructureSlug struct {
 	Name          string
 	ID            int64
@@ -186,13 +288,88 @@ type State struct {
 	// is, paused).  This is in Unix millis.  This can always be initialized
 	// within a level.
 	TimeRemainingMillis *int64
+
+	// ChipCounts are the most recent counts entered by the floor.  These are
+	// not necessarily complete; the floor may count only the big stacks, or
+	// count by table.
+	ChipCounts []*ChipCount
+
+	// Seating is where everyone sits, in table and seat order.
+	Seating []*Seat
+
+	// Lifecycle is where the tournament is in its life.  Empty means it
+	// predates lifecycles; see tournament.LifecycleOf.
+	Lifecycle Lifecycle `json:",omitempty"`
+	// FinishedAt is when the tournament finished, if it has.
+	FinishedAt time.Time `json:",omitzero"`
+
+	// Standings are the final results, entered by hand, in place order.
+	// Leagues score from these, with BuyIns as the field size.
+	Standings []*Standing `json:",omitempty"`
 }
 
+// Lifecycle is where a tournament is in its life, from being on the
+// calendar through being put away in the archive.
+type Lifecycle string
+
+const (
+	LifecycleScheduled   Lifecycle = "scheduled"
+	LifecycleRegistering Lifecycle = "registering"
+	LifecycleRunning     Lifecycle = "running"
+	LifecycleOnBreak     Lifecycle = "on-break"
+	LifecycleFinalTable  Lifecycle = "final-table"
+	LifecycleFinished    Lifecycle = "finished"
+	LifecycleArchived    Lifecycle = "archived"
+)
+
 func (s *State) Clone() *State {
 	new := *s
+	if s.ChipCounts != nil {
+		new.ChipCounts = make([]*ChipCount, len(s.ChipCounts))
+		for i, cc := range s.ChipCounts {
+			c := *cc
+			new.ChipCounts[i] = &c
+		}
+	}
+	if s.Seating != nil {
+		new.Seating = make([]*Seat, len(s.Seating))
+		for i, seat := range s.Seating {
+			c := *seat
+			new.Seating[i] = &c
+		}
+	}
+	if s.Standings != nil {
+		new.Standings = make([]*Standing, len(s.Standings))
+		for i, st := range s.Standings {
+			c := *st
+			new.Standings[i] = &c
+		}
+	}
 	return &new
 }
 
+// Seat is one player's seat assignment.
+type Seat struct {
+	Table int
+	Seat  int
+	Name  string
+}
+
+// Standing is where one player finished.  Players who chopped share a
+// place.
+type Standing struct {
+	Place int
+	Name  string
+}
+
+// ChipCount is a counted stack for one player (or one table, if that's how
+// the floor is counting).
+type ChipCount struct {
+	Name      string
+	Chips     int
+	CountedAt int64 // Unix millis
+}
+
 // Transients are computed from State and Structure, and are not serialized to the database.
 // (Transients should be split out of Tournament entirely.  When we fetch a model.Tournament,
 // these should arrive with it, but shouldn't be stored in that model.)
@@ -202,6 +379,13 @@ type Transients struct {
 	TotalChips      int
 	AverageChips    int
 
+	// Computed from State.ChipCounts.  These are all zero (or empty) if
+	// nobody has counted.
+	ChipLeaders         []*ChipCount
+	CountedAverageChips int
+	MedianChips         int
+	ChipsCountedAt      int64 // Unix millis of the most recent count
+
 	// Semi-stopgap.  We want the URL path to the sound file, but we store only the
 	// sound ID in the model, which is useless to the client.  So we'll fetch it as
 	// part of transients, which is currently quite cheap.
@@ -242,12 +426,16 @@ type TournamentSlug struct {
 	TournamentID   int64
 	TournamentName string
 	Description    string
+	ScheduledStart time.Time
+	Lifecycle      Lifecycle
 	// buyin, host, location, etc.
 }
 
 // Overview describes the available events for the event list.
 type Overview struct {
 	Slugs []TournamentSlug
+	// Total is how many tournaments there are in all, for pagination.
+	Total int
 }
 
 func (m *Tournament) TotalPrizePool() int {
@@ -259,3 +447,274 @@ func (m *Tournament) TotalPrizePool() int {
 		return buyIns + addOns
 	}
 }
+
+// DisplayMode says what a kiosk display should be showing.
+type DisplayMode string
+
+const (
+	DisplayModeUnassigned DisplayMode = ""
+	DisplayModeTournament DisplayMode = "tournament"
+	DisplayModeLobby      DisplayMode = "lobby"
+	DisplayModeSlideshow  DisplayMode = "slideshow"
+)
+
+// Display is a kiosk client, typically a headless Raspberry Pi driving a TV.
+// Displays register themselves under a device ID they make up; an operator
+// decides what they show.
+type Display struct {
+	DisplayID int64
+	Version   int64
+
+	DeviceID     string
+	Name         string
+	Mode         DisplayMode
+	TournamentID int64 // when Mode is DisplayModeTournament
+	LayoutID     int64 // overrides the tournament's layout if nonzero
+
+	// These come from the most recent heartbeat.  They are not part of the
+	// versioned data, so a heartbeat doesn't wake up listeners.
+	LastHeartbeat time.Time
+	RemoteAddr    string
+	UserAgent     string
+}
+
+func (d *Display) Clone() *Display {
+	new := *d
+	return &new
+}
+
+// LayoutVariant arranges panels on a CSS grid.  Areas has one string per grid
+// row, naming the panel in each cell (or "." for an empty cell), exactly like
+// CSS grid-template-areas.  Columns and Rows are optional track sizes, like
+// "3fr 1fr".
+type LayoutVariant struct {
+	Areas   []string
+	Columns string
+	Rows    string
+}
+
+// Layout is a named arrangement of clock panels, with separate arrangements
+// for landscape and portrait screens.
+type Layout struct {
+	LayoutID int64
+	Version  int64
+	Name     string
+
+	Landscape LayoutVariant
+	Portrait  LayoutVariant
+
+	// For the image panel.  If QRCodeText is set, the panel shows it as a
+	// QR code; otherwise it shows ImageURL.
+	ImageURL   string
+	QRCodeText string
+}
+
+func (l *Layout) Clone() *Layout {
+	new := *l
+	new.Landscape.Areas = append([]string(nil), l.Landscape.Areas...)
+	new.Portrait.Areas = append([]string(nil), l.Portrait.Areas...)
+	return &new
+}
+
+// SlideKind says where a slide's content comes from.  Markdown and image
+// slides are written by hand; the rest are generated from the tournament.
+type SlideKind string
+
+const (
+	SlideKindMarkdown    SlideKind = "markdown"
+	SlideKindImage       SlideKind = "image"
+	SlideKindPayouts     SlideKind = "payouts"
+	SlideKindStructure   SlideKind = "structure"
+	SlideKindLeaderboard SlideKind = "leaderboard"
+	SlideKindLeague      SlideKind = "league"
+)
+
+// Slide is one entry in the slide library.
+type Slide struct {
+	SlideID int64
+	Version int64
+	Name    string
+	Kind    SlideKind
+
+	Markdown string // for markdown slides
+	ImageURL string // for image slides, unless an image was uploaded
+	LeagueID int64  `json:",omitzero"` // for league slides
+
+	// UploadedImage is set when the slide has an image stored alongside it,
+	// which takes precedence over ImageURL.
+	UploadedImage bool
+
+	DurationSeconds int
+}
+
+func (s *Slide) Clone() *Slide {
+	new := *s
+	return &new
+}
+
+// SlideTrigger says when a slide in a set is shown.
+type SlideTrigger string
+
+const (
+	// SlideTriggerRotation slides are shown while the slideshow is on.
+	SlideTriggerRotation SlideTrigger = ""
+	// SlideTriggerBreak slides are shown automatically during breaks.
+	SlideTriggerBreak SlideTrigger = "break"
+	// SlideTriggerLevelStart slides are shown once, in order, as each level
+	// starts.
+	SlideTriggerLevelStart SlideTrigger = "level-start"
+)
+
+type SlideSetEntry struct {
+	SlideID int64
+	Trigger SlideTrigger
+}
+
+// SlideSet is an ordered selection of slides from the library.
+type SlideSet struct {
+	SlideSetID int64
+	Version    int64
+	Name       string
+	Entries    []SlideSetEntry
+}
+
+func (s *SlideSet) Clone() *SlideSet {
+	new := *s
+	new.Entries = append([]SlideSetEntry(nil), s.Entries...)
+	return &new
+}
+
+type AnnouncementStyle string
+
+const (
+	AnnouncementStyleBanner     AnnouncementStyle = "banner"
+	AnnouncementStyleFullScreen AnnouncementStyle = "fullscreen"
+)
+
+// Announcement is a message an operator pushes to the clocks, like "Dinner
+// break: food is in the back room."  It goes away by itself when it expires.
+type Announcement struct {
+	AnnouncementID int64
+	Version        int64
+
+	TournamentID int64 // 0 for every display
+	Style        AnnouncementStyle
+	Markdown     string
+	SoundID      int64 // 0 for none
+
+	Created time.Time
+	Expires time.Time
+}
+
+func (a *Announcement) Clone() *Announcement {
+	new := *a
+	return &new
+}
+
+type RecurrenceFrequency string
+
+const (
+	RecurrenceNone    RecurrenceFrequency = ""
+	RecurrenceWeekly  RecurrenceFrequency = "weekly"
+	RecurrenceMonthly RecurrenceFrequency = "monthly"
+)
+
+// Recurrence says when a tournament template's tournaments happen.
+type Recurrence struct {
+	Frequency RecurrenceFrequency
+
+	// Weekly: every Interval weeks (0 counts as 1), on each of Weekdays.
+	// Weeks are counted from the week of Starting.
+	Interval int
+	Weekdays []time.Weekday
+
+	// Monthly: on the WeekOfMonth'th (1 to 4, or -1 for the last) Weekday
+	// of the month.
+	WeekOfMonth int
+	Weekday     time.Weekday
+
+	StartTime string // "19:30", in Location
+	Location  string // IANA time zone name; empty means the server's
+
+	// Only dates from Starting through Until count.  Either may be zero.
+	Starting time.Time `json:",omitzero"`
+	Until    time.Time `json:",omitzero"`
+
+	// CreateAheadDays is how far ahead tournaments are created.  0 means
+	// the default.
+	CreateAheadDays int
+}
+
+// TournamentTemplate holds the settings for a tournament that is run again
+// and again, and when it runs.
+type TournamentTemplate struct {
+	TournamentTemplateID int64
+	Version              int64
+	Name                 string
+
+	// Tournament is copied for each new tournament.  Its State is the state
+	// a new tournament starts in.
+	Tournament *Tournament
+	Recurrence Recurrence
+
+	// ScheduledThrough is the latest start a tournament has been created
+	// for, so the same one isn't created twice.
+	ScheduledThrough time.Time `json:",omitzero"`
+}
+
+func (tt *TournamentTemplate) Clone() *TournamentTemplate {
+	new := *tt
+	if tt.Tournament != nil {
+		new.Tournament = tt.Tournament.Clone()
+	}
+	new.Recurrence.Weekdays = append([]time.Weekday(nil), tt.Recurrence.Weekdays...)
+	return &new
+}
+
+// PointsKind says how a league scores a finish.
+type PointsKind string
+
+const (
+	// PointsTable scores each place from a fixed table.
+	PointsTable PointsKind = "table"
+	// PointsField scores a point for each player finished ahead of, and
+	// one for playing.
+	PointsField PointsKind = "field"
+	// PointsSqrt scores sqrt(field size / place), so winning a big field
+	// counts for more but not in proportion.
+	PointsSqrt PointsKind = "sqrt"
+)
+
+// PointsFormula is how a league turns a finish into points.
+type PointsFormula struct {
+	Kind  PointsKind
+	Table []float64 `json:",omitempty"` // points for 1st, 2nd, ...; for table formulas
+
+	// Scale multiplies the points.  Zero means 1.
+	Scale float64 `json:",omitzero"`
+	// BaseBuyIn, if set, scales points by the tournament's buy-in (its
+	// PrizePoolPerBuyIn) over this, so bigger games count for more.
+	BaseBuyIn int `json:",omitzero"`
+	// Participation is added for every finish, including out of the points.
+	Participation float64 `json:",omitzero"`
+}
+
+// League groups tournaments, usually a season of them, and scores their
+// standings into a leaderboard.
+type League struct {
+	LeagueID    int64
+	Version     int64
+	Name        string
+	Season      string
+	Description string
+	Formula     PointsFormula
+
+	// CountBest, if set, counts only each player's best finishes.
+	CountBest int `json:",omitzero"`
+}
+
+func (l *League) Clone() *League {
+	new := *l
+	new.Formula.Table = append([]float64(nil), l.Formula.Table...)
+	return &new
+}
@@ -0,0 +1,43 @@
+package permission
+
+import (
+	"context"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// AnnouncementStorage lets anyone read announcements, since every clock shows
+// them, but only operators make them.
+type AnnouncementStorage struct {
+	Storage state.AnnouncementStorage
+}
+
+var _ state.AnnouncementStorage = &AnnouncementStorage{}
+
+func (s *AnnouncementStorage) FetchAnnouncements(ctx context.Context, expiringAfter time.Time) ([]*model.Announcement, error) {
+	return s.Storage.FetchAnnouncements(ctx, expiringAfter)
+}
+
+func (s *AnnouncementStorage) FetchAnnouncement(ctx context.Context, id int64) (*model.Announcement, error) {
+	return s.Storage.FetchAnnouncement(ctx, id)
+}
+
+func (s *AnnouncementStorage) CreateAnnouncement(ctx context.Context, a *model.Announcement) (int64, error) {
+	return requireOperatorReturning(ctx, func() (int64, error) {
+		return s.Storage.CreateAnnouncement(ctx, a)
+	})
+}
+
+func (s *AnnouncementStorage) SaveAnnouncement(ctx context.Context, a *model.Announcement) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.SaveAnnouncement(ctx, a)
+	})
+}
+
+func (s *AnnouncementStorage) DeleteAnnouncement(ctx context.Context, id int64) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.DeleteAnnouncement(ctx, id)
+	})
+}
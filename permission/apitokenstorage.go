@@ -0,0 +1,44 @@
+package permission
+
+import (
+	"context"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// APITokenStorage lets users manage their own tokens, and admins anyone's.
+type APITokenStorage struct {
+	Storage state.APITokenStorage
+}
+
+var _ state.APITokenStorage = &APITokenStorage{}
+
+func (s *APITokenStorage) CreateAPIToken(ctx context.Context, t *model.APIToken, hash string) (int64, error) {
+	return requireAdminOrUserIDReturning(ctx, t.UserID, func() (int64, error) {
+		return s.Storage.CreateAPIToken(ctx, t, hash)
+	})
+}
+
+func (s *APITokenStorage) FetchAPITokensByUserID(ctx context.Context, userID int64) ([]*model.APIToken, error) {
+	return requireAdminOrUserIDReturning(ctx, userID, func() ([]*model.APIToken, error) {
+		return s.Storage.FetchAPITokensByUserID(ctx, userID)
+	})
+}
+
+// This is how a request gets a user in the first place, so there's nobody
+// to check yet.
+func (s *APITokenStorage) FetchAPITokenByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	return s.Storage.FetchAPITokenByHash(ctx, hash)
+}
+
+func (s *APITokenStorage) TouchAPIToken(ctx context.Context, id int64, when time.Time) error {
+	return s.Storage.TouchAPIToken(ctx, id, when)
+}
+
+func (s *APITokenStorage) DeleteAPIToken(ctx context.Context, userID, id int64) error {
+	return requireAdminOrUserID(ctx, userID, func() error {
+		return s.Storage.DeleteAPIToken(ctx, userID, id)
+	})
+}
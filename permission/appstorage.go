@@ -13,7 +13,7 @@ type AppStorage struct {
 	Storage state.AppStorage
 }
 
-func (s *AppStorage) CreateFooterPlugSet(ctx context.Context, name string, plugs []string) (int64, error) {
+func (s *AppStorage) CreateFooterPlugSet(ctx context.Context, name string, plugs []model.FooterPlug) (int64, error) {
 	return requireOperatorReturning(ctx, func() (int64, error) {
 		return s.Storage.CreateFooterPlugSet(ctx, name, plugs)
 	})
@@ -34,7 +34,7 @@ func (s *AppStorage) ListFooterPlugSets(ctx context.Context) ([]*model.FooterPlu
 }
 
 // UpdateFooterPlugSet implements state.AppStorage.
-func (s *AppStorage) UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []string) error {
+func (s *AppStorage) UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []model.FooterPlug) error {
 	return requireOperator(ctx, func() error {
 		return s.Storage.UpdateFooterPlugSet(ctx, id, name, plugs)
 	})
@@ -3,8 +3,7 @@ package permission
 /*
 Package permission knows who you are and what you're allowed to do.
 
-TODO: Cookies aren't automatically rotated.  Site config is set at server
-boot time, which is wrong, but not wrong very often.
+TODO: Cookies aren't automatically rotated.
 */
 
 import (
@@ -88,6 +87,14 @@ func (bf *BakeryFactory) Bakery(ctx context.Context) (*Bakery, error) {
 	return bakery, nil
 }
 
+// Forget drops the cached Bakery, so the next one is made from the current
+// site config.  Call it when the site config changes.
+func (bf *BakeryFactory) Forget() {
+	bf.mutex.Lock()
+	defer bf.mutex.Unlock()
+	bf.cachedBakery = nil
+}
+
 // New creates a new Bakery instance.
 func (bf *BakeryFactory) NewBakery(ctx context.Context) (*Bakery, error) {
 	now := bf.clock.Now()
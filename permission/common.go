@@ -15,6 +15,16 @@ func requireAdminOrUserID(ctx context.Context, uid int64, fn func() error) error
 	}
 }
 
+func requireAdminOrUserIDReturning[T any](ctx context.Context, uid int64, fn func() (T, error)) (T, error) {
+	var zero T
+	if ui := UserFromContext(ctx); ui == nil {
+		return zero, errors.New("no user in context")
+	} else if !ui.IsAdmin && ui.ID != uid {
+		return zero, errors.New("permission denied")
+	}
+	return fn()
+}
+
 func requireUserAdminReturning[T any](ctx context.Context, fn func() (T, error)) (T, error) {
 	var zero T
 	u := UserFromContext(ctx)
@@ -0,0 +1,46 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// DisplayStorage lets anyone register (a kiosk has nobody logged in), but only
+// operators can see the list of displays or reassign them.
+type DisplayStorage struct {
+	Storage state.DisplayStorage
+}
+
+var _ state.DisplayStorage = &DisplayStorage{}
+
+func (s *DisplayStorage) FetchDisplays(ctx context.Context) ([]*model.Display, error) {
+	return requireOperatorReturning(ctx, func() ([]*model.Display, error) {
+		return s.Storage.FetchDisplays(ctx)
+	})
+}
+
+func (s *DisplayStorage) FetchDisplay(ctx context.Context, id int64) (*model.Display, error) {
+	return s.Storage.FetchDisplay(ctx, id)
+}
+
+func (s *DisplayStorage) FetchDisplayByDeviceID(ctx context.Context, deviceID string) (*model.Display, error) {
+	return s.Storage.FetchDisplayByDeviceID(ctx, deviceID)
+}
+
+func (s *DisplayStorage) RegisterDisplay(ctx context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error) {
+	return s.Storage.RegisterDisplay(ctx, deviceID, remoteAddr, userAgent)
+}
+
+func (s *DisplayStorage) SaveDisplay(ctx context.Context, d *model.Display) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.SaveDisplay(ctx, d)
+	})
+}
+
+func (s *DisplayStorage) DeleteDisplay(ctx context.Context, id int64) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.DeleteDisplay(ctx, id)
+	})
+}
@@ -0,0 +1,42 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// LayoutStorage lets anyone read layouts, since every clock needs one, but
+// only admins change them.
+type LayoutStorage struct {
+	Storage state.LayoutStorage
+}
+
+var _ state.LayoutStorage = &LayoutStorage{}
+
+func (s *LayoutStorage) FetchLayouts(ctx context.Context) ([]*model.Layout, error) {
+	return s.Storage.FetchLayouts(ctx)
+}
+
+func (s *LayoutStorage) FetchLayout(ctx context.Context, id int64) (*model.Layout, error) {
+	return s.Storage.FetchLayout(ctx, id)
+}
+
+func (s *LayoutStorage) CreateLayout(ctx context.Context, l *model.Layout) (int64, error) {
+	return requireSiteAdminReturning(ctx, func() (int64, error) {
+		return s.Storage.CreateLayout(ctx, l)
+	})
+}
+
+func (s *LayoutStorage) SaveLayout(ctx context.Context, l *model.Layout) error {
+	return requireSiteAdmin(ctx, func() error {
+		return s.Storage.SaveLayout(ctx, l)
+	})
+}
+
+func (s *LayoutStorage) DeleteLayout(ctx context.Context, id int64) error {
+	return requireSiteAdmin(ctx, func() error {
+		return s.Storage.DeleteLayout(ctx, id)
+	})
+}
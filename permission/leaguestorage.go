@@ -0,0 +1,46 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// LeagueStorage lets anyone read leagues, since leaderboards are public,
+// but only operators change them.
+type LeagueStorage struct {
+	Storage state.LeagueStorage
+}
+
+var _ state.LeagueStorage = &LeagueStorage{}
+
+func (s *LeagueStorage) FetchLeagues(ctx context.Context) ([]*model.League, error) {
+	return s.Storage.FetchLeagues(ctx)
+}
+
+func (s *LeagueStorage) FetchLeague(ctx context.Context, id int64) (*model.League, error) {
+	return s.Storage.FetchLeague(ctx, id)
+}
+
+func (s *LeagueStorage) CreateLeague(ctx context.Context, l *model.League) (int64, error) {
+	return requireOperatorReturning(ctx, func() (int64, error) {
+		return s.Storage.CreateLeague(ctx, l)
+	})
+}
+
+func (s *LeagueStorage) SaveLeague(ctx context.Context, l *model.League) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.SaveLeague(ctx, l)
+	})
+}
+
+func (s *LeagueStorage) DeleteLeague(ctx context.Context, id int64) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.DeleteLeague(ctx, id)
+	})
+}
+
+func (s *LeagueStorage) FetchLeagueTournaments(ctx context.Context, id int64) ([]*model.Tournament, error) {
+	return s.Storage.FetchLeagueTournaments(ctx, id)
+}
@@ -41,6 +41,22 @@ func UserFromContext(ctx context.Context) *model.UserIdentity {
 	}
 }
 
+type tokenContextKeyType struct{}
+
+// APITokenInContext records that the user in ctx came from an API token,
+// so can do only what the token's scopes allow.
+func APITokenInContext(ctx context.Context, t *model.APIToken) context.Context {
+	return context.WithValue(ctx, tokenContextKeyType{}, t)
+}
+
+// ScopeAllowed says whether the request may do what scope covers.  Users
+// logged in with a cookie may do anything; the storage still checks they
+// are allowed to.
+func ScopeAllowed(ctx context.Context, scope string) bool {
+	t, ok := ctx.Value(tokenContextKeyType{}).(*model.APIToken)
+	return !ok || t.HasScope(scope)
+}
+
 // Deprecated. Replace with requireOperator.
 func CheckWriteAccessToTournamentID(ctx context.Context, _ int64) error {
 	if !IsOperator(ctx) {
@@ -0,0 +1,78 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// SlideStorage lets anyone read slides, since they're shown on every clock,
+// but only operators change them.
+type SlideStorage struct {
+	Storage state.SlideStorage
+}
+
+var _ state.SlideStorage = &SlideStorage{}
+
+func (s *SlideStorage) FetchSlides(ctx context.Context) ([]*model.Slide, error) {
+	return s.Storage.FetchSlides(ctx)
+}
+
+func (s *SlideStorage) FetchSlide(ctx context.Context, id int64) (*model.Slide, error) {
+	return s.Storage.FetchSlide(ctx, id)
+}
+
+func (s *SlideStorage) CreateSlide(ctx context.Context, sl *model.Slide) (int64, error) {
+	return requireOperatorReturning(ctx, func() (int64, error) {
+		return s.Storage.CreateSlide(ctx, sl)
+	})
+}
+
+func (s *SlideStorage) SaveSlide(ctx context.Context, sl *model.Slide) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.SaveSlide(ctx, sl)
+	})
+}
+
+func (s *SlideStorage) DeleteSlide(ctx context.Context, id int64) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.DeleteSlide(ctx, id)
+	})
+}
+
+func (s *SlideStorage) FetchSlideImage(ctx context.Context, id int64) (string, []byte, error) {
+	return s.Storage.FetchSlideImage(ctx, id)
+}
+
+func (s *SlideStorage) SaveSlideImage(ctx context.Context, id int64, contentType string, data []byte) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.SaveSlideImage(ctx, id, contentType, data)
+	})
+}
+
+func (s *SlideStorage) FetchSlideSets(ctx context.Context) ([]*model.SlideSet, error) {
+	return s.Storage.FetchSlideSets(ctx)
+}
+
+func (s *SlideStorage) FetchSlideSet(ctx context.Context, id int64) (*model.SlideSet, error) {
+	return s.Storage.FetchSlideSet(ctx, id)
+}
+
+func (s *SlideStorage) CreateSlideSet(ctx context.Context, ss *model.SlideSet) (int64, error) {
+	return requireOperatorReturning(ctx, func() (int64, error) {
+		return s.Storage.CreateSlideSet(ctx, ss)
+	})
+}
+
+func (s *SlideStorage) SaveSlideSet(ctx context.Context, ss *model.SlideSet) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.SaveSlideSet(ctx, ss)
+	})
+}
+
+func (s *SlideStorage) DeleteSlideSet(ctx context.Context, id int64) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.DeleteSlideSet(ctx, id)
+	})
+}
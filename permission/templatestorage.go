@@ -0,0 +1,42 @@
+package permission
+
+import (
+	"context"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// TournamentTemplateStorage lets anyone read templates, since the public
+// calendar is made from them, but only operators change them.
+type TournamentTemplateStorage struct {
+	Storage state.TournamentTemplateStorage
+}
+
+var _ state.TournamentTemplateStorage = &TournamentTemplateStorage{}
+
+func (s *TournamentTemplateStorage) FetchTournamentTemplates(ctx context.Context) ([]*model.TournamentTemplate, error) {
+	return s.Storage.FetchTournamentTemplates(ctx)
+}
+
+func (s *TournamentTemplateStorage) FetchTournamentTemplate(ctx context.Context, id int64) (*model.TournamentTemplate, error) {
+	return s.Storage.FetchTournamentTemplate(ctx, id)
+}
+
+func (s *TournamentTemplateStorage) CreateTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) (int64, error) {
+	return requireOperatorReturning(ctx, func() (int64, error) {
+		return s.Storage.CreateTournamentTemplate(ctx, tt)
+	})
+}
+
+func (s *TournamentTemplateStorage) SaveTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.SaveTournamentTemplate(ctx, tt)
+	})
+}
+
+func (s *TournamentTemplateStorage) DeleteTournamentTemplate(ctx context.Context, id int64) error {
+	return requireOperator(ctx, func() error {
+		return s.Storage.DeleteTournamentTemplate(ctx, id)
+	})
+}
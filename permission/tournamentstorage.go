@@ -25,8 +25,8 @@ func (s *TournamentStorage) DeleteTournament(ctx context.Context, id int64) erro
 	})
 }
 
-func (s *TournamentStorage) FetchOverview(ctx context.Context, offset, limit int) (*model.Overview, error) {
-	return s.Storage.FetchOverview(ctx, offset, limit)
+func (s *TournamentStorage) FetchOverview(ctx context.Context, lifecycles []model.Lifecycle, offset, limit int) (*model.Overview, error) {
+	return s.Storage.FetchOverview(ctx, lifecycles, offset, limit)
 }
 
 func (s *TournamentStorage) FetchTournament(ctx context.Context, id int64) (*model.Tournament, error) {
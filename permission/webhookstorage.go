@@ -0,0 +1,72 @@
+package permission
+
+import (
+	"context"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// WebhookStorage is for admins only: a webhook can send tournaments to any
+// URL the server can reach.  The server itself fills and empties the
+// outbox with unprotected storage.
+type WebhookStorage struct {
+	Storage state.WebhookStorage
+}
+
+var _ state.WebhookStorage = &WebhookStorage{}
+
+func (s *WebhookStorage) FetchWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	return requireSiteAdminReturning(ctx, func() ([]*model.Webhook, error) {
+		return s.Storage.FetchWebhooks(ctx)
+	})
+}
+
+func (s *WebhookStorage) CreateWebhook(ctx context.Context, w *model.Webhook) (int64, error) {
+	return requireSiteAdminReturning(ctx, func() (int64, error) {
+		return s.Storage.CreateWebhook(ctx, w)
+	})
+}
+
+func (s *WebhookStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	return requireSiteAdmin(ctx, func() error {
+		return s.Storage.DeleteWebhook(ctx, id)
+	})
+}
+
+func (s *WebhookStorage) EnqueueWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) (bool, error) {
+	return requireSiteAdminReturning(ctx, func() (bool, error) {
+		return s.Storage.EnqueueWebhookDelivery(ctx, d)
+	})
+}
+
+func (s *WebhookStorage) ClaimWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	return requireSiteAdminReturning(ctx, func() ([]*model.WebhookDelivery, error) {
+		return s.Storage.ClaimWebhookDeliveries(ctx, now, until, limit)
+	})
+}
+
+func (s *WebhookStorage) SaveWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	return requireSiteAdmin(ctx, func() error {
+		return s.Storage.SaveWebhookDelivery(ctx, d)
+	})
+}
+
+func (s *WebhookStorage) FetchWebhookDeliveries(ctx context.Context, limit int) ([]*model.WebhookDelivery, error) {
+	return requireSiteAdminReturning(ctx, func() ([]*model.WebhookDelivery, error) {
+		return s.Storage.FetchWebhookDeliveries(ctx, limit)
+	})
+}
+
+func (s *WebhookStorage) RetryWebhookDelivery(ctx context.Context, id int64, now time.Time) error {
+	return requireSiteAdmin(ctx, func() error {
+		return s.Storage.RetryWebhookDelivery(ctx, id, now)
+	})
+}
+
+func (s *WebhookStorage) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	return requireSiteAdminReturning(ctx, func() (int64, error) {
+		return s.Storage.PruneWebhookDeliveries(ctx, before)
+	})
+}
@@ -0,0 +1,78 @@
+package schedule
+
+import (
+	"bufio"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Event is one entry in the calendar feed.
+type Event struct {
+	UID         string
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Description string
+	URL         string
+}
+
+const icsTimeLayout = "20060102T150405Z"
+
+var icsEscaper = strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`)
+
+// writeICSLine writes one content line, folded at 75 octets as RFC 5545
+// asks, without splitting a UTF-8 sequence.
+func writeICSLine(w *bufio.Writer, line string) {
+	limit := 75
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space of a continuation counts.
+		limit = 74
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+// WriteICS writes events as an iCalendar (RFC 5545) calendar named name.
+func WriteICS(out io.Writer, name string, events []Event, now time.Time) error {
+	w := bufio.NewWriter(out)
+	line := func(s string) { writeICSLine(w, s) }
+	text := func(key, value string) {
+		if value != "" {
+			line(key + ":" + icsEscaper.Replace(value))
+		}
+	}
+	stamp := func(t time.Time) string { return t.UTC().Format(icsTimeLayout) }
+
+	line("BEGIN:VCALENDAR")
+	line("VERSION:2.0")
+	line("PRODID:-//irata//poker clock//EN")
+	line("CALSCALE:GREGORIAN")
+	line("METHOD:PUBLISH")
+	text("X-WR-CALNAME", name)
+	for _, e := range events {
+		line("BEGIN:VEVENT")
+		text("UID", e.UID)
+		line("DTSTAMP:" + stamp(now))
+		line("DTSTART:" + stamp(e.Start))
+		if !e.End.IsZero() {
+			line("DTEND:" + stamp(e.End))
+		}
+		text("SUMMARY", e.Summary)
+		text("DESCRIPTION", e.Description)
+		if e.URL != "" {
+			line("URL:" + e.URL)
+		}
+		line("END:VEVENT")
+	}
+	line("END:VCALENDAR")
+	return w.Flush()
+}
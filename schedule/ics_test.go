@@ -0,0 +1,59 @@
+package schedule
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWriteICS(t *testing.T) {
+	start := time.Date(2026, 6, 2, 19, 0, 0, 0, time.FixedZone("PDT", -7*60*60))
+	var b strings.Builder
+	err := WriteICS(&b, "Home Game", []Event{{
+		UID:         "tournament-7@example.com",
+		Start:       start,
+		End:         start.Add(4 * time.Hour),
+		Summary:     "Turbo; $20, rebuys",
+		Description: "Line one\nLine two",
+		URL:         "https://example.com/t/7",
+	}}, time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Home Game\r\n",
+		"DTSTAMP:20260601T000000Z\r\n",
+		"DTSTART:20260603T020000Z\r\n",
+		"DTEND:20260603T060000Z\r\n",
+		`SUMMARY:Turbo\; $20\, rebuys` + "\r\n",
+		`DESCRIPTION:Line one\nLine two` + "\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in\n%s", want, got)
+		}
+	}
+}
+
+func TestFolding(t *testing.T) {
+	var b strings.Builder
+	long := strings.Repeat("é", 100)
+	if err := WriteICS(&b, long, nil, time.Time{}); err != nil {
+		t.Fatal(err)
+	}
+	var unfolded strings.Builder
+	for line := range strings.SplitSeq(strings.TrimSuffix(b.String(), "\r\n"), "\r\n") {
+		if len(line) > 75 {
+			t.Errorf("line is %d octets: %q", len(line), line)
+		}
+		if !strings.HasPrefix(line, " ") {
+			unfolded.WriteString("\n")
+		}
+		unfolded.WriteString(strings.TrimPrefix(line, " "))
+	}
+	if !strings.Contains(unfolded.String(), "X-WR-CALNAME:"+long) {
+		t.Errorf("folded line doesn't unfold to the original:\n%s", b.String())
+	}
+}
@@ -0,0 +1,349 @@
+// Package schedule works out when recurring tournaments happen, makes the
+// tournaments ahead of time, and writes the calendar feed.
+package schedule
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/model"
+)
+
+const (
+	// DefaultAheadDays is how far ahead tournaments are created if the
+	// template doesn't say.
+	DefaultAheadDays = 14
+	MaxAheadDays     = 366
+	MaxInterval      = 52
+
+	// TimeLayout and DateLayout are what time and date inputs send.
+	TimeLayout = "15:04"
+	DateLayout = "2006-01-02"
+)
+
+type Frequency struct {
+	Frequency   model.RecurrenceFrequency
+	Description string
+}
+
+// Frequencies are all the ways a template can recur, in the order the editor
+// offers them.
+var Frequencies = []Frequency{
+	{model.RecurrenceNone, "Not scheduled"},
+	{model.RecurrenceWeekly, "Weekly"},
+	{model.RecurrenceMonthly, "Monthly"},
+}
+
+type WeekOfMonth struct {
+	WeekOfMonth int
+	Description string
+}
+
+var WeeksOfMonth = []WeekOfMonth{
+	{1, "First"},
+	{2, "Second"},
+	{3, "Third"},
+	{4, "Fourth"},
+	{-1, "Last"},
+}
+
+// Location is where the rule's times are.
+func Location(r *model.Recurrence) (*time.Location, error) {
+	if r.Location == "" {
+		return time.Local, nil
+	}
+	return time.LoadLocation(r.Location)
+}
+
+// Validate checks a recurrence rule from the editor.
+func Validate(r *model.Recurrence) error {
+	switch r.Frequency {
+	case model.RecurrenceNone:
+		return nil
+	case model.RecurrenceWeekly:
+		if len(r.Weekdays) == 0 {
+			return errors.New("weekly schedule needs at least one day")
+		}
+		for _, d := range r.Weekdays {
+			if d < time.Sunday || d > time.Saturday {
+				return fmt.Errorf("no such weekday %d", d)
+			}
+		}
+		if r.Interval < 0 || r.Interval > MaxInterval {
+			return fmt.Errorf("interval must be between 1 and %d weeks", MaxInterval)
+		}
+		if r.Interval > 1 && r.Starting.IsZero() {
+			return errors.New("a schedule every few weeks needs a starting date to count from")
+		}
+	case model.RecurrenceMonthly:
+		if !slices.ContainsFunc(WeeksOfMonth, func(w WeekOfMonth) bool { return w.WeekOfMonth == r.WeekOfMonth }) {
+			return fmt.Errorf("no such week of the month %d", r.WeekOfMonth)
+		}
+		if r.Weekday < time.Sunday || r.Weekday > time.Saturday {
+			return fmt.Errorf("no such weekday %d", r.Weekday)
+		}
+	default:
+		return fmt.Errorf("no such frequency %q", r.Frequency)
+	}
+	if _, err := time.Parse(TimeLayout, r.StartTime); err != nil {
+		return fmt.Errorf("bad start time %q", r.StartTime)
+	}
+	if _, err := Location(r); err != nil {
+		return fmt.Errorf("bad time zone %q", r.Location)
+	}
+	if !r.Starting.IsZero() && !r.Until.IsZero() && r.Until.Before(r.Starting) {
+		return errors.New("schedule must end after it starts")
+	}
+	if r.CreateAheadDays < 0 || r.CreateAheadDays > MaxAheadDays {
+		return fmt.Errorf("tournaments can be created up to %d days ahead", MaxAheadDays)
+	}
+	return nil
+}
+
+// AheadDays is how far ahead the rule's tournaments are created.
+func AheadDays(r *model.Recurrence) int {
+	if r.CreateAheadDays == 0 {
+		return DefaultAheadDays
+	}
+	return r.CreateAheadDays
+}
+
+// dayNumber counts days, so dates can be compared without daylight saving
+// time getting in the way.
+func dayNumber(y int, m time.Month, d int) int {
+	return int(time.Date(y, m, d, 0, 0, 0, 0, time.UTC).Unix() / (24 * 60 * 60))
+}
+
+func dateNumber(t time.Time, loc *time.Location) int {
+	y, m, d := t.In(loc).Date()
+	return dayNumber(y, m, d)
+}
+
+func matches(r *model.Recurrence, y int, m time.Month, d int, loc *time.Location) bool {
+	date := time.Date(y, m, d, 12, 0, 0, 0, loc)
+	switch r.Frequency {
+	case model.RecurrenceWeekly:
+		if !slices.Contains(r.Weekdays, date.Weekday()) {
+			return false
+		}
+		if r.Interval <= 1 {
+			return true
+		}
+		// Weeks start on Sunday.
+		week := func(n int, wd time.Weekday) int { return (n - int(wd)) / 7 }
+		anchor := r.Starting.In(loc)
+		weeks := week(dayNumber(y, m, d), date.Weekday()) - week(dateNumber(anchor, loc), anchor.Weekday())
+		return weeks%r.Interval == 0
+	case model.RecurrenceMonthly:
+		if date.Weekday() != r.Weekday {
+			return false
+		}
+		if r.WeekOfMonth == -1 {
+			return date.AddDate(0, 0, 7).Month() != m
+		}
+		return (d-1)/7+1 == r.WeekOfMonth
+	}
+	return false
+}
+
+// Occurrences are the starts after after and no later than until.
+func Occurrences(r *model.Recurrence, after, until time.Time) ([]time.Time, error) {
+	if r.Frequency == model.RecurrenceNone {
+		return nil, nil
+	}
+	if err := Validate(r); err != nil {
+		return nil, err
+	}
+	loc, _ := Location(r)
+	clock, _ := time.Parse(TimeLayout, r.StartTime)
+
+	first, last := dateNumber(after, loc), dateNumber(until, loc)
+	if !r.Starting.IsZero() {
+		first = max(first, dateNumber(r.Starting, loc))
+	}
+	if !r.Until.IsZero() {
+		last = min(last, dateNumber(r.Until, loc))
+	}
+
+	starts := []time.Time{}
+	day := after.In(loc)
+	day = time.Date(day.Year(), day.Month(), day.Day(), 12, 0, 0, 0, loc)
+	for n := dateNumber(after, loc); n <= last; n++ {
+		y, m, d := day.Date()
+		day = day.AddDate(0, 0, 1)
+		if n < first || !matches(r, y, m, d, loc) {
+			continue
+		}
+		start := time.Date(y, m, d, clock.Hour(), clock.Minute(), 0, 0, loc)
+		if start.After(after) && !start.After(until) {
+			starts = append(starts, start)
+		}
+	}
+	return starts, nil
+}
+
+// Due are the starts the template's tournaments should be created for now:
+// those within its window that haven't been created already and haven't
+// already started.
+func Due(tt *model.TournamentTemplate, now time.Time) ([]time.Time, error) {
+	after := now
+	if tt.ScheduledThrough.After(after) {
+		after = tt.ScheduledThrough
+	}
+	return Occurrences(&tt.Recurrence, after, now.AddDate(0, 0, AheadDays(&tt.Recurrence)))
+}
+
+// Instantiate makes the template's tournament for the given start.
+func Instantiate(tt *model.TournamentTemplate, start time.Time) *model.Tournament {
+	t := tt.Tournament.Clone()
+	t.EventID = 0
+	t.Version = 0
+	t.EventName = strings.TrimSpace(t.EventName + " " + start.Format("Jan 2"))
+	t.ScheduledStart = start
+	t.FromTemplateID = tt.TournamentTemplateID
+	if t.State == nil {
+		t.State = &model.State{}
+	}
+	t.State.IsClockRunning = false
+	t.State.CurrentLevelEndsAt = nil
+	t.State.ChipCounts = nil
+	t.State.Seating = nil
+	t.State.Lifecycle = model.LifecycleScheduled
+	t.State.FinishedAt = time.Time{}
+	return t
+}
+
+// EstimatedDuration is how long a tournament's structure runs, for the
+// calendar.  Structures with no times are guessed at.
+func EstimatedDuration(t *model.Tournament) time.Duration {
+	minutes := 0
+	for _, l := range t.Structure.Levels {
+		minutes += l.DurationMinutes
+	}
+	if minutes == 0 {
+		return 4 * time.Hour
+	}
+	return time.Duration(minutes) * time.Minute
+}
+
+var weekdayNames = []string{"Sunday", "Monday", "Tuesday", "Wednesday", "Thursday", "Friday", "Saturday"}
+
+// Describe says when a rule happens, in English.
+func Describe(r *model.Recurrence) string {
+	var when string
+	switch r.Frequency {
+	case model.RecurrenceWeekly:
+		days := []string{}
+		for _, d := range r.Weekdays {
+			days = append(days, weekdayNames[d])
+		}
+		if r.Interval > 1 {
+			when = fmt.Sprintf("Every %d weeks on %s", r.Interval, strings.Join(days, ", "))
+		} else {
+			when = "Every " + strings.Join(days, ", ")
+		}
+	case model.RecurrenceMonthly:
+		week := ""
+		for _, w := range WeeksOfMonth {
+			if w.WeekOfMonth == r.WeekOfMonth {
+				week = w.Description
+			}
+		}
+		when = fmt.Sprintf("The %s %s of each month", strings.ToLower(week), weekdayNames[r.Weekday])
+	default:
+		return "Not scheduled"
+	}
+	when += " at " + r.StartTime
+	if r.Location != "" {
+		when += " " + r.Location
+	}
+	return when
+}
+
+func parseDate(s string, loc *time.Location) (time.Time, error) {
+	if s == "" {
+		return time.Time{}, nil
+	}
+	return time.ParseInLocation(DateLayout, s, loc)
+}
+
+// ParseRecurrence reads the recurrence part of the template editor.
+func ParseRecurrence(form url.Values) (model.Recurrence, error) {
+	r := model.Recurrence{
+		Frequency: model.RecurrenceFrequency(form.Get("Frequency")),
+		StartTime: form.Get("StartTime"),
+		Location:  strings.TrimSpace(form.Get("Location")),
+	}
+	atoi := func(key string) (int, error) {
+		s := strings.TrimSpace(form.Get(key))
+		if s == "" {
+			return 0, nil
+		}
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return 0, fmt.Errorf("bad %s %q", key, s)
+		}
+		return n, nil
+	}
+	var err error
+	if r.Interval, err = atoi("Interval"); err != nil {
+		return r, err
+	}
+	if r.WeekOfMonth, err = atoi("WeekOfMonth"); err != nil {
+		return r, err
+	}
+	wd, err := atoi("MonthlyWeekday")
+	if err != nil {
+		return r, err
+	}
+	r.Weekday = time.Weekday(wd)
+	for _, s := range form["Weekday"] {
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return r, fmt.Errorf("bad weekday %q", s)
+		}
+		r.Weekdays = append(r.Weekdays, time.Weekday(n))
+	}
+	slices.Sort(r.Weekdays)
+	if r.CreateAheadDays, err = atoi("CreateAheadDays"); err != nil {
+		return r, err
+	}
+
+	loc, err := Location(&r)
+	if err != nil {
+		return r, fmt.Errorf("bad time zone %q", r.Location)
+	}
+	if r.Starting, err = parseDate(form.Get("Starting"), loc); err != nil {
+		return r, fmt.Errorf("bad starting date: %w", err)
+	}
+	if r.Until, err = parseDate(form.Get("Until"), loc); err != nil {
+		return r, fmt.Errorf("bad ending date: %w", err)
+	}
+
+	// Only what the frequency uses is kept, so a rule reads back the way it
+	// works.
+	switch r.Frequency {
+	case model.RecurrenceWeekly:
+		r.WeekOfMonth, r.Weekday = 0, 0
+	case model.RecurrenceMonthly:
+		r.Interval, r.Weekdays = 0, nil
+	}
+	return r, Validate(&r)
+}
+
+// FormatDate formats t for a date input, in the rule's time zone.
+func FormatDate(r *model.Recurrence, t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	loc, err := Location(r)
+	if err != nil {
+		loc = time.Local
+	}
+	return t.In(loc).Format(DateLayout)
+}
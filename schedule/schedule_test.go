@@ -0,0 +1,262 @@
+package schedule
+
+import (
+	"net/url"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/model"
+)
+
+func mustLoad(t *testing.T, name string) *time.Location {
+	t.Helper()
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		t.Skipf("no time zone data for %s: %v", name, err)
+	}
+	return loc
+}
+
+func formatAll(ts []time.Time) []string {
+	got := []string{}
+	for _, t := range ts {
+		got = append(got, t.Format("Mon 2006-01-02 15:04 MST"))
+	}
+	return got
+}
+
+func TestValidate(t *testing.T) {
+	ok := model.Recurrence{Frequency: model.RecurrenceWeekly, Weekdays: []time.Weekday{time.Thursday}, StartTime: "19:00"}
+	for _, tc := range []struct {
+		name    string
+		edit    func(r *model.Recurrence)
+		wantErr string
+	}{
+		{"ok", func(r *model.Recurrence) {}, ""},
+		{"none", func(r *model.Recurrence) { *r = model.Recurrence{} }, ""},
+		{"no days", func(r *model.Recurrence) { r.Weekdays = nil }, "at least one day"},
+		{"bad day", func(r *model.Recurrence) { r.Weekdays = []time.Weekday{9} }, "no such weekday"},
+		{"bad frequency", func(r *model.Recurrence) { r.Frequency = "fortnightly" }, "no such frequency"},
+		{"bad time", func(r *model.Recurrence) { r.StartTime = "7pm" }, "start time"},
+		{"bad zone", func(r *model.Recurrence) { r.Location = "Mars/Olympus_Mons" }, "time zone"},
+		{"interval without start", func(r *model.Recurrence) { r.Interval = 2 }, "starting date"},
+		{"interval", func(r *model.Recurrence) { r.Interval = 2; r.Starting = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC) }, ""},
+		{"backwards", func(r *model.Recurrence) {
+			r.Starting = time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC)
+			r.Until = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+		}, "must end after"},
+		{"too far ahead", func(r *model.Recurrence) { r.CreateAheadDays = MaxAheadDays + 1 }, "days ahead"},
+		{"monthly", func(r *model.Recurrence) {
+			*r = model.Recurrence{Frequency: model.RecurrenceMonthly, WeekOfMonth: -1, Weekday: time.Friday, StartTime: "18:00"}
+		}, ""},
+		{"bad week", func(r *model.Recurrence) {
+			*r = model.Recurrence{Frequency: model.RecurrenceMonthly, WeekOfMonth: 5, StartTime: "18:00"}
+		}, "week of the month"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			r := ok
+			tc.edit(&r)
+			err := Validate(&r)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestOccurrences(t *testing.T) {
+	la := mustLoad(t, "America/Los_Angeles")
+	for _, tc := range []struct {
+		name        string
+		r           model.Recurrence
+		after, till time.Time
+		want        []string
+	}{
+		{
+			name:  "weekly",
+			r:     model.Recurrence{Frequency: model.RecurrenceWeekly, Weekdays: []time.Weekday{time.Tuesday, time.Thursday}, StartTime: "19:00", Location: "America/Los_Angeles"},
+			after: time.Date(2026, 6, 2, 19, 0, 0, 0, la), // a Tuesday, just as it starts
+			till:  time.Date(2026, 6, 11, 19, 0, 0, 0, la),
+			want:  []string{"Thu 2026-06-04 19:00 PDT", "Tue 2026-06-09 19:00 PDT", "Thu 2026-06-11 19:00 PDT"},
+		},
+		{
+			name:  "across daylight saving time",
+			r:     model.Recurrence{Frequency: model.RecurrenceWeekly, Weekdays: []time.Weekday{time.Saturday}, StartTime: "12:00", Location: "America/Los_Angeles"},
+			after: time.Date(2026, 10, 25, 0, 0, 0, 0, la),
+			till:  time.Date(2026, 11, 8, 0, 0, 0, 0, la),
+			want:  []string{"Sat 2026-10-31 12:00 PDT", "Sat 2026-11-07 12:00 PST"},
+		},
+		{
+			name: "every other week",
+			r: model.Recurrence{Frequency: model.RecurrenceWeekly, Interval: 2, Weekdays: []time.Weekday{time.Monday, time.Friday}, StartTime: "20:00",
+				Location: "America/Los_Angeles", Starting: time.Date(2026, 6, 3, 0, 0, 0, 0, la)},
+			after: time.Date(2026, 6, 1, 0, 0, 0, 0, la),
+			till:  time.Date(2026, 6, 30, 0, 0, 0, 0, la),
+			// The week of June 3 counts, but June 1 is before the start.
+			want: []string{"Fri 2026-06-05 20:00 PDT", "Mon 2026-06-15 20:00 PDT", "Fri 2026-06-19 20:00 PDT", "Mon 2026-06-29 20:00 PDT"},
+		},
+		{
+			name:  "second wednesday",
+			r:     model.Recurrence{Frequency: model.RecurrenceMonthly, WeekOfMonth: 2, Weekday: time.Wednesday, StartTime: "18:30", Location: "America/Los_Angeles"},
+			after: time.Date(2026, 1, 1, 0, 0, 0, 0, la),
+			till:  time.Date(2026, 4, 1, 0, 0, 0, 0, la),
+			want:  []string{"Wed 2026-01-14 18:30 PST", "Wed 2026-02-11 18:30 PST", "Wed 2026-03-11 18:30 PDT"},
+		},
+		{
+			name:  "last friday",
+			r:     model.Recurrence{Frequency: model.RecurrenceMonthly, WeekOfMonth: -1, Weekday: time.Friday, StartTime: "19:00", Location: "America/Los_Angeles"},
+			after: time.Date(2026, 1, 1, 0, 0, 0, 0, la),
+			till:  time.Date(2026, 3, 31, 0, 0, 0, 0, la),
+			want:  []string{"Fri 2026-01-30 19:00 PST", "Fri 2026-02-27 19:00 PST", "Fri 2026-03-27 19:00 PDT"},
+		},
+		{
+			name: "until",
+			r: model.Recurrence{Frequency: model.RecurrenceWeekly, Weekdays: []time.Weekday{time.Sunday}, StartTime: "14:00",
+				Location: "America/Los_Angeles", Until: time.Date(2026, 6, 14, 0, 0, 0, 0, la)},
+			after: time.Date(2026, 6, 1, 0, 0, 0, 0, la),
+			till:  time.Date(2026, 7, 1, 0, 0, 0, 0, la),
+			want:  []string{"Sun 2026-06-07 14:00 PDT", "Sun 2026-06-14 14:00 PDT"},
+		},
+		{
+			name:  "not scheduled",
+			r:     model.Recurrence{},
+			after: time.Date(2026, 6, 1, 0, 0, 0, 0, la),
+			till:  time.Date(2026, 7, 1, 0, 0, 0, 0, la),
+			want:  []string{},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := Occurrences(&tc.r, tc.after, tc.till)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if g := formatAll(got); !slices.Equal(g, tc.want) {
+				t.Errorf("got %q, want %q", g, tc.want)
+			}
+		})
+	}
+}
+
+func TestDue(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC) // a Monday
+	tt := &model.TournamentTemplate{
+		Recurrence: model.Recurrence{Frequency: model.RecurrenceWeekly, Weekdays: []time.Weekday{time.Monday}, StartTime: "19:00", Location: "UTC", CreateAheadDays: 14},
+	}
+	got, err := Due(tt, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// June 15 is just past the fourteen days.
+	want := []string{"Mon 2026-06-01 19:00 UTC", "Mon 2026-06-08 19:00 UTC"}
+	if g := formatAll(got); !slices.Equal(g, want) {
+		t.Errorf("got %q, want %q", g, want)
+	}
+
+	tt.ScheduledThrough = got[len(got)-1]
+	if got, _ := Due(tt, now); len(got) != 0 {
+		t.Errorf("after scheduling, got %q, want nothing", formatAll(got))
+	}
+	if got, _ := Due(tt, now.AddDate(0, 0, 7)); len(got) != 1 {
+		t.Errorf("a week later, got %q, want one more", formatAll(got))
+	}
+}
+
+func TestInstantiate(t *testing.T) {
+	ends := int64(12345)
+	tt := &model.TournamentTemplate{
+		TournamentTemplateID: 9,
+		Tournament: &model.Tournament{
+			EventID:   3,
+			Version:   7,
+			EventName: "Tuesday Turbo",
+			State: &model.State{
+				IsClockRunning:     true,
+				CurrentLevelEndsAt: &ends,
+				ChipCounts:         []*model.ChipCount{{}},
+				Lifecycle:          model.LifecycleFinished,
+			},
+		},
+	}
+	start := time.Date(2026, 6, 2, 19, 0, 0, 0, time.UTC)
+	got := Instantiate(tt, start)
+	if got.EventID != 0 || got.Version != 0 {
+		t.Errorf("got id %d version %d, want zeroes", got.EventID, got.Version)
+	}
+	if got.EventName != "Tuesday Turbo Jun 2" {
+		t.Errorf("got name %q", got.EventName)
+	}
+	if !got.ScheduledStart.Equal(start) || got.FromTemplateID != 9 {
+		t.Errorf("got start %v template %d", got.ScheduledStart, got.FromTemplateID)
+	}
+	if got.State.IsClockRunning || got.State.CurrentLevelEndsAt != nil || got.State.ChipCounts != nil ||
+		got.State.Lifecycle != model.LifecycleScheduled {
+		t.Errorf("state not reset: %+v", got.State)
+	}
+	if !tt.Tournament.State.IsClockRunning || tt.Tournament.EventID != 3 {
+		t.Errorf("template was changed")
+	}
+}
+
+func TestParseRecurrence(t *testing.T) {
+	r, err := ParseRecurrence(url.Values{
+		"Frequency":       {"weekly"},
+		"Weekday":         {"4", "2"},
+		"Interval":        {"1"},
+		"WeekOfMonth":     {"3"},
+		"StartTime":       {"19:30"},
+		"Location":        {"UTC"},
+		"Starting":        {"2026-06-01"},
+		"CreateAheadDays": {""},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(r.Weekdays, []time.Weekday{time.Tuesday, time.Thursday}) {
+		t.Errorf("got weekdays %v", r.Weekdays)
+	}
+	if r.WeekOfMonth != 0 {
+		t.Errorf("monthly field kept for weekly rule: %d", r.WeekOfMonth)
+	}
+	if got := FormatDate(&r, r.Starting); got != "2026-06-01" {
+		t.Errorf("got starting %q", got)
+	}
+	if got, want := Describe(&r), "Every Tuesday, Thursday at 19:30 UTC"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if _, err := ParseRecurrence(url.Values{"Frequency": {"weekly"}, "StartTime": {"19:30"}}); err == nil {
+		t.Errorf("weekly rule with no days parsed")
+	}
+}
+
+func TestDescribe(t *testing.T) {
+	for _, tc := range []struct {
+		r    model.Recurrence
+		want string
+	}{
+		{model.Recurrence{}, "Not scheduled"},
+		{model.Recurrence{Frequency: model.RecurrenceMonthly, WeekOfMonth: -1, Weekday: time.Friday, StartTime: "19:00"}, "The last Friday of each month at 19:00"},
+		{model.Recurrence{Frequency: model.RecurrenceWeekly, Interval: 2, Weekdays: []time.Weekday{time.Monday}, StartTime: "20:00"}, "Every 2 weeks on Monday at 20:00"},
+	} {
+		if got := Describe(&tc.r); got != tc.want {
+			t.Errorf("got %q, want %q", got, tc.want)
+		}
+	}
+}
+
+func TestEstimatedDuration(t *testing.T) {
+	tm := &model.Tournament{}
+	if got := EstimatedDuration(tm); got != 4*time.Hour {
+		t.Errorf("empty structure: got %v", got)
+	}
+	tm.Structure.Levels = []*model.Level{{DurationMinutes: 20}, {DurationMinutes: 25}}
+	if got := EstimatedDuration(tm); got != 45*time.Minute {
+		t.Errorf("got %v, want 45m", got)
+	}
+}
@@ -0,0 +1,142 @@
+package schedule
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/tournament"
+	"github.com/ts4z/irata/ts"
+)
+
+// DefaultInterval is how often the Scheduler looks for tournaments to
+// create.  Tournaments are made days ahead, so there's no hurry.
+const DefaultInterval = 15 * time.Minute
+
+// Lifecycler moves a tournament along its lifecycle.  tournament.Manager
+// implements this.
+type Lifecycler interface {
+	AdvanceLifecycle(ctx context.Context, m *model.Tournament) bool
+}
+
+// Scheduler creates the tournaments that templates call for, and moves
+// tournaments along their lifecycles when nobody is watching them.
+type Scheduler struct {
+	templates   state.TournamentTemplateStorage
+	tournaments state.TournamentStorage
+	lifecycler  Lifecycler
+	clock       ts.Clock
+}
+
+func NewScheduler(templates state.TournamentTemplateStorage, tournaments state.TournamentStorage, lifecycler Lifecycler, clock ts.Clock) *Scheduler {
+	return &Scheduler{templates: templates, tournaments: tournaments, lifecycler: lifecycler, clock: clock}
+}
+
+// RunOnce creates whatever tournaments are due, returning how many it made.
+//
+// A template is claimed by saving it with its new ScheduledThrough before its
+// tournaments are created; the version check means that when several servers
+// share a database, only one of them creates each tournament.  If creating
+// fails after the claim, that tournament is skipped rather than risk making
+// it twice; the operator can still make it by hand.
+func (s *Scheduler) RunOnce(ctx context.Context) (int, error) {
+	templates, err := s.templates.FetchTournamentTemplates(ctx)
+	if err != nil {
+		return 0, err
+	}
+	now := s.clock.Now()
+	created := 0
+	for _, tt := range templates {
+		if tt.Recurrence.Frequency == model.RecurrenceNone || tt.Tournament == nil {
+			continue
+		}
+		starts, err := Due(tt, now)
+		if err != nil {
+			log.Printf("schedule: template %d: %v", tt.TournamentTemplateID, err)
+			continue
+		}
+		if len(starts) == 0 {
+			continue
+		}
+		tt.ScheduledThrough = starts[len(starts)-1]
+		if err := s.templates.SaveTournamentTemplate(ctx, tt); err != nil {
+			log.Printf("schedule: can't claim template %d: %v", tt.TournamentTemplateID, err)
+			continue
+		}
+		for _, start := range starts {
+			id, err := s.tournaments.CreateTournament(ctx, Instantiate(tt, start))
+			if err != nil {
+				log.Printf("schedule: can't create tournament from template %d for %v: %v", tt.TournamentTemplateID, start, err)
+				continue
+			}
+			log.Printf("schedule: created tournament %d from template %d for %v", id, tt.TournamentTemplateID, start)
+			created++
+		}
+	}
+	return created, nil
+}
+
+// sweepBatch is how many tournaments SweepLifecycles looks at per query.
+const sweepBatch = 100
+
+// SweepLifecycles saves the lifecycle changes that time alone has brought
+// about, such as registration opening or the levels running out, returning
+// how many tournaments it changed.  A tournament someone is changing at the
+// same time is left for the next sweep.
+func (s *Scheduler) SweepLifecycles(ctx context.Context) (int, error) {
+	ids := []int64{}
+	for offset := 0; ; offset += sweepBatch {
+		o, err := s.tournaments.FetchOverview(ctx, tournament.ActiveLifecycles, offset, sweepBatch)
+		if err != nil {
+			return 0, err
+		}
+		for _, slug := range o.Slugs {
+			ids = append(ids, slug.TournamentID)
+		}
+		if len(o.Slugs) < sweepBatch {
+			break
+		}
+	}
+
+	changed := 0
+	for _, id := range ids {
+		t, err := s.tournaments.FetchTournament(ctx, id)
+		if err != nil {
+			log.Printf("schedule: can't fetch tournament %d: %v", id, err)
+			continue
+		}
+		// Don't change the cached copy.
+		t = t.Clone()
+		if !s.lifecycler.AdvanceLifecycle(ctx, t) {
+			continue
+		}
+		if err := s.tournaments.SaveTournament(ctx, t); err != nil {
+			log.Printf("schedule: can't save tournament %d: %v", id, err)
+			continue
+		}
+		log.Printf("schedule: tournament %d is now %s", id, t.State.Lifecycle)
+		changed++
+	}
+	return changed, nil
+}
+
+// Run calls RunOnce and SweepLifecycles every interval until ctx is done.
+func (s *Scheduler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		if _, err := s.RunOnce(ctx); err != nil {
+			log.Printf("schedule: %v", err)
+		}
+		if _, err := s.SweepLifecycles(ctx); err != nil {
+			log.Printf("schedule: %v", err)
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,188 @@
+package schedule
+
+import (
+	"context"
+	"errors"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+type fakeTemplates struct {
+	templates map[int64]*model.TournamentTemplate
+}
+
+var _ state.TournamentTemplateStorage = &fakeTemplates{}
+
+func (f *fakeTemplates) FetchTournamentTemplates(ctx context.Context) ([]*model.TournamentTemplate, error) {
+	got := []*model.TournamentTemplate{}
+	for _, tt := range f.templates {
+		got = append(got, tt.Clone())
+	}
+	return got, nil
+}
+
+func (f *fakeTemplates) FetchTournamentTemplate(ctx context.Context, id int64) (*model.TournamentTemplate, error) {
+	return f.templates[id].Clone(), nil
+}
+
+func (f *fakeTemplates) CreateTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) (int64, error) {
+	return 0, errors.New("not implemented")
+}
+
+func (f *fakeTemplates) SaveTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) error {
+	if f.templates[tt.TournamentTemplateID].Version != tt.Version {
+		return errors.New("optimistic lock failure")
+	}
+	tt.Version++
+	f.templates[tt.TournamentTemplateID] = tt.Clone()
+	return nil
+}
+
+func (f *fakeTemplates) DeleteTournamentTemplate(ctx context.Context, id int64) error {
+	return errors.New("not implemented")
+}
+
+// fakeTournaments keeps tournaments in created, with IDs counting from 1.
+type fakeTournaments struct {
+	created []*model.Tournament
+	saved   int
+}
+
+var _ state.TournamentStorage = &fakeTournaments{}
+
+func (f *fakeTournaments) FetchOverview(ctx context.Context, lifecycles []model.Lifecycle, offset, limit int) (*model.Overview, error) {
+	o := &model.Overview{}
+	for i, t := range f.created {
+		if len(lifecycles) == 0 || slices.Contains(lifecycles, t.State.Lifecycle) {
+			o.Slugs = append(o.Slugs, model.TournamentSlug{TournamentID: int64(i + 1)})
+		}
+	}
+	o.Total = len(o.Slugs)
+	o.Slugs = o.Slugs[min(offset, len(o.Slugs)):min(offset+limit, len(o.Slugs))]
+	return o, nil
+}
+
+func (f *fakeTournaments) CreateTournament(ctx context.Context, t *model.Tournament) (int64, error) {
+	f.created = append(f.created, t)
+	return int64(len(f.created)), nil
+}
+
+func (f *fakeTournaments) SaveTournament(ctx context.Context, t *model.Tournament) error {
+	f.created[t.EventID-1] = t
+	f.saved++
+	return nil
+}
+
+func (f *fakeTournaments) DeleteTournament(ctx context.Context, id int64) error {
+	return errors.New("not implemented")
+}
+
+func (f *fakeTournaments) FetchTournament(ctx context.Context, id int64) (*model.Tournament, error) {
+	return f.created[id-1], nil
+}
+
+// fakeLifecycler finishes every tournament.
+type fakeLifecycler struct{}
+
+func (fakeLifecycler) AdvanceLifecycle(ctx context.Context, m *model.Tournament) bool {
+	if m.State.Lifecycle == model.LifecycleFinished {
+		return false
+	}
+	m.State.Lifecycle = model.LifecycleFinished
+	return true
+}
+
+type fixedClock time.Time
+
+func (c fixedClock) Now() time.Time { return time.Time(c) }
+
+func TestRunOnce(t *testing.T) {
+	ctx := context.Background()
+	templates := &fakeTemplates{templates: map[int64]*model.TournamentTemplate{
+		1: {
+			TournamentTemplateID: 1,
+			Name:                 "Thursday",
+			Tournament:           &model.Tournament{EventName: "Thursday", State: &model.State{}},
+			Recurrence:           model.Recurrence{Frequency: model.RecurrenceWeekly, Weekdays: []time.Weekday{time.Thursday}, StartTime: "19:00", Location: "UTC"},
+		},
+		2: {
+			TournamentTemplateID: 2,
+			Name:                 "By hand",
+			Tournament:           &model.Tournament{EventName: "By hand", State: &model.State{}},
+		},
+	}}
+	tournaments := &fakeTournaments{}
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := NewScheduler(templates, tournaments, fakeLifecycler{}, fixedClock(now))
+
+	n, err := s.RunOnce(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n != 2 || len(tournaments.created) != 2 {
+		t.Fatalf("created %d tournaments, want 2", n)
+	}
+	if got := tournaments.created[0].EventName; got != "Thursday Jun 4" {
+		t.Errorf("got name %q", got)
+	}
+	if got := templates.templates[1].ScheduledThrough; !got.Equal(time.Date(2026, 6, 11, 19, 0, 0, 0, time.UTC)) {
+		t.Errorf("got scheduled through %v", got)
+	}
+
+	// Running again right away makes nothing more.
+	if n, err := s.RunOnce(ctx); err != nil || n != 0 {
+		t.Errorf("second run created %d, %v", n, err)
+	}
+}
+
+// claimingTemplates has another server claim every template just before this
+// one does.
+type claimingTemplates struct {
+	fakeTemplates
+}
+
+func (c *claimingTemplates) FetchTournamentTemplates(ctx context.Context) ([]*model.TournamentTemplate, error) {
+	got, err := c.fakeTemplates.FetchTournamentTemplates(ctx)
+	for _, tt := range c.templates {
+		tt.Version++
+	}
+	return got, err
+}
+
+func TestRunOnceLosesClaim(t *testing.T) {
+	templates := &claimingTemplates{fakeTemplates{templates: map[int64]*model.TournamentTemplate{
+		1: {
+			TournamentTemplateID: 1,
+			Tournament:           &model.Tournament{State: &model.State{}},
+			Recurrence:           model.Recurrence{Frequency: model.RecurrenceWeekly, Weekdays: []time.Weekday{time.Thursday}, StartTime: "19:00", Location: "UTC"},
+		},
+	}}}
+	tournaments := &fakeTournaments{}
+	s := NewScheduler(templates, tournaments, fakeLifecycler{}, fixedClock(time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)))
+	if n, err := s.RunOnce(context.Background()); err != nil || n != 0 {
+		t.Errorf("created %d, %v; want nothing", n, err)
+	}
+}
+
+func TestSweepLifecycles(t *testing.T) {
+	tournaments := &fakeTournaments{}
+	for i, l := range []model.Lifecycle{model.LifecycleRunning, model.LifecycleFinished, model.LifecycleScheduled, model.LifecycleArchived} {
+		tournaments.created = append(tournaments.created, &model.Tournament{EventID: int64(i + 1), State: &model.State{Lifecycle: l}})
+	}
+	s := NewScheduler(&fakeTemplates{}, tournaments, fakeLifecycler{}, fixedClock(time.Now()))
+	n, err := s.SweepLifecycles(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Only the running and scheduled tournaments are looked at.
+	if n != 2 || tournaments.saved != 2 {
+		t.Errorf("changed %d, saved %d; want 2", n, tournaments.saved)
+	}
+	if got := tournaments.created[3].State.Lifecycle; got != model.LifecycleArchived {
+		t.Errorf("archived tournament is now %q", got)
+	}
+}
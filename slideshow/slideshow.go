@@ -0,0 +1,175 @@
+// Package slideshow checks slides and slide sets, and works out which slides
+// a clock should carry.
+//
+// The clock gets every slide it might show, each tagged with its trigger and
+// duration, and movement.js picks the one to show from the wall clock.  Since
+// the clients' clocks are synchronized, so are their slideshows.
+package slideshow
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/league"
+	"github.com/ts4z/irata/model"
+)
+
+const (
+	MinDuration = 3 * time.Second
+	MaxDuration = time.Hour
+
+	// LegacyDuration is how long each slide in SiteConfig.Slides is shown.
+	LegacyDuration = 30 * time.Second
+)
+
+type Kind struct {
+	Kind        model.SlideKind
+	Description string
+}
+
+// Kinds are all the kinds of slide, in the order the editor offers them.
+var Kinds = []Kind{
+	{model.SlideKindMarkdown, "Markdown text"},
+	{model.SlideKindImage, "An image, uploaded or by URL"},
+	{model.SlideKindPayouts, "The tournament's payouts"},
+	{model.SlideKindStructure, "The next few levels of the structure"},
+	{model.SlideKindLeaderboard, "Chip leaders, when there are chip counts"},
+	{model.SlideKindLeague, "A league's season leaderboard"},
+}
+
+type Trigger struct {
+	Trigger     model.SlideTrigger
+	Description string
+}
+
+var Triggers = []Trigger{
+	{model.SlideTriggerRotation, "When the slideshow is on"},
+	{model.SlideTriggerBreak, "Automatically during breaks"},
+	{model.SlideTriggerLevelStart, "Once as each level starts"},
+}
+
+func isKind(k model.SlideKind) bool {
+	return slices.ContainsFunc(Kinds, func(x Kind) bool { return x.Kind == k })
+}
+
+func isTrigger(t model.SlideTrigger) bool {
+	return slices.ContainsFunc(Triggers, func(x Trigger) bool { return x.Trigger == t })
+}
+
+// Validate checks a slide from the editor.
+func Validate(sl *model.Slide) error {
+	if strings.TrimSpace(sl.Name) == "" {
+		return errors.New("slide needs a name")
+	}
+	if !isKind(sl.Kind) {
+		return fmt.Errorf("no such kind of slide %q", sl.Kind)
+	}
+	d := time.Duration(sl.DurationSeconds) * time.Second
+	if d < MinDuration || d > MaxDuration {
+		return fmt.Errorf("duration must be between %v and %v", MinDuration, MaxDuration)
+	}
+	switch sl.Kind {
+	case model.SlideKindMarkdown:
+		if strings.TrimSpace(sl.Markdown) == "" {
+			return errors.New("markdown slide has no text")
+		}
+	case model.SlideKindImage:
+		if !sl.UploadedImage && sl.ImageURL == "" {
+			return errors.New("image slide needs an image URL or an upload")
+		}
+	case model.SlideKindLeague:
+		if sl.LeagueID == 0 {
+			return errors.New("league slide needs a league")
+		}
+	}
+	return nil
+}
+
+// ImageURL is where the browser gets a slide's image.  Uploaded images are
+// tagged with the version so a new upload isn't hidden by the cache.
+func ImageURL(sl *model.Slide) string {
+	if sl.UploadedImage {
+		return fmt.Sprintf("/slide/%d/image?v=%d", sl.SlideID, sl.Version)
+	}
+	return sl.ImageURL
+}
+
+// Show is a slide as the clock will carry it.
+type Show struct {
+	Slide          *model.Slide
+	Trigger        model.SlideTrigger
+	DurationMillis int64
+	ImageURL       string
+
+	// League is the leaderboard for a league slide.  The caller fills it
+	// in, since it comes from the league's tournaments.
+	League *league.Board
+}
+
+// Resolve turns a slide set into the slides to show, in order.  Entries for
+// slides that have since been deleted are skipped.
+func Resolve(set *model.SlideSet, library []*model.Slide) []Show {
+	byID := map[int64]*model.Slide{}
+	for _, sl := range library {
+		byID[sl.SlideID] = sl
+	}
+	shows := []Show{}
+	for _, e := range set.Entries {
+		sl, ok := byID[e.SlideID]
+		if !ok {
+			continue
+		}
+		shows = append(shows, Show{
+			Slide:          sl,
+			Trigger:        e.Trigger,
+			DurationMillis: (time.Duration(sl.DurationSeconds) * time.Second).Milliseconds(),
+			ImageURL:       ImageURL(sl),
+		})
+	}
+	return shows
+}
+
+// Legacy is what to show for a site with no slide set: the image URLs from
+// the site config, then the chip leaders, all in rotation.
+func Legacy(urls []string) []Show {
+	shows := []Show{}
+	for _, u := range urls {
+		shows = append(shows, Show{
+			Slide:          &model.Slide{Kind: model.SlideKindImage, ImageURL: u},
+			DurationMillis: LegacyDuration.Milliseconds(),
+			ImageURL:       u,
+		})
+	}
+	return append(shows, Show{
+		Slide:          &model.Slide{Kind: model.SlideKindLeaderboard},
+		DurationMillis: LegacyDuration.Milliseconds(),
+	})
+}
+
+// ParseEntries reads the slide set editor, which sends parallel SlideID and
+// Trigger values, one pair per row.  Rows with no slide are dropped.
+func ParseEntries(slideIDs, triggers []string) ([]model.SlideSetEntry, error) {
+	if len(slideIDs) != len(triggers) {
+		return nil, fmt.Errorf("got %d slides but %d triggers", len(slideIDs), len(triggers))
+	}
+	entries := []model.SlideSetEntry{}
+	for i, s := range slideIDs {
+		id, err := strconv.ParseInt(s, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("row %d: bad slide id %q", i+1, s)
+		}
+		if id == 0 {
+			continue
+		}
+		trigger := model.SlideTrigger(triggers[i])
+		if !isTrigger(trigger) {
+			return nil, fmt.Errorf("row %d: no such trigger %q", i+1, trigger)
+		}
+		entries = append(entries, model.SlideSetEntry{SlideID: id, Trigger: trigger})
+	}
+	return entries, nil
+}
@@ -0,0 +1,102 @@
+package slideshow
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/ts4z/irata/model"
+)
+
+func TestValidate(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		sl      model.Slide
+		wantErr string
+	}{
+		{"markdown", model.Slide{Name: "hi", Kind: model.SlideKindMarkdown, Markdown: "# Hi", DurationSeconds: 10}, ""},
+		{"generated", model.Slide{Name: "pay", Kind: model.SlideKindPayouts, DurationSeconds: 20}, ""},
+		{"uploaded", model.Slide{Name: "logo", Kind: model.SlideKindImage, UploadedImage: true, DurationSeconds: 20}, ""},
+		{"no name", model.Slide{Kind: model.SlideKindPayouts, DurationSeconds: 20}, "needs a name"},
+		{"bad kind", model.Slide{Name: "x", Kind: "weather", DurationSeconds: 20}, "no such kind"},
+		{"too short", model.Slide{Name: "x", Kind: model.SlideKindPayouts, DurationSeconds: 1}, "duration"},
+		{"too long", model.Slide{Name: "x", Kind: model.SlideKindPayouts, DurationSeconds: 7200}, "duration"},
+		{"empty markdown", model.Slide{Name: "x", Kind: model.SlideKindMarkdown, Markdown: " \n", DurationSeconds: 10}, "no text"},
+		{"imageless", model.Slide{Name: "x", Kind: model.SlideKindImage, DurationSeconds: 10}, "needs an image"},
+		{"league", model.Slide{Name: "x", Kind: model.SlideKindLeague, LeagueID: 2, DurationSeconds: 10}, ""},
+		{"leagueless", model.Slide{Name: "x", Kind: model.SlideKindLeague, DurationSeconds: 10}, "needs a league"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			err := Validate(&tc.sl)
+			if tc.wantErr == "" {
+				if err != nil {
+					t.Errorf("unexpected error: %v", err)
+				}
+			} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+				t.Errorf("got error %v, want one containing %q", err, tc.wantErr)
+			}
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	library := []*model.Slide{
+		{SlideID: 1, Version: 4, Kind: model.SlideKindImage, UploadedImage: true, DurationSeconds: 10},
+		{SlideID: 2, Kind: model.SlideKindPayouts, DurationSeconds: 20},
+	}
+	set := &model.SlideSet{Entries: []model.SlideSetEntry{
+		{SlideID: 2, Trigger: model.SlideTriggerBreak},
+		{SlideID: 99},
+		{SlideID: 1},
+		{SlideID: 2},
+	}}
+	got := Resolve(set, library)
+	if len(got) != 3 {
+		t.Fatalf("got %d slides, want 3 (deleted slide skipped)", len(got))
+	}
+	if got[0].Slide.SlideID != 2 || got[0].Trigger != model.SlideTriggerBreak || got[0].DurationMillis != 20000 {
+		t.Errorf("first slide = %+v", got[0])
+	}
+	if got[1].ImageURL != "/slide/1/image?v=4" {
+		t.Errorf("uploaded image URL = %q", got[1].ImageURL)
+	}
+	if got[2].Trigger != model.SlideTriggerRotation {
+		t.Errorf("third slide trigger = %q", got[2].Trigger)
+	}
+}
+
+func TestLegacy(t *testing.T) {
+	got := Legacy([]string{"/a.png", "/b.png"})
+	kinds := []model.SlideKind{}
+	for _, s := range got {
+		kinds = append(kinds, s.Slide.Kind)
+		if s.DurationMillis != LegacyDuration.Milliseconds() {
+			t.Errorf("duration = %d", s.DurationMillis)
+		}
+	}
+	want := []model.SlideKind{model.SlideKindImage, model.SlideKindImage, model.SlideKindLeaderboard}
+	if !slices.Equal(kinds, want) {
+		t.Errorf("kinds = %v, want %v", kinds, want)
+	}
+}
+
+func TestParseEntries(t *testing.T) {
+	got, err := ParseEntries([]string{"3", "0", "1"}, []string{"", "", "break"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []model.SlideSetEntry{{SlideID: 3}, {SlideID: 1, Trigger: model.SlideTriggerBreak}}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %+v, want %+v", got, want)
+	}
+
+	if _, err := ParseEntries([]string{"1"}, []string{"sometimes"}); err == nil {
+		t.Error("bad trigger accepted")
+	}
+	if _, err := ParseEntries([]string{"x"}, []string{""}); err == nil {
+		t.Error("bad slide id accepted")
+	}
+	if _, err := ParseEntries([]string{"1", "2"}, []string{""}); err == nil {
+		t.Error("mismatched rows accepted")
+	}
+}
@@ -0,0 +1,114 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ AnnouncementStorage = &DBStorage{}
+
+func scanAnnouncement(row rowScanner) (*model.Announcement, error) {
+	var id, version int64
+	var bytes []byte
+	if err := row.Scan(&id, &version, &bytes); err != nil {
+		return nil, err
+	}
+	a := &model.Announcement{}
+	if err := json.Unmarshal(bytes, a); err != nil {
+		return nil, fmt.Errorf("unmarshal announcement %d: %w", id, err)
+	}
+	a.AnnouncementID = id
+	a.Version = version
+	return a, nil
+}
+
+func (s *DBStorage) FetchAnnouncements(ctx context.Context, expiringAfter time.Time) ([]*model.Announcement, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT announcement_id, version, model_data FROM announcements WHERE expires > $1 ORDER BY announcement_id`,
+		expiringAfter.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	announcements := []*model.Announcement{}
+	for rows.Next() {
+		a, err := scanAnnouncement(rows)
+		if err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return announcements, nil
+}
+
+func (s *DBStorage) FetchAnnouncement(ctx context.Context, id int64) (*model.Announcement, error) {
+	a, err := scanAnnouncement(s.db.QueryRowContext(ctx,
+		`SELECT announcement_id, version, model_data FROM announcements WHERE announcement_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no such announcement id %d", id))
+	} else if err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+func (s *DBStorage) CreateAnnouncement(ctx context.Context, a *model.Announcement) (int64, error) {
+	bytes, err := json.Marshal(a)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO announcements (expires, model_data) VALUES ($1, $2) RETURNING announcement_id`,
+		a.Expires.UTC(), bytes).Scan(&id); err != nil {
+		return 0, err
+	}
+	s.notify(ctx, "announcements", id, 0)
+	return id, nil
+}
+
+func (s *DBStorage) SaveAnnouncement(ctx context.Context, a *model.Announcement) error {
+	bytes, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	newVersion := a.Version + 1
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE announcements SET version = $1, expires = $2, model_data = $3 WHERE announcement_id = $4 AND version = $5`,
+		newVersion, a.Expires.UTC(), bytes, a.AnnouncementID, a.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return fmt.Errorf("optimistic lock failure, %d rows affected", n)
+	}
+	a.Version = newVersion
+	s.notify(ctx, "announcements", a.AnnouncementID, newVersion)
+	return nil
+}
+
+func (s *DBStorage) DeleteAnnouncement(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM announcements WHERE announcement_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return he.New(404, fmt.Errorf("%d rows deleted", n))
+	}
+	s.notifyDeleted(ctx, "announcements", id)
+	return nil
+}
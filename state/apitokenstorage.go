@@ -0,0 +1,93 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ APITokenStorage = &DBStorage{}
+
+// touchInterval is how stale last_used may get, so a busy token doesn't
+// write to the database on every request.
+const touchInterval = time.Minute
+
+func (s *DBStorage) CreateAPIToken(ctx context.Context, t *model.APIToken, hash string) (int64, error) {
+	var id int64
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO api_tokens (user_id, name, scopes, token_hash, created) VALUES ($1, $2, $3, $4, $5)
+		 RETURNING token_id`,
+		t.UserID, t.Name, strings.Join(t.Scopes, ","), hash, time.Now().UTC()).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func scanAPIToken(row interface{ Scan(...any) error }) (*model.APIToken, error) {
+	t := &model.APIToken{}
+	var scopes string
+	if err := row.Scan(&t.ID, &t.UserID, &t.Name, &scopes, &t.Created, &t.LastUsed); err != nil {
+		return nil, err
+	}
+	if scopes != "" {
+		t.Scopes = strings.Split(scopes, ",")
+	}
+	return t, nil
+}
+
+const apiTokenColumns = `token_id, user_id, name, scopes, created, last_used`
+
+func (s *DBStorage) FetchAPITokensByUserID(ctx context.Context, userID int64) ([]*model.APIToken, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+apiTokenColumns+` FROM api_tokens WHERE user_id = $1 ORDER BY token_id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tokens := []*model.APIToken{}
+	for rows.Next() {
+		t, err := scanAPIToken(rows)
+		if err != nil {
+			return nil, err
+		}
+		tokens = append(tokens, t)
+	}
+	return tokens, rows.Err()
+}
+
+func (s *DBStorage) FetchAPITokenByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	t, err := scanAPIToken(s.db.QueryRowContext(ctx,
+		`SELECT `+apiTokenColumns+` FROM api_tokens WHERE token_hash = $1`, hash))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no such API token"))
+	}
+	return t, err
+}
+
+func (s *DBStorage) TouchAPIToken(ctx context.Context, id int64, when time.Time) error {
+	when = when.UTC()
+	_, err := s.db.ExecContext(ctx,
+		`UPDATE api_tokens SET last_used = $1 WHERE token_id = $2 AND (last_used IS NULL OR last_used < $3)`,
+		when, id, when.Add(-touchInterval))
+	return err
+}
+
+func (s *DBStorage) DeleteAPIToken(ctx context.Context, userID, id int64) error {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM api_tokens WHERE token_id = $1 AND user_id = $2`, id, userID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return he.New(404, fmt.Errorf("no such API token id %d", id))
+	}
+	return nil
+}
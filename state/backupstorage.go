@@ -0,0 +1,165 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ts4z/irata/dbutil"
+	"github.com/ts4z/irata/model"
+)
+
+// UserBackup is what a backup keeps of a user.  Passwords are only ever
+// hashes.
+type UserBackup struct {
+	model.UserIdentity
+	EmailAddresses []string
+	Passwords      []model.Password
+}
+
+// backupTables are the tables a restore expects to find empty.  site_config
+// always has its one row, so it isn't here.
+var backupTables = []string{
+	"users",
+	"structures",
+	"footer_plug_sets",
+	"layouts",
+	"leagues",
+	"slides",
+	"slide_sets",
+	"tournament_templates",
+	"tournaments",
+	"displays",
+	"announcements",
+}
+
+// CountRows counts the rows in each table a backup covers.
+func (s *DBStorage) CountRows(ctx context.Context) (map[string]int64, error) {
+	counts := map[string]int64{}
+	for _, table := range backupTables {
+		var n int64
+		if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM "+table).Scan(&n); err != nil {
+			return nil, fmt.Errorf("counting %s: %w", table, err)
+		}
+		counts[table] = n
+	}
+	return counts, nil
+}
+
+// FetchUserBackups fetches every user with their email addresses and
+// password hashes.
+func (s *DBStorage) FetchUserBackups(ctx context.Context) ([]*UserBackup, error) {
+	users, err := s.FetchUsers(ctx)
+	if err != nil {
+		return nil, err
+	}
+	backups := []*UserBackup{}
+	byID := map[int64]*UserBackup{}
+	for _, u := range users {
+		b := &UserBackup{UserIdentity: *u}
+		backups = append(backups, b)
+		byID[u.ID] = b
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT user_id, email_address FROM user_email_addresses ORDER BY email_address`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var id int64
+		var email string
+		if err := rows.Scan(&id, &email); err != nil {
+			return nil, err
+		}
+		if b, ok := byID[id]; ok {
+			b.EmailAddresses = append(b.EmailAddresses, email)
+		}
+	}
+	if rows.Err() != nil {
+		return nil, rows.Err()
+	}
+
+	pwRows, err := s.db.QueryContext(ctx,
+		`SELECT user_id, hashed_password, expires FROM passwords ORDER BY password_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer pwRows.Close()
+	for pwRows.Next() {
+		var id int64
+		var hashed string
+		var expires *time.Time
+		if err := pwRows.Scan(&id, &hashed, &expires); err != nil {
+			return nil, err
+		}
+		if b, ok := byID[id]; ok {
+			b.Passwords = append(b.Passwords, model.Password{
+				PasswordHash: hashed,
+				ExpiresAt:    expires,
+			})
+		}
+	}
+	if pwRows.Err() != nil {
+		return nil, pwRows.Err()
+	}
+
+	return backups, nil
+}
+
+// RestoreUser creates a user from a backup, with its email addresses and
+// password hashes, and returns the new user ID.
+func (s *DBStorage) RestoreUser(ctx context.Context, u *UserBackup) (int64, error) {
+	tx, err := dbutil.NewTx(ctx, s.db, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.MaybeRollback()
+
+	var userID int64
+	if err := tx.QueryRow(ctx,
+		`INSERT INTO users (nick, is_admin, is_operator) VALUES ($1, $2, $3) RETURNING user_id`,
+		u.Nick, u.IsAdmin, u.IsOperator).Scan(&userID); err != nil {
+		return 0, fmt.Errorf("insert user %q: %w", u.Nick, err)
+	}
+	for _, email := range u.EmailAddresses {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO user_email_addresses (email_address, user_id) VALUES ($1, $2)`,
+			email, userID); err != nil {
+			return 0, fmt.Errorf("insert email address for %q: %w", u.Nick, err)
+		}
+	}
+	for _, pw := range u.Passwords {
+		if _, err := tx.Exec(ctx,
+			`INSERT INTO passwords (user_id, hashed_password, expires) VALUES ($1, $2, $3)`,
+			userID, pw.PasswordHash, pw.ExpiresAt); err != nil {
+			return 0, fmt.Errorf("insert password for %q: %w", u.Nick, err)
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+	s.notify(ctx, "users", userID, 0)
+	return userID, nil
+}
+
+// RestoreTournament creates a tournament exactly as given, clock and all.
+// CreateTournament is for new tournaments, and resets the clock.
+func (s *DBStorage) RestoreTournament(ctx context.Context, t *model.Tournament) (int64, error) {
+	cpy := *t
+	cpy.Transients = nil
+	bytes, err := json.Marshal(&cpy)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO tournaments (lifecycle, league_id, model_data) VALUES ($1, $2, $3) RETURNING tournament_id`,
+		storedLifecycle(&cpy), cpy.LeagueID, bytes).Scan(&id); err != nil {
+		return 0, err
+	}
+	s.notify(ctx, "tournaments", id, 0)
+	return id, nil
+}
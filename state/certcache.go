@@ -0,0 +1,38 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+)
+
+var _ CertCacheStorage = &DBStorage{}
+
+// ErrNotCached is what FetchCertCache returns for a key it doesn't have.
+var ErrNotCached = errors.New("not in certificate cache")
+
+func (s *DBStorage) FetchCertCache(ctx context.Context, key string) ([]byte, error) {
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT data FROM cert_cache WHERE key = $1`, key).Scan(&data)
+	if err == sql.ErrNoRows {
+		return nil, ErrNotCached
+	}
+	if err != nil {
+		return nil, err
+	}
+	return data, nil
+}
+
+func (s *DBStorage) SaveCertCache(ctx context.Context, key string, data []byte) error {
+	_, err := s.db.ExecContext(ctx,
+		`INSERT INTO cert_cache (key, data, updated) VALUES ($1, $2, $3)
+		 ON CONFLICT (key) DO UPDATE SET data = EXCLUDED.data, updated = EXCLUDED.updated`,
+		key, data, time.Now().UTC())
+	return err
+}
+
+func (s *DBStorage) DeleteCertCache(ctx context.Context, key string) error {
+	_, err := s.db.ExecContext(ctx, `DELETE FROM cert_cache WHERE key = $1`, key)
+	return err
+}
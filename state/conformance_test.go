@@ -0,0 +1,706 @@
+package state
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/dbutil"
+	"github.com/ts4z/irata/migrate"
+	"github.com/ts4z/irata/model"
+)
+
+// The conformance tests run against every backend there is.  SQLite always
+// runs, in a scratch file, as does MemStorage.  Postgres runs when
+// IRATA_TEST_DB_URL names a database the tests may scribble in; it is
+// migrated first.
+//
+// notifies says whether the backend tells its Notifier about changes, rather
+// than leaving that to database triggers.
+var backends = map[string]struct {
+	open     func(t *testing.T) Storage
+	notifies bool
+}{
+	"sqlite": {func(t *testing.T) Storage {
+		db, err := dbutil.OpenSQLite(filepath.Join(t.TempDir(), "irata.db"))
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		return migratedStorage(t, db)
+	}, true},
+	"postgres": {func(t *testing.T) Storage {
+		url := os.Getenv("IRATA_TEST_DB_URL")
+		if url == "" {
+			t.Skip("IRATA_TEST_DB_URL isn't set")
+		}
+		db, err := sql.Open("pgx", url)
+		if err != nil {
+			t.Fatalf("open: %v", err)
+		}
+		return migratedStorage(t, db)
+	}, false},
+	"memory": {func(t *testing.T) Storage { return NewMemStorage() }, true},
+}
+
+func migratedStorage(t *testing.T, db *sql.DB) *DBStorage {
+	ctx := context.Background()
+	t.Cleanup(func() { db.Close() })
+	if err := migrate.Migrate(ctx, db, nil); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	if err := migrate.Check(ctx, db); err != nil {
+		t.Fatalf("check after migrating: %v", err)
+	}
+	s, err := NewDBStorage(ctx, db)
+	if err != nil {
+		t.Fatalf("NewDBStorage: %v", err)
+	}
+	return s
+}
+
+type recordedNotification struct {
+	table       string
+	id, version int64
+	deleted     bool
+}
+
+type recordingNotifier struct {
+	mu   sync.Mutex
+	seen []recordedNotification
+}
+
+func (n *recordingNotifier) Notify(_ context.Context, table string, id, version int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.seen = append(n.seen, recordedNotification{table, id, version, false})
+}
+
+func (n *recordingNotifier) NotifyDeleted(_ context.Context, table string, id int64) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	n.seen = append(n.seen, recordedNotification{table, id, 0, true})
+}
+
+func TestConformance(t *testing.T) {
+	for name, backend := range backends {
+		t.Run(name, func(t *testing.T) {
+			s := backend.open(t)
+
+			t.Run("tournaments", func(t *testing.T) { testTournaments(t, s) })
+			t.Run("site config", func(t *testing.T) { testSiteConfig(t, s) })
+			t.Run("users", func(t *testing.T) { testUsers(t, s) })
+			t.Run("structures", func(t *testing.T) { testStructures(t, s) })
+			t.Run("footer plugs", func(t *testing.T) { testFooterPlugs(t, s) })
+			t.Run("announcements", func(t *testing.T) { testAnnouncements(t, s) })
+			t.Run("displays", func(t *testing.T) { testDisplays(t, s) })
+			t.Run("slides", func(t *testing.T) { testSlides(t, s) })
+			t.Run("cert cache", func(t *testing.T) { testCertCache(t, s) })
+			t.Run("api tokens", func(t *testing.T) { testAPITokens(t, s) })
+			t.Run("webhooks", func(t *testing.T) { testWebhooks(t, s) })
+			t.Run("notifications", func(t *testing.T) { testNotifications(t, s, backend.notifies) })
+		})
+	}
+}
+
+func newTestTournament(name string) *model.Tournament {
+	return &model.Tournament{
+		EventName: name,
+		Structure: model.StructureData{
+			Levels: []*model.Level{
+				{Description: "25/50", DurationMinutes: 20},
+				{Description: "50/100", DurationMinutes: 20},
+			},
+			ChipsPerBuyIn: 5000,
+		},
+		State: &model.State{CurrentPlayers: 9, BuyIns: 9},
+	}
+}
+
+func testTournaments(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id, err := s.CreateTournament(ctx, newTestTournament("Thursday Turbo"))
+	if err != nil {
+		t.Fatalf("CreateTournament: %v", err)
+	}
+	tm, err := s.FetchTournament(ctx, id)
+	if err != nil {
+		t.Fatalf("FetchTournament: %v", err)
+	}
+	if tm.EventID != id || tm.Version != 0 || tm.EventName != "Thursday Turbo" || tm.State.BuyIns != 9 {
+		t.Errorf("fetched %d version %d %q with %d buy-ins", tm.EventID, tm.Version, tm.EventName, tm.State.BuyIns)
+	}
+	if tm.State.TimeRemainingMillis == nil || *tm.State.TimeRemainingMillis != (20*time.Minute).Milliseconds() {
+		t.Errorf("clock wasn't set to the full level: %v", tm.State.TimeRemainingMillis)
+	}
+
+	stale := tm.Clone()
+	tm.State.BuyIns = 10
+	if err := s.SaveTournament(ctx, tm); err != nil {
+		t.Fatalf("SaveTournament: %v", err)
+	}
+	if tm.Version != 1 {
+		t.Errorf("version after save = %d, want 1", tm.Version)
+	}
+	if err := s.SaveTournament(ctx, stale); err == nil || !strings.Contains(err.Error(), "optimistic lock") {
+		t.Errorf("saving a stale tournament: got %v", err)
+	}
+
+	finishedID, err := s.CreateTournament(ctx, newTestTournament("Last Week"))
+	if err != nil {
+		t.Fatalf("CreateTournament: %v", err)
+	}
+	finished, err := s.FetchTournament(ctx, finishedID)
+	if err != nil {
+		t.Fatalf("FetchTournament: %v", err)
+	}
+	finished.State.Lifecycle = model.LifecycleFinished
+	if err := s.SaveTournament(ctx, finished); err != nil {
+		t.Fatalf("SaveTournament: %v", err)
+	}
+	overview, err := s.FetchOverview(ctx, []model.Lifecycle{model.LifecycleFinished}, 0, 100)
+	if err != nil {
+		t.Fatalf("FetchOverview: %v", err)
+	}
+	if !slices.ContainsFunc(overview.Slugs, func(sl model.TournamentSlug) bool { return sl.TournamentID == finishedID }) ||
+		slices.ContainsFunc(overview.Slugs, func(sl model.TournamentSlug) bool { return sl.TournamentID == id }) {
+		t.Errorf("finished overview = %+v", overview.Slugs)
+	}
+
+	finished.State.Lifecycle = model.LifecycleArchived
+	if err := s.SaveTournament(ctx, finished); err != nil {
+		t.Fatalf("archiving: %v", err)
+	}
+	finished.EventName = "Changed"
+	if err := s.SaveTournament(ctx, finished); err == nil || !strings.Contains(err.Error(), "archived") {
+		t.Errorf("changing an archived tournament: got %v", err)
+	}
+	finished.State.Lifecycle = model.LifecycleFinished
+	if err := s.SaveTournament(ctx, finished); err != nil {
+		t.Errorf("unarchiving: %v", err)
+	}
+
+	if err := s.DeleteTournament(ctx, id); err != nil {
+		t.Fatalf("DeleteTournament: %v", err)
+	}
+	if _, err := s.FetchTournament(ctx, id); err == nil {
+		t.Error("fetched a deleted tournament")
+	}
+}
+
+func testSiteConfig(t *testing.T, s Storage) {
+	ctx := context.Background()
+	config, err := s.FetchSiteConfig(ctx)
+	if err != nil {
+		t.Fatalf("FetchSiteConfig: %v", err)
+	}
+	if config.Name == "" {
+		t.Error("default site config has no name")
+	}
+	config.Name = "The Arcade"
+	if err := s.SaveSiteConfig(ctx, config); err != nil {
+		t.Fatalf("SaveSiteConfig: %v", err)
+	}
+	if got, err := s.FetchSiteConfig(ctx); err != nil || got.Name != "The Arcade" {
+		t.Errorf("after saving, got %+v, %v", got, err)
+	}
+}
+
+func testUsers(t *testing.T, s Storage) {
+	ctx := context.Background()
+	if err := s.CreateUserWithEmailAndPassword(ctx, "boss", "boss@example.com", "hash1", true); err != nil {
+		t.Fatalf("CreateUserWithEmailAndPassword: %v", err)
+	}
+	row, err := s.FetchUserRow(ctx, "boss")
+	if err != nil {
+		t.Fatalf("FetchUserRow: %v", err)
+	}
+	if !row.IsAdmin || len(row.Passwords) != 1 || row.Passwords[0].PasswordHash != "hash1" || row.Passwords[0].ExpiresAt != nil {
+		t.Errorf("user row = %+v", row)
+	}
+
+	now := time.Now()
+	if err := s.ReplacePassword(ctx, row.ID, "hash2", now.Add(time.Hour)); err != nil {
+		t.Fatalf("ReplacePassword: %v", err)
+	}
+	if row, err = s.FetchUserRow(ctx, "boss"); err != nil || len(row.Passwords) != 2 {
+		t.Fatalf("after replacing, got %+v, %v", row, err)
+	}
+	if err := s.RemoveExpiredPasswords(ctx, now.Add(30*time.Minute)); err != nil {
+		t.Fatalf("RemoveExpiredPasswords: %v", err)
+	}
+	if row, err = s.FetchUserRow(ctx, "boss"); err != nil || len(row.Passwords) != 2 {
+		t.Fatalf("a password that hadn't expired yet was removed: %+v, %v", row, err)
+	}
+	if err := s.RemoveExpiredPasswords(ctx, now.Add(2*time.Hour)); err != nil {
+		t.Fatalf("RemoveExpiredPasswords: %v", err)
+	}
+	if row, err = s.FetchUserRow(ctx, "boss"); err != nil || len(row.Passwords) != 1 || row.Passwords[0].PasswordHash != "hash2" {
+		t.Fatalf("after expiring, got %+v, %v", row, err)
+	}
+
+	id, err := s.CreateUser(ctx, &model.UserIdentity{Nick: "dealer"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	u, err := s.FetchUserByUserID(ctx, id)
+	if err != nil {
+		t.Fatalf("FetchUserByUserID: %v", err)
+	}
+	u.IsOperator = true
+	if err := s.SaveUser(ctx, u); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+	users, err := s.FetchUsers(ctx)
+	if err != nil {
+		t.Fatalf("FetchUsers: %v", err)
+	}
+	if !slices.ContainsFunc(users, func(u *model.UserIdentity) bool { return u.ID == id && u.IsOperator && !u.IsAdmin }) {
+		t.Errorf("users = %+v", users)
+	}
+
+	if err := s.DeleteUserByNick(ctx, "boss"); err != nil {
+		t.Fatalf("DeleteUserByNick: %v", err)
+	}
+	if err := s.DeleteUserByID(ctx, id); err != nil {
+		t.Fatalf("DeleteUserByID: %v", err)
+	}
+	if _, err := s.FetchUserByUserID(ctx, id); err == nil {
+		t.Error("fetched a deleted user")
+	}
+}
+
+func testStructures(t *testing.T, s Storage) {
+	ctx := context.Background()
+	st := &model.Structure{Name: "Deep Stack", StructureData: newTestTournament("").Structure}
+	id, err := s.CreateStructure(ctx, st)
+	if err != nil {
+		t.Fatalf("CreateStructure: %v", err)
+	}
+	got, err := s.FetchStructure(ctx, id)
+	if err != nil {
+		t.Fatalf("FetchStructure: %v", err)
+	}
+	if got.Name != "Deep Stack" || len(got.Levels) != 2 || got.Version != 0 {
+		t.Errorf("fetched %+v", got)
+	}
+	stale := *got
+	got.ChipsPerBuyIn = 20000
+	if err := s.SaveStructure(ctx, got); err != nil {
+		t.Fatalf("SaveStructure: %v", err)
+	}
+	if err := s.SaveStructure(ctx, &stale); err == nil {
+		t.Error("saved a stale structure")
+	}
+	slugs, err := s.FetchStructureSlugs(ctx, 0, 100)
+	if err != nil {
+		t.Fatalf("FetchStructureSlugs: %v", err)
+	}
+	if !slices.ContainsFunc(slugs, func(sl *model.StructureSlug) bool { return sl.ID == id && sl.ChipsPerBuyIn == 20000 }) {
+		t.Errorf("slugs = %+v", slugs)
+	}
+	if err := s.DeleteStructure(ctx, id); err != nil {
+		t.Fatalf("DeleteStructure: %v", err)
+	}
+	if _, err := s.FetchStructure(ctx, id); err == nil {
+		t.Error("fetched a deleted structure")
+	}
+}
+
+func testFooterPlugs(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id, err := s.CreateFooterPlugSet(ctx, "Sponsors", []model.FooterPlug{
+		{Kind: model.FooterPlugKindText, Text: "Tip your dealer"},
+		{Kind: model.FooterPlugKindMarkdown, Text: "**No** splashing the pot"},
+	})
+	if err != nil {
+		t.Fatalf("CreateFooterPlugSet: %v", err)
+	}
+	got, err := s.FetchPlugs(ctx, id)
+	if err != nil {
+		t.Fatalf("FetchPlugs: %v", err)
+	}
+	if got.Name != "Sponsors" || len(got.Plugs) != 2 || got.Plugs[1].Kind != model.FooterPlugKindMarkdown {
+		t.Errorf("fetched %+v", got)
+	}
+	if err := s.UpdateFooterPlugSet(ctx, id, "Rules", got.Plugs[1:]); err != nil {
+		t.Fatalf("UpdateFooterPlugSet: %v", err)
+	}
+	if got, err = s.FetchPlugs(ctx, id); err != nil || got.Name != "Rules" || len(got.Plugs) != 1 {
+		t.Errorf("after updating, got %+v, %v", got, err)
+	}
+	if err := s.DeleteFooterPlugSet(ctx, id); err != nil {
+		t.Fatalf("DeleteFooterPlugSet: %v", err)
+	}
+	if _, err := s.FetchPlugs(ctx, id); err == nil {
+		t.Error("fetched a deleted footer plug set")
+	}
+}
+
+func testAnnouncements(t *testing.T, s Storage) {
+	ctx := context.Background()
+	now := time.Now()
+	current := &model.Announcement{Markdown: "Table 7 is breaking", Style: model.AnnouncementStyleBanner, Created: now, Expires: now.Add(time.Hour)}
+	currentID, err := s.CreateAnnouncement(ctx, current)
+	if err != nil {
+		t.Fatalf("CreateAnnouncement: %v", err)
+	}
+	expiredID, err := s.CreateAnnouncement(ctx, &model.Announcement{Markdown: "Dinner", Created: now.Add(-time.Hour), Expires: now.Add(-time.Minute)})
+	if err != nil {
+		t.Fatalf("CreateAnnouncement: %v", err)
+	}
+	got, err := s.FetchAnnouncements(ctx, now)
+	if err != nil {
+		t.Fatalf("FetchAnnouncements: %v", err)
+	}
+	ids := []int64{}
+	for _, a := range got {
+		ids = append(ids, a.AnnouncementID)
+	}
+	if !slices.Contains(ids, currentID) || slices.Contains(ids, expiredID) {
+		t.Errorf("current announcements are %v; want %d and not %d", ids, currentID, expiredID)
+	}
+
+	a, err := s.FetchAnnouncement(ctx, currentID)
+	if err != nil {
+		t.Fatalf("FetchAnnouncement: %v", err)
+	}
+	a.Expires = now.Add(-time.Second)
+	if err := s.SaveAnnouncement(ctx, a); err != nil {
+		t.Fatalf("SaveAnnouncement: %v", err)
+	}
+	if got, err := s.FetchAnnouncements(ctx, now); err != nil || slices.ContainsFunc(got, func(a *model.Announcement) bool { return a.AnnouncementID == currentID }) {
+		t.Errorf("an announcement that was expired early is still current: %v", err)
+	}
+	for _, id := range []int64{currentID, expiredID} {
+		if err := s.DeleteAnnouncement(ctx, id); err != nil {
+			t.Errorf("DeleteAnnouncement: %v", err)
+		}
+	}
+}
+
+func testDisplays(t *testing.T, s Storage) {
+	ctx := context.Background()
+	d, err := s.RegisterDisplay(ctx, "pi-1", "10.0.0.5", "Chromium")
+	if err != nil {
+		t.Fatalf("RegisterDisplay: %v", err)
+	}
+	if d.DeviceID != "pi-1" || d.RemoteAddr != "10.0.0.5" || time.Since(d.LastHeartbeat) > time.Minute {
+		t.Errorf("registered %+v", d)
+	}
+	again, err := s.RegisterDisplay(ctx, "pi-1", "10.0.0.6", "Chromium")
+	if err != nil {
+		t.Fatalf("RegisterDisplay again: %v", err)
+	}
+	if again.DisplayID != d.DisplayID || again.RemoteAddr != "10.0.0.6" || again.LastHeartbeat.Before(d.LastHeartbeat) {
+		t.Errorf("registered again as %+v", again)
+	}
+	if got, err := s.FetchDisplayByDeviceID(ctx, "pi-1"); err != nil || got.DisplayID != d.DisplayID {
+		t.Errorf("FetchDisplayByDeviceID = %+v, %v", got, err)
+	}
+	if _, err := s.FetchDisplayByDeviceID(ctx, "pi-2"); err == nil {
+		t.Error("fetched a display that never registered")
+	}
+	for _, id := range []string{"", "pi 2", strings.Repeat("x", 65)} {
+		if _, err := s.RegisterDisplay(ctx, id, "10.0.0.7", "Chromium"); err == nil {
+			t.Errorf("registered device ID %q", id)
+		}
+	}
+
+	stale := *again
+	again.Name = "Bar TV"
+	again.Mode = model.DisplayModeLobby
+	if err := s.SaveDisplay(ctx, again); err != nil {
+		t.Fatalf("SaveDisplay: %v", err)
+	}
+	if err := s.SaveDisplay(ctx, &stale); err == nil {
+		t.Error("saved a stale display")
+	}
+	got, err := s.FetchDisplay(ctx, d.DisplayID)
+	if err != nil || got.Name != "Bar TV" || got.Mode != model.DisplayModeLobby {
+		t.Errorf("fetched %+v, %v", got, err)
+	}
+	if err := s.DeleteDisplay(ctx, d.DisplayID); err != nil {
+		t.Fatalf("DeleteDisplay: %v", err)
+	}
+}
+
+func testSlides(t *testing.T, s Storage) {
+	ctx := context.Background()
+	id, err := s.CreateSlide(ctx, &model.Slide{Name: "Logo", Kind: model.SlideKindImage})
+	if err != nil {
+		t.Fatalf("CreateSlide: %v", err)
+	}
+	image := []byte("\x89PNG\r\n\x1a\n\x00\x00")
+	if err := s.SaveSlideImage(ctx, id, "image/png", image); err != nil {
+		t.Fatalf("SaveSlideImage: %v", err)
+	}
+	contentType, data, err := s.FetchSlideImage(ctx, id)
+	if err != nil || contentType != "image/png" || !bytes.Equal(data, image) {
+		t.Errorf("fetched %q %q, %v", contentType, data, err)
+	}
+	if err := s.DeleteSlide(ctx, id); err != nil {
+		t.Fatalf("DeleteSlide: %v", err)
+	}
+}
+
+func testCertCache(t *testing.T, s Storage) {
+	ctx := context.Background()
+	if _, err := s.FetchCertCache(ctx, "example.com"); err != ErrNotCached {
+		t.Errorf("fetched missing key: %v", err)
+	}
+	for _, data := range []string{"first", "second"} {
+		if err := s.SaveCertCache(ctx, "example.com", []byte(data)); err != nil {
+			t.Fatalf("SaveCertCache: %v", err)
+		}
+		if got, err := s.FetchCertCache(ctx, "example.com"); err != nil || string(got) != data {
+			t.Errorf("fetched %q, %v, want %q", got, err, data)
+		}
+	}
+	if err := s.DeleteCertCache(ctx, "example.com"); err != nil {
+		t.Fatalf("DeleteCertCache: %v", err)
+	}
+	if _, err := s.FetchCertCache(ctx, "example.com"); err != ErrNotCached {
+		t.Errorf("fetched deleted key: %v", err)
+	}
+}
+
+func testAPITokens(t *testing.T, s Storage) {
+	ctx := context.Background()
+	uid, err := s.CreateUser(ctx, &model.UserIdentity{Nick: "robot", IsOperator: true})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	id, err := s.CreateAPIToken(ctx, &model.APIToken{UserID: uid, Name: "scoreboard", Scopes: []string{"read", "control"}}, "hash1")
+	if err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if _, err := s.CreateAPIToken(ctx, &model.APIToken{UserID: uid, Name: "again", Scopes: []string{"read"}}, "hash1"); err == nil {
+		t.Error("made two tokens with the same hash")
+	}
+
+	got, err := s.FetchAPITokenByHash(ctx, "hash1")
+	if err != nil {
+		t.Fatalf("FetchAPITokenByHash: %v", err)
+	}
+	if got.ID != id || got.UserID != uid || got.Name != "scoreboard" || !slices.Equal(got.Scopes, []string{"read", "control"}) ||
+		got.Created.IsZero() || got.LastUsed != nil {
+		t.Errorf("token = %+v", got)
+	}
+	if _, err := s.FetchAPITokenByHash(ctx, "nope"); err == nil {
+		t.Error("fetched a token that doesn't exist")
+	}
+
+	used := time.Now().Truncate(time.Second)
+	if err := s.TouchAPIToken(ctx, id, used); err != nil {
+		t.Fatalf("TouchAPIToken: %v", err)
+	}
+	// Too soon to bother writing again.
+	if err := s.TouchAPIToken(ctx, id, used.Add(time.Second)); err != nil {
+		t.Fatalf("TouchAPIToken: %v", err)
+	}
+	tokens, err := s.FetchAPITokensByUserID(ctx, uid)
+	if err != nil {
+		t.Fatalf("FetchAPITokensByUserID: %v", err)
+	}
+	if len(tokens) != 1 || tokens[0].LastUsed == nil || !tokens[0].LastUsed.Equal(used) {
+		t.Errorf("tokens = %+v", tokens)
+	}
+
+	if err := s.DeleteAPIToken(ctx, uid+1, id); err == nil {
+		t.Error("deleted somebody else's token")
+	}
+	if err := s.DeleteAPIToken(ctx, uid, id); err != nil {
+		t.Fatalf("DeleteAPIToken: %v", err)
+	}
+	if _, err := s.FetchAPITokenByHash(ctx, "hash1"); err == nil {
+		t.Error("fetched a deleted token")
+	}
+
+	// Tokens go with their user.
+	if _, err := s.CreateAPIToken(ctx, &model.APIToken{UserID: uid, Name: "doomed", Scopes: []string{"read"}}, "hash2"); err != nil {
+		t.Fatalf("CreateAPIToken: %v", err)
+	}
+	if err := s.DeleteUserByID(ctx, uid); err != nil {
+		t.Fatalf("DeleteUserByID: %v", err)
+	}
+	if _, err := s.FetchAPITokenByHash(ctx, "hash2"); err == nil {
+		t.Error("token outlived its user")
+	}
+}
+
+func testWebhooks(t *testing.T, s Storage) {
+	ctx := context.Background()
+	everywhere, err := s.CreateWebhook(ctx, &model.Webhook{URL: "http://example.com/all", Secret: "s1", Events: []string{"level.started", "clock.paused"}})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	one, err := s.CreateWebhook(ctx, &model.Webhook{TournamentID: 7, URL: "http://example.com/7", Secret: "s2", Events: []string{"tournament.finished"}})
+	if err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	hooks, err := s.FetchWebhooks(ctx)
+	if err != nil {
+		t.Fatalf("FetchWebhooks: %v", err)
+	}
+	if len(hooks) != 2 || hooks[0].ID != everywhere || hooks[0].TournamentID != 0 || hooks[0].Secret != "s1" ||
+		!slices.Equal(hooks[0].Events, []string{"level.started", "clock.paused"}) ||
+		hooks[1].ID != one || hooks[1].TournamentID != 7 || hooks[1].Created.IsZero() {
+		t.Errorf("webhooks = %+v %+v", hooks[0], hooks[1])
+	}
+
+	now := time.Now().Truncate(time.Second)
+	d := &model.WebhookDelivery{WebhookID: everywhere, TournamentID: 7, Event: "level.started", Key: "7/2/3/level.started",
+		Payload: `{"Event":"level.started"}`, NextAttempt: now, Created: now}
+	if added, err := s.EnqueueWebhookDelivery(ctx, d); err != nil || !added {
+		t.Fatalf("EnqueueWebhookDelivery = %v, %v", added, err)
+	}
+	// Another server noticing the same change.
+	if added, err := s.EnqueueWebhookDelivery(ctx, d); err != nil || added {
+		t.Errorf("enqueueing a duplicate = %v, %v", added, err)
+	}
+	later := &model.WebhookDelivery{WebhookID: everywhere, TournamentID: 7, Event: "clock.paused", Key: "7/3/3/clock.paused",
+		Payload: "{}", NextAttempt: now.Add(time.Hour), Created: now}
+	if added, err := s.EnqueueWebhookDelivery(ctx, later); err != nil || !added {
+		t.Fatalf("EnqueueWebhookDelivery = %v, %v", added, err)
+	}
+
+	lease := now.Add(time.Minute)
+	claimed, err := s.ClaimWebhookDeliveries(ctx, now, lease, 10)
+	if err != nil {
+		t.Fatalf("ClaimWebhookDeliveries: %v", err)
+	}
+	if len(claimed) != 1 || claimed[0].Key != d.Key || claimed[0].Payload != d.Payload || claimed[0].Status != model.WebhookPending ||
+		!claimed[0].NextAttempt.Equal(lease) || claimed[0].Delivered != nil {
+		t.Fatalf("claimed = %+v", claimed)
+	}
+	if again, err := s.ClaimWebhookDeliveries(ctx, now, lease, 10); err != nil || len(again) != 0 {
+		t.Errorf("claimed a claimed delivery: %+v, %v", again, err)
+	}
+
+	got := claimed[0]
+	got.Attempts = 1
+	got.Status = model.WebhookFailed
+	got.LastStatus = 500
+	got.LastError = "oops"
+	if err := s.SaveWebhookDelivery(ctx, got); err != nil {
+		t.Fatalf("SaveWebhookDelivery: %v", err)
+	}
+	if err := s.RetryWebhookDelivery(ctx, got.ID, now); err != nil {
+		t.Fatalf("RetryWebhookDelivery: %v", err)
+	}
+	if err := s.RetryWebhookDelivery(ctx, got.ID, now); err == nil {
+		t.Error("retried a delivery that hadn't failed")
+	}
+	claimed, err = s.ClaimWebhookDeliveries(ctx, now.Add(time.Second), lease, 10)
+	if err != nil || len(claimed) != 1 || claimed[0].Attempts != 1 || claimed[0].LastError != "oops" {
+		t.Fatalf("claimed after retry = %+v, %v", claimed, err)
+	}
+	got = claimed[0]
+	got.Status = model.WebhookDelivered
+	got.Attempts = 2
+	got.LastStatus = 204
+	got.LastError = ""
+	got.Delivered = &now
+	if err := s.SaveWebhookDelivery(ctx, got); err != nil {
+		t.Fatalf("SaveWebhookDelivery: %v", err)
+	}
+
+	log, err := s.FetchWebhookDeliveries(ctx, 10)
+	if err != nil {
+		t.Fatalf("FetchWebhookDeliveries: %v", err)
+	}
+	if len(log) != 2 || log[0].Key != later.Key || log[1].Status != model.WebhookDelivered || log[1].LastStatus != 204 ||
+		log[1].Delivered == nil || !log[1].Delivered.Equal(now) {
+		t.Errorf("log = %+v", log)
+	}
+
+	// Pending deliveries are kept, however old.
+	if n, err := s.PruneWebhookDeliveries(ctx, now.Add(time.Second)); err != nil || n != 1 {
+		t.Errorf("PruneWebhookDeliveries = %d, %v", n, err)
+	}
+
+	if err := s.DeleteWebhook(ctx, everywhere); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+	if err := s.DeleteWebhook(ctx, everywhere); err == nil {
+		t.Error("deleted a webhook twice")
+	}
+	if log, err := s.FetchWebhookDeliveries(ctx, 10); err != nil || len(log) != 0 {
+		t.Errorf("deliveries outlived their webhook: %+v, %v", log, err)
+	}
+	if err := s.DeleteWebhook(ctx, one); err != nil {
+		t.Fatalf("DeleteWebhook: %v", err)
+	}
+}
+
+// testNotifications checks that SQLite, which has no triggers, and memory
+// tell the notifier about changes, and that Postgres, which has, doesn't.
+func testNotifications(t *testing.T, s Storage, notifies bool) {
+	ctx := context.Background()
+	n := &recordingNotifier{}
+	s.SetNotifier(n)
+	defer s.SetNotifier(nil)
+
+	id, err := s.CreateTournament(ctx, newTestTournament("Noisy"))
+	if err != nil {
+		t.Fatalf("CreateTournament: %v", err)
+	}
+	tm, err := s.FetchTournament(ctx, id)
+	if err != nil {
+		t.Fatalf("FetchTournament: %v", err)
+	}
+	if err := s.SaveTournament(ctx, tm); err != nil {
+		t.Fatalf("SaveTournament: %v", err)
+	}
+	if err := s.DeleteTournament(ctx, id); err != nil {
+		t.Fatalf("DeleteTournament: %v", err)
+	}
+
+	plugsID, err := s.CreateFooterPlugSet(ctx, "Noisy", nil)
+	if err != nil {
+		t.Fatalf("CreateFooterPlugSet: %v", err)
+	}
+	if err := s.UpdateFooterPlugSet(ctx, plugsID, "Noisier", nil); err != nil {
+		t.Fatalf("UpdateFooterPlugSet: %v", err)
+	}
+	if err := s.DeleteFooterPlugSet(ctx, plugsID); err != nil {
+		t.Fatalf("DeleteFooterPlugSet: %v", err)
+	}
+
+	if err := s.CreateUserWithEmailAndPassword(ctx, "noisy", "noisy@example.com", "x", false); err != nil {
+		t.Fatalf("CreateUserWithEmailAndPassword: %v", err)
+	}
+	row, err := s.FetchUserRow(ctx, "noisy")
+	if err != nil {
+		t.Fatalf("FetchUserRow: %v", err)
+	}
+	if err := s.DeleteUserByNick(ctx, "noisy"); err != nil {
+		t.Fatalf("DeleteUserByNick: %v", err)
+	}
+
+	var want []recordedNotification
+	if notifies {
+		want = []recordedNotification{
+			{"tournaments", id, 0, false},
+			{"tournaments", id, 1, false},
+			{"tournaments", id, 0, true},
+			{"footer_plug_sets", plugsID, 0, false},
+			{"footer_plug_sets", plugsID, 1, false},
+			{"footer_plug_sets", plugsID, 0, true},
+			{"users", row.ID, 0, false},
+			{"users", row.ID, 0, true},
+		}
+	}
+	n.mu.Lock()
+	defer n.mu.Unlock()
+	if !slices.Equal(n.seen, want) {
+		t.Errorf("notified %+v, want %+v", n.seen, want)
+	}
+}
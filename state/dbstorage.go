@@ -8,12 +8,15 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
 	"time"
 
 	"github.com/ts4z/irata/dbutil"
 	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/logz"
 	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/tournament"
 	"github.com/ts4z/irata/varz"
 )
 
@@ -21,6 +24,8 @@ const (
 	ConfKey = 1
 )
 
+var logger = logz.New()
+
 var (
 	fetchFooterPlugs = varz.NewInt("fetchFooterPlugs")
 	fetchUserByID    = varz.NewInt("fetchUserByID")
@@ -34,7 +39,11 @@ var (
 // interfaces are easily seperable.  (The database handle can
 // be shared.)
 type DBStorage struct {
-	db *sql.DB
+	db      *sql.DB
+	dialect dbutil.Dialect
+	// notifier is told about changes on databases that can't notify us
+	// themselves.
+	notifier Notifier
 	// Map from tournament id to slice of notification functions
 	tournamentListeners   map[int64][]chan<- *model.Tournament
 	tournamentListenersMu sync.Mutex
@@ -52,18 +61,22 @@ func (s *DBStorage) FetchPlugs(ctx context.Context, id int64) (*model.FooterPlug
 		}
 		return nil, err
 	}
-	plugs := []string{}
-	rows, err := s.db.QueryContext(ctx, `SELECT text FROM text_footer_plugs WHERE footer_plug_set_id = $1 ORDER BY id`, id)
+	plugs := []model.FooterPlug{}
+	rows, err := s.db.QueryContext(ctx, `SELECT model_data FROM footer_plugs WHERE footer_plug_set_id = $1 ORDER BY id`, id)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 	for rows.Next() {
-		var text string
-		if err := rows.Scan(&text); err != nil {
+		var bytes []byte
+		if err := rows.Scan(&bytes); err != nil {
 			return nil, err
 		}
-		plugs = append(plugs, text)
+		var plug model.FooterPlug
+		if err := json.Unmarshal(bytes, &plug); err != nil {
+			return nil, fmt.Errorf("unmarshal plug in footer plug set %d: %w", id, err)
+		}
+		plugs = append(plugs, plug)
 	}
 	if rows.Err() != nil {
 		return nil, rows.Err()
@@ -72,7 +85,7 @@ func (s *DBStorage) FetchPlugs(ctx context.Context, id int64) (*model.FooterPlug
 		FooterPlugsID: setID,
 		Version:       version,
 		Name:          name,
-		TextPlugs:     plugs,
+		Plugs:         plugs,
 	}, nil
 }
 
@@ -94,7 +107,7 @@ func (s *DBStorage) ListFooterPlugSets(ctx context.Context) ([]*model.FooterPlug
 			FooterPlugsID: setID,
 			Version:       version,
 			Name:          name,
-			TextPlugs:     nil, // not loaded here
+			Plugs:         nil, // not loaded here
 		})
 	}
 	if rows.Err() != nil {
@@ -104,7 +117,7 @@ func (s *DBStorage) ListFooterPlugSets(ctx context.Context) ([]*model.FooterPlug
 }
 
 // CreateFooterPlugSet creates a new footer plug set with a name and initial plugs.
-func (s *DBStorage) CreateFooterPlugSet(ctx context.Context, name string, plugs []string) (int64, error) {
+func (s *DBStorage) CreateFooterPlugSet(ctx context.Context, name string, plugs []model.FooterPlug) (int64, error) {
 	tx, err := dbutil.NewTx(ctx, s.db, nil)
 	if err != nil {
 		return 0, err
@@ -115,49 +128,62 @@ func (s *DBStorage) CreateFooterPlugSet(ctx context.Context, name string, plugs
 	if err != nil {
 		return 0, err
 	}
-	for _, plug := range plugs {
-		_, err := tx.Exec(ctx, `INSERT INTO text_footer_plugs (footer_plug_set_id, text) VALUES ($1, $2)`, setID, plug)
-		if err != nil {
-			return 0, err
-		}
+	if err := insertFooterPlugs(ctx, tx, setID, plugs); err != nil {
+		return 0, err
 	}
 	if err := tx.Commit(); err != nil {
 		return 0, err
 	}
+	s.notify(ctx, "footer_plug_sets", setID, 0)
 	return setID, nil
 }
 
+func insertFooterPlugs(ctx context.Context, tx *dbutil.Tx, setID int64, plugs []model.FooterPlug) error {
+	for _, plug := range plugs {
+		bytes, err := json.Marshal(&plug)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, `INSERT INTO footer_plugs (footer_plug_set_id, model_data) VALUES ($1, $2)`, setID, bytes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // UpdateFooterPlugSet updates the name and plugs of a footer plug set.
-func (s *DBStorage) UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []string) error {
+func (s *DBStorage) UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []model.FooterPlug) error {
 	tx, err := dbutil.NewTx(ctx, s.db, nil)
 	if err != nil {
 		return err
 	}
 	defer tx.MaybeRollback()
-	_, err = tx.Exec(ctx, `UPDATE footer_plug_sets SET name = $1, version = version + 1 WHERE id = $2`, name, id)
+	var version int64
+	err = tx.QueryRow(ctx, `UPDATE footer_plug_sets SET name = $1, version = version + 1 WHERE id = $2 RETURNING version`, name, id).Scan(&version)
 	if err != nil {
 		return err
 	}
-	_, err = tx.Exec(ctx, `DELETE FROM text_footer_plugs WHERE footer_plug_set_id = $1`, id)
+	_, err = tx.Exec(ctx, `DELETE FROM footer_plugs WHERE footer_plug_set_id = $1`, id)
 	if err != nil {
 		return err
 	}
-	for _, plug := range plugs {
-		_, err := tx.Exec(ctx, `INSERT INTO text_footer_plugs (footer_plug_set_id, text) VALUES ($1, $2)`, id, plug)
-		if err != nil {
-			return err
-		}
+	if err := insertFooterPlugs(ctx, tx, id, plugs); err != nil {
+		return err
 	}
 	if err := tx.Commit(); err != nil {
 		return err
 	}
+	s.notify(ctx, "footer_plug_sets", id, version)
 	return nil
 }
 
 // DeleteFooterPlugSet deletes a footer plug set and all its plugs.
 func (s *DBStorage) DeleteFooterPlugSet(ctx context.Context, id int64) error {
-	_, err := s.db.ExecContext(ctx, `DELETE FROM footer_plug_sets WHERE id = $1`, id)
-	return err
+	if _, err := s.db.ExecContext(ctx, `DELETE FROM footer_plug_sets WHERE id = $1`, id); err != nil {
+		return err
+	}
+	s.notifyDeleted(ctx, "footer_plug_sets", id)
+	return nil
 }
 
 var _ AppStorage = &DBStorage{}
@@ -166,13 +192,51 @@ var _ SiteStorageReader = &DBStorage{}
 var _ UserStorage = &DBStorage{}
 var _ TournamentStorage = &DBStorage{}
 
+// Notifier hears about each change DBStorage commits to a table that has a
+// notify trigger in Postgres.  dbnotify.LocalListener is one.
+//
+// ctx is the context of the write, so the change can be traced back to the
+// request that made it.
+type Notifier interface {
+	Notify(ctx context.Context, table string, id, version int64)
+	NotifyDeleted(ctx context.Context, table string, id int64)
+}
+
 func NewDBStorage(ctx context.Context, db *sql.DB) (*DBStorage, error) {
 	return &DBStorage{
 		db:                  db,
+		dialect:             dbutil.DialectOf(db),
 		tournamentListeners: make(map[int64][]chan<- *model.Tournament),
 	}, nil
 }
 
+// SetNotifier sets the Notifier told about changes.  Postgres triggers
+// already send these, so it's only used with SQLite.
+func (s *DBStorage) SetNotifier(n Notifier) {
+	if s.dialect == dbutil.SQLite {
+		s.notifier = n
+	}
+}
+
+func (s *DBStorage) notify(ctx context.Context, table string, id, version int64) {
+	if s.notifier != nil {
+		s.notifier.Notify(ctx, table, id, version)
+	}
+}
+
+func (s *DBStorage) notifyDeleted(ctx context.Context, table string, id int64) {
+	if s.notifier != nil {
+		s.notifier.NotifyDeleted(ctx, table, id)
+	}
+}
+
+var _ HealthStorage = &DBStorage{}
+var _ Storage = &DBStorage{}
+
+func (s *DBStorage) Ping(ctx context.Context) error {
+	return s.db.PingContext(ctx)
+}
+
 func (s *DBStorage) Close() {
 	s.db.Close()
 }
@@ -298,33 +362,61 @@ func (s *DBStorage) FetchStructureSlugs(ctx context.Context, offset, limit int)
 	return slugs, nil
 }
 
-func (s *DBStorage) FetchOverview(ctx context.Context, offset, limit int) (*model.Overview, error) {
-	rows, err := s.db.Query("SELECT tournament_id, model_data from tournaments LIMIT $1 OFFSET $2;", limit, offset)
+// storedLifecycle is the lifecycle to keep in t's row, so the overview can
+// filter on it.
+func storedLifecycle(t *model.Tournament) string {
+	if t.State == nil {
+		return string(model.LifecycleScheduled)
+	}
+	return string(tournament.LifecycleOf(t))
+}
+
+func (s *DBStorage) FetchOverview(ctx context.Context, lifecycles []model.Lifecycle, offset, limit int) (*model.Overview, error) {
+	where := ""
+	args := []any{}
+	if len(lifecycles) > 0 {
+		placeholders := []string{}
+		for _, l := range lifecycles {
+			args = append(args, string(l))
+			placeholders = append(placeholders, fmt.Sprintf("$%d", len(args)))
+		}
+		where = " WHERE lifecycle IN (" + strings.Join(placeholders, ", ") + ")"
+	}
+
+	overview := &model.Overview{}
+	if err := s.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM tournaments"+where, args...).Scan(&overview.Total); err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.QueryContext(ctx,
+		fmt.Sprintf("SELECT tournament_id, lifecycle, model_data FROM tournaments%s ORDER BY tournament_id LIMIT $%d OFFSET $%d;", where, len(args)+1, len(args)+2),
+		append(args, limit, offset)...)
 	if err != nil {
 		return nil, err
 	}
 	defer rows.Close()
 
-	overview := &model.Overview{}
 	for rows.Next() {
 		var id int64
+		var lifecycle string
 		var bytes []byte
 
-		if err := rows.Scan(&id, &bytes); err != nil {
-			log.Printf("Row scan failed: %v", err)
+		if err := rows.Scan(&id, &lifecycle, &bytes); err != nil {
+			logger.ErrorContext(ctx, "tournament row scan failed", "err", err)
 			continue
 		}
-		// log.Printf("read: id=%d, bytes=%q", id, bytes)
 		tournament := model.Tournament{}
 		err := json.Unmarshal(bytes, &tournament)
 		if err != nil {
-			log.Printf("JSON unmarshal failed: %v", err)
+			logger.ErrorContext(ctx, "tournament JSON unmarshal failed", "tournament", id, "err", err)
 			continue
 		}
 		slug := model.TournamentSlug{
 			TournamentID:   id,
 			TournamentName: tournament.EventName,
 			Description:    tournament.Description,
+			ScheduledStart: tournament.ScheduledStart,
+			Lifecycle:      model.Lifecycle(lifecycle),
 		}
 
 		overview.Slugs = append(overview.Slugs, slug)
@@ -385,10 +477,11 @@ func (s *DBStorage) CreateTournament(
 		return 0, err
 	}
 
-	if err := s.db.QueryRowContext(ctx, `INSERT INTO tournaments (model_data) VALUES ($1) RETURNING tournament_id;`,
-		bytes).Scan(&id); err != nil {
+	if err := s.db.QueryRowContext(ctx, `INSERT INTO tournaments (lifecycle, league_id, model_data) VALUES ($1, $2, $3) RETURNING tournament_id;`,
+		storedLifecycle(&cpy), cpy.LeagueID, bytes).Scan(&id); err != nil {
 		return 0, err
 	}
+	s.notify(ctx, "tournaments", id, 0)
 
 	return id, nil
 }
@@ -407,23 +500,33 @@ func (s *DBStorage) SaveTournament(
 		return err
 	}
 	newVersion := tm.Version + 1
+	lifecycle := storedLifecycle(tm)
+	// An archived tournament can't be changed, except by taking it out of
+	// the archive.
 	if result, err := s.db.ExecContext(ctx,
-		`UPDATE tournaments SET version=$4, model_data=$2 WHERE tournament_id=$3 AND version=$1;`,
+		`UPDATE tournaments SET version=$4, lifecycle=$5, league_id=$6, model_data=$2 WHERE tournament_id=$3 AND version=$1 AND (lifecycle <> 'archived' OR $5 <> 'archived');`,
 		tm.Version,
 		bytes,
 		tm.EventID,
-		newVersion); err != nil {
-		log.Printf("update failed: %v", err)
+		newVersion,
+		lifecycle,
+		tm.LeagueID); err != nil {
+		logger.ErrorContext(ctx, "tournament update failed", "tournament", tm.EventID, "err", err)
 		return err
 	} else {
 		if n, err := result.RowsAffected(); err != nil {
 			return err
 		} else if n != 1 {
+			var stored string
+			if err := s.db.QueryRowContext(ctx, `SELECT lifecycle FROM tournaments WHERE tournament_id=$1`, tm.EventID).Scan(&stored); err == nil && stored == string(model.LifecycleArchived) {
+				return he.New(409, fmt.Errorf("tournament %d is archived", tm.EventID))
+			}
 			return fmt.Errorf("optimistic lock failure, %d rows affected", n)
 		}
 	}
 
 	tm.Version = newVersion
+	s.notify(ctx, "tournaments", tm.EventID, newVersion)
 
 	return nil
 }
@@ -440,6 +543,7 @@ func (s *DBStorage) DeleteTournament(ctx context.Context, id int64) error {
 		} else if n != 1 {
 			return fmt.Errorf("%d rows deleted", n)
 		} else {
+			s.notifyDeleted(ctx, "tournaments", id)
 			return nil
 		}
 	}
@@ -457,7 +561,7 @@ func (s *DBStorage) SaveStructure(
 	if result, err := s.db.ExecContext(ctx,
 		`UPDATE structures SET version=$1+1, name=$4, model_data=$2 WHERE structure_id=$3 AND version=$1;`,
 		st.Version, bytes, st.ID, st.Name); err != nil {
-		log.Printf("update failed: %v", err)
+		logger.ErrorContext(ctx, "structure update failed", "structure", st.ID, "err", err)
 		return err
 	} else {
 		if n, err := result.RowsAffected(); err != nil {
@@ -466,6 +570,7 @@ func (s *DBStorage) SaveStructure(
 			return fmt.Errorf("optimistic lock failure, %d rows affected", n)
 		}
 	}
+	s.notify(ctx, "structures", st.ID, st.Version+1)
 
 	return nil
 }
@@ -482,6 +587,7 @@ func (s *DBStorage) DeleteStructure(ctx context.Context, id int64) error {
 		} else if n != 1 {
 			return fmt.Errorf("%d rows deleted", n)
 		} else {
+			s.notifyDeleted(ctx, "structures", id)
 			return nil
 		}
 	}
@@ -501,9 +607,10 @@ func (s *DBStorage) CreateStructure(
 	if err := s.db.QueryRowContext(ctx,
 		`INSERT INTO structures (name, model_data) VALUES ($1, $2) RETURNING structure_id;`,
 		st.Name, bytes).Scan(&st.ID); err != nil {
-		log.Printf("insert structure failed: %v", err)
+		logger.ErrorContext(ctx, "insert structure failed", "err", err)
 		return 0, err
 	}
+	s.notify(ctx, "structures", st.ID, 0)
 
 	return st.ID, nil
 }
@@ -528,6 +635,7 @@ func (s *DBStorage) SaveSiteConfig(ctx context.Context, config *model.SiteConfig
 	if rows != 1 {
 		return fmt.Errorf("expected 1 row affected, got %d", rows)
 	}
+	s.notify(ctx, "site_config", ConfKey, 0)
 
 	return nil
 }
@@ -540,6 +648,7 @@ func (s *DBStorage) CreateUser(ctx context.Context, u *model.UserIdentity) (int6
 	if err != nil {
 		return -1, he.HTTPCodedErrorf(500, "dadbase insert failed: %w", err)
 	}
+	s.notify(ctx, "users", userID, 0)
 	return userID, nil
 }
 
@@ -576,13 +685,14 @@ func (s *DBStorage) CreateUserWithEmailAndPassword(ctx context.Context, nick str
 	if err := tx.Commit(); err != nil {
 		return fmt.Errorf("commit tx: %w", err)
 	}
+	s.notify(ctx, "users", userID, 0)
 
 	return nil
 }
 
 // TODO: This is broken in the case of multiple passwords.
 func (s *DBStorage) FetchUserRow(ctx context.Context, nick string) (*model.UserRow, error) {
-	log.Printf("FetchUserRow nick=%q", nick)
+	logger.DebugContext(ctx, "FetchUserRow", "nick", nick)
 	var row model.UserRow
 	rows, err := s.db.QueryContext(ctx,
 		`SELECT user_id, hashed_password, expires, is_admin, nick FROM users
@@ -590,7 +700,7 @@ func (s *DBStorage) FetchUserRow(ctx context.Context, nick string) (*model.UserR
 		WHERE nick=$1;`,
 		nick)
 	if err != nil {
-		log.Printf("error querying user row for %a: %v", err, nick)
+		logger.ErrorContext(ctx, "error querying user row", "nick", nick, "err", err)
 		return nil, err
 	}
 	defer rows.Close()
@@ -601,7 +711,7 @@ func (s *DBStorage) FetchUserRow(ctx context.Context, nick string) (*model.UserR
 		var nick string
 		var isAdmin bool
 		if err := rows.Scan(&row.ID, &hashed, &expires, &isAdmin, &nick); err != nil {
-			log.Printf("error scanning user row for %q: %v", nick, err)
+			logger.ErrorContext(ctx, "error scanning user row", "nick", nick, "err", err)
 			return nil, err
 		}
 		row.Passwords = append(row.Passwords, model.Password{
@@ -669,6 +779,7 @@ func (s *DBStorage) SaveUser(ctx context.Context, u *model.UserIdentity) error {
 	if n != 1 {
 		return he.HTTPCodedErrorf(http.StatusNotFound, "no rows affected")
 	}
+	s.notify(ctx, "users", u.ID, 0)
 
 	return nil
 }
@@ -685,26 +796,23 @@ func (s *DBStorage) DeleteUserByID(ctx context.Context, id int64) error {
 		} else if n != 1 {
 			return fmt.Errorf("%d rows deleted", n)
 		} else {
+			s.notifyDeleted(ctx, "users", id)
 			return nil
 		}
 	}
 }
 
 func (s *DBStorage) DeleteUserByNick(ctx context.Context, nick string) error {
-	result, err := s.db.ExecContext(ctx,
-		"DELETE from users WHERE nick=$1", nick)
-
-	if err != nil {
+	var id int64
+	err := s.db.QueryRowContext(ctx,
+		"DELETE from users WHERE nick=$1 RETURNING user_id", nick).Scan(&id)
+	if err == sql.ErrNoRows {
+		return fmt.Errorf("0 rows deleted")
+	} else if err != nil {
 		return err
-	} else {
-		if n, err := result.RowsAffected(); err != nil {
-			return err
-		} else if n != 1 {
-			return fmt.Errorf("%d rows deleted", n)
-		} else {
-			return nil
-		}
 	}
+	s.notifyDeleted(ctx, "users", id)
+	return nil
 }
 
 // ListenTournamentVersion registers a channel to be notified when the tournament version changes.
@@ -746,13 +854,13 @@ func (s *DBStorage) AddPassword(ctx context.Context, userID int64, passwordHash
 func (s *DBStorage) RemoveExpiredPasswords(ctx context.Context, before time.Time) error {
 	result, err := s.db.ExecContext(ctx,
 		`DELETE FROM passwords WHERE expires IS NOT NULL AND expires < $1`,
-		before)
+		before.UTC())
 	if err != nil {
-		log.Printf("error removing expired passwords: %v", err)
+		logger.ErrorContext(ctx, "error removing expired passwords", "err", err)
 	}
 	n, err := result.RowsAffected()
 	if err != nil {
-		log.Printf("error checking rows affected removing expired passwords: %v", err)
+		logger.ErrorContext(ctx, "error checking rows affected removing expired passwords", "err", err)
 	}
 	fmt.Printf("%d expired\n", n)
 	return err
@@ -769,7 +877,7 @@ func (s *DBStorage) ReplacePassword(ctx context.Context, userID int64, newPasswo
 	// Expire all current passwords for the user
 	_, err = tx.Exec(ctx,
 		`UPDATE passwords SET expires = $1 WHERE user_id = $2 AND (expires IS NULL OR expires > $1)`,
-		oldPasswordsExpire, userID)
+		oldPasswordsExpire.UTC(), userID)
 	if err != nil {
 		return err
 	}
@@ -0,0 +1,158 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ DisplayStorage = &DBStorage{}
+
+// maxDeviceIDLength is as long as a device ID may be.  A kiosk makes up a
+// 16 digit one; one pinned in its URL is a name someone typed.
+const maxDeviceIDLength = 64
+
+// CheckDeviceID returns a 400 unless id will do as a display's device ID:
+// 1 to 64 letters, digits and dashes.
+func CheckDeviceID(id string) error {
+	if id == "" {
+		return he.HTTPCodedErrorf(400, "display needs a device ID")
+	}
+	if len(id) > maxDeviceIDLength {
+		return he.HTTPCodedErrorf(400, "device ID is longer than %d characters", maxDeviceIDLength)
+	}
+	for _, c := range id {
+		if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+			return he.HTTPCodedErrorf(400, "device ID may only have letters, digits and dashes")
+		}
+	}
+	return nil
+}
+
+const displayColumns = `display_id, device_id, version, model_data, last_heartbeat, remote_addr, user_agent`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// displayData is the part of a Display that is kept in model_data.  The rest
+// lives in columns.
+type displayData struct {
+	Name         string
+	Mode         model.DisplayMode
+	TournamentID int64
+	LayoutID     int64
+}
+
+func scanDisplay(row rowScanner) (*model.Display, error) {
+	var bytes []byte
+	var heartbeat sql.NullTime
+	d := &model.Display{}
+	if err := row.Scan(&d.DisplayID, &d.DeviceID, &d.Version, &bytes, &heartbeat, &d.RemoteAddr, &d.UserAgent); err != nil {
+		return nil, err
+	}
+	data := displayData{}
+	if err := json.Unmarshal(bytes, &data); err != nil {
+		return nil, fmt.Errorf("unmarshal display %d: %w", d.DisplayID, err)
+	}
+	d.Name, d.Mode, d.TournamentID, d.LayoutID = data.Name, data.Mode, data.TournamentID, data.LayoutID
+	if heartbeat.Valid {
+		d.LastHeartbeat = heartbeat.Time
+	}
+	return d, nil
+}
+
+func (s *DBStorage) FetchDisplays(ctx context.Context) ([]*model.Display, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT `+displayColumns+` FROM displays ORDER BY display_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	displays := []*model.Display{}
+	for rows.Next() {
+		d, err := scanDisplay(rows)
+		if err != nil {
+			return nil, err
+		}
+		displays = append(displays, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return displays, nil
+}
+
+func (s *DBStorage) FetchDisplay(ctx context.Context, id int64) (*model.Display, error) {
+	d, err := scanDisplay(s.db.QueryRowContext(ctx, `SELECT `+displayColumns+` FROM displays WHERE display_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no such display id %d", id))
+	} else if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *DBStorage) FetchDisplayByDeviceID(ctx context.Context, deviceID string) (*model.Display, error) {
+	d, err := scanDisplay(s.db.QueryRowContext(ctx, `SELECT `+displayColumns+` FROM displays WHERE device_id = $1`, deviceID))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no display with device ID %q", deviceID))
+	} else if err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *DBStorage) RegisterDisplay(ctx context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error) {
+	if err := CheckDeviceID(deviceID); err != nil {
+		return nil, err
+	}
+	return scanDisplay(s.db.QueryRowContext(ctx,
+		`INSERT INTO displays (device_id, model_data, last_heartbeat, remote_addr, user_agent)
+		 VALUES ($1, '{}', $4, $2, $3)
+		 ON CONFLICT (device_id) DO UPDATE
+		 SET last_heartbeat = EXCLUDED.last_heartbeat, remote_addr = EXCLUDED.remote_addr, user_agent = EXCLUDED.user_agent
+		 RETURNING `+displayColumns,
+		deviceID, remoteAddr, userAgent, time.Now().UTC()))
+}
+
+func (s *DBStorage) SaveDisplay(ctx context.Context, d *model.Display) error {
+	bytes, err := json.Marshal(&displayData{d.Name, d.Mode, d.TournamentID, d.LayoutID})
+	if err != nil {
+		return err
+	}
+	newVersion := d.Version + 1
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE displays SET version = $1, model_data = $2 WHERE display_id = $3 AND version = $4`,
+		newVersion, bytes, d.DisplayID, d.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return fmt.Errorf("optimistic lock failure, %d rows affected", n)
+	}
+	d.Version = newVersion
+	s.notify(ctx, "displays", d.DisplayID, newVersion)
+	return nil
+}
+
+func (s *DBStorage) DeleteDisplay(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM displays WHERE display_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return he.New(404, fmt.Errorf("%d rows deleted", n))
+	}
+	s.notifyDeleted(ctx, "displays", id)
+	return nil
+}
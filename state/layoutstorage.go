@@ -0,0 +1,109 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ LayoutStorage = &DBStorage{}
+
+func scanLayout(row rowScanner) (*model.Layout, error) {
+	var id, version int64
+	var bytes []byte
+	if err := row.Scan(&id, &version, &bytes); err != nil {
+		return nil, err
+	}
+	l := &model.Layout{}
+	if err := json.Unmarshal(bytes, l); err != nil {
+		return nil, fmt.Errorf("unmarshal layout %d: %w", id, err)
+	}
+	// These come from the database row, not the JSON.
+	l.LayoutID = id
+	l.Version = version
+	return l, nil
+}
+
+func (s *DBStorage) FetchLayouts(ctx context.Context) ([]*model.Layout, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT layout_id, version, model_data FROM layouts ORDER BY layout_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	layouts := []*model.Layout{}
+	for rows.Next() {
+		l, err := scanLayout(rows)
+		if err != nil {
+			return nil, err
+		}
+		layouts = append(layouts, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return layouts, nil
+}
+
+func (s *DBStorage) FetchLayout(ctx context.Context, id int64) (*model.Layout, error) {
+	l, err := scanLayout(s.db.QueryRowContext(ctx, `SELECT layout_id, version, model_data FROM layouts WHERE layout_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no such layout id %d", id))
+	} else if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *DBStorage) CreateLayout(ctx context.Context, l *model.Layout) (int64, error) {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := s.db.QueryRowContext(ctx, `INSERT INTO layouts (model_data) VALUES ($1) RETURNING layout_id`, bytes).Scan(&id); err != nil {
+		return 0, err
+	}
+	s.notify(ctx, "layouts", id, 0)
+	return id, nil
+}
+
+func (s *DBStorage) SaveLayout(ctx context.Context, l *model.Layout) error {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	newVersion := l.Version + 1
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE layouts SET version = $1, model_data = $2 WHERE layout_id = $3 AND version = $4`,
+		newVersion, bytes, l.LayoutID, l.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return fmt.Errorf("optimistic lock failure, %d rows affected", n)
+	}
+	l.Version = newVersion
+	s.notify(ctx, "layouts", l.LayoutID, newVersion)
+	return nil
+}
+
+func (s *DBStorage) DeleteLayout(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM layouts WHERE layout_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return he.New(404, fmt.Errorf("%d rows deleted", n))
+	}
+	s.notifyDeleted(ctx, "layouts", id)
+	return nil
+}
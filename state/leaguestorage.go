@@ -0,0 +1,141 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ LeagueStorage = &DBStorage{}
+
+func scanLeague(row rowScanner) (*model.League, error) {
+	var id, version int64
+	var bytes []byte
+	if err := row.Scan(&id, &version, &bytes); err != nil {
+		return nil, err
+	}
+	l := &model.League{}
+	if err := json.Unmarshal(bytes, l); err != nil {
+		return nil, fmt.Errorf("unmarshal league %d: %w", id, err)
+	}
+	l.LeagueID = id
+	l.Version = version
+	return l, nil
+}
+
+func (s *DBStorage) FetchLeagues(ctx context.Context) ([]*model.League, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT league_id, version, model_data FROM leagues ORDER BY name, league_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	leagues := []*model.League{}
+	for rows.Next() {
+		l, err := scanLeague(rows)
+		if err != nil {
+			return nil, err
+		}
+		leagues = append(leagues, l)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return leagues, nil
+}
+
+func (s *DBStorage) FetchLeague(ctx context.Context, id int64) (*model.League, error) {
+	l, err := scanLeague(s.db.QueryRowContext(ctx,
+		`SELECT league_id, version, model_data FROM leagues WHERE league_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no such league id %d", id))
+	} else if err != nil {
+		return nil, err
+	}
+	return l, nil
+}
+
+func (s *DBStorage) CreateLeague(ctx context.Context, l *model.League) (int64, error) {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO leagues (name, model_data) VALUES ($1, $2) RETURNING league_id`,
+		l.Name, bytes).Scan(&id); err != nil {
+		return 0, err
+	}
+	s.notify(ctx, "leagues", id, 0)
+	return id, nil
+}
+
+func (s *DBStorage) SaveLeague(ctx context.Context, l *model.League) error {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	newVersion := l.Version + 1
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE leagues SET version = $1, name = $2, model_data = $3 WHERE league_id = $4 AND version = $5`,
+		newVersion, l.Name, bytes, l.LeagueID, l.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return fmt.Errorf("optimistic lock failure, %d rows affected", n)
+	}
+	l.Version = newVersion
+	s.notify(ctx, "leagues", l.LeagueID, newVersion)
+	return nil
+}
+
+func (s *DBStorage) DeleteLeague(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM leagues WHERE league_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return he.New(404, fmt.Errorf("%d rows deleted", n))
+	}
+	s.notifyDeleted(ctx, "leagues", id)
+	return nil
+}
+
+func (s *DBStorage) FetchLeagueTournaments(ctx context.Context, id int64) ([]*model.Tournament, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT tournament_id, version, model_data FROM tournaments WHERE league_id = $1 ORDER BY tournament_id`, id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	tournaments := []*model.Tournament{}
+	for rows.Next() {
+		var bytes []byte
+		t := &model.Tournament{}
+		if err := rows.Scan(&t.EventID, &t.Version, &bytes); err != nil {
+			return nil, err
+		}
+		// The ID and version come from the row, so unmarshal around them.
+		tid, version := t.EventID, t.Version
+		if err := json.Unmarshal(bytes, t); err != nil {
+			return nil, fmt.Errorf("unmarshal tournament %d: %w", tid, err)
+		}
+		t.EventID, t.Version = tid, version
+		tournaments = append(tournaments, t)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return tournaments, nil
+}
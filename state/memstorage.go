@@ -0,0 +1,1562 @@
+package state
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ Storage = &MemStorage{}
+
+// defaultSiteConfig is the site config a new database starts with; the
+// migrations insert the same.
+const defaultSiteConfig = `{"Name": "Irata Poker Tournament Clock", "Theme": "irata"}`
+
+// MemStorage keeps everything in memory, the way a freshly migrated
+// database would, and forgets it all when the process exits.  It's for the
+// demo and for tests that want a whole backend without a database.
+//
+// Models are kept as JSON, like model_data, so nothing a caller holds on to
+// is shared with the store.
+type MemStorage struct {
+	mu       sync.Mutex
+	notifier Notifier
+
+	// lastID is the last ID handed out in each table.  As with SQLite's
+	// AUTOINCREMENT, IDs are never reused.
+	lastID map[string]int64
+	// tables are the tables that keep a model as JSON, by name.
+	tables map[string]map[int64]*memRow
+
+	siteConfig []byte
+	users      map[int64]*model.UserIdentity
+	passwords  map[int64]*memPassword
+	emails     map[string]int64
+	displays   map[int64]*model.Display
+	certCache  map[string][]byte
+	apiTokens  map[int64]*memAPIToken
+	webhooks   map[int64]*model.Webhook
+	deliveries map[int64]*model.WebhookDelivery
+}
+
+// memRow is a row of a table that keeps its model as JSON.
+type memRow struct {
+	version int64
+	data    []byte
+
+	// Columns some tables keep beside the JSON, to sort or filter on.
+	name      string    // structures, footer_plug_sets, leagues, tournament_templates
+	lifecycle string    // tournaments
+	leagueID  int64     // tournaments
+	expires   time.Time // announcements
+	imageType string    // slides
+	imageData []byte    // slides
+}
+
+type memPassword struct {
+	userID  int64
+	hash    string
+	expires *time.Time
+}
+
+type memAPIToken struct {
+	token model.APIToken
+	hash  string
+}
+
+// memTables are the tables kept as JSON rows.
+var memTables = []string{
+	"tournaments",
+	"structures",
+	"footer_plug_sets",
+	"layouts",
+	"leagues",
+	"slides",
+	"slide_sets",
+	"tournament_templates",
+	"announcements",
+}
+
+func NewMemStorage() *MemStorage {
+	s := &MemStorage{
+		lastID:     map[string]int64{},
+		tables:     map[string]map[int64]*memRow{},
+		siteConfig: []byte(defaultSiteConfig),
+		users:      map[int64]*model.UserIdentity{},
+		passwords:  map[int64]*memPassword{},
+		emails:     map[string]int64{},
+		displays:   map[int64]*model.Display{},
+		certCache:  map[string][]byte{},
+		apiTokens:  map[int64]*memAPIToken{},
+		webhooks:   map[int64]*model.Webhook{},
+		deliveries: map[int64]*model.WebhookDelivery{},
+	}
+	for _, table := range memTables {
+		s.tables[table] = map[int64]*memRow{}
+	}
+	return s
+}
+
+// SetNotifier sets the Notifier told about changes.  Nothing else can tell
+// it, so unlike DBStorage, MemStorage always uses it.
+func (s *MemStorage) SetNotifier(n Notifier) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.notifier = n
+}
+
+// notify and notifyDeleted must be called without s.mu held, since whoever
+// hears about the change is apt to fetch it.
+func (s *MemStorage) notify(ctx context.Context, table string, id, version int64) {
+	s.mu.Lock()
+	n := s.notifier
+	s.mu.Unlock()
+	if n != nil {
+		n.Notify(ctx, table, id, version)
+	}
+}
+
+func (s *MemStorage) notifyDeleted(ctx context.Context, table string, id int64) {
+	s.mu.Lock()
+	n := s.notifier
+	s.mu.Unlock()
+	if n != nil {
+		n.NotifyDeleted(ctx, table, id)
+	}
+}
+
+func (s *MemStorage) Ping(ctx context.Context) error {
+	return nil
+}
+
+func (s *MemStorage) Close() {}
+
+// CountRows counts the rows in each table a backup covers.
+func (s *MemStorage) CountRows(ctx context.Context) (map[string]int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	counts := map[string]int64{}
+	for _, table := range backupTables {
+		switch table {
+		case "users":
+			counts[table] = int64(len(s.users))
+		case "displays":
+			counts[table] = int64(len(s.displays))
+		default:
+			counts[table] = int64(len(s.tables[table]))
+		}
+	}
+	return counts, nil
+}
+
+// newID hands out the next ID in table.  s.mu must be held.
+func (s *MemStorage) newID(table string) int64 {
+	s.lastID[table]++
+	return s.lastID[table]
+}
+
+// insert adds r to table and returns its ID.  s.mu must be held.
+func (s *MemStorage) insert(table string, r *memRow) int64 {
+	id := s.newID(table)
+	s.tables[table][id] = r
+	return id
+}
+
+// update replaces the JSON of the row if it's still at version, as the
+// UPDATE ... WHERE version = $n in DBStorage does, and returns the row for
+// the caller to update the other columns of.  s.mu must be held.
+func (s *MemStorage) update(table string, id, version int64, data []byte) (*memRow, error) {
+	r, ok := s.tables[table][id]
+	if !ok || r.version != version {
+		return nil, fmt.Errorf("optimistic lock failure, 0 rows affected")
+	}
+	r.version++
+	r.data = data
+	return r, nil
+}
+
+// remove deletes a row and says whether there was one.  s.mu must be held.
+func (s *MemStorage) remove(table string, id int64) bool {
+	if _, ok := s.tables[table][id]; !ok {
+		return false
+	}
+	delete(s.tables[table], id)
+	return true
+}
+
+// ids lists the rows of table in ID order.  s.mu must be held.
+func (s *MemStorage) ids(table string) []int64 {
+	return slices.Sorted(maps.Keys(s.tables[table]))
+}
+
+// page applies OFFSET and LIMIT to ids.
+func page(ids []int64, offset, limit int) []int64 {
+	if offset > len(ids) {
+		return nil
+	}
+	ids = ids[offset:]
+	if limit >= 0 && limit < len(ids) {
+		ids = ids[:limit]
+	}
+	return ids
+}
+
+// unmarshalRow decodes the JSON of the row with the given ID.
+func unmarshalRow[T any](r *memRow, what string, id int64) (*T, error) {
+	v := new(T)
+	if err := json.Unmarshal(r.data, v); err != nil {
+		return nil, fmt.Errorf("unmarshal %s %d: %w", what, id, err)
+	}
+	return v, nil
+}
+
+// Footer plugs.
+
+func (s *MemStorage) FetchPlugs(ctx context.Context, id int64) (*model.FooterPlugs, error) {
+	fetchFooterPlugs.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.tables["footer_plug_sets"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such footer plug set id %d", id))
+	}
+	plugs := []model.FooterPlug{}
+	if err := json.Unmarshal(r.data, &plugs); err != nil {
+		return nil, fmt.Errorf("unmarshal plugs in footer plug set %d: %w", id, err)
+	}
+	return &model.FooterPlugs{
+		FooterPlugsID: id,
+		Version:       r.version,
+		Name:          r.name,
+		Plugs:         plugs,
+	}, nil
+}
+
+func (s *MemStorage) ListFooterPlugSets(ctx context.Context) ([]*model.FooterPlugs, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sets := []*model.FooterPlugs{}
+	for _, id := range s.ids("footer_plug_sets") {
+		r := s.tables["footer_plug_sets"][id]
+		sets = append(sets, &model.FooterPlugs{
+			FooterPlugsID: id,
+			Version:       r.version,
+			Name:          r.name,
+		})
+	}
+	return sets, nil
+}
+
+func marshalPlugs(plugs []model.FooterPlug) ([]byte, error) {
+	if plugs == nil {
+		plugs = []model.FooterPlug{}
+	}
+	return json.Marshal(plugs)
+}
+
+func (s *MemStorage) CreateFooterPlugSet(ctx context.Context, name string, plugs []model.FooterPlug) (int64, error) {
+	bytes, err := marshalPlugs(plugs)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	id := s.insert("footer_plug_sets", &memRow{name: name, data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "footer_plug_sets", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []model.FooterPlug) error {
+	bytes, err := marshalPlugs(plugs)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	r, ok := s.tables["footer_plug_sets"][id]
+	if !ok {
+		s.mu.Unlock()
+		return he.New(404, fmt.Errorf("no such footer plug set id %d", id))
+	}
+	r.name = name
+	r.data = bytes
+	r.version++
+	version := r.version
+	s.mu.Unlock()
+	s.notify(ctx, "footer_plug_sets", id, version)
+	return nil
+}
+
+func (s *MemStorage) DeleteFooterPlugSet(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	s.remove("footer_plug_sets", id)
+	s.mu.Unlock()
+	s.notifyDeleted(ctx, "footer_plug_sets", id)
+	return nil
+}
+
+// Structures.
+
+func (s *MemStorage) FetchStructure(ctx context.Context, id int64) (*model.Structure, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.tables["structures"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such structure id %d", id))
+	}
+	st, err := unmarshalRow[model.Structure](r, "structure", id)
+	if err != nil {
+		return nil, err
+	}
+	st.Name = r.name
+	st.ID = id
+	st.Version = r.version
+	return st, nil
+}
+
+func (s *MemStorage) FetchStructureSlugs(ctx context.Context, offset, limit int) ([]*model.StructureSlug, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slugs := []*model.StructureSlug{}
+	for _, id := range page(s.ids("structures"), offset, limit) {
+		r := s.tables["structures"][id]
+		sd, err := unmarshalRow[model.StructureData](r, "structure", id)
+		if err != nil {
+			return nil, err
+		}
+		slugs = append(slugs, &model.StructureSlug{
+			ID:            id,
+			Name:          r.name,
+			ChipsPerBuyIn: sd.ChipsPerBuyIn,
+			ChipsPerAddOn: sd.ChipsPerAddOn,
+		})
+	}
+	return slugs, nil
+}
+
+func (s *MemStorage) SaveStructure(ctx context.Context, st *model.Structure) error {
+	bytes, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	r, err := s.update("structures", st.ID, st.Version, bytes)
+	if err == nil {
+		r.name = st.Name
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	s.notify(ctx, "structures", st.ID, st.Version+1)
+	return nil
+}
+
+func (s *MemStorage) DeleteStructure(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	ok := s.remove("structures", id)
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("0 rows deleted")
+	}
+	s.notifyDeleted(ctx, "structures", id)
+	return nil
+}
+
+func (s *MemStorage) CreateStructure(ctx context.Context, st *model.Structure) (int64, error) {
+	bytes, err := json.Marshal(st)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	st.ID = s.insert("structures", &memRow{name: st.Name, data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "structures", st.ID, 0)
+	return st.ID, nil
+}
+
+// Site config.
+
+func (s *MemStorage) FetchSiteConfig(ctx context.Context) (*model.SiteConfig, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	config := &model.SiteConfig{}
+	if err := json.Unmarshal(s.siteConfig, config); err != nil {
+		return nil, err
+	}
+	return config, nil
+}
+
+func (s *MemStorage) SaveSiteConfig(ctx context.Context, config *model.SiteConfig) error {
+	bytes, err := json.Marshal(config)
+	if err != nil {
+		return fmt.Errorf("marshaling config: %w", err)
+	}
+	s.mu.Lock()
+	s.siteConfig = bytes
+	s.mu.Unlock()
+	s.notify(ctx, "site_config", ConfKey, 0)
+	return nil
+}
+
+// Users.
+
+// nickTaken says whether some user other than id has nick.  s.mu must be
+// held.
+func (s *MemStorage) nickTaken(nick string, id int64) bool {
+	for uid, u := range s.users {
+		if u.Nick == nick && uid != id {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemStorage) addPassword(userID int64, hash string) error {
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("no such user id %d", userID)
+	}
+	s.passwords[s.newID("passwords")] = &memPassword{userID: userID, hash: hash}
+	return nil
+}
+
+func (s *MemStorage) CreateUser(ctx context.Context, u *model.UserIdentity) (int64, error) {
+	s.mu.Lock()
+	if s.nickTaken(u.Nick, 0) {
+		s.mu.Unlock()
+		return -1, he.HTTPCodedErrorf(500, "nick %q is taken", u.Nick)
+	}
+	id := s.newID("users")
+	s.users[id] = &model.UserIdentity{ID: id, Nick: u.Nick, IsAdmin: u.IsAdmin, IsOperator: u.IsOperator}
+	s.mu.Unlock()
+	s.notify(ctx, "users", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) CreateUserWithEmailAndPassword(ctx context.Context, nick string, emailAddress string, passwordHash string, isAdmin bool) error {
+	s.mu.Lock()
+	if s.nickTaken(nick, 0) {
+		s.mu.Unlock()
+		return fmt.Errorf("insert users: nick %q is taken", nick)
+	}
+	if _, ok := s.emails[emailAddress]; ok {
+		s.mu.Unlock()
+		return fmt.Errorf("insert user_email_addresses: %q is taken", emailAddress)
+	}
+	id := s.newID("users")
+	s.users[id] = &model.UserIdentity{ID: id, Nick: nick, IsAdmin: isAdmin}
+	s.emails[emailAddress] = id
+	s.addPassword(id, passwordHash)
+	s.mu.Unlock()
+	s.notify(ctx, "users", id, 0)
+	return nil
+}
+
+func (s *MemStorage) FetchUserRow(ctx context.Context, nick string) (*model.UserRow, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var row model.UserRow
+	for _, u := range s.users {
+		if u.Nick != nick {
+			continue
+		}
+		for _, pid := range slices.Sorted(maps.Keys(s.passwords)) {
+			p := s.passwords[pid]
+			if p.userID != u.ID {
+				continue
+			}
+			var expires *time.Time
+			if p.expires != nil {
+				t := *p.expires
+				expires = &t
+			}
+			row.Passwords = append(row.Passwords, model.Password{PasswordHash: p.hash, ExpiresAt: expires})
+		}
+		// Like the join, a user with no passwords has no row.
+		if len(row.Passwords) > 0 {
+			row.UserIdentity = *u
+		}
+	}
+	return &row, nil
+}
+
+func (s *MemStorage) FetchUserByUserID(ctx context.Context, id int64) (*model.UserIdentity, error) {
+	fetchUserByID.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	u, ok := s.users[id]
+	if !ok {
+		return nil, errors.New("user not found")
+	}
+	return u.Clone(), nil
+}
+
+func (s *MemStorage) FetchUsers(ctx context.Context) ([]*model.UserIdentity, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var users []*model.UserIdentity
+	for _, id := range slices.Sorted(maps.Keys(s.users)) {
+		users = append(users, s.users[id].Clone())
+	}
+	return users, nil
+}
+
+func (s *MemStorage) SaveUser(ctx context.Context, u *model.UserIdentity) error {
+	s.mu.Lock()
+	if _, ok := s.users[u.ID]; !ok {
+		s.mu.Unlock()
+		return he.HTTPCodedErrorf(404, "no rows affected")
+	}
+	if s.nickTaken(u.Nick, u.ID) {
+		s.mu.Unlock()
+		return he.HTTPCodedErrorf(500, "nick %q is taken", u.Nick)
+	}
+	s.users[u.ID] = u.Clone()
+	s.mu.Unlock()
+	s.notify(ctx, "users", u.ID, 0)
+	return nil
+}
+
+// deleteUser removes a user and everything that goes with them, as ON
+// DELETE CASCADE would.  s.mu must be held.
+func (s *MemStorage) deleteUser(id int64) {
+	delete(s.users, id)
+	maps.DeleteFunc(s.passwords, func(_ int64, p *memPassword) bool { return p.userID == id })
+	maps.DeleteFunc(s.emails, func(_ string, uid int64) bool { return uid == id })
+	maps.DeleteFunc(s.apiTokens, func(_ int64, t *memAPIToken) bool { return t.token.UserID == id })
+}
+
+func (s *MemStorage) DeleteUserByID(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	if _, ok := s.users[id]; !ok {
+		s.mu.Unlock()
+		return fmt.Errorf("0 rows deleted")
+	}
+	s.deleteUser(id)
+	s.mu.Unlock()
+	s.notifyDeleted(ctx, "users", id)
+	return nil
+}
+
+func (s *MemStorage) DeleteUserByNick(ctx context.Context, nick string) error {
+	s.mu.Lock()
+	var id int64
+	for uid, u := range s.users {
+		if u.Nick == nick {
+			id = uid
+		}
+	}
+	if id == 0 {
+		s.mu.Unlock()
+		return fmt.Errorf("0 rows deleted")
+	}
+	s.deleteUser(id)
+	s.mu.Unlock()
+	s.notifyDeleted(ctx, "users", id)
+	return nil
+}
+
+func (s *MemStorage) AddPassword(ctx context.Context, userID int64, passwordHash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.addPassword(userID, passwordHash)
+}
+
+func (s *MemStorage) RemoveExpiredPasswords(ctx context.Context, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	maps.DeleteFunc(s.passwords, func(_ int64, p *memPassword) bool {
+		return p.expires != nil && p.expires.Before(before)
+	})
+	return nil
+}
+
+func (s *MemStorage) ReplacePassword(ctx context.Context, userID int64, newPasswordHash string, oldPasswordsExpire time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[userID]; !ok {
+		return fmt.Errorf("no such user id %d", userID)
+	}
+	expires := oldPasswordsExpire.UTC()
+	for _, p := range s.passwords {
+		if p.userID == userID && (p.expires == nil || p.expires.After(expires)) {
+			p.expires = &expires
+		}
+	}
+	return s.addPassword(userID, newPasswordHash)
+}
+
+// Tournaments.
+
+func (s *MemStorage) FetchOverview(ctx context.Context, lifecycles []model.Lifecycle, offset, limit int) (*model.Overview, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := []int64{}
+	for _, id := range s.ids("tournaments") {
+		lifecycle := model.Lifecycle(s.tables["tournaments"][id].lifecycle)
+		if len(lifecycles) == 0 || slices.Contains(lifecycles, lifecycle) {
+			ids = append(ids, id)
+		}
+	}
+
+	overview := &model.Overview{Total: len(ids)}
+	for _, id := range page(ids, offset, limit) {
+		r := s.tables["tournaments"][id]
+		t, err := unmarshalRow[model.Tournament](r, "tournament", id)
+		if err != nil {
+			logger.ErrorContext(ctx, "tournament JSON unmarshal failed", "tournament", id, "err", err)
+			continue
+		}
+		overview.Slugs = append(overview.Slugs, model.TournamentSlug{
+			TournamentID:   id,
+			TournamentName: t.EventName,
+			Description:    t.Description,
+			ScheduledStart: t.ScheduledStart,
+			Lifecycle:      model.Lifecycle(r.lifecycle),
+		})
+	}
+	return overview, nil
+}
+
+// tournament fetches a tournament.  s.mu must be held.
+func (s *MemStorage) tournament(id int64) (*model.Tournament, error) {
+	r, ok := s.tables["tournaments"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such tournament id %d", id))
+	}
+	t, err := unmarshalRow[model.Tournament](r, "tournament", id)
+	if err != nil {
+		return nil, err
+	}
+	// These come from the row, not the JSON.
+	t.EventID = id
+	t.Version = r.version
+	return t, nil
+}
+
+func (s *MemStorage) FetchTournament(ctx context.Context, id int64) (*model.Tournament, error) {
+	fetchTournament.Add(1)
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tournament(id)
+}
+
+func (s *MemStorage) CreateTournament(ctx context.Context, t *model.Tournament) (int64, error) {
+	if len(t.Structure.Levels) == 0 {
+		return 0, fmt.Errorf("cannot create tournament with no structure levels")
+	}
+
+	cpy := *t
+	cpy.State.IsClockRunning = false
+
+	// Set level to full time.
+	// q.v. Tournament.restartLevel
+	millis := (time.Duration(t.CurrentLevel().DurationMinutes) * time.Minute).Milliseconds()
+	cpy.State.TimeRemainingMillis = &millis
+
+	bytes, err := json.Marshal(&cpy)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	id := s.insert("tournaments", &memRow{lifecycle: storedLifecycle(&cpy), leagueID: cpy.LeagueID, data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "tournaments", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) SaveTournament(ctx context.Context, tm *model.Tournament) error {
+	saveTournament.Add(1)
+
+	cpy := *tm
+	cpy.Transients = nil
+	bytes, err := json.Marshal(&cpy)
+	if err != nil {
+		return err
+	}
+	lifecycle := storedLifecycle(tm)
+
+	s.mu.Lock()
+	// An archived tournament can't be changed, except by taking it out of
+	// the archive.
+	if r, ok := s.tables["tournaments"][tm.EventID]; ok &&
+		r.lifecycle == string(model.LifecycleArchived) && lifecycle == string(model.LifecycleArchived) {
+		s.mu.Unlock()
+		return he.New(409, fmt.Errorf("tournament %d is archived", tm.EventID))
+	}
+	r, err := s.update("tournaments", tm.EventID, tm.Version, bytes)
+	if err == nil {
+		r.lifecycle = lifecycle
+		r.leagueID = tm.LeagueID
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+
+	tm.Version++
+	s.notify(ctx, "tournaments", tm.EventID, tm.Version)
+	return nil
+}
+
+func (s *MemStorage) DeleteTournament(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	ok := s.remove("tournaments", id)
+	s.mu.Unlock()
+	if !ok {
+		return fmt.Errorf("0 rows deleted")
+	}
+	s.notifyDeleted(ctx, "tournaments", id)
+	return nil
+}
+
+// Displays.
+
+func (s *MemStorage) FetchDisplays(ctx context.Context) ([]*model.Display, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	displays := []*model.Display{}
+	for _, id := range slices.Sorted(maps.Keys(s.displays)) {
+		displays = append(displays, s.displays[id].Clone())
+	}
+	return displays, nil
+}
+
+func (s *MemStorage) FetchDisplay(ctx context.Context, id int64) (*model.Display, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.displays[id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such display id %d", id))
+	}
+	return d.Clone(), nil
+}
+
+func (s *MemStorage) FetchDisplayByDeviceID(ctx context.Context, deviceID string) (*model.Display, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, d := range s.displays {
+		if d.DeviceID == deviceID {
+			return d.Clone(), nil
+		}
+	}
+	return nil, he.New(404, fmt.Errorf("no display with device ID %q", deviceID))
+}
+
+func (s *MemStorage) RegisterDisplay(ctx context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error) {
+	if err := CheckDeviceID(deviceID); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var d *model.Display
+	for _, seen := range s.displays {
+		if seen.DeviceID == deviceID {
+			d = seen
+		}
+	}
+	if d == nil {
+		d = &model.Display{DisplayID: s.newID("displays"), DeviceID: deviceID}
+		s.displays[d.DisplayID] = d
+	}
+	d.LastHeartbeat = time.Now().UTC()
+	d.RemoteAddr = remoteAddr
+	d.UserAgent = userAgent
+	return d.Clone(), nil
+}
+
+func (s *MemStorage) SaveDisplay(ctx context.Context, d *model.Display) error {
+	s.mu.Lock()
+	stored, ok := s.displays[d.DisplayID]
+	if !ok || stored.Version != d.Version {
+		s.mu.Unlock()
+		return fmt.Errorf("optimistic lock failure, 0 rows affected")
+	}
+	stored.Version++
+	stored.Name, stored.Mode, stored.TournamentID, stored.LayoutID = d.Name, d.Mode, d.TournamentID, d.LayoutID
+	s.mu.Unlock()
+	d.Version++
+	s.notify(ctx, "displays", d.DisplayID, d.Version)
+	return nil
+}
+
+func (s *MemStorage) DeleteDisplay(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	_, ok := s.displays[id]
+	delete(s.displays, id)
+	s.mu.Unlock()
+	if !ok {
+		return he.New(404, fmt.Errorf("0 rows deleted"))
+	}
+	s.notifyDeleted(ctx, "displays", id)
+	return nil
+}
+
+// Layouts.
+
+func (s *MemStorage) layout(id int64) (*model.Layout, error) {
+	r, ok := s.tables["layouts"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such layout id %d", id))
+	}
+	l, err := unmarshalRow[model.Layout](r, "layout", id)
+	if err != nil {
+		return nil, err
+	}
+	l.LayoutID = id
+	l.Version = r.version
+	return l, nil
+}
+
+func (s *MemStorage) FetchLayouts(ctx context.Context) ([]*model.Layout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	layouts := []*model.Layout{}
+	for _, id := range s.ids("layouts") {
+		l, err := s.layout(id)
+		if err != nil {
+			return nil, err
+		}
+		layouts = append(layouts, l)
+	}
+	return layouts, nil
+}
+
+func (s *MemStorage) FetchLayout(ctx context.Context, id int64) (*model.Layout, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.layout(id)
+}
+
+func (s *MemStorage) CreateLayout(ctx context.Context, l *model.Layout) (int64, error) {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	id := s.insert("layouts", &memRow{data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "layouts", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) SaveLayout(ctx context.Context, l *model.Layout) error {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	_, err = s.update("layouts", l.LayoutID, l.Version, bytes)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	l.Version++
+	s.notify(ctx, "layouts", l.LayoutID, l.Version)
+	return nil
+}
+
+func (s *MemStorage) DeleteLayout(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	ok := s.remove("layouts", id)
+	s.mu.Unlock()
+	if !ok {
+		return he.New(404, fmt.Errorf("0 rows deleted"))
+	}
+	s.notifyDeleted(ctx, "layouts", id)
+	return nil
+}
+
+// Slides and slide sets.
+
+func (s *MemStorage) slide(id int64) (*model.Slide, error) {
+	r, ok := s.tables["slides"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such slide id %d", id))
+	}
+	sl, err := unmarshalRow[model.Slide](r, "slide", id)
+	if err != nil {
+		return nil, err
+	}
+	sl.SlideID = id
+	sl.Version = r.version
+	return sl, nil
+}
+
+func (s *MemStorage) FetchSlides(ctx context.Context) ([]*model.Slide, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	slides := []*model.Slide{}
+	for _, id := range s.ids("slides") {
+		sl, err := s.slide(id)
+		if err != nil {
+			return nil, err
+		}
+		slides = append(slides, sl)
+	}
+	return slides, nil
+}
+
+func (s *MemStorage) FetchSlide(ctx context.Context, id int64) (*model.Slide, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.slide(id)
+}
+
+func (s *MemStorage) CreateSlide(ctx context.Context, sl *model.Slide) (int64, error) {
+	bytes, err := json.Marshal(sl)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	id := s.insert("slides", &memRow{data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "slides", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) SaveSlide(ctx context.Context, sl *model.Slide) error {
+	bytes, err := json.Marshal(sl)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	_, err = s.update("slides", sl.SlideID, sl.Version, bytes)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	sl.Version++
+	s.notify(ctx, "slides", sl.SlideID, sl.Version)
+	return nil
+}
+
+func (s *MemStorage) DeleteSlide(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	ok := s.remove("slides", id)
+	s.mu.Unlock()
+	if !ok {
+		return he.New(404, fmt.Errorf("0 rows deleted"))
+	}
+	s.notifyDeleted(ctx, "slides", id)
+	return nil
+}
+
+func (s *MemStorage) FetchSlideImage(ctx context.Context, id int64) (string, []byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.tables["slides"][id]
+	if !ok || r.imageType == "" {
+		return "", nil, he.New(404, fmt.Errorf("no image for slide id %d", id))
+	}
+	return r.imageType, slices.Clone(r.imageData), nil
+}
+
+// SaveSlideImage replaces the image for a slide, or removes it if
+// contentType is empty.  As with DBStorage, the version is left alone.
+func (s *MemStorage) SaveSlideImage(ctx context.Context, id int64, contentType string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	r, ok := s.tables["slides"][id]
+	if !ok {
+		return he.New(404, fmt.Errorf("no such slide id %d", id))
+	}
+	r.imageType = contentType
+	r.imageData = slices.Clone(data)
+	return nil
+}
+
+func (s *MemStorage) slideSet(id int64) (*model.SlideSet, error) {
+	r, ok := s.tables["slide_sets"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such slide set id %d", id))
+	}
+	ss, err := unmarshalRow[model.SlideSet](r, "slide set", id)
+	if err != nil {
+		return nil, err
+	}
+	ss.SlideSetID = id
+	ss.Version = r.version
+	return ss, nil
+}
+
+func (s *MemStorage) FetchSlideSets(ctx context.Context) ([]*model.SlideSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	sets := []*model.SlideSet{}
+	for _, id := range s.ids("slide_sets") {
+		ss, err := s.slideSet(id)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, ss)
+	}
+	return sets, nil
+}
+
+func (s *MemStorage) FetchSlideSet(ctx context.Context, id int64) (*model.SlideSet, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.slideSet(id)
+}
+
+func (s *MemStorage) CreateSlideSet(ctx context.Context, ss *model.SlideSet) (int64, error) {
+	bytes, err := json.Marshal(ss)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	id := s.insert("slide_sets", &memRow{data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "slide_sets", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) SaveSlideSet(ctx context.Context, ss *model.SlideSet) error {
+	bytes, err := json.Marshal(ss)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	_, err = s.update("slide_sets", ss.SlideSetID, ss.Version, bytes)
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	ss.Version++
+	s.notify(ctx, "slide_sets", ss.SlideSetID, ss.Version)
+	return nil
+}
+
+func (s *MemStorage) DeleteSlideSet(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	ok := s.remove("slide_sets", id)
+	s.mu.Unlock()
+	if !ok {
+		return he.New(404, fmt.Errorf("0 rows deleted"))
+	}
+	s.notifyDeleted(ctx, "slide_sets", id)
+	return nil
+}
+
+// Announcements.
+
+func (s *MemStorage) announcement(id int64) (*model.Announcement, error) {
+	r, ok := s.tables["announcements"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such announcement id %d", id))
+	}
+	a, err := unmarshalRow[model.Announcement](r, "announcement", id)
+	if err != nil {
+		return nil, err
+	}
+	a.AnnouncementID = id
+	a.Version = r.version
+	return a, nil
+}
+
+func (s *MemStorage) FetchAnnouncements(ctx context.Context, expiringAfter time.Time) ([]*model.Announcement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	announcements := []*model.Announcement{}
+	for _, id := range s.ids("announcements") {
+		if !s.tables["announcements"][id].expires.After(expiringAfter) {
+			continue
+		}
+		a, err := s.announcement(id)
+		if err != nil {
+			return nil, err
+		}
+		announcements = append(announcements, a)
+	}
+	return announcements, nil
+}
+
+func (s *MemStorage) FetchAnnouncement(ctx context.Context, id int64) (*model.Announcement, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.announcement(id)
+}
+
+func (s *MemStorage) CreateAnnouncement(ctx context.Context, a *model.Announcement) (int64, error) {
+	bytes, err := json.Marshal(a)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	id := s.insert("announcements", &memRow{expires: a.Expires.UTC(), data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "announcements", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) SaveAnnouncement(ctx context.Context, a *model.Announcement) error {
+	bytes, err := json.Marshal(a)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	r, err := s.update("announcements", a.AnnouncementID, a.Version, bytes)
+	if err == nil {
+		r.expires = a.Expires.UTC()
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	a.Version++
+	s.notify(ctx, "announcements", a.AnnouncementID, a.Version)
+	return nil
+}
+
+func (s *MemStorage) DeleteAnnouncement(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	ok := s.remove("announcements", id)
+	s.mu.Unlock()
+	if !ok {
+		return he.New(404, fmt.Errorf("0 rows deleted"))
+	}
+	s.notifyDeleted(ctx, "announcements", id)
+	return nil
+}
+
+// byName lists the rows of table ordered by name, then ID.  s.mu must be
+// held.
+func (s *MemStorage) byName(table string) []int64 {
+	ids := s.ids(table)
+	slices.SortStableFunc(ids, func(a, b int64) int {
+		return strings.Compare(s.tables[table][a].name, s.tables[table][b].name)
+	})
+	return ids
+}
+
+// Leagues.
+
+func (s *MemStorage) league(id int64) (*model.League, error) {
+	r, ok := s.tables["leagues"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such league id %d", id))
+	}
+	l, err := unmarshalRow[model.League](r, "league", id)
+	if err != nil {
+		return nil, err
+	}
+	l.LeagueID = id
+	l.Version = r.version
+	return l, nil
+}
+
+func (s *MemStorage) FetchLeagues(ctx context.Context) ([]*model.League, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	leagues := []*model.League{}
+	for _, id := range s.byName("leagues") {
+		l, err := s.league(id)
+		if err != nil {
+			return nil, err
+		}
+		leagues = append(leagues, l)
+	}
+	return leagues, nil
+}
+
+func (s *MemStorage) FetchLeague(ctx context.Context, id int64) (*model.League, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.league(id)
+}
+
+func (s *MemStorage) CreateLeague(ctx context.Context, l *model.League) (int64, error) {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	id := s.insert("leagues", &memRow{name: l.Name, data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "leagues", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) SaveLeague(ctx context.Context, l *model.League) error {
+	bytes, err := json.Marshal(l)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	r, err := s.update("leagues", l.LeagueID, l.Version, bytes)
+	if err == nil {
+		r.name = l.Name
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	l.Version++
+	s.notify(ctx, "leagues", l.LeagueID, l.Version)
+	return nil
+}
+
+func (s *MemStorage) DeleteLeague(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	ok := s.remove("leagues", id)
+	s.mu.Unlock()
+	if !ok {
+		return he.New(404, fmt.Errorf("0 rows deleted"))
+	}
+	s.notifyDeleted(ctx, "leagues", id)
+	return nil
+}
+
+func (s *MemStorage) FetchLeagueTournaments(ctx context.Context, id int64) ([]*model.Tournament, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tournaments := []*model.Tournament{}
+	for _, tid := range s.ids("tournaments") {
+		if s.tables["tournaments"][tid].leagueID != id {
+			continue
+		}
+		t, err := s.tournament(tid)
+		if err != nil {
+			return nil, err
+		}
+		tournaments = append(tournaments, t)
+	}
+	return tournaments, nil
+}
+
+// Tournament templates.
+
+func (s *MemStorage) tournamentTemplate(id int64) (*model.TournamentTemplate, error) {
+	r, ok := s.tables["tournament_templates"][id]
+	if !ok {
+		return nil, he.New(404, fmt.Errorf("no such tournament template id %d", id))
+	}
+	tt, err := unmarshalRow[model.TournamentTemplate](r, "tournament template", id)
+	if err != nil {
+		return nil, err
+	}
+	tt.TournamentTemplateID = id
+	tt.Version = r.version
+	return tt, nil
+}
+
+func (s *MemStorage) FetchTournamentTemplates(ctx context.Context) ([]*model.TournamentTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	templates := []*model.TournamentTemplate{}
+	for _, id := range s.byName("tournament_templates") {
+		tt, err := s.tournamentTemplate(id)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tt)
+	}
+	return templates, nil
+}
+
+func (s *MemStorage) FetchTournamentTemplate(ctx context.Context, id int64) (*model.TournamentTemplate, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.tournamentTemplate(id)
+}
+
+func (s *MemStorage) CreateTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) (int64, error) {
+	bytes, err := json.Marshal(tt)
+	if err != nil {
+		return 0, err
+	}
+	s.mu.Lock()
+	id := s.insert("tournament_templates", &memRow{name: tt.Name, data: bytes})
+	s.mu.Unlock()
+	s.notify(ctx, "tournament_templates", id, 0)
+	return id, nil
+}
+
+func (s *MemStorage) SaveTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) error {
+	bytes, err := json.Marshal(tt)
+	if err != nil {
+		return err
+	}
+	s.mu.Lock()
+	r, err := s.update("tournament_templates", tt.TournamentTemplateID, tt.Version, bytes)
+	if err == nil {
+		r.name = tt.Name
+	}
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	tt.Version++
+	s.notify(ctx, "tournament_templates", tt.TournamentTemplateID, tt.Version)
+	return nil
+}
+
+func (s *MemStorage) DeleteTournamentTemplate(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	ok := s.remove("tournament_templates", id)
+	s.mu.Unlock()
+	if !ok {
+		return he.New(404, fmt.Errorf("0 rows deleted"))
+	}
+	s.notifyDeleted(ctx, "tournament_templates", id)
+	return nil
+}
+
+// Certificate cache.
+
+func (s *MemStorage) FetchCertCache(ctx context.Context, key string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	data, ok := s.certCache[key]
+	if !ok {
+		return nil, ErrNotCached
+	}
+	return slices.Clone(data), nil
+}
+
+func (s *MemStorage) SaveCertCache(ctx context.Context, key string, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.certCache[key] = slices.Clone(data)
+	return nil
+}
+
+func (s *MemStorage) DeleteCertCache(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.certCache, key)
+	return nil
+}
+
+// API tokens.
+
+func cloneAPIToken(t *model.APIToken) *model.APIToken {
+	cpy := *t
+	cpy.Scopes = slices.Clone(t.Scopes)
+	if t.LastUsed != nil {
+		lastUsed := *t.LastUsed
+		cpy.LastUsed = &lastUsed
+	}
+	return &cpy
+}
+
+func (s *MemStorage) CreateAPIToken(ctx context.Context, t *model.APIToken, hash string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.users[t.UserID]; !ok {
+		return 0, fmt.Errorf("no such user id %d", t.UserID)
+	}
+	for _, seen := range s.apiTokens {
+		if seen.hash == hash {
+			return 0, fmt.Errorf("an API token already has that hash")
+		}
+	}
+	stored := &memAPIToken{token: *cloneAPIToken(t), hash: hash}
+	stored.token.ID = s.newID("api_tokens")
+	stored.token.Created = time.Now().UTC()
+	stored.token.LastUsed = nil
+	s.apiTokens[stored.token.ID] = stored
+	return stored.token.ID, nil
+}
+
+func (s *MemStorage) FetchAPITokensByUserID(ctx context.Context, userID int64) ([]*model.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	tokens := []*model.APIToken{}
+	for _, id := range slices.Sorted(maps.Keys(s.apiTokens)) {
+		if t := s.apiTokens[id]; t.token.UserID == userID {
+			tokens = append(tokens, cloneAPIToken(&t.token))
+		}
+	}
+	return tokens, nil
+}
+
+func (s *MemStorage) FetchAPITokenByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, t := range s.apiTokens {
+		if t.hash == hash {
+			return cloneAPIToken(&t.token), nil
+		}
+	}
+	return nil, he.New(404, fmt.Errorf("no such API token"))
+}
+
+func (s *MemStorage) TouchAPIToken(ctx context.Context, id int64, when time.Time) error {
+	when = when.UTC()
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.apiTokens[id]
+	if ok && (t.token.LastUsed == nil || t.token.LastUsed.Before(when.Add(-touchInterval))) {
+		t.token.LastUsed = &when
+	}
+	return nil
+}
+
+func (s *MemStorage) DeleteAPIToken(ctx context.Context, userID, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	t, ok := s.apiTokens[id]
+	if !ok || t.token.UserID != userID {
+		return he.New(404, fmt.Errorf("no such API token id %d", id))
+	}
+	delete(s.apiTokens, id)
+	return nil
+}
+
+// Webhooks.
+
+func cloneWebhookDelivery(d *model.WebhookDelivery) *model.WebhookDelivery {
+	cpy := *d
+	if d.Delivered != nil {
+		delivered := *d.Delivered
+		cpy.Delivered = &delivered
+	}
+	return &cpy
+}
+
+func (s *MemStorage) FetchWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	hooks := []*model.Webhook{}
+	for _, id := range slices.Sorted(maps.Keys(s.webhooks)) {
+		w := *s.webhooks[id]
+		w.Events = slices.Clone(w.Events)
+		hooks = append(hooks, &w)
+	}
+	return hooks, nil
+}
+
+func (s *MemStorage) CreateWebhook(ctx context.Context, w *model.Webhook) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored := *w
+	stored.ID = s.newID("webhooks")
+	stored.Events = slices.Clone(w.Events)
+	stored.Created = time.Now().UTC()
+	s.webhooks[stored.ID] = &stored
+	return stored.ID, nil
+}
+
+func (s *MemStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[id]; !ok {
+		return he.New(404, fmt.Errorf("no such webhook id %d", id))
+	}
+	delete(s.webhooks, id)
+	maps.DeleteFunc(s.deliveries, func(_ int64, d *model.WebhookDelivery) bool { return d.WebhookID == id })
+	return nil
+}
+
+func (s *MemStorage) EnqueueWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.webhooks[d.WebhookID]; !ok {
+		return false, fmt.Errorf("no such webhook id %d", d.WebhookID)
+	}
+	for _, seen := range s.deliveries {
+		if seen.WebhookID == d.WebhookID && seen.Key == d.Key {
+			return false, nil
+		}
+	}
+	stored := &model.WebhookDelivery{
+		ID:           s.newID("webhook_deliveries"),
+		WebhookID:    d.WebhookID,
+		TournamentID: d.TournamentID,
+		Event:        d.Event,
+		Key:          d.Key,
+		Payload:      d.Payload,
+		Status:       model.WebhookPending,
+		NextAttempt:  d.NextAttempt.UTC(),
+		Created:      d.Created.UTC(),
+	}
+	s.deliveries[stored.ID] = stored
+	return true, nil
+}
+
+func (s *MemStorage) ClaimWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	due := []*model.WebhookDelivery{}
+	for _, id := range slices.Sorted(maps.Keys(s.deliveries)) {
+		d := s.deliveries[id]
+		if d.Status == model.WebhookPending && !d.NextAttempt.After(now) {
+			due = append(due, d)
+		}
+	}
+	slices.SortStableFunc(due, func(a, b *model.WebhookDelivery) int { return a.NextAttempt.Compare(b.NextAttempt) })
+	if limit >= 0 && limit < len(due) {
+		due = due[:limit]
+	}
+
+	claimed := []*model.WebhookDelivery{}
+	for _, d := range due {
+		d.NextAttempt = until.UTC()
+		claimed = append(claimed, cloneWebhookDelivery(d))
+	}
+	return claimed, nil
+}
+
+func (s *MemStorage) SaveWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	stored, ok := s.deliveries[d.ID]
+	if !ok {
+		return he.New(404, fmt.Errorf("no such webhook delivery id %d", d.ID))
+	}
+	stored.Status = d.Status
+	stored.Attempts = d.Attempts
+	stored.NextAttempt = d.NextAttempt.UTC()
+	stored.LastStatus = d.LastStatus
+	stored.LastError = d.LastError
+	stored.Delivered = nil
+	if d.Delivered != nil {
+		delivered := d.Delivered.UTC()
+		stored.Delivered = &delivered
+	}
+	return nil
+}
+
+func (s *MemStorage) FetchWebhookDeliveries(ctx context.Context, limit int) ([]*model.WebhookDelivery, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids := slices.Sorted(maps.Keys(s.deliveries))
+	slices.Reverse(ids)
+	deliveries := []*model.WebhookDelivery{}
+	for _, id := range page(ids, 0, limit) {
+		deliveries = append(deliveries, cloneWebhookDelivery(s.deliveries[id]))
+	}
+	return deliveries, nil
+}
+
+func (s *MemStorage) RetryWebhookDelivery(ctx context.Context, id int64, now time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	d, ok := s.deliveries[id]
+	if !ok || d.Status != model.WebhookFailed {
+		return he.New(404, fmt.Errorf("no failed webhook delivery id %d", id))
+	}
+	d.Status = model.WebhookPending
+	d.NextAttempt = now.UTC()
+	return nil
+}
+
+func (s *MemStorage) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	n := len(s.deliveries)
+	maps.DeleteFunc(s.deliveries, func(_ int64, d *model.WebhookDelivery) bool {
+		return d.Status != model.WebhookPending && d.Created.Before(before)
+	})
+	return int64(n - len(s.deliveries)), nil
+}
@@ -0,0 +1,234 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ SlideStorage = &DBStorage{}
+
+func scanSlide(row rowScanner) (*model.Slide, error) {
+	var id, version int64
+	var bytes []byte
+	if err := row.Scan(&id, &version, &bytes); err != nil {
+		return nil, err
+	}
+	sl := &model.Slide{}
+	if err := json.Unmarshal(bytes, sl); err != nil {
+		return nil, fmt.Errorf("unmarshal slide %d: %w", id, err)
+	}
+	// These come from the database row, not the JSON.
+	sl.SlideID = id
+	sl.Version = version
+	return sl, nil
+}
+
+func (s *DBStorage) FetchSlides(ctx context.Context) ([]*model.Slide, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT slide_id, version, model_data FROM slides ORDER BY slide_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	slides := []*model.Slide{}
+	for rows.Next() {
+		sl, err := scanSlide(rows)
+		if err != nil {
+			return nil, err
+		}
+		slides = append(slides, sl)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return slides, nil
+}
+
+func (s *DBStorage) FetchSlide(ctx context.Context, id int64) (*model.Slide, error) {
+	sl, err := scanSlide(s.db.QueryRowContext(ctx, `SELECT slide_id, version, model_data FROM slides WHERE slide_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no such slide id %d", id))
+	} else if err != nil {
+		return nil, err
+	}
+	return sl, nil
+}
+
+func (s *DBStorage) CreateSlide(ctx context.Context, sl *model.Slide) (int64, error) {
+	bytes, err := json.Marshal(sl)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := s.db.QueryRowContext(ctx, `INSERT INTO slides (model_data) VALUES ($1) RETURNING slide_id`, bytes).Scan(&id); err != nil {
+		return 0, err
+	}
+	s.notify(ctx, "slides", id, 0)
+	return id, nil
+}
+
+func (s *DBStorage) SaveSlide(ctx context.Context, sl *model.Slide) error {
+	bytes, err := json.Marshal(sl)
+	if err != nil {
+		return err
+	}
+	newVersion := sl.Version + 1
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE slides SET version = $1, model_data = $2 WHERE slide_id = $3 AND version = $4`,
+		newVersion, bytes, sl.SlideID, sl.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return fmt.Errorf("optimistic lock failure, %d rows affected", n)
+	}
+	sl.Version = newVersion
+	s.notify(ctx, "slides", sl.SlideID, newVersion)
+	return nil
+}
+
+func (s *DBStorage) DeleteSlide(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM slides WHERE slide_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return he.New(404, fmt.Errorf("%d rows deleted", n))
+	}
+	s.notifyDeleted(ctx, "slides", id)
+	return nil
+}
+
+func (s *DBStorage) FetchSlideImage(ctx context.Context, id int64) (string, []byte, error) {
+	var contentType sql.NullString
+	var data []byte
+	err := s.db.QueryRowContext(ctx, `SELECT image_type, image_data FROM slides WHERE slide_id = $1`, id).Scan(&contentType, &data)
+	if err == sql.ErrNoRows || (err == nil && !contentType.Valid) {
+		return "", nil, he.New(404, fmt.Errorf("no image for slide id %d", id))
+	} else if err != nil {
+		return "", nil, err
+	}
+	return contentType.String, data, nil
+}
+
+// SaveSlideImage replaces the image for a slide, or removes it if contentType
+// is empty.  It doesn't touch the version; callers save the slide itself to
+// note whether the image is there.
+func (s *DBStorage) SaveSlideImage(ctx context.Context, id int64, contentType string, data []byte) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE slides SET image_type = NULLIF($1, ''), image_data = $2 WHERE slide_id = $3`,
+		contentType, data, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return he.New(404, fmt.Errorf("no such slide id %d", id))
+	}
+	return nil
+}
+
+func scanSlideSet(row rowScanner) (*model.SlideSet, error) {
+	var id, version int64
+	var bytes []byte
+	if err := row.Scan(&id, &version, &bytes); err != nil {
+		return nil, err
+	}
+	ss := &model.SlideSet{}
+	if err := json.Unmarshal(bytes, ss); err != nil {
+		return nil, fmt.Errorf("unmarshal slide set %d: %w", id, err)
+	}
+	ss.SlideSetID = id
+	ss.Version = version
+	return ss, nil
+}
+
+func (s *DBStorage) FetchSlideSets(ctx context.Context) ([]*model.SlideSet, error) {
+	rows, err := s.db.QueryContext(ctx, `SELECT slide_set_id, version, model_data FROM slide_sets ORDER BY slide_set_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	sets := []*model.SlideSet{}
+	for rows.Next() {
+		ss, err := scanSlideSet(rows)
+		if err != nil {
+			return nil, err
+		}
+		sets = append(sets, ss)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return sets, nil
+}
+
+func (s *DBStorage) FetchSlideSet(ctx context.Context, id int64) (*model.SlideSet, error) {
+	ss, err := scanSlideSet(s.db.QueryRowContext(ctx, `SELECT slide_set_id, version, model_data FROM slide_sets WHERE slide_set_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no such slide set id %d", id))
+	} else if err != nil {
+		return nil, err
+	}
+	return ss, nil
+}
+
+func (s *DBStorage) CreateSlideSet(ctx context.Context, ss *model.SlideSet) (int64, error) {
+	bytes, err := json.Marshal(ss)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := s.db.QueryRowContext(ctx, `INSERT INTO slide_sets (model_data) VALUES ($1) RETURNING slide_set_id`, bytes).Scan(&id); err != nil {
+		return 0, err
+	}
+	s.notify(ctx, "slide_sets", id, 0)
+	return id, nil
+}
+
+func (s *DBStorage) SaveSlideSet(ctx context.Context, ss *model.SlideSet) error {
+	bytes, err := json.Marshal(ss)
+	if err != nil {
+		return err
+	}
+	newVersion := ss.Version + 1
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE slide_sets SET version = $1, model_data = $2 WHERE slide_set_id = $3 AND version = $4`,
+		newVersion, bytes, ss.SlideSetID, ss.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return fmt.Errorf("optimistic lock failure, %d rows affected", n)
+	}
+	ss.Version = newVersion
+	s.notify(ctx, "slide_sets", ss.SlideSetID, newVersion)
+	return nil
+}
+
+func (s *DBStorage) DeleteSlideSet(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM slide_sets WHERE slide_set_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return he.New(404, fmt.Errorf("%d rows deleted", n))
+	}
+	s.notifyDeleted(ctx, "slide_sets", id)
+	return nil
+}
@@ -12,7 +12,9 @@ import (
 )
 
 type TournamentStorage interface {
-	FetchOverview(ctx context.Context, offset, limit int) (*model.Overview, error)
+	// FetchOverview lists tournaments in any of the given lifecycle states,
+	// or all of them if none are given.
+	FetchOverview(ctx context.Context, lifecycles []model.Lifecycle, offset, limit int) (*model.Overview, error)
 
 	CreateTournament(ctx context.Context, t *model.Tournament) (int64, error)
 	SaveTournament(ctx context.Context, m *model.Tournament) error
@@ -33,10 +35,10 @@ type AppStorage interface {
 	ListFooterPlugSets(ctx context.Context) ([]*model.FooterPlugs, error)
 
 	// Create a new footer plug set with a name and initial plugs.
-	CreateFooterPlugSet(ctx context.Context, name string, plugs []string) (int64, error)
+	CreateFooterPlugSet(ctx context.Context, name string, plugs []model.FooterPlug) (int64, error)
 
 	// Update the name and plugs of a footer plug set.
-	UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []string) error
+	UpdateFooterPlugSet(ctx context.Context, id int64, name string, plugs []model.FooterPlug) error
 
 	// Delete a footer plug set and all its plugs.
 	DeleteFooterPlugSet(ctx context.Context, id int64) error
@@ -82,3 +84,154 @@ type SoundEffectStorage interface {
 	FetchSoundEffectByID(ctx context.Context, id int64) (*soundmodel.SoundEffect, error)
 	FetchSoundEffectSlugs(ctx context.Context) ([]*soundmodel.SoundEffectSlug, error)
 }
+
+// DisplayStorage keeps track of kiosk displays.
+type DisplayStorage interface {
+	FetchDisplays(ctx context.Context) ([]*model.Display, error)
+	FetchDisplay(ctx context.Context, id int64) (*model.Display, error)
+	// FetchDisplayByDeviceID returns a 404 if the device has never
+	// registered.
+	FetchDisplayByDeviceID(ctx context.Context, deviceID string) (*model.Display, error)
+
+	// RegisterDisplay records a heartbeat from the display with the given
+	// device ID, creating it if it has never been seen before.
+	RegisterDisplay(ctx context.Context, deviceID, remoteAddr, userAgent string) (*model.Display, error)
+
+	SaveDisplay(ctx context.Context, d *model.Display) error
+	DeleteDisplay(ctx context.Context, id int64) error
+}
+
+// LayoutStorage keeps the clock display layouts.
+type LayoutStorage interface {
+	FetchLayouts(ctx context.Context) ([]*model.Layout, error)
+	FetchLayout(ctx context.Context, id int64) (*model.Layout, error)
+	CreateLayout(ctx context.Context, l *model.Layout) (int64, error)
+	SaveLayout(ctx context.Context, l *model.Layout) error
+	DeleteLayout(ctx context.Context, id int64) error
+}
+
+// SlideStorage keeps the slide library and the slide sets built from it.
+// Uploaded images are kept apart from the slides, since they're big and
+// nothing but the image handler wants them.
+type SlideStorage interface {
+	FetchSlides(ctx context.Context) ([]*model.Slide, error)
+	FetchSlide(ctx context.Context, id int64) (*model.Slide, error)
+	CreateSlide(ctx context.Context, s *model.Slide) (int64, error)
+	SaveSlide(ctx context.Context, s *model.Slide) error
+	DeleteSlide(ctx context.Context, id int64) error
+	FetchSlideImage(ctx context.Context, id int64) (contentType string, data []byte, err error)
+	SaveSlideImage(ctx context.Context, id int64, contentType string, data []byte) error
+
+	FetchSlideSets(ctx context.Context) ([]*model.SlideSet, error)
+	FetchSlideSet(ctx context.Context, id int64) (*model.SlideSet, error)
+	CreateSlideSet(ctx context.Context, ss *model.SlideSet) (int64, error)
+	SaveSlideSet(ctx context.Context, ss *model.SlideSet) error
+	DeleteSlideSet(ctx context.Context, id int64) error
+}
+
+// AnnouncementStorage keeps operator announcements.  Expired announcements
+// are left in place; nothing shows them, and the operator can clear them out.
+type AnnouncementStorage interface {
+	// FetchAnnouncements fetches the announcements that expire after the
+	// given time.  Pass the zero time to fetch them all.
+	FetchAnnouncements(ctx context.Context, expiringAfter time.Time) ([]*model.Announcement, error)
+	FetchAnnouncement(ctx context.Context, id int64) (*model.Announcement, error)
+	CreateAnnouncement(ctx context.Context, a *model.Announcement) (int64, error)
+	SaveAnnouncement(ctx context.Context, a *model.Announcement) error
+	DeleteAnnouncement(ctx context.Context, id int64) error
+}
+
+// LeagueStorage keeps leagues.  The tournaments in a league say so
+// themselves, in LeagueID.
+type LeagueStorage interface {
+	FetchLeagues(ctx context.Context) ([]*model.League, error)
+	FetchLeague(ctx context.Context, id int64) (*model.League, error)
+	CreateLeague(ctx context.Context, l *model.League) (int64, error)
+	SaveLeague(ctx context.Context, l *model.League) error
+	DeleteLeague(ctx context.Context, id int64) error
+	// FetchLeagueTournaments fetches every tournament in the league.
+	FetchLeagueTournaments(ctx context.Context, id int64) ([]*model.Tournament, error)
+}
+
+// TournamentTemplateStorage keeps the templates recurring tournaments are
+// made from.
+type TournamentTemplateStorage interface {
+	FetchTournamentTemplates(ctx context.Context) ([]*model.TournamentTemplate, error)
+	FetchTournamentTemplate(ctx context.Context, id int64) (*model.TournamentTemplate, error)
+	CreateTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) (int64, error)
+	SaveTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) error
+	DeleteTournamentTemplate(ctx context.Context, id int64) error
+}
+
+// CertCacheStorage keeps what the ACME client wants kept: certificates and
+// account keys, by name.
+type CertCacheStorage interface {
+	// FetchCertCache returns ErrNotCached if there's nothing under key.
+	FetchCertCache(ctx context.Context, key string) ([]byte, error)
+	SaveCertCache(ctx context.Context, key string, data []byte) error
+	DeleteCertCache(ctx context.Context, key string) error
+}
+
+// APITokenStorage keeps the tokens programs use with the REST API.  Tokens
+// are found by a hash of their secret, which is all that's stored.
+type APITokenStorage interface {
+	CreateAPIToken(ctx context.Context, t *model.APIToken, hash string) (int64, error)
+	FetchAPITokensByUserID(ctx context.Context, userID int64) ([]*model.APIToken, error)
+	FetchAPITokenByHash(ctx context.Context, hash string) (*model.APIToken, error)
+	// TouchAPIToken notes that a token was used.
+	TouchAPIToken(ctx context.Context, id int64, when time.Time) error
+	DeleteAPIToken(ctx context.Context, userID, id int64) error
+}
+
+// WebhookStorage keeps webhooks, and the outbox of deliveries to them,
+// which is also their log.
+type WebhookStorage interface {
+	FetchWebhooks(ctx context.Context) ([]*model.Webhook, error)
+	CreateWebhook(ctx context.Context, w *model.Webhook) (int64, error)
+	DeleteWebhook(ctx context.Context, id int64) error
+	// EnqueueWebhookDelivery puts d in the outbox, unless its webhook already
+	// has a delivery with the same key, and says whether it did.
+	EnqueueWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) (bool, error)
+	// ClaimWebhookDeliveries returns up to limit pending deliveries due by
+	// now, putting their next attempts off until until so no one else
+	// tries them meanwhile.
+	ClaimWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]*model.WebhookDelivery, error)
+	// SaveWebhookDelivery records how an attempt went.
+	SaveWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) error
+	// FetchWebhookDeliveries returns the newest deliveries first.
+	FetchWebhookDeliveries(ctx context.Context, limit int) ([]*model.WebhookDelivery, error)
+	// RetryWebhookDelivery makes a failed delivery pending again, due at now.
+	RetryWebhookDelivery(ctx context.Context, id int64, now time.Time) error
+	// PruneWebhookDeliveries forgets finished deliveries made before before.
+	PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error)
+}
+
+// HealthStorage is what a health check asks of storage.
+type HealthStorage interface {
+	// Ping says whether the database can be reached.
+	Ping(ctx context.Context) error
+}
+
+// Storage is everything kept, all in one place: what DBStorage and
+// MemStorage are.
+type Storage interface {
+	AppStorage
+	SiteStorage
+	UserStorage
+	TournamentStorage
+	DisplayStorage
+	LayoutStorage
+	SlideStorage
+	AnnouncementStorage
+	LeagueStorage
+	TournamentTemplateStorage
+	CertCacheStorage
+	APITokenStorage
+	WebhookStorage
+	HealthStorage
+
+	// SetNotifier sets the Notifier told about changes that nothing else
+	// would announce.
+	SetNotifier(n Notifier)
+	Close()
+}
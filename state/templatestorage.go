@@ -0,0 +1,112 @@
+package state
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ TournamentTemplateStorage = &DBStorage{}
+
+func scanTournamentTemplate(row rowScanner) (*model.TournamentTemplate, error) {
+	var id, version int64
+	var bytes []byte
+	if err := row.Scan(&id, &version, &bytes); err != nil {
+		return nil, err
+	}
+	tt := &model.TournamentTemplate{}
+	if err := json.Unmarshal(bytes, tt); err != nil {
+		return nil, fmt.Errorf("unmarshal tournament template %d: %w", id, err)
+	}
+	tt.TournamentTemplateID = id
+	tt.Version = version
+	return tt, nil
+}
+
+func (s *DBStorage) FetchTournamentTemplates(ctx context.Context) ([]*model.TournamentTemplate, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT tournament_template_id, version, model_data FROM tournament_templates ORDER BY name, tournament_template_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	templates := []*model.TournamentTemplate{}
+	for rows.Next() {
+		tt, err := scanTournamentTemplate(rows)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, tt)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return templates, nil
+}
+
+func (s *DBStorage) FetchTournamentTemplate(ctx context.Context, id int64) (*model.TournamentTemplate, error) {
+	tt, err := scanTournamentTemplate(s.db.QueryRowContext(ctx,
+		`SELECT tournament_template_id, version, model_data FROM tournament_templates WHERE tournament_template_id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, he.New(404, fmt.Errorf("no such tournament template id %d", id))
+	} else if err != nil {
+		return nil, err
+	}
+	return tt, nil
+}
+
+func (s *DBStorage) CreateTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) (int64, error) {
+	bytes, err := json.Marshal(tt)
+	if err != nil {
+		return 0, err
+	}
+	var id int64
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO tournament_templates (name, model_data) VALUES ($1, $2) RETURNING tournament_template_id`,
+		tt.Name, bytes).Scan(&id); err != nil {
+		return 0, err
+	}
+	s.notify(ctx, "tournament_templates", id, 0)
+	return id, nil
+}
+
+func (s *DBStorage) SaveTournamentTemplate(ctx context.Context, tt *model.TournamentTemplate) error {
+	bytes, err := json.Marshal(tt)
+	if err != nil {
+		return err
+	}
+	newVersion := tt.Version + 1
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE tournament_templates SET version = $1, name = $2, model_data = $3 WHERE tournament_template_id = $4 AND version = $5`,
+		newVersion, tt.Name, bytes, tt.TournamentTemplateID, tt.Version)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return fmt.Errorf("optimistic lock failure, %d rows affected", n)
+	}
+	tt.Version = newVersion
+	s.notify(ctx, "tournament_templates", tt.TournamentTemplateID, newVersion)
+	return nil
+}
+
+func (s *DBStorage) DeleteTournamentTemplate(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM tournament_templates WHERE tournament_template_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n != 1 {
+		return he.New(404, fmt.Errorf("%d rows deleted", n))
+	}
+	s.notifyDeleted(ctx, "tournament_templates", id)
+	return nil
+}
@@ -0,0 +1,207 @@
+package state
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+var _ WebhookStorage = &DBStorage{}
+
+func (s *DBStorage) FetchWebhooks(ctx context.Context) ([]*model.Webhook, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT webhook_id, tournament_id, url, secret, events, created FROM webhooks ORDER BY webhook_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	hooks := []*model.Webhook{}
+	for rows.Next() {
+		w := &model.Webhook{}
+		var tournamentID *int64
+		var events string
+		if err := rows.Scan(&w.ID, &tournamentID, &w.URL, &w.Secret, &events, &w.Created); err != nil {
+			return nil, err
+		}
+		if tournamentID != nil {
+			w.TournamentID = *tournamentID
+		}
+		if events != "" {
+			w.Events = strings.Split(events, ",")
+		}
+		hooks = append(hooks, w)
+	}
+	return hooks, rows.Err()
+}
+
+func (s *DBStorage) CreateWebhook(ctx context.Context, w *model.Webhook) (int64, error) {
+	var tournamentID *int64
+	if w.TournamentID != 0 {
+		tournamentID = &w.TournamentID
+	}
+	var id int64
+	if err := s.db.QueryRowContext(ctx,
+		`INSERT INTO webhooks (tournament_id, url, secret, events, created) VALUES ($1, $2, $3, $4, $5)
+		 RETURNING webhook_id`,
+		tournamentID, w.URL, w.Secret, strings.Join(w.Events, ","), time.Now().UTC()).Scan(&id); err != nil {
+		return 0, err
+	}
+	return id, nil
+}
+
+func (s *DBStorage) DeleteWebhook(ctx context.Context, id int64) error {
+	result, err := s.db.ExecContext(ctx, `DELETE FROM webhooks WHERE webhook_id = $1`, id)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return he.New(404, fmt.Errorf("no such webhook id %d", id))
+	}
+	return nil
+}
+
+func (s *DBStorage) EnqueueWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) (bool, error) {
+	result, err := s.db.ExecContext(ctx,
+		`INSERT INTO webhook_deliveries (webhook_id, tournament_id, event, dedupe_key, payload, status, next_attempt, created)
+		 VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		 ON CONFLICT (webhook_id, dedupe_key) DO NOTHING`,
+		d.WebhookID, d.TournamentID, d.Event, d.Key, d.Payload, model.WebhookPending, d.NextAttempt.UTC(), d.Created.UTC())
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n == 1, err
+}
+
+const webhookDeliveryColumns = `delivery_id, webhook_id, tournament_id, event, dedupe_key, payload, status,
+	attempts, next_attempt, last_status, last_error, created, delivered`
+
+func scanWebhookDelivery(row interface{ Scan(...any) error }) (*model.WebhookDelivery, error) {
+	d := &model.WebhookDelivery{}
+	if err := row.Scan(&d.ID, &d.WebhookID, &d.TournamentID, &d.Event, &d.Key, &d.Payload, &d.Status,
+		&d.Attempts, &d.NextAttempt, &d.LastStatus, &d.LastError, &d.Created, &d.Delivered); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+func (s *DBStorage) ClaimWebhookDeliveries(ctx context.Context, now, until time.Time, limit int) ([]*model.WebhookDelivery, error) {
+	now, until = now.UTC(), until.UTC()
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT delivery_id FROM webhook_deliveries WHERE status = $1 AND next_attempt <= $2
+		 ORDER BY next_attempt LIMIT $3`,
+		model.WebhookPending, now, limit)
+	if err != nil {
+		return nil, err
+	}
+	ids := []int64{}
+	for rows.Next() {
+		var id int64
+		if err := rows.Scan(&id); err != nil {
+			rows.Close()
+			return nil, err
+		}
+		ids = append(ids, id)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	// Another server may be looking at the same rows; whoever moves
+	// next_attempt first has the delivery.
+	claimed := []*model.WebhookDelivery{}
+	for _, id := range ids {
+		result, err := s.db.ExecContext(ctx,
+			`UPDATE webhook_deliveries SET next_attempt = $1
+			 WHERE delivery_id = $2 AND status = $3 AND next_attempt <= $4`,
+			until, id, model.WebhookPending, now)
+		if err != nil {
+			return nil, err
+		}
+		if n, err := result.RowsAffected(); err != nil {
+			return nil, err
+		} else if n != 1 {
+			continue
+		}
+		d, err := scanWebhookDelivery(s.db.QueryRowContext(ctx,
+			`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries WHERE delivery_id = $1`, id))
+		if err != nil {
+			return nil, err
+		}
+		claimed = append(claimed, d)
+	}
+	return claimed, nil
+}
+
+func (s *DBStorage) SaveWebhookDelivery(ctx context.Context, d *model.WebhookDelivery) error {
+	var delivered *time.Time
+	if d.Delivered != nil {
+		t := d.Delivered.UTC()
+		delivered = &t
+	}
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = $1, attempts = $2, next_attempt = $3, last_status = $4, last_error = $5, delivered = $6
+		 WHERE delivery_id = $7`,
+		d.Status, d.Attempts, d.NextAttempt.UTC(), d.LastStatus, d.LastError, delivered, d.ID)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return he.New(404, fmt.Errorf("no such webhook delivery id %d", d.ID))
+	}
+	return nil
+}
+
+func (s *DBStorage) FetchWebhookDeliveries(ctx context.Context, limit int) ([]*model.WebhookDelivery, error) {
+	rows, err := s.db.QueryContext(ctx,
+		`SELECT `+webhookDeliveryColumns+` FROM webhook_deliveries ORDER BY delivery_id DESC LIMIT $1`, limit)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*model.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanWebhookDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+func (s *DBStorage) RetryWebhookDelivery(ctx context.Context, id int64, now time.Time) error {
+	result, err := s.db.ExecContext(ctx,
+		`UPDATE webhook_deliveries SET status = $1, next_attempt = $2 WHERE delivery_id = $3 AND status = $4`,
+		model.WebhookPending, now.UTC(), id, model.WebhookFailed)
+	if err != nil {
+		return err
+	}
+	if n, err := result.RowsAffected(); err != nil {
+		return err
+	} else if n == 0 {
+		return he.New(404, fmt.Errorf("no failed webhook delivery id %d", id))
+	}
+	return nil
+}
+
+func (s *DBStorage) PruneWebhookDeliveries(ctx context.Context, before time.Time) (int64, error) {
+	result, err := s.db.ExecContext(ctx,
+		`DELETE FROM webhook_deliveries WHERE status <> $1 AND created < $2`,
+		model.WebhookPending, before.UTC())
+	if err != nil {
+		return 0, err
+	}
+	return result.RowsAffected()
+}
@@ -3,6 +3,7 @@ package textutil
 import (
 	"errors"
 	"fmt"
+	"math"
 	"regexp"
 	"strconv"
 	"strings"
@@ -103,3 +104,38 @@ var enPrinter = message.NewPrinter(language.English)
 func FormatDollars(amount int) string {
 	return enPrinter.Sprintf("$%d", amount)
 }
+
+// FormatCommas formats an integer with thousands separators, e.g. 31337 -> "31,337".
+func FormatCommas(n int) string {
+	return enPrinter.Sprintf("%d", n)
+}
+
+// ParseChips parses a chip count as a floor person might type it on a phone:
+// "31337", "31,337", "31.5k" or "1.2m".
+func ParseChips(s string) (int, error) {
+	s = strings.ToLower(strings.TrimSpace(strings.ReplaceAll(s, ",", "")))
+	mult := 1.0
+	switch {
+	case strings.HasSuffix(s, "k"):
+		mult = 1_000
+		s = strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		mult = 1_000_000
+		s = strings.TrimSuffix(s, "m")
+	}
+	f, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+	if err != nil {
+		return 0, errors.New("can't parse chip count")
+	}
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, errors.New("can't parse chip count")
+	}
+	if f < 0 {
+		return 0, errors.New("chip count can't be negative")
+	}
+	// More chips than that is a typo, and would overflow.
+	if f*mult > math.MaxInt32 {
+		return 0, errors.New("chip count is too big")
+	}
+	return int(f*mult + 0.5), nil
+}
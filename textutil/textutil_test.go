@@ -166,3 +166,31 @@ func TestWrapAttributionInNobr(t *testing.T) {
 		})
 	}
 }
+
+func TestParseChips(t *testing.T) {
+	tests := []struct {
+		input string
+		want  int
+	}{
+		{"31337", 31337},
+		{"31,337", 31337},
+		{" 25k ", 25000},
+		{"31.5K", 31500},
+		{"1.2m", 1200000},
+		{"0", 0},
+	}
+	for _, tt := range tests {
+		got, err := ParseChips(tt.input)
+		if err != nil {
+			t.Errorf("ParseChips(%q) returned error: %v", tt.input, err)
+		} else if got != tt.want {
+			t.Errorf("ParseChips(%q) = %d, want %d", tt.input, got, tt.want)
+		}
+	}
+
+	for _, bad := range []string{"", "lots", "-5", "k", "nan", "NaN", "inf", "+Inf", "-inf", "1e30", "3000m"} {
+		if _, err := ParseChips(bad); err == nil {
+			t.Errorf("ParseChips(%q) should have failed", bad)
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package tournament
+
+import (
+	"errors"
+	"math"
+	"slices"
+	"strings"
+
+	"github.com/ts4z/irata/model"
+)
+
+// ChipLeadersShown is how many stacks make the leaderboard.
+const ChipLeadersShown = 10
+
+// SetChipCount records a counted stack.  Names are matched without regard to
+// case or surrounding whitespace, so "Table 3" and "table 3 " are the same
+// count.  A count of zero means the player (or table) is gone, and removes
+// the entry.
+func (tm *Manager) SetChipCount(m *model.Tournament, name string, chips int) error {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return errors.New("chip count needs a name")
+	}
+	if chips < 0 {
+		return errors.New("chip count can't be negative")
+	}
+
+	i := findChipCount(m.State.ChipCounts, name)
+	if chips == 0 {
+		if i >= 0 {
+			m.State.ChipCounts = slices.Delete(m.State.ChipCounts, i, i+1)
+		}
+		return nil
+	}
+
+	now := tm.clock.Now().UnixMilli()
+	if i >= 0 {
+		m.State.ChipCounts[i].Name = name
+		m.State.ChipCounts[i].Chips = chips
+		m.State.ChipCounts[i].CountedAt = now
+	} else {
+		m.State.ChipCounts = append(m.State.ChipCounts, &model.ChipCount{
+			Name:      name,
+			Chips:     chips,
+			CountedAt: now,
+		})
+	}
+	return nil
+}
+
+// ClearChipCounts throws away all the counts, say, at the start of a new day.
+func (tm *Manager) ClearChipCounts(m *model.Tournament) {
+	m.State.ChipCounts = nil
+}
+
+func findChipCount(counts []*model.ChipCount, name string) int {
+	return slices.IndexFunc(counts, func(cc *model.ChipCount) bool {
+		return strings.EqualFold(cc.Name, name)
+	})
+}
+
+// fillChipCountTransients computes the leaderboard and summary stats.
+func fillChipCountTransients(m *model.Tournament) {
+	counts := m.State.ChipCounts
+	if len(counts) == 0 {
+		return
+	}
+
+	sorted := slices.Clone(counts)
+	slices.SortStableFunc(sorted, func(a, b *model.ChipCount) int {
+		return b.Chips - a.Chips
+	})
+
+	leaders := make([]*model.ChipCount, 0, min(len(sorted), ChipLeadersShown))
+	for _, cc := range sorted[:min(len(sorted), ChipLeadersShown)] {
+		c := *cc
+		leaders = append(leaders, &c)
+	}
+	m.Transients.ChipLeaders = leaders
+
+	total := 0
+	for _, cc := range sorted {
+		total += cc.Chips
+		m.Transients.ChipsCountedAt = max(m.Transients.ChipsCountedAt, cc.CountedAt)
+	}
+	m.Transients.CountedAverageChips = int(math.Round(float64(total) / float64(len(sorted))))
+
+	// sorted is descending, but the median doesn't care.
+	mid := len(sorted) / 2
+	if len(sorted)%2 == 1 {
+		m.Transients.MedianChips = sorted[mid].Chips
+	} else {
+		m.Transients.MedianChips = int(math.Round(float64(sorted[mid-1].Chips+sorted[mid].Chips) / 2))
+	}
+}
@@ -0,0 +1,131 @@
+package tournament
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/ts4z/irata/model"
+)
+
+func newChipCountTournament() *model.Tournament {
+	return &model.Tournament{
+		NextLevelSoundID: -1, // no sound fetcher here
+		Structure: model.StructureData{
+			Levels: []*model.Level{{DurationMinutes: 20}},
+		},
+		State: &model.State{CurrentPlayers: 4},
+	}
+}
+
+func TestSetChipCountUpserts(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.UnixMilli(1_000_000))
+	tm := NewManager(clock, nil, nil)
+	m := newChipCountTournament()
+
+	if err := tm.SetChipCount(m, "Alice", 10000); err != nil {
+		t.Fatal(err)
+	}
+	clock.Advance(time.Minute)
+	if err := tm.SetChipCount(m, " alice ", 12000); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.State.ChipCounts) != 1 {
+		t.Fatalf("got %d counts, want 1", len(m.State.ChipCounts))
+	}
+	cc := m.State.ChipCounts[0]
+	if cc.Chips != 12000 {
+		t.Errorf("chips = %d, want 12000", cc.Chips)
+	}
+	if cc.CountedAt != 1_060_000 {
+		t.Errorf("counted at %d, want 1060000", cc.CountedAt)
+	}
+}
+
+func TestSetChipCountZeroRemoves(t *testing.T) {
+	tm := NewManager(clockwork.NewFakeClock(), nil, nil)
+	m := newChipCountTournament()
+
+	tm.SetChipCount(m, "Alice", 10000)
+	tm.SetChipCount(m, "Bob", 5000)
+	if err := tm.SetChipCount(m, "ALICE", 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if len(m.State.ChipCounts) != 1 || m.State.ChipCounts[0].Name != "Bob" {
+		t.Errorf("expected only Bob left, got %+v", m.State.ChipCounts)
+	}
+}
+
+func TestSetChipCountErrors(t *testing.T) {
+	tm := NewManager(clockwork.NewFakeClock(), nil, nil)
+	m := newChipCountTournament()
+
+	if err := tm.SetChipCount(m, "  ", 100); err == nil {
+		t.Error("expected error for empty name")
+	}
+	if err := tm.SetChipCount(m, "Alice", -1); err == nil {
+		t.Error("expected error for negative chips")
+	}
+}
+
+func TestChipCountTransients(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(time.UnixMilli(5_000))
+	tm := NewManager(clock, nil, nil)
+	m := newChipCountTournament()
+
+	tm.SetChipCount(m, "Carol", 3000)
+	tm.SetChipCount(m, "Alice", 10000)
+	clock.Advance(time.Second)
+	tm.SetChipCount(m, "Dave", 1000)
+	tm.SetChipCount(m, "Bob", 6000)
+
+	tm.FillTransientsAndAdvanceClock(context.Background(), m)
+	tr := m.Transients
+
+	wantOrder := []string{"Alice", "Bob", "Carol", "Dave"}
+	if len(tr.ChipLeaders) != len(wantOrder) {
+		t.Fatalf("got %d leaders, want %d", len(tr.ChipLeaders), len(wantOrder))
+	}
+	for i, name := range wantOrder {
+		if tr.ChipLeaders[i].Name != name {
+			t.Errorf("leader %d = %s, want %s", i, tr.ChipLeaders[i].Name, name)
+		}
+	}
+
+	// (10000 + 6000 + 3000 + 1000) / 4 = 5000
+	if tr.CountedAverageChips != 5000 {
+		t.Errorf("average = %d, want 5000", tr.CountedAverageChips)
+	}
+	// (6000 + 3000) / 2 = 4500
+	if tr.MedianChips != 4500 {
+		t.Errorf("median = %d, want 4500", tr.MedianChips)
+	}
+	if tr.ChipsCountedAt != 6_000 {
+		t.Errorf("counted at = %d, want 6000", tr.ChipsCountedAt)
+	}
+
+	// Odd count: median is the middle stack.
+	tm.SetChipCount(m, "Dave", 0)
+	tm.FillTransientsAndAdvanceClock(context.Background(), m)
+	if m.Transients.MedianChips != 6000 {
+		t.Errorf("median = %d, want 6000", m.Transients.MedianChips)
+	}
+}
+
+func TestChipLeadersAreLimited(t *testing.T) {
+	tm := NewManager(clockwork.NewFakeClock(), nil, nil)
+	m := newChipCountTournament()
+
+	for i := range ChipLeadersShown + 5 {
+		tm.SetChipCount(m, string(rune('A'+i)), 1000*(i+1))
+	}
+
+	tm.FillTransientsAndAdvanceClock(context.Background(), m)
+	if len(m.Transients.ChipLeaders) != ChipLeadersShown {
+		t.Errorf("got %d leaders, want %d", len(m.Transients.ChipLeaders), ChipLeadersShown)
+	}
+}
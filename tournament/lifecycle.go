@@ -0,0 +1,202 @@
+package tournament
+
+import (
+	"context"
+	"slices"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+)
+
+const (
+	// RegistrationOpens is how long before its scheduled start a scheduled
+	// tournament starts registering players.
+	RegistrationOpens = 2 * time.Hour
+
+	// FinalTablePlayers is how few players make a final table, when the
+	// seating chart doesn't say.
+	FinalTablePlayers = 9
+)
+
+type LifecycleChoice struct {
+	Lifecycle   model.Lifecycle
+	Description string
+	// Manual is set for the states an operator may choose.  The others
+	// follow from the clock and the player count.
+	Manual bool
+}
+
+// Lifecycles are all the lifecycle states, in order.
+var Lifecycles = []LifecycleChoice{
+	{model.LifecycleScheduled, "Scheduled", true},
+	{model.LifecycleRegistering, "Registering", true},
+	{model.LifecycleRunning, "Running", true},
+	{model.LifecycleOnBreak, "On Break", false},
+	{model.LifecycleFinalTable, "Final Table", false},
+	{model.LifecycleFinished, "Finished", true},
+	{model.LifecycleArchived, "Archived", false},
+}
+
+var (
+	// LiveLifecycles are the states of a tournament in progress.
+	LiveLifecycles = []model.Lifecycle{model.LifecycleRegistering, model.LifecycleRunning, model.LifecycleOnBreak, model.LifecycleFinalTable}
+
+	// ActiveLifecycles are the states of a tournament that isn't over.
+	ActiveLifecycles = append([]model.Lifecycle{model.LifecycleScheduled}, LiveLifecycles...)
+
+	// CurrentLifecycles are the states of a tournament that hasn't been
+	// put away.
+	CurrentLifecycles = append(slices.Clone(ActiveLifecycles), model.LifecycleFinished)
+)
+
+// DescribeLifecycle names a lifecycle state for people.
+func DescribeLifecycle(l model.Lifecycle) string {
+	for _, c := range Lifecycles {
+		if c.Lifecycle == l {
+			return c.Description
+		}
+	}
+	return string(l)
+}
+
+func isRunningLifecycle(l model.Lifecycle) bool {
+	return l == model.LifecycleRunning || l == model.LifecycleOnBreak || l == model.LifecycleFinalTable
+}
+
+// hasStarted says whether the clock has ever run, near enough.
+func hasStarted(m *model.Tournament) bool {
+	if m.State.IsClockRunning || m.State.CurrentLevelNumber > 0 {
+		return true
+	}
+	lvl := m.CurrentLevel()
+	return lvl != nil && m.State.TimeRemainingMillis != nil &&
+		*m.State.TimeRemainingMillis < (time.Duration(lvl.DurationMinutes)*time.Minute).Milliseconds()
+}
+
+// atEndOfTime says whether the tournament has run off the end of its
+// structure; see endOfTime.
+func atEndOfTime(m *model.Tournament) bool {
+	return !m.State.IsClockRunning &&
+		len(m.Structure.Levels) > 0 &&
+		m.State.CurrentLevelNumber == len(m.Structure.Levels)-1 &&
+		m.State.TimeRemainingMillis != nil && *m.State.TimeRemainingMillis == 0
+}
+
+// atFinalTable says whether everyone left fits at one table.
+func atFinalTable(m *model.Tournament) bool {
+	if m.State.CurrentPlayers <= 1 {
+		return false
+	}
+	if len(m.State.Seating) > 0 {
+		return !slices.ContainsFunc(m.State.Seating, func(s *model.Seat) bool {
+			return s.Table != m.State.Seating[0].Table
+		})
+	}
+	return m.State.CurrentPlayers <= FinalTablePlayers
+}
+
+// LifecycleOf is m's lifecycle state.  Tournaments from before lifecycles
+// get one from their clock.
+func LifecycleOf(m *model.Tournament) model.Lifecycle {
+	if m.State.Lifecycle != "" {
+		return m.State.Lifecycle
+	}
+	if hasStarted(m) {
+		return model.LifecycleRunning
+	}
+	return model.LifecycleScheduled
+}
+
+// NextLifecycle is where m's lifecycle goes on its own: scheduled tournaments
+// open registration shortly before they start, and start running when the
+// clock does; running tournaments go on break with the structure, reach the
+// final table, and finish when one player is left or the levels run out.
+// Finished and archived tournaments stay put.
+func NextLifecycle(m *model.Tournament, now time.Time) model.Lifecycle {
+	l := LifecycleOf(m)
+	switch {
+	case l == model.LifecycleScheduled && hasStarted(m):
+		l = model.LifecycleRunning
+	case l == model.LifecycleScheduled && !m.ScheduledStart.IsZero() && !now.Before(m.ScheduledStart.Add(-RegistrationOpens)):
+		return model.LifecycleRegistering
+	case l == model.LifecycleRegistering && hasStarted(m):
+		l = model.LifecycleRunning
+	}
+	if !isRunningLifecycle(l) {
+		return l
+	}
+
+	switch lvl := m.CurrentLevel(); {
+	case atEndOfTime(m):
+		return model.LifecycleFinished
+	case m.State.CurrentPlayers == 1:
+		return model.LifecycleFinished
+	case lvl != nil && lvl.IsBreak:
+		return model.LifecycleOnBreak
+	case atFinalTable(m):
+		return model.LifecycleFinalTable
+	default:
+		return model.LifecycleRunning
+	}
+}
+
+func (tm *Manager) setLifecycle(m *model.Tournament, l model.Lifecycle) {
+	if l == model.LifecycleFinished && m.State.Lifecycle != model.LifecycleFinished {
+		m.State.FinishedAt = tm.clock.Now()
+	} else if l != model.LifecycleFinished && l != model.LifecycleArchived {
+		m.State.FinishedAt = time.Time{}
+	}
+	m.State.Lifecycle = l
+}
+
+// UpdateLifecycle moves m along its lifecycle, returning whether it moved.
+func (tm *Manager) UpdateLifecycle(m *model.Tournament) bool {
+	old := m.State.Lifecycle
+	if next := NextLifecycle(m, tm.clock.Now()); next != old {
+		tm.setLifecycle(m, next)
+	}
+	return m.State.Lifecycle != old
+}
+
+// AdvanceLifecycle brings m up to date with the clock, and then moves it
+// along its lifecycle, returning whether it moved.
+func (tm *Manager) AdvanceLifecycle(ctx context.Context, m *model.Tournament) bool {
+	old := m.State.Lifecycle
+	tm.FillTransientsAndAdvanceClock(ctx, m)
+	return m.State.Lifecycle != old
+}
+
+// SetLifecycle is for an operator to choose m's lifecycle state.  Only the
+// manual states can be chosen, and the archive has its own door.
+func (tm *Manager) SetLifecycle(m *model.Tournament, l model.Lifecycle) error {
+	i := slices.IndexFunc(Lifecycles, func(c LifecycleChoice) bool { return c.Lifecycle == l })
+	if i < 0 || !Lifecycles[i].Manual {
+		return he.HTTPCodedErrorf(400, "can't set tournament to %q", l)
+	}
+	if LifecycleOf(m) == model.LifecycleArchived {
+		return he.HTTPCodedErrorf(409, "tournament is archived")
+	}
+	tm.setLifecycle(m, l)
+	return nil
+}
+
+// Archive puts a finished tournament away.  Archived tournaments can't be
+// changed, except to take them back out with Unarchive.
+func (tm *Manager) Archive(m *model.Tournament) error {
+	if l := LifecycleOf(m); l != model.LifecycleFinished {
+		return he.HTTPCodedErrorf(409, "only finished tournaments can be archived, not %s ones", DescribeLifecycle(l))
+	}
+	m.State.IsClockRunning = false
+	tm.setLifecycle(m, model.LifecycleArchived)
+	return nil
+}
+
+// Unarchive takes a tournament back out of the archive, finished.
+func (tm *Manager) Unarchive(m *model.Tournament) error {
+	if l := LifecycleOf(m); l != model.LifecycleArchived {
+		return he.HTTPCodedErrorf(409, "tournament is %s, not archived", DescribeLifecycle(l))
+	}
+	m.State.Lifecycle = model.LifecycleFinished
+	return nil
+}
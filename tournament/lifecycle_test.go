@@ -0,0 +1,137 @@
+package tournament
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/ts4z/irata/model"
+)
+
+var lifecycleT0 = time.Date(2026, 6, 2, 19, 0, 0, 0, time.UTC)
+
+func newLifecycleTournament() *model.Tournament {
+	full := (20 * time.Minute).Milliseconds()
+	return &model.Tournament{
+		NextLevelSoundID: -1,
+		Structure: model.StructureData{
+			Levels: []*model.Level{{DurationMinutes: 20}, {DurationMinutes: 10, IsBreak: true}, {DurationMinutes: 20}},
+		},
+		State: &model.State{CurrentPlayers: 30, TimeRemainingMillis: &full},
+	}
+}
+
+func TestNextLifecycle(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		edit func(m *model.Tournament)
+		want model.Lifecycle
+	}{
+		{"new", func(m *model.Tournament) {}, model.LifecycleScheduled},
+		{"far off", func(m *model.Tournament) { m.ScheduledStart = lifecycleT0.Add(3 * time.Hour) }, model.LifecycleScheduled},
+		{"registration open", func(m *model.Tournament) { m.ScheduledStart = lifecycleT0.Add(time.Hour) }, model.LifecycleRegistering},
+		{"clock started", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleRegistering
+			m.State.IsClockRunning = true
+		}, model.LifecycleRunning},
+		{"old and started", func(m *model.Tournament) { m.State.CurrentLevelNumber = 2 }, model.LifecycleRunning},
+		{"break", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleRunning
+			m.State.CurrentLevelNumber = 1
+		}, model.LifecycleOnBreak},
+		{"final table by count", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleRunning
+			m.State.CurrentPlayers = 9
+		}, model.LifecycleFinalTable},
+		{"not final table by seating", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleRunning
+			m.State.CurrentPlayers = 8
+			m.State.Seating = []*model.Seat{{Table: 1, Seat: 1}, {Table: 2, Seat: 1}}
+		}, model.LifecycleRunning},
+		{"final table by seating", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleFinalTable
+			m.State.CurrentPlayers = 10
+			m.State.Seating = []*model.Seat{{Table: 3, Seat: 1}, {Table: 3, Seat: 2}}
+		}, model.LifecycleFinalTable},
+		{"winner", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleFinalTable
+			m.State.CurrentPlayers = 1
+		}, model.LifecycleFinished},
+		{"out of levels", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleRunning
+			endOfTime(m)
+		}, model.LifecycleFinished},
+		{"one player registered isn't a winner", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleRegistering
+			m.State.CurrentPlayers = 1
+		}, model.LifecycleRegistering},
+		{"finished stays finished", func(m *model.Tournament) {
+			m.State.Lifecycle = model.LifecycleFinished
+			m.State.CurrentPlayers = 5
+		}, model.LifecycleFinished},
+		{"archived stays archived", func(m *model.Tournament) { m.State.Lifecycle = model.LifecycleArchived }, model.LifecycleArchived},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			m := newLifecycleTournament()
+			tc.edit(m)
+			if got := NextLifecycle(m, lifecycleT0); got != tc.want {
+				t.Errorf("got %q, want %q", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestLifecycleRunsOutWithTheClock(t *testing.T) {
+	clock := clockwork.NewFakeClockAt(lifecycleT0)
+	tm := NewManager(clock, nil, nil)
+	m := newLifecycleTournament()
+
+	tm.FillTransientsAndAdvanceClock(context.Background(), m)
+	if m.State.Lifecycle != model.LifecycleScheduled {
+		t.Fatalf("got %q, want scheduled", m.State.Lifecycle)
+	}
+
+	tm.StartClock(m)
+	clock.Advance(25 * time.Minute)
+	if !tm.AdvanceLifecycle(context.Background(), m) || m.State.Lifecycle != model.LifecycleOnBreak {
+		t.Fatalf("got %q, want on break", m.State.Lifecycle)
+	}
+
+	clock.Advance(time.Hour)
+	tm.AdvanceLifecycle(context.Background(), m)
+	if m.State.Lifecycle != model.LifecycleFinished {
+		t.Fatalf("got %q, want finished", m.State.Lifecycle)
+	}
+	if !m.State.FinishedAt.Equal(clock.Now()) {
+		t.Errorf("finished at %v, want %v", m.State.FinishedAt, clock.Now())
+	}
+}
+
+func TestArchive(t *testing.T) {
+	tm := NewManager(clockwork.NewFakeClockAt(lifecycleT0), nil, nil)
+	m := newLifecycleTournament()
+
+	if err := tm.Archive(m); err == nil {
+		t.Errorf("archived a scheduled tournament")
+	}
+	if err := tm.SetLifecycle(m, model.LifecycleFinished); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.Archive(m); err != nil {
+		t.Fatal(err)
+	}
+	if err := tm.SetLifecycle(m, model.LifecycleRunning); err == nil {
+		t.Errorf("changed an archived tournament")
+	}
+	if m.State.FinishedAt.IsZero() {
+		t.Errorf("archiving lost the finish time")
+	}
+	if err := tm.Unarchive(m); err != nil || m.State.Lifecycle != model.LifecycleFinished {
+		t.Errorf("unarchive: %v, %q", err, m.State.Lifecycle)
+	}
+	if err := tm.SetLifecycle(m, model.LifecycleOnBreak); err == nil {
+		t.Errorf("set an automatic state by hand")
+	}
+}
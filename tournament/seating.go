@@ -0,0 +1,75 @@
+package tournament
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ts4z/irata/model"
+)
+
+// ParseSeating reads a seating chart with one seat per line, as
+// "table seat name" or "table-seat name".  Blank lines are ignored.
+func ParseSeating(text string) ([]*model.Seat, error) {
+	seats := []*model.Seat{}
+	n := 0
+	for line := range strings.Lines(text) {
+		n++
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		fields := strings.Fields(line)
+		if t, s, ok := strings.Cut(fields[0], "-"); ok {
+			fields = append([]string{t, s}, fields[1:]...)
+		}
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("line %d: want table, seat and name", n)
+		}
+		table, err := strconv.Atoi(fields[0])
+		if err != nil || table <= 0 {
+			return nil, fmt.Errorf("line %d: bad table %q", n, fields[0])
+		}
+		seat, err := strconv.Atoi(fields[1])
+		if err != nil || seat <= 0 {
+			return nil, fmt.Errorf("line %d: bad seat %q", n, fields[1])
+		}
+		seats = append(seats, &model.Seat{
+			Table: table,
+			Seat:  seat,
+			Name:  strings.Join(fields[2:], " "),
+		})
+	}
+	return seats, nil
+}
+
+// FormatSeating is the inverse of ParseSeating.
+func FormatSeating(seats []*model.Seat) string {
+	sb := &strings.Builder{}
+	for _, s := range seats {
+		fmt.Fprintf(sb, "%d %d %s\n", s.Table, s.Seat, s.Name)
+	}
+	return sb.String()
+}
+
+// SetSeating replaces the seating chart.  Two players can't have the same
+// seat.
+func (tm *Manager) SetSeating(m *model.Tournament, seats []*model.Seat) error {
+	seats = slices.Clone(seats)
+	slices.SortStableFunc(seats, func(a, b *model.Seat) int {
+		return cmp.Or(cmp.Compare(a.Table, b.Table), cmp.Compare(a.Seat, b.Seat))
+	})
+	for i := 1; i < len(seats); i++ {
+		if seats[i].Table == seats[i-1].Table && seats[i].Seat == seats[i-1].Seat {
+			return fmt.Errorf("table %d seat %d has both %s and %s",
+				seats[i].Table, seats[i].Seat, seats[i-1].Name, seats[i].Name)
+		}
+	}
+	if len(seats) == 0 {
+		seats = nil
+	}
+	m.State.Seating = seats
+	return nil
+}
@@ -0,0 +1,53 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/ts4z/irata/model"
+)
+
+func TestParseSeating(t *testing.T) {
+	seats, err := ParseSeating("2 3 Bob Jones\n\n1-9  Alice\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Seat{{Table: 2, Seat: 3, Name: "Bob Jones"}, {Table: 1, Seat: 9, Name: "Alice"}}
+	if len(seats) != len(want) {
+		t.Fatalf("got %d seats, want %d", len(seats), len(want))
+	}
+	for i := range want {
+		if *seats[i] != want[i] {
+			t.Errorf("seat %d: got %+v, want %+v", i, *seats[i], want[i])
+		}
+	}
+
+	if got := FormatSeating(seats); got != "2 3 Bob Jones\n1 9 Alice\n" {
+		t.Errorf("FormatSeating = %q", got)
+	}
+
+	for _, bad := range []string{"1 Alice", "x 1 Alice", "1 0 Alice", "1-2"} {
+		if _, err := ParseSeating(bad); err == nil {
+			t.Errorf("ParseSeating(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSetSeatingSortsAndRejectsDuplicates(t *testing.T) {
+	tm := NewManager(nil, nil, nil)
+	m := &model.Tournament{State: &model.State{}}
+
+	err := tm.SetSeating(m, []*model.Seat{{Table: 2, Seat: 1, Name: "Carol"}, {Table: 1, Seat: 2, Name: "Bob"}, {Table: 1, Seat: 1, Name: "Alice"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if names := []string{m.State.Seating[0].Name, m.State.Seating[1].Name, m.State.Seating[2].Name}; names[0] != "Alice" || names[1] != "Bob" || names[2] != "Carol" {
+		t.Errorf("seating not sorted: %v", names)
+	}
+
+	if err := tm.SetSeating(m, []*model.Seat{{Table: 1, Seat: 1, Name: "Alice"}, {Table: 1, Seat: 1, Name: "Bob"}}); err == nil {
+		t.Error("two players in one seat should fail")
+	}
+	if len(m.State.Seating) != 3 {
+		t.Error("failed SetSeating should leave the old chart alone")
+	}
+}
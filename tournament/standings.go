@@ -0,0 +1,72 @@
+package tournament
+
+import (
+	"cmp"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/ts4z/irata/model"
+)
+
+// ParseStandings reads final standings with one player per line, winner
+// first.  A line may start with the place, as "3 Doyle" or "3. Doyle";
+// players who chopped can share one.  Lines without a place finish just
+// after the line before.  Blank lines are ignored.
+func ParseStandings(text string) ([]*model.Standing, error) {
+	standings := []*model.Standing{}
+	n := 0
+	place := 0
+	for line := range strings.Lines(text) {
+		n++
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+		place++
+		first, rest, _ := strings.Cut(line, " ")
+		if p, err := strconv.Atoi(strings.TrimSuffix(first, ".")); err == nil {
+			if p <= 0 {
+				return nil, fmt.Errorf("line %d: bad place %q", n, first)
+			}
+			place = p
+			line = strings.TrimSpace(rest)
+		}
+		if line == "" {
+			return nil, fmt.Errorf("line %d: no name", n)
+		}
+		standings = append(standings, &model.Standing{Place: place, Name: line})
+	}
+	return standings, nil
+}
+
+// FormatStandings is the inverse of ParseStandings.
+func FormatStandings(standings []*model.Standing) string {
+	sb := &strings.Builder{}
+	for _, s := range standings {
+		fmt.Fprintf(sb, "%d %s\n", s.Place, s.Name)
+	}
+	return sb.String()
+}
+
+// SetStandings replaces the final standings.  Nobody can finish twice.
+func (tm *Manager) SetStandings(m *model.Tournament, standings []*model.Standing) error {
+	standings = slices.Clone(standings)
+	slices.SortStableFunc(standings, func(a, b *model.Standing) int {
+		return cmp.Compare(a.Place, b.Place)
+	})
+	seen := map[string]bool{}
+	for _, s := range standings {
+		key := strings.ToLower(s.Name)
+		if seen[key] {
+			return fmt.Errorf("%s finished more than once", s.Name)
+		}
+		seen[key] = true
+	}
+	if len(standings) == 0 {
+		standings = nil
+	}
+	m.State.Standings = standings
+	return nil
+}
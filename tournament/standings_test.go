@@ -0,0 +1,52 @@
+package tournament
+
+import (
+	"testing"
+
+	"github.com/ts4z/irata/model"
+)
+
+func TestParseStandings(t *testing.T) {
+	standings, err := ParseStandings("1. Alice\nBob Jones\n\n3 Carol\n3 Dave\nErin\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []model.Standing{{Place: 1, Name: "Alice"}, {Place: 2, Name: "Bob Jones"}, {Place: 3, Name: "Carol"}, {Place: 3, Name: "Dave"}, {Place: 4, Name: "Erin"}}
+	if len(standings) != len(want) {
+		t.Fatalf("got %d standings, want %d", len(standings), len(want))
+	}
+	for i := range want {
+		if *standings[i] != want[i] {
+			t.Errorf("standing %d: got %+v, want %+v", i, *standings[i], want[i])
+		}
+	}
+
+	if got := FormatStandings(standings[:2]); got != "1 Alice\n2 Bob Jones\n" {
+		t.Errorf("FormatStandings = %q", got)
+	}
+
+	for _, bad := range []string{"0 Alice", "-1 Alice", "2."} {
+		if _, err := ParseStandings(bad); err == nil {
+			t.Errorf("ParseStandings(%q) should fail", bad)
+		}
+	}
+}
+
+func TestSetStandingsSortsAndRejectsDuplicates(t *testing.T) {
+	tm := NewManager(nil, nil, nil)
+	m := &model.Tournament{State: &model.State{}}
+
+	if err := tm.SetStandings(m, []*model.Standing{{Place: 2, Name: "Bob"}, {Place: 1, Name: "Alice"}}); err != nil {
+		t.Fatal(err)
+	}
+	if m.State.Standings[0].Name != "Alice" || m.State.Standings[1].Name != "Bob" {
+		t.Errorf("standings not sorted: %+v %+v", m.State.Standings[0], m.State.Standings[1])
+	}
+
+	if err := tm.SetStandings(m, []*model.Standing{{Place: 1, Name: "Alice"}, {Place: 2, Name: "alice"}}); err == nil {
+		t.Error("finishing twice should fail")
+	}
+	if len(m.State.Standings) != 2 {
+		t.Error("failed SetStandings should leave the old standings alone")
+	}
+}
@@ -300,7 +300,10 @@ func (tm *Manager) FillTransientsAndAdvanceClock(ctx context.Context, m *model.T
 		m.Transients.AverageChips = int(math.Round(float64(m.Transients.TotalChips) / float64(m.State.CurrentPlayers)))
 	}
 
+	fillChipCountTransients(m)
+
 	tm.adjustStateForElapsedTime(m)
+	tm.UpdateLifecycle(m)
 
 	if tm.ptf != nil && m.State.AutoComputePrizePool {
 		if ppt, err := tm.ComputePrizePoolText(m); err == nil {
@@ -32,3 +32,38 @@ func (c *LocalTimeClock) Now() time.Time {
 func (c *LocalTimeClock) Clockwork() clockwork.Clock {
 	return c.clock
 }
+
+// AcceleratedClock runs faster than real time, by its rate, starting from
+// when it was made.  It's for demos: at a rate of 10, a 20-minute level goes
+// by in two minutes.  Clients need to run at the same speed; see Epoch.
+type AcceleratedClock struct {
+	clock clockwork.Clock
+	epoch time.Time
+	rate  float64
+}
+
+// NewAcceleratedClock gets a clock that starts at the real time and runs
+// rate times as fast, in local time truncated to the second.
+func NewAcceleratedClock(rate float64) *AcceleratedClock {
+	clock := clockwork.NewRealClock()
+	return &AcceleratedClock{
+		clock: clock,
+		epoch: clock.Now(),
+		rate:  rate,
+	}
+}
+
+func (c *AcceleratedClock) Now() time.Time {
+	elapsed := c.clock.Since(c.epoch)
+	return c.epoch.Add(time.Duration(float64(elapsed) * c.rate)).Local().Truncate(time.Second)
+}
+
+// Epoch is when the clock agreed with real time.  Another clock that knows
+// the epoch and rate can keep the same time.
+func (c *AcceleratedClock) Epoch() time.Time {
+	return c.epoch
+}
+
+func (c *AcceleratedClock) Rate() float64 {
+	return c.rate
+}
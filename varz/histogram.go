@@ -0,0 +1,132 @@
+package varz
+
+import (
+	"encoding/json"
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+	"sync"
+)
+
+// Histogram counts observations into buckets, Prometheus style: each bucket
+// counts everything at or below its bound, and there's always a +Inf bucket.
+type Histogram struct {
+	mu      sync.Mutex
+	buckets []float64
+	counts  []uint64 // counts[i] is for buckets[i]; the last is +Inf
+	sum     float64
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		buckets: buckets,
+		counts:  make([]uint64, len(buckets)+1),
+	}
+}
+
+// Observe adds v to the histogram.
+func (h *Histogram) Observe(v float64) {
+	i, _ := slices.BinarySearch(h.buckets, v)
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.counts[i]++
+	h.sum += v
+}
+
+type histogramSnapshot struct {
+	Buckets []float64
+	Counts  []uint64 // cumulative, with +Inf last
+	Count   uint64
+	Sum     float64
+}
+
+func (h *Histogram) snapshot() histogramSnapshot {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	s := histogramSnapshot{
+		Buckets: h.buckets,
+		Counts:  make([]uint64, len(h.counts)),
+		Sum:     h.sum,
+	}
+	for i, c := range h.counts {
+		s.Count += c
+		s.Counts[i] = s.Count
+	}
+	return s
+}
+
+// String implements expvar.Var.
+func (h *Histogram) String() string {
+	s := h.snapshot()
+	// JSON can't say +Inf, so /debug/vars leaves that bucket to Count.
+	b, _ := json.Marshal(struct {
+		Buckets map[string]uint64
+		Count   uint64
+		Sum     float64
+	}{bucketMap(s), s.Count, s.Sum})
+	return string(b)
+}
+
+func bucketMap(s histogramSnapshot) map[string]uint64 {
+	m := map[string]uint64{}
+	for i, le := range s.Buckets {
+		m[formatFloat(le)] = s.Counts[i]
+	}
+	return m
+}
+
+// HistogramMap is a Histogram for each key.
+type HistogramMap struct {
+	buckets []float64
+
+	mu sync.Mutex
+	m  map[string]*Histogram
+}
+
+// Observe adds v to the histogram for key.
+func (hm *HistogramMap) Observe(key string, v float64) {
+	hm.get(key).Observe(v)
+}
+
+func (hm *HistogramMap) get(key string) *Histogram {
+	hm.mu.Lock()
+	defer hm.mu.Unlock()
+	h, ok := hm.m[key]
+	if !ok {
+		h = newHistogram(hm.buckets)
+		hm.m[key] = h
+	}
+	return h
+}
+
+func (hm *HistogramMap) snapshot() map[string]histogramSnapshot {
+	hm.mu.Lock()
+	hs := maps.Clone(hm.m)
+	hm.mu.Unlock()
+	r := map[string]histogramSnapshot{}
+	for k, h := range hs {
+		r[k] = h.snapshot()
+	}
+	return r
+}
+
+// String implements expvar.Var.
+func (hm *HistogramMap) String() string {
+	hm.mu.Lock()
+	hs := maps.Clone(hm.m)
+	hm.mu.Unlock()
+	m := map[string]json.RawMessage{}
+	for k, h := range hs {
+		m[k] = json.RawMessage(h.String())
+	}
+	b, _ := json.Marshal(m)
+	return string(b)
+}
+
+func formatFloat(f float64) string {
+	if math.IsInf(f, 1) {
+		return "+Inf"
+	}
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,173 @@
+package varz
+
+import (
+	"bufio"
+	"expvar"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"path"
+	"slices"
+	"strings"
+	"sync"
+	"unicode"
+)
+
+type kind string
+
+const (
+	counter   kind = "counter"
+	gauge     kind = "gauge"
+	histogram kind = "histogram"
+)
+
+// metric is a var made through varz, as Prometheus sees it.
+type metric struct {
+	name   string // Prometheus name
+	expvar string // expvar name, for HELP
+	label  string // for maps; "" for plain vars
+	kind   kind
+	v      expvar.Var
+}
+
+var (
+	metricsMu sync.Mutex
+	metrics   []*metric
+)
+
+func register(pkg, name string, k kind, v expvar.Var) {
+	m := &metric{
+		name:   metricName(pkg, name),
+		expvar: fmt.Sprintf("%s.%s", pkg, name),
+		kind:   k,
+		v:      v,
+	}
+	switch v.(type) {
+	case *expvar.Map, *HistogramMap:
+		m.label = labelName(name)
+	}
+	if k == counter {
+		m.name += "_total"
+	}
+	metricsMu.Lock()
+	defer metricsMu.Unlock()
+	metrics = append(metrics, m)
+}
+
+// metricName makes "irata_webapp_listens_by_proto" from the package
+// "github.com/ts4z/irata/webapp" and the name "listensByProto".
+func metricName(pkg, name string) string {
+	return "irata_" + snake(path.Base(pkg)) + "_" + snake(name)
+}
+
+// labelName is what a map's keys are called: "proto" for "listensByProto",
+// or "key" without a "By".
+func labelName(name string) string {
+	i := strings.LastIndex(name, "By")
+	if i < 0 || i+2 >= len(name) || !unicode.IsUpper(rune(name[i+2])) {
+		return "key"
+	}
+	return snake(name[i+2:])
+}
+
+// snake turns camelCase into snake_case, keeping acronyms together:
+// "chopomaticAPICalls" is "chopomatic_api_calls".
+func snake(s string) string {
+	rs := []rune(s)
+	var b strings.Builder
+	for i, r := range rs {
+		if unicode.IsUpper(r) && i > 0 {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if unicode.IsLower(prev) || unicode.IsDigit(prev) || (unicode.IsUpper(prev) && nextLower) {
+				b.WriteByte('_')
+			}
+		}
+		switch {
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(unicode.ToLower(r))
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// MetricsHandler serves every var made through varz in the Prometheus text
+// format.
+func MetricsHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		WriteMetrics(w)
+	})
+}
+
+// WriteMetrics writes every var made through varz in the Prometheus text
+// format.
+func WriteMetrics(w io.Writer) error {
+	metricsMu.Lock()
+	ms := slices.Clone(metrics)
+	metricsMu.Unlock()
+	slices.SortFunc(ms, func(a, b *metric) int {
+		return strings.Compare(a.name, b.name)
+	})
+
+	bw := bufio.NewWriter(w)
+	for _, m := range ms {
+		m.write(bw)
+	}
+	return bw.Flush()
+}
+
+func (m *metric) write(w io.Writer) {
+	fmt.Fprintf(w, "# HELP %s %s\n", m.name, m.expvar)
+	fmt.Fprintf(w, "# TYPE %s %s\n", m.name, m.kind)
+	switch v := m.v.(type) {
+	case *expvar.Int:
+		fmt.Fprintf(w, "%s %d\n", m.name, v.Value())
+	case *expvar.Map:
+		v.Do(func(kv expvar.KeyValue) {
+			fmt.Fprintf(w, "%s{%s} %s\n", m.name, m.labels(kv.Key), numeric(kv.Value))
+		})
+	case *Histogram:
+		writeHistogram(w, m.name, "", v.snapshot())
+	case *HistogramMap:
+		snaps := v.snapshot()
+		for _, k := range slices.Sorted(maps.Keys(snaps)) {
+			writeHistogram(w, m.name, m.labels(k)+",", snaps[k])
+		}
+	}
+}
+
+func (m *metric) labels(key string) string {
+	return fmt.Sprintf("%s=\"%s\"", m.label, labelEscaper.Replace(key))
+}
+
+func writeHistogram(w io.Writer, name, labels string, s histogramSnapshot) {
+	for i, le := range s.Buckets {
+		fmt.Fprintf(w, "%s_bucket{%sle=\"%s\"} %d\n", name, labels, formatFloat(le), s.Counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket{%sle=\"+Inf\"} %d\n", name, labels, s.Count)
+	labels = strings.TrimSuffix(labels, ",")
+	if labels != "" {
+		labels = "{" + labels + "}"
+	}
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, labels, formatFloat(s.Sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, labels, s.Count)
+}
+
+// numeric is a map value as a sample value.  Maps only hold numbers unless
+// somebody Set something else; that's reported as NaN rather than breaking
+// the whole scrape.
+func numeric(v expvar.Var) string {
+	switch v := v.(type) {
+	case *expvar.Int:
+		return fmt.Sprint(v.Value())
+	case *expvar.Float:
+		return formatFloat(v.Value())
+	}
+	return "NaN"
+}
+
+var labelEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
@@ -0,0 +1,88 @@
+package varz
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSnake(t *testing.T) {
+	for in, want := range map[string]string{
+		"listensByProto":             "listens_by_proto",
+		"chopomaticAPICalls":         "chopomatic_api_calls",
+		"fetchUserByID":              "fetch_user_by_id",
+		"tournamentStoragecacheHits": "tournament_storagecache_hits",
+		"kbd":                        "kbd",
+		"http3Listens":               "http3_listens",
+	} {
+		if got := snake(in); got != want {
+			t.Errorf("snake(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestLabelName(t *testing.T) {
+	for in, want := range map[string]string{
+		"keyboardEventsByType":  "type",
+		"listenersByTournament": "tournament",
+		"chopomaticAlgoTypes":   "key",
+		"Bystanders":            "key",
+	} {
+		if got := labelName(in); got != want {
+			t.Errorf("labelName(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestWriteMetrics(t *testing.T) {
+	c := NewInt("testWidgets")
+	c.Add(3)
+	g := NewGauge("testOpenWidgets")
+	g.Add(2)
+	g.Add(-1)
+	m := NewMap("testWidgetsByColor")
+	m.Add("red", 1)
+	m.Add(`"blue"`, 2)
+	h := NewHistogram("testWidgetSeconds", []float64{0.1, 1})
+	h.Observe(0.05)
+	h.Observe(1)
+	h.Observe(5)
+	hm := NewHistogramMap("testWidgetSecondsByColor", []float64{1})
+	hm.Observe("red", 0.5)
+
+	var b strings.Builder
+	if err := WriteMetrics(&b); err != nil {
+		t.Fatal(err)
+	}
+	got := b.String()
+	for _, want := range []string{
+		"# HELP irata_varz_test_widgets_total github.com/ts4z/irata/varz.testWidgets\n",
+		"# TYPE irata_varz_test_widgets_total counter\nirata_varz_test_widgets_total 3\n",
+		"# TYPE irata_varz_test_open_widgets gauge\nirata_varz_test_open_widgets 1\n",
+		"irata_varz_test_widgets_by_color_total{color=\"\\\"blue\\\"\"} 2\n",
+		"irata_varz_test_widgets_by_color_total{color=\"red\"} 1\n",
+		"# TYPE irata_varz_test_widget_seconds histogram\n" +
+			"irata_varz_test_widget_seconds_bucket{le=\"0.1\"} 1\n" +
+			"irata_varz_test_widget_seconds_bucket{le=\"1\"} 2\n" +
+			"irata_varz_test_widget_seconds_bucket{le=\"+Inf\"} 3\n" +
+			"irata_varz_test_widget_seconds_sum 6.05\n" +
+			"irata_varz_test_widget_seconds_count 3\n",
+		"irata_varz_test_widget_seconds_by_color_bucket{color=\"red\",le=\"1\"} 1\n" +
+			"irata_varz_test_widget_seconds_by_color_bucket{color=\"red\",le=\"+Inf\"} 1\n" +
+			"irata_varz_test_widget_seconds_by_color_sum{color=\"red\"} 0.5\n" +
+			"irata_varz_test_widget_seconds_by_color_count{color=\"red\"} 1\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("missing %q in:\n%s", want, got)
+		}
+	}
+}
+
+func TestHistogramString(t *testing.T) {
+	h := newHistogram([]float64{1, 2})
+	h.Observe(1.5)
+	h.Observe(3)
+	want := `{"Buckets":{"1":0,"2":1},"Count":2,"Sum":4.5}`
+	if got := h.String(); got != want {
+		t.Errorf("got %s, want %s", got, want)
+	}
+}
@@ -2,7 +2,8 @@
 varz provides helpers to create expvar variables with package-qualified names.
 It also imports expvar, so it will register it with http.DefaultServeMux.
 
-varz, ironically, doesn't export /varz.  Maybe later.
+varz, ironically, doesn't export /varz.  It does export everything made
+through it to Prometheus; see MetricsHandler.
 */
 package varz
 
@@ -37,10 +38,56 @@ func callerPackage() string {
 	return n
 }
 
+// NewInt makes a counter.
 func NewInt(name string) *expvar.Int {
-	return expvar.NewInt(fmt.Sprintf("%s.%s", callerPackage(), name))
+	pkg := callerPackage()
+	v := expvar.NewInt(fmt.Sprintf("%s.%s", pkg, name))
+	register(pkg, name, counter, v)
+	return v
 }
 
+// NewGauge makes an Int that can go down as well as up, like the number of
+// open connections.
+func NewGauge(name string) *expvar.Int {
+	pkg := callerPackage()
+	v := expvar.NewInt(fmt.Sprintf("%s.%s", pkg, name))
+	register(pkg, name, gauge, v)
+	return v
+}
+
+// NewMap makes a counter for each key.  A name like "listensByProto" gives
+// the keys the label "proto"; otherwise they are labeled "key".
 func NewMap(name string) *expvar.Map {
-	return expvar.NewMap(fmt.Sprintf("%s.%s", callerPackage(), name))
+	pkg := callerPackage()
+	v := expvar.NewMap(fmt.Sprintf("%s.%s", pkg, name))
+	register(pkg, name, counter, v)
+	return v
+}
+
+// NewGaugeMap is NewMap for gauges.
+func NewGaugeMap(name string) *expvar.Map {
+	pkg := callerPackage()
+	v := expvar.NewMap(fmt.Sprintf("%s.%s", pkg, name))
+	register(pkg, name, gauge, v)
+	return v
+}
+
+// NewHistogram makes a histogram with the given bucket upper bounds, which
+// must be in increasing order.
+func NewHistogram(name string, buckets []float64) *Histogram {
+	pkg := callerPackage()
+	v := newHistogram(buckets)
+	expvar.Publish(fmt.Sprintf("%s.%s", pkg, name), v)
+	register(pkg, name, histogram, v)
+	return v
+}
+
+// NewHistogramMap makes a histogram for each key, all with the same
+// buckets.  Keys are labeled as for NewMap.
+func NewHistogramMap(name string, buckets []float64) *HistogramMap {
+	pkg := callerPackage()
+	v := &HistogramMap{buckets: buckets, m: map[string]*Histogram{}}
+	expvar.Publish(fmt.Sprintf("%s.%s", pkg, name), v)
+	register(pkg, name, histogram, v)
+	return v
 }
@@ -0,0 +1,303 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/announcement"
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/soundmodel"
+	"github.com/ts4z/irata/tournament"
+	"github.com/ts4z/irata/varz"
+)
+
+const (
+	// A clock re-polls for announcements at least this often.
+	announcementListenTimeout = time.Minute
+	// Expired announcements stay on the management page this long.
+	announcementHistory = 12 * time.Hour
+)
+
+var (
+	announcementListens        = varz.NewInt("announcementListens")
+	announcementListenTimeouts = varz.NewInt("announcementListenTimeouts")
+	announcementNotifiedClient = varz.NewInt("announcementNotifiedClient")
+	announcementClientClosed   = varz.NewInt("announcementClientClosed")
+	announcementsMade          = varz.NewInt("announcementsMade")
+)
+
+// apiAnnouncement is an announcement as a clock gets it, already drawn.
+type apiAnnouncement struct {
+	AnnouncementID int64
+	Version        int64
+	Style          model.AnnouncementStyle
+	HTML           string
+	SoundPath      string
+	Expires        time.Time
+}
+
+func (app *App) apiAnnouncements(ctx context.Context, as []*model.Announcement) []apiAnnouncement {
+	out := []apiAnnouncement{}
+	for _, a := range as {
+		aa := apiAnnouncement{
+			AnnouncementID: a.AnnouncementID,
+			Version:        a.Version,
+			Style:          a.Style,
+			HTML:           string(markdownToHTML(a.Markdown)),
+			Expires:        a.Expires,
+		}
+		if a.SoundID != 0 {
+			if se, err := app.soundStorage.FetchSoundEffectByID(ctx, a.SoundID); err != nil {
+				log.Printf("announcement %d: can't fetch sound %d: %v", a.AnnouncementID, a.SoundID, err)
+			} else {
+				aa.SoundPath = se.Path
+			}
+		}
+		out = append(out, aa)
+	}
+	return out
+}
+
+// handleAPIAnnouncementListen answers right away if the caller's
+// announcements are out of date, and otherwise waits for them to change.
+// After announcementListenTimeout it answers with what the caller already
+// has, and the caller asks again.
+func (app *App) handleAPIAnnouncementListen(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	announcementListens.Add(1)
+	defer countListen(r)()
+	var req struct {
+		TournamentID int64
+		Key          string
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		he.SendErrorToHTTPClient(w, "/api/announcement-listen", he.HTTPCodedErrorf(http.StatusBadRequest, "decoding json: %w", err))
+		return
+	}
+	if req.TournamentID < 0 {
+		he.SendErrorToHTTPClient(w, "prep announcement listen", he.HTTPCodedErrorf(http.StatusBadRequest, "invalid tournament ID %d", req.TournamentID))
+		return
+	}
+	errCh := make(chan error, 1)
+	announcementCh := make(chan []*model.Announcement, 1)
+	app.announcementGossiper.ListenAnnouncements(ctx, req.TournamentID, req.Key, errCh, announcementCh)
+	var as []*model.Announcement
+	select {
+	case err := <-errCh:
+		he.SendErrorToHTTPClient(w, "listen for announcements", err)
+		return
+	case as = <-announcementCh:
+		announcementNotifiedClient.Add(1)
+	case <-time.After(announcementListenTimeout):
+		announcementListenTimeouts.Add(1)
+		app.announcementGossiper.Forget(announcementCh)
+		// Answer with whatever is current, which is usually what the caller
+		// already has.
+		now := app.clock.Now()
+		all, err := app.announcementStorage.FetchAnnouncements(ctx, now)
+		if err != nil {
+			he.SendErrorToHTTPClient(w, "fetch announcements", err)
+			return
+		}
+		as = announcement.For(all, req.TournamentID, now)
+	case <-ctx.Done():
+		announcementClientClosed.Add(1)
+		app.announcementGossiper.Forget(announcementCh)
+		http.Error(w, "request cancelled", http.StatusRequestTimeout)
+		return
+	case <-app.draining:
+		app.announcementGossiper.Forget(announcementCh)
+		sendReconnect(w)
+		return
+	}
+
+	bytes, err := json.Marshal(struct {
+		Key           string
+		Announcements []apiAnnouncement
+	}{announcement.Key(as), app.apiAnnouncements(ctx, as)})
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "marshal announcements", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+func (app *App) applyAnnouncementForm(ctx context.Context, r *http.Request) (string, error) {
+	if err := r.ParseForm(); err != nil {
+		return "", he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+	parseID := func(name string) (int64, error) {
+		id, err := strconv.ParseInt(r.FormValue(name), 10, 64)
+		if err != nil {
+			return 0, he.HTTPCodedErrorf(http.StatusBadRequest, "bad %s", name)
+		}
+		return id, nil
+	}
+
+	now := app.clock.Now()
+	switch r.FormValue("Action") {
+	case "create":
+		a := &model.Announcement{
+			Style:    model.AnnouncementStyle(r.FormValue("Style")),
+			Markdown: strings.TrimSpace(r.FormValue("Markdown")),
+			Created:  now,
+		}
+		var err error
+		if a.TournamentID, err = parseID("TournamentID"); err != nil {
+			return "", err
+		}
+		if a.SoundID, err = parseID("SoundID"); err != nil {
+			return "", err
+		}
+		if a.SoundID != 0 {
+			if _, err := app.soundStorage.FetchSoundEffectByID(ctx, a.SoundID); err != nil {
+				return "", err
+			}
+		}
+		minutes, err := strconv.ParseFloat(r.FormValue("Minutes"), 64)
+		if err != nil {
+			return "", he.HTTPCodedErrorf(http.StatusBadRequest, "bad number of minutes")
+		}
+		a.Expires = now.Add(time.Duration(minutes * float64(time.Minute))).Round(time.Second)
+		if err := announcement.Validate(a); err != nil {
+			return "", he.New(http.StatusBadRequest, err)
+		}
+		if _, err := app.announcementStorage.CreateAnnouncement(ctx, a); err != nil {
+			return "", err
+		}
+		announcementsMade.Add(1)
+		return "Announced.", nil
+	case "end":
+		id, err := parseID("AnnouncementID")
+		if err != nil {
+			return "", err
+		}
+		a, err := app.announcementStorage.FetchAnnouncement(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		if !announcement.Current(a, now) {
+			return "That announcement had already ended.", nil
+		}
+		a.Expires = now
+		if err := app.announcementStorage.SaveAnnouncement(ctx, a); err != nil {
+			return "", err
+		}
+		return "Ended the announcement.", nil
+	case "delete":
+		id, err := parseID("AnnouncementID")
+		if err != nil {
+			return "", err
+		}
+		if err := app.announcementStorage.DeleteAnnouncement(ctx, id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted announcement %d.", id), nil
+	default:
+		return "", he.HTTPCodedErrorf(http.StatusBadRequest, "unknown action")
+	}
+}
+
+func (app *App) handleManageAnnouncements(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var flash, flashType string
+	if r.Method == http.MethodPost {
+		if msg, err := app.applyAnnouncementForm(ctx, r); err != nil {
+			log.Printf("manage announcements: %v", err)
+			flash, flashType = err.Error(), "boo"
+		} else {
+			flash, flashType = msg, "yay"
+		}
+	}
+
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	now := app.clock.Now()
+	as, err := app.announcementStorage.FetchAnnouncements(ctx, now.Add(-announcementHistory))
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch announcements", err)
+		return
+	}
+	// TODO: pagination
+	overview, err := app.tournamentStorage.FetchOverview(ctx, tournament.CurrentLifecycles, 0, 100)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch overview", err)
+		return
+	}
+	sounds, err := app.soundStorage.FetchSoundEffectSlugs(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch sounds", err)
+		return
+	}
+
+	tournamentNames := map[int64]string{}
+	for _, slug := range overview.Slugs {
+		tournamentNames[slug.TournamentID] = slug.TournamentName
+	}
+	type announcementRow struct {
+		*model.Announcement
+		Target  string
+		Current bool
+		When    string
+	}
+	rows := []announcementRow{}
+	// Newest first.
+	for i := len(as) - 1; i >= 0; i-- {
+		a := as[i]
+		row := announcementRow{Announcement: a, Target: "Every display", Current: announcement.Current(a, now)}
+		if a.TournamentID != 0 {
+			row.Target = tournamentNames[a.TournamentID]
+			if row.Target == "" {
+				row.Target = fmt.Sprintf("Tournament %d", a.TournamentID)
+			}
+		}
+		if row.Current {
+			row.When = "ends in " + a.Expires.Sub(now).Round(time.Second).String()
+		} else {
+			row.When = "ended " + now.Sub(a.Expires).Round(time.Minute).String() + " ago"
+		}
+		rows = append(rows, row)
+	}
+
+	// The tournament page links here to announce to itself.
+	selected, _ := strconv.ParseInt(r.URL.Query().Get("t"), 10, 64)
+
+	data := struct {
+		Announcements []announcementRow
+		Tournaments   []model.TournamentSlug
+		Selected      int64
+		Styles        []announcement.Style
+		Sounds        []*soundmodel.SoundEffectSlug
+		Flash         string
+		FlashType     string
+		Theme         string
+		Nick          string
+		IsAdmin       bool
+		IsOperator    bool
+	}{
+		Announcements: rows,
+		Tournaments:   overview.Slugs,
+		Selected:      selected,
+		Styles:        announcement.Styles,
+		Sounds:        sounds,
+		Flash:         flash,
+		FlashType:     flashType,
+		Theme:         sc.Theme,
+		Nick:          app.currentUserNick(ctx),
+		IsAdmin:       permission.IsAdmin(ctx),
+		IsOperator:    permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "manage-announcements.html.tmpl", data); err != nil {
+		log.Printf("can't render manage-announcements template: %v", err)
+	}
+}
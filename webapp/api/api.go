@@ -0,0 +1,482 @@
+/*
+api is the REST API, for programs that want to run tournaments: make them,
+read them, and work the clock.  It's described in openapi.yaml, which is
+served at /api/v1/openapi.yaml.
+
+Programs authenticate with a bearer token, which a user makes on their
+account page (or with "irataadmin token create").  A token acts as its
+user, but only for what its scopes allow.  A browser that's logged in can
+use the API too, with all the scopes its user has.
+*/
+package api
+
+import (
+	"context"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/apitoken"
+	"github.com/ts4z/irata/dep"
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/logz"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/schedule"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/tournament"
+	"github.com/ts4z/irata/varz"
+)
+
+var logger = logz.New()
+
+var (
+	callsByScope      = varz.NewMap("callsByScope")
+	actionsByName     = varz.NewMap("actionsByName")
+	tokenFailures     = varz.NewInt("tokenFailures")
+	permissionDenials = varz.NewInt("permissionDenials")
+)
+
+//go:embed openapi.yaml
+var openAPI []byte
+
+const (
+	// Prefix is where the API lives.
+	Prefix = "/api/v1/"
+
+	defaultLimit = 100
+	maxLimit     = 1000
+	maxBodyBytes = 1 << 20
+
+	// maxCount is the most an action may do at once.  No clock needs more,
+	// and it keeps minutes from overflowing and level loops short.
+	maxCount = 1000
+)
+
+type nower interface {
+	Now() time.Time
+}
+
+type Config struct {
+	TokenStorage      state.APITokenStorage
+	UserStorage       state.UserStorage
+	TournamentStorage state.TournamentStorage
+	AppStorage        state.AppStorage
+	TemplateStorage   state.TournamentTemplateStorage
+	TournamentManager *tournament.Manager
+	Clock             nower
+}
+
+type API struct {
+	tokens      state.APITokenStorage
+	users       state.UserStorage
+	tournaments state.TournamentStorage
+	app         state.AppStorage
+	templates   state.TournamentTemplateStorage
+	tm          *tournament.Manager
+	clock       nower
+}
+
+func New(c *Config) *API {
+	return &API{
+		tokens:      dep.Required(c.TokenStorage),
+		users:       dep.Required(c.UserStorage),
+		tournaments: dep.Required(c.TournamentStorage),
+		app:         dep.Required(c.AppStorage),
+		templates:   dep.Required(c.TemplateStorage),
+		tm:          dep.Required(c.TournamentManager),
+		clock:       dep.Required(c.Clock),
+	}
+}
+
+// Install adds the API's routes to mux.
+func (a *API) Install(mux *http.ServeMux) {
+	mux.HandleFunc("GET "+Prefix+"openapi.yaml", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/yaml")
+		w.Write(openAPI)
+	})
+	a.handle(mux, "GET "+Prefix+"tournaments", apitoken.ScopeRead, a.listTournaments)
+	a.handle(mux, "POST "+Prefix+"tournaments", apitoken.ScopeCreate, a.createTournament)
+	a.handle(mux, "GET "+Prefix+"tournaments/{id}", apitoken.ScopeRead, a.getTournament)
+	a.handle(mux, "POST "+Prefix+"tournaments/{id}/actions/{action}", apitoken.ScopeControl, a.doAction)
+	// Anything else under the prefix gets a JSON 404, not the web app's.
+	mux.HandleFunc(Prefix, func(w http.ResponseWriter, r *http.Request) {
+		writeError(w, he.HTTPCodedErrorf(http.StatusNotFound, "no such API endpoint %s %s", r.Method, r.URL.Path))
+	})
+}
+
+// handle authenticates the request, checks that it may use scope, and
+// runs fn, which returns a value to send as JSON or an error.
+func (a *API) handle(mux *http.ServeMux, pattern, scope string, fn func(context.Context, *http.Request) (any, int, error)) {
+	mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+		ctx, err := a.authenticate(r)
+		if err != nil {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="irata"`)
+			writeError(w, err)
+			return
+		}
+		if !permission.ScopeAllowed(ctx, scope) {
+			permissionDenials.Add(1)
+			writeError(w, he.HTTPCodedErrorf(http.StatusForbidden, "token doesn't have the %q scope", scope))
+			return
+		}
+		// Only operators may change anything.  Storage checks too, but
+		// can't say why as clearly.
+		if scope != apitoken.ScopeRead && !permission.IsOperator(ctx) {
+			permissionDenials.Add(1)
+			writeError(w, he.HTTPCodedErrorf(http.StatusForbidden, "permission denied"))
+			return
+		}
+		callsByScope.Add(scope, 1)
+
+		v, code, err := fn(ctx, r.WithContext(ctx))
+		if err != nil {
+			logger.InfoContext(ctx, "API error", "path", r.URL.Path, "err", err)
+			writeError(w, err)
+			return
+		}
+		writeJSON(w, code, v)
+	})
+}
+
+// authenticate returns the request's context with the token's user in it.
+// Without a token, it's whoever is logged in, if anyone.
+func (a *API) authenticate(r *http.Request) (context.Context, error) {
+	ctx := r.Context()
+	secret, ok := apitoken.FromRequest(r)
+	if !ok {
+		if permission.UserFromContext(ctx) == nil {
+			return nil, he.HTTPCodedErrorf(http.StatusUnauthorized, "no API token")
+		}
+		return ctx, nil
+	}
+
+	t, err := a.tokens.FetchAPITokenByHash(ctx, apitoken.Hash(secret))
+	if err != nil {
+		tokenFailures.Add(1)
+		if he.Code(err) != http.StatusNotFound {
+			logger.WarnContext(ctx, "can't fetch API token", "err", err)
+		}
+		return nil, he.HTTPCodedErrorf(http.StatusUnauthorized, "bad API token")
+	}
+	user, err := a.users.FetchUserByUserID(ctx, t.UserID)
+	if err != nil {
+		tokenFailures.Add(1)
+		return nil, he.HTTPCodedErrorf(http.StatusUnauthorized, "API token has no user")
+	}
+	if err := a.tokens.TouchAPIToken(ctx, t.ID, a.clock.Now()); err != nil {
+		logger.WarnContext(ctx, "can't note API token use", "token", t.ID, "err", err)
+	}
+	ctx = permission.UserIdentityInContext(ctx, user)
+	return permission.APITokenInContext(ctx, t), nil
+}
+
+// Error is what the API sends instead of what was asked for.
+type Error struct {
+	Error string
+}
+
+func writeError(w http.ResponseWriter, err error) {
+	writeJSON(w, he.Code(err), &Error{Error: err.Error()})
+}
+
+func writeJSON(w http.ResponseWriter, code int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(v); err != nil {
+		logger.Warn("can't write API response", "err", err)
+	}
+}
+
+// readJSON reads the request body into v.  An empty body leaves v alone.
+func readJSON(r *http.Request, v any) error {
+	dec := json.NewDecoder(http.MaxBytesReader(nil, r.Body, maxBodyBytes))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil && err != io.EOF {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "bad request body: %w", err)
+	}
+	return nil
+}
+
+func idParam(r *http.Request) (int64, error) {
+	id, err := strconv.ParseInt(r.PathValue("id"), 10, 64)
+	if err != nil {
+		return 0, he.HTTPCodedErrorf(http.StatusBadRequest, "bad tournament id %q", r.PathValue("id"))
+	}
+	return id, nil
+}
+
+func intParam(r *http.Request, name string, def int) (int, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		return 0, he.HTTPCodedErrorf(http.StatusBadRequest, "bad %s %q", name, v)
+	}
+	return n, nil
+}
+
+// TournamentList is a page of tournaments.
+type TournamentList struct {
+	Tournaments []model.TournamentSlug
+	// Total is how many tournaments match, on every page.
+	Total int
+}
+
+func (a *API) listTournaments(ctx context.Context, r *http.Request) (any, int, error) {
+	var lifecycles []model.Lifecycle
+	for l := range strings.SplitSeq(r.URL.Query().Get("lifecycle"), ",") {
+		if l == "" {
+			continue
+		}
+		if !knownLifecycle(model.Lifecycle(l)) {
+			return nil, 0, he.HTTPCodedErrorf(http.StatusBadRequest, "unknown lifecycle %q", l)
+		}
+		lifecycles = append(lifecycles, model.Lifecycle(l))
+	}
+	offset, err := intParam(r, "offset", 0)
+	if err != nil {
+		return nil, 0, err
+	}
+	limit, err := intParam(r, "limit", defaultLimit)
+	if err != nil {
+		return nil, 0, err
+	}
+	limit = min(limit, maxLimit)
+
+	o, err := a.tournaments.FetchOverview(ctx, lifecycles, offset, limit)
+	if err != nil {
+		return nil, 0, err
+	}
+	list := &TournamentList{Tournaments: o.Slugs, Total: o.Total}
+	if list.Tournaments == nil {
+		list.Tournaments = []model.TournamentSlug{}
+	}
+	return list, http.StatusOK, nil
+}
+
+func knownLifecycle(l model.Lifecycle) bool {
+	return slices.ContainsFunc(tournament.Lifecycles, func(c tournament.LifecycleChoice) bool {
+		return c.Lifecycle == l
+	})
+}
+
+// fetch gets a tournament as the clocks see it, with the clock brought up
+// to date and the transients filled in.
+func (a *API) fetch(ctx context.Context, id int64) (*model.Tournament, error) {
+	t, err := a.tournaments.FetchTournament(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	a.tm.FillTransientsAndAdvanceClock(ctx, t)
+	return t, nil
+}
+
+func (a *API) getTournament(ctx context.Context, r *http.Request) (any, int, error) {
+	id, err := idParam(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	t, err := a.fetch(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	return t, http.StatusOK, nil
+}
+
+// CreateTournament is what's needed to make a tournament: a template, or
+// a structure and whatever else.
+type CreateTournament struct {
+	// TemplateID makes the tournament from a template, like the schedule
+	// does.  The rest, if set, override what the template says.
+	TemplateID int64
+
+	EventName         string
+	Description       string
+	StructureID       int64
+	FooterPlugsID     int64
+	LeagueID          int64
+	PrizePoolPerBuyIn int
+	PrizePoolPerAddOn int
+	ScheduledStart    time.Time
+}
+
+func (a *API) createTournament(ctx context.Context, r *http.Request) (any, int, error) {
+	var req CreateTournament
+	if err := readJSON(r, &req); err != nil {
+		return nil, 0, err
+	}
+
+	var t *model.Tournament
+	if req.TemplateID != 0 {
+		tt, err := a.templates.FetchTournamentTemplate(ctx, req.TemplateID)
+		if err != nil {
+			return nil, 0, err
+		}
+		start := req.ScheduledStart
+		if start.IsZero() {
+			start = a.clock.Now()
+		}
+		t = schedule.Instantiate(tt, start)
+	} else if req.StructureID == 0 {
+		return nil, 0, he.HTTPCodedErrorf(http.StatusBadRequest, "need a TemplateID or a StructureID")
+	} else {
+		t = &model.Tournament{
+			ScheduledStart: req.ScheduledStart,
+			State:          &model.State{},
+		}
+	}
+
+	if req.StructureID != 0 {
+		s, err := a.app.FetchStructure(ctx, req.StructureID)
+		if err != nil {
+			return nil, 0, err
+		}
+		t.Structure = s.Clone().StructureData
+		t.FromStructureID = req.StructureID
+		t.State.CurrentLevelNumber = 0
+	}
+	if req.EventName != "" {
+		t.EventName = req.EventName
+	}
+	if t.EventName == "" {
+		return nil, 0, he.HTTPCodedErrorf(http.StatusBadRequest, "need an EventName")
+	}
+	if req.Description != "" {
+		t.Description = req.Description
+	}
+	if req.FooterPlugsID != 0 {
+		t.FooterPlugsID = req.FooterPlugsID
+	}
+	if req.LeagueID != 0 {
+		t.LeagueID = req.LeagueID
+	}
+	if req.PrizePoolPerBuyIn != 0 {
+		t.PrizePoolPerBuyIn = req.PrizePoolPerBuyIn
+	}
+	if req.PrizePoolPerAddOn != 0 {
+		t.PrizePoolPerAddOn = req.PrizePoolPerAddOn
+	}
+
+	id, err := a.tournaments.CreateTournament(ctx, t)
+	if err != nil {
+		return nil, 0, err
+	}
+	if t, err = a.fetch(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return t, http.StatusCreated, nil
+}
+
+// Action is the optional body of an action.
+type Action struct {
+	// Count is how many: players, buy-ins, levels, or minutes.  Zero is
+	// one, and it may be at most 1000.
+	Count int
+}
+
+// actions are what the clock's operator can do from the keyboard.
+var actions = map[string]func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error{
+	"start": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.StartClock(t)
+	},
+	"stop": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.StopClock(t)
+	},
+	"next-level": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return repeat(n, func() error { return tm.AdvanceLevel(t) })
+	},
+	"previous-level": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return repeat(n, func() error { return tm.PreviousLevel(t) })
+	},
+	"restart-level": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.PauseAndRestartLevel(t)
+	},
+	"add-time": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.PlusTime(ctx, t, time.Duration(n)*time.Minute)
+	},
+	"remove-time": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.MinusTime(ctx, t, time.Duration(n)*time.Minute)
+	},
+	"add-buy-in": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.ChangeBuyIns(ctx, t, n)
+	},
+	"remove-buy-in": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.ChangeBuyIns(ctx, t, -n)
+	},
+	"add-add-on": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.ChangeAddOns(ctx, t, n)
+	},
+	"remove-add-on": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.ChangeAddOns(ctx, t, -n)
+	},
+	"add-player": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.ChangePlayers(ctx, t, n)
+	},
+	"remove-player": func(ctx context.Context, tm *tournament.Manager, t *model.Tournament, n int) error {
+		return tm.ChangePlayers(ctx, t, -n)
+	},
+}
+
+func repeat(n int, fn func() error) error {
+	for range n {
+		if err := fn(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (a *API) doAction(ctx context.Context, r *http.Request) (any, int, error) {
+	id, err := idParam(r)
+	if err != nil {
+		return nil, 0, err
+	}
+	name := r.PathValue("action")
+	fn, ok := actions[name]
+	if !ok {
+		return nil, 0, he.HTTPCodedErrorf(http.StatusNotFound, "no such action %q", name)
+	}
+	var req Action
+	if err := readJSON(r, &req); err != nil {
+		return nil, 0, err
+	}
+	if req.Count < 0 || req.Count > maxCount {
+		return nil, 0, he.HTTPCodedErrorf(http.StatusBadRequest, "Count must be from 0 to %d", maxCount)
+	}
+	n := max(req.Count, 1)
+	actionsByName.Add(name, 1)
+
+	t, err := a.tournaments.FetchTournament(ctx, id)
+	if err != nil {
+		return nil, 0, err
+	}
+	// Don't change the cached copy, in case the action fails.
+	t = t.Clone()
+	if err := fn(ctx, a.tm, t, n); err != nil {
+		var httpErr *he.HTTPError
+		if errors.As(err, &httpErr) {
+			return nil, 0, err
+		}
+		return nil, 0, he.HTTPCodedErrorf(http.StatusConflict, "can't %s: %v", name, err)
+	}
+	if err := a.tournaments.SaveTournament(ctx, t); err != nil {
+		return nil, 0, fmt.Errorf("save tournament: %w", err)
+	}
+	if t, err = a.fetch(ctx, id); err != nil {
+		return nil, 0, err
+	}
+	return t, http.StatusOK, nil
+}
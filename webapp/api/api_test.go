@@ -0,0 +1,239 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/ts4z/irata/apitoken"
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/tournament"
+)
+
+// The fakes embed the interface they stand in for, so calling anything the
+// API shouldn't need panics.
+
+type fakeTokens struct {
+	state.APITokenStorage
+	byHash map[string]*model.APIToken
+}
+
+func (f *fakeTokens) FetchAPITokenByHash(ctx context.Context, hash string) (*model.APIToken, error) {
+	t, ok := f.byHash[hash]
+	if !ok {
+		return nil, he.HTTPCodedErrorf(http.StatusNotFound, "no such token")
+	}
+	return t, nil
+}
+
+func (f *fakeTokens) TouchAPIToken(ctx context.Context, id int64, when time.Time) error {
+	return nil
+}
+
+type fakeUsers struct {
+	state.UserStorage
+	users map[int64]*model.UserIdentity
+}
+
+func (f *fakeUsers) FetchUserByUserID(ctx context.Context, id int64) (*model.UserIdentity, error) {
+	u, ok := f.users[id]
+	if !ok {
+		return nil, he.HTTPCodedErrorf(http.StatusNotFound, "no such user")
+	}
+	return u.Clone(), nil
+}
+
+type fakeTournaments struct {
+	state.TournamentStorage
+	tournaments map[int64]*model.Tournament
+	saves       int
+}
+
+func (f *fakeTournaments) FetchTournament(ctx context.Context, id int64) (*model.Tournament, error) {
+	t, ok := f.tournaments[id]
+	if !ok {
+		return nil, he.HTTPCodedErrorf(http.StatusNotFound, "no such tournament")
+	}
+	return t.Clone(), nil
+}
+
+func (f *fakeTournaments) SaveTournament(ctx context.Context, t *model.Tournament) error {
+	f.saves++
+	t = t.Clone()
+	t.Version++
+	f.tournaments[t.EventID] = t
+	return nil
+}
+
+type fakeApp struct{ state.AppStorage }
+
+type fakeTemplates struct {
+	state.TournamentTemplateStorage
+}
+
+const (
+	operator = iota + 1
+	dealer   // not an operator
+)
+
+type testAPI struct {
+	mux         *http.ServeMux
+	tournaments *fakeTournaments
+	secrets     map[string]string // by name
+}
+
+func newTestAPI(t *testing.T) *testAPI {
+	t.Helper()
+	clock := clockwork.NewFakeClockAt(time.Date(2026, 10, 18, 19, 0, 0, 0, time.UTC))
+	tokens := &fakeTokens{byHash: map[string]*model.APIToken{}}
+	ta := &testAPI{
+		mux: http.NewServeMux(),
+		tournaments: &fakeTournaments{tournaments: map[int64]*model.Tournament{
+			1: {
+				EventID:          1,
+				Version:          1,
+				EventName:        "Thursday Hold'em",
+				NextLevelSoundID: -1,
+				Structure: model.StructureData{Levels: []*model.Level{
+					{Description: "Level 1", DurationMinutes: 20},
+					{Description: "Level 2", DurationMinutes: 20},
+				}},
+				State: &model.State{CurrentPlayers: 10},
+			},
+		}},
+		secrets: map[string]string{},
+	}
+	for i, tok := range []struct {
+		name   string
+		userID int64
+		scopes []string
+	}{
+		{"read-only", operator, []string{apitoken.ScopeRead}},
+		{"control", operator, []string{apitoken.ScopeRead, apitoken.ScopeControl}},
+		{"dealer", dealer, []string{apitoken.ScopeRead, apitoken.ScopeControl}},
+	} {
+		secret, hash := apitoken.New()
+		tokens.byHash[hash] = &model.APIToken{ID: int64(i + 1), UserID: tok.userID, Name: tok.name, Scopes: tok.scopes}
+		ta.secrets[tok.name] = secret
+	}
+
+	New(&Config{
+		TokenStorage: tokens,
+		UserStorage: &fakeUsers{users: map[int64]*model.UserIdentity{
+			operator: {ID: operator, Nick: "floor", IsOperator: true},
+			dealer:   {ID: dealer, Nick: "dealer"},
+		}},
+		TournamentStorage: ta.tournaments,
+		AppStorage:        &fakeApp{},
+		TemplateStorage:   &fakeTemplates{},
+		TournamentManager: tournament.NewManager(clock, nil, nil),
+		Clock:             clock,
+	}).Install(ta.mux)
+	return ta
+}
+
+// do makes a request with the named token, or none if name is "", and
+// decodes the answer into v.
+func (ta *testAPI) do(t *testing.T, method, path, token, body string, v any) int {
+	t.Helper()
+	r := httptest.NewRequest(method, path, strings.NewReader(body))
+	switch token {
+	case "":
+	case "unknown":
+		r.Header.Set("Authorization", "Bearer "+apitoken.Prefix+"nosuchtoken")
+	default:
+		r.Header.Set("Authorization", "Bearer "+ta.secrets[token])
+	}
+	w := httptest.NewRecorder()
+	ta.mux.ServeHTTP(w, r)
+	if ct := w.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("%s %s: Content-Type %q", method, path, ct)
+	}
+	if v != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), v); err != nil {
+			t.Errorf("%s %s: can't decode %q: %v", method, path, w.Body, err)
+		}
+	}
+	return w.Code
+}
+
+func TestAPIRefusals(t *testing.T) {
+	for _, tc := range []struct {
+		name, method, path, token, body string
+		want                            int
+	}{
+		{"no token", "GET", "/api/v1/tournaments/1", "", "", http.StatusUnauthorized},
+		{"unknown token", "GET", "/api/v1/tournaments/1", "unknown", "", http.StatusUnauthorized},
+		{"read-only token", "POST", "/api/v1/tournaments/1/actions/start", "read-only", "", http.StatusForbidden},
+		{"not an operator", "POST", "/api/v1/tournaments/1/actions/start", "dealer", "", http.StatusForbidden},
+		{"unknown action", "POST", "/api/v1/tournaments/1/actions/shuffle", "control", "", http.StatusNotFound},
+		{"negative count", "POST", "/api/v1/tournaments/1/actions/add-player", "control", `{"Count": -1}`, http.StatusBadRequest},
+		{"huge count", "POST", "/api/v1/tournaments/1/actions/add-time", "control", fmt.Sprintf(`{"Count": %d}`, maxCount+1), http.StatusBadRequest},
+		{"unknown field", "POST", "/api/v1/tournaments/1/actions/add-player", "control", `{"Players": 2}`, http.StatusBadRequest},
+		{"unknown tournament", "GET", "/api/v1/tournaments/2", "read-only", "", http.StatusNotFound},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ta := newTestAPI(t)
+			var e Error
+			if got := ta.do(t, tc.method, tc.path, tc.token, tc.body, &e); got != tc.want {
+				t.Errorf("status %d, want %d (%q)", got, tc.want, e.Error)
+			}
+			if e.Error == "" {
+				t.Errorf("no error message")
+			}
+			if ta.tournaments.saves != 0 {
+				t.Errorf("refused request saved the tournament")
+			}
+		})
+	}
+}
+
+func TestAPIAction(t *testing.T) {
+	ta := newTestAPI(t)
+
+	var got model.Tournament
+	if code := ta.do(t, "POST", "/api/v1/tournaments/1/actions/add-player", "control", `{"Count": 3}`, &got); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if got.State.CurrentPlayers != 13 {
+		t.Errorf("answered with %d players, want 13", got.State.CurrentPlayers)
+	}
+	if got.Version != 2 {
+		t.Errorf("answered with version %d, want the saved one", got.Version)
+	}
+	if ta.tournaments.saves != 1 || ta.tournaments.tournaments[1].State.CurrentPlayers != 13 {
+		t.Errorf("not saved")
+	}
+
+	if code := ta.do(t, "POST", "/api/v1/tournaments/1/actions/next-level", "control", "", &got); code != http.StatusOK {
+		t.Fatalf("status %d", code)
+	}
+	if got.State.CurrentLevelNumber != 1 {
+		t.Errorf("at level %d, want 1", got.State.CurrentLevelNumber)
+	}
+	// The read-only token can see the result.
+	if code := ta.do(t, "GET", "/api/v1/tournaments/1", "read-only", "", &got); code != http.StatusOK || got.Version != 3 {
+		t.Errorf("read back status %d, version %d", code, got.Version)
+	}
+}
+
+func TestAPICountCapped(t *testing.T) {
+	ta := newTestAPI(t)
+	var got model.Tournament
+	body := fmt.Sprintf(`{"Count": %d}`, maxCount)
+	if code := ta.do(t, "POST", "/api/v1/tournaments/1/actions/add-player", "control", body, &got); code != http.StatusOK {
+		t.Fatalf("status %d for Count %d", code, maxCount)
+	}
+	if got.State.CurrentPlayers != 10+maxCount {
+		t.Errorf("%d players, want %d", got.State.CurrentPlayers, 10+maxCount)
+	}
+}
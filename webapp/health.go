@@ -0,0 +1,155 @@
+package webapp
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/varz"
+)
+
+const (
+	// defaultDrainTimeout fits inside the ten seconds Cloud Run allows
+	// between SIGTERM and SIGKILL.
+	defaultDrainTimeout = 8 * time.Second
+
+	healthCheckTimeout = 2 * time.Second
+)
+
+var (
+	listensDrained = varz.NewInt("listensDrained")
+	drains         = varz.NewInt("drains")
+)
+
+// shutdowner is an http.Server or an http3.Server.
+type shutdowner interface {
+	Shutdown(ctx context.Context) error
+	Close() error
+}
+
+// isDraining says whether the server is on its way down.
+func (app *App) isDraining() bool {
+	select {
+	case <-app.draining:
+		return true
+	default:
+		return false
+	}
+}
+
+// sendReconnect answers a long poll cut short by a drain.  The client
+// should come straight back, and will find another server (or this one,
+// restarted).  The short random wait keeps a room full of clocks from
+// arriving all at once.
+func sendReconnect(w http.ResponseWriter) {
+	listensDrained.Add(1)
+	w.Header().Set("Retry-After", strconv.Itoa(1+rand.IntN(3)))
+	http.Error(w, "server restarting, reconnect", http.StatusServiceUnavailable)
+}
+
+// drain stops the servers: it answers the long polls, stops taking new
+// connections, and waits for requests in flight, for up to drainTimeout.
+// Anything left after that is cut off.  It closes drained when it's done.
+func (app *App) drain(servers []shutdowner) {
+	defer close(app.drained)
+	drains.Add(1)
+	app.drainOnce.Do(func() { close(app.draining) })
+	logger.Info("draining", "timeout", app.drainTimeout)
+
+	ctx, cancel := context.WithTimeout(context.Background(), app.drainTimeout)
+	defer cancel()
+	wg := sync.WaitGroup{}
+	for _, s := range servers {
+		wg.Go(func() {
+			if err := s.Shutdown(ctx); err != nil {
+				logger.Warn("drain didn't finish, closing", "err", err)
+				s.Close()
+			}
+		})
+	}
+	wg.Go(func() {
+		app.bonusPorts.shutdown(ctx)
+	})
+	wg.Wait()
+	logger.Info("drained")
+}
+
+// healthErrors checks what the server needs to be any use: the database,
+// and the listener that tells us about changes.
+func (app *App) healthErrors(ctx context.Context) map[string]error {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	var listenErr error
+	if !app.dbListener.Listening() {
+		listenErr = errors.New("not listening")
+	}
+	return map[string]error{
+		"db":       app.healthStorage.Ping(ctx),
+		"dbnotify": listenErr,
+	}
+}
+
+func writeHealth(w http.ResponseWriter, errs map[string]error) {
+	code := http.StatusOK
+	for _, err := range errs {
+		if err != nil {
+			code = http.StatusServiceUnavailable
+		}
+	}
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(code)
+	for _, name := range []string{"db", "dbnotify", "drain"} {
+		err, ok := errs[name]
+		if !ok {
+			continue
+		}
+		if err != nil {
+			fmt.Fprintf(w, "%s: %v\n", name, err)
+		} else {
+			fmt.Fprintf(w, "%s: ok\n", name)
+		}
+	}
+}
+
+// handleHealthz says whether this server is working.
+func (app *App) handleHealthz(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	writeHealth(w, app.healthErrors(ctx))
+}
+
+// handleReadyz says whether this server wants requests: it's healthy and
+// isn't on its way down.
+func (app *App) handleReadyz(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	errs := app.healthErrors(ctx)
+	errs["drain"] = nil
+	if app.isDraining() {
+		errs["drain"] = errors.New("draining")
+	}
+	writeHealth(w, errs)
+}
+
+// handleMetrics serves the metrics to an administrator, or to a scraper
+// that has the bearer token from MetricsToken.  They say more about the
+// site than visitors need to know.
+func (app *App) handleMetrics(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	if !permission.IsAdmin(ctx) && !app.hasMetricsToken(r) {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="metrics"`)
+		he.SendErrorToHTTPClient(w, "authorize", he.HTTPCodedErrorf(http.StatusUnauthorized, "permission denied"))
+		return
+	}
+	varz.MetricsHandler().ServeHTTP(w, r)
+}
+
+func (app *App) hasMetricsToken(r *http.Request) bool {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	return ok && app.metricsToken != "" && subtle.ConstantTimeCompare([]byte(token), []byte(app.metricsToken)) == 1
+}
@@ -0,0 +1,272 @@
+package webapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/protocol"
+)
+
+type fakeListener struct {
+	listening atomic.Bool
+}
+
+func (f *fakeListener) Listen(ctx context.Context) error {
+	f.listening.Store(true)
+	<-ctx.Done()
+	f.listening.Store(false)
+	return ctx.Err()
+}
+
+func (f *fakeListener) Listening() bool { return f.listening.Load() }
+
+type fakeHealth struct{ err error }
+
+func (f *fakeHealth) Ping(ctx context.Context) error { return f.err }
+
+type fakeSiteReader struct{}
+
+func (fakeSiteReader) FetchSiteConfig(ctx context.Context) (*model.SiteConfig, error) {
+	return &model.SiteConfig{}, nil
+}
+
+func newHealthApp(listening bool) *App {
+	l := &fakeListener{}
+	l.listening.Store(listening)
+	return &App{
+		dbListener:    l,
+		healthStorage: &fakeHealth{},
+		draining:      make(chan struct{}),
+		drained:       make(chan struct{}),
+	}
+}
+
+func health(t *testing.T, handle func(context.Context, http.ResponseWriter, *http.Request)) (int, string) {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/", nil)
+	w := httptest.NewRecorder()
+	handle(r.Context(), w, r)
+	return w.Code, w.Body.String()
+}
+
+func TestHealthzNeedsListener(t *testing.T) {
+	app := newHealthApp(true)
+	if code, body := health(t, app.handleHealthz); code != http.StatusOK {
+		t.Errorf("healthy server: %d %q", code, body)
+	}
+
+	app = newHealthApp(false)
+	code, body := health(t, app.handleHealthz)
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "dbnotify: not listening") {
+		t.Errorf("server not listening: %d %q", code, body)
+	}
+}
+
+func TestHealthzNeedsDatabase(t *testing.T) {
+	app := newHealthApp(true)
+	app.healthStorage = &fakeHealth{errors.New("connection refused")}
+	code, body := health(t, app.handleHealthz)
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "db: connection refused") {
+		t.Errorf("server without a database: %d %q", code, body)
+	}
+}
+
+func TestReadyzWhileDraining(t *testing.T) {
+	app := newHealthApp(true)
+	if code, body := health(t, app.handleReadyz); code != http.StatusOK || !strings.Contains(body, "drain: ok") {
+		t.Errorf("before the drain: %d %q", code, body)
+	}
+
+	close(app.draining)
+	code, body := health(t, app.handleReadyz)
+	if code != http.StatusServiceUnavailable || !strings.Contains(body, "drain: draining") {
+		t.Errorf("while draining: %d %q", code, body)
+	}
+	// Draining isn't unhealthy; the server is finishing its work.
+	if code, body := health(t, app.handleHealthz); code != http.StatusOK {
+		t.Errorf("healthz while draining: %d %q", code, body)
+	}
+}
+
+func TestDrainAsksListenToReconnect(t *testing.T) {
+	app, _ := newListenApp()
+	body := fmt.Sprintf(`{"TournamentID": 1, "Version": 5, "ProtocolVersion": %d}`, protocol.Version)
+	r := httptest.NewRequest(http.MethodPost, "/api/tournament-listen", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	done := make(chan struct{})
+	go func() {
+		app.handleAPITournamentListen(r.Context(), w, r)
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		t.Fatalf("listen answered with nothing changed: %d %q", w.Code, w.Body)
+	case <-time.After(50 * time.Millisecond):
+	}
+	close(app.draining)
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("listen didn't answer the drain")
+	}
+
+	if w.Code != http.StatusServiceUnavailable {
+		t.Errorf("status %d, want %d", w.Code, http.StatusServiceUnavailable)
+	}
+	if n, err := strconv.Atoi(w.Header().Get("Retry-After")); err != nil || n < 1 || n > 3 {
+		t.Errorf("Retry-After %q, want 1 to 3 seconds", w.Header().Get("Retry-After"))
+	}
+}
+
+// serveSlowly starts app.Serve with a handler that holds each request until
+// release is closed.  It returns once a request is in flight.
+func serveSlowly(t *testing.T, app *App, release <-chan struct{}) (cancel func(), served <-chan error, response <-chan error) {
+	t.Helper()
+	started := make(chan struct{})
+	app.handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+		io.WriteString(w, "done")
+	})
+	app.siteStorageReader = fakeSiteReader{}
+	app.bonusPorts = newBonusPorts(app.handler, nil)
+	addr := fmt.Sprintf("127.0.0.1:%d", freePort(t))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	serveCh := make(chan error, 1)
+	go func() { serveCh <- app.Serve(ctx, addr) }()
+
+	responseCh := make(chan error, 1)
+	go func() {
+		for deadline := time.Now().Add(time.Second); ; time.Sleep(10 * time.Millisecond) {
+			resp, err := http.Get("http://" + addr + "/slow")
+			if err != nil && time.Now().Before(deadline) {
+				continue // not listening yet
+			}
+			if err == nil {
+				_, err = io.ReadAll(resp.Body)
+				resp.Body.Close()
+			}
+			responseCh <- err
+			return
+		}
+	}()
+	select {
+	case <-started:
+	case err := <-responseCh:
+		t.Fatalf("request failed: %v", err)
+	case <-time.After(2 * time.Second):
+		t.Fatal("request never arrived")
+	}
+	return cancel, serveCh, responseCh
+}
+
+func TestServeWaitsForRequestsToFinish(t *testing.T) {
+	app := newHealthApp(false)
+	app.drainTimeout = 10 * time.Second
+	release := make(chan struct{})
+	cancel, served, response := serveSlowly(t, app, release)
+
+	cancel()
+	select {
+	case err := <-served:
+		t.Fatalf("Serve returned with a request in flight: %v", err)
+	case <-time.After(100 * time.Millisecond):
+	}
+	if !app.dbListener.Listening() {
+		t.Error("listener stopped when the drain started")
+	}
+
+	close(release)
+	if err := <-response; err != nil {
+		t.Errorf("request cut off: %v", err)
+	}
+	select {
+	case err := <-served:
+		if err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("Serve didn't return after the last request finished")
+	}
+	if app.dbListener.Listening() {
+		t.Error("listener still running after Serve returned")
+	}
+}
+
+func TestServeGivesUpAfterDrainTimeout(t *testing.T) {
+	app := newHealthApp(false)
+	app.drainTimeout = 200 * time.Millisecond
+	release := make(chan struct{})
+	defer close(release)
+	cancel, served, _ := serveSlowly(t, app, release)
+
+	start := time.Now()
+	cancel()
+	select {
+	case err := <-served:
+		if elapsed := time.Since(start); elapsed < app.drainTimeout {
+			t.Errorf("Serve returned after %v, before the drain timeout", elapsed)
+		}
+		if err != nil {
+			t.Errorf("Serve: %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("Serve didn't give up on the stuck request")
+	}
+}
+
+func TestMetricsNeedAdminOrToken(t *testing.T) {
+	app := newHealthApp(true)
+	app.metricsToken = "s3cret"
+	admin := permission.UserIdentityInContext(context.Background(), &model.UserIdentity{Nick: "boss", IsAdmin: true})
+	operator := permission.UserIdentityInContext(context.Background(), &model.UserIdentity{Nick: "dealer", IsOperator: true})
+	for _, tc := range []struct {
+		name          string
+		ctx           context.Context
+		authorization string
+		want          int
+	}{
+		{"nobody", context.Background(), "", http.StatusUnauthorized},
+		{"operator", operator, "", http.StatusUnauthorized},
+		{"admin", admin, "", http.StatusOK},
+		{"token", context.Background(), "Bearer s3cret", http.StatusOK},
+		{"wrong token", context.Background(), "Bearer guess", http.StatusUnauthorized},
+		{"token as basic", context.Background(), "Basic s3cret", http.StatusUnauthorized},
+	} {
+		r := httptest.NewRequestWithContext(tc.ctx, http.MethodGet, "/metrics", nil)
+		if tc.authorization != "" {
+			r.Header.Set("Authorization", tc.authorization)
+		}
+		w := httptest.NewRecorder()
+		app.handleMetrics(r.Context(), w, r)
+		if w.Code != tc.want {
+			t.Errorf("%s: status %d, want %d", tc.name, w.Code, tc.want)
+		}
+		if w.Code == http.StatusOK && !strings.Contains(w.Body.String(), "listensDrained") {
+			t.Errorf("%s: metrics are missing listensDrained:\n%s", tc.name, w.Body)
+		}
+	}
+
+	// With no token set, an empty bearer token is no key.
+	app.metricsToken = ""
+	r := httptest.NewRequest(http.MethodGet, "/metrics", nil)
+	r.Header.Set("Authorization", "Bearer ")
+	w := httptest.NewRecorder()
+	app.handleMetrics(r.Context(), w, r)
+	if w.Code != http.StatusUnauthorized {
+		t.Errorf("empty token: status %d", w.Code)
+	}
+}
@@ -0,0 +1,64 @@
+package webapp
+
+import (
+	"crypto/tls"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/quic-go/quic-go"
+	"github.com/quic-go/quic-go/http3"
+
+	"github.com/ts4z/irata/varz"
+)
+
+var (
+	// Keyed by r.Proto: "HTTP/1.1", "HTTP/2.0" or "HTTP/3.0".
+	listensByProto     = varz.NewMap("listensByProto")
+	openListensByProto = varz.NewGaugeMap("openListensByProto")
+)
+
+// countListen counts a long poll by protocol, and as open until the returned
+// func is called.
+func countListen(r *http.Request) func() {
+	listensByProto.Add(r.Proto, 1)
+	openListensByProto.Add(r.Proto, 1)
+	return func() {
+		openListensByProto.Add(r.Proto, -1)
+	}
+}
+
+// newHTTP3Server makes an HTTP/3 server for addr, UDP rather than TCP.
+//
+// A clock holds a long poll open for up to an hour with nothing to say, so
+// QUIC keeps the connection alive with pings rather than letting it go idle.
+// That also lets a display that changes networks (flaky venue Wi-Fi) carry
+// on with the same connection.
+//
+// 0-RTT stays off: early data can be replayed, and a replayed POST would
+// repeat a clock action.
+func newHTTP3Server(addr string, h http.Handler, tlsConfig *tls.Config) *http3.Server {
+	return &http3.Server{
+		Addr:      addr,
+		Handler:   h,
+		TLSConfig: tlsConfig,
+		QUICConfig: &quic.Config{
+			MaxIdleTimeout:  time.Minute,
+			KeepAlivePeriod: 20 * time.Second,
+		},
+		IdleTimeout: 12 * time.Hour,
+	}
+}
+
+// advertiseHTTP3 adds an Alt-Svc header to everything h serves, so browsers
+// that speak HTTP/3 move over to h3.
+func advertiseHTTP3(h3 *http3.Server, h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			if err := h3.SetQUICHeaders(w.Header()); err != nil {
+				log.Printf("can't advertise HTTP/3: %v", err)
+			}
+		}
+		h.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,78 @@
+package webapp
+
+import (
+	"crypto/tls"
+	"expvar"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestAdvertiseHTTP3(t *testing.T) {
+	// Alt-Svc names the port h3 is listening on, so it has to be listening.
+	pc, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	port := pc.LocalAddr().(*net.UDPAddr).Port
+	h3 := newHTTP3Server("", http.NotFoundHandler(), &tls.Config{})
+	go h3.Serve(pc)
+	defer h3.Close()
+	for deadline := time.Now().Add(time.Second); h3.SetQUICHeaders(http.Header{}) != nil; {
+		if time.Now().After(deadline) {
+			t.Fatal("HTTP/3 server didn't start")
+		}
+		time.Sleep(time.Millisecond)
+	}
+	want := fmt.Sprintf(`h3=":%d"`, port)
+	h := advertiseHTTP3(h3, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for _, tc := range []struct {
+		proto     string
+		major     int
+		advertise bool
+	}{
+		{"HTTP/1.1", 1, true},
+		{"HTTP/2.0", 2, true},
+		{"HTTP/3.0", 3, false},
+	} {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Proto, r.ProtoMajor, r.ProtoMinor = tc.proto, tc.major, 0
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, r)
+		altSvc := w.Header().Get("Alt-Svc")
+		if advertised := strings.Contains(altSvc, want); advertised != tc.advertise {
+			t.Errorf("%s: Alt-Svc %q, want advertised %v", tc.proto, altSvc, tc.advertise)
+		}
+	}
+}
+
+func gaugeValue(m *expvar.Map, key string) int64 {
+	if v, ok := m.Get(key).(*expvar.Int); ok {
+		return v.Value()
+	}
+	return 0
+}
+
+func TestCountListen(t *testing.T) {
+	const proto = "HTTP/3.0"
+	r := httptest.NewRequest(http.MethodPost, "/api/tournament-listen", nil)
+	r.Proto = proto
+
+	total, open := gaugeValue(listensByProto, proto), gaugeValue(openListensByProto, proto)
+	done := countListen(r)
+	if got := gaugeValue(openListensByProto, proto); got != open+1 {
+		t.Errorf("open listens %d during the listen, want %d", got, open+1)
+	}
+	done()
+	if got := gaugeValue(openListensByProto, proto); got != open {
+		t.Errorf("open listens %d after the listen, want %d", got, open)
+	}
+	if got := gaugeValue(listensByProto, proto); got != total+1 {
+		t.Errorf("listens %d, want %d", got, total+1)
+	}
+}
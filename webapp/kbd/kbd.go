@@ -4,11 +4,11 @@ import (
 	"context"
 	"encoding/json"
 	"io"
-	"log"
 	"net/http"
 	"time"
 
 	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/logz"
 	"github.com/ts4z/irata/model"
 	"github.com/ts4z/irata/permission"
 	"github.com/ts4z/irata/state"
@@ -16,6 +16,8 @@ import (
 	"github.com/ts4z/irata/varz"
 )
 
+var logger = logz.New()
+
 var (
 	keyboardEventsReceived  = varz.NewInt("keyboardEventsReceived")
 	keyboardEventsSuccesses = varz.NewInt("keyboardEventsSuccesses")
@@ -50,12 +52,10 @@ type KeyboardShortcutDispatcher struct {
 func NewKeyboardShortcutDispatcher(tm *tournament.Manager, ts state.TournamentStorage) *KeyboardShortcutDispatcher {
 	k2m := map[string]func(ctx context.Context, t *model.Tournament, bb *modifiers) error{
 		"StopSlideshow": func(ctx context.Context, t *model.Tournament, bb *modifiers) error {
-			log.Printf("StopSlideshow")
 			t.State.Slideshow = false
 			return nil
 		},
 		"StartSlideshow": func(ctx context.Context, t *model.Tournament, bb *modifiers) error {
-			log.Printf("StartSlideshow")
 			t.State.Slideshow = true
 			return nil
 		},
@@ -108,7 +108,7 @@ func NewKeyboardShortcutDispatcher(tm *tournament.Manager, ts state.TournamentSt
 func (app *KeyboardShortcutDispatcher) HandleKeypress(ctx context.Context, r *http.Request) error {
 	body, err := io.ReadAll(r.Body)
 	if err != nil {
-		log.Printf("can't read response body: %v", err)
+		logger.WarnContext(ctx, "can't read request body", "err", err)
 	}
 
 	type KeyboardModifyEvent struct {
@@ -119,7 +119,7 @@ func (app *KeyboardShortcutDispatcher) HandleKeypress(ctx context.Context, r *ht
 
 	var event KeyboardModifyEvent
 	if err := json.Unmarshal(body, &event); err != nil {
-		log.Printf("can't unmarshal event %s: %v", string(body), err)
+		logger.WarnContext(ctx, "can't unmarshal keyboard event", "body", string(body), "err", err)
 	}
 
 	// Redundant check (storage checks too) to marginally improve logs + error.
@@ -129,6 +129,7 @@ func (app *KeyboardShortcutDispatcher) HandleKeypress(ctx context.Context, r *ht
 
 	keyboardEventsReceived.Add(1)
 	keyboardEventsByType.Add(event.Event, 1)
+	logger.DebugContext(ctx, "keyboard event", "tournament", event.TournamentID, "event", event.Event, "shift", event.Shift)
 
 	if h, ok := app.keyToMutation[event.Event]; !ok {
 
@@ -0,0 +1,397 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	lru "github.com/hashicorp/golang-lru/v2"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/middleware/clientip"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/protocol"
+	"github.com/ts4z/irata/slideshow"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/tournament"
+	"github.com/ts4z/irata/varz"
+)
+
+const (
+	// A kiosk re-polls at least this often, and every poll is a heartbeat.
+	kioskListenTimeout = time.Minute
+	// A display that hasn't polled in this long is shown as offline.
+	displayOfflineAfter = 3 * kioskListenTimeout
+
+	// kioskListenMaxBytes is plenty for a device ID and two versions.
+	kioskListenMaxBytes = 1 << 10
+
+	// Nobody logs in to register a display, so each address may only
+	// register newDisplaysPerAddress new ones every newDisplayWindow, which
+	// is more than a club with a dozen TVs behind one NAT needs.
+	newDisplaysPerAddress = 20
+	newDisplayWindow      = time.Hour
+	// newDisplayAddresses is how many addresses the limit keeps track of.
+	newDisplayAddresses = 1024
+)
+
+var (
+	kioskListens          = varz.NewInt("kioskListens")
+	kioskListenTimeouts   = varz.NewInt("kioskListenTimeouts")
+	kioskNotifiedClient   = varz.NewInt("kioskNotifiedClient")
+	kioskClientClosed     = varz.NewInt("kioskClientClosed")
+	displayAssignmentSets = varz.NewInt("displayAssignmentSets")
+	newDisplaysRefused    = varz.NewInt("newDisplaysRefused")
+)
+
+// newDisplayLimiter counts the displays each address has registered since
+// its window started.  It keeps real time even when the clock doesn't.
+type newDisplayLimiter struct {
+	now func() time.Time
+
+	mu     sync.Mutex
+	counts *lru.Cache[string, *newDisplayCount]
+}
+
+type newDisplayCount struct {
+	since time.Time
+	n     int
+}
+
+func newNewDisplayLimiter(now func() time.Time) *newDisplayLimiter {
+	counts, err := lru.New[string, *newDisplayCount](newDisplayAddresses)
+	if err != nil {
+		log.Fatalf("can't create new display limiter: %v", err)
+	}
+	return &newDisplayLimiter{now: now, counts: counts}
+}
+
+// allow counts a new display from addr, unless addr has had its fill.
+func (l *newDisplayLimiter) allow(addr string) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	c, ok := l.counts.Get(addr)
+	if !ok || now.Sub(c.since) >= newDisplayWindow {
+		c = &newDisplayCount{since: now}
+		l.counts.Add(addr, c)
+	}
+	if c.n >= newDisplaysPerAddress {
+		return false
+	}
+	c.n++
+	return true
+}
+
+// kioskState is what the kiosk page needs to know about itself.
+type kioskState struct {
+	DisplayID       int64
+	Version         int64
+	DeviceID        string
+	Name            string
+	URL             string // empty means show the waiting screen
+	ProtocolVersion int64
+}
+
+// displayURL is the page a display should load for its assignment.
+func displayURL(d *model.Display) string {
+	switch d.Mode {
+	case model.DisplayModeTournament:
+		if d.TournamentID > 0 && d.LayoutID > 0 {
+			return fmt.Sprintf("/t/%d?layout=%d", d.TournamentID, d.LayoutID)
+		} else if d.TournamentID > 0 {
+			return fmt.Sprintf("/t/%d", d.TournamentID)
+		}
+	case model.DisplayModeLobby:
+		return "/lobby"
+	case model.DisplayModeSlideshow:
+		return "/slideshow"
+	}
+	return ""
+}
+
+// assignmentValue encodes a display's assignment as a single form value, so
+// the management page can use one select for it.
+func assignmentValue(d *model.Display) string {
+	if d.Mode == model.DisplayModeTournament {
+		return fmt.Sprintf("%s:%d", d.Mode, d.TournamentID)
+	}
+	return string(d.Mode)
+}
+
+func parseAssignment(v string) (model.DisplayMode, int64, error) {
+	mode, idStr, hasID := strings.Cut(v, ":")
+	switch model.DisplayMode(mode) {
+	case model.DisplayModeUnassigned, model.DisplayModeLobby, model.DisplayModeSlideshow:
+		if hasID {
+			return "", 0, he.HTTPCodedErrorf(http.StatusBadRequest, "unexpected tournament for %q", mode)
+		}
+		return model.DisplayMode(mode), 0, nil
+	case model.DisplayModeTournament:
+		id, err := strconv.ParseInt(idStr, 10, 64)
+		if err != nil || id <= 0 {
+			return "", 0, he.HTTPCodedErrorf(http.StatusBadRequest, "bad tournament in assignment %q", v)
+		}
+		return model.DisplayModeTournament, id, nil
+	default:
+		return "", 0, he.HTTPCodedErrorf(http.StatusBadRequest, "unknown assignment %q", v)
+	}
+}
+
+func (app *App) handleKiosk(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	data := struct {
+		Theme           string
+		SiteName        string
+		ProtocolVersion int64
+	}{
+		Theme:           sc.Theme,
+		SiteName:        sc.Name,
+		ProtocolVersion: protocol.Version,
+	}
+	if err := app.templates.ExecuteTemplate(w, "kiosk.html.tmpl", data); err != nil {
+		log.Printf("can't render kiosk template: %v", err)
+	}
+}
+
+// handleAPIKioskListen registers a heartbeat for the calling display, then
+// waits for its assignment to change.  It answers right away if the caller
+// is behind, and otherwise answers with the unchanged state after
+// kioskListenTimeout so the next poll can register another heartbeat.
+func (app *App) handleAPIKioskListen(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	kioskListens.Add(1)
+	defer countListen(r)()
+	var req struct {
+		DeviceID        string
+		Version         int64
+		ProtocolVersion int64
+	}
+	if err := json.NewDecoder(http.MaxBytesReader(w, r.Body, kioskListenMaxBytes)).Decode(&req); err != nil {
+		he.SendErrorToHTTPClient(w, "/api/kiosk-listen", he.HTTPCodedErrorf(http.StatusBadRequest, "decoding json: %w", err))
+		return
+	}
+	if err := state.CheckDeviceID(req.DeviceID); err != nil {
+		he.SendErrorToHTTPClient(w, "/api/kiosk-listen", err)
+		return
+	}
+
+	addr := clientip.Of(r).String()
+	if _, err := app.displayStorage.FetchDisplayByDeviceID(ctx, req.DeviceID); he.Code(err) == http.StatusNotFound {
+		if !app.newDisplays.allow(addr) {
+			newDisplaysRefused.Add(1)
+			he.SendErrorToHTTPClient(w, "/api/kiosk-listen",
+				he.HTTPCodedErrorf(http.StatusTooManyRequests, "too many new displays from %s", addr))
+			return
+		}
+	} else if err != nil {
+		he.SendErrorToHTTPClient(w, "look up display", err)
+		return
+	}
+
+	d, err := app.displayStorage.RegisterDisplay(ctx, req.DeviceID, addr, r.UserAgent())
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "register display", err)
+		return
+	}
+
+	if d.Version == req.Version && req.ProtocolVersion == protocol.Version {
+		errCh := make(chan error, 1)
+		displayCh := make(chan *model.Display, 1)
+		app.displayGossiper.ListenDisplayVersion(ctx, d.DisplayID, d.Version, errCh, displayCh)
+		select {
+		case err := <-errCh:
+			he.SendErrorToHTTPClient(w, "listen for display change", err)
+			return
+		case d = <-displayCh:
+			kioskNotifiedClient.Add(1)
+		case <-time.After(kioskListenTimeout):
+			kioskListenTimeouts.Add(1)
+			app.displayGossiper.Forget(d.DisplayID, displayCh)
+		case <-ctx.Done():
+			kioskClientClosed.Add(1)
+			app.displayGossiper.Forget(d.DisplayID, displayCh)
+			http.Error(w, "request cancelled", http.StatusRequestTimeout)
+			return
+		case <-app.draining:
+			app.displayGossiper.Forget(d.DisplayID, displayCh)
+			sendReconnect(w)
+			return
+		}
+	}
+
+	bytes, err := json.Marshal(&kioskState{
+		DisplayID:       d.DisplayID,
+		Version:         d.Version,
+		DeviceID:        d.DeviceID,
+		Name:            d.Name,
+		URL:             displayURL(d),
+		ProtocolVersion: protocol.Version,
+	})
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "marshal display", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+}
+
+func (app *App) handleSlideshow(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	// There's no tournament here, so only slides that stand on their own.
+	slides := []slideshow.Show{}
+	for _, show := range app.slidesFor(ctx, 0, sc) {
+		standsAlone := show.Slide.Kind == model.SlideKindMarkdown || show.Slide.Kind == model.SlideKindImage ||
+			(show.Slide.Kind == model.SlideKindLeague && show.League != nil && len(show.League.Rows) > 0)
+		if show.Trigger == model.SlideTriggerRotation && standsAlone {
+			slides = append(slides, show)
+		}
+	}
+	data := struct {
+		Theme    string
+		SiteName string
+		Slides   []slideshow.Show
+	}{
+		Theme:    sc.Theme,
+		SiteName: sc.Name,
+		Slides:   slides,
+	}
+	if err := app.templates.ExecuteTemplate(w, "slideshow.html.tmpl", data); err != nil {
+		log.Printf("can't render slideshow template: %v", err)
+	}
+}
+
+func (app *App) applyDisplayForm(ctx context.Context, r *http.Request) (string, error) {
+	if err := r.ParseForm(); err != nil {
+		return "", he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+	id, err := strconv.ParseInt(r.FormValue("DisplayID"), 10, 64)
+	if err != nil {
+		return "", he.HTTPCodedErrorf(http.StatusBadRequest, "bad display id")
+	}
+
+	switch r.FormValue("Action") {
+	case "save":
+		d, err := app.displayStorage.FetchDisplay(ctx, id)
+		if err != nil {
+			return "", err
+		}
+		mode, tournamentID, err := parseAssignment(r.FormValue("Assignment"))
+		if err != nil {
+			return "", err
+		}
+		d.Name = strings.TrimSpace(r.FormValue("Name"))
+		d.Mode = mode
+		d.TournamentID = tournamentID
+		d.LayoutID, err = strconv.ParseInt(r.FormValue("LayoutID"), 10, 64)
+		if err != nil {
+			return "", he.HTTPCodedErrorf(http.StatusBadRequest, "bad layout id")
+		}
+		if err := app.displayStorage.SaveDisplay(ctx, d); err != nil {
+			return "", err
+		}
+		displayAssignmentSets.Add(1)
+		return fmt.Sprintf("Saved display %d.", id), nil
+	case "delete":
+		if err := app.displayStorage.DeleteDisplay(ctx, id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Forgot display %d.  It will re-register if it is still running.", id), nil
+	default:
+		return "", he.HTTPCodedErrorf(http.StatusBadRequest, "unknown action")
+	}
+}
+
+func (app *App) handleManageDisplays(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var flash, flashType string
+	if r.Method == http.MethodPost {
+		if msg, err := app.applyDisplayForm(ctx, r); err != nil {
+			log.Printf("manage displays: %v", err)
+			flash, flashType = err.Error(), "boo"
+		} else {
+			flash, flashType = msg, "yay"
+		}
+	}
+
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	displays, err := app.displayStorage.FetchDisplays(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch displays", err)
+		return
+	}
+	// TODO: pagination
+	overview, err := app.tournamentStorage.FetchOverview(ctx, tournament.CurrentLifecycles, 0, 100)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch overview", err)
+		return
+	}
+
+	layouts, err := app.layoutStorage.FetchLayouts(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch layouts", err)
+		return
+	}
+
+	type displayRow struct {
+		*model.Display
+		Assignment   string
+		Online       bool
+		HeartbeatAgo string
+	}
+	// Heartbeats are stamped by the database in real time, even when the
+	// clock is accelerated for a demo.
+	now := time.Now()
+	rows := make([]displayRow, len(displays))
+	for i, d := range displays {
+		row := displayRow{Display: d, Assignment: assignmentValue(d), HeartbeatAgo: "never"}
+		if !d.LastHeartbeat.IsZero() {
+			age := now.Sub(d.LastHeartbeat)
+			row.Online = age < displayOfflineAfter
+			row.HeartbeatAgo = age.Round(time.Second).String() + " ago"
+		}
+		rows[i] = row
+	}
+
+	data := struct {
+		Displays    []displayRow
+		Tournaments []model.TournamentSlug
+		Layouts     []*model.Layout
+		Flash       string
+		FlashType   string
+		Theme       string
+		Nick        string
+		IsAdmin     bool
+		IsOperator  bool
+	}{
+		Displays:    rows,
+		Tournaments: overview.Slugs,
+		Layouts:     layouts,
+		Flash:       flash,
+		FlashType:   flashType,
+		Theme:       sc.Theme,
+		Nick:        app.currentUserNick(ctx),
+		IsAdmin:     permission.IsAdmin(ctx),
+		IsOperator:  permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "manage-displays.html.tmpl", data); err != nil {
+		log.Printf("can't render manage-displays template: %v", err)
+	}
+}
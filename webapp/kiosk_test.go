@@ -0,0 +1,95 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ts4z/irata/dbcache"
+	"github.com/ts4z/irata/gossip"
+	"github.com/ts4z/irata/protocol"
+	"github.com/ts4z/irata/state"
+)
+
+func newKioskApp() (*App, *state.MemStorage, *time.Time) {
+	s := state.NewMemStorage()
+	cached := dbcache.NewDisplayStorage(64, s)
+	now := time.Date(2026, 10, 17, 19, 0, 0, 0, time.UTC)
+	return &App{
+		displayStorage:  cached,
+		displayGossiper: gossip.NewDisplayGossiper(cached),
+		newDisplays:     newNewDisplayLimiter(func() time.Time { return now }),
+		draining:        make(chan struct{}),
+	}, s, &now
+}
+
+// kioskListen polls as the device would, from addr.  Version -1 is never
+// current, so it doesn't wait.
+func kioskListen(app *App, addr, body string) *httptest.ResponseRecorder {
+	r := httptest.NewRequest(http.MethodPost, "/api/kiosk-listen", strings.NewReader(body))
+	r.RemoteAddr = addr + ":5000"
+	w := httptest.NewRecorder()
+	app.handleAPIKioskListen(r.Context(), w, r)
+	return w
+}
+
+func kioskBody(deviceID string) string {
+	body, _ := json.Marshal(map[string]any{"DeviceID": deviceID, "Version": -1, "ProtocolVersion": protocol.Version})
+	return string(body)
+}
+
+func TestKioskListenRejectsBadDevices(t *testing.T) {
+	app, s, _ := newKioskApp()
+	for name, body := range map[string]string{
+		"no device":     kioskBody(""),
+		"too long":      kioskBody(strings.Repeat("a", 65)),
+		"spaces":        kioskBody("bar tv"),
+		"path":          kioskBody("../../etc"),
+		"not ASCII":     kioskBody("télé"),
+		"huge body":     `{"DeviceID": "pi-1", "Padding": "` + strings.Repeat("x", 4096) + `"}`,
+		"not even JSON": "DeviceID=pi-1",
+	} {
+		if w := kioskListen(app, "10.0.0.5", body); w.Code != http.StatusBadRequest {
+			t.Errorf("%s: status %d: %s", name, w.Code, w.Body)
+		}
+	}
+	if displays, err := s.FetchDisplays(context.Background()); err != nil || len(displays) != 0 {
+		t.Errorf("registered %d displays, %v", len(displays), err)
+	}
+
+	for _, id := range []string{"0123456789abcdef", "Bar-TV-2", strings.Repeat("a", 64)} {
+		if w := kioskListen(app, "10.0.0.5", kioskBody(id)); w.Code != http.StatusOK {
+			t.Errorf("%q: status %d: %s", id, w.Code, w.Body)
+		}
+	}
+}
+
+func TestKioskListenLimitsNewDisplaysPerAddress(t *testing.T) {
+	app, _, now := newKioskApp()
+	for i := range newDisplaysPerAddress {
+		if w := kioskListen(app, "10.0.0.5", kioskBody(fmt.Sprintf("pi-%d", i))); w.Code != http.StatusOK {
+			t.Fatalf("display %d: status %d: %s", i, w.Code, w.Body)
+		}
+	}
+	if w := kioskListen(app, "10.0.0.5", kioskBody("one-too-many")); w.Code != http.StatusTooManyRequests {
+		t.Errorf("one too many: status %d: %s", w.Code, w.Body)
+	}
+	// Displays already registered keep polling, and other addresses
+	// have limits of their own.
+	if w := kioskListen(app, "10.0.0.5", kioskBody("pi-0")); w.Code != http.StatusOK {
+		t.Errorf("known display: status %d: %s", w.Code, w.Body)
+	}
+	if w := kioskListen(app, "10.0.0.6", kioskBody("other-pi")); w.Code != http.StatusOK {
+		t.Errorf("other address: status %d: %s", w.Code, w.Body)
+	}
+
+	*now = now.Add(newDisplayWindow)
+	if w := kioskListen(app, "10.0.0.5", kioskBody("one-too-many")); w.Code != http.StatusOK {
+		t.Errorf("next window: status %d: %s", w.Code, w.Body)
+	}
+}
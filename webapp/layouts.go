@@ -0,0 +1,247 @@
+package webapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"rsc.io/qr"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/layout"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/tournament"
+)
+
+// A QR code this long is too dense to read from across the room anyway.
+const maxQRCodeText = 1024
+
+// A starting point for new layouts: the classic clock, roughly.
+var newLayout = model.Layout{
+	Landscape: model.LayoutVariant{
+		Areas:   []string{"clock clock clock stats", "blinds blinds blinds stats", "footer footer footer stats"},
+		Columns: "1fr 1fr 1fr 1fr",
+		Rows:    "3fr 1fr 1fr",
+	},
+	Portrait: model.LayoutVariant{
+		Areas: []string{"clock", "blinds", "stats", "footer"},
+		Rows:  "3fr 1fr 2fr 1fr",
+	},
+}
+
+func (app *App) handleManageLayouts(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	layouts, err := app.layoutStorage.FetchLayouts(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch layouts", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+
+	data := struct {
+		Layouts    []*model.Layout
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Layouts:    layouts,
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "manage-layouts.html.tmpl", data); err != nil {
+		log.Printf("can't render manage-layouts template: %v", err)
+	}
+}
+
+// applyLayoutForm copies the editor form into l and validates the result.
+func applyLayoutForm(r *http.Request, l *model.Layout) error {
+	if err := r.ParseForm(); err != nil {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+	l.Name = strings.TrimSpace(r.FormValue("Name"))
+	l.Landscape = model.LayoutVariant{
+		Areas:   layout.ParseAreas(r.FormValue("LandscapeAreas")),
+		Columns: strings.TrimSpace(r.FormValue("LandscapeColumns")),
+		Rows:    strings.TrimSpace(r.FormValue("LandscapeRows")),
+	}
+	l.Portrait = model.LayoutVariant{
+		Areas:   layout.ParseAreas(r.FormValue("PortraitAreas")),
+		Columns: strings.TrimSpace(r.FormValue("PortraitColumns")),
+		Rows:    strings.TrimSpace(r.FormValue("PortraitRows")),
+	}
+	l.ImageURL = strings.TrimSpace(r.FormValue("ImageURL"))
+	l.QRCodeText = strings.TrimSpace(r.FormValue("QRCodeText"))
+	if len(l.QRCodeText) > maxQRCodeText {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "QR code text is too long")
+	}
+	if err := layout.Validate(l); err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func (app *App) renderLayoutEditor(ctx context.Context, w http.ResponseWriter, l *model.Layout, isNew bool, flash string) {
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	data := struct {
+		Layout     *model.Layout
+		Panels     []layout.Panel
+		IsNew      bool
+		Flash      string
+		FlashType  string
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Layout:     l,
+		Panels:     layout.Panels,
+		IsNew:      isNew,
+		Flash:      flash,
+		FlashType:  "boo",
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "edit-layout.html.tmpl", data); err != nil {
+		log.Printf("can't render edit-layout template: %v", err)
+	}
+}
+
+func (app *App) handleCreateLayout(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	l := newLayout.Clone()
+	if r.Method == http.MethodPost {
+		if err := applyLayoutForm(r, l); err != nil {
+			app.renderLayoutEditor(ctx, w, l, true, err.Error())
+			return
+		}
+		if _, err := app.layoutStorage.CreateLayout(ctx, l); err != nil {
+			log.Printf("can't create layout: %v", err)
+			app.renderLayoutEditor(ctx, w, l, true, "Error creating layout")
+			return
+		}
+		http.Redirect(w, r, "/manage/layouts", http.StatusSeeOther)
+		return
+	}
+	app.renderLayoutEditor(ctx, w, l, true, "")
+}
+
+func (app *App) handleEditLayout(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	l, err := app.layoutStorage.FetchLayout(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch layout", err)
+		return
+	}
+	if r.Method == http.MethodPost {
+		if err := applyLayoutForm(r, l); err != nil {
+			app.renderLayoutEditor(ctx, w, l, false, err.Error())
+			return
+		}
+		if err := app.layoutStorage.SaveLayout(ctx, l); err != nil {
+			log.Printf("can't save layout %d: %v", id, err)
+			app.renderLayoutEditor(ctx, w, l, false, "Error saving layout")
+			return
+		}
+		http.Redirect(w, r, "/manage/layouts", http.StatusSeeOther)
+		return
+	}
+	app.renderLayoutEditor(ctx, w, l, false, "")
+}
+
+// handleQRCode renders ?text= as a QR code, for the image panel.
+func (app *App) handleQRCode(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	text := r.URL.Query().Get("text")
+	if text == "" || len(text) > maxQRCodeText {
+		he.SendErrorToHTTPClient(w, "make qr code", he.HTTPCodedErrorf(http.StatusBadRequest, "want 1 to %d bytes of text", maxQRCodeText))
+		return
+	}
+	code, err := qr.Encode(text, qr.M)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "make qr code", he.New(http.StatusBadRequest, err))
+		return
+	}
+	code.Scale = 16
+	w.Header().Set("Content-Type", "image/png")
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(code.PNG())
+}
+
+func (app *App) handleSeating(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	var flash, flashType string
+	var text string
+	if r.Method == http.MethodPost {
+		text = r.FormValue("Seating")
+		if err := app.applySeatingForm(ctx, id, text); err != nil {
+			log.Printf("seating for tournament %d: %v", id, err)
+			flash, flashType = err.Error(), "boo"
+		} else {
+			http.Redirect(w, r, fmt.Sprintf("/t/%d/seating", id), http.StatusSeeOther)
+			return
+		}
+	}
+
+	t, err := app.fetchTournament(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch tournament", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	if flash == "" {
+		text = tournament.FormatSeating(t.State.Seating)
+	}
+
+	data := struct {
+		Tournament *model.Tournament
+		Seating    string
+		Flash      string
+		FlashType  string
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Tournament: t,
+		Seating:    text,
+		Flash:      flash,
+		FlashType:  flashType,
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "seating.html.tmpl", data); err != nil {
+		log.Printf("can't render seating template: %v", err)
+	}
+}
+
+func (app *App) applySeatingForm(ctx context.Context, id int64, text string) error {
+	seats, err := tournament.ParseSeating(text)
+	if err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	t, err := app.tournamentStorage.FetchTournament(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := app.tm.SetSeating(t, seats); err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	return app.tournamentStorage.SaveTournament(ctx, t)
+}
@@ -0,0 +1,335 @@
+package webapp
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/league"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/tournament"
+)
+
+// leagueBoard scores a league for a slide.  A league that can't be had
+// is logged and left off.
+func (app *App) leagueBoard(ctx context.Context, id int64) *league.Board {
+	board, err := app.fetchLeagueBoard(ctx, id)
+	if err != nil {
+		log.Printf("can't score league %d for slide: %v", id, err)
+		return nil
+	}
+	return board
+}
+
+func (app *App) fetchLeagueBoard(ctx context.Context, id int64) (*league.Board, error) {
+	l, err := app.leagueStorage.FetchLeague(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	tournaments, err := app.leagueStorage.FetchLeagueTournaments(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return league.Leaderboard(l, tournaments), nil
+}
+
+// leagueFloat and leagueInt read numbers from the league editor; blank is
+// zero.
+func leagueFloat(r *http.Request, name string) (float64, error) {
+	v := strings.TrimSpace(r.FormValue(name))
+	if v == "" {
+		return 0, nil
+	}
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return 0, he.HTTPCodedErrorf(http.StatusBadRequest, "bad %s %q", name, v)
+	}
+	return f, nil
+}
+
+func leagueInt(r *http.Request, name string) (int, error) {
+	v := strings.TrimSpace(r.FormValue(name))
+	if v == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return 0, he.HTTPCodedErrorf(http.StatusBadRequest, "bad %s %q", name, v)
+	}
+	return n, nil
+}
+
+// applyLeagueForm copies the editor form into l and validates the result.
+func applyLeagueForm(r *http.Request, l *model.League) error {
+	if err := r.ParseForm(); err != nil {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+	if v := r.FormValue("Version"); v != "" {
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return he.HTTPCodedErrorf(http.StatusBadRequest, "bad version")
+		}
+		l.Version = version
+	}
+	l.Name = strings.TrimSpace(r.FormValue("Name"))
+	l.Season = strings.TrimSpace(r.FormValue("Season"))
+	l.Description = strings.TrimSpace(r.FormValue("Description"))
+	l.Formula.Kind = model.PointsKind(r.FormValue("Kind"))
+
+	table, err := league.ParseTable(r.FormValue("Table"))
+	if err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	l.Formula.Table = table
+
+	if l.Formula.Scale, err = leagueFloat(r, "Scale"); err != nil {
+		return err
+	}
+	if l.Formula.Participation, err = leagueFloat(r, "Participation"); err != nil {
+		return err
+	}
+	if l.Formula.BaseBuyIn, err = leagueInt(r, "BaseBuyIn"); err != nil {
+		return err
+	}
+	if l.CountBest, err = leagueInt(r, "CountBest"); err != nil {
+		return err
+	}
+
+	if err := league.Validate(l); err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	return nil
+}
+
+func (app *App) renderLeagueEditor(ctx context.Context, w http.ResponseWriter, l *model.League, isNew bool, flash string) {
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	data := struct {
+		League     *model.League
+		Table      string
+		Kinds      []league.Kind
+		IsNew      bool
+		Flash      string
+		FlashType  string
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		League:     l,
+		Table:      league.FormatTable(l.Formula.Table),
+		Kinds:      league.Kinds,
+		IsNew:      isNew,
+		Flash:      flash,
+		FlashType:  "boo",
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "edit-league.html.tmpl", data); err != nil {
+		log.Printf("can't render edit-league template: %v", err)
+	}
+}
+
+func (app *App) handleCreateLeague(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	l := &model.League{
+		Formula: model.PointsFormula{
+			Kind:  model.PointsTable,
+			Table: []float64{10, 7, 5, 3, 2, 1},
+		},
+	}
+	if r.Method != http.MethodPost {
+		app.renderLeagueEditor(ctx, w, l, true, "")
+		return
+	}
+	if err := applyLeagueForm(r, l); err != nil {
+		app.renderLeagueEditor(ctx, w, l, true, err.Error())
+		return
+	}
+	if _, err := app.leagueStorage.CreateLeague(ctx, l); err != nil {
+		log.Printf("can't create league: %v", err)
+		app.renderLeagueEditor(ctx, w, l, true, "Error creating league")
+		return
+	}
+	http.Redirect(w, r, "/leagues", http.StatusSeeOther)
+}
+
+func (app *App) handleEditLeague(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	l, err := app.leagueStorage.FetchLeague(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch league", err)
+		return
+	}
+	if r.Method != http.MethodPost {
+		app.renderLeagueEditor(ctx, w, l, false, "")
+		return
+	}
+	if err := applyLeagueForm(r, l); err != nil {
+		app.renderLeagueEditor(ctx, w, l, false, err.Error())
+		return
+	}
+	if err := app.leagueStorage.SaveLeague(ctx, l); err != nil {
+		log.Printf("can't save league %d: %v", id, err)
+		app.renderLeagueEditor(ctx, w, l, false, "Error saving league; reload and try again")
+		return
+	}
+	http.Redirect(w, r, "/leagues", http.StatusSeeOther)
+}
+
+func (app *App) handleLeagues(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	leagues, err := app.leagueStorage.FetchLeagues(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch leagues", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	data := struct {
+		Leagues    []*model.League
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Leagues:    leagues,
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "leagues.html.tmpl", data); err != nil {
+		log.Printf("can't render leagues template: %v", err)
+	}
+}
+
+// handleLeague shows a league's leaderboard to anyone.
+func (app *App) handleLeague(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	board, err := app.fetchLeagueBoard(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch league", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	data := struct {
+		Board      *league.Board
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Board:      board,
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "league.html.tmpl", data); err != nil {
+		log.Printf("can't render league template: %v", err)
+	}
+}
+
+func (app *App) handleLeagueCSV(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	board, err := app.fetchLeagueBoard(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch league", err)
+		return
+	}
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="league-%d.csv"`, id))
+	if err := league.WriteCSV(w, board); err != nil {
+		log.Printf("can't write league %d as CSV: %v", id, err)
+	}
+}
+
+// handleStandings is where the floor enters final standings, for leagues.
+func (app *App) handleStandings(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	var flash, flashType string
+	var text string
+	if r.Method == http.MethodPost {
+		text = r.FormValue("Standings")
+		if err := app.applyStandingsForm(ctx, id, text); err != nil {
+			log.Printf("standings for tournament %d: %v", id, err)
+			flash, flashType = err.Error(), "boo"
+		} else {
+			http.Redirect(w, r, fmt.Sprintf("/t/%d/standings", id), http.StatusSeeOther)
+			return
+		}
+	}
+
+	t, err := app.fetchTournament(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch tournament", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	if flash == "" {
+		text = tournament.FormatStandings(t.State.Standings)
+	}
+	var l *model.League
+	if t.LeagueID != 0 {
+		if l, err = app.leagueStorage.FetchLeague(ctx, t.LeagueID); err != nil {
+			log.Printf("tournament %d: can't fetch league %d: %v", id, t.LeagueID, err)
+		}
+	}
+
+	data := struct {
+		Tournament *model.Tournament
+		League     *model.League
+		Standings  string
+		Flash      string
+		FlashType  string
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Tournament: t,
+		League:     l,
+		Standings:  text,
+		Flash:      flash,
+		FlashType:  flashType,
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "standings.html.tmpl", data); err != nil {
+		log.Printf("can't render standings template: %v", err)
+	}
+}
+
+func (app *App) applyStandingsForm(ctx context.Context, id int64, text string) error {
+	standings, err := tournament.ParseStandings(text)
+	if err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	t, err := app.tournamentStorage.FetchTournament(ctx, id)
+	if err != nil {
+		return err
+	}
+	t = t.Clone()
+	if err := app.tm.SetStandings(t, standings); err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	return app.tournamentStorage.SaveTournament(ctx, t)
+}
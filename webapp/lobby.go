@@ -0,0 +1,199 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/protocol"
+	"github.com/ts4z/irata/tournament"
+	"github.com/ts4z/irata/varz"
+)
+
+// The lobby board gets unreadable past this many tournaments on one screen.
+const lobbyMaxTournaments = 12
+
+var (
+	lobbyListens        = varz.NewInt("lobbyListens")
+	lobbyNotifiedClient = varz.NewInt("lobbyNotifiedClient")
+	lobbyListenTimeouts = varz.NewInt("lobbyListenTimeouts")
+	lobbyClientClosed   = varz.NewInt("lobbyClientClosed")
+)
+
+// lobbyLayout picks a grid for n tournaments, preferring wide grids because
+// displays are generally landscape.
+func lobbyLayout(n int) (cols, rows int) {
+	switch {
+	case n <= 1:
+		return 1, 1
+	case n <= 3:
+		return n, 1
+	case n == 4:
+		return 2, 2
+	case n <= 6:
+		return 3, 2
+	case n <= 8:
+		return 4, 2
+	case n == 9:
+		return 3, 3
+	default:
+		return 4, 3
+	}
+}
+
+// lobbyTournamentIDs returns the tournaments named with ?id= in the request,
+// or if there are none, the first tournaments in progress.
+func (app *App) lobbyTournamentIDs(ctx context.Context, r *http.Request) ([]int64, error) {
+	ids := []int64{}
+	for _, v := range r.URL.Query()["id"] {
+		id, err := strconv.ParseInt(v, 10, 64)
+		if err != nil || id <= 0 {
+			return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "bad tournament id %q", v)
+		}
+		ids = append(ids, id)
+	}
+	if len(ids) > 0 {
+		if len(ids) > lobbyMaxTournaments {
+			return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "at most %d tournaments fit on the lobby board", lobbyMaxTournaments)
+		}
+		return ids, nil
+	}
+
+	o, err := app.tournamentStorage.FetchOverview(ctx, tournament.LiveLifecycles, 0, lobbyMaxTournaments)
+	if err != nil {
+		return nil, err
+	}
+	for _, slug := range o.Slugs {
+		ids = append(ids, slug.TournamentID)
+	}
+	return ids, nil
+}
+
+func (app *App) handleLobby(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	ids, err := app.lobbyTournamentIDs(ctx, r)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "pick lobby tournaments", err)
+		return
+	}
+
+	cols, rows := lobbyLayout(len(ids))
+	data := struct {
+		Theme           string
+		SiteName        string
+		TournamentIDs   []int64
+		Columns         int
+		Rows            int
+		Explicit        bool
+		ProtocolVersion int64
+		IsOperator      bool
+	}{
+		Theme:           sc.Theme,
+		SiteName:        sc.Name,
+		TournamentIDs:   ids,
+		Columns:         cols,
+		Rows:            rows,
+		Explicit:        r.URL.Query().Has("id"),
+		ProtocolVersion: protocol.Version,
+		IsOperator:      permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "lobby.html.tmpl", data); err != nil {
+		log.Printf("can't render lobby template: %v", err)
+	}
+}
+
+// handleAPILobbyListen is /api/tournament-listen for several tournaments at
+// once.  It answers as soon as any of them differs from the version the
+// client has, with every tournament that has changed by then.
+func (app *App) handleAPILobbyListen(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	lobbyListens.Add(1)
+	defer countListen(r)()
+	type versionedID struct {
+		TournamentID int64
+		Version      int64
+	}
+	var req struct {
+		Tournaments     []versionedID
+		ProtocolVersion int64
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		he.SendErrorToHTTPClient(w, "/api/lobby-listen", he.HTTPCodedErrorf(http.StatusBadRequest, "decoding json: %w", err))
+		return
+	}
+	if len(req.Tournaments) == 0 || len(req.Tournaments) > lobbyMaxTournaments {
+		he.SendErrorToHTTPClient(w, "prep lobby listen", he.HTTPCodedErrorf(http.StatusBadRequest, "want 1 to %d tournaments, got %d", lobbyMaxTournaments, len(req.Tournaments)))
+		return
+	}
+
+	// Every listen writes exactly once, so buffering for all of them means
+	// the gossiper never blocks on us, even after we've stopped reading.
+	n := len(req.Tournaments)
+	errCh := make(chan error, n)
+	tournamentCh := make(chan *model.Tournament, n)
+	for _, vid := range req.Tournaments {
+		if vid.TournamentID <= 0 {
+			badTournamentIDForListen.Add(1)
+			he.SendErrorToHTTPClient(w, "prep lobby listen", he.HTTPCodedErrorf(http.StatusBadRequest, "invalid tournament ID %d", vid.TournamentID))
+			return
+		}
+		version := vid.Version
+		if req.ProtocolVersion != protocol.Version {
+			version = -1
+		}
+		go app.tournamentGossiper.ListenTournamentVersion(ctx, vid.TournamentID, version, errCh, tournamentCh)
+	}
+
+	changed := []*model.Tournament{}
+	select {
+	case err := <-errCh:
+		errorListening.Add(1)
+		he.SendErrorToHTTPClient(w, "listen for lobby change", err)
+		return
+	case t := <-tournamentCh:
+		changed = append(changed, t)
+	case <-time.After(time.Hour):
+		lobbyListenTimeouts.Add(1)
+		he.SendErrorToHTTPClient(w, "wait for lobby update", he.HTTPCodedErrorf(http.StatusGatewayTimeout, "timeout"))
+		return
+	case <-ctx.Done():
+		lobbyClientClosed.Add(1)
+		http.Error(w, "request cancelled", http.StatusRequestTimeout)
+		return
+	case <-app.draining:
+		sendReconnect(w)
+		return
+	}
+
+	// Several tournaments are usually stale at once (on first load, for
+	// instance), so give the rest a moment to turn up before answering.
+	grace := time.After(50 * time.Millisecond)
+collect:
+	for len(changed) < n {
+		select {
+		case t := <-tournamentCh:
+			changed = append(changed, t)
+		case <-grace:
+			break collect
+		}
+	}
+
+	bytes, err := json.Marshal(changed)
+	if err != nil {
+		errorWhileMarshalingForListen.Add(1)
+		he.SendErrorToHTTPClient(w, "marshal models", err)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.Write(bytes)
+	lobbyNotifiedClient.Add(1)
+}
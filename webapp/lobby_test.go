@@ -0,0 +1,205 @@
+package webapp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/ts4z/irata/gossip"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/protocol"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/tournament"
+)
+
+func TestLobbyLayout(t *testing.T) {
+	for _, tc := range []struct {
+		n, cols, rows int
+	}{
+		{0, 1, 1},
+		{1, 1, 1},
+		{2, 2, 1},
+		{3, 3, 1},
+		{4, 2, 2},
+		{5, 3, 2},
+		{6, 3, 2},
+		{7, 4, 2},
+		{8, 4, 2},
+		{9, 3, 3},
+		{10, 4, 3},
+		{12, 4, 3},
+		{13, 4, 3},
+	} {
+		cols, rows := lobbyLayout(tc.n)
+		if cols != tc.cols || rows != tc.rows {
+			t.Errorf("lobbyLayout(%d) = %dx%d, want %dx%d", tc.n, cols, rows, tc.cols, tc.rows)
+		}
+		if tc.n <= lobbyMaxTournaments && cols*rows < tc.n {
+			t.Errorf("lobbyLayout(%d) = %dx%d has no room", tc.n, cols, rows)
+		}
+	}
+}
+
+func TestLobbyShowsTournamentsInProgress(t *testing.T) {
+	ctx := context.Background()
+	s := state.NewMemStorage()
+	app := &App{tournamentStorage: s}
+	want := []int64{}
+	for i := range 2*lobbyMaxTournaments + 4 {
+		tm := newTestTournament(0, 0)
+		tm.State.Lifecycle = []model.Lifecycle{
+			model.LifecycleScheduled,
+			model.LifecycleRunning,
+			model.LifecycleFinished,
+			model.LifecycleOnBreak,
+		}[i%4]
+		id, err := s.CreateTournament(ctx, tm)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if slices.Contains(tournament.LiveLifecycles, tm.State.Lifecycle) && len(want) < lobbyMaxTournaments {
+			want = append(want, id)
+		}
+	}
+
+	ids, err := app.lobbyTournamentIDs(ctx, httptest.NewRequest(http.MethodGet, "/lobby", nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !slices.Equal(ids, want) {
+		t.Errorf("lobby shows %v, want %v", ids, want)
+	}
+}
+
+// fakeTournamentCache is the cache a TournamentGossiper reads through.
+type fakeTournamentCache struct {
+	mu          sync.Mutex
+	tournaments map[int64]*model.Tournament
+}
+
+var _ gossip.CacheStorage[model.Tournament] = &fakeTournamentCache{}
+
+func (f *fakeTournamentCache) Fetch(ctx context.Context, id int64) (*model.Tournament, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t, ok := f.tournaments[id]
+	if !ok {
+		return nil, fmt.Errorf("no tournament %d", id)
+	}
+	return t.Clone(), nil
+}
+
+func (f *fakeTournamentCache) CacheInvalidate(ctx context.Context, id, version int64) {}
+
+// bump saves a new version of tournament id.
+func (f *fakeTournamentCache) bump(id int64) *model.Tournament {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	t := f.tournaments[id].Clone()
+	t.Version++
+	f.tournaments[id] = t
+	return t.Clone()
+}
+
+func newTestTournament(id, version int64) *model.Tournament {
+	return &model.Tournament{
+		EventID:          id,
+		Version:          version,
+		EventName:        fmt.Sprintf("Tournament %d", id),
+		NextLevelSoundID: -1,
+		Structure:        model.StructureData{Levels: []*model.Level{{DurationMinutes: 20}}},
+		State:            &model.State{CurrentPlayers: 10},
+	}
+}
+
+func newListenApp() (*App, *fakeTournamentCache) {
+	cache := &fakeTournamentCache{tournaments: map[int64]*model.Tournament{
+		1: newTestTournament(1, 5),
+		2: newTestTournament(2, 7),
+	}}
+	tm := tournament.NewManager(clockwork.NewFakeClock(), nil, nil)
+	return &App{
+		tournamentGossiper: gossip.NewTournamentGossiper(cache, tm),
+		draining:           make(chan struct{}),
+	}, cache
+}
+
+func lobbyListen(t *testing.T, app *App, versions map[int64]int64) []*model.Tournament {
+	type versionedID struct{ TournamentID, Version int64 }
+	req := struct {
+		Tournaments     []versionedID
+		ProtocolVersion int64
+	}{ProtocolVersion: protocol.Version}
+	for _, id := range []int64{1, 2} {
+		req.Tournaments = append(req.Tournaments, versionedID{id, versions[id]})
+	}
+	body, err := json.Marshal(&req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := httptest.NewRequest(http.MethodPost, "/api/lobby-listen", strings.NewReader(string(body)))
+	w := httptest.NewRecorder()
+	app.handleAPILobbyListen(r.Context(), w, r)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var got []*model.Tournament
+	if err := json.Unmarshal(w.Body.Bytes(), &got); err != nil {
+		t.Fatalf("decoding %s: %v", w.Body, err)
+	}
+	return got
+}
+
+func versionsOf(ts []*model.Tournament) map[int64]int64 {
+	vs := map[int64]int64{}
+	for _, t := range ts {
+		vs[t.EventID] = t.Version
+	}
+	return vs
+}
+
+func TestLobbyListenAnswersWithEveryStaleTournament(t *testing.T) {
+	app, _ := newListenApp()
+	// A board just loaded has nothing.
+	got := versionsOf(lobbyListen(t, app, map[int64]int64{1: 0, 2: 0}))
+	if len(got) != 2 || got[1] != 5 || got[2] != 7 {
+		t.Errorf("got versions %v, want both tournaments", got)
+	}
+}
+
+func TestLobbyListenWaitsForChangesTogether(t *testing.T) {
+	app, cache := newListenApp()
+	done := make(chan []*model.Tournament)
+	go func() { done <- lobbyListen(t, app, map[int64]int64{1: 5, 2: 7}) }()
+
+	select {
+	case got := <-done:
+		t.Fatalf("answered with nothing changed: %v", versionsOf(got))
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Two changes close together, as when the floor moves players between
+	// tournaments, come back in one answer.
+	ctx := context.Background()
+	app.tournamentGossiper.NotifyUpdated(ctx, cache.bump(1))
+	time.Sleep(10 * time.Millisecond)
+	app.tournamentGossiper.NotifyUpdated(ctx, cache.bump(2))
+
+	select {
+	case got := <-done:
+		if vs := versionsOf(got); len(vs) != 2 || vs[1] != 6 || vs[2] != 8 {
+			t.Errorf("got versions %v, want both new ones", vs)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("listen didn't answer")
+	}
+}
@@ -0,0 +1,183 @@
+package webapp
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+
+	"github.com/rs/cors"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/varz"
+)
+
+var (
+	siteConfigReloads = varz.NewInt("siteConfigReloads")
+	bonusPortsOpen    = varz.NewGauge("bonusPortsOpen")
+)
+
+// siteConfigChanged is subscribed to the site config gossiper.  Most of the
+// site config is read on each request, so only the things made from it up
+// front need redoing here.
+func (app *App) siteConfigChanged(ctx context.Context, sc *model.SiteConfig) {
+	siteConfigReloads.Add(1)
+	log.Printf("site config changed, reloading")
+	app.setCORS(sc)
+	app.bakeryFactory.Forget()
+	app.bonusPorts.reconcile(sc)
+}
+
+// setCORS replaces the CORS middleware with one allowing the origins in sc.
+func (app *App) setCORS(sc *model.SiteConfig) {
+	h := cors.New(cors.Options{
+		AllowedOrigins:   allowedOrigins(sc),
+		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodDelete},
+		AllowCredentials: true,
+	}).Handler(app.inner)
+	app.corsHandler.Store(&h)
+}
+
+// bonusPort is a port the site config asks for, beyond the main ones.
+type bonusPort struct {
+	port  int
+	https bool
+}
+
+func (bp bonusPort) String() string {
+	if bp.https {
+		return fmt.Sprintf("HTTPS port %d", bp.port)
+	}
+	return fmt.Sprintf("HTTP port %d", bp.port)
+}
+
+// bonusPorts keeps a listener open on each of the site config's bonus ports,
+// serving the same handler as the main listeners.
+type bonusPorts struct {
+	handler   http.Handler
+	tlsConfig *tls.Config // nil if we have no certificates
+
+	mu        sync.Mutex
+	ctx       context.Context // set by start; nil before then and after shutdown
+	mainPorts []int
+	servers   map[bonusPort]*http.Server
+	pending   *model.SiteConfig // seen before start
+}
+
+func newBonusPorts(handler http.Handler, tlsConfig *tls.Config) *bonusPorts {
+	return &bonusPorts{
+		handler:   handler,
+		tlsConfig: tlsConfig,
+		servers:   make(map[bonusPort]*http.Server),
+	}
+}
+
+// start opens the ports in sc, and from then on reconcile opens and closes
+// them.  Ports already served by the main listeners, at mainAddresses, are
+// skipped.  Ports go on being served until shutdown.
+func (b *bonusPorts) start(ctx context.Context, sc *model.SiteConfig, mainAddresses ...string) {
+	b.mu.Lock()
+	b.ctx = ctx
+	for _, addr := range mainAddresses {
+		if _, port, err := net.SplitHostPort(addr); err == nil {
+			if n, err := strconv.Atoi(port); err == nil {
+				b.mainPorts = append(b.mainPorts, n)
+			}
+		}
+	}
+	if b.pending != nil {
+		sc = b.pending
+	}
+	b.mu.Unlock()
+
+	b.reconcile(sc)
+}
+
+// reconcile opens the bonus ports in sc that aren't open and closes the
+// ones that are no longer wanted.
+func (b *bonusPorts) reconcile(sc *model.SiteConfig) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.ctx == nil {
+		b.pending = sc
+		return
+	}
+
+	wanted := []bonusPort{}
+	for _, port := range sc.BonusHTTPPorts {
+		wanted = append(wanted, bonusPort{port, false})
+	}
+	if b.tlsConfig != nil {
+		for _, port := range sc.BonusHTTPSPorts {
+			wanted = append(wanted, bonusPort{port, true})
+		}
+	} else if len(sc.BonusHTTPSPorts) > 0 {
+		log.Printf("not listening on bonus HTTPS ports %v: TLS isn't configured", sc.BonusHTTPSPorts)
+	}
+
+	for bp, server := range b.servers {
+		if !slices.Contains(wanted, bp) {
+			log.Printf("closing bonus %s", bp)
+			server.Close()
+			delete(b.servers, bp)
+			bonusPortsOpen.Add(-1)
+		}
+	}
+	for _, bp := range wanted {
+		if _, open := b.servers[bp]; open || slices.Contains(b.mainPorts, bp.port) {
+			continue
+		}
+		if err := b.open(bp); err != nil {
+			log.Printf("can't listen on bonus %s: %v", bp, err)
+		}
+	}
+}
+
+// open starts serving bp.  Caller holds b.mu.
+func (b *bonusPorts) open(bp bonusPort) error {
+	l, err := net.Listen("tcp", fmt.Sprintf(":%d", bp.port))
+	if err != nil {
+		return err
+	}
+	if bp.https {
+		l = tls.NewListener(l, b.tlsConfig)
+	}
+	server := newServer(b.ctx, "", b.handler)
+	b.servers[bp] = server
+	bonusPortsOpen.Add(1)
+	log.Printf("listening on bonus %s", bp)
+	go func() {
+		if err := server.Serve(l); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Printf("bonus %s: %v", bp, err)
+		}
+	}()
+	return nil
+}
+
+// shutdown shuts the bonus ports down as http.Server.Shutdown does, and
+// keeps reconcile from opening more.
+func (b *bonusPorts) shutdown(ctx context.Context) {
+	b.mu.Lock()
+	servers := b.servers
+	b.servers = make(map[bonusPort]*http.Server)
+	b.ctx = nil
+	b.mu.Unlock()
+
+	wg := sync.WaitGroup{}
+	for bp, server := range servers {
+		wg.Go(func() {
+			if err := server.Shutdown(ctx); err != nil {
+				log.Printf("bonus %s didn't drain, closing: %v", bp, err)
+				server.Close()
+			}
+			bonusPortsOpen.Add(-1)
+		})
+	}
+	wg.Wait()
+}
@@ -0,0 +1,103 @@
+package webapp
+
+import (
+	"context"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/ts4z/irata/gossip"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+)
+
+// freePort finds a port nobody is listening on.
+func freePort(t *testing.T) int {
+	t.Helper()
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port
+}
+
+func dial(port int) error {
+	c, err := net.DialTimeout("tcp", net.JoinHostPort("127.0.0.1", strconv.Itoa(port)), time.Second)
+	if err == nil {
+		c.Close()
+	}
+	return err
+}
+
+func TestBonusPortsFollowSiteConfig(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	port := freePort(t)
+	b := newBonusPorts(http.NotFoundHandler(), nil)
+	// Seen before start, as when the config changes during startup.
+	b.reconcile(&model.SiteConfig{BonusHTTPPorts: []int{port}})
+	if err := dial(port); err == nil {
+		t.Fatalf("port %d open before start", port)
+	}
+	b.start(ctx, &model.SiteConfig{})
+	defer b.shutdown(ctx)
+	if err := dial(port); err != nil {
+		t.Fatalf("port %d not open after start: %v", port, err)
+	}
+
+	b.reconcile(&model.SiteConfig{})
+	if err := dial(port); err == nil {
+		t.Errorf("port %d still open after it was removed", port)
+	}
+}
+
+func TestBonusPortsSkipMainPorts(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	port := freePort(t)
+	b := newBonusPorts(http.NotFoundHandler(), nil)
+	b.start(ctx, &model.SiteConfig{BonusHTTPPorts: []int{port}}, ":"+strconv.Itoa(port))
+	defer b.shutdown(ctx)
+	if len(b.servers) != 0 {
+		t.Errorf("opened %d bonus ports, want none", len(b.servers))
+	}
+}
+
+func TestSiteConfigChangeAllowsNewOrigin(t *testing.T) {
+	app := &App{
+		inner:         http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}),
+		bakeryFactory: permission.NewBakeryFactory(clockwork.NewFakeClock(), nil),
+		bonusPorts:    newBonusPorts(http.NotFoundHandler(), nil),
+	}
+	app.setCORS(&model.SiteConfig{})
+	g := gossip.NewSiteConfigGossiper()
+	g.Subscribe(app.siteConfigChanged)
+
+	allowed := func(origin string) bool {
+		r := httptest.NewRequest(http.MethodGet, "/", nil)
+		r.Header.Set("Origin", origin)
+		w := httptest.NewRecorder()
+		(*app.corsHandler.Load()).ServeHTTP(w, r)
+		return w.Header().Get("Access-Control-Allow-Origin") == origin
+	}
+
+	const origin = "https://poker.example.com"
+	if allowed(origin) {
+		t.Fatalf("%s allowed before it was configured", origin)
+	}
+	g.NotifyUpdated(context.Background(), &model.SiteConfig{AllowedOriginDomains: []string{"poker.example.com"}})
+	if !allowed(origin) {
+		t.Errorf("%s not allowed after the site config changed", origin)
+	}
+	if allowed("https://elsewhere.example.com") {
+		t.Errorf("unconfigured origin allowed")
+	}
+}
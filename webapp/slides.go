@@ -0,0 +1,387 @@
+package webapp
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/slideshow"
+)
+
+const (
+	maxSlideImageBytes = 8 << 20
+
+	// Blank rows at the bottom of the slide set editor, for adding slides.
+	slideSetBlankRows = 5
+)
+
+// slidesFor works out what a clock should carry: the tournament's slide set,
+// else the site's, else the site's old list of image URLs.
+func (app *App) slidesFor(ctx context.Context, slideSetID int64, sc *model.SiteConfig) []slideshow.Show {
+	if slideSetID == 0 {
+		slideSetID = sc.DefaultSlideSetID
+	}
+	if slideSetID == 0 {
+		return slideshow.Legacy(sc.Slides)
+	}
+	set, err := app.slideStorage.FetchSlideSet(ctx, slideSetID)
+	if err != nil {
+		log.Printf("can't fetch slide set %d, using site slides: %v", slideSetID, err)
+		return slideshow.Legacy(sc.Slides)
+	}
+	library, err := app.slideStorage.FetchSlides(ctx)
+	if err != nil {
+		log.Printf("can't fetch slides, using site slides: %v", err)
+		return slideshow.Legacy(sc.Slides)
+	}
+	shows := slideshow.Resolve(set, library)
+	for i, show := range shows {
+		if show.Slide.Kind == model.SlideKindLeague {
+			shows[i].League = app.leagueBoard(ctx, show.Slide.LeagueID)
+		}
+	}
+	return shows
+}
+
+func (app *App) handleManageSlides(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	slides, err := app.slideStorage.FetchSlides(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slides", err)
+		return
+	}
+	sets, err := app.slideStorage.FetchSlideSets(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slide sets", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+
+	data := struct {
+		Slides     []*model.Slide
+		SlideSets  []*model.SlideSet
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Slides:     slides,
+		SlideSets:  sets,
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "manage-slides.html.tmpl", data); err != nil {
+		log.Printf("can't render manage-slides template: %v", err)
+	}
+}
+
+// slideUpload is an image that came with the slide editor form.
+type slideUpload struct {
+	contentType string
+	data        []byte
+}
+
+func readSlideUpload(r *http.Request) (*slideUpload, error) {
+	f, _, err := r.FormFile("Image")
+	if errors.Is(err, http.ErrMissingFile) {
+		return nil, nil
+	} else if err != nil {
+		return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "can't read upload: %w", err)
+	}
+	defer f.Close()
+
+	data, err := io.ReadAll(io.LimitReader(f, maxSlideImageBytes+1))
+	if err != nil {
+		return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "can't read upload: %w", err)
+	}
+	if len(data) > maxSlideImageBytes {
+		return nil, he.HTTPCodedErrorf(http.StatusRequestEntityTooLarge, "image is over %d MB", maxSlideImageBytes>>20)
+	}
+	// Sniff rather than trust the browser.  This also turns away SVG, which
+	// can carry script.
+	contentType := http.DetectContentType(data)
+	if !strings.HasPrefix(contentType, "image/") {
+		return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "upload is %s, not an image", contentType)
+	}
+	return &slideUpload{contentType, data}, nil
+}
+
+// applySlideForm copies the editor form into sl and validates the result.
+// It returns the uploaded image, if there was one; the caller stores it.
+func applySlideForm(r *http.Request, sl *model.Slide) (*slideUpload, error) {
+	if err := r.ParseMultipartForm(maxSlideImageBytes); err != nil {
+		return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+	sl.Name = strings.TrimSpace(r.FormValue("Name"))
+	sl.Kind = model.SlideKind(r.FormValue("Kind"))
+	sl.Markdown = r.FormValue("Markdown")
+	sl.ImageURL = strings.TrimSpace(r.FormValue("ImageURL"))
+	sl.LeagueID = 0
+	if sl.Kind == model.SlideKindLeague {
+		id, err := strconv.ParseInt(r.FormValue("LeagueID"), 10, 64)
+		if err != nil {
+			return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "bad league")
+		}
+		sl.LeagueID = id
+	}
+	d, err := strconv.Atoi(r.FormValue("DurationSeconds"))
+	if err != nil {
+		return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "bad duration")
+	}
+	sl.DurationSeconds = d
+	if r.FormValue("RemoveImage") != "" {
+		sl.UploadedImage = false
+	}
+
+	upload, err := readSlideUpload(r)
+	if err != nil {
+		return nil, err
+	}
+	if upload != nil {
+		sl.UploadedImage = true
+	}
+
+	if sl.Kind == model.SlideKindMarkdown {
+		if err := validateMarkdown(sl.Markdown); err != nil {
+			return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "bad markdown: %w", err)
+		}
+	}
+	if err := slideshow.Validate(sl); err != nil {
+		return nil, he.New(http.StatusBadRequest, err)
+	}
+	return upload, nil
+}
+
+func (app *App) renderSlideEditor(ctx context.Context, w http.ResponseWriter, sl *model.Slide, isNew bool, flash string) {
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	leagues, err := app.leagueStorage.FetchLeagues(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch leagues", err)
+		return
+	}
+	data := struct {
+		Slide      *model.Slide
+		ImageURL   string
+		Kinds      []slideshow.Kind
+		Leagues    []*model.League
+		IsNew      bool
+		Flash      string
+		FlashType  string
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Slide:      sl,
+		ImageURL:   slideshow.ImageURL(sl),
+		Kinds:      slideshow.Kinds,
+		Leagues:    leagues,
+		IsNew:      isNew,
+		Flash:      flash,
+		FlashType:  "boo",
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "edit-slide.html.tmpl", data); err != nil {
+		log.Printf("can't render edit-slide template: %v", err)
+	}
+}
+
+// saveSlide stores the slide and any new image, clearing the stored image if
+// it's no longer used.
+func (app *App) saveSlide(ctx context.Context, sl *model.Slide, upload *slideUpload) error {
+	if upload != nil {
+		if err := app.slideStorage.SaveSlideImage(ctx, sl.SlideID, upload.contentType, upload.data); err != nil {
+			return err
+		}
+	} else if !sl.UploadedImage {
+		if err := app.slideStorage.SaveSlideImage(ctx, sl.SlideID, "", nil); err != nil {
+			return err
+		}
+	}
+	return app.slideStorage.SaveSlide(ctx, sl)
+}
+
+func (app *App) handleCreateSlide(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	sl := &model.Slide{Kind: model.SlideKindMarkdown, DurationSeconds: 15}
+	if r.Method != http.MethodPost {
+		app.renderSlideEditor(ctx, w, sl, true, "")
+		return
+	}
+
+	upload, err := applySlideForm(r, sl)
+	if err != nil {
+		app.renderSlideEditor(ctx, w, sl, true, err.Error())
+		return
+	}
+	// The image is stored against the slide, so the slide has to exist first.
+	sl.UploadedImage = false
+	if sl.SlideID, err = app.slideStorage.CreateSlide(ctx, sl); err != nil {
+		log.Printf("can't create slide: %v", err)
+		app.renderSlideEditor(ctx, w, sl, true, "Error creating slide")
+		return
+	}
+	if upload != nil {
+		sl.UploadedImage = true
+		if err := app.saveSlide(ctx, sl, upload); err != nil {
+			log.Printf("can't save image for slide %d: %v", sl.SlideID, err)
+			app.renderSlideEditor(ctx, w, sl, false, "Created the slide, but couldn't save the image")
+			return
+		}
+	}
+	http.Redirect(w, r, "/manage/slides", http.StatusSeeOther)
+}
+
+func (app *App) handleEditSlide(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	sl, err := app.slideStorage.FetchSlide(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slide", err)
+		return
+	}
+	if r.Method != http.MethodPost {
+		app.renderSlideEditor(ctx, w, sl, false, "")
+		return
+	}
+
+	upload, err := applySlideForm(r, sl)
+	if err != nil {
+		app.renderSlideEditor(ctx, w, sl, false, err.Error())
+		return
+	}
+	if err := app.saveSlide(ctx, sl, upload); err != nil {
+		log.Printf("can't save slide %d: %v", id, err)
+		app.renderSlideEditor(ctx, w, sl, false, "Error saving slide")
+		return
+	}
+	http.Redirect(w, r, "/manage/slides", http.StatusSeeOther)
+}
+
+func (app *App) handleSlideImage(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	contentType, data, err := app.slideStorage.FetchSlideImage(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slide image", err)
+		return
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+	// Clocks ask for ?v=version, so a new upload gets a new URL.
+	w.Header().Set("Cache-Control", "public, max-age=86400")
+	w.Write(data)
+}
+
+func (app *App) renderSlideSetEditor(ctx context.Context, w http.ResponseWriter, ss *model.SlideSet, isNew bool, flash string) {
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	slides, err := app.slideStorage.FetchSlides(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slides", err)
+		return
+	}
+	rows := append(append([]model.SlideSetEntry{}, ss.Entries...), make([]model.SlideSetEntry, slideSetBlankRows)...)
+
+	data := struct {
+		SlideSet   *model.SlideSet
+		Rows       []model.SlideSetEntry
+		Slides     []*model.Slide
+		Triggers   []slideshow.Trigger
+		IsNew      bool
+		Flash      string
+		FlashType  string
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		SlideSet:   ss,
+		Rows:       rows,
+		Slides:     slides,
+		Triggers:   slideshow.Triggers,
+		IsNew:      isNew,
+		Flash:      flash,
+		FlashType:  "boo",
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "edit-slide-set.html.tmpl", data); err != nil {
+		log.Printf("can't render edit-slide-set template: %v", err)
+	}
+}
+
+func applySlideSetForm(r *http.Request, ss *model.SlideSet) error {
+	if err := r.ParseForm(); err != nil {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+	ss.Name = strings.TrimSpace(r.FormValue("Name"))
+	if ss.Name == "" {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "slide set needs a name")
+	}
+	entries, err := slideshow.ParseEntries(r.Form["SlideID"], r.Form["Trigger"])
+	if err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	ss.Entries = entries
+	return nil
+}
+
+func (app *App) handleCreateSlideSet(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	ss := &model.SlideSet{}
+	if r.Method != http.MethodPost {
+		app.renderSlideSetEditor(ctx, w, ss, true, "")
+		return
+	}
+	if err := applySlideSetForm(r, ss); err != nil {
+		app.renderSlideSetEditor(ctx, w, ss, true, err.Error())
+		return
+	}
+	if _, err := app.slideStorage.CreateSlideSet(ctx, ss); err != nil {
+		log.Printf("can't create slide set: %v", err)
+		app.renderSlideSetEditor(ctx, w, ss, true, "Error creating slide set")
+		return
+	}
+	http.Redirect(w, r, "/manage/slides", http.StatusSeeOther)
+}
+
+func (app *App) handleEditSlideSet(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	ss, err := app.slideStorage.FetchSlideSet(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slide set", err)
+		return
+	}
+	if r.Method != http.MethodPost {
+		app.renderSlideSetEditor(ctx, w, ss, false, "")
+		return
+	}
+	if err := applySlideSetForm(r, ss); err != nil {
+		app.renderSlideSetEditor(ctx, w, ss, false, err.Error())
+		return
+	}
+	if err := app.slideStorage.SaveSlideSet(ctx, ss); err != nil {
+		log.Printf("can't save slide set %d: %v", id, err)
+		app.renderSlideSetEditor(ctx, w, ss, false, "Error saving slide set")
+		return
+	}
+	http.Redirect(w, r, "/manage/slides", http.StatusSeeOther)
+}
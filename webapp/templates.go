@@ -0,0 +1,480 @@
+package webapp
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/form"
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/middleware/clientip"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/schedule"
+)
+
+const (
+	// The calendar looks this far ahead unless asked with ?weeks=.
+	calendarWeeks    = 8
+	calendarMaxWeeks = 52
+
+	// Tournaments stay on the calendar for a while after they start, so
+	// tonight's game doesn't vanish at 7:01.
+	calendarGrace = 12 * time.Hour
+)
+
+type weekdayChoice struct {
+	Weekday int
+	Name    string
+	Checked bool
+}
+
+// templateForm is what edit-tournament.html.tmpl needs to edit a template.
+type templateForm struct {
+	TournamentTemplateID int64
+	Version              int64
+	Name                 string
+	Recurrence           model.Recurrence
+	Starting             string
+	Until                string
+	Weekdays             []weekdayChoice
+	MonthlyWeekday       int
+	Frequencies          []schedule.Frequency
+	WeeksOfMonth         []schedule.WeekOfMonth
+}
+
+func newTemplateForm(tt *model.TournamentTemplate) *templateForm {
+	tf := &templateForm{
+		TournamentTemplateID: tt.TournamentTemplateID,
+		Version:              tt.Version,
+		Name:                 tt.Name,
+		Recurrence:           tt.Recurrence,
+		Starting:             schedule.FormatDate(&tt.Recurrence, tt.Recurrence.Starting),
+		Until:                schedule.FormatDate(&tt.Recurrence, tt.Recurrence.Until),
+		MonthlyWeekday:       int(tt.Recurrence.Weekday),
+		Frequencies:          schedule.Frequencies,
+		WeeksOfMonth:         schedule.WeeksOfMonth,
+	}
+	for d := time.Sunday; d <= time.Saturday; d++ {
+		tf.Weekdays = append(tf.Weekdays, weekdayChoice{
+			Weekday: int(d),
+			Name:    d.String(),
+			Checked: slices.Contains(tt.Recurrence.Weekdays, d),
+		})
+	}
+	return tf
+}
+
+// fillEditTournamentChoices fetches everything the tournament editor offers
+// to choose from.
+func (app *App) fillEditTournamentChoices(ctx context.Context, args *editTournamentArgs) error {
+	var err error
+	if args.Structures, err = app.appStorage.FetchStructureSlugs(ctx, 0, 100); err != nil {
+		return fmt.Errorf("fetch structure slugs: %w", err)
+	}
+	if args.FooterSets, err = app.appStorage.ListFooterPlugSets(ctx); err != nil {
+		return fmt.Errorf("fetch footer plug sets: %w", err)
+	}
+	if args.Paytables, err = app.paytableStorage.FetchPaytableSlugs(ctx); err != nil {
+		return fmt.Errorf("fetch paytable slugs: %w", err)
+	}
+	if args.Sounds, err = app.soundStorage.FetchSoundEffectSlugs(ctx); err != nil {
+		return fmt.Errorf("fetch sound slugs: %w", err)
+	}
+	if args.Layouts, err = app.layoutStorage.FetchLayouts(ctx); err != nil {
+		return fmt.Errorf("fetch layouts: %w", err)
+	}
+	if args.SlideSets, err = app.slideStorage.FetchSlideSets(ctx); err != nil {
+		return fmt.Errorf("fetch slide sets: %w", err)
+	}
+	if args.Leagues, err = app.leagueStorage.FetchLeagues(ctx); err != nil {
+		return fmt.Errorf("fetch leagues: %w", err)
+	}
+	if args.SiteConfig, err = app.siteStorageReader.FetchSiteConfig(ctx); err != nil {
+		return fmt.Errorf("fetch site config: %w", err)
+	}
+	args.ThemeSlugs = app.themeStorage.FetchThemeSlugs()
+	args.IsAdmin = permission.IsAdmin(ctx)
+	args.IsOperator = permission.IsOperator(ctx)
+	args.Nick = app.currentUserNick(ctx)
+	return nil
+}
+
+func (app *App) renderTemplateEditor(ctx context.Context, w http.ResponseWriter, tt *model.TournamentTemplate, flash string) {
+	args := &editTournamentArgs{
+		Flash:      flash,
+		FlashType:  "boo",
+		Tournament: tt.Tournament,
+		// The template's tournament has never started, so the editor
+		// treats it as new whether or not the template is.
+		IsNew:    true,
+		Template: newTemplateForm(tt),
+	}
+	if err := app.fillEditTournamentChoices(ctx, args); err != nil {
+		he.SendErrorToHTTPClient(w, "fetch editor choices", err)
+		return
+	}
+	if err := app.templates.ExecuteTemplate(w, "edit-tournament.html.tmpl", args); err != nil {
+		log.Printf("can't render edit-tournament template: %v", err)
+	}
+}
+
+// applyTemplateForm copies the editor form into tt and validates the result.
+func (app *App) applyTemplateForm(ctx context.Context, r *http.Request, tt *model.TournamentTemplate) error {
+	if err := r.ParseForm(); err != nil {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+	tt.Name = strings.TrimSpace(r.FormValue("TemplateName"))
+	if tt.Name == "" {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "template needs a name")
+	}
+	if v := r.FormValue("TemplateVersion"); v != "" {
+		version, err := strconv.ParseInt(v, 10, 64)
+		if err != nil {
+			return he.HTTPCodedErrorf(http.StatusBadRequest, "bad version %q", v)
+		}
+		tt.Version = version
+	}
+	recurrence, err := schedule.ParseRecurrence(r.Form)
+	if err != nil {
+		return he.New(http.StatusBadRequest, err)
+	}
+	tt.Recurrence = recurrence
+	if err := app.formProcessor.ApplyFormToTournament(ctx, r.Form, tt.Tournament); err != nil {
+		return err
+	}
+	tt.Tournament.EventID = 0
+	tt.Tournament.Version = 0
+	tt.Tournament.ScheduledStart = time.Time{}
+	return nil
+}
+
+func (app *App) handleCreateTemplate(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	tt := &model.TournamentTemplate{
+		Tournament: &model.Tournament{
+			NextLevelSoundID: sc.DefaultNextLevelSoundID,
+			State:            &model.State{AutoComputePrizePool: true},
+		},
+		Recurrence: model.Recurrence{StartTime: "19:00"},
+	}
+	// ?from= starts the template from an existing tournament.
+	if v := r.URL.Query().Get("from"); v != "" {
+		if id, err := strconv.ParseInt(v, 10, 64); err != nil {
+			log.Printf("invalid tournament ID %q: %v", v, err)
+		} else if t, err := app.fetchTournament(ctx, id); err != nil {
+			log.Printf("error fetching tournament %d for template: %v", id, err)
+		} else {
+			tt.Tournament = copyTournament(t)
+			tt.Name = t.EventName
+			if !t.ScheduledStart.IsZero() {
+				tt.Recurrence.StartTime = t.ScheduledStart.Format(schedule.TimeLayout)
+			}
+		}
+	}
+
+	if r.Method == http.MethodPost {
+		if err := app.applyTemplateForm(ctx, r, tt); err != nil {
+			app.renderTemplateEditor(ctx, w, tt, err.Error())
+			return
+		}
+		if _, err := app.templateStorage.CreateTournamentTemplate(ctx, tt); err != nil {
+			log.Printf("can't create template: %v", err)
+			app.renderTemplateEditor(ctx, w, tt, "Error creating template")
+			return
+		}
+		http.Redirect(w, r, "/manage/templates", http.StatusSeeOther)
+		return
+	}
+	app.renderTemplateEditor(ctx, w, tt, "")
+}
+
+func (app *App) handleEditTemplate(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	tt, err := app.templateStorage.FetchTournamentTemplate(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch template", err)
+		return
+	}
+	if r.Method == http.MethodPost {
+		if err := app.applyTemplateForm(ctx, r, tt); err != nil {
+			app.renderTemplateEditor(ctx, w, tt, err.Error())
+			return
+		}
+		if err := app.templateStorage.SaveTournamentTemplate(ctx, tt); err != nil {
+			log.Printf("can't save template %d: %v", id, err)
+			app.renderTemplateEditor(ctx, w, tt, "Error saving template; reload and try again")
+			return
+		}
+		http.Redirect(w, r, "/manage/templates", http.StatusSeeOther)
+		return
+	}
+	app.renderTemplateEditor(ctx, w, tt, "")
+}
+
+// handleInstantiateTemplate makes a tournament from a template right away,
+// for a game that isn't on the schedule.
+func (app *App) handleInstantiateTemplate(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		he.SendErrorToHTTPClient(w, "instantiate template", he.HTTPCodedErrorf(http.StatusMethodNotAllowed, "use POST"))
+		return
+	}
+	tt, err := app.templateStorage.FetchTournamentTemplate(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch template", err)
+		return
+	}
+	start := app.clock.Now()
+	if v := r.FormValue("ScheduledStart"); v != "" {
+		if start, err = time.ParseInLocation(form.ScheduledStartLayout, v, time.Local); err != nil {
+			he.SendErrorToHTTPClient(w, "parse start", he.HTTPCodedErrorf(http.StatusBadRequest, "bad start %q", v))
+			return
+		}
+	}
+	tid, err := app.tournamentStorage.CreateTournament(ctx, schedule.Instantiate(tt, start))
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "create tournament", err)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/t/%d/edit", tid), http.StatusSeeOther)
+}
+
+func (app *App) handleManageTemplates(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	templates, err := app.templateStorage.FetchTournamentTemplates(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch templates", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+
+	type templateRow struct {
+		TournamentTemplateID int64
+		Name                 string
+		When                 string
+		Next                 string
+		ScheduledThrough     string
+	}
+	now := app.clock.Now()
+	rows := []templateRow{}
+	for _, tt := range templates {
+		row := templateRow{
+			TournamentTemplateID: tt.TournamentTemplateID,
+			Name:                 tt.Name,
+			When:                 schedule.Describe(&tt.Recurrence),
+		}
+		if starts, err := schedule.Occurrences(&tt.Recurrence, now, now.AddDate(1, 0, 0)); err != nil {
+			row.Next = err.Error()
+		} else if len(starts) > 0 {
+			row.Next = starts[0].Format("Mon Jan 2 15:04 MST")
+		}
+		if !tt.ScheduledThrough.IsZero() {
+			row.ScheduledThrough = tt.ScheduledThrough.Format("Mon Jan 2 15:04 MST")
+		}
+		rows = append(rows, row)
+	}
+
+	data := struct {
+		Templates  []templateRow
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Templates:  rows,
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "manage-templates.html.tmpl", data); err != nil {
+		log.Printf("can't render manage-templates template: %v", err)
+	}
+}
+
+// calendarEntry is a tournament on the calendar.  Scheduled tournaments that
+// haven't been created yet come from their templates and have no
+// TournamentID.
+type calendarEntry struct {
+	Start        time.Time
+	End          time.Time
+	Name         string
+	Description  string
+	TournamentID int64
+	TemplateID   int64
+}
+
+// calendarEntries lists what's happening from a little before now through
+// the given number of weeks.
+func (app *App) calendarEntries(ctx context.Context, r *http.Request) ([]calendarEntry, error) {
+	weeks := calendarWeeks
+	if v := r.URL.Query().Get("weeks"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 1 || n > calendarMaxWeeks {
+			return nil, he.HTTPCodedErrorf(http.StatusBadRequest, "weeks must be between 1 and %d", calendarMaxWeeks)
+		}
+		weeks = n
+	}
+	now := app.clock.Now()
+	from, until := now.Add(-calendarGrace), now.AddDate(0, 0, 7*weeks)
+
+	entries := []calendarEntry{}
+	// TODO: pagination
+	o, err := app.tournamentStorage.FetchOverview(ctx, nil, 0, 1000)
+	if err != nil {
+		return nil, err
+	}
+	for _, slug := range o.Slugs {
+		if slug.ScheduledStart.Before(from) || slug.ScheduledStart.After(until) {
+			continue
+		}
+		t, err := app.tournamentStorage.FetchTournament(ctx, slug.TournamentID)
+		if err != nil {
+			log.Printf("calendar: skipping tournament %d: %v", slug.TournamentID, err)
+			continue
+		}
+		entries = append(entries, calendarEntry{
+			Start:        t.ScheduledStart,
+			End:          t.ScheduledStart.Add(schedule.EstimatedDuration(t)),
+			Name:         t.EventName,
+			Description:  t.Description,
+			TournamentID: t.EventID,
+			TemplateID:   t.FromTemplateID,
+		})
+	}
+
+	templates, err := app.templateStorage.FetchTournamentTemplates(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, tt := range templates {
+		if tt.Tournament == nil {
+			continue
+		}
+		// Whatever is scheduled through ScheduledThrough has been created
+		// already, and is listed above.
+		after := from
+		if tt.ScheduledThrough.After(after) {
+			after = tt.ScheduledThrough
+		}
+		starts, err := schedule.Occurrences(&tt.Recurrence, after, until)
+		if err != nil {
+			log.Printf("calendar: skipping template %d: %v", tt.TournamentTemplateID, err)
+			continue
+		}
+		for _, start := range starts {
+			t := schedule.Instantiate(tt, start)
+			entries = append(entries, calendarEntry{
+				Start:       start,
+				End:         start.Add(schedule.EstimatedDuration(t)),
+				Name:        t.EventName,
+				Description: t.Description,
+				TemplateID:  tt.TournamentTemplateID,
+			})
+		}
+	}
+
+	slices.SortStableFunc(entries, func(a, b calendarEntry) int {
+		return cmp.Or(a.Start.Compare(b.Start), cmp.Compare(a.Name, b.Name))
+	})
+	return entries, nil
+}
+
+func (app *App) handleCalendar(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	entries, err := app.calendarEntries(ctx, r)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "list calendar", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+
+	type calendarRow struct {
+		calendarEntry
+		Day    string
+		NewDay bool
+		Time   string
+	}
+	rows := []calendarRow{}
+	for i, e := range entries {
+		start := e.Start.In(time.Local)
+		row := calendarRow{
+			calendarEntry: e,
+			Day:           start.Format("Monday, January 2"),
+			Time:          start.Format("15:04"),
+		}
+		row.NewDay = i == 0 || rows[i-1].Day != row.Day
+		rows = append(rows, row)
+	}
+
+	data := struct {
+		Entries    []calendarRow
+		Theme      string
+		Nick       string
+		IsAdmin    bool
+		IsOperator bool
+	}{
+		Entries:    rows,
+		Theme:      sc.Theme,
+		Nick:       app.currentUserNick(ctx),
+		IsAdmin:    permission.IsAdmin(ctx),
+		IsOperator: permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "calendar.html.tmpl", data); err != nil {
+		log.Printf("can't render calendar template: %v", err)
+	}
+}
+
+func (app *App) handleCalendarICS(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	entries, err := app.calendarEntries(ctx, r)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "list calendar", err)
+		return
+	}
+	client := clientip.Of(r)
+	base := client.BaseURL()
+
+	events := []schedule.Event{}
+	for _, e := range entries {
+		ev := schedule.Event{
+			Start:       e.Start,
+			End:         e.End,
+			Summary:     e.Name,
+			Description: e.Description,
+		}
+		// A tournament keeps the UID its template gave it before it was
+		// created, so calendar apps see one event rather than two.
+		if e.TemplateID != 0 {
+			ev.UID = fmt.Sprintf("template-%d-%d@%s", e.TemplateID, e.Start.Unix(), client.Host)
+		} else {
+			ev.UID = fmt.Sprintf("tournament-%d@%s", e.TournamentID, client.Host)
+		}
+		if e.TournamentID != 0 {
+			ev.URL = fmt.Sprintf("%s/t/%d", base, e.TournamentID)
+		} else {
+			ev.URL = base + "/calendar"
+		}
+		events = append(events, ev)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="irata.ics"`)
+	if err := schedule.WriteICS(w, "Tournaments at "+client.Host, events, app.clock.Now()); err != nil {
+		log.Printf("can't write calendar: %v", err)
+	}
+}
@@ -3,53 +3,70 @@ package webapp
 import (
 	"bytes"
 	"context"
+	"crypto/tls"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"html"
 	"html/template"
 	"io/fs"
 	"log"
 	"math/rand/v2"
 	"net"
 	"net/http"
+	"net/url"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/jonboulle/clockwork"
-	"github.com/rs/cors"
 	"github.com/yuin/goldmark"
 
+	"github.com/ts4z/irata/apitoken"
 	"github.com/ts4z/irata/app/handlers"
 	"github.com/ts4z/irata/assets"
 	"github.com/ts4z/irata/builtins"
+	"github.com/ts4z/irata/certs"
 	"github.com/ts4z/irata/chop"
+	"github.com/ts4z/irata/chop/floor"
 	"github.com/ts4z/irata/chop/icm"
+	"github.com/ts4z/irata/chop/mw"
 	"github.com/ts4z/irata/chop/proportional"
+	"github.com/ts4z/irata/chop/save"
 	"github.com/ts4z/irata/dbnotify"
 	"github.com/ts4z/irata/dep"
+	"github.com/ts4z/irata/footerplug"
 	"github.com/ts4z/irata/form"
 	"github.com/ts4z/irata/gossip"
 	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/layout"
+	"github.com/ts4z/irata/league"
+	"github.com/ts4z/irata/logz"
 	"github.com/ts4z/irata/middleware"
 	"github.com/ts4z/irata/middleware/c2ctx"
+	"github.com/ts4z/irata/middleware/clientip"
 	"github.com/ts4z/irata/middleware/labrea"
 	"github.com/ts4z/irata/model"
 	"github.com/ts4z/irata/password"
 	"github.com/ts4z/irata/paytable"
 	"github.com/ts4z/irata/permission"
 	"github.com/ts4z/irata/protocol"
+	"github.com/ts4z/irata/slideshow"
 	"github.com/ts4z/irata/soundmodel"
 	"github.com/ts4z/irata/state"
 	"github.com/ts4z/irata/textutil"
 	"github.com/ts4z/irata/tournament"
 	"github.com/ts4z/irata/urlpath"
 	"github.com/ts4z/irata/varz"
+	"github.com/ts4z/irata/webapp/api"
 	"github.com/ts4z/irata/webapp/kbd"
 )
 
+var logger = logz.New()
+
 var (
 	clientClosedWhileListening    = varz.NewInt("clientClosedWhileListening")
 	timedOutWhileListening        = varz.NewInt("timedOutWhileListening")
@@ -67,7 +84,11 @@ var (
 var templateFuncs template.FuncMap = template.FuncMap{
 	"join":           textutil.Join,
 	"joinInts":       textutil.JoinInts,
+	"commas":         textutil.FormatCommas,
 	"markdownToHTML": markdownToHTML,
+	"lifecycle":      tournament.DescribeLifecycle,
+	"lifecycleOf":    tournament.LifecycleOf,
+	"points":         league.FormatPoints,
 }
 
 func markdownToHTML(markdown string) template.HTML {
@@ -100,25 +121,70 @@ type editTournamentArgs struct {
 	IsNew      bool
 	SiteConfig *model.SiteConfig
 	Sounds     []*soundmodel.SoundEffectSlug
+	Layouts    []*model.Layout
+	SlideSets  []*model.SlideSet
+	Leagues    []*model.League
 	Nick       string
+
+	// Lifecycles are the states offered in the lifecycle select: the ones
+	// an operator may choose, and wherever the tournament is now.
+	Lifecycles []tournament.LifecycleChoice
+
+	// Template is set when the form edits a tournament template rather
+	// than a tournament.
+	Template *templateForm
 }
 
 // Config holds the configuration for creating a new IrataApp.
 type Config struct {
-	DBListener         *dbnotify.DBNotifyListener
-	TournamentGossiper *gossip.TournamentGossiper
-	TournamentStorage  state.TournamentStorage
-	AppStorage         state.AppStorage
-	SiteStorage        state.SiteStorage
-	SiteStorageReader  state.SiteStorageReader
-	UserStorage        state.UserStorage
-	PaytableStorage    state.PaytableStorage
-	SoundStorage       state.SoundEffectStorage
-	FormProcessor      *form.FormProcessor
-	SubFS              fs.FS
-	BakeryFactory      *permission.BakeryFactory
-	Clock              nower
-	TournamentManager  *tournament.Manager
+	DBListener           dbnotify.Listener
+	TournamentGossiper   *gossip.TournamentGossiper
+	DisplayGossiper      *gossip.DisplayGossiper
+	AnnouncementGossiper *gossip.AnnouncementGossiper
+	SiteConfigGossiper   *gossip.SiteConfigGossiper
+	TournamentStorage    state.TournamentStorage
+	DisplayStorage       state.DisplayStorage
+	LayoutStorage        state.LayoutStorage
+	SlideStorage         state.SlideStorage
+	AnnouncementStorage  state.AnnouncementStorage
+	TemplateStorage      state.TournamentTemplateStorage
+	LeagueStorage        state.LeagueStorage
+	AppStorage           state.AppStorage
+	SiteStorage          state.SiteStorage
+	SiteStorageReader    state.SiteStorageReader
+	UserStorage          state.UserStorage
+	APITokenStorage      state.APITokenStorage
+	WebhookStorage       state.WebhookStorage
+	PaytableStorage      state.PaytableStorage
+	SoundStorage         state.SoundEffectStorage
+	FormProcessor        *form.FormProcessor
+	SubFS                fs.FS
+	BakeryFactory        *permission.BakeryFactory
+	Clock                nower
+	TournamentManager    *tournament.Manager
+
+	// TrustedProxies are believed about who the client is.  Nil trusts
+	// nobody.
+	TrustedProxies *clientip.Trusted
+
+	// Certs, if set, turns on HTTPS at HTTPSListenAddress.  Plain HTTP is
+	// then redirected to it, unless ServeHTTP is set.  HTTP3 also serves
+	// HTTP/3 there, over UDP.
+	Certs              *certs.Certs
+	HTTPSListenAddress string
+	ServeHTTP          bool
+	HTTP3              bool
+
+	// HealthStorage is checked by /healthz and /readyz.
+	HealthStorage state.HealthStorage
+
+	// MetricsToken, if set, lets a scraper that sends it as a bearer token
+	// read /metrics.  Otherwise only administrators can.
+	MetricsToken string
+
+	// DrainTimeout is how long Serve waits for requests to finish once its
+	// context is done.  Zero is eight seconds.
+	DrainTimeout time.Duration
 }
 
 // App is the main web application.
@@ -128,24 +194,50 @@ type App struct {
 	subFS     fs.FS
 
 	// dependencies
-	dbListener         *dbnotify.DBNotifyListener
-	tournamentGossiper *gossip.TournamentGossiper
-	tournamentStorage  state.TournamentStorage
-	appStorage         state.AppStorage
-	siteStorage        state.SiteStorage
-	siteStorageReader  state.SiteStorageReader
-	userStorage        state.UserStorage
-	paytableStorage    state.PaytableStorage
-	soundStorage       state.SoundEffectStorage
-	formProcessor      *form.FormProcessor
-	bakeryFactory      *permission.BakeryFactory
-	clock              nower
-	tm                 *tournament.Manager
-	themeStorage       *builtins.ThemeStorage
+	dbListener           dbnotify.Listener
+	tournamentGossiper   *gossip.TournamentGossiper
+	displayGossiper      *gossip.DisplayGossiper
+	announcementGossiper *gossip.AnnouncementGossiper
+	tournamentStorage    state.TournamentStorage
+	displayStorage       state.DisplayStorage
+	layoutStorage        state.LayoutStorage
+	slideStorage         state.SlideStorage
+	announcementStorage  state.AnnouncementStorage
+	templateStorage      state.TournamentTemplateStorage
+	leagueStorage        state.LeagueStorage
+	appStorage           state.AppStorage
+	siteStorage          state.SiteStorage
+	siteStorageReader    state.SiteStorageReader
+	userStorage          state.UserStorage
+	apiTokenStorage      state.APITokenStorage
+	webhookStorage       state.WebhookStorage
+	paytableStorage      state.PaytableStorage
+	soundStorage         state.SoundEffectStorage
+	formProcessor        *form.FormProcessor
+	bakeryFactory        *permission.BakeryFactory
+	clock                nower
+	tm                   *tournament.Manager
+	themeStorage         *builtins.ThemeStorage
+
+	certs              *certs.Certs
+	httpsListenAddress string
+	serveHTTP          bool
+	http3              bool
+
+	healthStorage state.HealthStorage
+	metricsToken  string
+	drainTimeout  time.Duration
+	newDisplays   *newDisplayLimiter
+	draining      chan struct{} // closed when Serve starts to drain
+	drained       chan struct{} // closed when the drain is over
+	drainOnce     sync.Once
 
 	// internals
-	mux     *http.ServeMux
-	handler http.Handler
+	mux         *http.ServeMux
+	handler     http.Handler
+	inner       http.Handler                 // everything under CORS
+	corsHandler atomic.Pointer[http.Handler] // inner, as of the last site config
+	bonusPorts  *bonusPorts
 }
 
 func allowedOrigins(sc *model.SiteConfig) []string {
@@ -190,22 +282,45 @@ func New(ctx context.Context, config *Config) *App {
 	}
 
 	app := &App{
-		dbListener:         dep.Required(config.DBListener),
-		tournamentGossiper: dep.Required(config.TournamentGossiper),
-		appStorage:         dep.Required(config.AppStorage),
-		tournamentStorage:  dep.Required(config.TournamentStorage),
-		siteStorage:        dep.Required(config.SiteStorage),
-		siteStorageReader:  dep.Required(config.SiteStorageReader),
-		userStorage:        dep.Required(config.UserStorage),
-		paytableStorage:    dep.Required(config.PaytableStorage),
-		soundStorage:       dep.Required(config.SoundStorage),
-		formProcessor:      dep.Required(config.FormProcessor),
-		subFS:              dep.Required(config.SubFS),
-		bakeryFactory:      dep.Required(config.BakeryFactory),
-		clock:              dep.Required(config.Clock),
-		tm:                 dep.Required(config.TournamentManager),
-		mux:                dep.Required(http.DefaultServeMux),
-		themeStorage:       builtins.NewThemeStorage(),
+		dbListener:           dep.Required(config.DBListener),
+		tournamentGossiper:   dep.Required(config.TournamentGossiper),
+		appStorage:           dep.Required(config.AppStorage),
+		tournamentStorage:    dep.Required(config.TournamentStorage),
+		displayGossiper:      dep.Required(config.DisplayGossiper),
+		displayStorage:       dep.Required(config.DisplayStorage),
+		layoutStorage:        dep.Required(config.LayoutStorage),
+		slideStorage:         dep.Required(config.SlideStorage),
+		announcementGossiper: dep.Required(config.AnnouncementGossiper),
+		announcementStorage:  dep.Required(config.AnnouncementStorage),
+		templateStorage:      dep.Required(config.TemplateStorage),
+		leagueStorage:        dep.Required(config.LeagueStorage),
+		siteStorage:          dep.Required(config.SiteStorage),
+		siteStorageReader:    dep.Required(config.SiteStorageReader),
+		userStorage:          dep.Required(config.UserStorage),
+		apiTokenStorage:      dep.Required(config.APITokenStorage),
+		webhookStorage:       dep.Required(config.WebhookStorage),
+		paytableStorage:      dep.Required(config.PaytableStorage),
+		soundStorage:         dep.Required(config.SoundStorage),
+		formProcessor:        dep.Required(config.FormProcessor),
+		subFS:                dep.Required(config.SubFS),
+		bakeryFactory:        dep.Required(config.BakeryFactory),
+		clock:                dep.Required(config.Clock),
+		tm:                   dep.Required(config.TournamentManager),
+		mux:                  dep.Required(http.DefaultServeMux),
+		themeStorage:         builtins.NewThemeStorage(),
+		certs:                config.Certs,
+		httpsListenAddress:   config.HTTPSListenAddress,
+		serveHTTP:            config.ServeHTTP,
+		http3:                config.HTTP3,
+		healthStorage:        dep.Required(config.HealthStorage),
+		metricsToken:         config.MetricsToken,
+		drainTimeout:         config.DrainTimeout,
+		newDisplays:          newNewDisplayLimiter(time.Now),
+		draining:             make(chan struct{}),
+		drained:              make(chan struct{}),
+	}
+	if app.drainTimeout == 0 {
+		app.drainTimeout = defaultDrainTimeout
 	}
 
 	// Stack the handlers together.
@@ -215,18 +330,24 @@ func New(ctx context.Context, config *Config) *App {
 		Next:          app.mux,
 	})
 	csp := http.NewCrossOriginProtection()
-	logger := middleware.NewRequestLogger(csp.Handler(c2c), app.clock)
+	// Request times are real time, even when a demo speeds up app.clock.
+	requestLogger := middleware.NewRequestLogger(csp.Handler(c2c), clockwork.NewRealClock())
 	tarpit := labrea.Handler(&labrea.Config{
 		// Use real clock here for sub-ms precision.
 		Clock: clockwork.NewRealClock(),
-		Next:  logger,
+		Next:  requestLogger,
 	})
-	corsMW := cors.New(cors.Options{
-		AllowedOrigins:   allowedOrigins(sc),
-		AllowedMethods:   []string{http.MethodGet, http.MethodPost, http.MethodDelete},
-		AllowCredentials: true,
-	})
-	app.handler = corsMW.Handler(tarpit)
+	app.inner = tarpit
+	app.setCORS(sc)
+	app.handler = clientip.Handler(config.TrustedProxies, middleware.RequestID(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		(*app.corsHandler.Load()).ServeHTTP(w, r)
+	})))
+	var tlsConfig *tls.Config
+	if app.certs != nil {
+		tlsConfig = app.certs.TLSConfig()
+	}
+	app.bonusPorts = newBonusPorts(app.handler, tlsConfig)
+	dep.Required(config.SiteConfigGossiper).Subscribe(app.siteConfigChanged)
 
 	app.loadTemplates()
 	app.InstallHandlers()
@@ -324,7 +445,7 @@ func (app *App) requiringOperatorTakingIDHandleFunc(pattern string, handler func
 	})
 }
 
-func (app *App) renderTournament(ctx context.Context, id int64, w http.ResponseWriter, _ *http.Request) {
+func (app *App) renderTournament(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
 	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
 	if err != nil {
 		he.SendErrorToHTTPClient(w, "fetch site config", err)
@@ -343,18 +464,47 @@ func (app *App) renderTournament(ctx context.Context, id int64, w http.ResponseW
 		theme = sc.Theme
 	}
 
+	// A display can ask for its own layout with ?layout=N; ?layout=0 is the
+	// classic clock.
+	layoutID := t.LayoutID
+	if v := r.URL.Query().Get("layout"); v != "" {
+		if layoutID, err = strconv.ParseInt(v, 10, 64); err != nil {
+			he.SendErrorToHTTPClient(w, "parse layout", he.HTTPCodedErrorf(http.StatusBadRequest, "bad layout %q", v))
+			return
+		}
+	}
+	var lo *model.Layout
+	if layoutID != 0 {
+		if lo, err = app.layoutStorage.FetchLayout(ctx, layoutID); err != nil {
+			log.Printf("tournament %d: can't fetch layout %d, using classic layout: %v", id, layoutID, err)
+			lo = nil
+		}
+	}
+
 	args := struct {
 		Tournament                      *model.Tournament
 		InstallOperatorKeyboardHandlers bool
 		Theme                           string
-		Slides                          []string
+		Slides                          []slideshow.Show
+		Layout                          *model.Layout
+		LayoutCSS                       template.CSS
+		Panels                          []string
 	}{
 		Tournament:                      t,
 		InstallOperatorKeyboardHandlers: permission.CheckWriteAccessToTournamentID(ctx, id) == nil,
 		Theme:                           theme,
-		Slides:                          sc.Slides,
+		Slides:                          app.slidesFor(ctx, t.SlideSetID, sc),
+	}
+
+	tmpl := "view-tournament.html.tmpl"
+	if lo != nil {
+		tmpl = "view-layout.html.tmpl"
+		args.Layout = lo
+		// Layouts are validated when saved, so this is safe to paste in.
+		args.LayoutCSS = template.CSS(layout.StyleSheet(lo))
+		args.Panels = layout.Used(lo)
 	}
-	if err := app.templates.ExecuteTemplate(w, "view-tournament.html.tmpl", args); err != nil {
+	if err := app.templates.ExecuteTemplate(w, tmpl, args); err != nil {
 		log.Printf("can't render template: %v", err)
 	}
 }
@@ -564,6 +714,7 @@ func (app *App) handleCreateUser(ctx context.Context, w http.ResponseWriter, r *
 func (app *App) handleEditOwnAccount(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	var flash string
 	var flashType string
+	var newToken string
 
 	// Get the current user from context
 	currentUser := permission.UserFromContext(ctx)
@@ -605,6 +756,27 @@ func (app *App) handleEditOwnAccount(ctx context.Context, w http.ResponseWriter,
 					flash = "Password updated successfully"
 					flashType = "yay"
 				}
+			case "token-create":
+				if secret, err := app.createAPIToken(ctx, currentUser.ID, r.Form); err != nil {
+					flash = fmt.Sprintf("Error making token: %v", err)
+					flashType = "boo"
+				} else {
+					newToken = secret
+					flash = "Token made; copy it now, as it won't be shown again"
+					flashType = "yay"
+				}
+			case "token-revoke":
+				id, err := strconv.ParseInt(r.FormValue("TokenID"), 10, 64)
+				if err == nil {
+					err = app.apiTokenStorage.DeleteAPIToken(ctx, currentUser.ID, id)
+				}
+				if err != nil {
+					flash = fmt.Sprintf("Error revoking token: %v", err)
+					flashType = "boo"
+				} else {
+					flash = "Token revoked"
+					flashType = "yay"
+				}
 			default:
 				flash = "Unknown form type"
 				flashType = "boo"
@@ -618,6 +790,12 @@ func (app *App) handleEditOwnAccount(ctx context.Context, w http.ResponseWriter,
 		return
 	}
 
+	tokens, err := app.apiTokenStorage.FetchAPITokensByUserID(ctx, currentUser.ID)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch API tokens", err)
+		return
+	}
+
 	// Re-fetch user to get latest data
 	user, err := app.userStorage.FetchUserByUserID(ctx, currentUser.ID)
 	if err != nil {
@@ -633,6 +811,9 @@ func (app *App) handleEditOwnAccount(ctx context.Context, w http.ResponseWriter,
 		Nick       string
 		IsAdmin    bool
 		IsOperator bool
+		Tokens     []*model.APIToken
+		Scopes     []string
+		NewToken   string
 	}{
 		User:       user,
 		Theme:      sc.Theme,
@@ -641,6 +822,9 @@ func (app *App) handleEditOwnAccount(ctx context.Context, w http.ResponseWriter,
 		Nick:       app.currentUserNick(ctx),
 		IsAdmin:    permission.IsAdmin(ctx),
 		IsOperator: permission.IsOperator(ctx),
+		Tokens:     tokens,
+		Scopes:     apitoken.Scopes,
+		NewToken:   newToken,
 	}
 
 	if err := app.templates.ExecuteTemplate(w, "edit-own-account.html.tmpl", data); err != nil {
@@ -648,6 +832,49 @@ func (app *App) handleEditOwnAccount(ctx context.Context, w http.ResponseWriter,
 	}
 }
 
+// createAPIToken makes a token for the REST API from the account page's
+// form, returning the secret.
+func (app *App) createAPIToken(ctx context.Context, userID int64, form url.Values) (string, error) {
+	name := strings.TrimSpace(form.Get("TokenName"))
+	if name == "" {
+		return "", errors.New("name the token, so you know what it's for")
+	}
+	scopes, err := apitoken.ParseScopes(strings.Join(form["Scopes"], ","))
+	if err != nil {
+		return "", err
+	}
+	secret, hash := apitoken.New()
+	if _, err := app.apiTokenStorage.CreateAPIToken(ctx, &model.APIToken{
+		UserID: userID,
+		Name:   name,
+		Scopes: scopes,
+	}, hash); err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// copyTournament makes a new tournament that keeps most of t's settings but
+// starts from the beginning.
+func copyTournament(t *model.Tournament) *model.Tournament {
+	tournament := t.Clone()
+	tournament.EventID = 0
+	tournament.Version = 0
+	tournament.ScheduledStart = time.Time{}
+	tournament.FromTemplateID = 0
+
+	// Reset state to the beginning.
+	tournament.State = &model.State{
+		AutoComputePrizePool:   t.State.AutoComputePrizePool,
+		TotalChipsOverride:     t.State.TotalChipsOverride,
+		TotalPrizePoolOverride: t.State.TotalPrizePoolOverride,
+
+		// Reset clock/level state for new tournament
+		CurrentLevelNumber: 0,
+	}
+	return tournament
+}
+
 func (app *App) handleCreateTournament(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	var flash string
 	flashType := "boo"
@@ -693,6 +920,24 @@ func (app *App) handleCreateTournament(ctx context.Context, w http.ResponseWrite
 
 	themeSlugs := app.themeStorage.FetchThemeSlugs()
 
+	layouts, err := app.layoutStorage.FetchLayouts(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch layouts", err)
+		return
+	}
+
+	slideSets, err := app.slideStorage.FetchSlideSets(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slide sets", err)
+		return
+	}
+
+	leagues, err := app.leagueStorage.FetchLeagues(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch leagues", err)
+		return
+	}
+
 	// Handle template ID from query param for pre-populating
 	templateID := r.URL.Query().Get("template")
 	var tournament *model.Tournament
@@ -702,23 +947,8 @@ func (app *App) handleCreateTournament(ctx context.Context, w http.ResponseWrite
 		} else if t, err := app.fetchTournament(ctx, id); err != nil {
 			log.Printf("error fetching template tournament %d: %v", id, err)
 		} else {
-			tournament = t.Clone()
-
-			// Make this a different tournament than the old one but keep
-			// most of the values.
-			tournament.EventID = 0
-			tournament.Version = 0
+			tournament = copyTournament(t)
 			tournament.EventName = t.EventName + " (Copy)"
-
-			// Reset state to the beginning.
-			tournament.State = &model.State{
-				AutoComputePrizePool:   t.State.AutoComputePrizePool,
-				TotalChipsOverride:     t.State.TotalChipsOverride,
-				TotalPrizePoolOverride: t.State.TotalPrizePoolOverride,
-
-				// Reset clock/level state for new tournament
-				CurrentLevelNumber: 0,
-			}
 		}
 	}
 
@@ -744,6 +974,9 @@ func (app *App) handleCreateTournament(ctx context.Context, w http.ResponseWrite
 		Paytables:  paytables,
 		ThemeSlugs: themeSlugs,
 		Sounds:     sounds,
+		Layouts:    layouts,
+		SlideSets:  slideSets,
+		Leagues:    leagues,
 		Nick:       app.currentUserNick(ctx),
 	}
 	if err := app.templates.ExecuteTemplate(w, "edit-tournament.html.tmpl", data); err != nil {
@@ -859,6 +1092,38 @@ func (app *App) handleEditStructure(ctx context.Context, id int64, w http.Respon
 	}
 }
 
+// footerPlugRow is a footer plug as the footer set editor shows it.
+type footerPlugRow struct {
+	Kinds      []footerplug.Kind
+	Kind       model.FooterPlugKind
+	Text       string
+	URL        string
+	Weight     int
+	ValidFrom  string
+	ValidUntil string
+}
+
+func footerPlugRows(plugs []model.FooterPlug) []footerPlugRow {
+	rows := []footerPlugRow{}
+	for i := range plugs {
+		p := &plugs[i]
+		rows = append(rows, footerPlugRow{
+			Kinds:      footerplug.Kinds,
+			Kind:       footerplug.KindOf(p),
+			Text:       p.Text,
+			URL:        p.URL,
+			Weight:     footerplug.WeightOf(p),
+			ValidFrom:  footerplug.FormatTime(p.ValidFrom, time.Local),
+			ValidUntil: footerplug.FormatTime(p.ValidUntil, time.Local),
+		})
+	}
+	return rows
+}
+
+func newFooterPlugRow() footerPlugRow {
+	return footerPlugRow{Kinds: footerplug.Kinds, Kind: model.FooterPlugKindText, Weight: 1}
+}
+
 func (app *App) handleEditFooterSet(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
 	var flash string
 	if r.Method == http.MethodPost {
@@ -867,22 +1132,13 @@ func (app *App) handleEditFooterSet(ctx context.Context, id int64, w http.Respon
 			flash = "Error parsing form"
 		} else {
 			name := r.FormValue("Name")
-			plugs := []string{}
-			for i := 0; ; i++ {
-				plug := r.FormValue(fmt.Sprintf("Plug%d", i))
-				if plug == "" && i >= len(r.Form)-1 {
-					break
-				}
-				plug = strings.TrimSpace(plug)
-				if plug != "" {
-					plugs = append(plugs, plug)
-				}
-			}
+			plugs, err := footerplug.ParsePlugs(r.Form, time.Local)
 			if name == "" {
 				flash = "Set name required"
+			} else if err != nil {
+				flash = err.Error()
 			} else {
-				err := app.appStorage.UpdateFooterPlugSet(ctx, id, name, plugs)
-				if err != nil {
+				if err := app.appStorage.UpdateFooterPlugSet(ctx, id, name, plugs); err != nil {
 					flash = "Error saving footer plug set"
 				} else {
 					http.Redirect(w, r, "/manage/footer-set", http.StatusSeeOther)
@@ -903,6 +1159,8 @@ func (app *App) handleEditFooterSet(ctx context.Context, id int64, w http.Respon
 	}
 	data := struct {
 		FooterSet  *model.FooterPlugs
+		Plugs      []footerPlugRow
+		NewPlug    footerPlugRow
 		Flash      string
 		IsNew      bool
 		Theme      string
@@ -911,6 +1169,8 @@ func (app *App) handleEditFooterSet(ctx context.Context, id int64, w http.Respon
 		IsOperator bool
 	}{
 		FooterSet:  fp,
+		Plugs:      footerPlugRows(fp.Plugs),
+		NewPlug:    newFooterPlugRow(),
 		Flash:      flash,
 		IsNew:      false,
 		Theme:      sc.Theme,
@@ -1066,22 +1326,13 @@ func (app *App) handleCreateFooterSet(ctx context.Context, w http.ResponseWriter
 			flash = "Error parsing form"
 		} else {
 			name := r.FormValue("Name")
-			plugs := []string{}
-			for i := 0; ; i++ {
-				plug := r.FormValue(fmt.Sprintf("Plug%d", i))
-				if plug == "" && i >= len(r.Form)-1 {
-					break
-				}
-				plug = strings.TrimSpace(plug)
-				if plug != "" {
-					plugs = append(plugs, plug)
-				}
-			}
+			plugs, err := footerplug.ParsePlugs(r.Form, time.Local)
 			if name == "" {
 				flash = "Set name required"
+			} else if err != nil {
+				flash = err.Error()
 			} else {
-				_, err := app.appStorage.CreateFooterPlugSet(ctx, name, plugs)
-				if err != nil {
+				if _, err := app.appStorage.CreateFooterPlugSet(ctx, name, plugs); err != nil {
 					log.Printf("error creating footer plug set: %v", err)
 					flash = "Error saving footer plug set"
 				} else {
@@ -1101,14 +1352,14 @@ func (app *App) handleCreateFooterSet(ctx context.Context, w http.ResponseWriter
 			log.Printf("error fetching footer plug set for template ID %d: %v (ignoring parameter)", id, err)
 		} else {
 			footerSet = &model.FooterPlugs{
-				Name:      fs.Name + " (Copy)",
-				TextPlugs: fs.TextPlugs,
+				Name:  fs.Name + " (Copy)",
+				Plugs: fs.Plugs,
 			}
 		}
 	}
 
 	if footerSet == nil {
-		footerSet = &model.FooterPlugs{TextPlugs: []string{}}
+		footerSet = &model.FooterPlugs{Plugs: []model.FooterPlug{}}
 	}
 
 	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
@@ -1119,6 +1370,8 @@ func (app *App) handleCreateFooterSet(ctx context.Context, w http.ResponseWriter
 
 	data := struct {
 		FooterSet  *model.FooterPlugs
+		Plugs      []footerPlugRow
+		NewPlug    footerPlugRow
 		Flash      string
 		IsNew      bool
 		Theme      string
@@ -1127,6 +1380,8 @@ func (app *App) handleCreateFooterSet(ctx context.Context, w http.ResponseWriter
 		IsOperator bool
 	}{
 		FooterSet:  footerSet,
+		Plugs:      footerPlugRows(footerSet.Plugs),
+		NewPlug:    newFooterPlugRow(),
 		Flash:      flash,
 		IsNew:      true,
 		Theme:      sc.Theme,
@@ -1170,6 +1425,25 @@ func (app *App) handleManageFooterSets(ctx context.Context, w http.ResponseWrite
 	}
 }
 
+const indexPageSize = 25
+
+// indexFilter is a tab on the index page.
+type indexFilter struct {
+	Name        string
+	Description string
+	Lifecycles  []model.Lifecycle
+}
+
+// indexFilters are the index page's tabs.  The first is the default.
+var indexFilters = []indexFilter{
+	{"current", "Current", tournament.CurrentLifecycles},
+	{"live", "Live", tournament.LiveLifecycles},
+	{"upcoming", "Upcoming", []model.Lifecycle{model.LifecycleScheduled}},
+	{"finished", "Finished", []model.Lifecycle{model.LifecycleFinished}},
+	{"archived", "Archive", []model.Lifecycle{model.LifecycleArchived}},
+	{"all", "All", nil},
+}
+
 func (app *App) handleIndex(ctx context.Context, w http.ResponseWriter, r *http.Request) {
 	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
 	if err != nil {
@@ -1177,8 +1451,24 @@ func (app *App) handleIndex(ctx context.Context, w http.ResponseWriter, r *http.
 		return
 	}
 
-	// TODO: pagination
-	o, err := app.tournamentStorage.FetchOverview(ctx, 0, 100)
+	show := r.URL.Query().Get("show")
+	if show == "" {
+		show = indexFilters[0].Name
+	}
+	i := slices.IndexFunc(indexFilters, func(f indexFilter) bool { return f.Name == show })
+	if i < 0 {
+		he.SendErrorToHTTPClient(w, "parse filter", he.HTTPCodedErrorf(http.StatusBadRequest, "no such filter %q", show))
+		return
+	}
+	page := 1
+	if v := r.URL.Query().Get("page"); v != "" {
+		if page, err = strconv.Atoi(v); err != nil || page < 1 {
+			he.SendErrorToHTTPClient(w, "parse page", he.HTTPCodedErrorf(http.StatusBadRequest, "bad page %q", v))
+			return
+		}
+	}
+
+	o, err := app.tournamentStorage.FetchOverview(ctx, indexFilters[i].Lifecycles, (page-1)*indexPageSize, indexPageSize)
 	if err != nil {
 		he.SendErrorToHTTPClient(w, "fetch overview", err)
 		return
@@ -1189,6 +1479,12 @@ func (app *App) handleIndex(ctx context.Context, w http.ResponseWriter, r *http.
 		Overview   *model.Overview
 		SiteConfig *model.SiteConfig
 		Nick       string
+		Filters    []indexFilter
+		Show       string
+		Page       int
+		Pages      int
+		PrevPage   int // 0 if none
+		NextPage   int // 0 if none
 	}
 	inputs := &Inputs{
 		IsAdmin:    permission.IsAdmin(ctx),
@@ -1196,6 +1492,16 @@ func (app *App) handleIndex(ctx context.Context, w http.ResponseWriter, r *http.
 		Overview:   o,
 		SiteConfig: sc,
 		Nick:       app.currentUserNick(ctx),
+		Filters:    indexFilters,
+		Show:       show,
+		Page:       page,
+		Pages:      max(1, (o.Total+indexPageSize-1)/indexPageSize),
+	}
+	if page > 1 {
+		inputs.PrevPage = page - 1
+	}
+	if page < inputs.Pages {
+		inputs.NextPage = page + 1
 	}
 	if err := app.templates.ExecuteTemplate(w, "slash.html.tmpl", inputs); err != nil {
 		log.Printf("can't render template: %v", err)
@@ -1245,6 +1551,24 @@ func (app *App) handleEditTournament(ctx context.Context, id64 int64, w http.Res
 
 	themeSlugs := app.themeStorage.FetchThemeSlugs()
 
+	layouts, err := app.layoutStorage.FetchLayouts(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch layouts", err)
+		return
+	}
+
+	slideSets, err := app.slideStorage.FetchSlideSets(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slide sets", err)
+		return
+	}
+
+	leagues, err := app.leagueStorage.FetchLeagues(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch leagues", err)
+		return
+	}
+
 	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
 	if err != nil {
 		he.SendErrorToHTTPClient(w, "fetch site config", err)
@@ -1262,13 +1586,171 @@ func (app *App) handleEditTournament(ctx context.Context, id64 int64, w http.Res
 		IsNew:      false,
 		SiteConfig: sc,
 		Sounds:     sounds,
+		Layouts:    layouts,
+		SlideSets:  slideSets,
+		Leagues:    leagues,
 		Nick:       app.currentUserNick(ctx),
+		Lifecycles: lifecycleChoices(tournament.LifecycleOf(t)),
 	}
 	if err := app.templates.ExecuteTemplate(w, "edit-tournament.html.tmpl", args); err != nil {
 		log.Printf("can't render edit-tournament template: %v", err)
 	}
 }
 
+func lifecycleChoices(current model.Lifecycle) []tournament.LifecycleChoice {
+	var choices []tournament.LifecycleChoice
+	for _, c := range tournament.Lifecycles {
+		if c.Manual || c.Lifecycle == current {
+			choices = append(choices, c)
+		}
+	}
+	return choices
+}
+
+// handleArchiveTournament puts a finished tournament in the archive, or
+// (with archive false) takes it back out.
+func (app *App) handleArchiveTournament(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request, archive bool) {
+	if r.Method != http.MethodPost {
+		he.SendErrorToHTTPClient(w, "archive tournament", he.HTTPCodedErrorf(http.StatusMethodNotAllowed, "use POST"))
+		return
+	}
+	t, err := app.fetchTournament(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch tournament", err)
+		return
+	}
+	t = t.Clone()
+	if archive {
+		err = app.tm.Archive(t)
+	} else {
+		err = app.tm.Unarchive(t)
+	}
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "archive tournament", err)
+		return
+	}
+	if err := app.tournamentStorage.SaveTournament(ctx, t); err != nil {
+		he.SendErrorToHTTPClient(w, "save tournament", err)
+		return
+	}
+	http.Redirect(w, r, fmt.Sprintf("/t/%d/edit", id), http.StatusSeeOther)
+}
+
+// handleChipCounts is a phone-sized page for the floor to enter chip counts.
+func (app *App) handleChipCounts(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+	var flash, flashType string
+	if r.Method == http.MethodPost {
+		if err := app.applyChipCountForm(ctx, id, r); err != nil {
+			log.Printf("chip count for tournament %d: %v", id, err)
+			flash, flashType = err.Error(), "boo"
+		} else {
+			http.Redirect(w, r, fmt.Sprintf("/t/%d/chips", id), http.StatusSeeOther)
+			return
+		}
+	}
+
+	t, err := app.fetchTournament(ctx, id)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch tournament", err)
+		return
+	}
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+
+	type countRow struct {
+		Name  string
+		Chips string
+		Age   string
+	}
+	now := app.clock.Now()
+	counts := slices.Clone(t.State.ChipCounts)
+	slices.SortStableFunc(counts, func(a, b *model.ChipCount) int { return b.Chips - a.Chips })
+	rows := make([]countRow, len(counts))
+	counted := 0
+	for i, cc := range counts {
+		counted += cc.Chips
+		rows[i] = countRow{
+			Name:  cc.Name,
+			Chips: textutil.FormatCommas(cc.Chips),
+			Age:   now.Sub(time.UnixMilli(cc.CountedAt)).Round(time.Minute).String(),
+		}
+	}
+
+	data := struct {
+		Tournament   *model.Tournament
+		Counts       []countRow
+		CountedChips string
+		TotalChips   string
+		AverageChips string
+		MedianChips  string
+		Flash        string
+		FlashType    string
+		Theme        string
+		Nick         string
+		IsAdmin      bool
+		IsOperator   bool
+	}{
+		Tournament:   t,
+		Counts:       rows,
+		CountedChips: textutil.FormatCommas(counted),
+		TotalChips:   textutil.FormatCommas(t.Transients.TotalChips),
+		AverageChips: textutil.FormatCommas(t.Transients.CountedAverageChips),
+		MedianChips:  textutil.FormatCommas(t.Transients.MedianChips),
+		Flash:        flash,
+		FlashType:    flashType,
+		Theme:        sc.Theme,
+		Nick:         app.currentUserNick(ctx),
+		IsAdmin:      permission.IsAdmin(ctx),
+		IsOperator:   permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "chip-counts.html.tmpl", data); err != nil {
+		log.Printf("can't render chip-counts template: %v", err)
+	}
+}
+
+func (app *App) applyChipCountForm(ctx context.Context, id int64, r *http.Request) error {
+	if err := r.ParseForm(); err != nil {
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+
+	t, err := app.tournamentStorage.FetchTournament(ctx, id)
+	if err != nil {
+		return err
+	}
+
+	switch r.FormValue("Action") {
+	case "set":
+		chips, err := textutil.ParseChips(r.FormValue("Chips"))
+		if err != nil {
+			return err
+		}
+		if err := app.tm.SetChipCount(t, r.FormValue("Name"), chips); err != nil {
+			return err
+		}
+	case "remove":
+		if err := app.tm.SetChipCount(t, r.FormValue("Name"), 0); err != nil {
+			return err
+		}
+	case "clear":
+		app.tm.ClearChipCounts(t)
+	default:
+		return he.HTTPCodedErrorf(http.StatusBadRequest, "unknown action")
+	}
+
+	return app.tournamentStorage.SaveTournament(ctx, t)
+}
+
+// apiFooterPlug is a footer plug as the clock gets it, already drawn.
+type apiFooterPlug struct {
+	HTML       string
+	Weight     int
+	ValidFrom  time.Time `json:",omitzero"`
+	ValidUntil time.Time `json:",omitzero"`
+}
+
 func (app *App) handleAPIFooterPlugs(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
 	fp, err := app.appStorage.FetchPlugs(ctx, id)
 	if err != nil {
@@ -1276,14 +1758,34 @@ func (app *App) handleAPIFooterPlugs(ctx context.Context, id int64, w http.Respo
 		return
 	}
 
-	for i, s := range fp.TextPlugs {
-		escaped := html.EscapeString(s)
-		// Note: This corrupts the data in the model, which is kind of weird.
-		// appStorage cloned the model, so this is disposable, but still...
-		fp.TextPlugs[i] = textutil.WrapAttributionInNobr(escaped)
+	// Plugs that haven't started yet are sent along, since the clock may
+	// stay up until they do.  Plugs that have ended are not.
+	now := app.clock.Now()
+	plugs := []apiFooterPlug{}
+	for i := range fp.Plugs {
+		p := &fp.Plugs[i]
+		if !p.ValidUntil.IsZero() && !now.Before(p.ValidUntil) {
+			continue
+		}
+		h, err := footerplug.Render(p)
+		if err != nil {
+			log.Printf("can't render plug %d of footer plug set %d: %v", i, id, err)
+			continue
+		}
+		plugs = append(plugs, apiFooterPlug{
+			HTML:       h,
+			Weight:     footerplug.WeightOf(p),
+			ValidFrom:  p.ValidFrom,
+			ValidUntil: p.ValidUntil,
+		})
 	}
 
-	bytes, err := json.Marshal(fp)
+	bytes, err := json.Marshal(struct {
+		FooterPlugsID int64
+		Version       int64
+		Name          string
+		Plugs         []apiFooterPlug
+	}{fp.FooterPlugsID, fp.Version, fp.Name, plugs})
 	if err != nil {
 		he.SendErrorToHTTPClient(w, "marshalling plugs", he.New(500, err))
 		return
@@ -1351,7 +1853,7 @@ func (app *App) handleLogin(ctx context.Context, w http.ResponseWriter, r *http.
 			http.Redirect(w, r, "/login?error=internal+error", http.StatusSeeOther)
 		}
 		badLogin := func() {
-			log.Printf("bad password")
+			log.Printf("bad password from %s", clientip.Of(r))
 			time.Sleep(sleepyTime)
 			http.Redirect(w, r, "/login?error=invalid+user+or+password", http.StatusSeeOther)
 		}
@@ -1401,6 +1903,8 @@ func (app *App) handleLogin(ctx context.Context, w http.ResponseWriter, r *http.
 }
 
 func (app *App) handleAPITournamentListen(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	defer countListen(r)()
+
 	type reqBody struct {
 		TournamentID    int64
 		Version         int64
@@ -1458,6 +1962,9 @@ func (app *App) handleAPITournamentListen(ctx context.Context, w http.ResponseWr
 		log.Printf("client closed connection while listening for tournament update")
 		http.Error(w, "request cancelled", http.StatusRequestTimeout)
 		return
+	case <-app.draining:
+		sendReconnect(w)
+		return
 	}
 }
 
@@ -1551,9 +2058,14 @@ func (app *App) handleManageSite(ctx context.Context, w http.ResponseWriter, r *
 				config.Name = name
 				config.CookieDomain = cookieDomain
 				config.BonusHTTPPorts = parsePorts(r.FormValue("BonusHTTPPorts"))
+				config.BonusHTTPSPorts = parsePorts(r.FormValue("BonusHTTPSPorts"))
 				config.AllowedOriginDomains = parseAllowedOrigins(allowedOriginDomains)
 				config.Theme = theme
 				config.Slides = parseSlides(slidesRaw)
+				if config.DefaultSlideSetID, err = strconv.ParseInt(r.FormValue("DefaultSlideSetID"), 10, 64); err != nil {
+					he.SendErrorToHTTPClient(w, "get default slide set ID", he.HTTPCodedErrorf(http.StatusBadRequest, "bad slide set id"))
+					return
+				}
 				config.Motd = motd
 				if config.DefaultNextLevelSoundID, err = app.parseSoundID(ctx, soundID); err != nil {
 					he.SendErrorToHTTPClient(w, "get default sound ID", err)
@@ -1579,10 +2091,17 @@ func (app *App) handleManageSite(ctx context.Context, w http.ResponseWriter, r *
 
 	themeSlugs := app.themeStorage.FetchThemeSlugs()
 
+	slideSets, err := app.slideStorage.FetchSlideSets(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch slide sets", err)
+		return
+	}
+
 	data := struct {
 		Config     *model.SiteConfig
 		Sounds     []*soundmodel.SoundEffectSlug
 		ThemeSlugs []*builtins.ThemeSlug
+		SlideSets  []*model.SlideSet
 		Flash      string
 		FlashType  string
 		Nick       string
@@ -1594,6 +2113,7 @@ func (app *App) handleManageSite(ctx context.Context, w http.ResponseWriter, r *
 		FlashType:  flashType,
 		Sounds:     soundSlugs,
 		ThemeSlugs: themeSlugs,
+		SlideSets:  slideSets,
 		Nick:       app.currentUserNick(ctx),
 		IsAdmin:    permission.IsAdmin(ctx),
 		IsOperator: permission.IsOperator(ctx),
@@ -1839,6 +2359,28 @@ func (app *App) handleThemedCSS(ctx context.Context, w http.ResponseWriter, r *h
 	}
 }
 
+// acceleratedClock is a clock that doesn't keep real time, like
+// ts.AcceleratedClock in demo mode.
+type acceleratedClock interface {
+	Epoch() time.Time
+	Rate() float64
+}
+
+// handleClockJS defines irataNow(), which the clock pages use in place of
+// Date.now() so they keep the server's time.  With a real clock it is
+// Date.now().
+func (app *App) handleClockJS(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var epoch int64
+	rate := 1.0
+	if ac, ok := app.clock.(acceleratedClock); ok {
+		epoch = ac.Epoch().UnixMilli()
+		rate = ac.Rate()
+	}
+	w.Header().Set("Content-Type", "text/javascript; charset=utf-8")
+	w.Header().Set("Cache-Control", "no-cache")
+	fmt.Fprintf(w, "function irataNow() {\n  return %d + (Date.now() - %d) * %g;\n}\n", epoch, epoch, rate)
+}
+
 // InstallHandlers registers all HTTP routes.
 func (app *App) InstallHandlers() {
 
@@ -1855,9 +2397,16 @@ func (app *App) InstallHandlers() {
 
 	app.handleFunc("/robots.txt", handlers.HandleRobotsTXT)
 
+	app.handleFunc("/healthz", app.handleHealthz)
+	app.handleFunc("/readyz", app.handleReadyz)
+
+	app.handleFunc("/metrics", app.handleMetrics)
+
 	// Themed CSS route
 	app.handleFunc("/style/{theme}/css", app.handleThemedCSS)
 
+	app.handleFunc("/clock.js", app.handleClockJS)
+
 	// anything in fs is a file trivially shared
 	fileServer := http.FileServer(http.FS(app.subFS))
 	cachedFileServer := middleware.NewCacheHeaderAdder(&middleware.CacheHeaderAdderConfig{
@@ -1872,6 +2421,16 @@ func (app *App) InstallHandlers() {
 
 	app.requiringOperatorHandleFunc("/api/keyboard-control", app.handleKeyboardControl)
 
+	api.New(&api.Config{
+		TokenStorage:      app.apiTokenStorage,
+		UserStorage:       app.userStorage,
+		TournamentStorage: app.tournamentStorage,
+		AppStorage:        app.appStorage,
+		TemplateStorage:   app.templateStorage,
+		TournamentManager: app.tm,
+		Clock:             app.clock,
+	}).Install(app.mux)
+
 	app.requiringOperatorHandleFunc("/manage/structure", app.handleManageStructures)
 
 	app.requiringAdminHandleFunc("/manage/users", app.handleManageUsers)
@@ -1939,6 +2498,10 @@ func (app *App) InstallHandlers() {
 
 	app.requiringOperatorTakingIDHandleFunc("/t/{id}/edit", app.handleEditTournament)
 
+	app.requiringOperatorTakingIDHandleFunc("/t/{id}/chips", app.handleChipCounts)
+
+	app.handleFuncTakingID("/api/footer-plugs/{id}", app.handleAPIFooterPlugs)
+	// Clocks loaded before the rename still ask here.
 	app.handleFuncTakingID("/api/footerPlugs/{id}", app.handleAPIFooterPlugs)
 
 	app.handleFuncTakingID("/api/model/{id}", app.handleAPIModel)
@@ -1958,14 +2521,125 @@ func (app *App) InstallHandlers() {
 	app.handleFunc("/api/chopomatic", app.handleChopomaticAPI)
 
 	app.requiringAdminHandleFunc("/manage/site", app.handleManageSite)
+
+	app.requiringAdminHandleFunc("/manage/webhooks", app.handleManageWebhooks)
+
+	app.handleFunc("/kiosk", app.handleKiosk)
+
+	app.handleFunc("/api/kiosk-listen", app.handleAPIKioskListen)
+	app.handleFunc("/api/announcement-listen", app.handleAPIAnnouncementListen)
+	app.requiringOperatorHandleFunc("/manage/announcements", app.handleManageAnnouncements)
+
+	app.handleFunc("/slideshow", app.handleSlideshow)
+
+	app.handleFunc("/lobby", app.handleLobby)
+
+	app.handleFunc("/api/lobby-listen", app.handleAPILobbyListen)
+
+	app.requiringOperatorHandleFunc("/manage/displays", app.handleManageDisplays)
+
+	app.requiringAdminHandleFunc("/manage/layouts", app.handleManageLayouts)
+
+	app.requiringAdminHandleFunc("/create/layout", app.handleCreateLayout)
+
+	app.requiringAdminTakingIDHandleFunc("/manage/layout/{id}/edit", app.handleEditLayout)
+
+	app.requiringAdminTakingIDHandleFunc("/manage/layout/{id}/delete", func(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+		if err := app.layoutStorage.DeleteLayout(ctx, id); err != nil {
+			he.SendErrorToHTTPClient(w, "delete layout", err)
+			return
+		}
+		http.Redirect(w, r, "/manage/layouts", http.StatusSeeOther)
+	})
+
+	app.handleFunc("/qr", app.handleQRCode)
+
+	app.requiringOperatorHandleFunc("/manage/slides", app.handleManageSlides)
+
+	app.requiringOperatorHandleFunc("/create/slide", app.handleCreateSlide)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/slide/{id}/edit", app.handleEditSlide)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/slide/{id}/delete", func(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+		if err := app.slideStorage.DeleteSlide(ctx, id); err != nil {
+			he.SendErrorToHTTPClient(w, "delete slide", err)
+			return
+		}
+		http.Redirect(w, r, "/manage/slides", http.StatusSeeOther)
+	})
+
+	app.handleFuncTakingID("/slide/{id}/image", app.handleSlideImage)
+
+	app.requiringOperatorHandleFunc("/create/slide-set", app.handleCreateSlideSet)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/slide-set/{id}/edit", app.handleEditSlideSet)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/slide-set/{id}/delete", func(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+		if err := app.slideStorage.DeleteSlideSet(ctx, id); err != nil {
+			he.SendErrorToHTTPClient(w, "delete slide set", err)
+			return
+		}
+		http.Redirect(w, r, "/manage/slides", http.StatusSeeOther)
+	})
+
+	app.requiringOperatorTakingIDHandleFunc("/t/{id}/seating", app.handleSeating)
+
+	app.requiringOperatorHandleFunc("/manage/templates", app.handleManageTemplates)
+
+	app.requiringOperatorHandleFunc("/create/template", app.handleCreateTemplate)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/template/{id}/edit", app.handleEditTemplate)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/template/{id}/instantiate", app.handleInstantiateTemplate)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/template/{id}/delete", func(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+		if err := app.templateStorage.DeleteTournamentTemplate(ctx, id); err != nil {
+			he.SendErrorToHTTPClient(w, "delete template", err)
+			return
+		}
+		http.Redirect(w, r, "/manage/templates", http.StatusSeeOther)
+	})
+
+	app.handleFunc("/calendar", app.handleCalendar)
+
+	app.handleFunc("/calendar.ics", app.handleCalendarICS)
+
+	app.requiringOperatorTakingIDHandleFunc("/t/{id}/archive", func(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+		app.handleArchiveTournament(ctx, id, w, r, true)
+	})
+
+	app.requiringAdminTakingIDHandleFunc("/t/{id}/unarchive", func(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+		app.handleArchiveTournament(ctx, id, w, r, false)
+	})
+
+	app.requiringOperatorTakingIDHandleFunc("/t/{id}/standings", app.handleStandings)
+
+	app.handleFunc("/leagues", app.handleLeagues)
+
+	app.handleFuncTakingID("/league/{id}", app.handleLeague)
+
+	app.handleFuncTakingID("/league/{id}/csv", app.handleLeagueCSV)
+
+	app.requiringOperatorHandleFunc("/create/league", app.handleCreateLeague)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/league/{id}/edit", app.handleEditLeague)
+
+	app.requiringOperatorTakingIDHandleFunc("/manage/league/{id}/delete", func(ctx context.Context, id int64, w http.ResponseWriter, r *http.Request) {
+		if err := app.leagueStorage.DeleteLeague(ctx, id); err != nil {
+			he.SendErrorToHTTPClient(w, "delete league", err)
+			return
+		}
+		http.Redirect(w, r, "/leagues", http.StatusSeeOther)
+	})
 }
 
 var chopAlgorithms = map[string]struct {
 	name    string
 	chopper chop.Chopper
 }{
-	"icm":          {"ICM", &icm.Chopper{}},
-	"proportional": {"Proportional (chip chop)", &proportional.Chopper{}},
+	"icm":              {"ICM", &icm.Chopper{}},
+	"malmuth-weitzman": {"Malmuth-Weitzman", &mw.Chopper{}},
+	"proportional":     {"Proportional (chip chop)", &proportional.Chopper{}},
 }
 
 func (app *App) handleChopomaticPage(ctx context.Context, w http.ResponseWriter, r *http.Request) {
@@ -2081,6 +2755,11 @@ func (app *App) handleChopomaticAPI(ctx context.Context, w http.ResponseWriter,
 		Chips     []int  `json:"chips"`
 		Prizes    []int  `json:"prizes"`
 		Algorithm string `json:"algorithm"`
+		// Save is the amount set aside for the winner, if any.
+		Save int `json:"save"`
+		// Floor, if present, is the amount everybody locks before the
+		// chop; zero means the next payout.
+		Floor *int `json:"floor"`
 	}
 
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
@@ -2109,9 +2788,21 @@ func (app *App) handleChopomaticAPI(ctx context.Context, w http.ResponseWriter,
 		return
 	}
 
-	chopped, err := algoInfo.chopper.Chop(req.Chips, req.Prizes)
+	// The save comes off the top before the floor is locked, so everyone's
+	// floor is still guaranteed.
+	chopper := algoInfo.chopper
+	if req.Floor != nil {
+		chopper = &floor.Chopper{Base: chopper, Amount: *req.Floor}
+		chopomaticAlgoTypes.Add("floor", 1)
+	}
+	if req.Save != 0 {
+		chopper = &save.Chopper{Base: chopper, Amount: req.Save}
+		chopomaticAlgoTypes.Add("save", 1)
+	}
+
+	chopped, err := chopper.Chop(req.Chips, req.Prizes)
 	if err != nil {
-		he.SendErrorToHTTPClient(w, "chop", err)
+		he.SendErrorToHTTPClient(w, "chop", he.New(http.StatusBadRequest, err))
 		return
 	}
 
@@ -2145,8 +2836,25 @@ func contextualizer(ctx context.Context) func(net.Listener) context.Context {
 	}
 }
 
-// Serve starts the HTTP server on the given listen address.
+func newServer(ctx context.Context, addr string, h http.Handler) *http.Server {
+	return &http.Server{
+		Addr:         addr,
+		Handler:      h,
+		BaseContext:  contextualizer(ctx),
+		ReadTimeout:  10 * time.Second,
+		WriteTimeout: 1 * time.Hour,
+		IdleTimeout:  12 * time.Hour,
+	}
+}
+
+// Serve starts the HTTP server on the given listen address.  When ctx is
+// done, it drains: long polls are told to reconnect, and Serve returns once
+// the requests in flight finish, or after the drain timeout.
 func (app *App) Serve(ctx context.Context, listenAddress string) error {
+	// Requests shouldn't be cancelled just because ctx is done; the drain
+	// ends them more gently.
+	serveCtx := context.WithoutCancel(ctx)
+
 	wg := sync.WaitGroup{}
 
 	type result struct {
@@ -2156,24 +2864,68 @@ func (app *App) Serve(ctx context.Context, listenAddress string) error {
 
 	ch := make(chan *result)
 
+	// The requests still being served during the drain want fresh caches,
+	// so the listener stops after the drain rather than when it starts.
+	listenCtx, stopListening := context.WithCancel(serveCtx)
+	defer stopListening()
 	wg.Go(func() {
-		err := app.dbListener.Listen(ctx)
+		err := app.dbListener.Listen(listenCtx)
+		if listenCtx.Err() != nil {
+			err = nil
+		}
 		ch <- &result{"dbListener", err}
-		wg.Done()
 	})
 
-	wg.Go(func() {
-		server := &http.Server{
-			Addr:         listenAddress,
-			Handler:      app.handler,
-			BaseContext:  contextualizer(ctx),
-			ReadTimeout:  10 * time.Second,
-			WriteTimeout: 1 * time.Hour,
-			IdleTimeout:  12 * time.Hour,
-		}
-		ch <- &result{"http", server.ListenAndServe()}
-		wg.Done()
-	})
+	servers := []shutdowner{}
+	serve := func(name string, s shutdowner, run func() error) {
+		servers = append(servers, s)
+		wg.Go(func() {
+			err := run()
+			if errors.Is(err, http.ErrServerClosed) {
+				err = nil
+			}
+			ch <- &result{name, err}
+		})
+	}
+
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		return fmt.Errorf("can't get SiteConfig: %w", err)
+	}
+	httpHandler := app.handler
+	if app.certs != nil {
+		app.bonusPorts.start(serveCtx, sc, listenAddress, app.httpsListenAddress)
+		if !app.serveHTTP {
+			httpHandler = certs.Redirect(app.httpsListenAddress)
+		}
+		// The CA checks we own the name over plain HTTP.
+		httpHandler = app.certs.HTTPHandler(httpHandler)
+
+		httpsHandler := app.handler
+		if app.http3 {
+			h3 := newHTTP3Server(app.httpsListenAddress, app.handler, app.certs.TLSConfig())
+			httpsHandler = advertiseHTTP3(h3, httpsHandler)
+			serve("http3", h3, h3.ListenAndServe)
+		}
+
+		server := newServer(serveCtx, app.httpsListenAddress, httpsHandler)
+		server.TLSConfig = app.certs.TLSConfig()
+		serve("https", server, func() error { return server.ListenAndServeTLS("", "") })
+	} else {
+		if app.http3 {
+			log.Printf("HTTP/3 needs TLS, not serving it")
+		}
+		app.bonusPorts.start(serveCtx, sc, listenAddress)
+	}
+
+	server := newServer(serveCtx, listenAddress, httpHandler)
+	serve("http", server, server.ListenAndServe)
+
+	go func() {
+		<-ctx.Done()
+		app.drain(servers)
+		stopListening()
+	}()
 
 	// In a seperate thread, wait for the waitgroup, then close the channel
 	// to signal the main collector below that we're done with all the things.
@@ -2182,17 +2934,24 @@ func (app *App) Serve(ctx context.Context, listenAddress string) error {
 		close(ch)
 	}()
 
-	errors := []error{}
+	errs := []error{}
 	for res := range ch {
 		if res.err != nil {
 			log.Printf("server %s exited: %v", res.name, res.err)
-			errors = append(errors, res.err)
+			errs = append(errs, res.err)
 		}
 	}
 
-	if len(errors) == 0 {
+	// The servers return as soon as the drain starts, and the listener may
+	// have failed before it; either way, the requests in flight may still
+	// be going.
+	if ctx.Err() != nil {
+		<-app.drained
+	}
+
+	if len(errs) == 0 {
 		return nil
 	}
 
-	return fmt.Errorf("servers exited: %v", errors)
+	return fmt.Errorf("servers exited: %v", errs)
 }
@@ -0,0 +1,185 @@
+package webapp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/ts4z/irata/he"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/permission"
+	"github.com/ts4z/irata/tournament"
+	"github.com/ts4z/irata/webhook"
+)
+
+// The webhook page shows this many of the latest deliveries.
+const webhookLogLength = 100
+
+func (app *App) applyWebhookForm(ctx context.Context, r *http.Request) (string, error) {
+	if err := r.ParseForm(); err != nil {
+		return "", he.HTTPCodedErrorf(http.StatusBadRequest, "can't parse form")
+	}
+	parseID := func(name string) (int64, error) {
+		id, err := strconv.ParseInt(r.FormValue(name), 10, 64)
+		if err != nil {
+			return 0, he.HTTPCodedErrorf(http.StatusBadRequest, "bad %s", name)
+		}
+		return id, nil
+	}
+
+	switch r.FormValue("Action") {
+	case "create":
+		u, err := url.Parse(strings.TrimSpace(r.FormValue("URL")))
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return "", he.HTTPCodedErrorf(http.StatusBadRequest, "want an http or https URL")
+		}
+		events, err := webhook.ParseEvents(r.Form["Events"])
+		if err != nil {
+			return "", he.New(http.StatusBadRequest, err)
+		}
+		w := &model.Webhook{URL: u.String(), Secret: webhook.NewSecret(), Events: events}
+		if w.TournamentID, err = parseID("TournamentID"); err != nil {
+			return "", err
+		}
+		id, err := app.webhookStorage.CreateWebhook(ctx, w)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Added webhook %d.", id), nil
+	case "delete":
+		id, err := parseID("WebhookID")
+		if err != nil {
+			return "", err
+		}
+		if err := app.webhookStorage.DeleteWebhook(ctx, id); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Deleted webhook %d and its deliveries.", id), nil
+	case "retry":
+		id, err := parseID("DeliveryID")
+		if err != nil {
+			return "", err
+		}
+		if err := app.webhookStorage.RetryWebhookDelivery(ctx, id, app.clock.Now()); err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("Delivery %d will be tried again.", id), nil
+	default:
+		return "", he.HTTPCodedErrorf(http.StatusBadRequest, "unknown action")
+	}
+}
+
+func (app *App) handleManageWebhooks(ctx context.Context, w http.ResponseWriter, r *http.Request) {
+	var flash, flashType string
+	if r.Method == http.MethodPost {
+		if msg, err := app.applyWebhookForm(ctx, r); err != nil {
+			logger.WarnContext(ctx, "manage webhooks", "err", err)
+			flash, flashType = err.Error(), "boo"
+		} else {
+			flash, flashType = msg, "yay"
+		}
+	}
+
+	sc, err := app.siteStorageReader.FetchSiteConfig(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch site config", err)
+		return
+	}
+	hooks, err := app.webhookStorage.FetchWebhooks(ctx)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch webhooks", err)
+		return
+	}
+	deliveries, err := app.webhookStorage.FetchWebhookDeliveries(ctx, webhookLogLength)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch webhook deliveries", err)
+		return
+	}
+	// TODO: pagination
+	overview, err := app.tournamentStorage.FetchOverview(ctx, tournament.CurrentLifecycles, 0, 100)
+	if err != nil {
+		he.SendErrorToHTTPClient(w, "fetch overview", err)
+		return
+	}
+
+	tournamentNames := map[int64]string{}
+	for _, slug := range overview.Slugs {
+		tournamentNames[slug.TournamentID] = slug.TournamentName
+	}
+	tournamentName := func(id int64) string {
+		if name := tournamentNames[id]; name != "" {
+			return name
+		}
+		return fmt.Sprintf("Tournament %d", id)
+	}
+
+	type webhookRow struct {
+		*model.Webhook
+		Target string
+	}
+	rows := []webhookRow{}
+	urls := map[int64]string{}
+	for _, h := range hooks {
+		row := webhookRow{Webhook: h, Target: "Every tournament"}
+		if h.TournamentID != 0 {
+			row.Target = tournamentName(h.TournamentID)
+		}
+		rows = append(rows, row)
+		urls[h.ID] = h.URL
+	}
+
+	now := app.clock.Now()
+	type deliveryRow struct {
+		*model.WebhookDelivery
+		URL        string
+		Tournament string
+		When       string
+		Next       string
+	}
+	deliveryRows := []deliveryRow{}
+	for _, d := range deliveries {
+		row := deliveryRow{
+			WebhookDelivery: d,
+			URL:             urls[d.WebhookID],
+			Tournament:      tournamentName(d.TournamentID),
+			When:            now.Sub(d.Created).Round(time.Second).String() + " ago",
+		}
+		if d.Status == model.WebhookPending && d.Attempts > 0 {
+			row.Next = "next try in " + max(d.NextAttempt.Sub(now), 0).Round(time.Second).String()
+		}
+		deliveryRows = append(deliveryRows, row)
+	}
+
+	data := struct {
+		Webhooks    []webhookRow
+		Deliveries  []deliveryRow
+		Tournaments []model.TournamentSlug
+		Events      []string
+		MaxAttempts int
+		Flash       string
+		FlashType   string
+		Theme       string
+		Nick        string
+		IsAdmin     bool
+		IsOperator  bool
+	}{
+		Webhooks:    rows,
+		Deliveries:  deliveryRows,
+		Tournaments: overview.Slugs,
+		Events:      webhook.Events,
+		MaxAttempts: webhook.MaxAttempts,
+		Flash:       flash,
+		FlashType:   flashType,
+		Theme:       sc.Theme,
+		Nick:        app.currentUserNick(ctx),
+		IsAdmin:     permission.IsAdmin(ctx),
+		IsOperator:  permission.IsOperator(ctx),
+	}
+	if err := app.templates.ExecuteTemplate(w, "manage-webhooks.html.tmpl", data); err != nil {
+		logger.ErrorContext(ctx, "can't render template", "template", "manage-webhooks.html.tmpl", "err", err)
+	}
+}
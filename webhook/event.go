@@ -0,0 +1,91 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/ts4z/irata/model"
+)
+
+// What a webhook can be told about.
+const (
+	LevelStarted       = "level.started"
+	BreakStarted       = "break.started"
+	ClockPaused        = "clock.paused"
+	ClockResumed       = "clock.resumed"
+	PlayersChanged     = "players.changed"
+	TournamentFinished = "tournament.finished"
+	ConfigEdited       = "config.edited"
+)
+
+// Events are all the events, in the order they're shown.
+var Events = []string{LevelStarted, BreakStarted, ClockPaused, ClockResumed, PlayersChanged, TournamentFinished, ConfigEdited}
+
+// ParseEvents checks names are events, returning them in the order of
+// Events.  There must be at least one.
+func ParseEvents(names []string) ([]string, error) {
+	for _, name := range names {
+		if !slices.Contains(Events, name) {
+			return nil, fmt.Errorf("unknown event %q, want some of %s", name, strings.Join(Events, ", "))
+		}
+	}
+	events := []string{}
+	for _, e := range Events {
+		if slices.Contains(names, e) {
+			events = append(events, e)
+		}
+	}
+	if len(events) == 0 {
+		return nil, fmt.Errorf("no events, want some of %s", strings.Join(Events, ", "))
+	}
+	return events, nil
+}
+
+// Diff returns the events that happened between before and after, two
+// copies of the same tournament: as stored and as saved over it, or as
+// stored and brought up to date.
+func Diff(before, after *model.Tournament) []string {
+	events := []string{}
+	b, a := before.State, after.State
+	if a.CurrentLevelNumber != b.CurrentLevelNumber {
+		if l := after.CurrentLevel(); l != nil && l.IsBreak {
+			events = append(events, BreakStarted)
+		} else if l != nil {
+			events = append(events, LevelStarted)
+		}
+	}
+	if a.IsClockRunning != b.IsClockRunning {
+		if a.IsClockRunning {
+			events = append(events, ClockResumed)
+		} else {
+			events = append(events, ClockPaused)
+		}
+	}
+	if a.CurrentPlayers != b.CurrentPlayers {
+		events = append(events, PlayersChanged)
+	}
+	if a.Lifecycle == model.LifecycleFinished && b.Lifecycle != model.LifecycleFinished {
+		events = append(events, TournamentFinished)
+	}
+	if !sameConfig(before, after) {
+		events = append(events, ConfigEdited)
+	}
+	return events
+}
+
+// sameConfig says whether two tournaments are set up the same, ignoring
+// what changes as they run.
+func sameConfig(a, b *model.Tournament) bool {
+	config := func(t *model.Tournament) []byte {
+		cpy := *t
+		cpy.Version = 0
+		cpy.State = nil
+		cpy.Transients = nil
+		// Tournaments are saved this way, so this can't fail.
+		bytes, _ := json.Marshal(&cpy)
+		return bytes
+	}
+	return string(config(a)) == string(config(b))
+}
@@ -0,0 +1,73 @@
+package webhook
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/ts4z/irata/model"
+)
+
+func newEventTournament() *model.Tournament {
+	return &model.Tournament{
+		EventID:          1,
+		EventName:        "Tuesday",
+		NextLevelSoundID: -1,
+		Structure: model.StructureData{
+			Levels: []*model.Level{{DurationMinutes: 20}, {DurationMinutes: 10, IsBreak: true}, {DurationMinutes: 20}},
+		},
+		State: &model.State{CurrentPlayers: 30, IsClockRunning: true, Lifecycle: model.LifecycleRunning},
+	}
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		edit func(m *model.Tournament)
+		want []string
+	}{
+		{"nothing", func(m *model.Tournament) {}, []string{}},
+		{"next level", func(m *model.Tournament) { m.State.CurrentLevelNumber = 2 }, []string{LevelStarted}},
+		{"break", func(m *model.Tournament) { m.State.CurrentLevelNumber = 1 }, []string{BreakStarted}},
+		{"paused", func(m *model.Tournament) { m.State.IsClockRunning = false }, []string{ClockPaused}},
+		{"bust out", func(m *model.Tournament) { m.State.CurrentPlayers-- }, []string{PlayersChanged}},
+		{"buy-in isn't a player change", func(m *model.Tournament) { m.State.BuyIns++ }, []string{}},
+		{"finished", func(m *model.Tournament) {
+			m.State.CurrentPlayers = 1
+			m.State.Lifecycle = model.LifecycleFinished
+		}, []string{PlayersChanged, TournamentFinished}},
+		{"renamed", func(m *model.Tournament) { m.EventName = "Wednesday" }, []string{ConfigEdited}},
+		{"structure", func(m *model.Tournament) { m.Structure.Levels[2].DurationMinutes = 30 }, []string{ConfigEdited}},
+		{"saving isn't editing", func(m *model.Tournament) {
+			m.Version++
+			m.Transients = &model.Transients{TotalChips: 5}
+		}, []string{}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			before := newEventTournament()
+			after := newEventTournament()
+			tc.edit(after)
+			if got := Diff(before, after); !slices.Equal(got, tc.want) {
+				t.Errorf("got %v, want %v", got, tc.want)
+			}
+		})
+	}
+
+	before := newEventTournament()
+	before.State.IsClockRunning = false
+	if got := Diff(before, newEventTournament()); !slices.Equal(got, []string{ClockResumed}) {
+		t.Errorf("resuming: got %v", got)
+	}
+}
+
+func TestParseEvents(t *testing.T) {
+	got, err := ParseEvents([]string{ConfigEdited, LevelStarted, LevelStarted})
+	if err != nil || !slices.Equal(got, []string{LevelStarted, ConfigEdited}) {
+		t.Errorf("got %v, %v", got, err)
+	}
+	if _, err := ParseEvents(nil); err == nil {
+		t.Error("no events parsed")
+	}
+	if _, err := ParseEvents([]string{"level.ended"}); err == nil {
+		t.Error("unknown event parsed")
+	}
+}
@@ -0,0 +1,60 @@
+package webhook
+
+import (
+	"context"
+
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+)
+
+// TournamentStorage notices the events in each tournament saved through
+// it.  It must wrap storage as stored, under any cache, so it can see what
+// a save replaces.
+type TournamentStorage struct {
+	hooks *Hooks
+	next  state.TournamentStorage
+}
+
+func NewTournamentStorage(storage state.TournamentStorage, h *Hooks) *TournamentStorage {
+	return &TournamentStorage{
+		next:  storage,
+		hooks: h,
+	}
+}
+
+var _ state.TournamentStorage = (*TournamentStorage)(nil)
+
+func (s *TournamentStorage) CreateTournament(ctx context.Context, t *model.Tournament) (int64, error) {
+	return s.next.CreateTournament(ctx, t)
+}
+
+func (s *TournamentStorage) DeleteTournament(ctx context.Context, id int64) error {
+	return s.next.DeleteTournament(ctx, id)
+}
+
+func (s *TournamentStorage) FetchOverview(ctx context.Context, lifecycles []model.Lifecycle, offset int, limit int) (*model.Overview, error) {
+	return s.next.FetchOverview(ctx, lifecycles, offset, limit)
+}
+
+func (s *TournamentStorage) FetchTournament(ctx context.Context, id int64) (*model.Tournament, error) {
+	return s.next.FetchTournament(ctx, id)
+}
+
+func (s *TournamentStorage) SaveTournament(ctx context.Context, t *model.Tournament) error {
+	before, err := s.next.FetchTournament(ctx, t.EventID)
+	if err != nil {
+		logger.WarnContext(ctx, "can't fetch tournament before saving", "tournament", t.EventID, "err", err)
+		before = nil
+	} else if before.Version != t.Version {
+		// The save will fail the version check anyway.
+		before = nil
+	}
+
+	if err := s.next.SaveTournament(ctx, t); err != nil {
+		return err
+	}
+	if before != nil {
+		s.hooks.Saved(ctx, before, t)
+	}
+	return nil
+}
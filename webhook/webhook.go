@@ -0,0 +1,414 @@
+/*
+Package webhook tells other programs what tournaments are doing, by POSTing
+JSON to URLs an admin registers.
+
+Events are found by comparing a tournament as stored with the tournament
+being saved over it, and, since the clock moves on by itself, by comparing
+each running tournament as stored with where its clock has got to.  Each
+event goes into an outbox in the database, one delivery per webhook that
+wants it, and is sent from there, so deliveries survive a restart.  A
+delivery that fails is tried again, backing off, until MaxAttempts.
+
+Every delivery is keyed by the change it's about, so servers sharing a
+database, which all notice the same changes, deliver each once.  A new
+level or break is keyed by the tournament and the level: a save and a sweep
+can both notice the clock moving on, and it's announced once however it's
+noticed.  A finish is keyed by the tournament alone, and anything else by
+the tournament, the stored version and the event.
+
+Each request is signed: the X-Irata-Signature header is "sha256=" and the
+hex HMAC-SHA256 of the body, keyed with the webhook's secret.
+*/
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/ts4z/irata/logz"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/tournament"
+	"github.com/ts4z/irata/ts"
+	"github.com/ts4z/irata/varz"
+)
+
+var logger = logz.New()
+
+var (
+	foundByEvent   = varz.NewMap("foundByEvent")
+	queued         = varz.NewInt("queued")
+	delivered      = varz.NewInt("delivered")
+	attemptsFailed = varz.NewInt("attemptsFailed")
+	abandoned      = varz.NewInt("abandoned")
+)
+
+// Headers sent with each delivery.
+const (
+	SignatureHeader = "X-Irata-Signature"
+	EventHeader     = "X-Irata-Event"
+	DeliveryHeader  = "X-Irata-Delivery"
+)
+
+const (
+	// DefaultInterval is how often Run looks at running clocks, and for
+	// deliveries due another try.  Level changes are noticed this late.
+	DefaultInterval = 5 * time.Second
+
+	// MaxAttempts is how many times a delivery is tried before giving up.
+	MaxAttempts = 10
+
+	// Keep is how long delivered and abandoned deliveries stay in the log.
+	Keep = 7 * 24 * time.Hour
+
+	firstRetry     = 30 * time.Second
+	maxRetry       = time.Hour
+	requestTimeout = 10 * time.Second
+	// A claimed delivery is left alone by other servers this long, which
+	// is plenty to send it.
+	lease     = 2 * time.Minute
+	sendBatch = 20
+)
+
+// Backoff is how long to wait after a delivery has failed attempts times.
+func Backoff(attempts int) time.Duration {
+	d := firstRetry
+	for i := 1; i < attempts && d < maxRetry; i++ {
+		d *= 2
+	}
+	return min(d, maxRetry)
+}
+
+// NewSecret makes a secret to sign a webhook's deliveries.
+func NewSecret() string {
+	return rand.Text()
+}
+
+// Sign is the signature header for body.
+func Sign(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify says whether signature is Sign(secret, body), for receivers.
+func Verify(secret string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(Sign(secret, body)), []byte(signature))
+}
+
+// Payload is the body of a delivery.
+type Payload struct {
+	Event string
+	Time  time.Time
+	// Tournament is as the clocks see it when the event happened, as
+	// from the REST API.
+	Tournament *model.Tournament
+}
+
+// Advancer brings a tournament's clock up to date.  tournament.Manager
+// implements this.
+type Advancer interface {
+	FillTransientsAndAdvanceClock(ctx context.Context, m *model.Tournament)
+}
+
+// Hooks finds events and delivers them.
+type Hooks struct {
+	storage     state.WebhookStorage
+	tournaments state.TournamentStorage
+	advancer    Advancer
+	clock       ts.Clock
+	client      *http.Client
+	wake        chan struct{}
+
+	mu sync.Mutex
+	// swept has the events each tournament's last sweep found, so a
+	// clock that's moved on isn't queued over and over.
+	swept     map[int64]string
+	lastPrune time.Time
+}
+
+// New makes Hooks.  tournaments must be storage as stored: not a cache,
+// whose tournaments get their clocks advanced in place.
+func New(storage state.WebhookStorage, tournaments state.TournamentStorage, advancer Advancer, clock ts.Clock) *Hooks {
+	return &Hooks{
+		storage:     storage,
+		tournaments: tournaments,
+		advancer:    advancer,
+		clock:       clock,
+		client: &http.Client{
+			Timeout: requestTimeout,
+			// A redirect is an answer we didn't want.
+			CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse },
+		},
+		wake:  make(chan struct{}, 1),
+		swept: map[int64]string{},
+	}
+}
+
+// advanced is a copy of t with its clock brought up to now.
+func (h *Hooks) advanced(ctx context.Context, t *model.Tournament) *model.Tournament {
+	t = t.Clone()
+	h.advancer.FillTransientsAndAdvanceClock(ctx, t)
+	return t
+}
+
+// Saved queues the events between before, as it was stored, and after,
+// just saved over it.  They're compared as stored: a save often carries the
+// clock past a level's end, and that's the only time the new level is seen
+// stored.
+func (h *Hooks) Saved(ctx context.Context, before, after *model.Tournament) {
+	if events := Diff(before, after); len(events) > 0 {
+		if err := h.queue(ctx, h.advanced(ctx, after), after.Version, events); err != nil {
+			logger.ErrorContext(ctx, "can't queue webhook deliveries", "tournament", after.EventID, "err", err)
+		}
+	}
+}
+
+// Sweep queues the events that time alone has brought about in live
+// tournaments, like a new level starting.
+func (h *Hooks) Sweep(ctx context.Context) error {
+	ids := []int64{}
+	const batch = 100
+	for offset := 0; ; offset += batch {
+		o, err := h.tournaments.FetchOverview(ctx, tournament.LiveLifecycles, offset, batch)
+		if err != nil {
+			return err
+		}
+		for _, slug := range o.Slugs {
+			ids = append(ids, slug.TournamentID)
+		}
+		if len(o.Slugs) < batch {
+			break
+		}
+	}
+
+	swept := map[int64]string{}
+	for _, id := range ids {
+		t, err := h.tournaments.FetchTournament(ctx, id)
+		if err != nil {
+			logger.WarnContext(ctx, "can't fetch tournament", "tournament", id, "err", err)
+			continue
+		}
+		if !t.State.IsClockRunning {
+			// Nothing happens by itself to a stopped clock.
+			continue
+		}
+		now := h.advanced(ctx, t)
+		events := Diff(t, now)
+		if len(events) == 0 {
+			continue
+		}
+		// Keyed by the stored version, the same for every server.
+		key := fmt.Sprintf("%d/%d/%s", t.Version, now.State.CurrentLevelNumber, strings.Join(events, ","))
+		swept[id] = key
+		if h.sweptBefore(id, key) {
+			continue
+		}
+		if err := h.queue(ctx, now, t.Version, events); err != nil {
+			logger.ErrorContext(ctx, "can't queue webhook deliveries", "tournament", id, "err", err)
+			delete(swept, id)
+		}
+	}
+
+	h.mu.Lock()
+	h.swept = swept
+	h.mu.Unlock()
+	return nil
+}
+
+func (h *Hooks) sweptBefore(id int64, key string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.swept[id] == key
+}
+
+// deliveryKey is what deduplicates deliveries of event, which happened to
+// t, stored as version.
+func deliveryKey(t *model.Tournament, version int64, event string) string {
+	switch event {
+	case LevelStarted, BreakStarted:
+		return fmt.Sprintf("%d/level/%d/%s", t.EventID, t.State.CurrentLevelNumber, event)
+	case TournamentFinished:
+		return fmt.Sprintf("%d/%s", t.EventID, event)
+	default:
+		return fmt.Sprintf("%d/%d/%s", t.EventID, version, event)
+	}
+}
+
+// queue puts a delivery of each event in the outbox for each webhook that
+// wants it.  t is the tournament after the events, and version the stored
+// version they came from.
+func (h *Hooks) queue(ctx context.Context, t *model.Tournament, version int64, events []string) error {
+	hooks, err := h.storage.FetchWebhooks(ctx)
+	if err != nil {
+		return err
+	}
+	now := h.clock.Now()
+	added := false
+	for _, event := range events {
+		foundByEvent.Add(event, 1)
+		body, err := json.Marshal(&Payload{Event: event, Time: now, Tournament: t})
+		if err != nil {
+			return err
+		}
+		for _, w := range hooks {
+			if !w.Wants(t.EventID, event) {
+				continue
+			}
+			ok, err := h.storage.EnqueueWebhookDelivery(ctx, &model.WebhookDelivery{
+				WebhookID:    w.ID,
+				TournamentID: t.EventID,
+				Event:        event,
+				Key:          deliveryKey(t, version, event),
+				Payload:      string(body),
+				NextAttempt:  now,
+				Created:      now,
+			})
+			if err != nil {
+				return err
+			}
+			if ok {
+				queued.Add(1)
+				added = true
+			}
+		}
+	}
+	if added {
+		select {
+		case h.wake <- struct{}{}:
+		default:
+		}
+	}
+	return nil
+}
+
+// SendDue tries the deliveries that are due, returning how many it tried.
+func (h *Hooks) SendDue(ctx context.Context) (int, error) {
+	now := h.clock.Now()
+	ds, err := h.storage.ClaimWebhookDeliveries(ctx, now, now.Add(lease), sendBatch)
+	if err != nil || len(ds) == 0 {
+		return 0, err
+	}
+	hooks, err := h.storage.FetchWebhooks(ctx)
+	if err != nil {
+		return 0, err
+	}
+	byID := map[int64]*model.Webhook{}
+	for _, w := range hooks {
+		byID[w.ID] = w
+	}
+
+	for _, d := range ds {
+		w := byID[d.WebhookID]
+		if w == nil {
+			// Deleted since, and its deliveries with it.
+			continue
+		}
+		h.send(ctx, w, d)
+		if err := h.storage.SaveWebhookDelivery(ctx, d); err != nil {
+			logger.ErrorContext(ctx, "can't save webhook delivery", "delivery", d.ID, "err", err)
+		}
+	}
+	return len(ds), nil
+}
+
+// send makes one attempt at d, recording how it went in d.
+func (h *Hooks) send(ctx context.Context, w *model.Webhook, d *model.WebhookDelivery) {
+	d.Attempts++
+	d.LastStatus = 0
+	err := func() error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodPost, w.URL, strings.NewReader(d.Payload))
+		if err != nil {
+			return err
+		}
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("User-Agent", "irata-webhook")
+		req.Header.Set(EventHeader, d.Event)
+		req.Header.Set(DeliveryHeader, strconv.FormatInt(d.ID, 10))
+		req.Header.Set(SignatureHeader, Sign(w.Secret, []byte(d.Payload)))
+		resp, err := h.client.Do(req)
+		if err != nil {
+			return err
+		}
+		defer resp.Body.Close()
+		// Read a little, so the connection can be used again.
+		io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+		d.LastStatus = resp.StatusCode
+		if resp.StatusCode < 200 || resp.StatusCode > 299 {
+			return fmt.Errorf("%s", resp.Status)
+		}
+		return nil
+	}()
+
+	now := h.clock.Now()
+	switch {
+	case err == nil:
+		delivered.Add(1)
+		d.Status = model.WebhookDelivered
+		d.Delivered = &now
+		d.LastError = ""
+	case d.Attempts >= MaxAttempts:
+		abandoned.Add(1)
+		d.Status = model.WebhookFailed
+		d.LastError = err.Error()
+		logger.WarnContext(ctx, "giving up on webhook delivery", "delivery", d.ID, "url", w.URL, "err", err)
+	default:
+		attemptsFailed.Add(1)
+		d.NextAttempt = now.Add(Backoff(d.Attempts))
+		d.LastError = err.Error()
+		logger.InfoContext(ctx, "webhook delivery failed", "delivery", d.ID, "url", w.URL, "attempts", d.Attempts, "err", err)
+	}
+}
+
+// Run sweeps clocks and sends deliveries every interval, and sends new
+// deliveries as soon as they're queued, until ctx is done.
+func (h *Hooks) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := h.Sweep(ctx); err != nil {
+				logger.ErrorContext(ctx, "can't sweep clocks", "err", err)
+			}
+			h.prune(ctx)
+		case <-h.wake:
+		}
+		for {
+			n, err := h.SendDue(ctx)
+			if err != nil {
+				logger.ErrorContext(ctx, "can't send webhook deliveries", "err", err)
+			}
+			if n < sendBatch {
+				break
+			}
+		}
+	}
+}
+
+// prune forgets old deliveries, about once an hour.
+func (h *Hooks) prune(ctx context.Context) {
+	now := h.clock.Now()
+	if now.Sub(h.lastPrune) < time.Hour {
+		return
+	}
+	h.lastPrune = now
+	if n, err := h.storage.PruneWebhookDeliveries(ctx, now.Add(-Keep)); err != nil {
+		logger.ErrorContext(ctx, "can't prune webhook deliveries", "err", err)
+	} else if n > 0 {
+		logger.InfoContext(ctx, "pruned webhook deliveries", "count", n)
+	}
+}
@@ -0,0 +1,352 @@
+package webhook
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jonboulle/clockwork"
+
+	"github.com/ts4z/irata/dbutil"
+	"github.com/ts4z/irata/migrate"
+	"github.com/ts4z/irata/model"
+	"github.com/ts4z/irata/state"
+	"github.com/ts4z/irata/tournament"
+)
+
+var t0 = time.Date(2026, 6, 2, 19, 0, 0, 0, time.UTC)
+
+const secret = "sekrit"
+
+type received struct {
+	event   string
+	payload Payload
+}
+
+// receiver is a webhook's far end.  It answers with status, and checks
+// each request is signed.
+type receiver struct {
+	t      *testing.T
+	mu     sync.Mutex
+	status int
+	got    []received
+}
+
+func (rc *receiver) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		rc.t.Errorf("reading body: %v", err)
+	}
+	if !Verify(secret, body, r.Header.Get(SignatureHeader)) {
+		rc.t.Errorf("bad signature %q", r.Header.Get(SignatureHeader))
+	}
+	var p Payload
+	if err := json.Unmarshal(body, &p); err != nil {
+		rc.t.Errorf("decoding payload: %v", err)
+	}
+	if r.Header.Get(EventHeader) != p.Event || r.Header.Get(DeliveryHeader) == "" {
+		rc.t.Errorf("headers %v for %s", r.Header, p.Event)
+	}
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.got = append(rc.got, received{p.Event, p})
+	w.WriteHeader(rc.status)
+}
+
+func (rc *receiver) take() []received {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	got := rc.got
+	rc.got = nil
+	return got
+}
+
+func (rc *receiver) answer(status int) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	rc.status = status
+}
+
+type fixture struct {
+	storage *state.DBStorage
+	clock   *clockwork.FakeClock
+	tm      *tournament.Manager
+	rc      *receiver
+	id      int64
+}
+
+func newFixture(t *testing.T, events ...string) *fixture {
+	ctx := context.Background()
+	db, err := dbutil.OpenSQLite(filepath.Join(t.TempDir(), "irata.db"))
+	if err != nil {
+		t.Fatalf("open: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+	if err := migrate.Migrate(ctx, db, nil); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	s, err := state.NewDBStorage(ctx, db)
+	if err != nil {
+		t.Fatalf("NewDBStorage: %v", err)
+	}
+
+	f := &fixture{storage: s, clock: clockwork.NewFakeClockAt(t0), rc: &receiver{t: t, status: http.StatusNoContent}}
+	f.tm = tournament.NewManager(f.clock, nil, nil)
+	srv := httptest.NewServer(f.rc)
+	t.Cleanup(srv.Close)
+
+	m := newEventTournament()
+	m.EventID = 0
+	full := (20 * time.Minute).Milliseconds()
+	m.State.IsClockRunning = false
+	m.State.TimeRemainingMillis = &full
+	if f.id, err = s.CreateTournament(ctx, m); err != nil {
+		t.Fatalf("CreateTournament: %v", err)
+	}
+	if _, err := s.CreateWebhook(ctx, &model.Webhook{URL: srv.URL, Secret: secret, Events: events}); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	// Somebody else's tournament.
+	if _, err := s.CreateWebhook(ctx, &model.Webhook{TournamentID: f.id + 1, URL: srv.URL, Secret: "other", Events: Events}); err != nil {
+		t.Fatalf("CreateWebhook: %v", err)
+	}
+	return f
+}
+
+// hooks is a server, which may be one of several, or one restarted.
+func (f *fixture) hooks() (*Hooks, *TournamentStorage) {
+	h := New(f.storage, f.storage, f.tm, f.clock)
+	return h, NewTournamentStorage(f.storage, h)
+}
+
+func (f *fixture) change(t *testing.T, ts *TournamentStorage, edit func(m *model.Tournament)) {
+	ctx := context.Background()
+	m, err := ts.FetchTournament(ctx, f.id)
+	if err != nil {
+		t.Fatalf("FetchTournament: %v", err)
+	}
+	f.tm.FillTransientsAndAdvanceClock(ctx, m)
+	edit(m)
+	if err := ts.SaveTournament(ctx, m); err != nil {
+		t.Fatalf("SaveTournament: %v", err)
+	}
+}
+
+func sendDue(t *testing.T, h *Hooks) int {
+	n, err := h.SendDue(context.Background())
+	if err != nil {
+		t.Fatalf("SendDue: %v", err)
+	}
+	return n
+}
+
+func events(rs []received) []string {
+	es := []string{}
+	for _, r := range rs {
+		es = append(es, r.event)
+	}
+	return es
+}
+
+func TestDeliversSavedEvents(t *testing.T) {
+	f := newFixture(t, ClockResumed, PlayersChanged, ConfigEdited)
+	h, ts := f.hooks()
+
+	f.change(t, ts, func(m *model.Tournament) { f.tm.StartClock(m) })
+	f.change(t, ts, func(m *model.Tournament) { m.State.BuyIns++ })
+	f.change(t, ts, func(m *model.Tournament) { m.State.CurrentPlayers-- })
+	if n := sendDue(t, h); n != 2 {
+		t.Errorf("sent %d, want 2", n)
+	}
+	got := f.rc.take()
+	if want := []string{ClockResumed, PlayersChanged}; len(got) != 2 || got[0].event != want[0] || got[1].event != want[1] {
+		t.Fatalf("got %v, want %v", events(got), want)
+	}
+	p := got[1].payload
+	if p.Tournament.EventID != f.id || p.Tournament.State.CurrentPlayers != 29 || !p.Tournament.State.IsClockRunning ||
+		!p.Time.Equal(t0) || p.Tournament.Transients == nil {
+		t.Errorf("payload = %+v", p)
+	}
+	if n := sendDue(t, h); n != 0 {
+		t.Errorf("sent %d again", n)
+	}
+
+	log, err := f.storage.FetchWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("FetchWebhookDeliveries: %v", err)
+	}
+	for _, d := range log {
+		if d.Status != model.WebhookDelivered || d.Attempts != 1 || d.LastStatus != http.StatusNoContent || d.Delivered == nil {
+			t.Errorf("delivery = %+v", d)
+		}
+	}
+}
+
+func TestRetriesAcrossRestarts(t *testing.T) {
+	f := newFixture(t, PlayersChanged)
+	h, ts := f.hooks()
+	f.rc.answer(http.StatusServiceUnavailable)
+
+	f.change(t, ts, func(m *model.Tournament) { m.State.CurrentPlayers++ })
+	sendDue(t, h)
+	if got := f.rc.take(); len(got) != 1 {
+		t.Fatalf("got %v", events(got))
+	}
+	log, err := f.storage.FetchWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("FetchWebhookDeliveries: %v", err)
+	}
+	if d := log[0]; d.Status != model.WebhookPending || d.Attempts != 1 || d.LastStatus != http.StatusServiceUnavailable ||
+		d.LastError == "" || !d.NextAttempt.Equal(t0.Add(Backoff(1))) {
+		t.Errorf("after failing, delivery = %+v", d)
+	}
+
+	// Not yet.
+	f.clock.Advance(Backoff(1) - time.Second)
+	if n := sendDue(t, h); n != 0 {
+		t.Errorf("retried %d early", n)
+	}
+
+	// The server restarts, and the receiver comes back.
+	f.clock.Advance(time.Second)
+	f.rc.answer(http.StatusOK)
+	h, _ = f.hooks()
+	if n := sendDue(t, h); n != 1 {
+		t.Errorf("retried %d, want 1", n)
+	}
+	if got := f.rc.take(); len(got) != 1 || got[0].event != PlayersChanged {
+		t.Errorf("got %v", events(got))
+	}
+}
+
+func TestGivesUp(t *testing.T) {
+	f := newFixture(t, PlayersChanged)
+	h, ts := f.hooks()
+	f.rc.answer(http.StatusInternalServerError)
+
+	f.change(t, ts, func(m *model.Tournament) { m.State.CurrentPlayers++ })
+	for i := 1; i <= MaxAttempts; i++ {
+		if n := sendDue(t, h); n != 1 {
+			t.Fatalf("attempt %d sent %d", i, n)
+		}
+		f.clock.Advance(Backoff(i))
+	}
+	if n := sendDue(t, h); n != 0 {
+		t.Errorf("sent %d after giving up", n)
+	}
+	log, err := f.storage.FetchWebhookDeliveries(context.Background(), 10)
+	if err != nil {
+		t.Fatalf("FetchWebhookDeliveries: %v", err)
+	}
+	if d := log[0]; d.Status != model.WebhookFailed || d.Attempts != MaxAttempts {
+		t.Errorf("delivery = %+v", d)
+	}
+}
+
+func TestSweepFindsTheClockMovingOn(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, LevelStarted, BreakStarted, ClockPaused)
+	h, ts := f.hooks()
+	f.change(t, ts, func(m *model.Tournament) { f.tm.StartClock(m) })
+
+	if err := h.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	if n := sendDue(t, h); n != 0 {
+		t.Errorf("sent %d before anything happened", n)
+	}
+
+	f.clock.Advance(21 * time.Minute)
+	if err := h.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	// Again, and from another server sharing the database.
+	if err := h.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	other, _ := f.hooks()
+	if err := other.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	sendDue(t, h)
+	if got := f.rc.take(); len(got) != 1 || got[0].event != BreakStarted || got[0].payload.Tournament.State.CurrentLevelNumber != 1 {
+		t.Fatalf("got %v", events(got))
+	}
+
+	// Somebody saves during the break, which isn't news.
+	f.change(t, ts, func(m *model.Tournament) { m.State.BuyIns++ })
+	sendDue(t, h)
+	if got := f.rc.take(); len(got) != 0 {
+		t.Errorf("got %v", events(got))
+	}
+
+	// The levels run out, stopping the clock.
+	f.clock.Advance(time.Hour)
+	if err := h.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	sendDue(t, h)
+	if got := events(f.rc.take()); len(got) != 2 || got[0] != LevelStarted || got[1] != ClockPaused {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestSaveAfterLevelEnds(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, LevelStarted, BreakStarted, PlayersChanged)
+	h, ts := f.hooks()
+	f.change(t, ts, func(m *model.Tournament) { f.tm.StartClock(m) })
+
+	// The level ends, and before the next sweep, somebody busts out.  The
+	// save stores the break; it's the only place the break is seen.
+	f.clock.Advance(21 * time.Minute)
+	f.change(t, ts, func(m *model.Tournament) { m.State.CurrentPlayers-- })
+	sendDue(t, h)
+	if got := events(f.rc.take()); len(got) != 2 || got[0] != BreakStarted || got[1] != PlayersChanged {
+		t.Fatalf("got %v", got)
+	}
+
+	// A sweep from a server that didn't see the save finds nothing new.
+	other, _ := f.hooks()
+	if err := other.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	sendDue(t, h)
+	if got := f.rc.take(); len(got) != 0 {
+		t.Errorf("got %v", events(got))
+	}
+}
+
+func TestSweepThenSaveAnnouncesLevelOnce(t *testing.T) {
+	ctx := context.Background()
+	f := newFixture(t, LevelStarted, BreakStarted)
+	h, ts := f.hooks()
+	f.change(t, ts, func(m *model.Tournament) { f.tm.StartClock(m) })
+
+	f.clock.Advance(21 * time.Minute)
+	if err := h.Sweep(ctx); err != nil {
+		t.Fatalf("Sweep: %v", err)
+	}
+	// The save stores the break the sweep already found.
+	f.change(t, ts, func(m *model.Tournament) { m.State.BuyIns++ })
+	sendDue(t, h)
+	if got := events(f.rc.take()); len(got) != 1 || got[0] != BreakStarted {
+		t.Errorf("got %v", got)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	for attempts, want := range map[int]time.Duration{
+		1: 30 * time.Second, 2: time.Minute, 3: 2 * time.Minute, 7: 32 * time.Minute, 8: time.Hour, 20: time.Hour,
+	} {
+		if got := Backoff(attempts); got != want {
+			t.Errorf("Backoff(%d) = %v, want %v", attempts, got, want)
+		}
+	}
+}